@@ -0,0 +1,117 @@
+// Command bpmn-import converts a BPMN 2.0 process into a workflow template and its node templates.
+//
+// Usage:
+//
+//	bpmn-import [-process id] [-version v] [-family id] [-format json|sql] [-apply] process.bpmn
+//
+// The converted templates are written to stdout as JSON, or as INSERT statements that can be
+// added as a seed migration. With -apply they are also created in the database configured by the
+// same environment variables as the server. The workflow template is created as a DRAFT version,
+// of a new family or, with -family, as the next version of an existing one. Diagnostics are written to stderr, and the command
+// exits with status 1 when the process cannot be imported.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/config"
+	"github.com/OpenNSW/nsw/internal/database"
+	"github.com/OpenNSW/nsw/internal/workflow/bpmn"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
+func main() {
+	processID := flag.String("process", "", "ID of the process to import when the document defines several")
+	version := flag.String("version", bpmn.DefaultVersion, "version of the created workflow template")
+	family := flag.String("family", "", "ID of the workflow template family to add the template to as its next version")
+	format := flag.String("format", "json", "output format: json or sql")
+	apply := flag.Bool("apply", false, "create the templates in the configured database")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <process.bpmn | ->\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || (*format != "json" && *format != "sql") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := bpmn.Options{ProcessID: *processID, Version: *version}
+	if *family != "" {
+		familyID, err := uuid.Parse(*family)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -family: %v\n", err)
+			os.Exit(2)
+		}
+		opts.FamilyID = familyID
+	}
+
+	if err := run(flag.Arg(0), opts, *format, *apply); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, opts bpmn.Options, format string, apply bool) error {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open BPMN document: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	result, err := bpmn.Import(in, opts)
+	if err != nil {
+		return err
+	}
+	for _, d := range result.Diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if result.HasErrors() {
+		return bpmn.ErrImportFailed
+	}
+
+	if apply {
+		if err := createTemplates(result); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "created draft workflow template %s (version %d of family %s) with %d node templates\n",
+			result.WorkflowTemplate.ID, result.WorkflowTemplate.VersionNumber, result.WorkflowTemplate.FamilyID, len(result.NodeTemplates))
+	}
+
+	if format == "sql" {
+		return bpmn.WriteSQL(os.Stdout, result)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func createTemplates(result *bpmn.Result) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(db); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close database: %v\n", err)
+		}
+	}()
+
+	return service.NewTemplateService(db).CreateWorkflowTemplate(context.Background(), &result.WorkflowTemplate, result.NodeTemplates)
+}
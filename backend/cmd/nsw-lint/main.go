@@ -0,0 +1,158 @@
+// Command nsw-lint checks workflow templates and their node templates for dependency cycles,
+// nodes that can never become READY, unlock conditions that can never hold and SIMPLE_FORM
+// configurations referencing forms that do not exist.
+//
+// Usage:
+//
+//	nsw-lint -db [-format json|text]
+//	nsw-lint [-forms] [-format json|text] templates.json...
+//
+// With -db every template in the database configured by the same environment variables as the
+// server is checked. Otherwise the templates are read from JSON files, each holding either the
+// output of bpmn-import or an object with "workflowTemplates" and "nodeTemplates" arrays; node
+// templates of all files are shared. Form IDs are only checked against the database, so with
+// files they are skipped unless -forms is given.
+//
+// Findings are written to stdout, as a JSON object with a "findings" array by default. The
+// command exits with status 1 when any finding is an error.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/config"
+	"github.com/OpenNSW/nsw/internal/database"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// templateFile is a JSON file of templates. The output of bpmn-import sets WorkflowTemplate.
+type templateFile struct {
+	WorkflowTemplate  *model.WorkflowTemplate      `json:"workflowTemplate"`
+	WorkflowTemplates []model.WorkflowTemplate     `json:"workflowTemplates"`
+	NodeTemplates     []model.WorkflowNodeTemplate `json:"nodeTemplates"`
+}
+
+func main() {
+	fromDB := flag.Bool("db", false, "check the templates in the configured database")
+	checkForms := flag.Bool("forms", false, "check form IDs against the configured database when reading files")
+	format := flag.String("format", "json", "output format: json or text")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [templates.json...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if (*fromDB) == (flag.NArg() > 0) || (*format != "json" && *format != "text") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	findings, err := run(context.Background(), *fromDB, *checkForms, flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := write(os.Stdout, findings, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if lint.HasErrors(findings) {
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, fromDB, checkForms bool, paths []string) ([]lint.Finding, error) {
+	var in lint.Input
+	if !fromDB {
+		var err error
+		if in, err = readFiles(paths); err != nil {
+			return nil, err
+		}
+	}
+
+	if fromDB || checkForms {
+		err := withDatabase(func(db *gorm.DB) error {
+			if fromDB {
+				var err error
+				in, err = lint.Load(ctx, db)
+				return err
+			}
+			formIDs, err := lint.LoadFormIDs(ctx, db)
+			in.FormIDs = formIDs
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Plugins are only built to inspect their configuration and FSM, never run, so they need
+	// neither the server configuration nor the form service.
+	return lint.Lint(ctx, plugin.NewTaskFactory(nil, nil), in), nil
+}
+
+func readFiles(paths []string) (lint.Input, error) {
+	var in lint.Input
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return lint.Input{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var file templateFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return lint.Input{}, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if file.WorkflowTemplate != nil {
+			in.WorkflowTemplates = append(in.WorkflowTemplates, *file.WorkflowTemplate)
+		}
+		in.WorkflowTemplates = append(in.WorkflowTemplates, file.WorkflowTemplates...)
+		in.NodeTemplates = append(in.NodeTemplates, file.NodeTemplates...)
+	}
+	return in, nil
+}
+
+func withDatabase(fn func(db *gorm.DB) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(db); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close database: %v\n", err)
+		}
+	}()
+	return fn(db)
+}
+
+func write(w io.Writer, findings []lint.Finding, format string) error {
+	if format == "text" {
+		for _, f := range findings {
+			if _, err := fmt.Fprintln(w, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if findings == nil {
+		findings = []lint.Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Findings []lint.Finding `json:"findings"`
+	}{findings})
+}
@@ -17,13 +17,28 @@ import (
 	"github.com/OpenNSW/nsw/internal/form"
 	"github.com/OpenNSW/nsw/internal/middleware"
 	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
 	"github.com/OpenNSW/nsw/internal/uploads"
 	"github.com/OpenNSW/nsw/internal/workflow"
 )
 
-// ChannelSize defines the buffer size for workflow node update notifications.
-// A larger buffer (1000) prevents notification drops during high load scenarios.
-const ChannelSize = 1000
+// OutboxPollInterval defines how often pending task notifications are delivered to the workflow manager.
+const OutboxPollInterval = 200 * time.Millisecond
+
+// FailedUpdateRetryInterval defines how often failed workflow node updates are checked for retry.
+const FailedUpdateRetryInterval = time.Second
+
+// ReconciliationInterval defines how often workflow nodes are reconciled with task records.
+const ReconciliationInterval = 5 * time.Minute
+
+// TimerPollInterval defines how often due task timers are fired.
+const TimerPollInterval = time.Second
+
+// SLACheckInterval defines how often workflow nodes are checked for expired SLAs.
+const SLACheckInterval = 30 * time.Second
+
+// PreConsignmentExpiryCheckInterval defines how often completed pre-consignments are checked for expiry.
+const PreConsignmentExpiryCheckInterval = time.Minute
 
 func main() {
 	// Load configuration from environment variables
@@ -74,20 +89,37 @@ func main() {
 		log.Fatalf("database health check failed: %v", err)
 	}
 
-	// Create task completion notification channel
-	ch := make(chan taskManager.WorkflowManagerNotification, ChannelSize)
-
 	// Initialize form service
 	formService := form.NewFormService(db)
 
 	// Initialize task manager with database connection
-	tm, err := taskManager.NewTaskManager(db, ch, cfg, formService)
+	tm, err := taskManager.NewTaskManager(db, cfg, formService)
 	if err != nil {
 		log.Fatalf("failed to create task manager: %v", err)
 	}
 
-	// Initialize workflow manager with database connection
-	wm := workflow.NewManager(tm, ch, db)
+	// Initialize workflow manager with database connection and a task factory to validate template node configurations
+	wm := workflow.NewManager(tm, plugin.NewTaskFactory(cfg, formService), db)
+	// Let tasks in LIVE global context mode read the current context of their workflow
+	tm.SetGlobalContextSource(wm.GetTaskGlobalContext)
+	wm.StartRetryWorker(FailedUpdateRetryInterval)
+	wm.StartReconciler(ReconciliationInterval)
+	wm.StartSLAMonitor(SLACheckInterval)
+	wm.StartPreConsignmentExpiryMonitor(PreConsignmentExpiryCheckInterval)
+
+	// Deliver persisted task notifications to the workflow manager
+	outboxDispatcher, err := taskManager.NewOutboxDispatcher(db, wm.HandleTaskNotification, OutboxPollInterval)
+	if err != nil {
+		log.Fatalf("failed to create outbox dispatcher: %v", err)
+	}
+	outboxDispatcher.Start()
+
+	// Fire persisted task timers once they are due
+	timerScheduler, err := taskManager.NewTimerScheduler(db, tm.ExecuteScheduledAction, TimerPollInterval)
+	if err != nil {
+		log.Fatalf("failed to create timer scheduler: %v", err)
+	}
+	timerScheduler.Start()
 
 	// Initialize storage driver and upload service
 	storageDriver, err := uploads.NewStorageFromConfig(context.Background(), cfg.Storage)
@@ -118,14 +150,64 @@ func main() {
 	mux.HandleFunc("GET /api/v1/tasks/{id}", tm.HandleGetTask)
 	mux.HandleFunc("GET /api/v1/hscodes", wm.HandleGetAllHSCodes)
 	mux.HandleFunc("POST /api/v1/consignments", wm.HandleCreateConsignment)
+	mux.HandleFunc("POST /api/v1/consignments/{id}/cancel", wm.HandleCancelConsignment)
+	mux.HandleFunc("POST /api/v1/consignments/{id}/amendments", wm.HandleAmendConsignment)
+	mux.HandleFunc("GET /api/v1/consignments/{id}/amendments", wm.HandleGetConsignmentAmendments)
+	mux.HandleFunc("GET /api/v1/consignments/{id}/events", wm.HandleGetConsignmentEvents)
 	mux.HandleFunc("GET /api/v1/consignments/{id}", wm.HandleGetConsignmentByID)
+	mux.HandleFunc("GET /api/v1/consignments/{id}/graph", wm.HandleGetConsignmentGraph)
 	mux.HandleFunc("GET /api/v1/consignments", wm.HandleGetConsignmentsByTraderID)
+	mux.HandleFunc("GET /api/v1/consignments/search", wm.HandleSearchConsignments)
 
 	// Pre-consignment routes
 	mux.HandleFunc("POST /api/v1/pre-consignments", wm.HandleCreatePreConsignment)
 	mux.HandleFunc("GET /api/v1/pre-consignments/{preConsignmentId}", wm.HandleGetPreConsignmentByID)
+	mux.HandleFunc("POST /api/v1/pre-consignments/{preConsignmentId}/renew", wm.HandleRenewPreConsignment)
+	mux.HandleFunc("POST /api/v1/pre-consignments/{preConsignmentId}/revoke", wm.HandleRevokePreConsignment)
+	mux.HandleFunc("GET /api/v1/pre-consignments/{preConsignmentId}/events", wm.HandleGetPreConsignmentEvents)
 	mux.HandleFunc("GET /api/v1/pre-consignments", wm.HandleGetPreConsignmentsByTraderID)
 
+	// Workflow node routes
+	mux.HandleFunc("POST /api/v1/workflow-nodes/{id}/reopen", wm.HandleReopenWorkflowNode)
+	mux.HandleFunc("GET /api/v1/workflow-nodes/{id}/unlock-explanation", wm.HandleExplainWorkflowNodeUnlock)
+
+	// Admin routes
+	mux.HandleFunc("GET /api/v1/admin/failed-workflow-updates", wm.HandleGetFailedUpdates)
+	mux.HandleFunc("GET /api/v1/admin/failed-workflow-updates/{id}", wm.HandleGetFailedUpdateByID)
+	mux.HandleFunc("POST /api/v1/admin/failed-workflow-updates/{id}/replay", wm.HandleReplayFailedUpdate)
+	mux.HandleFunc("POST /api/v1/admin/failed-workflow-updates/{id}/discard", wm.HandleDiscardFailedUpdate)
+	mux.HandleFunc("POST /api/v1/admin/reconciliations", wm.HandleRunReconciliation)
+	mux.HandleFunc("GET /api/v1/admin/reconciliations/latest", wm.HandleGetLatestReconciliation)
+	mux.HandleFunc("POST /api/v1/admin/workflow-nodes/{id}/reopen", wm.HandleAdminReopenWorkflowNode)
+	mux.HandleFunc("GET /api/v1/admin/workflow-nodes/{id}/unlock-explanation", wm.HandleAdminExplainWorkflowNodeUnlock)
+	mux.HandleFunc("GET /api/v1/admin/consignments/{id}/graph", wm.HandleAdminGetConsignmentGraph)
+	mux.HandleFunc("GET /api/v1/admin/consignments/{id}/amendments", wm.HandleAdminGetConsignmentAmendments)
+	mux.HandleFunc("GET /api/v1/admin/consignments/{id}/events", wm.HandleAdminGetConsignmentEvents)
+	mux.HandleFunc("GET /api/v1/admin/pre-consignments/{preConsignmentId}/events", wm.HandleAdminGetPreConsignmentEvents)
+	mux.HandleFunc("POST /api/v1/admin/workflow-templates/import/bpmn", wm.HandleImportBPMN)
+	mux.HandleFunc("POST /api/v1/admin/workflow-templates/{id}/publish", wm.HandlePublishWorkflowTemplate)
+	mux.HandleFunc("POST /api/v1/admin/workflow-template-migrations", wm.HandleMigrateConsignments)
+	mux.HandleFunc("GET /api/v1/admin/workflow-node-templates", wm.HandleListWorkflowNodeTemplates)
+	mux.HandleFunc("GET /api/v1/admin/workflow-node-templates/{id}", wm.HandleGetWorkflowNodeTemplate)
+	mux.HandleFunc("POST /api/v1/admin/workflow-node-templates", wm.HandleCreateWorkflowNodeTemplate)
+	mux.HandleFunc("PUT /api/v1/admin/workflow-node-templates/{id}", wm.HandleUpdateWorkflowNodeTemplate)
+	mux.HandleFunc("DELETE /api/v1/admin/workflow-node-templates/{id}", wm.HandleDeleteWorkflowNodeTemplate)
+	mux.HandleFunc("GET /api/v1/admin/workflow-templates", wm.HandleListWorkflowTemplates)
+	mux.HandleFunc("GET /api/v1/admin/workflow-templates/{id}", wm.HandleGetWorkflowTemplate)
+	mux.HandleFunc("POST /api/v1/admin/workflow-templates", wm.HandleCreateWorkflowTemplate)
+	mux.HandleFunc("PUT /api/v1/admin/workflow-templates/{id}", wm.HandleUpdateWorkflowTemplate)
+	mux.HandleFunc("DELETE /api/v1/admin/workflow-templates/{id}", wm.HandleDeleteWorkflowTemplate)
+	mux.HandleFunc("GET /api/v1/admin/workflow-template-maps", wm.HandleListWorkflowTemplateMaps)
+	mux.HandleFunc("GET /api/v1/admin/workflow-template-maps/{id}", wm.HandleGetWorkflowTemplateMap)
+	mux.HandleFunc("POST /api/v1/admin/workflow-template-maps", wm.HandleCreateWorkflowTemplateMap)
+	mux.HandleFunc("PUT /api/v1/admin/workflow-template-maps/{id}", wm.HandleUpdateWorkflowTemplateMap)
+	mux.HandleFunc("DELETE /api/v1/admin/workflow-template-maps/{id}", wm.HandleDeleteWorkflowTemplateMap)
+	mux.HandleFunc("GET /api/v1/admin/pre-consignment-templates", wm.HandleListPreConsignmentTemplates)
+	mux.HandleFunc("GET /api/v1/admin/pre-consignment-templates/{id}", wm.HandleGetPreConsignmentTemplate)
+	mux.HandleFunc("POST /api/v1/admin/pre-consignment-templates", wm.HandleCreatePreConsignmentTemplate)
+	mux.HandleFunc("PUT /api/v1/admin/pre-consignment-templates/{id}", wm.HandleUpdatePreConsignmentTemplate)
+	mux.HandleFunc("DELETE /api/v1/admin/pre-consignment-templates/{id}", wm.HandleDeletePreConsignmentTemplate)
+
 	// Upload routes
 	mux.HandleFunc("POST /api/v1/uploads", uploadHandler.Upload)
 	mux.HandleFunc("GET /api/v1/uploads/{key}", uploadHandler.Download)
@@ -172,9 +254,12 @@ func main() {
 		slog.Info("server gracefully stopped")
 	}
 
-	// Stop the workflow manager's task update listener
-	slog.Info("stopping task update listener...")
-	wm.StopWorkflowNodeUpdateListener()
+	// Stop firing timers and delivering task notifications before tearing down the workflow manager
+	slog.Info("stopping timer scheduler...")
+	timerScheduler.Stop()
+	slog.Info("stopping outbox dispatcher...")
+	outboxDispatcher.Stop()
+	wm.Stop()
 
 	slog.Info("server stopped")
 }
@@ -0,0 +1,52 @@
+package auth
+
+import "context"
+
+// ActorType identifies the kind of party that triggered a change.
+type ActorType string
+
+const (
+	ActorTypeTrader ActorType = "TRADER" // An authenticated trader
+	ActorTypeAgency ActorType = "AGENCY" // An agency officer or OGA callback, authenticated with the agency role
+	ActorTypeAdmin  ActorType = "ADMIN"  // An administrator
+	ActorTypeSystem ActorType = "SYSTEM" // The system itself, e.g. a timer, SLA monitor or background worker
+)
+
+// Actor identifies who triggered a change, for audit records.
+type Actor struct {
+	Type ActorType `gorm:"type:varchar(20);column:type" json:"type"`        // Kind of party
+	ID   string    `gorm:"type:varchar(100);column:id" json:"id,omitempty"` // Trader ID of the party; empty for the system
+}
+
+// SystemActor is the actor of changes no party requested directly.
+var SystemActor = Actor{Type: ActorTypeSystem}
+
+// actorContextKey is the context key under which an explicit actor is stored.
+type actorContextKey struct{}
+
+// WithActor returns a copy of ctx that attributes changes to actor, e.g. when applying a change that a
+// trader requested earlier from a background worker.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns who changes made with ctx are attributed to. An actor set with WithActor
+// takes precedence; otherwise the authenticated party of the request is used, and the system when
+// there is none.
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorContextKey{}).(Actor); ok && actor.Type != "" {
+		return actor
+	}
+	authCtx := GetAuthContext(ctx)
+	if authCtx == nil || authCtx.TraderContext == nil {
+		return SystemActor
+	}
+	switch {
+	case authCtx.HasRole(RoleAdmin):
+		return Actor{Type: ActorTypeAdmin, ID: authCtx.TraderID}
+	case authCtx.HasRole(RoleAgency):
+		return Actor{Type: ActorTypeAgency, ID: authCtx.TraderID}
+	default:
+		return Actor{Type: ActorTypeTrader, ID: authCtx.TraderID}
+	}
+}
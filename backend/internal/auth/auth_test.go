@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 )
@@ -126,6 +127,64 @@ func TestAuthContextCreation(t *testing.T) {
 	}
 }
 
+// TestAuthContextHasRole tests role lookup from the trader context
+func TestAuthContextHasRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		context json.RawMessage
+		want    bool
+	}{
+		{name: "Admin role", context: json.RawMessage(`{"role": "admin"}`), want: true},
+		{name: "Other role", context: json.RawMessage(`{"role": "trader"}`), want: false},
+		{name: "No role", context: json.RawMessage(`{}`), want: false},
+		{name: "Invalid context", context: json.RawMessage(`not-json`), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authCtx := &AuthContext{
+				TraderContext: &TraderContext{TraderID: "TRADER-TEST", TraderContext: tt.context},
+			}
+			if got := authCtx.HasRole(RoleAdmin); got != tt.want {
+				t.Errorf("HasRole() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestActorFromContext tests who changes made with a context are attributed to
+func TestActorFromContext(t *testing.T) {
+	withAuth := func(traderContext string) context.Context {
+		return context.WithValue(context.Background(), AuthContextKey, &AuthContext{
+			TraderContext: &TraderContext{TraderID: "TRADER-TEST", TraderContext: json.RawMessage(traderContext)},
+		})
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want Actor
+	}{
+		{name: "No auth context", ctx: context.Background(), want: SystemActor},
+		{name: "Trader", ctx: withAuth(`{}`), want: Actor{Type: ActorTypeTrader, ID: "TRADER-TEST"}},
+		{name: "Agency", ctx: withAuth(`{"role": "agency"}`), want: Actor{Type: ActorTypeAgency, ID: "TRADER-TEST"}},
+		{name: "Admin", ctx: withAuth(`{"role": "admin"}`), want: Actor{Type: ActorTypeAdmin, ID: "TRADER-TEST"}},
+		{
+			name: "Explicit actor takes precedence",
+			ctx:  WithActor(withAuth(`{}`), Actor{Type: ActorTypeAgency, ID: "OGA-1"}),
+			want: Actor{Type: ActorTypeAgency, ID: "OGA-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ActorFromContext(tt.ctx); got != tt.want {
+				t.Errorf("ActorFromContext() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // Example benchmark for token extraction
 func BenchmarkTokenExtraction(b *testing.B) {
 	extractor := NewTokenExtractor()
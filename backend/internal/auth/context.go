@@ -43,3 +43,19 @@ func (ac *AuthContext) GetTraderContextMap() (map[string]any, error) {
 
 	return contextMap, nil
 }
+
+// RoleAdmin is the trader context role granting access to administrative endpoints.
+const RoleAdmin = "admin"
+
+// RoleAgency is the trader context role of agency officers, e.g. allowing them to revoke pre-consignments.
+const RoleAgency = "agency"
+
+// HasRole reports whether the trader context carries the given role under the "role" key.
+func (ac *AuthContext) HasRole(role string) bool {
+	contextMap, err := ac.GetTraderContextMap()
+	if err != nil {
+		return false
+	}
+	value, ok := contextMap["role"].(string)
+	return ok && value == role
+}
@@ -0,0 +1,36 @@
+-- Migration: 014_create_task_outbox.sql
+-- Description: Create task_outbox table for durable task-to-workflow notifications
+-- Created: 2026-10-17
+-- Notes: Rows are written in the same transaction as the task_infos state update and
+--        delivered to the workflow manager at least once, in sequence order per workflow.
+
+-- ============================================================================
+-- Table: task_outbox
+-- Description: Pending and delivered task state notifications
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS task_outbox (
+    sequence BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL,
+    workflow_id UUID NOT NULL,
+    state VARCHAR(50) NOT NULL,
+    extended_state VARCHAR(100),
+    outcome VARCHAR(100),
+    append_global_context JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+-- Partial index for the dispatcher's pending scan
+CREATE INDEX IF NOT EXISTS idx_task_outbox_pending ON task_outbox(sequence) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_task_outbox_workflow_id ON task_outbox(workflow_id);
+CREATE INDEX IF NOT EXISTS idx_task_outbox_task_id ON task_outbox(task_id);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON TABLE task_outbox IS 'Transactional outbox of task state changes awaiting delivery to the workflow manager';
+COMMENT ON COLUMN task_outbox.sequence IS 'Monotonic sequence used to deliver notifications in order per workflow';
+COMMENT ON COLUMN task_outbox.attempts IS 'Number of failed delivery attempts';
+COMMENT ON COLUMN task_outbox.delivered_at IS 'Timestamp of successful delivery; NULL while pending';
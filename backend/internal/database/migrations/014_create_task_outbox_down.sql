@@ -0,0 +1,3 @@
+-- Rollback: Drop task_outbox table
+
+DROP TABLE IF EXISTS task_outbox CASCADE;
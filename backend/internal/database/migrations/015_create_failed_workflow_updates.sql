@@ -0,0 +1,38 @@
+-- Migration: 015_create_failed_workflow_updates.sql
+-- Description: Create failed_workflow_updates table for retrying and dead-lettering workflow node updates
+-- Created: 2026-10-17
+-- Notes: Updates are retried with exponential backoff. Once the maximum number of attempts is
+--        reached they are dead-lettered and wait for an administrator to replay or discard them.
+
+-- ============================================================================
+-- Table: failed_workflow_updates
+-- Description: Workflow node state updates that could not be applied
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS failed_workflow_updates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_node_id UUID NOT NULL,
+    state VARCHAR(50) NOT NULL,
+    extended_state TEXT,
+    outcome VARCHAR(100),
+    append_global_context JSONB,
+    status VARCHAR(50) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    dead_lettered_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_failed_workflow_updates_status CHECK (status IN ('PENDING', 'DEAD_LETTERED', 'RESOLVED', 'DISCARDED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_workflow_updates_status ON failed_workflow_updates(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_failed_workflow_updates_workflow_node_id ON failed_workflow_updates(workflow_node_id);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON TABLE failed_workflow_updates IS 'Retry and dead-letter queue for workflow node updates that failed to apply';
+COMMENT ON COLUMN failed_workflow_updates.status IS 'PENDING (awaiting retry), DEAD_LETTERED (retries exhausted), RESOLVED (applied) or DISCARDED (dropped by an administrator)';
+COMMENT ON COLUMN failed_workflow_updates.attempts IS 'Number of failed attempts to apply the update';
+COMMENT ON COLUMN failed_workflow_updates.next_attempt_at IS 'Earliest time the retry worker may attempt the update again';
@@ -0,0 +1,3 @@
+-- Rollback: Drop failed_workflow_updates table
+
+DROP TABLE IF EXISTS failed_workflow_updates CASCADE;
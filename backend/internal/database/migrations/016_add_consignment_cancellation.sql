@@ -0,0 +1,63 @@
+-- Migration: 016_add_consignment_cancellation.sql
+-- Description: Allow traders to cancel (withdraw) consignments
+-- Created: 2026-10-17
+-- Notes: Cancelled consignments keep their workflow nodes, which are locked, and their tasks,
+--        which move to CANCELLED. OGA-bound forms get a cancel URL so an application already
+--        injected into the OGA portal can be closed.
+--        Nodes are locked in the same transaction that flags them as pending task cancellation.
+--        Their tasks are cancelled after the commit, and the flag is cleared once that succeeds.
+--        If the server stops in between, the reconciler cancels the tasks of the flagged nodes.
+
+-- ============================================================================
+-- Table: consignments
+-- Description: Add the CANCELLED state and record why and when a consignment was cancelled
+-- ============================================================================
+ALTER TABLE consignments
+    DROP CONSTRAINT IF EXISTS consignments_state_check;
+
+ALTER TABLE consignments
+    ADD CONSTRAINT consignments_state_check
+        CHECK (state IN ('IN_PROGRESS', 'FINISHED', 'CANCELLED'));
+
+ALTER TABLE consignments
+    ADD COLUMN IF NOT EXISTS cancellation_reason TEXT,
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
+-- ============================================================================
+-- Table: task_infos
+-- Description: Add the CANCELLED task state
+-- ============================================================================
+ALTER TABLE task_infos
+    DROP CONSTRAINT IF EXISTS task_infos_state_check;
+
+ALTER TABLE task_infos
+    ADD CONSTRAINT task_infos_state_check
+        CHECK (state IN ('INITIALIZED', 'IN_PROGRESS', 'COMPLETED', 'FAILED', 'CANCELLED'));
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Add the pending task cancellation flag
+-- ============================================================================
+ALTER TABLE workflow_nodes
+    ADD COLUMN IF NOT EXISTS task_cancellation_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Partial index for the reconciler's scan of pending cancellations
+CREATE INDEX IF NOT EXISTS idx_workflow_nodes_task_cancellation_pending ON workflow_nodes(id)
+    WHERE task_cancellation_pending;
+
+-- ============================================================================
+-- Table: workflow_node_templates
+-- Description: Point OGA-bound form submissions at the OGA portal's cancel endpoint
+-- ============================================================================
+UPDATE workflow_node_templates
+SET config = jsonb_set(config, '{submission,cancelUrl}',
+                       to_jsonb(replace(config -> 'submission' ->> 'url', '/api/oga/inject', '/api/oga/cancel')))
+WHERE config -> 'submission' ->> 'url' LIKE '%/api/oga/inject';
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN consignments.state IS 'Current state: IN_PROGRESS, FINISHED or CANCELLED';
+COMMENT ON COLUMN consignments.cancellation_reason IS 'Reason given by the trader when the consignment was cancelled';
+COMMENT ON COLUMN consignments.cancelled_at IS 'When the consignment was cancelled';
+COMMENT ON COLUMN workflow_nodes.task_cancellation_pending IS 'Whether the task of the locked node has yet to be cancelled';
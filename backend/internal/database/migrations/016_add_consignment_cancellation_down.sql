@@ -0,0 +1,30 @@
+-- Rollback: Remove consignment cancellation support
+-- Note: Cancelled consignments and tasks must be removed or moved to another state first,
+--       otherwise restoring the original check constraints fails.
+
+UPDATE workflow_node_templates
+SET config = config #- '{submission,cancelUrl}'
+WHERE config -> 'submission' ? 'cancelUrl';
+
+DROP INDEX IF EXISTS idx_workflow_nodes_task_cancellation_pending;
+
+ALTER TABLE workflow_nodes
+    DROP COLUMN IF EXISTS task_cancellation_pending;
+
+ALTER TABLE task_infos
+    DROP CONSTRAINT IF EXISTS task_infos_state_check;
+
+ALTER TABLE task_infos
+    ADD CONSTRAINT task_infos_state_check
+        CHECK (state IN ('INITIALIZED', 'IN_PROGRESS', 'COMPLETED', 'FAILED'));
+
+ALTER TABLE consignments
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS cancellation_reason;
+
+ALTER TABLE consignments
+    DROP CONSTRAINT IF EXISTS consignments_state_check;
+
+ALTER TABLE consignments
+    ADD CONSTRAINT consignments_state_check
+        CHECK (state IN ('IN_PROGRESS', 'FINISHED'));
@@ -0,0 +1,45 @@
+-- Migration: 017_add_workflow_node_reopen.sql
+-- Description: Allow FAILED workflow nodes to be reopened and retried
+-- Created: 2026-10-17
+-- Notes: Whether a node can be reopened, and by whom, is a per-template policy. A reopened node
+--        moves back to READY and its task starts a new attempt; the local state of each earlier
+--        attempt is archived in the task's history.
+
+-- ============================================================================
+-- Table: workflow_node_templates
+-- Description: Add the reopen policy (NULL means FAILED nodes cannot be reopened)
+-- ============================================================================
+ALTER TABLE workflow_node_templates
+    ADD COLUMN IF NOT EXISTS reopen_policy VARCHAR(20);
+
+ALTER TABLE workflow_node_templates
+    ADD CONSTRAINT workflow_node_templates_reopen_policy_check
+        CHECK (reopen_policy IS NULL OR reopen_policy IN ('TRADER', 'ADMIN'));
+
+-- OGA-reviewed forms are the nodes that can be rejected; let traders resubmit them
+UPDATE workflow_node_templates
+SET reopen_policy = 'TRADER'
+WHERE config -> 'submission' ->> 'url' LIKE '%/api/oga/inject';
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Track which attempt of the node is current
+-- ============================================================================
+ALTER TABLE workflow_nodes
+    ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 1;
+
+-- ============================================================================
+-- Table: task_infos
+-- Description: Track the task attempt and archive the state of earlier attempts
+-- ============================================================================
+ALTER TABLE task_infos
+    ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS history JSONB;
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_node_templates.reopen_policy IS 'Who may reopen a FAILED node: TRADER (trader or admin), ADMIN (admin only) or NULL (nobody)';
+COMMENT ON COLUMN workflow_nodes.attempt IS 'Current attempt of the node, incremented each time a FAILED node is reopened';
+COMMENT ON COLUMN task_infos.attempt IS 'Current attempt of the task, incremented each time it is reopened';
+COMMENT ON COLUMN task_infos.history IS 'Archived state and local state of earlier attempts, oldest first';
@@ -0,0 +1,15 @@
+-- Rollback: Remove workflow node reopen support
+-- Note: The history of earlier task attempts is lost.
+
+ALTER TABLE task_infos
+    DROP COLUMN IF EXISTS history,
+    DROP COLUMN IF EXISTS attempt;
+
+ALTER TABLE workflow_nodes
+    DROP COLUMN IF EXISTS attempt;
+
+ALTER TABLE workflow_node_templates
+    DROP CONSTRAINT IF EXISTS workflow_node_templates_reopen_policy_check;
+
+ALTER TABLE workflow_node_templates
+    DROP COLUMN IF EXISTS reopen_policy;
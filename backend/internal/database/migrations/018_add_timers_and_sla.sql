@@ -0,0 +1,68 @@
+-- Migration: 018_add_timers_and_sla.sql
+-- Description: Add persistent task timers (TIMER task type) and workflow node SLAs
+-- Created: 2026-10-17
+-- Notes: Timers and SLA deadlines are persisted so that they fire even if the server restarts
+--        before they are due. An expired SLA sets the node's outcome (default TIMED_OUT) without
+--        changing its state, so that escalation nodes can unlock on it.
+
+-- ============================================================================
+-- Table: task_infos
+-- Description: Add the TIMER task type
+-- ============================================================================
+ALTER TABLE task_infos
+    DROP CONSTRAINT IF EXISTS task_infos_type_check;
+
+ALTER TABLE task_infos
+    ADD CONSTRAINT task_infos_type_check
+        CHECK (type IN ('SIMPLE_FORM', 'WAIT_FOR_EVENT', 'TIMER'));
+
+-- ============================================================================
+-- Table: task_timers
+-- Description: Actions scheduled to be executed on tasks at a given time
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS task_timers (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    fire_at TIMESTAMPTZ NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fired_at TIMESTAMPTZ
+);
+
+-- Partial index for the scheduler's due scan
+CREATE INDEX IF NOT EXISTS idx_task_timers_due ON task_timers(fire_at) WHERE fired_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_task_timers_task_id ON task_timers(task_id);
+
+-- ============================================================================
+-- Table: workflow_node_templates
+-- Description: Add the optional SLA of nodes of the template
+-- ============================================================================
+ALTER TABLE workflow_node_templates
+    ADD COLUMN IF NOT EXISTS sla JSONB;
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Add the SLA copied from the template and the deadline of the current attempt
+-- ============================================================================
+ALTER TABLE workflow_nodes
+    ADD COLUMN IF NOT EXISTS sla JSONB,
+    ADD COLUMN IF NOT EXISTS sla_deadline TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS sla_breached_at TIMESTAMPTZ;
+
+-- Partial index for the SLA monitor's expiry scan
+CREATE INDEX IF NOT EXISTS idx_workflow_nodes_sla_pending ON workflow_nodes(sla_deadline)
+    WHERE sla_deadline IS NOT NULL AND sla_breached_at IS NULL;
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON TABLE task_timers IS 'Persistent timers that execute an action on a task once due';
+COMMENT ON COLUMN task_timers.action IS 'Action executed on the task when the timer fires';
+COMMENT ON COLUMN task_timers.attempts IS 'Number of failed attempts to fire the timer';
+COMMENT ON COLUMN task_timers.fired_at IS 'Timestamp the timer fired; NULL while pending';
+COMMENT ON COLUMN workflow_node_templates.sla IS 'Optional SLA, e.g. {"duration": "72h", "outcome": "TIMED_OUT"}';
+COMMENT ON COLUMN workflow_nodes.sla IS 'SLA copied from the template when the node was created';
+COMMENT ON COLUMN workflow_nodes.sla_deadline IS 'When the SLA of the current attempt expires, set when the node becomes READY';
+COMMENT ON COLUMN workflow_nodes.sla_breached_at IS 'When the SLA of the current attempt expired; NULL while not breached';
@@ -0,0 +1,21 @@
+-- Rollback: Remove task timers and workflow node SLAs
+-- Note: TIMER tasks must be removed before the type constraint can be restored.
+
+DROP INDEX IF EXISTS idx_workflow_nodes_sla_pending;
+
+ALTER TABLE workflow_nodes
+    DROP COLUMN IF EXISTS sla_breached_at,
+    DROP COLUMN IF EXISTS sla_deadline,
+    DROP COLUMN IF EXISTS sla;
+
+ALTER TABLE workflow_node_templates
+    DROP COLUMN IF EXISTS sla;
+
+DROP TABLE IF EXISTS task_timers;
+
+ALTER TABLE task_infos
+    DROP CONSTRAINT IF EXISTS task_infos_type_check;
+
+ALTER TABLE task_infos
+    ADD CONSTRAINT task_infos_type_check
+        CHECK (type IN ('SIMPLE_FORM', 'WAIT_FOR_EVENT'));
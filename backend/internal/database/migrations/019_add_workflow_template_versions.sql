@@ -0,0 +1,191 @@
+-- Migration: 019_add_workflow_template_versions.sql
+-- Description: Add immutable, published workflow template versions grouped into families
+-- Created: 2026-10-17
+-- Notes: Every existing workflow template becomes version 1 of its own family (the family
+--        reuses the template ID) and is published. HS code mappings now point at a family and
+--        new consignments use its latest published version. Published versions, and the node
+--        templates they reference, can no longer be changed; a change is published as a new
+--        version and in-flight consignments are moved to it explicitly.
+
+-- ============================================================================
+-- Table: workflow_template_families
+-- Description: Groups the versions of a workflow template
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS workflow_template_families (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO workflow_template_families (id, name, description, created_at, updated_at)
+SELECT id, name, description, created_at, updated_at
+FROM workflow_templates
+ON CONFLICT (id) DO NOTHING;
+
+-- ============================================================================
+-- Table: workflow_templates
+-- Description: Add the family, sequential version number and publication status
+-- ============================================================================
+ALTER TABLE workflow_templates
+    ADD COLUMN IF NOT EXISTS family_id UUID,
+    ADD COLUMN IF NOT EXISTS version_number INTEGER,
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'DRAFT',
+    ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;
+
+UPDATE workflow_templates
+SET family_id = id,
+    version_number = 1,
+    status = 'PUBLISHED',
+    published_at = created_at
+WHERE family_id IS NULL;
+
+ALTER TABLE workflow_templates
+    ALTER COLUMN family_id SET NOT NULL,
+    ALTER COLUMN version_number SET NOT NULL;
+
+ALTER TABLE workflow_templates
+    ADD CONSTRAINT fk_workflow_templates_family
+        FOREIGN KEY (family_id) REFERENCES workflow_template_families(id)
+        ON DELETE RESTRICT ON UPDATE CASCADE,
+    ADD CONSTRAINT workflow_templates_status_check
+        CHECK (status IN ('DRAFT', 'PUBLISHED')),
+    ADD CONSTRAINT workflow_templates_version_number_check
+        CHECK (version_number > 0),
+    ADD CONSTRAINT uq_workflow_templates_family_version
+        UNIQUE (family_id, version_number);
+
+-- Index for resolving the latest published version of a family
+CREATE INDEX IF NOT EXISTS idx_workflow_templates_family_published
+    ON workflow_templates(family_id, version_number DESC) WHERE status = 'PUBLISHED';
+
+-- ============================================================================
+-- Table: workflow_template_maps
+-- Description: Map HS codes to a workflow template family instead of a single template
+-- ============================================================================
+ALTER TABLE workflow_template_maps
+    ADD COLUMN IF NOT EXISTS workflow_template_family_id UUID;
+
+UPDATE workflow_template_maps m
+SET workflow_template_family_id = wt.family_id
+FROM workflow_templates wt
+WHERE wt.id = m.workflow_template_id
+  AND m.workflow_template_family_id IS NULL;
+
+ALTER TABLE workflow_template_maps
+    ALTER COLUMN workflow_template_family_id SET NOT NULL;
+
+ALTER TABLE workflow_template_maps
+    ADD CONSTRAINT fk_workflow_template_maps_workflow_template_family
+        FOREIGN KEY (workflow_template_family_id) REFERENCES workflow_template_families(id)
+        ON DELETE RESTRICT ON UPDATE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_workflow_template_maps_workflow_template_family_id
+    ON workflow_template_maps(workflow_template_family_id);
+
+DROP INDEX IF EXISTS idx_workflow_template_maps_workflow_template_id;
+
+ALTER TABLE workflow_template_maps
+    DROP CONSTRAINT IF EXISTS fk_workflow_template_maps_workflow_template,
+    DROP COLUMN IF EXISTS workflow_template_id;
+
+-- ============================================================================
+-- Table: consignments
+-- Description: Record the workflow template versions the consignment's nodes were built from
+-- ============================================================================
+ALTER TABLE consignments
+    ADD COLUMN IF NOT EXISTS workflow_template_ids JSONB NOT NULL DEFAULT '[]';
+
+-- Existing consignments did not record their templates: a template is assumed to have been used
+-- if all of its node templates have a node in the consignment.
+UPDATE consignments c
+SET workflow_template_ids = COALESCE((
+    SELECT jsonb_agg(wt.id)
+    FROM workflow_templates wt
+    WHERE jsonb_array_length(wt.nodes) > 0
+      AND wt.nodes <@ (
+          SELECT COALESCE(jsonb_agg(wn.workflow_node_template_id::text), '[]'::jsonb)
+          FROM workflow_nodes wn
+          WHERE wn.consignment_id = c.id
+      )
+), '[]'::jsonb)
+WHERE c.workflow_template_ids = '[]'::jsonb;
+
+-- GIN index for selecting the consignments to migrate off a template version
+CREATE INDEX IF NOT EXISTS idx_consignments_workflow_template_ids
+    ON consignments USING GIN (workflow_template_ids);
+
+-- ============================================================================
+-- Triggers: immutability of published workflow template versions
+-- ============================================================================
+CREATE OR REPLACE FUNCTION protect_published_workflow_template()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.status <> 'DRAFT' THEN
+            RAISE EXCEPTION 'workflow template % is % and cannot be deleted', OLD.id, OLD.status;
+        END IF;
+        RETURN OLD;
+    END IF;
+
+    IF OLD.status <> 'DRAFT' AND (
+        NEW.status IS DISTINCT FROM OLD.status OR
+        NEW.family_id IS DISTINCT FROM OLD.family_id OR
+        NEW.version_number IS DISTINCT FROM OLD.version_number OR
+        NEW.version IS DISTINCT FROM OLD.version OR
+        NEW.nodes IS DISTINCT FROM OLD.nodes OR
+        NEW.end_node_template_id IS DISTINCT FROM OLD.end_node_template_id
+    ) THEN
+        RAISE EXCEPTION 'workflow template % is % and cannot be changed', OLD.id, OLD.status;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_protect_published_workflow_template ON workflow_templates;
+CREATE TRIGGER trg_protect_published_workflow_template
+    BEFORE UPDATE OR DELETE ON workflow_templates
+    FOR EACH ROW EXECUTE FUNCTION protect_published_workflow_template();
+
+CREATE OR REPLACE FUNCTION protect_published_workflow_node_template()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND
+        NEW.type IS NOT DISTINCT FROM OLD.type AND
+        NEW.config IS NOT DISTINCT FROM OLD.config AND
+        NEW.depends_on IS NOT DISTINCT FROM OLD.depends_on AND
+        NEW.unlock_configuration IS NOT DISTINCT FROM OLD.unlock_configuration AND
+        NEW.sla IS NOT DISTINCT FROM OLD.sla THEN
+        RETURN NEW;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM workflow_templates
+        WHERE status <> 'DRAFT' AND nodes ? OLD.id::text
+    ) THEN
+        RAISE EXCEPTION 'workflow node template % is used by a published workflow template and cannot be changed', OLD.id;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_protect_published_workflow_node_template ON workflow_node_templates;
+CREATE TRIGGER trg_protect_published_workflow_node_template
+    BEFORE UPDATE OR DELETE ON workflow_node_templates
+    FOR EACH ROW EXECUTE FUNCTION protect_published_workflow_node_template();
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON TABLE workflow_template_families IS 'Groups the versions of a workflow template; HS code mappings point at a family';
+COMMENT ON COLUMN workflow_templates.family_id IS 'Family this workflow template is a version of';
+COMMENT ON COLUMN workflow_templates.version_number IS 'Sequential version number within the family, starting at 1';
+COMMENT ON COLUMN workflow_templates.status IS 'DRAFT versions can be edited; PUBLISHED versions and their node templates are immutable';
+COMMENT ON COLUMN workflow_templates.published_at IS 'When the version was published';
+COMMENT ON COLUMN workflow_template_maps.workflow_template_family_id IS 'Family whose latest published version is used for new consignments';
+COMMENT ON COLUMN consignments.workflow_template_ids IS 'JSONB array of the workflow template versions the consignment''s nodes were built from';
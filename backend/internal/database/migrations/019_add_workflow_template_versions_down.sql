@@ -0,0 +1,57 @@
+-- Rollback: Remove workflow template versions and families
+-- Note: HS code mappings are pointed back at the latest published version of their family.
+--       Draft versions and the consignments' template versions are dropped.
+
+DROP TRIGGER IF EXISTS trg_protect_published_workflow_node_template ON workflow_node_templates;
+DROP FUNCTION IF EXISTS protect_published_workflow_node_template();
+DROP TRIGGER IF EXISTS trg_protect_published_workflow_template ON workflow_templates;
+DROP FUNCTION IF EXISTS protect_published_workflow_template();
+
+DROP INDEX IF EXISTS idx_consignments_workflow_template_ids;
+
+ALTER TABLE consignments
+    DROP COLUMN IF EXISTS workflow_template_ids;
+
+ALTER TABLE workflow_template_maps
+    ADD COLUMN IF NOT EXISTS workflow_template_id UUID;
+
+UPDATE workflow_template_maps m
+SET workflow_template_id = (
+    SELECT wt.id FROM workflow_templates wt
+    WHERE wt.family_id = m.workflow_template_family_id AND wt.status = 'PUBLISHED'
+    ORDER BY wt.version_number DESC
+    LIMIT 1
+);
+
+ALTER TABLE workflow_template_maps
+    ALTER COLUMN workflow_template_id SET NOT NULL;
+
+ALTER TABLE workflow_template_maps
+    ADD CONSTRAINT fk_workflow_template_maps_workflow_template
+        FOREIGN KEY (workflow_template_id) REFERENCES workflow_templates(id)
+        ON DELETE RESTRICT ON UPDATE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_workflow_template_maps_workflow_template_id
+    ON workflow_template_maps(workflow_template_id);
+
+DROP INDEX IF EXISTS idx_workflow_template_maps_workflow_template_family_id;
+
+ALTER TABLE workflow_template_maps
+    DROP CONSTRAINT IF EXISTS fk_workflow_template_maps_workflow_template_family,
+    DROP COLUMN IF EXISTS workflow_template_family_id;
+
+DELETE FROM workflow_templates WHERE status = 'DRAFT';
+
+DROP INDEX IF EXISTS idx_workflow_templates_family_published;
+
+ALTER TABLE workflow_templates
+    DROP CONSTRAINT IF EXISTS uq_workflow_templates_family_version,
+    DROP CONSTRAINT IF EXISTS workflow_templates_version_number_check,
+    DROP CONSTRAINT IF EXISTS workflow_templates_status_check,
+    DROP CONSTRAINT IF EXISTS fk_workflow_templates_family,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS version_number,
+    DROP COLUMN IF EXISTS family_id;
+
+DROP TABLE IF EXISTS workflow_template_families;
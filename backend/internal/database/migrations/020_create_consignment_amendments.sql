@@ -0,0 +1,37 @@
+-- Migration: 020_create_consignment_amendments.sql
+-- Description: Allow traders to add or remove consignment items after creation
+-- Created: 2026-10-17
+-- Notes: An amendment recomputes the workflow templates of the consignment. Nodes that are no
+--        longer required are kept, locked with the AMENDMENT_VOIDED extended state, unless they
+--        were already completed; every amendment is recorded with the nodes it changed.
+
+-- ============================================================================
+-- Table: consignment_amendments
+-- Description: History of the item changes made to consignments
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS consignment_amendments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    consignment_id UUID NOT NULL REFERENCES consignments(id) ON DELETE CASCADE,
+    trader_id VARCHAR(100) NOT NULL,
+    reason TEXT,
+    added_items JSONB NOT NULL DEFAULT '[]'::jsonb,
+    removed_items JSONB NOT NULL DEFAULT '[]'::jsonb,
+    previous_workflow_template_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    workflow_template_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    added_node_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    voided_node_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    relocked_node_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    unlocked_node_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    finished BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_amendments_consignment_id ON consignment_amendments(consignment_id, created_at);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON TABLE consignment_amendments IS 'Items added to or removed from consignments after creation, and how their workflow nodes changed';
+COMMENT ON COLUMN consignment_amendments.voided_node_ids IS 'Unfinished nodes no longer required, locked with the AMENDMENT_VOIDED extended state';
+COMMENT ON COLUMN consignment_amendments.finished IS 'Whether the amendment completed the end node and finished the consignment';
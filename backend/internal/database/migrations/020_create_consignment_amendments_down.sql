@@ -0,0 +1,3 @@
+-- Rollback: Drop consignment_amendments table
+
+DROP TABLE IF EXISTS consignment_amendments CASCADE;
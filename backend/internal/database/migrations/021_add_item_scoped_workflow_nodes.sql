@@ -0,0 +1,29 @@
+-- Migration: 021_add_item_scoped_workflow_nodes.sql
+-- Description: Instantiate item-scoped workflow node templates once per consignment item
+-- Created: 2026-10-17
+-- Notes: Node templates are consignment-wide unless their scope is ITEM, in which case a
+--        consignment gets one node per item whose workflow template lists the node template.
+--        Such nodes record the index of their item. Existing node templates stay consignment-wide.
+
+-- ============================================================================
+-- Table: workflow_node_templates
+-- Description: Add the scope of a node template
+-- ============================================================================
+ALTER TABLE workflow_node_templates
+    ADD COLUMN IF NOT EXISTS scope VARCHAR(20) NOT NULL DEFAULT 'CONSIGNMENT';
+
+ALTER TABLE workflow_node_templates
+    ADD CONSTRAINT workflow_node_templates_scope_check CHECK (scope IN ('CONSIGNMENT', 'ITEM'));
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Add the consignment item of nodes of item-scoped node templates
+-- ============================================================================
+ALTER TABLE workflow_nodes
+    ADD COLUMN IF NOT EXISTS item_index INTEGER CHECK (item_index >= 0);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_node_templates.scope IS 'CONSIGNMENT for one node per consignment, ITEM for one node per consignment item';
+COMMENT ON COLUMN workflow_nodes.item_index IS 'Index in the consignment items of the item the node is for, if its node template is item-scoped';
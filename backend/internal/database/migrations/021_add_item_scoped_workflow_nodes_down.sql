@@ -0,0 +1,9 @@
+-- Rollback: Remove item-scoped workflow nodes
+-- Note: Consignments keep every node created for their items; the nodes are no longer told apart.
+
+ALTER TABLE workflow_nodes
+    DROP COLUMN IF EXISTS item_index;
+
+ALTER TABLE workflow_node_templates
+    DROP CONSTRAINT IF EXISTS workflow_node_templates_scope_check,
+    DROP COLUMN IF EXISTS scope;
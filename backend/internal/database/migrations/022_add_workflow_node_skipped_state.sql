@@ -0,0 +1,22 @@
+-- Migration: 022_add_workflow_node_skipped_state.sql
+-- Description: Add the SKIPPED workflow node state
+-- Created: 2026-10-17
+-- Notes: A LOCKED node is SKIPPED once the nodes it depends on have finished in a way that can never
+--        meet its dependencies, e.g. when the other branch of an exclusive choice was taken. SKIPPED
+--        nodes count as finished for the completion of their workflow.
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Allow the SKIPPED state
+-- ============================================================================
+ALTER TABLE workflow_nodes
+    DROP CONSTRAINT IF EXISTS workflow_nodes_state_check;
+
+ALTER TABLE workflow_nodes
+    ADD CONSTRAINT workflow_nodes_state_check
+        CHECK (state IN ('LOCKED', 'READY', 'IN_PROGRESS', 'COMPLETED', 'FAILED', 'SKIPPED'));
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_nodes.state IS 'Current state: LOCKED, READY, IN_PROGRESS, COMPLETED, FAILED or SKIPPED';
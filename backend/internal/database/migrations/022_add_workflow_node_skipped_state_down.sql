@@ -0,0 +1,10 @@
+-- Rollback: Remove the SKIPPED workflow node state
+-- Note: SKIPPED nodes must be removed or moved to another state first, otherwise restoring the original
+--       check constraint fails.
+
+ALTER TABLE workflow_nodes
+    DROP CONSTRAINT IF EXISTS workflow_nodes_state_check;
+
+ALTER TABLE workflow_nodes
+    ADD CONSTRAINT workflow_nodes_state_check
+        CHECK (state IN ('LOCKED', 'READY', 'IN_PROGRESS', 'COMPLETED', 'FAILED'));
@@ -0,0 +1,31 @@
+-- Migration: 023_add_sub_workflow_nodes.sql
+-- Description: Add the SUB_WORKFLOW task type and the child workflows of workflow nodes
+-- Created: 2026-10-17
+-- Notes: A SUB_WORKFLOW node creates the nodes of a child workflow, from another workflow template,
+--        under the same consignment or pre-consignment once its task starts. The child nodes
+--        reference the SUB_WORKFLOW node, which completes with the child workflow's outcome.
+
+-- ============================================================================
+-- Table: task_infos
+-- Description: Add the SUB_WORKFLOW task type
+-- ============================================================================
+ALTER TABLE task_infos
+    DROP CONSTRAINT IF EXISTS task_infos_type_check;
+
+ALTER TABLE task_infos
+    ADD CONSTRAINT task_infos_type_check
+        CHECK (type IN ('SIMPLE_FORM', 'WAIT_FOR_EVENT', 'TIMER', 'SUB_WORKFLOW'));
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Add the SUB_WORKFLOW node a node of a child workflow belongs to
+-- ============================================================================
+ALTER TABLE workflow_nodes
+    ADD COLUMN IF NOT EXISTS parent_node_id UUID REFERENCES workflow_nodes(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_workflow_nodes_parent_node_id ON workflow_nodes(parent_node_id) WHERE parent_node_id IS NOT NULL;
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_nodes.parent_node_id IS 'SUB_WORKFLOW node whose child workflow the node belongs to; NULL for nodes of the consignment or pre-consignment workflow';
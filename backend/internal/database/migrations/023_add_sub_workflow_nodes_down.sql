@@ -0,0 +1,15 @@
+-- Rollback: Remove the SUB_WORKFLOW task type and child workflows
+-- Note: SUB_WORKFLOW tasks must be removed before the type constraint can be restored. Nodes of child
+--       workflows are kept and can no longer be told apart from the nodes of their consignment.
+
+DROP INDEX IF EXISTS idx_workflow_nodes_parent_node_id;
+
+ALTER TABLE workflow_nodes
+    DROP COLUMN IF EXISTS parent_node_id;
+
+ALTER TABLE task_infos
+    DROP CONSTRAINT IF EXISTS task_infos_type_check;
+
+ALTER TABLE task_infos
+    ADD CONSTRAINT task_infos_type_check
+        CHECK (type IN ('SIMPLE_FORM', 'WAIT_FOR_EVENT', 'TIMER'));
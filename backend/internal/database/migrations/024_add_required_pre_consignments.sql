@@ -0,0 +1,20 @@
+-- Migration: 024_add_required_pre_consignments.sql
+-- Description: Let workflow templates require completed pre-consignments
+-- Created: 2026-10-17
+-- Notes: A consignment can only be created once the trader has completed the pre-consignments its
+--        workflow templates require. The trader context of each of them is seeded into the
+--        consignment's global context under the context key of its requirement.
+
+-- ============================================================================
+-- Table: workflow_templates
+-- Description: Add the pre-consignments a trader must complete before creating a consignment
+-- ============================================================================
+ALTER TABLE workflow_templates
+    ADD COLUMN IF NOT EXISTS required_pre_consignments JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_workflow_templates_required_pre_consignments ON workflow_templates USING GIN (required_pre_consignments);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_templates.required_pre_consignments IS 'Array of {preConsignmentTemplateId, contextKey}: pre-consignments the trader must have completed, and the global context key their trader context is seeded under';
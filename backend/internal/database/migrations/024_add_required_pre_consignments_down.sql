@@ -0,0 +1,6 @@
+-- Rollback: Remove the pre-consignments required by workflow templates
+
+DROP INDEX IF EXISTS idx_workflow_templates_required_pre_consignments;
+
+ALTER TABLE workflow_templates
+    DROP COLUMN IF EXISTS required_pre_consignments;
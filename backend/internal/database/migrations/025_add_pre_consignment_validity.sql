@@ -0,0 +1,53 @@
+-- Migration: 025_add_pre_consignment_validity.sql
+-- Description: Add expiry, renewal and revocation of pre-consignments
+-- Created: 2026-10-17
+-- Notes: A pre-consignment template can give completed pre-consignments a validity, either a fixed
+--        duration from completion or an expiry date taken from the trader context. Expired and
+--        revoked pre-consignments no longer satisfy the pre-consignments and consignments that
+--        depend on them until the trader renews them.
+
+-- ============================================================================
+-- Table: pre_consignment_templates
+-- Description: Add the validity of completed pre-consignments
+-- ============================================================================
+ALTER TABLE pre_consignment_templates
+    ADD COLUMN IF NOT EXISTS validity JSONB;
+
+-- ============================================================================
+-- Table: pre_consignments
+-- Description: Add the expiry, revocation and renewal of pre-consignments
+-- ============================================================================
+ALTER TABLE pre_consignments
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS revocation_reason TEXT,
+    ADD COLUMN IF NOT EXISTS renewed_from_id UUID;
+
+ALTER TABLE pre_consignments
+    DROP CONSTRAINT IF EXISTS fk_pre_consignments_renewed_from;
+
+ALTER TABLE pre_consignments
+    ADD CONSTRAINT fk_pre_consignments_renewed_from
+        FOREIGN KEY (renewed_from_id) REFERENCES pre_consignments(id)
+        ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE pre_consignments
+    DROP CONSTRAINT IF EXISTS pre_consignments_state_check;
+
+ALTER TABLE pre_consignments
+    ADD CONSTRAINT pre_consignments_state_check
+        CHECK (state IN ('LOCKED', 'READY', 'IN_PROGRESS', 'COMPLETED', 'EXPIRED', 'REVOKED'));
+
+-- Completed pre-consignments are scanned by expiry by the expiry monitor
+CREATE INDEX IF NOT EXISTS idx_pre_consignments_expires_at ON pre_consignments(expires_at)
+    WHERE state = 'COMPLETED' AND expires_at IS NOT NULL;
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN pre_consignment_templates.validity IS 'Validity of completed pre-consignments: {duration, expiresAtContextKey}; NULL when they never expire';
+COMMENT ON COLUMN pre_consignments.state IS 'Current state: LOCKED, READY, IN_PROGRESS, COMPLETED, EXPIRED or REVOKED';
+COMMENT ON COLUMN pre_consignments.expires_at IS 'When the completed pre-consignment stops being valid; NULL when it never expires';
+COMMENT ON COLUMN pre_consignments.revoked_at IS 'When an agency revoked the pre-consignment';
+COMMENT ON COLUMN pre_consignments.revocation_reason IS 'Why the pre-consignment was revoked';
+COMMENT ON COLUMN pre_consignments.renewed_from_id IS 'The pre-consignment this pre-consignment renews';
@@ -0,0 +1,24 @@
+-- Rollback: Remove expiry, renewal and revocation of pre-consignments
+-- Note: EXPIRED and REVOKED pre-consignments must be removed or moved to another state first, otherwise
+--       restoring the original check constraint fails.
+
+DROP INDEX IF EXISTS idx_pre_consignments_expires_at;
+
+ALTER TABLE pre_consignments
+    DROP CONSTRAINT IF EXISTS pre_consignments_state_check;
+
+ALTER TABLE pre_consignments
+    ADD CONSTRAINT pre_consignments_state_check
+        CHECK (state IN ('LOCKED', 'READY', 'IN_PROGRESS', 'COMPLETED'));
+
+ALTER TABLE pre_consignments
+    DROP CONSTRAINT IF EXISTS fk_pre_consignments_renewed_from;
+
+ALTER TABLE pre_consignments
+    DROP COLUMN IF EXISTS renewed_from_id,
+    DROP COLUMN IF EXISTS revocation_reason,
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE pre_consignment_templates
+    DROP COLUMN IF EXISTS validity;
@@ -0,0 +1,79 @@
+-- Migration: 026_create_workflow_events.sql
+-- Description: Record an append-only event timeline of consignment and pre-consignment workflows
+-- Created: 2026-10-17
+-- Notes: Every workflow node creation, state transition, outcome change, plugin FSM transition
+--        and global context append is recorded with the actor that triggered it (a trader, an
+--        agency officer, an administrator or the system). Events are never updated or deleted,
+--        and have no foreign keys so that the history outlives the records it describes.
+--        Task notifications carry their actor and FSM transitions through the task outbox;
+--        failed workflow updates keep their actor so that a replay is attributed correctly.
+
+-- ============================================================================
+-- Table: workflow_events
+-- Description: Append-only history of changes to consignment and pre-consignment workflows
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS workflow_events (
+    sequence BIGSERIAL PRIMARY KEY,
+    consignment_id UUID,
+    pre_consignment_id UUID,
+    workflow_node_id UUID,
+    type VARCHAR(50) NOT NULL,
+    from_state VARCHAR(100),
+    to_state VARCHAR(100),
+    outcome VARCHAR(100),
+    action VARCHAR(100),
+    data JSONB,
+    actor_type VARCHAR(20) NOT NULL DEFAULT 'SYSTEM',
+    actor_id VARCHAR(100),
+    task_sequence BIGINT,
+    ordinal INTEGER,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT workflow_events_type_check
+        CHECK (type IN ('NODE_CREATED', 'NODE_STATE_CHANGED', 'NODE_OUTCOME_SET', 'TASK_TRANSITION', 'CONTEXT_APPENDED')),
+    CONSTRAINT workflow_events_actor_type_check
+        CHECK (actor_type IN ('TRADER', 'AGENCY', 'ADMIN', 'SYSTEM'))
+);
+
+-- Timelines are listed per consignment or pre-consignment in occurrence order
+CREATE INDEX IF NOT EXISTS idx_workflow_events_consignment_id ON workflow_events(consignment_id, occurred_at)
+    WHERE consignment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_workflow_events_pre_consignment_id ON workflow_events(pre_consignment_id, occurred_at)
+    WHERE pre_consignment_id IS NOT NULL;
+
+-- Transitions of redelivered task notifications conflict on their outbox sequence and ordinal, and are skipped
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_events_task_sequence ON workflow_events(workflow_node_id, task_sequence, ordinal)
+    WHERE task_sequence IS NOT NULL;
+
+-- ============================================================================
+-- Table: task_outbox
+-- Description: Add the actor and plugin FSM transitions of task notifications
+-- ============================================================================
+ALTER TABLE task_outbox
+    ADD COLUMN IF NOT EXISTS actor_type VARCHAR(20) NOT NULL DEFAULT 'SYSTEM',
+    ADD COLUMN IF NOT EXISTS actor_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS transitions JSONB;
+
+-- ============================================================================
+-- Table: failed_workflow_updates
+-- Description: Add the actor of failed workflow updates
+-- ============================================================================
+ALTER TABLE failed_workflow_updates
+    ADD COLUMN IF NOT EXISTS actor_type VARCHAR(20) NOT NULL DEFAULT 'SYSTEM',
+    ADD COLUMN IF NOT EXISTS actor_id VARCHAR(100);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON TABLE workflow_events IS 'Append-only timeline of workflow changes and who triggered them';
+COMMENT ON COLUMN workflow_events.sequence IS 'Monotonic sequence, ordering events that occurred at the same time';
+COMMENT ON COLUMN workflow_events.type IS 'NODE_CREATED, NODE_STATE_CHANGED, NODE_OUTCOME_SET, TASK_TRANSITION or CONTEXT_APPENDED';
+COMMENT ON COLUMN workflow_events.from_state IS 'Node state, or plugin state for TASK_TRANSITION, before the change';
+COMMENT ON COLUMN workflow_events.to_state IS 'Node state, or plugin state for TASK_TRANSITION, after the change';
+COMMENT ON COLUMN workflow_events.action IS 'Plugin FSM action applied by a TASK_TRANSITION';
+COMMENT ON COLUMN workflow_events.data IS 'Event details, e.g. the context appended by CONTEXT_APPENDED';
+COMMENT ON COLUMN workflow_events.actor_type IS 'Who triggered the change: TRADER, AGENCY, ADMIN or SYSTEM';
+COMMENT ON COLUMN workflow_events.task_sequence IS 'Task outbox sequence a TASK_TRANSITION was recorded from';
+COMMENT ON COLUMN workflow_events.ordinal IS 'Position of a TASK_TRANSITION among the transitions of its task notification';
+COMMENT ON COLUMN task_outbox.transitions IS 'Plugin FSM transitions applied by the task update';
+COMMENT ON COLUMN failed_workflow_updates.actor_type IS 'Who triggered the failed update, kept for when it is replayed';
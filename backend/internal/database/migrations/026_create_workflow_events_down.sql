@@ -0,0 +1,12 @@
+-- Rollback: Drop workflow_events table and the actor columns of task notifications and failed updates
+
+ALTER TABLE failed_workflow_updates
+    DROP COLUMN IF EXISTS actor_id,
+    DROP COLUMN IF EXISTS actor_type;
+
+ALTER TABLE task_outbox
+    DROP COLUMN IF EXISTS transitions,
+    DROP COLUMN IF EXISTS actor_id,
+    DROP COLUMN IF EXISTS actor_type;
+
+DROP TABLE IF EXISTS workflow_events CASCADE;
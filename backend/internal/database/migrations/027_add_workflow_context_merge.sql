@@ -0,0 +1,50 @@
+-- Migration: 027_add_workflow_context_merge.sql
+-- Description: Merge workflow context appends per key, with conflict rules and per-key provenance
+-- Created: 2026-10-17
+-- Notes: Nodes appending to the global context of a consignment or the trader context of a
+--        pre-consignment lock the row and merge only the keys they change, so parallel nodes no
+--        longer overwrite each other's keys. A node template can reject changes to keys another
+--        node wrote, or write its keys under a namespace. Every changed key is recorded with the
+--        node that wrote it and its previous value.
+
+-- ============================================================================
+-- Table: workflow_node_templates
+-- Description: Add how nodes merge the context they append (NULL means last writer wins)
+-- ============================================================================
+ALTER TABLE workflow_node_templates
+    ADD COLUMN IF NOT EXISTS context_merge JSONB;
+
+-- ============================================================================
+-- Table: workflow_context_changes
+-- Description: Append-only provenance of the context keys written by workflow nodes
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS workflow_context_changes (
+    sequence BIGSERIAL PRIMARY KEY,
+    consignment_id UUID,
+    pre_consignment_id UUID,
+    workflow_node_id UUID NOT NULL,
+    workflow_node_template_id UUID NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    previous_value JSONB,
+    value JSONB,
+    actor_type VARCHAR(20) NOT NULL DEFAULT 'SYSTEM',
+    actor_id VARCHAR(100),
+    changed_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT workflow_context_changes_owner_check
+        CHECK (consignment_id IS NOT NULL OR pre_consignment_id IS NOT NULL)
+);
+
+-- The last writer of a key is looked up per consignment or pre-consignment
+CREATE INDEX IF NOT EXISTS idx_workflow_context_changes_consignment_id ON workflow_context_changes(consignment_id, key, sequence)
+    WHERE consignment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_workflow_context_changes_pre_consignment_id ON workflow_context_changes(pre_consignment_id, key, sequence)
+    WHERE pre_consignment_id IS NOT NULL;
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_node_templates.context_merge IS 'How nodes merge appended context: {conflictPolicy: LAST_WRITER_WINS, REJECT or NAMESPACED, namespace}; NULL means LAST_WRITER_WINS';
+COMMENT ON TABLE workflow_context_changes IS 'Append-only provenance of the context keys written by workflow nodes';
+COMMENT ON COLUMN workflow_context_changes.workflow_node_id IS 'Node that wrote the key; its task has the same ID';
+COMMENT ON COLUMN workflow_context_changes.key IS 'Dot-separated path of the key, including the namespace of NAMESPACED nodes';
+COMMENT ON COLUMN workflow_context_changes.previous_value IS 'Value before the change; NULL if the key was not set';
@@ -0,0 +1,6 @@
+-- Rollback: Drop workflow_context_changes table and the context merge configuration of node templates
+
+DROP TABLE IF EXISTS workflow_context_changes CASCADE;
+
+ALTER TABLE workflow_node_templates
+    DROP COLUMN IF EXISTS context_merge;
@@ -0,0 +1,27 @@
+-- Migration: 028_add_global_context_mode.sql
+-- Description: Let tasks read the current global context of their workflow instead of a snapshot
+-- Created: 2026-10-17
+-- Notes: A task reads the global context snapshot taken when it was initialized unless its node
+--        template sets global_context_mode to LIVE, in which case it reads the current global context
+--        of the consignment or trader context of the pre-consignment, cached for a few seconds. The
+--        mode is copied onto the task, so changing the template does not affect running tasks.
+
+-- ============================================================================
+-- Table: workflow_node_templates
+-- Description: Add which global context tasks of the template read (NULL means SNAPSHOT)
+-- ============================================================================
+ALTER TABLE workflow_node_templates
+    ADD COLUMN IF NOT EXISTS global_context_mode VARCHAR(20);
+
+-- ============================================================================
+-- Table: task_infos
+-- Description: Add which global context the task reads
+-- ============================================================================
+ALTER TABLE task_infos
+    ADD COLUMN IF NOT EXISTS global_context_mode VARCHAR(20) NOT NULL DEFAULT 'SNAPSHOT';
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON COLUMN workflow_node_templates.global_context_mode IS 'Global context read by tasks of the template: SNAPSHOT or LIVE; NULL means SNAPSHOT';
+COMMENT ON COLUMN task_infos.global_context_mode IS 'SNAPSHOT reads global_context; LIVE reads the current context of the workflow';
@@ -0,0 +1,7 @@
+-- Rollback: Drop the global context mode of tasks and node templates
+
+ALTER TABLE task_infos
+    DROP COLUMN IF EXISTS global_context_mode;
+
+ALTER TABLE workflow_node_templates
+    DROP COLUMN IF EXISTS global_context_mode;
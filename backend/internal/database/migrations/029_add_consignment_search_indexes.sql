@@ -0,0 +1,36 @@
+-- Migration: 029_add_consignment_search_indexes.sql
+-- Description: Index consignments for the cross-trader search of agency officers and administrators
+-- Created: 2026-10-17
+-- Notes: The search matches global context values and HS codes of items by JSONB containment (@>),
+--        which jsonb_path_ops GIN indexes serve with smaller indexes than the default operator class.
+--        HS codes are matched by prefix, and workflow nodes by node template and state.
+
+-- ============================================================================
+-- Table: consignments
+-- Description: Rebuild the JSONB GIN indexes with jsonb_path_ops and index the sortable timestamps
+-- ============================================================================
+DROP INDEX IF EXISTS idx_consignments_global_context;
+CREATE INDEX IF NOT EXISTS idx_consignments_global_context ON consignments USING GIN (global_context jsonb_path_ops);
+
+DROP INDEX IF EXISTS idx_consignments_items;
+CREATE INDEX IF NOT EXISTS idx_consignments_items ON consignments USING GIN (items jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_consignments_updated_at ON consignments(updated_at DESC);
+
+-- ============================================================================
+-- Table: hs_codes
+-- Description: Serve HS code prefix matches (LIKE '0902%') regardless of the database collation
+-- ============================================================================
+CREATE INDEX IF NOT EXISTS idx_hs_codes_hs_code_prefix ON hs_codes(hs_code varchar_pattern_ops);
+
+-- ============================================================================
+-- Table: workflow_nodes
+-- Description: Find consignments with a node of a template in a given state
+-- ============================================================================
+CREATE INDEX IF NOT EXISTS idx_workflow_nodes_template_state ON workflow_nodes(workflow_node_template_id, state, consignment_id);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON INDEX idx_consignments_global_context IS 'Serves global context containment (@>) in consignment search';
+COMMENT ON INDEX idx_consignments_items IS 'Serves HS code containment (@>) of items in consignment search';
@@ -0,0 +1,11 @@
+-- Rollback: Drop the consignment search indexes and restore the default JSONB GIN indexes
+
+DROP INDEX IF EXISTS idx_workflow_nodes_template_state;
+DROP INDEX IF EXISTS idx_hs_codes_hs_code_prefix;
+DROP INDEX IF EXISTS idx_consignments_updated_at;
+
+DROP INDEX IF EXISTS idx_consignments_items;
+CREATE INDEX IF NOT EXISTS idx_consignments_items ON consignments USING GIN (items);
+
+DROP INDEX IF EXISTS idx_consignments_global_context;
+CREATE INDEX IF NOT EXISTS idx_consignments_global_context ON consignments USING GIN (global_context);
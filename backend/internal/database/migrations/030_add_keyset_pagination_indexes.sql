@@ -0,0 +1,26 @@
+-- Migration: 030_add_keyset_pagination_indexes.sql
+-- Description: Index the sort keys of keyset (cursor) pagination
+-- Created: 2026-10-17
+-- Notes: Cursor pages select the rows after the last item of the previous page with a row comparison on the
+--        sort key and the ID, e.g. (created_at, id) < ($1, $2), which these indexes serve without scanning the
+--        skipped rows. HS codes are unique, so the existing index on hs_code serves their cursors.
+
+-- ============================================================================
+-- Table: consignments
+-- Description: Page the consignments of a trader, and all consignments, from the newest
+-- ============================================================================
+CREATE INDEX IF NOT EXISTS idx_consignments_trader_id_created_at_id ON consignments(trader_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_consignments_created_at_id ON consignments(created_at DESC, id DESC);
+
+-- ============================================================================
+-- Table: pre_consignment_templates
+-- Description: Page the pre-consignment templates by name
+-- ============================================================================
+CREATE INDEX IF NOT EXISTS idx_pre_consignment_templates_name_id ON pre_consignment_templates(name, id);
+
+-- ============================================================================
+-- Comments for documentation
+-- ============================================================================
+COMMENT ON INDEX idx_consignments_trader_id_created_at_id IS 'Serves keyset pagination of the consignments of a trader';
+COMMENT ON INDEX idx_consignments_created_at_id IS 'Serves keyset pagination of consignment search ordered by creation time';
+COMMENT ON INDEX idx_pre_consignment_templates_name_id IS 'Serves keyset pagination of pre-consignment templates';
@@ -0,0 +1,5 @@
+-- Rollback: Drop the keyset pagination indexes
+
+DROP INDEX IF EXISTS idx_pre_consignment_templates_name_id;
+DROP INDEX IF EXISTS idx_consignments_created_at_id;
+DROP INDEX IF EXISTS idx_consignments_trader_id_created_at_id;
@@ -47,6 +47,23 @@ MIGRATIONS=(
     "011_insert_unlock_config_seed.sql"
     "012_add_conditional_state_identification.sql"
     "013_add_oga_review_view_form.sql"
+    "014_create_task_outbox.sql"
+    "015_create_failed_workflow_updates.sql"
+    "016_add_consignment_cancellation.sql"
+    "017_add_workflow_node_reopen.sql"
+    "018_add_timers_and_sla.sql"
+    "019_add_workflow_template_versions.sql"
+    "020_create_consignment_amendments.sql"
+    "021_add_item_scoped_workflow_nodes.sql"
+    "022_add_workflow_node_skipped_state.sql"
+    "023_add_sub_workflow_nodes.sql"
+    "024_add_required_pre_consignments.sql"
+    "025_add_pre_consignment_validity.sql"
+    "026_create_workflow_events.sql"
+    "027_add_workflow_context_merge.sql"
+    "028_add_global_context_mode.sql"
+    "029_add_consignment_search_indexes.sql"
+    "030_add_keyset_pagination_indexes.sql"
 )
 
 echo "Starting database migrations..."
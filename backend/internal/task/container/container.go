@@ -2,14 +2,30 @@ package container
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/task/persistence"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
 )
 
+// ErrTaskCancelled is returned when an action is attempted on a cancelled task.
+var ErrTaskCancelled = errors.New("task has been cancelled")
+
+// LiveGlobalContextTTL is how long a container reuses the global context it read from its GlobalContextSource.
+const LiveGlobalContextTTL = 5 * time.Second
+
+// GlobalContextSource returns the current global context of the workflow a task belongs to, as the task
+// would be initialized with it now.
+type GlobalContextSource func(ctx context.Context, taskID uuid.UUID) (map[string]any, error)
+
 type Container struct {
 	TaskID                 uuid.UUID
 	WorkflowID             uuid.UUID
@@ -21,7 +37,12 @@ type Container struct {
 	taskStore              persistence.TaskStoreInterface
 	pluginState            string // Cache for plugin-level business state
 	fsm                    *plugin.PluginFSM
+	transitions            []plugin.TransitionRecord // FSM transitions applied since the last commit
 	mu                     sync.RWMutex
+	globalContextSource    GlobalContextSource // Source of live global context reads; nil for SNAPSHOT tasks
+	liveGlobalState        map[string]any      // Global context last read from globalContextSource
+	liveGlobalStateReadAt  time.Time           // When liveGlobalState was read
+	liveMu                 sync.Mutex          // Protects the live global context cache
 }
 
 func (c *Container) GetTaskState() plugin.State {
@@ -42,48 +63,40 @@ func (c *Container) CanTransition(action string) bool {
 	return c.fsm.CanTransition(c.GetPluginState(), action)
 }
 
-// Transition applies the FSM transition for action, updating in-memory state and
-// persisting both plugin state and task state to the store.
+// Transition applies the FSM transition for action, updating the in-memory plugin state
+// and task state. The change is persisted, together with its Workflow Manager notification
+// that records the transition, when the surrounding Start or Execute call returns.
 func (c *Container) Transition(action string) error {
 	if c.fsm == nil {
 		return nil
 	}
-	outcome, err := c.fsm.Transition(c.GetPluginState(), action)
+	fromState := c.GetPluginState()
+	outcome, err := c.fsm.Transition(fromState, action)
 	if err != nil {
 		return err
 	}
 	c.mu.Lock()
+	c.transitions = append(c.transitions, plugin.TransitionRecord{
+		Action:    action,
+		FromState: fromState,
+		ToState:   outcome.NextPluginState,
+		TaskState: outcome.NextTaskState,
+	})
 	c.pluginState = outcome.NextPluginState
 	if outcome.NextTaskState != "" {
 		c.State = outcome.NextTaskState
 	}
 	c.mu.Unlock()
-	if err := c.taskStore.UpdatePluginState(c.TaskID, outcome.NextPluginState); err != nil {
-		return err
-	}
-	if outcome.NextTaskState != "" {
-		return c.taskStore.UpdateStatus(c.TaskID, &outcome.NextTaskState)
-	}
 	return nil
 }
 
 func (c *Container) Start(ctx context.Context) (*plugin.ExecutionResponse, error) {
-	prev := c.GetPluginState()
-	resp, err := c.Executable.Start(ctx)
-	if err != nil {
-		return resp, err
-	}
-	if resp == nil {
-		resp = &plugin.ExecutionResponse{}
+	if c.GetTaskState() == plugin.Cancelled {
+		return nil, ErrTaskCancelled
 	}
-	c.mu.RLock()
-	state, pluginState := c.State, c.pluginState
-	c.mu.RUnlock()
-	if pluginState != prev {
-		resp.NewState = &state
-		resp.ExtendedState = &pluginState
-	}
-	return resp, nil
+	prevState, prevPluginState := c.snapshot()
+	resp, err := c.Executable.Start(ctx)
+	return c.commit(ctx, prevState, prevPluginState, resp, err)
 }
 
 func (c *Container) GetRenderInfo(ctx context.Context) (*plugin.ApiResponse, error) {
@@ -91,22 +104,99 @@ func (c *Container) GetRenderInfo(ctx context.Context) (*plugin.ApiResponse, err
 }
 
 func (c *Container) Execute(ctx context.Context, request *plugin.ExecutionRequest) (*plugin.ExecutionResponse, error) {
-	prev := c.GetPluginState()
+	if c.GetTaskState() == plugin.Cancelled {
+		return nil, ErrTaskCancelled
+	}
+	prevState, prevPluginState := c.snapshot()
 	resp, err := c.Executable.Execute(ctx, request)
-	if err != nil {
-		return resp, err
+	return c.commit(ctx, prevState, prevPluginState, resp, err)
+}
+
+// Cancel moves an unfinished task to CANCELLED so that further actions are rejected, then lets
+// the plugin notify any external system it handed work over to. The cancellation is persisted
+// without a Workflow Manager notification, since the Workflow Manager initiates it. A failure to
+// notify the external system is logged but does not undo the cancellation.
+func (c *Container) Cancel(ctx context.Context, reason string) error {
+	state, pluginState := c.snapshot()
+	if state == plugin.Cancelled || state == plugin.Completed || state == plugin.Failed {
+		return nil
+	}
+
+	if c.taskStore != nil {
+		if err := c.taskStore.CommitStateChange(c.TaskID, pluginState, plugin.Cancelled, nil); err != nil {
+			return fmt.Errorf("failed to persist task cancellation: %w", err)
+		}
 	}
-	if resp == nil {
-		resp = &plugin.ExecutionResponse{}
+	c.mu.Lock()
+	c.State = plugin.Cancelled
+	c.mu.Unlock()
+
+	if canceller, ok := c.Executable.(plugin.Canceller); ok {
+		if err := canceller.Cancel(ctx, reason); err != nil {
+			slog.WarnContext(ctx, "failed to notify external system of task cancellation",
+				"taskID", c.TaskID,
+				"error", err)
+		}
 	}
+	return nil
+}
+
+// snapshot returns the current task state and plugin state.
+func (c *Container) snapshot() (plugin.State, string) {
 	c.mu.RLock()
-	state, pluginState := c.State, c.pluginState
-	c.mu.RUnlock()
-	if pluginState != prev {
+	defer c.mu.RUnlock()
+	return c.State, c.pluginState
+}
+
+// commit persists any state change made during a plugin call together with its outbox
+// notification, which records the FSM transitions applied and who triggered the call. A plugin
+// state change is persisted even when the plugin returned an error (e.g. SUBMISSION_FAILED), so
+// the stored state always matches the FSM. If persistence fails, the in-memory state is rolled
+// back to the values captured before the call.
+func (c *Container) commit(ctx context.Context, prevState plugin.State, prevPluginState string, resp *plugin.ExecutionResponse, execErr error) (*plugin.ExecutionResponse, error) {
+	c.mu.Lock()
+	transitions := c.transitions
+	c.transitions = nil
+	c.mu.Unlock()
+
+	state, pluginState := c.snapshot()
+	changed := pluginState != prevPluginState
+	if changed {
+		if resp == nil {
+			resp = &plugin.ExecutionResponse{}
+		}
 		resp.NewState = &state
 		resp.ExtendedState = &pluginState
+	} else if execErr != nil || resp == nil || resp.NewState == nil {
+		return resp, execErr
+	}
+
+	// A plugin may report a task state directly without going through the FSM.
+	state = *resp.NewState
+	c.mu.Lock()
+	c.State = state
+	c.mu.Unlock()
+
+	if c.taskStore != nil {
+		notification := &persistence.OutboxEntry{
+			TaskID:              c.TaskID,
+			WorkflowID:          c.WorkflowID,
+			State:               state,
+			ExtendedState:       resp.ExtendedState,
+			Outcome:             resp.Outcome,
+			AppendGlobalContext: resp.AppendGlobalContext,
+			Actor:               auth.ActorFromContext(ctx),
+			Transitions:         transitions,
+		}
+		if err := c.taskStore.CommitStateChange(c.TaskID, pluginState, state, notification); err != nil {
+			c.mu.Lock()
+			c.State, c.pluginState = prevState, prevPluginState
+			c.mu.Unlock()
+			return nil, fmt.Errorf("failed to persist task state change: %w", err)
+		}
 	}
-	return resp, nil
+
+	return resp, execErr
 }
 
 func (c *Container) GetTaskID() uuid.UUID {
@@ -136,6 +226,50 @@ func (c *Container) ReadFromGlobalStore(key string) (any, bool) {
 	return c.globalState[key], true
 }
 
+// ReadGlobalContext returns the global context snapshot of the task, or, if the task reads its global context
+// live, the current context of its workflow. Live reads are cached for LiveGlobalContextTTL.
+func (c *Container) ReadGlobalContext(ctx context.Context) (map[string]any, error) {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	if c.globalContextSource == nil {
+		return maps.Clone(c.globalState), nil
+	}
+	if c.liveGlobalState != nil && time.Since(c.liveGlobalStateReadAt) < LiveGlobalContextTTL {
+		return maps.Clone(c.liveGlobalState), nil
+	}
+	globalContext, err := c.globalContextSource(ctx, c.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global context of task %s: %w", c.TaskID, err)
+	}
+	if globalContext == nil {
+		globalContext = map[string]any{}
+	}
+	c.liveGlobalState = globalContext
+	c.liveGlobalStateReadAt = time.Now()
+	return maps.Clone(globalContext), nil
+}
+
+// UseLiveGlobalContext makes ReadGlobalContext read the current global context of the task's workflow from
+// source instead of the snapshot the container was created with.
+func (c *Container) UseLiveGlobalContext(source GlobalContextSource) {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.globalContextSource = source
+	c.liveGlobalState = nil
+}
+
+// ScheduleAction persists a timer that executes action on the task at the given time.
+func (c *Container) ScheduleAction(action string, at time.Time) error {
+	if c.taskStore == nil {
+		return fmt.Errorf("cannot schedule action %q: task store not configured", action)
+	}
+	return c.taskStore.ScheduleTimer(&persistence.TaskTimer{
+		TaskID: c.TaskID,
+		Action: action,
+		FireAt: at.UTC(),
+	})
+}
+
 func (c *Container) GetPluginState() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+)
+
+// dispatchBatchSize is the maximum number of outbox entries processed per dispatch cycle.
+const dispatchBatchSize = 100
+
+// NotificationHandler processes a single task state notification.
+// Returning an error leaves the notification in the outbox so it is redelivered later.
+type NotificationHandler func(ctx context.Context, notification WorkflowManagerNotification) error
+
+// OutboxDispatcher delivers persisted task state notifications to the Workflow Manager.
+// Delivery is at-least-once: an entry is only marked delivered after the handler succeeds.
+// Entries are delivered in sequence order per workflow; when an entry fails, later entries
+// of the same workflow are held back until it has been delivered. Dispatchers running side by
+// side, e.g. in several replicas, claim different workflows, so each entry is delivered by one.
+type OutboxDispatcher struct {
+	store    persistence.OutboxStoreInterface
+	handler  NotificationHandler
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewOutboxDispatcher creates a new OutboxDispatcher that polls the outbox every interval.
+func NewOutboxDispatcher(db *gorm.DB, handler NotificationHandler, interval time.Duration) (*OutboxDispatcher, error) {
+	store, err := persistence.NewOutboxStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outbox store: %w", err)
+	}
+	return newOutboxDispatcher(store, handler, interval), nil
+}
+
+func newOutboxDispatcher(store persistence.OutboxStoreInterface, handler NotificationHandler, interval time.Duration) *OutboxDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OutboxDispatcher{
+		store:    store,
+		handler:  handler,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the dispatch loop in a background goroutine.
+func (d *OutboxDispatcher) Start() {
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			d.dispatchPending(d.ctx)
+			select {
+			case <-d.ctx.Done():
+				slog.Info("outbox dispatcher stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the dispatch loop and waits for the in-flight cycle to finish.
+func (d *OutboxDispatcher) Stop() {
+	d.cancel()
+	<-d.done
+}
+
+// dispatchPending claims and delivers one batch of pending outbox entries.
+// Returns the number of entries delivered successfully.
+func (d *OutboxDispatcher) dispatchPending(ctx context.Context) int {
+	delivered := 0
+	err := d.store.ClaimPending(dispatchBatchSize, func(entries []persistence.OutboxEntry, claim persistence.OutboxClaim) error {
+		blockedWorkflows := make(map[uuid.UUID]struct{})
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			// Preserve per-workflow ordering: never deliver past an undelivered entry.
+			if _, blocked := blockedWorkflows[entry.WorkflowID]; blocked {
+				continue
+			}
+
+			if err := d.handler(ctx, notificationFromOutboxEntry(entry)); err != nil {
+				blockedWorkflows[entry.WorkflowID] = struct{}{}
+				slog.WarnContext(ctx, "failed to deliver outbox entry, will retry",
+					"sequence", entry.Sequence,
+					"taskID", entry.TaskID,
+					"workflowID", entry.WorkflowID,
+					"attempts", entry.Attempts+1,
+					"error", err)
+				if recordErr := claim.RecordFailure(entry.Sequence, err.Error()); recordErr != nil {
+					return fmt.Errorf("failed to record delivery failure of outbox entry %d: %w", entry.Sequence, recordErr)
+				}
+				continue
+			}
+
+			if err := claim.MarkDelivered(entry.Sequence); err != nil {
+				return fmt.Errorf("failed to mark outbox entry %d as delivered: %w", entry.Sequence, err)
+			}
+			delivered++
+		}
+		return nil
+	})
+	if err != nil {
+		// The claim is rolled back and its entries are redelivered; handlers must tolerate duplicates.
+		slog.ErrorContext(ctx, "failed to dispatch pending outbox entries", "error", err)
+		return 0
+	}
+
+	return delivered
+}
+
+func notificationFromOutboxEntry(entry persistence.OutboxEntry) WorkflowManagerNotification {
+	state := entry.State
+	return WorkflowManagerNotification{
+		TaskID:              entry.TaskID,
+		UpdatedState:        &state,
+		AppendGlobalContext: entry.AppendGlobalContext,
+		ExtendedState:       entry.ExtendedState,
+		Outcome:             entry.Outcome,
+		Sequence:            entry.Sequence,
+		OccurredAt:          entry.CreatedAt,
+		Actor:               entry.Actor,
+		Transitions:         entry.Transitions,
+	}
+}
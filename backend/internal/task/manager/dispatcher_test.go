@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// MockOutboxStore
+type MockOutboxStore struct {
+	mock.Mock
+}
+
+func (m *MockOutboxStore) ClaimPending(limit int, process func([]persistence.OutboxEntry, persistence.OutboxClaim) error) error {
+	args := m.Called(limit)
+	if args.Error(1) != nil {
+		return args.Error(1)
+	}
+	entries, _ := args.Get(0).([]persistence.OutboxEntry)
+	return process(entries, m)
+}
+
+func (m *MockOutboxStore) MarkDelivered(sequence int64) error {
+	args := m.Called(sequence)
+	return args.Error(0)
+}
+
+func (m *MockOutboxStore) RecordFailure(sequence int64, reason string) error {
+	args := m.Called(sequence, reason)
+	return args.Error(0)
+}
+
+func (m *MockOutboxStore) Resend(taskID, workflowID uuid.UUID, state plugin.State) (bool, error) {
+	args := m.Called(taskID, workflowID, state)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestOutboxDispatcher_DispatchPending(t *testing.T) {
+	t.Run("Delivers In Order", func(t *testing.T) {
+		store := new(MockOutboxStore)
+		workflowID := uuid.New()
+		taskID := uuid.New()
+		extended := "SUBMITTED"
+		entries := []persistence.OutboxEntry{
+			{Sequence: 1, TaskID: taskID, WorkflowID: workflowID, State: plugin.InProgress},
+			{
+				Sequence: 2, TaskID: taskID, WorkflowID: workflowID, State: plugin.Completed, ExtendedState: &extended,
+				Actor:       auth.Actor{Type: auth.ActorTypeTrader, ID: "trader1"},
+				Transitions: []plugin.TransitionRecord{{Action: "SUBMIT", FromState: "DRAFT", ToState: "SUBMITTED", TaskState: plugin.Completed}},
+			},
+		}
+		store.On("ClaimPending", dispatchBatchSize).Return(entries, nil).Once()
+		store.On("MarkDelivered", int64(1)).Return(nil).Once()
+		store.On("MarkDelivered", int64(2)).Return(nil).Once()
+
+		var received []WorkflowManagerNotification
+		handler := func(_ context.Context, n WorkflowManagerNotification) error {
+			received = append(received, n)
+			return nil
+		}
+
+		d := newOutboxDispatcher(store, handler, time.Second)
+		delivered := d.dispatchPending(context.Background())
+
+		assert.Equal(t, 2, delivered)
+		assert.Len(t, received, 2)
+		assert.Equal(t, plugin.InProgress, *received[0].UpdatedState)
+		assert.Equal(t, plugin.Completed, *received[1].UpdatedState)
+		assert.Equal(t, &extended, received[1].ExtendedState)
+		assert.Equal(t, int64(2), received[1].Sequence)
+		assert.Equal(t, auth.Actor{Type: auth.ActorTypeTrader, ID: "trader1"}, received[1].Actor)
+		assert.Equal(t, entries[1].Transitions, received[1].Transitions)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Failure Holds Back Same Workflow Only", func(t *testing.T) {
+		store := new(MockOutboxStore)
+		blockedWorkflow := uuid.New()
+		otherWorkflow := uuid.New()
+		failingTask := uuid.New()
+		entries := []persistence.OutboxEntry{
+			{Sequence: 1, TaskID: failingTask, WorkflowID: blockedWorkflow, State: plugin.InProgress},
+			{Sequence: 2, TaskID: uuid.New(), WorkflowID: otherWorkflow, State: plugin.InProgress},
+			{Sequence: 3, TaskID: failingTask, WorkflowID: blockedWorkflow, State: plugin.Completed},
+		}
+		store.On("ClaimPending", dispatchBatchSize).Return(entries, nil).Once()
+		store.On("RecordFailure", int64(1), "db unavailable").Return(nil).Once()
+		store.On("MarkDelivered", int64(2)).Return(nil).Once()
+
+		var sequences []uuid.UUID
+		handler := func(_ context.Context, n WorkflowManagerNotification) error {
+			sequences = append(sequences, n.TaskID)
+			if n.TaskID == failingTask {
+				return errors.New("db unavailable")
+			}
+			return nil
+		}
+
+		d := newOutboxDispatcher(store, handler, time.Second)
+		delivered := d.dispatchPending(context.Background())
+
+		assert.Equal(t, 1, delivered)
+		assert.Len(t, sequences, 2)
+		store.AssertNotCalled(t, "MarkDelivered", int64(3))
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Mark Delivered Error Rolls Back Claim", func(t *testing.T) {
+		store := new(MockOutboxStore)
+		entries := []persistence.OutboxEntry{
+			{Sequence: 1, TaskID: uuid.New(), WorkflowID: uuid.New(), State: plugin.InProgress},
+			{Sequence: 2, TaskID: uuid.New(), WorkflowID: uuid.New(), State: plugin.InProgress},
+		}
+		store.On("ClaimPending", dispatchBatchSize).Return(entries, nil).Once()
+		store.On("MarkDelivered", int64(1)).Return(errors.New("db error")).Once()
+
+		handled := 0
+		d := newOutboxDispatcher(store, func(context.Context, WorkflowManagerNotification) error {
+			handled++
+			return nil
+		}, time.Second)
+
+		assert.Equal(t, 0, d.dispatchPending(context.Background()))
+		assert.Equal(t, 1, handled)
+		store.AssertNotCalled(t, "MarkDelivered", int64(2))
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Store Error", func(t *testing.T) {
+		store := new(MockOutboxStore)
+		store.On("ClaimPending", dispatchBatchSize).Return(nil, errors.New("db error")).Once()
+
+		d := newOutboxDispatcher(store, func(context.Context, WorkflowManagerNotification) error {
+			t.Fatal("handler must not be called")
+			return nil
+		}, time.Second)
+
+		assert.Equal(t, 0, d.dispatchPending(context.Background()))
+	})
+}
+
+func TestOutboxDispatcher_StartStop(t *testing.T) {
+	store := new(MockOutboxStore)
+	entry := persistence.OutboxEntry{Sequence: 7, TaskID: uuid.New(), WorkflowID: uuid.New(), State: plugin.Completed}
+	store.On("ClaimPending", dispatchBatchSize).Return([]persistence.OutboxEntry{entry}, nil).Once()
+	store.On("ClaimPending", dispatchBatchSize).Return([]persistence.OutboxEntry{}, nil)
+	store.On("MarkDelivered", int64(7)).Return(nil).Once()
+
+	delivered := make(chan WorkflowManagerNotification, 1)
+	d := newOutboxDispatcher(store, func(_ context.Context, n WorkflowManagerNotification) error {
+		delivered <- n
+		return nil
+	}, 10*time.Millisecond)
+
+	d.Start()
+	select {
+	case n := <-delivered:
+		assert.Equal(t, entry.TaskID, n.TaskID)
+	case <-time.After(time.Second):
+		t.Fatal("expected notification to be delivered")
+	}
+	d.Stop()
+}
@@ -3,6 +3,7 @@ package manager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -24,7 +25,8 @@ type InitTaskRequest struct {
 	WorkflowNodeTemplateID uuid.UUID   `json:"workflow_node_template_id"`
 	Type                   plugin.Type `json:"type"`
 	GlobalState            map[string]any
-	Config                 json.RawMessage `json:"config"`
+	GlobalContextMode      plugin.GlobalContextMode `json:"global_context_mode,omitempty"` // Which global context the task reads; SNAPSHOT if empty
+	Config                 json.RawMessage          `json:"config"`
 }
 
 type InitTaskResponse struct {
@@ -43,7 +45,7 @@ type ExecuteTaskResponse struct {
 // Architecture: Trader Portal → Workflow Engine → Task Manager
 // - Workflow Manager triggers Task Manager to get task info (e.g., form schema)
 // - ExecutionUnit Manager executes tasks and determines the next tasks to activate
-// - ExecutionUnit Manager notifies Workflow Engine on task completion via Go channel
+// - ExecutionUnit Manager notifies Workflow Engine of task state changes via the persistent outbox
 type TaskManager interface {
 	// InitTask initializes and executes a task using the provided TaskContext.
 	InitTask(ctx context.Context, request InitTaskRequest) (*InitTaskResponse, error)
@@ -53,6 +55,47 @@ type TaskManager interface {
 
 	// HandleGetTask is an HTTP handler for retrieving a task via GET request
 	HandleGetTask(w http.ResponseWriter, r *http.Request)
+
+	// GetTaskStates returns the persisted state of each task among taskIDs that exists.
+	// Tasks without a record are absent from the returned map.
+	GetTaskStates(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID]plugin.State, error)
+
+	// ResendStateNotification appends a notification carrying the task's current state to the
+	// outbox so the Workflow Manager can catch up with it. Returns false if the task already has
+	// a pending notification and nothing was resent.
+	ResendStateNotification(ctx context.Context, taskID uuid.UUID) (bool, error)
+
+	// CancelTask moves an unfinished task to CANCELLED so that further actions on it are rejected,
+	// and notifies any external system the task handed work over to. Cancelling a task without a
+	// record or one that already finished is a no-op.
+	CancelTask(ctx context.Context, taskID uuid.UUID, reason string) error
+
+	// ReopenTask starts a new attempt of a FAILED task with globalState as its global context.
+	// The failed attempt is archived in the task's history, which the new attempt can read from
+	// its local store, and the plugin starts over from its initial state. An error means that the
+	// task is still in its failed attempt.
+	ReopenTask(ctx context.Context, taskID uuid.UUID, globalState map[string]any) error
+
+	// DiscardTask deletes a task that has not progressed past INITIALIZED, e.g. because its
+	// workflow node was locked again, so that the node can register a fresh task once it unlocks.
+	// Discarding a task without a record is a no-op; persistence.ErrTaskStarted is returned for a
+	// task that has progressed.
+	DiscardTask(ctx context.Context, taskID uuid.UUID) error
+
+	// ExecuteScheduledAction executes an action a task scheduled for itself (e.g. a timer elapsing).
+	// Actions on tasks without a record, cancelled tasks, and actions the task's FSM no longer
+	// permits are discarded without error.
+	ExecuteScheduledAction(ctx context.Context, taskID uuid.UUID, action string) error
+
+	// ExecuteAction executes an action on a task on behalf of the Workflow Manager, e.g. to complete the task
+	// of a SUB_WORKFLOW node whose child workflow finished. Actions on tasks without a record, cancelled tasks,
+	// and actions the task's FSM does not permit are discarded without error.
+	ExecuteAction(ctx context.Context, taskID uuid.UUID, request *plugin.ExecutionRequest) error
+
+	// SetGlobalContextSource sets where tasks in LIVE global context mode read the current global context of
+	// their workflow from. Until it is set, they read the snapshot taken when they were initialized. It must be
+	// called before the Task Manager starts handling tasks.
+	SetGlobalContextSource(source container.GlobalContextSource)
 }
 
 // ExecuteTaskRequest represents the request body for task execution
@@ -64,31 +107,37 @@ type ExecuteTaskRequest struct {
 
 type taskManager struct {
 	factory          plugin.TaskFactory
-	store            persistence.TaskStoreInterface     // Storage for task executions
-	completionChan   chan<- WorkflowManagerNotification // Channel to notify Workflow Manager of task completions
-	config           *config.Config                     // Application configuration
-	containerCache   *containerCache                    // LRU cache for active containers
-	containerBuildMu sync.Mutex                         // Protects container creation to prevent duplicates
+	store            persistence.TaskStoreInterface   // Storage for task executions
+	outbox           persistence.OutboxStoreInterface // Outbox of task state notifications
+	config           *config.Config                   // Application configuration
+	containerCache   *containerCache                  // LRU cache for active containers
+	containerBuildMu sync.Mutex                       // Protects container creation to prevent duplicates
+
+	globalContextSource container.GlobalContextSource // Source of the global context read by LIVE tasks
 }
 
 // NewTaskManager creates a new TaskManager instance with persistence data store.
 // db is the shared database connection
-// completionChan is a channel for notifying Workflow Manager when tasks complete.
-// Note: The completionChan should have a sufficient buffer size (recommended: 1000+)
-// to prevent notification drops during high load.
-func NewTaskManager(db *gorm.DB, completionChan chan<- WorkflowManagerNotification, cfg *config.Config, formService form.FormService) (TaskManager, error) {
+// Task state changes are written to the task_outbox table in the same transaction as the
+// task state update; an OutboxDispatcher delivers them to the Workflow Manager.
+func NewTaskManager(db *gorm.DB, cfg *config.Config, formService form.FormService) (TaskManager, error) {
 	store, err := persistence.NewTaskStore(db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task store: %w", err)
 	}
 
+	outbox, err := persistence.NewOutboxStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outbox store: %w", err)
+	}
+
 	// Initialize container cache with capacity of 100 active containers
 	cache := newContainerCache(100)
 
 	return &taskManager{
 		factory:        plugin.NewTaskFactory(cfg, formService),
 		store:          store,
-		completionChan: completionChan,
+		outbox:         outbox,
 		config:         cfg,
 		containerCache: cache,
 	}, nil
@@ -157,6 +206,10 @@ func (tm *taskManager) HandleExecuteTask(w http.ResponseWriter, r *http.Request)
 
 	// Execute task
 	result, err := tm.execute(ctx, activeTask, req.Payload)
+	if errors.Is(err, container.ErrTaskCancelled) {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("task %s has been cancelled", req.TaskID))
+		return
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to execute task",
 			"taskID", req.TaskID,
@@ -170,6 +223,146 @@ func (tm *taskManager) HandleExecuteTask(w http.ResponseWriter, r *http.Request)
 	writeJSONResponse(w, http.StatusOK, result.ApiResponse)
 }
 
+// GetTaskStates returns the persisted state of each existing task among taskIDs
+func (tm *taskManager) GetTaskStates(_ context.Context, taskIDs []uuid.UUID) (map[uuid.UUID]plugin.State, error) {
+	taskInfos, err := tm.store.GetByIDs(taskIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task infos: %w", err)
+	}
+
+	states := make(map[uuid.UUID]plugin.State, len(taskInfos))
+	for _, taskInfo := range taskInfos {
+		states[taskInfo.ID] = taskInfo.State
+	}
+	return states, nil
+}
+
+// ResendStateNotification re-emits the current state of a task through the outbox
+func (tm *taskManager) ResendStateNotification(ctx context.Context, taskID uuid.UUID) (bool, error) {
+	taskInfo, err := tm.store.GetByID(taskID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load task %s: %w", taskID, err)
+	}
+
+	resent, err := tm.outbox.Resend(taskInfo.ID, taskInfo.WorkflowID, taskInfo.State)
+	if err != nil {
+		return false, fmt.Errorf("failed to resend state notification for task %s: %w", taskID, err)
+	}
+	if resent {
+		slog.InfoContext(ctx, "resent task state notification",
+			"taskID", taskID,
+			"state", taskInfo.State)
+	}
+	return resent, nil
+}
+
+// CancelTask cancels a task and notifies any external system it handed work over to
+func (tm *taskManager) CancelTask(ctx context.Context, taskID uuid.UUID, reason string) error {
+	activeTask, err := tm.getTask(ctx, taskID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.DebugContext(ctx, "no task record to cancel", "taskID", taskID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load task %s: %w", taskID, err)
+	}
+
+	if err := activeTask.Cancel(ctx, reason); err != nil {
+		return fmt.Errorf("failed to cancel task %s: %w", taskID, err)
+	}
+	slog.InfoContext(ctx, "task cancelled", "taskID", taskID)
+	return nil
+}
+
+// ReopenTask archives the failed attempt of a task and starts a new one. Once the new attempt is
+// committed, a plugin that fails to start is only logged, leaving the task INITIALIZED
+func (tm *taskManager) ReopenTask(ctx context.Context, taskID uuid.UUID, globalState map[string]any) error {
+	globalContextBytes, err := json.Marshal(globalState)
+	if err != nil {
+		return fmt.Errorf("failed to marshal global context: %w", err)
+	}
+
+	// Hold the build lock so that no container is rebuilt from the failed attempt while it is reset
+	tm.containerBuildMu.Lock()
+	taskInfo, err := tm.store.StartNewAttempt(taskID, globalContextBytes)
+	if err == nil {
+		tm.containerCache.Delete(taskID)
+	}
+	tm.containerBuildMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to start new attempt of task %s: %w", taskID, err)
+	}
+
+	activeTask, err := tm.getTask(ctx, taskID)
+	if err == nil {
+		_, err = tm.start(ctx, activeTask)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to start new attempt of reopened task",
+			"taskID", taskID,
+			"attempt", taskInfo.Attempt,
+			"error", err)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "task reopened", "taskID", taskID, "attempt", taskInfo.Attempt)
+	return nil
+}
+
+// DiscardTask deletes a task that has not started any work yet
+func (tm *taskManager) DiscardTask(ctx context.Context, taskID uuid.UUID) error {
+	// Hold the build lock so that no container is rebuilt from the record while it is deleted
+	tm.containerBuildMu.Lock()
+	err := tm.store.DeleteInitialized(taskID)
+	if err == nil {
+		tm.containerCache.Delete(taskID)
+	}
+	tm.containerBuildMu.Unlock()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.DebugContext(ctx, "no task record to discard", "taskID", taskID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to discard task %s: %w", taskID, err)
+	}
+
+	slog.InfoContext(ctx, "task discarded", "taskID", taskID)
+	return nil
+}
+
+// ExecuteScheduledAction executes an action scheduled by a task once it is due
+func (tm *taskManager) ExecuteScheduledAction(ctx context.Context, taskID uuid.UUID, action string) error {
+	return tm.ExecuteAction(ctx, taskID, &plugin.ExecutionRequest{Action: action})
+}
+
+// ExecuteAction executes an action on a task on behalf of the system, e.g. a scheduled action or the
+// completion of a sub-workflow
+func (tm *taskManager) ExecuteAction(ctx context.Context, taskID uuid.UUID, request *plugin.ExecutionRequest) error {
+	activeTask, err := tm.getTask(ctx, taskID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.WarnContext(ctx, "discarding action of unknown task", "taskID", taskID, "action", request.Action)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load task %s: %w", taskID, err)
+	}
+
+	if activeTask.GetTaskState() == plugin.Cancelled || !activeTask.CanTransition(request.Action) {
+		slog.InfoContext(ctx, "discarding action no longer permitted",
+			"taskID", taskID,
+			"action", request.Action,
+			"state", activeTask.GetTaskState(),
+			"pluginState", activeTask.GetPluginState())
+		return nil
+	}
+
+	if _, err := tm.execute(ctx, activeTask, request); err != nil {
+		return fmt.Errorf("failed to execute action %q on task %s: %w", request.Action, taskID, err)
+	}
+	slog.InfoContext(ctx, "action executed", "taskID", taskID, "action", request.Action)
+	return nil
+}
+
 func writeJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -220,7 +413,13 @@ func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*
 		globalStateCopy[k] = v
 	}
 
+	globalContextMode := request.GlobalContextMode
+	if globalContextMode == "" {
+		globalContextMode = plugin.GlobalContextSnapshot
+	}
+
 	activeTask := container.NewContainer(request.TaskID, request.WorkflowID, request.WorkflowNodeTemplateID, plugin.Initialized, globalStateCopy, localStateManager, tm.store, exec.Plugin, exec.FSM)
+	tm.applyGlobalContextMode(activeTask, globalContextMode)
 
 	// Convert request.Config to json.RawMessage
 	configBytes, err := json.Marshal(request.Config)
@@ -243,6 +442,7 @@ func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*
 		State:                  plugin.Initialized,
 		Config:                 configBytes,
 		GlobalContext:          globalContextBytes,
+		GlobalContextMode:      globalContextMode,
 	}
 
 	// Store in SQLite
@@ -261,31 +461,23 @@ func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*
 }
 
 func (tm *taskManager) start(ctx context.Context, activeTask *container.Container) (*InitTaskResponse, error) {
-	result, err := activeTask.Start(ctx)
-
-	if err != nil {
+	// The container persists the initial state change (e.g., InProgress) together with its
+	// outbox notification, so the workflow manager learns about it without a separate send.
+	if _, err := activeTask.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start task: %w", err)
 	}
 
-	// Notify the workflow manager of the initial state after starting the task (e.g., InProgress). This ensures that
-	//the workflow manager is aware of the task's state change immediately after initialization.
-	tm.notifyWorkflowManager(ctx, activeTask.TaskID, result.NewState, result.ExtendedState, result.AppendGlobalContext, result.Outcome)
-
 	return &InitTaskResponse{Success: true}, nil
 }
 
 // execute is a unified method that executes a task and returns the result.
+// State changes and their workflow notifications are persisted by the container.
 func (tm *taskManager) execute(ctx context.Context, activeTask *container.Container, payload *plugin.ExecutionRequest) (*plugin.ExecutionResponse, error) {
-	// Execute task
 	result, err := activeTask.Execute(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	if result.NewState != nil {
-		tm.notifyWorkflowManager(ctx, activeTask.TaskID, result.NewState, result.ExtendedState, result.AppendGlobalContext, result.Outcome)
-	}
-
 	return result, nil
 }
 
@@ -357,6 +549,7 @@ func (tm *taskManager) getTask(ctx context.Context, taskID uuid.UUID) (*containe
 
 	activeContainer := container.NewContainer(
 		execution.ID, execution.WorkflowID, execution.WorkflowNodeTemplateID, execution.State, globalContext, localState, tm.store, exec.Plugin, exec.FSM)
+	tm.applyGlobalContextMode(activeContainer, execution.GlobalContextMode)
 
 	// Cache the rebuilt container
 	tm.containerCache.Set(taskID, activeContainer)
@@ -367,42 +560,16 @@ func (tm *taskManager) getTask(ctx context.Context, taskID uuid.UUID) (*containe
 	return activeContainer, nil
 }
 
-// notifyWorkflowManager sends notification to Workflow Manager via Go channel
-func (tm *taskManager) notifyWorkflowManager(ctx context.Context, taskID uuid.UUID, state *plugin.State, extendedState *string, appendGlobalContext map[string]any, outcome *string) {
-	if tm.completionChan == nil {
-		slog.WarnContext(ctx, "completion channel not configured, skipping notification",
-			"taskID", taskID,
-			"state", state,
-			"extendedState", extendedState,
-			"appendGlobalContext", appendGlobalContext,
-		)
-		return
-	}
-
-	notification := WorkflowManagerNotification{
-		TaskID:              taskID,
-		UpdatedState:        state,
-		ExtendedState:       extendedState,
-		AppendGlobalContext: appendGlobalContext,
-		Outcome:             outcome,
-	}
+// SetGlobalContextSource sets the source LIVE tasks read their global context from.
+func (tm *taskManager) SetGlobalContextSource(source container.GlobalContextSource) {
+	tm.globalContextSource = source
+}
 
-	// Non-blocking send - if a channel is full, log warning but don't block
-	select {
-	case tm.completionChan <- notification:
-		slog.DebugContext(ctx, "task completion notification sent via channel",
-			"taskID", taskID,
-			"state", state,
-			"extendedState", extendedState,
-			"appendGlobalContext", appendGlobalContext,
-		)
-	default:
-		// Channel is full or closed
-		slog.WarnContext(ctx, "completion channel full or unavailable, notification dropped",
-			"taskID", taskID,
-			"state", state,
-			"extendedState", extendedState,
-			"appendGlobalContext", appendGlobalContext,
-		)
+// applyGlobalContextMode makes a task in LIVE global context mode read the current global context of its
+// workflow. Tasks in SNAPSHOT mode, and all tasks while no source is set, keep reading their snapshot.
+func (tm *taskManager) applyGlobalContextMode(activeTask *container.Container, mode plugin.GlobalContextMode) {
+	if mode != plugin.GlobalContextLive || tm.globalContextSource == nil {
+		return
 	}
+	activeTask.UseLiveGlobalContext(tm.globalContextSource)
 }
@@ -16,6 +16,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/config"
 	"github.com/OpenNSW/nsw/internal/task/container"
 	"github.com/OpenNSW/nsw/internal/task/persistence"
@@ -53,6 +54,14 @@ func (m *MockTaskStore) GetByID(id uuid.UUID) (*persistence.TaskInfo, error) {
 	return args.Get(0).(*persistence.TaskInfo), args.Error(1)
 }
 
+func (m *MockTaskStore) GetByIDs(ids []uuid.UUID) ([]persistence.TaskInfo, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]persistence.TaskInfo), args.Error(1)
+}
+
 func (m *MockTaskStore) UpdateStatus(id uuid.UUID, status *plugin.State) error {
 	args := m.Called(id, status)
 	return args.Error(0)
@@ -103,6 +112,29 @@ func (m *MockTaskStore) GetPluginState(id uuid.UUID) (string, error) {
 	return args.Get(0).(string), args.Error(1)
 }
 
+func (m *MockTaskStore) CommitStateChange(id uuid.UUID, pluginState string, state plugin.State, notification *persistence.OutboxEntry) error {
+	args := m.Called(id, pluginState, state, notification)
+	return args.Error(0)
+}
+
+func (m *MockTaskStore) StartNewAttempt(id uuid.UUID, globalContext json.RawMessage) (*persistence.TaskInfo, error) {
+	args := m.Called(id, globalContext)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*persistence.TaskInfo), args.Error(1)
+}
+
+func (m *MockTaskStore) DeleteInitialized(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTaskStore) ScheduleTimer(timer *persistence.TaskTimer) error {
+	args := m.Called(timer)
+	return args.Error(0)
+}
+
 // MockPlugin
 type MockPlugin struct {
 	mock.Mock
@@ -136,6 +168,16 @@ func (m *MockPlugin) GetRenderInfo(ctx context.Context) (*plugin.ApiResponse, er
 	return args.Get(0).(*plugin.ApiResponse), args.Error(1)
 }
 
+// MockCancellablePlugin is a MockPlugin that also implements plugin.Canceller
+type MockCancellablePlugin struct {
+	MockPlugin
+}
+
+func (m *MockCancellablePlugin) Cancel(ctx context.Context, reason string) error {
+	args := m.Called(ctx, reason)
+	return args.Error(0)
+}
+
 func setupTest(t *testing.T) (*taskManager, *MockTaskFactory, *MockTaskStore, *MockPlugin) {
 	t.Helper()
 
@@ -215,10 +257,14 @@ func TestInitTask(t *testing.T) {
 			NewState: &state,
 		}
 		mockPlugin.On("Start", ctx).Return(resp, nil).Once()
+		mockStore.On("CommitStateChange", req.TaskID, "", plugin.InProgress, mock.MatchedBy(func(n *persistence.OutboxEntry) bool {
+			return n.TaskID == req.TaskID && n.WorkflowID == req.WorkflowID && n.State == plugin.InProgress
+		})).Return(nil).Once()
 
 		result, err := tm.InitTask(ctx, req)
 		assert.NoError(t, err)
 		assert.True(t, result.Success)
+		mockStore.AssertExpectations(t)
 	})
 
 	t.Run("BuildExecutor Error", func(t *testing.T) {
@@ -325,12 +371,79 @@ func TestHandleExecuteTask(t *testing.T) {
 			ApiResponse: &plugin.ApiResponse{Success: true},
 		}
 		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(execResp, nil).Once()
-		mockStore.On("UpdateStatus", taskID, &newState).Return(nil).Once()
+		mockStore.On("CommitStateChange", taskID, "", newState, mock.AnythingOfType("*persistence.OutboxEntry")).Return(nil).Once()
 
 		tm.HandleExecuteTask(w, req)
 
 		resp := w.Result()
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockStore.AssertCalled(t, "CommitStateChange", taskID, "", newState, mock.AnythingOfType("*persistence.OutboxEntry"))
+	})
+
+	t.Run("State Commit Error", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+
+		taskID := uuid.New()
+		reqBody := ExecuteTaskRequest{
+			TaskID:  taskID,
+			Payload: &plugin.ExecutionRequest{Action: "submit"},
+		}
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		taskInfo := &persistence.TaskInfo{
+			ID:     taskID,
+			Type:   plugin.TaskTypeSimpleForm,
+			State:  plugin.InProgress,
+			Config: json.RawMessage(`{}`),
+		}
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+
+		newState := plugin.Completed
+		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(&plugin.ExecutionResponse{NewState: &newState}, nil).Once()
+		mockStore.On("CommitStateChange", taskID, "", newState, mock.AnythingOfType("*persistence.OutboxEntry")).Return(errors.New("db error")).Once()
+
+		tm.HandleExecuteTask(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+
+		// The in-memory state must not run ahead of the persisted state
+		cached, found := tm.containerCache.Get(taskID)
+		assert.True(t, found)
+		assert.Equal(t, plugin.InProgress, cached.GetTaskState())
+	})
+
+	t.Run("Cancelled Task", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+
+		taskID := uuid.New()
+		reqBody := ExecuteTaskRequest{
+			TaskID:  taskID,
+			Payload: &plugin.ExecutionRequest{Action: "submit"},
+		}
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		taskInfo := &persistence.TaskInfo{
+			ID:     taskID,
+			Type:   plugin.TaskTypeSimpleForm,
+			State:  plugin.Cancelled,
+			Config: json.RawMessage(`{}`),
+		}
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+
+		tm.HandleExecuteTask(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+		mockPlugin.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
 	})
 
 	t.Run("Execute Error", func(t *testing.T) {
@@ -492,49 +605,6 @@ func TestHandleGetTask(t *testing.T) {
 	})
 }
 
-func TestNotifyWorkflowManager(t *testing.T) {
-	t.Run("Channel Nil", func(t *testing.T) {
-		tm := &taskManager{
-			completionChan: nil,
-		}
-		// Should not panic
-		tm.notifyWorkflowManager(context.Background(), uuid.New(), nil, nil, nil, nil)
-	})
-
-	t.Run("Channel Default Send", func(t *testing.T) {
-		ch := make(chan WorkflowManagerNotification, 1)
-		tm := &taskManager{
-			completionChan: ch,
-		}
-		taskID := uuid.New()
-		state := plugin.Completed
-		tm.notifyWorkflowManager(context.Background(), taskID, &state, nil, nil, nil)
-
-		select {
-		case n := <-ch:
-			assert.Equal(t, taskID, n.TaskID)
-			assert.Equal(t, &state, n.UpdatedState)
-		default:
-			t.Fatal("expected notification")
-		}
-	})
-
-	t.Run("Channel Full (Drop)", func(t *testing.T) {
-		ch := make(chan WorkflowManagerNotification, 1)
-		tm := &taskManager{
-			completionChan: ch,
-		}
-		// Fill channel
-		ch <- WorkflowManagerNotification{}
-
-		// Attempt verify non-blocking drop
-		taskID := uuid.New()
-		tm.notifyWorkflowManager(context.Background(), taskID, nil, nil, nil, nil)
-
-		assert.Len(t, ch, 1) // Should still have 1 item
-	})
-}
-
 func TestGetTask_CacheRebuild(t *testing.T) {
 	t.Run("Cache Hit", func(t *testing.T) {
 		tm, _, _, mockPlugin := setupTest(t)
@@ -599,6 +669,454 @@ func TestGetTask_CacheRebuild(t *testing.T) {
 	})
 }
 
+func TestGlobalContextMode(t *testing.T) {
+	rebuild := func(t *testing.T, mode plugin.GlobalContextMode, source container.GlobalContextSource) *container.Container {
+		t.Helper()
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		tm.SetGlobalContextSource(source)
+		taskID := uuid.New()
+		taskInfo := &persistence.TaskInfo{
+			ID:                taskID,
+			WorkflowID:        uuid.New(),
+			Type:              plugin.TaskTypeSimpleForm,
+			Config:            json.RawMessage(`{}`),
+			GlobalContext:     json.RawMessage(`{"exporterName":"Snapshot Co"}`),
+			GlobalContextMode: mode,
+			LocalState:        json.RawMessage(`{}`),
+		}
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+
+		activeTask, err := tm.getTask(context.Background(), taskID)
+		assert.NoError(t, err)
+		return activeTask
+	}
+
+	t.Run("Snapshot", func(t *testing.T) {
+		activeTask := rebuild(t, plugin.GlobalContextSnapshot, func(ctx context.Context, taskID uuid.UUID) (map[string]any, error) {
+			t.Fatal("SNAPSHOT task must not read the live global context")
+			return nil, nil
+		})
+
+		globalContext, err := activeTask.ReadGlobalContext(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"exporterName": "Snapshot Co"}, globalContext)
+	})
+
+	t.Run("Live Reads Are Cached", func(t *testing.T) {
+		reads := 0
+		activeTask := rebuild(t, plugin.GlobalContextLive, func(ctx context.Context, taskID uuid.UUID) (map[string]any, error) {
+			reads++
+			return map[string]any{"exporterName": "Live Co"}, nil
+		})
+
+		for range 2 {
+			globalContext, err := activeTask.ReadGlobalContext(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]any{"exporterName": "Live Co"}, globalContext)
+		}
+		assert.Equal(t, 1, reads)
+	})
+
+	t.Run("Live Read Error", func(t *testing.T) {
+		activeTask := rebuild(t, plugin.GlobalContextLive, func(ctx context.Context, taskID uuid.UUID) (map[string]any, error) {
+			return nil, errors.New("db error")
+		})
+
+		globalContext, err := activeTask.ReadGlobalContext(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, globalContext)
+	})
+
+	t.Run("InitTask Persists Mode", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		tm.SetGlobalContextSource(func(ctx context.Context, taskID uuid.UUID) (map[string]any, error) {
+			return map[string]any{"exporterName": "Live Co"}, nil
+		})
+		ctx := context.Background()
+		req := InitTaskRequest{
+			TaskID:            uuid.New(),
+			WorkflowID:        uuid.New(),
+			Type:              plugin.TaskTypeSimpleForm,
+			Config:            json.RawMessage(`{}`),
+			GlobalState:       map[string]any{"exporterName": "Snapshot Co"},
+			GlobalContextMode: plugin.GlobalContextLive,
+		}
+		mockFactory.On("BuildExecutor", ctx, req.Type, req.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetLocalState", req.TaskID).Return(json.RawMessage(`{}`), nil).Once()
+		mockStore.On("GetPluginState", req.TaskID).Return("", nil).Once()
+		mockStore.On("Create", mock.MatchedBy(func(taskInfo *persistence.TaskInfo) bool {
+			return taskInfo.GlobalContextMode == plugin.GlobalContextLive
+		})).Return(nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockPlugin.On("Start", ctx).Return(&plugin.ExecutionResponse{}, nil).Once()
+
+		_, err := tm.InitTask(ctx, req)
+		assert.NoError(t, err)
+		mockStore.AssertExpectations(t)
+
+		activeTask, found := tm.containerCache.Get(req.TaskID)
+		assert.True(t, found)
+		globalContext, err := activeTask.ReadGlobalContext(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"exporterName": "Live Co"}, globalContext)
+	})
+}
+
+func TestGetTaskStates(t *testing.T) {
+	tm, _, mockStore, _ := setupTest(t)
+	existing := uuid.New()
+	missing := uuid.New()
+
+	mockStore.On("GetByIDs", []uuid.UUID{existing, missing}).Return([]persistence.TaskInfo{
+		{ID: existing, State: plugin.Completed},
+	}, nil)
+
+	states, err := tm.GetTaskStates(context.Background(), []uuid.UUID{existing, missing})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]plugin.State{existing: plugin.Completed}, states)
+	mockStore.AssertExpectations(t)
+}
+
+func TestResendStateNotification(t *testing.T) {
+	t.Run("Resent", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		outbox := new(MockOutboxStore)
+		tm.outbox = outbox
+		taskID := uuid.New()
+		workflowID := uuid.New()
+
+		mockStore.On("GetByID", taskID).Return(&persistence.TaskInfo{ID: taskID, WorkflowID: workflowID, State: plugin.Completed}, nil)
+		outbox.On("Resend", taskID, workflowID, plugin.Completed).Return(true, nil)
+
+		resent, err := tm.ResendStateNotification(context.Background(), taskID)
+		assert.NoError(t, err)
+		assert.True(t, resent)
+		outbox.AssertExpectations(t)
+	})
+
+	t.Run("Task Not Found", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		outbox := new(MockOutboxStore)
+		tm.outbox = outbox
+		taskID := uuid.New()
+
+		mockStore.On("GetByID", taskID).Return(nil, errors.New("record not found"))
+
+		resent, err := tm.ResendStateNotification(context.Background(), taskID)
+		assert.Error(t, err)
+		assert.False(t, resent)
+		outbox.AssertNotCalled(t, "Resend", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCancelTask(t *testing.T) {
+	newTaskInfo := func(taskID uuid.UUID, state plugin.State) *persistence.TaskInfo {
+		return &persistence.TaskInfo{
+			ID:     taskID,
+			Type:   plugin.TaskTypeSimpleForm,
+			State:  state,
+			Config: json.RawMessage(`{}`),
+		}
+	}
+
+	t.Run("Cancels And Notifies Plugin", func(t *testing.T) {
+		tm, mockFactory, mockStore, _ := setupTest(t)
+		mockPlugin := new(MockCancellablePlugin)
+		taskID := uuid.New()
+		taskInfo := newTaskInfo(taskID, plugin.InProgress)
+
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("OGA_ACKNOWLEDGED", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockStore.On("CommitStateChange", taskID, "OGA_ACKNOWLEDGED", plugin.Cancelled, (*persistence.OutboxEntry)(nil)).Return(nil).Once()
+		mockPlugin.On("Cancel", mock.Anything, "created by mistake").Return(nil).Once()
+
+		err := tm.CancelTask(context.Background(), taskID, "created by mistake")
+
+		assert.NoError(t, err)
+		cached, found := tm.containerCache.Get(taskID)
+		assert.True(t, found)
+		assert.Equal(t, plugin.Cancelled, cached.GetTaskState())
+		mockStore.AssertExpectations(t)
+		mockPlugin.AssertExpectations(t)
+	})
+
+	t.Run("Notification Failure Keeps Cancellation", func(t *testing.T) {
+		tm, mockFactory, mockStore, _ := setupTest(t)
+		mockPlugin := new(MockCancellablePlugin)
+		taskID := uuid.New()
+		taskInfo := newTaskInfo(taskID, plugin.InProgress)
+
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("OGA_ACKNOWLEDGED", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockStore.On("CommitStateChange", taskID, "OGA_ACKNOWLEDGED", plugin.Cancelled, (*persistence.OutboxEntry)(nil)).Return(nil).Once()
+		mockPlugin.On("Cancel", mock.Anything, "created by mistake").Return(errors.New("OGA unavailable")).Once()
+
+		err := tm.CancelTask(context.Background(), taskID, "created by mistake")
+
+		assert.NoError(t, err)
+		cached, _ := tm.containerCache.Get(taskID)
+		assert.Equal(t, plugin.Cancelled, cached.GetTaskState())
+	})
+
+	t.Run("Persist Error", func(t *testing.T) {
+		tm, mockFactory, mockStore, _ := setupTest(t)
+		mockPlugin := new(MockCancellablePlugin)
+		taskID := uuid.New()
+		taskInfo := newTaskInfo(taskID, plugin.InProgress)
+
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockStore.On("CommitStateChange", taskID, "", plugin.Cancelled, (*persistence.OutboxEntry)(nil)).Return(errors.New("db error")).Once()
+
+		err := tm.CancelTask(context.Background(), taskID, "created by mistake")
+
+		assert.Error(t, err)
+		cached, _ := tm.containerCache.Get(taskID)
+		assert.Equal(t, plugin.InProgress, cached.GetTaskState())
+		mockPlugin.AssertNotCalled(t, "Cancel", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Finished Task Is Left Alone", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.New()
+		taskInfo := newTaskInfo(taskID, plugin.Completed)
+
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+
+		err := tm.CancelTask(context.Background(), taskID, "created by mistake")
+
+		assert.NoError(t, err)
+		mockStore.AssertNotCalled(t, "CommitStateChange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Task Not Found", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		taskID := uuid.New()
+
+		mockStore.On("GetByID", taskID).Return(nil, gorm.ErrRecordNotFound).Once()
+
+		err := tm.CancelTask(context.Background(), taskID, "created by mistake")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestDiscardTask(t *testing.T) {
+	t.Run("Deletes Initialized Task", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		ctx := context.Background()
+		taskID := uuid.New()
+
+		mockPlugin.On("Init", mock.Anything).Return()
+		tm.containerCache.Set(taskID, container.NewContainer(taskID, uuid.New(), uuid.New(), plugin.Initialized, nil, nil, nil, mockPlugin, nil))
+		mockStore.On("DeleteInitialized", taskID).Return(nil).Once()
+
+		err := tm.DiscardTask(ctx, taskID)
+
+		assert.NoError(t, err)
+		_, found := tm.containerCache.Get(taskID)
+		assert.False(t, found)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("No Task Record", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		taskID := uuid.New()
+		mockStore.On("DeleteInitialized", taskID).Return(gorm.ErrRecordNotFound).Once()
+
+		assert.NoError(t, tm.DiscardTask(context.Background(), taskID))
+	})
+
+	t.Run("Task Already Started", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.New()
+
+		mockPlugin.On("Init", mock.Anything).Return()
+		tm.containerCache.Set(taskID, container.NewContainer(taskID, uuid.New(), uuid.New(), plugin.InProgress, nil, nil, nil, mockPlugin, nil))
+		mockStore.On("DeleteInitialized", taskID).Return(persistence.ErrTaskStarted).Once()
+
+		err := tm.DiscardTask(context.Background(), taskID)
+
+		assert.ErrorIs(t, err, persistence.ErrTaskStarted)
+		_, found := tm.containerCache.Get(taskID)
+		assert.True(t, found)
+	})
+}
+
+func TestReopenTask(t *testing.T) {
+	t.Run("Starts New Attempt", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		ctx := context.Background()
+		taskID := uuid.New()
+		workflowID := uuid.New()
+		globalState := map[string]any{"foo": "bar"}
+
+		// A stale container of the failed attempt must not be reused
+		mockPlugin.On("Init", mock.Anything).Return()
+		tm.containerCache.Set(taskID, container.NewContainer(taskID, workflowID, uuid.New(), plugin.Failed, nil, nil, nil, mockPlugin, nil))
+
+		reopened := &persistence.TaskInfo{
+			ID:            taskID,
+			WorkflowID:    workflowID,
+			Type:          plugin.TaskTypeSimpleForm,
+			State:         plugin.Initialized,
+			Config:        json.RawMessage(`{}`),
+			GlobalContext: json.RawMessage(`{"foo":"bar"}`),
+			LocalState:    json.RawMessage(`{"task:history":[{"attempt":1,"state":"FAILED"}]}`),
+			Attempt:       2,
+		}
+		mockStore.On("StartNewAttempt", taskID, json.RawMessage(`{"foo":"bar"}`)).Return(reopened, nil).Once()
+		mockStore.On("GetByID", taskID).Return(reopened, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, reopened.Type, reopened.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+
+		state := plugin.InProgress
+		mockPlugin.On("Start", ctx).Return(&plugin.ExecutionResponse{NewState: &state}, nil).Once()
+		mockStore.On("CommitStateChange", taskID, "", plugin.InProgress, mock.MatchedBy(func(n *persistence.OutboxEntry) bool {
+			return n.TaskID == taskID && n.WorkflowID == workflowID && n.State == plugin.InProgress
+		})).Return(nil).Once()
+
+		err := tm.ReopenTask(ctx, taskID, globalState)
+
+		assert.NoError(t, err)
+		cached, found := tm.containerCache.Get(taskID)
+		assert.True(t, found)
+		assert.Equal(t, plugin.InProgress, cached.GetTaskState())
+		history, err := cached.ReadFromLocalStore(persistence.LocalStateHistoryKey)
+		assert.NoError(t, err)
+		assert.Len(t, history, 1)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Plugin Fails To Start New Attempt", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		ctx := context.Background()
+		taskID := uuid.New()
+
+		reopened := &persistence.TaskInfo{
+			ID:         taskID,
+			WorkflowID: uuid.New(),
+			Type:       plugin.TaskTypeSimpleForm,
+			State:      plugin.Initialized,
+			Config:     json.RawMessage(`{}`),
+			Attempt:    2,
+		}
+		mockStore.On("StartNewAttempt", taskID, mock.Anything).Return(reopened, nil).Once()
+		mockStore.On("GetByID", taskID).Return(reopened, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, reopened.Type, reopened.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return()
+		mockPlugin.On("Start", ctx).Return(nil, errors.New("plugin unavailable")).Once()
+
+		err := tm.ReopenTask(ctx, taskID, nil)
+
+		// The new attempt is committed, so the reopen of the workflow node must stand
+		assert.NoError(t, err)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Task Not Failed", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.New()
+
+		mockPlugin.On("Init", mock.Anything).Return()
+		cached := container.NewContainer(taskID, uuid.New(), uuid.New(), plugin.InProgress, nil, nil, nil, mockPlugin, nil)
+		tm.containerCache.Set(taskID, cached)
+		mockStore.On("StartNewAttempt", taskID, mock.Anything).Return(nil, persistence.ErrTaskNotFailed).Once()
+
+		err := tm.ReopenTask(context.Background(), taskID, nil)
+
+		assert.ErrorIs(t, err, persistence.ErrTaskNotFailed)
+		stillCached, found := tm.containerCache.Get(taskID)
+		assert.True(t, found)
+		assert.Equal(t, cached, stillCached)
+	})
+}
+
+func TestExecuteScheduledAction(t *testing.T) {
+	fsm := plugin.NewPluginFSM(map[plugin.TransitionKey]plugin.TransitionOutcome{
+		{FromState: "WAITING", Action: "ELAPSE"}: {NextPluginState: "ELAPSED", NextTaskState: plugin.Completed},
+	})
+
+	t.Run("Executes Permitted Action", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		ctx := context.Background()
+		taskID := uuid.New()
+
+		mockStore.On("GetPluginState", taskID).Return("WAITING", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return()
+		cached := container.NewContainer(taskID, uuid.New(), uuid.New(), plugin.InProgress, nil, nil, mockStore, mockPlugin, fsm)
+		tm.containerCache.Set(taskID, cached)
+
+		mockPlugin.On("Execute", ctx, &plugin.ExecutionRequest{Action: "ELAPSE"}).Run(func(mock.Arguments) {
+			_ = cached.Transition("ELAPSE")
+		}).Return(&plugin.ExecutionResponse{}, nil).Once()
+		mockStore.On("CommitStateChange", taskID, "ELAPSED", plugin.Completed, mock.MatchedBy(func(entry *persistence.OutboxEntry) bool {
+			return entry.Actor == auth.SystemActor && assert.ObjectsAreEqual([]plugin.TransitionRecord{
+				{Action: "ELAPSE", FromState: "WAITING", ToState: "ELAPSED", TaskState: plugin.Completed},
+			}, entry.Transitions)
+		})).Return(nil).Once()
+
+		err := tm.ExecuteScheduledAction(ctx, taskID, "ELAPSE")
+
+		assert.NoError(t, err)
+		assert.Equal(t, plugin.Completed, cached.GetTaskState())
+		mockPlugin.AssertExpectations(t)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Action No Longer Permitted", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.New()
+
+		mockStore.On("GetPluginState", taskID).Return("ELAPSED", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return()
+		tm.containerCache.Set(taskID, container.NewContainer(taskID, uuid.New(), uuid.New(), plugin.Completed, nil, nil, mockStore, mockPlugin, fsm))
+
+		err := tm.ExecuteScheduledAction(context.Background(), taskID, "ELAPSE")
+
+		assert.NoError(t, err)
+		mockPlugin.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Task Not Found", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		taskID := uuid.New()
+		mockStore.On("GetByID", taskID).Return(nil, gorm.ErrRecordNotFound).Once()
+
+		err := tm.ExecuteScheduledAction(context.Background(), taskID, "ELAPSE")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Execution Error", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		ctx := context.Background()
+		taskID := uuid.New()
+
+		mockStore.On("GetPluginState", taskID).Return("WAITING", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return()
+		tm.containerCache.Set(taskID, container.NewContainer(taskID, uuid.New(), uuid.New(), plugin.InProgress, nil, nil, mockStore, mockPlugin, fsm))
+		mockPlugin.On("Execute", ctx, mock.Anything).Return(nil, errors.New("timer has not elapsed yet")).Once()
+
+		err := tm.ExecuteScheduledAction(ctx, taskID, "ELAPSE")
+
+		assert.Error(t, err)
+	})
+}
+
 func TestNewTaskManager(t *testing.T) {
 	db, _, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -610,12 +1128,11 @@ func TestNewTaskManager(t *testing.T) {
 	assert.NoError(t, err)
 
 	cfg := &config.Config{}
-	ch := make(chan WorkflowManagerNotification, 1)
 
 	// Since NewTaskStore connects to DB and migrates (maybe?), or just returns struct
 	// Here persistence.NewTaskStore(db) likely just returns struct.
 
-	tm, err := NewTaskManager(gormDB, ch, cfg, nil)
+	tm, err := NewTaskManager(gormDB, cfg, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, tm)
 }
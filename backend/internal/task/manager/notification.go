@@ -1,8 +1,11 @@
 package manager
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
 )
 
@@ -11,5 +14,9 @@ type WorkflowManagerNotification struct {
 	UpdatedState        *plugin.State
 	AppendGlobalContext map[string]any
 	ExtendedState       *string
-	Outcome             *string // Outcome sub-state for COMPLETED transitions (e.g., APPROVED, REJECTED)
+	Outcome             *string                   // Outcome sub-state for COMPLETED transitions (e.g., APPROVED, REJECTED)
+	Sequence            int64                     // Outbox sequence of the notification; the same on redelivery
+	OccurredAt          time.Time                 // When the task state changed
+	Actor               auth.Actor                // Who triggered the state change
+	Transitions         []plugin.TransitionRecord // FSM transitions that led to the state change
 }
@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+)
+
+// timerBatchSize is the maximum number of due timers fired per scheduler cycle.
+const timerBatchSize = 100
+
+// TimerHandler executes the action of a due timer on its task.
+// Returning an error leaves the timer pending so it fires again in a later cycle.
+type TimerHandler func(ctx context.Context, taskID uuid.UUID, action string) error
+
+// TimerScheduler fires persisted task timers once they are due. Because timers are stored in
+// the database, timers that fell due while the server was down fire on the first cycle after
+// it restarts. A timer is only marked fired after the handler succeeds.
+type TimerScheduler struct {
+	store    persistence.TimerStoreInterface
+	handler  TimerHandler
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewTimerScheduler creates a new TimerScheduler that checks for due timers every interval.
+func NewTimerScheduler(db *gorm.DB, handler TimerHandler, interval time.Duration) (*TimerScheduler, error) {
+	store, err := persistence.NewTimerStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timer store: %w", err)
+	}
+	return newTimerScheduler(store, handler, interval), nil
+}
+
+func newTimerScheduler(store persistence.TimerStoreInterface, handler TimerHandler, interval time.Duration) *TimerScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TimerScheduler{
+		store:    store,
+		handler:  handler,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the scheduler loop in a background goroutine.
+func (s *TimerScheduler) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.fireDue(s.ctx)
+			select {
+			case <-s.ctx.Done():
+				slog.Info("timer scheduler stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler loop and waits for the in-flight cycle to finish.
+func (s *TimerScheduler) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// fireDue fires one batch of due timers.
+// Returns the number of timers fired successfully.
+func (s *TimerScheduler) fireDue(ctx context.Context) int {
+	timers, err := s.store.GetDue(time.Now().UTC(), timerBatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load due timers", "error", err)
+		return 0
+	}
+
+	fired := 0
+	for _, timer := range timers {
+		if ctx.Err() != nil {
+			return fired
+		}
+
+		if err := s.handler(ctx, timer.TaskID, timer.Action); err != nil {
+			slog.WarnContext(ctx, "failed to fire timer, will retry",
+				"timerID", timer.ID,
+				"taskID", timer.TaskID,
+				"action", timer.Action,
+				"attempts", timer.Attempts+1,
+				"error", err)
+			if recordErr := s.store.RecordFailure(timer.ID, err.Error()); recordErr != nil {
+				slog.ErrorContext(ctx, "failed to record timer failure",
+					"timerID", timer.ID,
+					"error", recordErr)
+			}
+			continue
+		}
+
+		if err := s.store.MarkFired(timer.ID); err != nil {
+			// The timer fires again; the task's FSM discards the repeated action.
+			slog.ErrorContext(ctx, "failed to mark timer as fired",
+				"timerID", timer.ID,
+				"error", err)
+			continue
+		}
+		fired++
+	}
+
+	return fired
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+)
+
+// MockTimerStore
+type MockTimerStore struct {
+	mock.Mock
+}
+
+func (m *MockTimerStore) GetDue(now time.Time, limit int) ([]persistence.TaskTimer, error) {
+	args := m.Called(now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]persistence.TaskTimer), args.Error(1)
+}
+
+func (m *MockTimerStore) MarkFired(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTimerStore) RecordFailure(id int64, reason string) error {
+	args := m.Called(id, reason)
+	return args.Error(0)
+}
+
+func TestTimerScheduler_FireDue(t *testing.T) {
+	t.Run("Fires Due Timers", func(t *testing.T) {
+		store := new(MockTimerStore)
+		firstTask := uuid.New()
+		secondTask := uuid.New()
+		timers := []persistence.TaskTimer{
+			{ID: 1, TaskID: firstTask, Action: "ELAPSE"},
+			{ID: 2, TaskID: secondTask, Action: "ELAPSE"},
+		}
+		store.On("GetDue", mock.AnythingOfType("time.Time"), timerBatchSize).Return(timers, nil).Once()
+		store.On("MarkFired", int64(1)).Return(nil).Once()
+		store.On("MarkFired", int64(2)).Return(nil).Once()
+
+		var firedTasks []uuid.UUID
+		handler := func(_ context.Context, taskID uuid.UUID, action string) error {
+			assert.Equal(t, "ELAPSE", action)
+			firedTasks = append(firedTasks, taskID)
+			return nil
+		}
+
+		s := newTimerScheduler(store, handler, time.Second)
+		fired := s.fireDue(context.Background())
+
+		assert.Equal(t, 2, fired)
+		assert.Equal(t, []uuid.UUID{firstTask, secondTask}, firedTasks)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Failure Leaves Timer Pending", func(t *testing.T) {
+		store := new(MockTimerStore)
+		failingTask := uuid.New()
+		otherTask := uuid.New()
+		timers := []persistence.TaskTimer{
+			{ID: 1, TaskID: failingTask, Action: "ELAPSE"},
+			{ID: 2, TaskID: otherTask, Action: "ELAPSE"},
+		}
+		store.On("GetDue", mock.AnythingOfType("time.Time"), timerBatchSize).Return(timers, nil).Once()
+		store.On("RecordFailure", int64(1), "task manager unavailable").Return(nil).Once()
+		store.On("MarkFired", int64(2)).Return(nil).Once()
+
+		handler := func(_ context.Context, taskID uuid.UUID, _ string) error {
+			if taskID == failingTask {
+				return errors.New("task manager unavailable")
+			}
+			return nil
+		}
+
+		s := newTimerScheduler(store, handler, time.Second)
+		fired := s.fireDue(context.Background())
+
+		assert.Equal(t, 1, fired)
+		store.AssertExpectations(t)
+		store.AssertNotCalled(t, "MarkFired", int64(1))
+	})
+
+	t.Run("Load Error", func(t *testing.T) {
+		store := new(MockTimerStore)
+		store.On("GetDue", mock.AnythingOfType("time.Time"), timerBatchSize).Return(nil, errors.New("db error")).Once()
+
+		s := newTimerScheduler(store, func(context.Context, uuid.UUID, string) error { return nil }, time.Second)
+		fired := s.fireDue(context.Background())
+
+		assert.Equal(t, 0, fired)
+	})
+}
+
+func TestTimerScheduler_StartStop(t *testing.T) {
+	store := new(MockTimerStore)
+	store.On("GetDue", mock.AnythingOfType("time.Time"), timerBatchSize).Return([]persistence.TaskTimer{}, nil)
+
+	s := newTimerScheduler(store, func(context.Context, uuid.UUID, string) error { return nil }, 10*time.Millisecond)
+	s.Start()
+	time.Sleep(30 * time.Millisecond)
+	s.Stop()
+
+	store.AssertCalled(t, "GetDue", mock.AnythingOfType("time.Time"), timerBatchSize)
+}
@@ -0,0 +1,149 @@
+package persistence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// OutboxEntry represents a pending task state notification for the Workflow Manager.
+// Entries are written in the same transaction as the task_infos state change they describe,
+// so a committed state change can never be lost before it is delivered.
+type OutboxEntry struct {
+	Sequence            int64                     `gorm:"column:sequence;primaryKey;autoIncrement" json:"sequence"` // Monotonic sequence used for in-order delivery
+	TaskID              uuid.UUID                 `gorm:"type:uuid;column:task_id;not null" json:"taskId"`
+	WorkflowID          uuid.UUID                 `gorm:"type:uuid;column:workflow_id;not null;index" json:"workflowId"`
+	State               plugin.State              `gorm:"type:varchar(50);column:state;not null" json:"state"`
+	ExtendedState       *string                   `gorm:"type:varchar(100);column:extended_state" json:"extendedState,omitempty"`
+	Outcome             *string                   `gorm:"type:varchar(100);column:outcome" json:"outcome,omitempty"`
+	AppendGlobalContext map[string]any            `gorm:"type:jsonb;column:append_global_context;serializer:json" json:"appendGlobalContext,omitempty"`
+	Actor               auth.Actor                `gorm:"embedded;embeddedPrefix:actor_" json:"actor"`                                // Who triggered the state change
+	Transitions         []plugin.TransitionRecord `gorm:"type:jsonb;column:transitions;serializer:json" json:"transitions,omitempty"` // FSM transitions that led to the state change
+	Attempts            int                       `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError           *string                   `gorm:"type:text;column:last_error" json:"lastError,omitempty"`
+	CreatedAt           time.Time                 `gorm:"type:timestamptz;column:created_at;not null" json:"createdAt"`
+	DeliveredAt         *time.Time                `gorm:"type:timestamptz;column:delivered_at" json:"deliveredAt,omitempty"`
+}
+
+// TableName returns the table name for OutboxEntry
+func (OutboxEntry) TableName() string {
+	return "task_outbox"
+}
+
+// OutboxStore handles database operations for the task notification outbox
+type OutboxStore struct {
+	db *gorm.DB
+}
+
+type OutboxStoreInterface interface {
+	ClaimPending(limit int, process func(entries []OutboxEntry, claim OutboxClaim) error) error
+	Resend(taskID, workflowID uuid.UUID, state plugin.State) (bool, error)
+}
+
+// OutboxClaim records the delivery results of claimed outbox entries in the transaction that claims them.
+type OutboxClaim interface {
+	MarkDelivered(sequence int64) error
+	RecordFailure(sequence int64, reason string) error
+}
+
+// NewOutboxStore creates a new OutboxStore with the provided database connection
+func NewOutboxStore(db *gorm.DB) (*OutboxStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection cannot be nil")
+	}
+
+	return &OutboxStore{db: db}, nil
+}
+
+// ClaimPending claims up to limit undelivered outbox entries and passes them to process in sequence order. The
+// entries are claimed workflow by workflow in a transaction that stays open while process runs: the earliest
+// undelivered entry of each claimed workflow is locked with FOR UPDATE SKIP LOCKED, so that other dispatchers,
+// e.g. of other replicas, skip the workflow and never deliver its entries twice or out of order. The deliveries
+// and failures process records through claim are committed when it returns nil, and rolled back with the claim
+// otherwise.
+func (s *OutboxStore) ClaimPending(limit int, process func(entries []OutboxEntry, claim OutboxClaim) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var heads []OutboxEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("delivered_at IS NULL AND NOT EXISTS (?)",
+				tx.Model(&OutboxEntry{}).Table("task_outbox AS earlier").Select("1").
+					Where("earlier.workflow_id = task_outbox.workflow_id AND earlier.delivered_at IS NULL AND earlier.sequence < task_outbox.sequence")).
+			Order("sequence ASC").
+			Limit(limit).
+			Find(&heads).Error; err != nil {
+			return fmt.Errorf("failed to claim pending outbox entries: %w", err)
+		}
+		if len(heads) == 0 {
+			return nil
+		}
+
+		workflowIDs := make([]uuid.UUID, len(heads))
+		for i, head := range heads {
+			workflowIDs[i] = head.WorkflowID
+		}
+		var entries []OutboxEntry
+		if err := tx.Where("delivered_at IS NULL AND workflow_id IN ?", workflowIDs).
+			Order("sequence ASC").
+			Limit(limit).
+			Find(&entries).Error; err != nil {
+			return fmt.Errorf("failed to load claimed outbox entries: %w", err)
+		}
+
+		return process(entries, &OutboxStore{db: tx})
+	})
+}
+
+// MarkDelivered records that an outbox entry was processed by the Workflow Manager
+func (s *OutboxStore) MarkDelivered(sequence int64) error {
+	return s.db.Model(&OutboxEntry{}).Where("sequence = ?", sequence).Update("delivered_at", time.Now().UTC()).Error
+}
+
+// RecordFailure increments the attempt counter of an outbox entry and stores the failure reason
+func (s *OutboxStore) RecordFailure(sequence int64, reason string) error {
+	return s.db.Model(&OutboxEntry{}).Where("sequence = ?", sequence).Updates(map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": reason,
+	}).Error
+}
+
+// Resend appends a new pending notification carrying the given task state. The details
+// (extended state, outcome, global context) are copied from the latest earlier notification
+// of the task in that state, if any; the resent notification is attributed to the system and
+// records no FSM transitions, since none were applied. Nothing is written and false is returned if the task
+// already has a pending notification, since delivering it will bring the workflow up to date.
+func (s *OutboxStore) Resend(taskID, workflowID uuid.UUID, state plugin.State) (bool, error) {
+	resent := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := tx.Model(&OutboxEntry{}).Where("task_id = ? AND delivered_at IS NULL", taskID).Count(&pending).Error; err != nil {
+			return fmt.Errorf("failed to count pending outbox entries: %w", err)
+		}
+		if pending > 0 {
+			return nil
+		}
+
+		entry := OutboxEntry{TaskID: taskID, WorkflowID: workflowID, State: state, Actor: auth.SystemActor}
+		var previous []OutboxEntry
+		if err := tx.Where("task_id = ? AND state = ?", taskID, state).Order("sequence DESC").Limit(1).Find(&previous).Error; err != nil {
+			return fmt.Errorf("failed to load previous outbox entry: %w", err)
+		}
+		if len(previous) > 0 {
+			entry.ExtendedState = previous[0].ExtendedState
+			entry.Outcome = previous[0].Outcome
+			entry.AppendGlobalContext = previous[0].AppendGlobalContext
+		}
+
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to append outbox entry: %w", err)
+		}
+		resent = true
+		return nil
+	})
+	return resent, err
+}
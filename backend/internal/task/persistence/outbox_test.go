@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	return gdb, mock
+}
+
+func TestOutboxStore_ClaimPending(t *testing.T) {
+	columns := []string{"sequence", "task_id", "workflow_id", "state"}
+
+	t.Run("Claims Workflows And Commits Deliveries", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		store, err := NewOutboxStore(db)
+		require.NoError(t, err)
+		workflowID, taskID := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "task_outbox" WHERE delivered_at IS NULL AND NOT EXISTS \(SELECT 1 FROM task_outbox AS earlier WHERE earlier.workflow_id = task_outbox.workflow_id AND earlier.delivered_at IS NULL AND earlier.sequence < task_outbox.sequence\) ORDER BY sequence ASC LIMIT \$1 FOR UPDATE SKIP LOCKED`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, taskID, workflowID, "IN_PROGRESS"))
+		sqlMock.ExpectQuery(`SELECT \* FROM "task_outbox" WHERE delivered_at IS NULL AND workflow_id IN \(\$1\) ORDER BY sequence ASC LIMIT \$2`).
+			WithArgs(workflowID, 10).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, taskID, workflowID, "IN_PROGRESS").
+				AddRow(2, taskID, workflowID, "COMPLETED"))
+		sqlMock.ExpectExec(`UPDATE "task_outbox" SET "delivered_at"=\$1 WHERE sequence = \$2`).
+			WithArgs(sqlmock.AnyArg(), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectExec(`UPDATE "task_outbox" SET "attempts"=attempts \+ 1,"last_error"=\$1 WHERE sequence = \$2`).
+			WithArgs("db unavailable", int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		var sequences []int64
+		err = store.ClaimPending(10, func(entries []OutboxEntry, claim OutboxClaim) error {
+			for _, entry := range entries {
+				sequences = append(sequences, entry.Sequence)
+			}
+			require.NoError(t, claim.MarkDelivered(1))
+			require.NoError(t, claim.RecordFailure(2, "db unavailable"))
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, sequences)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Nothing To Claim", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		store, err := NewOutboxStore(db)
+		require.NoError(t, err)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "task_outbox" .* FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(sqlmock.NewRows(columns))
+		sqlMock.ExpectCommit()
+
+		err = store.ClaimPending(10, func([]OutboxEntry, OutboxClaim) error {
+			t.Fatal("process must not be called")
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Process Error Rolls Back Claim", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		store, err := NewOutboxStore(db)
+		require.NoError(t, err)
+		workflowID := uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "task_outbox" .* FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, uuid.New(), workflowID, "IN_PROGRESS"))
+		sqlMock.ExpectQuery(`SELECT \* FROM "task_outbox" WHERE delivered_at IS NULL AND workflow_id IN`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, uuid.New(), workflowID, "IN_PROGRESS"))
+		sqlMock.ExpectRollback()
+
+		err = store.ClaimPending(10, func([]OutboxEntry, OutboxClaim) error {
+			return errors.New("stop")
+		})
+
+		assert.ErrorContains(t, err, "stop")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
@@ -2,28 +2,33 @@ package persistence
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/OpenNSW/nsw/internal/task/plugin"
 )
 
 // TaskInfo represents a task execution record in the database
 type TaskInfo struct {
-	ID                     uuid.UUID       `gorm:"type:uuid;column:id;not null;primaryKey" json:"id"`
-	WorkflowID             uuid.UUID       `gorm:"type:uuid;column:workflow_id;not null;index" json:"workflowId"`
-	WorkflowNodeTemplateID uuid.UUID       `gorm:"type:uuid;column:workflow_node_template_id;not null" json:"workflowNodeTemplateId"`
-	Type                   plugin.Type     `gorm:"type:varchar(50);column:type;not null" json:"type"`
-	State                  plugin.State    `gorm:"type:varchar(50);column:state;not null" json:"state"`      // Container-level state (lifecycle)
-	PluginState            string          `gorm:"type:varchar(100);column:plugin_state" json:"pluginState"` // Plugin-level state (business logic)
-	Config                 json.RawMessage `gorm:"type:jsonb;column:config;serializer:json" json:"config"`
-	LocalState             json.RawMessage `gorm:"type:jsonb;column:local_state;serializer:json" json:"localState"`
-	GlobalContext          json.RawMessage `gorm:"type:jsonb;column:global_context;serializer:json" json:"globalContext"`
-	CreatedAt              time.Time       `gorm:"type:timestamptz;column:created_at;not null" json:"createdAt"`
-	UpdatedAt              time.Time       `gorm:"type:timestamptz;column:updated_at;not null" json:"updatedAt"`
+	ID                     uuid.UUID                `gorm:"type:uuid;column:id;not null;primaryKey" json:"id"`
+	WorkflowID             uuid.UUID                `gorm:"type:uuid;column:workflow_id;not null;index" json:"workflowId"`
+	WorkflowNodeTemplateID uuid.UUID                `gorm:"type:uuid;column:workflow_node_template_id;not null" json:"workflowNodeTemplateId"`
+	Type                   plugin.Type              `gorm:"type:varchar(50);column:type;not null" json:"type"`
+	State                  plugin.State             `gorm:"type:varchar(50);column:state;not null" json:"state"`      // Container-level state (lifecycle)
+	PluginState            string                   `gorm:"type:varchar(100);column:plugin_state" json:"pluginState"` // Plugin-level state (business logic)
+	Config                 json.RawMessage          `gorm:"type:jsonb;column:config;serializer:json" json:"config"`
+	LocalState             json.RawMessage          `gorm:"type:jsonb;column:local_state;serializer:json" json:"localState"`
+	GlobalContext          json.RawMessage          `gorm:"type:jsonb;column:global_context;serializer:json" json:"globalContext"`
+	GlobalContextMode      plugin.GlobalContextMode `gorm:"type:varchar(20);column:global_context_mode;not null;default:SNAPSHOT" json:"globalContextMode"` // Whether the task reads GlobalContext or the current context of its workflow
+	Attempt                int                      `gorm:"column:attempt;not null;default:1" json:"attempt"`                                               // Current attempt, incremented each time the task is reopened
+	History                json.RawMessage          `gorm:"type:jsonb;column:history;serializer:json" json:"history"`                                       // Archived earlier attempts ([]TaskAttempt)
+	CreatedAt              time.Time                `gorm:"type:timestamptz;column:created_at;not null" json:"createdAt"`
+	UpdatedAt              time.Time                `gorm:"type:timestamptz;column:updated_at;not null" json:"updatedAt"`
 }
 
 // TableName returns the table name for TaskInfo
@@ -31,6 +36,26 @@ func (TaskInfo) TableName() string {
 	return "task_infos"
 }
 
+// LocalStateHistoryKey is the local state key under which a reopened task can read the
+// archived attempts that preceded it.
+const LocalStateHistoryKey = "task:history"
+
+var (
+	// ErrTaskNotFailed is returned when reopening a task that has not failed.
+	ErrTaskNotFailed = errors.New("task has not failed")
+	// ErrTaskStarted is returned when discarding a task that has progressed past INITIALIZED.
+	ErrTaskStarted = errors.New("task has already started")
+)
+
+// TaskAttempt is an archived attempt of a task that was reopened after failing.
+type TaskAttempt struct {
+	Attempt     int             `json:"attempt"`
+	State       plugin.State    `json:"state"`
+	PluginState string          `json:"pluginState"`
+	LocalState  json.RawMessage `json:"localState,omitempty"`
+	EndedAt     time.Time       `json:"endedAt"`
+}
+
 // TaskStore handles database operations for task infos
 type TaskStore struct {
 	db *gorm.DB
@@ -39,6 +64,7 @@ type TaskStore struct {
 type TaskStoreInterface interface {
 	Create(*TaskInfo) error
 	GetByID(uuid.UUID) (*TaskInfo, error)
+	GetByIDs([]uuid.UUID) ([]TaskInfo, error)
 	UpdateStatus(uuid.UUID, *plugin.State) error
 	Update(*TaskInfo) error
 	Delete(uuid.UUID) error
@@ -48,6 +74,10 @@ type TaskStoreInterface interface {
 	GetLocalState(uuid.UUID) (json.RawMessage, error)
 	UpdatePluginState(uuid.UUID, string) error
 	GetPluginState(uuid.UUID) (string, error)
+	CommitStateChange(uuid.UUID, string, plugin.State, *OutboxEntry) error
+	StartNewAttempt(uuid.UUID, json.RawMessage) (*TaskInfo, error)
+	DeleteInitialized(uuid.UUID) error
+	ScheduleTimer(*TaskTimer) error
 }
 
 // NewTaskStore creates a new TaskStore with the provided database connection
@@ -73,6 +103,18 @@ func (s *TaskStore) GetByID(id uuid.UUID) (*TaskInfo, error) {
 	return &taskRecord, nil
 }
 
+// GetByIDs retrieves the task executions that exist among the given IDs
+func (s *TaskStore) GetByIDs(ids []uuid.UUID) ([]TaskInfo, error) {
+	var executions []TaskInfo
+	if len(ids) == 0 {
+		return executions, nil
+	}
+	if err := s.db.Where("id IN ?", ids).Find(&executions).Error; err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
 // UpdateStatus updates the status of a task execution
 func (s *TaskStore) UpdateStatus(id uuid.UUID, status *plugin.State) error {
 	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Update("state", &status).Error
@@ -88,6 +130,21 @@ func (s *TaskStore) Delete(id uuid.UUID) error {
 	return s.db.Delete(&TaskInfo{}, "id = ?", id).Error
 }
 
+// DeleteInitialized removes the record of a task that is still INITIALIZED.
+// Returns ErrTaskStarted if the task has progressed past INITIALIZED.
+func (s *TaskStore) DeleteInitialized(id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var taskInfo TaskInfo
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&taskInfo, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if taskInfo.State != plugin.Initialized {
+			return ErrTaskStarted
+		}
+		return tx.Delete(&TaskInfo{}, "id = ?", id).Error
+	})
+}
+
 // GetAll retrieves all task executions
 func (s *TaskStore) GetAll() ([]TaskInfo, error) {
 	var executions []TaskInfo
@@ -134,6 +191,101 @@ func (s *TaskStore) GetPluginState(id uuid.UUID) (string, error) {
 	return taskInfo.PluginState, nil
 }
 
+// CommitStateChange persists the plugin state and task state of a task execution and appends the
+// corresponding notification to the outbox within a single transaction.
+func (s *TaskStore) CommitStateChange(id uuid.UUID, pluginState string, state plugin.State, notification *OutboxEntry) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&TaskInfo{}).Where("id = ?", id).Updates(map[string]any{
+			"plugin_state": pluginState,
+			"state":        state,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update task state: %w", err)
+		}
+		if notification == nil {
+			return nil
+		}
+		if err := tx.Create(notification).Error; err != nil {
+			return fmt.Errorf("failed to append outbox entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// StartNewAttempt archives the current attempt of a FAILED task in its history and resets the
+// task to INITIALIZED with an empty plugin state, so that its plugin starts over. The local state
+// of the new attempt only holds the archived attempts, under LocalStateHistoryKey, and its
+// global context is replaced with globalContext. Returns ErrTaskNotFailed if the task has not failed.
+func (s *TaskStore) StartNewAttempt(id uuid.UUID, globalContext json.RawMessage) (*TaskInfo, error) {
+	var taskInfo TaskInfo
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&taskInfo, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if taskInfo.State != plugin.Failed {
+			return ErrTaskNotFailed
+		}
+
+		var history []TaskAttempt
+		if len(taskInfo.History) > 0 && string(taskInfo.History) != "null" {
+			if err := json.Unmarshal(taskInfo.History, &history); err != nil {
+				return fmt.Errorf("failed to unmarshal task history: %w", err)
+			}
+		}
+
+		// Drop the history carried into the failed attempt's local state so it is not archived twice
+		archivedLocalState := taskInfo.LocalState
+		localState := map[string]any{}
+		if len(taskInfo.LocalState) > 0 && string(taskInfo.LocalState) != "null" {
+			if err := json.Unmarshal(taskInfo.LocalState, &localState); err != nil {
+				return fmt.Errorf("failed to unmarshal local state: %w", err)
+			}
+			if _, ok := localState[LocalStateHistoryKey]; ok {
+				delete(localState, LocalStateHistoryKey)
+				stripped, err := json.Marshal(localState)
+				if err != nil {
+					return fmt.Errorf("failed to marshal local state: %w", err)
+				}
+				archivedLocalState = stripped
+			}
+		}
+
+		history = append(history, TaskAttempt{
+			Attempt:     taskInfo.Attempt,
+			State:       taskInfo.State,
+			PluginState: taskInfo.PluginState,
+			LocalState:  archivedLocalState,
+			EndedAt:     taskInfo.UpdatedAt,
+		})
+		historyJSON, err := json.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("failed to marshal task history: %w", err)
+		}
+		newLocalState, err := json.Marshal(map[string]any{LocalStateHistoryKey: history})
+		if err != nil {
+			return fmt.Errorf("failed to marshal local state: %w", err)
+		}
+
+		taskInfo.State = plugin.Initialized
+		taskInfo.PluginState = ""
+		taskInfo.LocalState = newLocalState
+		taskInfo.GlobalContext = globalContext
+		taskInfo.Attempt++
+		taskInfo.History = historyJSON
+		return tx.Model(&TaskInfo{}).Where("id = ?", id).Updates(map[string]any{
+			"state":          taskInfo.State,
+			"plugin_state":   taskInfo.PluginState,
+			"local_state":    taskInfo.LocalState,
+			"global_context": taskInfo.GlobalContext,
+			"attempt":        taskInfo.Attempt,
+			"history":        taskInfo.History,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &taskInfo, nil
+}
+
 // Close closes the database connection
 func (s *TaskStore) Close() error {
 	sqlDB, err := s.db.DB()
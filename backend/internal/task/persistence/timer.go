@@ -0,0 +1,74 @@
+package persistence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaskTimer is an action scheduled to be executed on a task at a given time.
+// Timers are persisted so that they fire even if the server restarts before they are due.
+type TaskTimer struct {
+	ID        int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TaskID    uuid.UUID  `gorm:"type:uuid;column:task_id;not null;index" json:"taskId"`
+	Action    string     `gorm:"type:varchar(100);column:action;not null" json:"action"` // Action executed on the task when the timer fires
+	FireAt    time.Time  `gorm:"type:timestamptz;column:fire_at;not null" json:"fireAt"`
+	Attempts  int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError *string    `gorm:"type:text;column:last_error" json:"lastError,omitempty"`
+	CreatedAt time.Time  `gorm:"type:timestamptz;column:created_at;not null" json:"createdAt"`
+	FiredAt   *time.Time `gorm:"type:timestamptz;column:fired_at" json:"firedAt,omitempty"`
+}
+
+// TableName returns the table name for TaskTimer
+func (TaskTimer) TableName() string {
+	return "task_timers"
+}
+
+// TimerStore handles database operations for scheduled task timers
+type TimerStore struct {
+	db *gorm.DB
+}
+
+type TimerStoreInterface interface {
+	GetDue(now time.Time, limit int) ([]TaskTimer, error)
+	MarkFired(id int64) error
+	RecordFailure(id int64, reason string) error
+}
+
+// NewTimerStore creates a new TimerStore with the provided database connection
+func NewTimerStore(db *gorm.DB) (*TimerStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection cannot be nil")
+	}
+
+	return &TimerStore{db: db}, nil
+}
+
+// GetDue retrieves timers that are due at now and have not fired yet, earliest first
+func (s *TimerStore) GetDue(now time.Time, limit int) ([]TaskTimer, error) {
+	var timers []TaskTimer
+	if err := s.db.Where("fired_at IS NULL AND fire_at <= ?", now).Order("fire_at ASC, id ASC").Limit(limit).Find(&timers).Error; err != nil {
+		return nil, err
+	}
+	return timers, nil
+}
+
+// MarkFired records that a timer was executed
+func (s *TimerStore) MarkFired(id int64) error {
+	return s.db.Model(&TaskTimer{}).Where("id = ?", id).Update("fired_at", time.Now().UTC()).Error
+}
+
+// RecordFailure increments the attempt counter of a timer and stores the failure reason
+func (s *TimerStore) RecordFailure(id int64, reason string) error {
+	return s.db.Model(&TaskTimer{}).Where("id = ?", id).Updates(map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": reason,
+	}).Error
+}
+
+// ScheduleTimer persists a timer for a task
+func (s *TaskStore) ScheduleTimer(timer *TaskTimer) error {
+	return s.db.Create(timer).Error
+}
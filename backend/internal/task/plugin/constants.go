@@ -6,6 +6,8 @@ type Type string
 const (
 	TaskTypeSimpleForm   Type = "SIMPLE_FORM"
 	TaskTypeWaitForEvent Type = "WAIT_FOR_EVENT"
+	TaskTypeTimer        Type = "TIMER"
+	TaskTypeSubWorkflow  Type = "SUB_WORKFLOW"
 )
 
 type State string
@@ -15,4 +17,13 @@ const (
 	InProgress  State = "IN_PROGRESS"
 	Completed   State = "COMPLETED"
 	Failed      State = "FAILED"
+	Cancelled   State = "CANCELLED"
+)
+
+// GlobalContextMode determines which global context a task reads through API.ReadGlobalContext.
+type GlobalContextMode string
+
+const (
+	GlobalContextSnapshot GlobalContextMode = "SNAPSHOT" // The context of the workflow when the task was initialized
+	GlobalContextLive     GlobalContextMode = "LIVE"     // The current context of the workflow, including values appended after the task was initialized
 )
@@ -42,6 +42,12 @@ func (f *taskFactory) BuildExecutor(ctx context.Context, taskType Type, config j
 	case TaskTypeWaitForEvent:
 		p, err := NewWaitForEventTask(config)
 		return Executor{Plugin: p, FSM: NewWaitForEventFSM()}, err
+	case TaskTypeTimer:
+		p, err := NewTimerTask(config)
+		return Executor{Plugin: p, FSM: NewTimerFSM()}, err
+	case TaskTypeSubWorkflow:
+		p, err := NewSubWorkflowTask(config)
+		return Executor{Plugin: p, FSM: NewSubWorkflowFSM()}, err
 	default:
 		return Executor{}, fmt.Errorf("unknown task type: %s", taskType)
 	}
@@ -1,6 +1,9 @@
 package plugin
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 // FSMActionStart is a conventional action name for the Plugin.Start transition.
 // Plugins are not required to use this name.
@@ -21,6 +24,14 @@ type TransitionOutcome struct {
 	NextTaskState   State
 }
 
+// TransitionRecord records an FSM transition applied to a task, for the audit history of its workflow.
+type TransitionRecord struct {
+	Action    string `json:"action"`              // Action that was applied
+	FromState string `json:"fromState"`           // Plugin state before the transition; empty before the plugin started
+	ToState   string `json:"toState"`             // Plugin state after the transition
+	TaskState State  `json:"taskState,omitempty"` // Task state the transition moved the task into, if it changed it
+}
+
 // PluginFSM is a declarative, table-driven finite state machine for plugin state
 // transitions. It is generic and plugin-agnostic; callers supply the full transition
 // table at construction time.
@@ -62,3 +73,15 @@ func (f *PluginFSM) CanTransition(currentState, action string) bool {
 	_, ok := f.transitions[TransitionKey{FromState: currentState, Action: action}]
 	return ok
 }
+
+// TaskStates returns the task-level states the FSM can move a task into, in sorted order.
+func (f *PluginFSM) TaskStates() []State {
+	var states []State
+	for _, outcome := range f.transitions {
+		if outcome.NextTaskState != "" && !slices.Contains(states, outcome.NextTaskState) {
+			states = append(states, outcome.NextTaskState)
+		}
+	}
+	slices.Sort(states)
+	return states
+}
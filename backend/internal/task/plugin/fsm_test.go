@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -168,6 +169,26 @@ func TestPluginFSM_NoTaskStateChange(t *testing.T) {
 	}
 }
 
+func TestPluginFSM_TaskStates(t *testing.T) {
+	tests := []struct {
+		name string
+		fsm  *PluginFSM
+		want []State
+	}{
+		{name: "simple form", fsm: NewSimpleFormFSM(), want: []State{Completed, Failed, InProgress}},
+		{name: "wait for event", fsm: NewWaitForEventFSM(), want: []State{Completed, InProgress}},
+		{name: "timer", fsm: NewTimerFSM(), want: []State{Completed, InProgress}},
+		{name: "no task state change", fsm: NewPluginFSM(map[TransitionKey]TransitionOutcome{{"", FSMActionStart}: {"INITIALISED", ""}}), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fsm.TaskStates(); !slices.Equal(got, tt.want) {
+				t.Errorf("TaskStates: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNewSimpleFormFSM(t *testing.T) {
 	fsm := NewSimpleFormFSM()
 
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -19,15 +20,25 @@ type API interface {
 	GetTaskID() uuid.UUID
 	GetWorkflowID() uuid.UUID
 	GetTaskState() State
+	// ReadFromGlobalStore reads key from the global context snapshot taken when the task was initialized.
 	ReadFromGlobalStore(key string) (any, bool)
+	// ReadGlobalContext returns the global context of the task according to its GlobalContextMode: the
+	// snapshot taken when the task was initialized, or the current context of its workflow. Live reads
+	// are cached briefly, so the reads of a single render or submit cost at most one lookup.
+	ReadGlobalContext(ctx context.Context) (map[string]any, error)
 	WriteToLocalStore(key string, value any) error
 	ReadFromLocalStore(key string) (any, error)
 	GetPluginState() string
 	// CanTransition reports whether action is a legal FSM transition from the current plugin state.
 	CanTransition(action string) bool
-	// Transition applies the FSM transition for action, updating and persisting both
-	// plugin state and task state. Returns an error if the action is not permitted.
+	// Transition applies the FSM transition for action, updating both plugin state and task
+	// state. The change is persisted when the current Start or Execute call returns.
+	// Returns an error if the action is not permitted.
 	Transition(action string) error
+	// ScheduleAction arranges for action to be executed on the task at the given time.
+	// Scheduled actions are persisted and survive restarts; an action that is no longer
+	// permitted by the FSM when it fires is discarded.
+	ScheduleAction(action string, at time.Time) error
 }
 
 type ExecutionRequest struct {
@@ -70,3 +81,15 @@ type Plugin interface {
 	GetRenderInfo(ctx context.Context) (*ApiResponse, error)
 	Execute(ctx context.Context, request *ExecutionRequest) (*ExecutionResponse, error)
 }
+
+// OutcomeReporter is implemented by plugins that report an outcome when they complete, so that
+// unlock conditions can be checked against the outcomes a node can actually produce.
+type OutcomeReporter interface {
+	Outcomes() []string
+}
+
+// Canceller is implemented by plugins that hand work over to an external system (e.g. an OGA)
+// and must tell that system to close it when the task is cancelled.
+type Canceller interface {
+	Cancel(ctx context.Context, reason string) error
+}
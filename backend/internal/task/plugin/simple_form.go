@@ -89,9 +89,10 @@ type Display struct {
 }
 
 type SubmissionConfig struct {
-	Url      string    `json:"url"` // URL to submit form data to
-	Request  *Request  `json:"request,omitempty"`
-	Response *Response `json:"response,omitempty"` // Expected response mapping after submission
+	Url       string    `json:"url"`                 // URL to submit form data to
+	CancelUrl string    `json:"cancelUrl,omitempty"` // URL to notify when a submitted application is withdrawn (optional)
+	Request   *Request  `json:"request,omitempty"`
+	Response  *Response `json:"response,omitempty"` // Expected response mapping after submission
 }
 
 type CallbackConfig struct {
@@ -264,6 +265,29 @@ func (s *SimpleForm) Execute(ctx context.Context, request *ExecutionRequest) (*E
 	return resp, nil
 }
 
+// Cancel tells the OGA that received this form's submission to close the application.
+// It is a no-op unless the submission is still awaiting OGA verification.
+func (s *SimpleForm) Cancel(ctx context.Context, reason string) error {
+	if SimpleFormState(s.api.GetPluginState()) != OGAAcknowledged {
+		return nil
+	}
+	if s.config.Submission == nil || s.config.Submission.CancelUrl == "" {
+		slog.WarnContext(ctx, "no cancel URL configured, OGA was not notified of cancellation",
+			"formId", s.config.FormID, "taskId", s.api.GetTaskID())
+		return nil
+	}
+
+	requestPayload := map[string]any{
+		"taskId":     s.api.GetTaskID().String(),
+		"workflowId": s.api.GetWorkflowID().String(),
+		"reason":     reason,
+	}
+	if _, err := s.sendFormSubmission(s.config.Submission.CancelUrl, requestPayload); err != nil {
+		return fmt.Errorf("failed to notify OGA of cancellation: %w", err)
+	}
+	return nil
+}
+
 // resolveAction maps the public API action string to an FSM action.
 // For actions with conditional outcomes it inspects the request to pick the right edge.
 func (s *SimpleForm) resolveAction(request *ExecutionRequest) (string, error) {
@@ -341,10 +365,6 @@ func (s *SimpleForm) submitHandler(ctx context.Context, content any) (*Execution
 		}, err
 	}
 
-	if err := s.api.WriteToLocalStore("trader:form", formData); err != nil {
-		slog.Warn("failed to write form data to local store", "error", err)
-	}
-
 	if err := s.populateFromRegistry(ctx); err != nil {
 		return &ExecutionResponse{
 			ApiResponse: &ApiResponse{
@@ -364,6 +384,23 @@ func (s *SimpleForm) submitHandler(ctx context.Context, content any) (*Execution
 		}, err
 	}
 
+	// Fields read from the global context are resolved again, so fields whose keys were appended
+	// after the form was rendered are filled in. The submitted values take priority.
+	globalContextData, err := s.readGlobalContextFields(ctx, &parsedSchema)
+	if err != nil {
+		return &ExecutionResponse{
+			ApiResponse: &ApiResponse{
+				Success: false,
+				Error:   &ApiError{Code: "INVALID_FORM_DATA", Message: "Failed to process form data."},
+			},
+		}, err
+	}
+	formData = s.mergeFormData(globalContextData, formData)
+
+	if err := s.api.WriteToLocalStore("trader:form", formData); err != nil {
+		slog.Warn("failed to write form data to local store", "error", err)
+	}
+
 	globalContextPairs := make(map[string]any)
 	err = jsonform.Traverse(&parsedSchema, func(path string, node *jsonform.JSONSchema, parent *jsonform.JSONSchema) error {
 		if node.Type == "string" || node.Type == "number" || node.Type == "boolean" {
@@ -595,7 +632,8 @@ func (s *SimpleForm) parseFormData(content interface{}) (map[string]interface{},
 	}
 }
 
-// prepopulateFormData builds formData from schema by looking up values from global store
+// prepopulateFormData builds formData from schema by looking up values from the global context of the task,
+// which is the current context of its workflow for tasks in LIVE global context mode
 func (s *SimpleForm) prepopulateFormData(ctx context.Context, existingFormData json.RawMessage) (json.RawMessage, error) {
 	// Parse the schema using JSONSchema struct
 	var parsedSchema jsonform.JSONSchema
@@ -603,27 +641,9 @@ func (s *SimpleForm) prepopulateFormData(ctx context.Context, existingFormData j
 		return nil, fmt.Errorf("failed to unmarshal schema: %w", err)
 	}
 
-	// Build formData from schema using traverse
-	formData := make(map[string]any)
-
-	err := jsonform.Traverse(&parsedSchema, func(path string, node *jsonform.JSONSchema, parent *jsonform.JSONSchema) error {
-		// Check if this field should be read from a global context
-		if node.XGlobalContext != nil &&
-			node.XGlobalContext.ReadFrom != nil &&
-			strings.TrimSpace(*node.XGlobalContext.ReadFrom) != "" {
-
-			// Lookup value from global store
-			value := s.lookupValueFromGlobalStore(ctx, *node.XGlobalContext.ReadFrom)
-			if value != nil {
-				// Set the value at the current path in formData
-				jsonform.SetValueByPath(formData, path, value)
-			}
-		}
-		return nil
-	})
-
+	formData, err := s.readGlobalContextFields(ctx, &parsedSchema)
 	if err != nil {
-		return nil, fmt.Errorf("failed to traverse schema for prepopulation: %w", err)
+		return nil, err
 	}
 
 	// If we have existing formData, merge it (existing formData takes priority)
@@ -648,8 +668,40 @@ func (s *SimpleForm) prepopulateFormData(ctx context.Context, existingFormData j
 	return prepopulatedJSON, nil
 }
 
-// lookupValueFromGlobalStore retrieves a value from global store using dot notation path
-func (s *SimpleForm) lookupValueFromGlobalStore(_ context.Context, path string) interface{} {
+// readGlobalContextFields builds formData from the fields of schema that are read from the global context of the task
+func (s *SimpleForm) readGlobalContextFields(ctx context.Context, schema *jsonform.JSONSchema) (map[string]any, error) {
+	globalContext, err := s.api.ReadGlobalContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build formData from schema using traverse
+	formData := make(map[string]any)
+
+	err = jsonform.Traverse(schema, func(path string, node *jsonform.JSONSchema, parent *jsonform.JSONSchema) error {
+		// Check if this field should be read from a global context
+		if node.XGlobalContext != nil &&
+			node.XGlobalContext.ReadFrom != nil &&
+			strings.TrimSpace(*node.XGlobalContext.ReadFrom) != "" {
+
+			// Lookup value from global context
+			value := lookupValueFromGlobalContext(globalContext, *node.XGlobalContext.ReadFrom)
+			if value != nil {
+				// Set the value at the current path in formData
+				jsonform.SetValueByPath(formData, path, value)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to traverse schema for global context fields: %w", err)
+	}
+	return formData, nil
+}
+
+// lookupValueFromGlobalContext retrieves a value from globalContext using dot notation path
+func lookupValueFromGlobalContext(globalContext map[string]any, path string) interface{} {
 	if path == "" {
 		return nil
 	}
@@ -660,8 +712,8 @@ func (s *SimpleForm) lookupValueFromGlobalStore(_ context.Context, path string)
 		return nil
 	}
 
-	// Read from global store
-	value, found := s.api.ReadFromGlobalStore(keys[0])
+	// Read from global context
+	value, found := globalContext[keys[0]]
 	if !found {
 		return nil
 	}
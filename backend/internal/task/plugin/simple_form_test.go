@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	formmodel "github.com/OpenNSW/nsw/internal/form/model"
 )
 
 // MockAPI is a mock implementation of the API interface for testing plugins
@@ -36,6 +41,14 @@ func (m *MockAPI) ReadFromGlobalStore(key string) (any, bool) {
 	return args.Get(0), args.Bool(1)
 }
 
+func (m *MockAPI) ReadGlobalContext(ctx context.Context) (map[string]any, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]any), args.Error(1)
+}
+
 func (m *MockAPI) WriteToLocalStore(key string, value any) error {
 	args := m.Called(key, value)
 	return args.Error(0)
@@ -61,6 +74,24 @@ func (m *MockAPI) Transition(action string) error {
 	return args.Error(0)
 }
 
+func (m *MockAPI) ScheduleAction(action string, at time.Time) error {
+	args := m.Called(action, at)
+	return args.Error(0)
+}
+
+// MockFormService is a mock implementation of form.FormService
+type MockFormService struct {
+	mock.Mock
+}
+
+func (m *MockFormService) GetFormByID(ctx context.Context, formID uuid.UUID) (*formmodel.FormResponse, error) {
+	args := m.Called(ctx, formID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*formmodel.FormResponse), args.Error(1)
+}
+
 func TestSimpleForm_Execute_SaveAsDraft(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		mockAPI := new(MockAPI)
@@ -149,3 +180,160 @@ func TestSimpleForm_Execute_SaveAsDraft(t *testing.T) {
 		mockAPI.AssertExpectations(t)
 	})
 }
+
+func TestSimpleForm_Execute_Submit(t *testing.T) {
+	t.Run("Resolves Global Context Fields", func(t *testing.T) {
+		ctx := context.Background()
+		mockAPI := new(MockAPI)
+		formService := new(MockFormService)
+		formID := uuid.New()
+		sf, err := NewSimpleForm(json.RawMessage(`{"formId": "`+formID.String()+`"}`), nil, formService)
+		assert.NoError(t, err)
+		sf.Init(mockAPI)
+
+		formService.On("GetFormByID", ctx, formID).Return(&formmodel.FormResponse{
+			ID: formID,
+			Schema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"exporter": {"type": "string", "x-globalContext": {"readFrom": "exporterName"}},
+					"permit":   {"type": "string", "x-globalContext": {"readFrom": "permitNo", "writeTo": "permitNo"}},
+					"quantity": {"type": "number"}
+				}
+			}`),
+		}, nil).Once()
+		// permitNo was appended after the form was rendered, so the trader did not submit it
+		mockAPI.On("ReadGlobalContext", ctx).Return(map[string]any{
+			"exporterName": "Ceylon Tea Co",
+			"permitNo":     "PH-42",
+		}, nil).Once()
+		mockAPI.On("CanTransition", simpleFormFSMSubmitComplete).Return(true).Once()
+		mockAPI.On("WriteToLocalStore", "trader:form", map[string]any{
+			"exporter": "Edited",
+			"permit":   "PH-42",
+			"quantity": float64(10),
+		}).Return(nil).Once()
+		mockAPI.On("Transition", simpleFormFSMSubmitComplete).Return(nil).Once()
+
+		resp, err := sf.Execute(ctx, &ExecutionRequest{
+			Action:  SimpleFormActionSubmit,
+			Content: map[string]any{"exporter": "Edited", "quantity": float64(10)},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"permitNo": "PH-42"}, resp.AppendGlobalContext)
+		mockAPI.AssertExpectations(t)
+		formService.AssertExpectations(t)
+	})
+}
+
+func TestSimpleForm_Cancel(t *testing.T) {
+	newForm := func(t *testing.T, cancelURL string, mockAPI *MockAPI) *SimpleForm {
+		t.Helper()
+		cfg, _ := json.Marshal(map[string]any{
+			"submission": map[string]any{"url": "http://oga.local/api/oga/inject", "cancelUrl": cancelURL},
+		})
+		sf, err := NewSimpleForm(cfg, nil, nil)
+		assert.NoError(t, err)
+		sf.Init(mockAPI)
+		return sf
+	}
+
+	t.Run("Notifies OGA Awaiting Verification", func(t *testing.T) {
+		taskID := uuid.New()
+		workflowID := uuid.New()
+		var received map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		mockAPI := new(MockAPI)
+		mockAPI.On("GetPluginState").Return(string(OGAAcknowledged))
+		mockAPI.On("GetTaskID").Return(taskID)
+		mockAPI.On("GetWorkflowID").Return(workflowID)
+
+		err := newForm(t, server.URL, mockAPI).Cancel(context.Background(), "created by mistake")
+
+		assert.NoError(t, err)
+		assert.Equal(t, taskID.String(), received["taskId"])
+		assert.Equal(t, workflowID.String(), received["workflowId"])
+		assert.Equal(t, "created by mistake", received["reason"])
+	})
+
+	t.Run("OGA Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		mockAPI := new(MockAPI)
+		mockAPI.On("GetPluginState").Return(string(OGAAcknowledged))
+		mockAPI.On("GetTaskID").Return(uuid.New())
+		mockAPI.On("GetWorkflowID").Return(uuid.New())
+
+		err := newForm(t, server.URL, mockAPI).Cancel(context.Background(), "created by mistake")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Not Yet Submitted", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("OGA must not be notified")
+		}))
+		defer server.Close()
+
+		mockAPI := new(MockAPI)
+		mockAPI.On("GetPluginState").Return(string(TraderSavedAsDraft))
+
+		err := newForm(t, server.URL, mockAPI).Cancel(context.Background(), "created by mistake")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestSimpleForm_PrepopulateFormData(t *testing.T) {
+	newForm := func(t *testing.T, mockAPI *MockAPI) *SimpleForm {
+		t.Helper()
+		cfg, _ := json.Marshal(map[string]any{
+			"schema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"exporter": map[string]any{"type": "string", "x-globalContext": map[string]any{"readFrom": "exporterName"}},
+					"permit":   map[string]any{"type": "string", "x-globalContext": map[string]any{"readFrom": "phytosanitary.permitNo"}},
+				},
+			},
+		})
+		sf, err := NewSimpleForm(cfg, nil, nil)
+		assert.NoError(t, err)
+		sf.Init(mockAPI)
+		return sf
+	}
+
+	t.Run("Reads Global Context", func(t *testing.T) {
+		ctx := context.Background()
+		mockAPI := new(MockAPI)
+		mockAPI.On("ReadGlobalContext", ctx).Return(map[string]any{
+			"exporterName":  "Ceylon Tea Co",
+			"phytosanitary": map[string]any{"permitNo": "PH-42"},
+		}, nil).Once()
+
+		formData, err := newForm(t, mockAPI).prepopulateFormData(ctx, json.RawMessage(`{"exporter": "Edited"}`))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"exporter": "Edited", "permit": "PH-42"}`, string(formData))
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Read Error", func(t *testing.T) {
+		ctx := context.Background()
+		mockAPI := new(MockAPI)
+		mockAPI.On("ReadGlobalContext", ctx).Return(nil, errors.New("db error")).Once()
+
+		formData, err := newForm(t, mockAPI).prepopulateFormData(ctx, nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, formData)
+	})
+}
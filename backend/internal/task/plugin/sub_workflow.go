@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type subWorkflowState string
+
+const (
+	subWorkflowRunning  subWorkflowState = "RUNNING"
+	subWorkflowFinished subWorkflowState = "FINISHED"
+)
+
+// SubWorkflowActionComplete is the action the Workflow Manager executes on a SUB_WORKFLOW task once its
+// child workflow finished. Its content may carry the child workflow's outcome under "outcome".
+const SubWorkflowActionComplete = "COMPLETE_SUB_WORKFLOW"
+
+// SubWorkflowConfig represents the configuration for a SUB_WORKFLOW task.
+type SubWorkflowConfig struct {
+	WorkflowTemplateID uuid.UUID         `json:"workflowTemplateId"`     // Workflow template the child workflow is created from
+	InputMapping       map[string]string `json:"inputMapping,omitempty"` // GlobalContext keys of the child workflow, mapped to the parent GlobalContext keys they are read from
+	Outcomes           []string          `json:"outcomes,omitempty"`     // Outcomes the child workflow's end node can report, so unlock conditions on them can be checked
+}
+
+// ParseSubWorkflowConfig parses and validates the configuration of a SUB_WORKFLOW task.
+func ParseSubWorkflowConfig(raw json.RawMessage) (*SubWorkflowConfig, error) {
+	var cfg SubWorkflowConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.WorkflowTemplateID == uuid.Nil {
+		return nil, fmt.Errorf("sub-workflow config must set workflowTemplateId")
+	}
+	for childKey, parentKey := range cfg.InputMapping {
+		if childKey == "" || parentKey == "" {
+			return nil, fmt.Errorf("sub-workflow input mapping must not have empty keys")
+		}
+	}
+	return &cfg, nil
+}
+
+// MapContext returns the GlobalContext the child workflow starts with: the values of globalContext
+// under the keys of InputMapping, renamed to the child's keys. Keys globalContext has no value at are left out.
+func (c *SubWorkflowConfig) MapContext(globalContext map[string]any) map[string]any {
+	childContext := make(map[string]any, len(c.InputMapping))
+	for childKey, parentKey := range c.InputMapping {
+		if value, ok := globalContext[parentKey]; ok {
+			childContext[childKey] = value
+		}
+	}
+	return childContext
+}
+
+// SubWorkflowTask stands for a child workflow created from another workflow template. The Workflow
+// Manager creates the child workflow's nodes once the task starts, and completes the task with the
+// child workflow's outcome once it finished.
+type SubWorkflowTask struct {
+	api    API
+	config SubWorkflowConfig
+}
+
+// NewSubWorkflowFSM returns the state graph for SubWorkflowTask.
+//
+// State graph:
+//
+//	""      ──START─────────────────► RUNNING  [IN_PROGRESS]
+//	RUNNING ──COMPLETE_SUB_WORKFLOW─► FINISHED [COMPLETED]
+func NewSubWorkflowFSM() *PluginFSM {
+	return NewPluginFSM(map[TransitionKey]TransitionOutcome{
+		{"", FSMActionStart}: {string(subWorkflowRunning), InProgress},
+		{string(subWorkflowRunning), SubWorkflowActionComplete}: {string(subWorkflowFinished), Completed},
+	})
+}
+
+func NewSubWorkflowTask(raw json.RawMessage) (*SubWorkflowTask, error) {
+	cfg, err := ParseSubWorkflowConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &SubWorkflowTask{config: *cfg}, nil
+}
+
+func (t *SubWorkflowTask) Init(api API) {
+	t.api = api
+}
+
+func (t *SubWorkflowTask) Start(_ context.Context) (*ExecutionResponse, error) {
+	if !t.api.CanTransition(FSMActionStart) {
+		return &ExecutionResponse{Message: "Sub-workflow already started"}, nil
+	}
+	if err := t.api.Transition(FSMActionStart); err != nil {
+		return nil, err
+	}
+	return &ExecutionResponse{Message: "Sub-workflow started from workflow template " + t.config.WorkflowTemplateID.String()}, nil
+}
+
+func (t *SubWorkflowTask) Execute(_ context.Context, request *ExecutionRequest) (*ExecutionResponse, error) {
+	if request == nil {
+		return nil, fmt.Errorf("execution request is required")
+	}
+	if request.Action != SubWorkflowActionComplete {
+		return nil, fmt.Errorf("unsupported action %q for sub-workflow task", request.Action)
+	}
+
+	var outcome *string
+	if content, ok := request.Content.(map[string]any); ok {
+		if value, ok := content["outcome"].(string); ok && value != "" {
+			outcome = &value
+		}
+	}
+	if err := t.api.Transition(SubWorkflowActionComplete); err != nil {
+		return nil, err
+	}
+	return &ExecutionResponse{Message: "Sub-workflow finished", Outcome: outcome}, nil
+}
+
+// Outcomes returns the outcomes the configuration declares for the child workflow.
+func (t *SubWorkflowTask) Outcomes() []string {
+	return t.config.Outcomes
+}
+
+func (t *SubWorkflowTask) GetRenderInfo(_ context.Context) (*ApiResponse, error) {
+	return &ApiResponse{
+		Success: true,
+		Data: GetRenderInfoResponse{
+			Type:        TaskTypeSubWorkflow,
+			PluginState: t.api.GetPluginState(),
+			State:       t.api.GetTaskState(),
+			Content:     map[string]any{"workflowTemplateId": t.config.WorkflowTemplateID},
+		},
+	}, nil
+}
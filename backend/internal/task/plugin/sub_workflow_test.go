@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSubWorkflowConfig(t *testing.T) {
+	templateID := uuid.New()
+
+	t.Run("Valid", func(t *testing.T) {
+		cfg, err := ParseSubWorkflowConfig(json.RawMessage(`{"workflowTemplateId": "` + templateID.String() + `", "inputMapping": {"commodity": "hsCode"}, "outcomes": ["APPROVED"]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, templateID, cfg.WorkflowTemplateID)
+		assert.Equal(t, map[string]string{"commodity": "hsCode"}, cfg.InputMapping)
+	})
+
+	t.Run("Missing Workflow Template", func(t *testing.T) {
+		_, err := ParseSubWorkflowConfig(json.RawMessage(`{"inputMapping": {"commodity": "hsCode"}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Empty Mapping Key", func(t *testing.T) {
+		_, err := ParseSubWorkflowConfig(json.RawMessage(`{"workflowTemplateId": "` + templateID.String() + `", "inputMapping": {"commodity": ""}}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestSubWorkflowConfig_MapContext(t *testing.T) {
+	cfg := SubWorkflowConfig{InputMapping: map[string]string{"commodity": "hsCode", "exporter": "exporterName"}}
+
+	childContext := cfg.MapContext(map[string]any{"hsCode": "0902.10", "declaredQuantityKg": 5000})
+
+	assert.Equal(t, map[string]any{"commodity": "0902.10"}, childContext)
+}
+
+func TestSubWorkflowTask_Execute(t *testing.T) {
+	raw := json.RawMessage(`{"workflowTemplateId": "` + uuid.NewString() + `"}`)
+
+	t.Run("Completes With Outcome", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewSubWorkflowTask(raw)
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+		mockAPI.On("Transition", SubWorkflowActionComplete).Return(nil).Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{
+			Action:  SubWorkflowActionComplete,
+			Content: map[string]any{"outcome": "APPROVED"},
+		})
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp.Outcome) {
+			assert.Equal(t, "APPROVED", *resp.Outcome)
+		}
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Completes Without Outcome", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewSubWorkflowTask(raw)
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+		mockAPI.On("Transition", SubWorkflowActionComplete).Return(nil).Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{Action: SubWorkflowActionComplete})
+
+		assert.NoError(t, err)
+		assert.Nil(t, resp.Outcome)
+	})
+
+	t.Run("Unsupported Action", func(t *testing.T) {
+		task, err := NewSubWorkflowTask(raw)
+		assert.NoError(t, err)
+		task.Init(new(MockAPI))
+
+		_, err = task.Execute(context.Background(), &ExecutionRequest{Action: "SUBMIT_FORM"})
+
+		assert.Error(t, err)
+	})
+}
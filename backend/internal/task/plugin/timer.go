@@ -0,0 +1,215 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type timerState string
+
+const (
+	timerWaiting timerState = "WAITING"
+	timerElapsed timerState = "ELAPSED"
+)
+
+// TimerActionElapse is the action scheduled by a TIMER task to complete itself once it is due.
+const TimerActionElapse = "ELAPSE"
+
+// timerFireAtKey is the local store key holding the time at which the timer fires (RFC 3339).
+const timerFireAtKey = "timer:fireAt"
+
+// TimerConfig represents the configuration for a TIMER task.
+// Exactly one of Duration, At and AtGlobalContextKey must be set.
+type TimerConfig struct {
+	Duration           string `json:"duration,omitempty"`           // Time to wait after the task starts, as a Go duration (e.g. "72h")
+	At                 string `json:"at,omitempty"`                 // Absolute time to wait for (RFC 3339)
+	AtGlobalContextKey string `json:"atGlobalContextKey,omitempty"` // GlobalContext key holding the absolute time to wait for (RFC 3339 or YYYY-MM-DD)
+	Outcome            string `json:"outcome,omitempty"`            // Optional outcome reported when the timer elapses
+}
+
+// TimerTask completes after a duration or at an absolute time. The time is computed when the
+// task starts and is scheduled through the API, so the task completes even across restarts.
+type TimerTask struct {
+	api      API
+	config   TimerConfig
+	duration time.Duration
+	at       time.Time
+}
+
+// NewTimerFSM returns the state graph for TimerTask.
+//
+// State graph:
+//
+//	""      ──START──► WAITING [IN_PROGRESS]
+//	WAITING ──ELAPSE─► ELAPSED [COMPLETED]
+func NewTimerFSM() *PluginFSM {
+	return NewPluginFSM(map[TransitionKey]TransitionOutcome{
+		{"", FSMActionStart}:                      {string(timerWaiting), InProgress},
+		{string(timerWaiting), TimerActionElapse}: {string(timerElapsed), Completed},
+	})
+}
+
+func NewTimerTask(raw json.RawMessage) (*TimerTask, error) {
+	var cfg TimerConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+
+	set := 0
+	for _, v := range []string{cfg.Duration, cfg.At, cfg.AtGlobalContextKey} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("timer config must set exactly one of duration, at and atGlobalContextKey")
+	}
+
+	t := &TimerTask{config: cfg}
+	if cfg.Duration != "" {
+		d, err := time.ParseDuration(cfg.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timer duration %q: %w", cfg.Duration, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("timer duration must not be negative")
+		}
+		t.duration = d
+	}
+	if cfg.At != "" {
+		at, err := time.Parse(time.RFC3339, cfg.At)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timer time %q: %w", cfg.At, err)
+		}
+		t.at = at
+	}
+	return t, nil
+}
+
+func (t *TimerTask) Init(api API) {
+	t.api = api
+}
+
+func (t *TimerTask) Start(ctx context.Context) (*ExecutionResponse, error) {
+	if !t.api.CanTransition(FSMActionStart) {
+		return &ExecutionResponse{Message: "Timer already started"}, nil
+	}
+
+	fireAt, err := t.resolveFireAt(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := t.api.WriteToLocalStore(timerFireAtKey, fireAt.Format(time.RFC3339)); err != nil {
+		return nil, fmt.Errorf("failed to store timer: %w", err)
+	}
+	if err := t.api.ScheduleAction(TimerActionElapse, fireAt); err != nil {
+		return nil, fmt.Errorf("failed to schedule timer: %w", err)
+	}
+	if err := t.api.Transition(FSMActionStart); err != nil {
+		return nil, err
+	}
+	return &ExecutionResponse{Message: "Timer started, fires at " + fireAt.Format(time.RFC3339)}, nil
+}
+
+func (t *TimerTask) Execute(_ context.Context, request *ExecutionRequest) (*ExecutionResponse, error) {
+	if request == nil {
+		return nil, fmt.Errorf("execution request is required")
+	}
+	if request.Action != TimerActionElapse {
+		return nil, fmt.Errorf("unsupported action %q for timer task", request.Action)
+	}
+
+	fireAt, err := t.fireAt()
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(fireAt) {
+		return nil, fmt.Errorf("timer has not elapsed yet, fires at %s", fireAt.Format(time.RFC3339))
+	}
+	if err := t.api.Transition(TimerActionElapse); err != nil {
+		return nil, err
+	}
+
+	resp := &ExecutionResponse{Message: "Timer elapsed"}
+	if t.config.Outcome != "" {
+		outcome := t.config.Outcome
+		resp.Outcome = &outcome
+	}
+	return resp, nil
+}
+
+// Outcomes returns the configured outcome, if any, reported when the timer elapses.
+func (t *TimerTask) Outcomes() []string {
+	if t.config.Outcome == "" {
+		return nil
+	}
+	return []string{t.config.Outcome}
+}
+
+func (t *TimerTask) GetRenderInfo(_ context.Context) (*ApiResponse, error) {
+	content := map[string]any{}
+	if fireAt, err := t.fireAt(); err == nil {
+		content["fireAt"] = fireAt.Format(time.RFC3339)
+	}
+	return &ApiResponse{
+		Success: true,
+		Data: GetRenderInfoResponse{
+			Type:        TaskTypeTimer,
+			PluginState: t.api.GetPluginState(),
+			State:       t.api.GetTaskState(),
+			Content:     content,
+		},
+	}, nil
+}
+
+// resolveFireAt computes when the timer fires for a task started at now.
+// AtGlobalContextKey is read from the global context of the task according to its GlobalContextMode.
+func (t *TimerTask) resolveFireAt(ctx context.Context, now time.Time) (time.Time, error) {
+	switch {
+	case t.config.Duration != "":
+		return now.Add(t.duration), nil
+	case t.config.At != "":
+		return t.at, nil
+	default:
+		globalContext, err := t.api.ReadGlobalContext(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		value, ok := globalContext[t.config.AtGlobalContextKey]
+		if !ok {
+			return time.Time{}, fmt.Errorf("global context key %q not found", t.config.AtGlobalContextKey)
+		}
+		return parseTimerTime(value)
+	}
+}
+
+// fireAt returns the fire time stored when the timer started.
+func (t *TimerTask) fireAt() (time.Time, error) {
+	value, err := t.api.ReadFromLocalStore(timerFireAtKey)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read timer: %w", err)
+	}
+	if value == nil {
+		return time.Time{}, errors.New("timer has not started")
+	}
+	return parseTimerTime(value)
+}
+
+// parseTimerTime parses an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC).
+func parseTimerTime(value any) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("timer time must be a string, got %T", value)
+	}
+	if at, err := time.Parse(time.RFC3339, s); err == nil {
+		return at, nil
+	}
+	at, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timer time %q: expected RFC 3339 timestamp or YYYY-MM-DD date", s)
+	}
+	return at, nil
+}
@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewTimerTask(t *testing.T) {
+	t.Run("Duration", func(t *testing.T) {
+		task, err := NewTimerTask(json.RawMessage(`{"duration": "72h"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, 72*time.Hour, task.duration)
+	})
+
+	t.Run("Absolute Time", func(t *testing.T) {
+		task, err := NewTimerTask(json.RawMessage(`{"at": "2026-11-01T09:00:00Z"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC), task.at)
+	})
+
+	t.Run("Nothing Set", func(t *testing.T) {
+		_, err := NewTimerTask(json.RawMessage(`{"outcome": "ELAPSED"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("More Than One Set", func(t *testing.T) {
+		_, err := NewTimerTask(json.RawMessage(`{"duration": "1h", "atGlobalContextKey": "arrivalDate"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid Duration", func(t *testing.T) {
+		_, err := NewTimerTask(json.RawMessage(`{"duration": "3 days"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestTimerTask_Outcomes(t *testing.T) {
+	task, err := NewTimerTask(json.RawMessage(`{"duration": "1h", "outcome": "EXPIRED"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EXPIRED"}, task.Outcomes())
+
+	task, err = NewTimerTask(json.RawMessage(`{"duration": "1h"}`))
+	assert.NoError(t, err)
+	assert.Empty(t, task.Outcomes())
+}
+
+func TestTimerTask_Start(t *testing.T) {
+	t.Run("Schedules After Duration", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"duration": "2h"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		before := time.Now().UTC()
+		mockAPI.On("CanTransition", FSMActionStart).Return(true).Once()
+		mockAPI.On("WriteToLocalStore", timerFireAtKey, mock.AnythingOfType("string")).Return(nil).Once()
+		mockAPI.On("ScheduleAction", TimerActionElapse, mock.MatchedBy(func(at time.Time) bool {
+			return !at.Before(before.Add(2*time.Hour).Truncate(time.Second)) && !at.After(time.Now().UTC().Add(2*time.Hour))
+		})).Return(nil).Once()
+		mockAPI.On("Transition", FSMActionStart).Return(nil).Once()
+
+		_, err = task.Start(context.Background())
+
+		assert.NoError(t, err)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Schedules At Global Context Date", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"atGlobalContextKey": "arrivalDate"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		fireAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+		mockAPI.On("CanTransition", FSMActionStart).Return(true).Once()
+		mockAPI.On("ReadGlobalContext", mock.Anything).Return(map[string]any{"arrivalDate": "2026-11-01"}, nil).Once()
+		mockAPI.On("WriteToLocalStore", timerFireAtKey, "2026-11-01T00:00:00Z").Return(nil).Once()
+		mockAPI.On("ScheduleAction", TimerActionElapse, fireAt).Return(nil).Once()
+		mockAPI.On("Transition", FSMActionStart).Return(nil).Once()
+
+		_, err = task.Start(context.Background())
+
+		assert.NoError(t, err)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Missing Global Context Key", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"atGlobalContextKey": "arrivalDate"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", FSMActionStart).Return(true).Once()
+		mockAPI.On("ReadGlobalContext", mock.Anything).Return(map[string]any{}, nil).Once()
+
+		_, err = task.Start(context.Background())
+
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "ScheduleAction", mock.Anything, mock.Anything)
+		mockAPI.AssertNotCalled(t, "Transition", mock.Anything)
+	})
+
+	t.Run("Global Context Read Error", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"atGlobalContextKey": "arrivalDate"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", FSMActionStart).Return(true).Once()
+		mockAPI.On("ReadGlobalContext", mock.Anything).Return(nil, errors.New("db error")).Once()
+
+		_, err = task.Start(context.Background())
+
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "ScheduleAction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already Started", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"duration": "2h"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", FSMActionStart).Return(false).Once()
+
+		_, err = task.Start(context.Background())
+
+		assert.NoError(t, err)
+		mockAPI.AssertNotCalled(t, "ScheduleAction", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTimerTask_Execute(t *testing.T) {
+	t.Run("Elapsed", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"duration": "1h", "outcome": "GRACE_PERIOD_OVER"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		mockAPI.On("ReadFromLocalStore", timerFireAtKey).Return(time.Now().UTC().Add(-time.Minute).Format(time.RFC3339), nil).Once()
+		mockAPI.On("Transition", TimerActionElapse).Return(nil).Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{Action: TimerActionElapse})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "GRACE_PERIOD_OVER", *resp.Outcome)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Not Elapsed Yet", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"duration": "1h"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		mockAPI.On("ReadFromLocalStore", timerFireAtKey).Return(time.Now().UTC().Add(time.Hour).Format(time.RFC3339), nil).Once()
+
+		_, err = task.Execute(context.Background(), &ExecutionRequest{Action: TimerActionElapse})
+
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "Transition", mock.Anything)
+	})
+
+	t.Run("Unsupported Action", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		task, err := NewTimerTask(json.RawMessage(`{"duration": "1h"}`))
+		assert.NoError(t, err)
+		task.Init(mockAPI)
+
+		_, err = task.Execute(context.Background(), &ExecutionRequest{Action: "SUBMIT_FORM"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNewTimerFSM(t *testing.T) {
+	fsm := NewTimerFSM()
+
+	outcome, err := fsm.Transition("", FSMActionStart)
+	assert.NoError(t, err)
+	assert.Equal(t, TransitionOutcome{NextPluginState: "WAITING", NextTaskState: InProgress}, outcome)
+
+	outcome, err = fsm.Transition("WAITING", TimerActionElapse)
+	assert.NoError(t, err)
+	assert.Equal(t, TransitionOutcome{NextPluginState: "ELAPSED", NextTaskState: Completed}, outcome)
+
+	assert.False(t, fsm.CanTransition("ELAPSED", TimerActionElapse))
+}
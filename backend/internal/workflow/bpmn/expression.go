@@ -0,0 +1,192 @@
+package bpmn
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// The unlock expressions built while walking the process use nil to mean "always satisfied",
+// e.g. for elements that directly follow the start event.
+
+// completedLeaf returns the expression satisfied once the given node template is COMPLETED.
+func completedLeaf(nodeTemplateID uuid.UUID) *model.UnlockExpression {
+	state := string(model.WorkflowNodeStateCompleted)
+	return &model.UnlockExpression{NodeTemplateID: nodeTemplateID, State: &state}
+}
+
+// allOf combines expressions with AND, flattening nested allOf expressions and dropping
+// duplicates and always-satisfied (nil) operands.
+func allOf(exprs ...*model.UnlockExpression) *model.UnlockExpression {
+	var children []model.UnlockExpression
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		if len(expr.AllOf) > 0 {
+			for _, child := range expr.AllOf {
+				children = appendUnique(children, child)
+			}
+			continue
+		}
+		children = appendUnique(children, *expr)
+	}
+	return collapse(children, func(c []model.UnlockExpression) *model.UnlockExpression {
+		return &model.UnlockExpression{AllOf: c}
+	})
+}
+
+// anyOf combines expressions with OR, flattening nested anyOf expressions and dropping
+// duplicates. The result is always satisfied (nil) if any operand is.
+func anyOf(exprs ...*model.UnlockExpression) *model.UnlockExpression {
+	var children []model.UnlockExpression
+	for _, expr := range exprs {
+		if expr == nil {
+			return nil
+		}
+		if len(expr.AnyOf) > 0 {
+			for _, child := range expr.AnyOf {
+				children = appendUnique(children, child)
+			}
+			continue
+		}
+		children = appendUnique(children, *expr)
+	}
+	return collapse(children, func(c []model.UnlockExpression) *model.UnlockExpression {
+		return &model.UnlockExpression{AnyOf: c}
+	})
+}
+
+func appendUnique(exprs []model.UnlockExpression, expr model.UnlockExpression) []model.UnlockExpression {
+	for _, existing := range exprs {
+		if reflect.DeepEqual(existing, expr) {
+			return exprs
+		}
+	}
+	return append(exprs, expr)
+}
+
+func collapse(children []model.UnlockExpression, wrap func([]model.UnlockExpression) *model.UnlockExpression) *model.UnlockExpression {
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return &children[0]
+	default:
+		return wrap(children)
+	}
+}
+
+// isCompletedLeaf reports whether expr only requires a single node to be COMPLETED.
+func isCompletedLeaf(expr *model.UnlockExpression) bool {
+	return expr != nil && len(expr.AnyOf) == 0 && len(expr.AllOf) == 0 && expr.Outcome == nil &&
+		expr.State != nil && *expr.State == string(model.WorkflowNodeStateCompleted)
+}
+
+// isDependsOnOnly reports whether expr can be expressed by DependsOn alone, i.e. it only
+// requires every referenced node to be COMPLETED.
+func isDependsOnOnly(expr *model.UnlockExpression) bool {
+	if isCompletedLeaf(expr) {
+		return true
+	}
+	if expr == nil || len(expr.AllOf) == 0 {
+		return false
+	}
+	for i := range expr.AllOf {
+		if !isCompletedLeaf(&expr.AllOf[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// referencedNodeTemplates returns the node template IDs referenced by expr, in order of appearance.
+func referencedNodeTemplates(expr *model.UnlockExpression) []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	var walk func(e *model.UnlockExpression)
+	walk = func(e *model.UnlockExpression) {
+		if e.NodeTemplateID != uuid.Nil && !seen[e.NodeTemplateID] {
+			seen[e.NodeTemplateID] = true
+			ids = append(ids, e.NodeTemplateID)
+		}
+		for i := range e.AnyOf {
+			walk(&e.AnyOf[i])
+		}
+		for i := range e.AllOf {
+			walk(&e.AllOf[i])
+		}
+	}
+	if expr != nil {
+		walk(expr)
+	}
+	return ids
+}
+
+var conditionTermPattern = regexp.MustCompile(`^(state|outcome)\s*==\s*(?:"([^"]+)"|'([^']+)')$`)
+
+// parseCondition parses a sequence flow condition into the state and outcome it requires of
+// the node the flow leaves from. Conditions compare the node's state and/or outcome, optionally
+// wrapped in an expression language delimiter and joined with &&, e.g.
+//
+//	${outcome == "APPROVED"}
+//	state == 'FAILED' && outcome == 'REJECTED'
+func parseCondition(text string) (state, outcome *string, err error) {
+	text = strings.TrimSpace(text)
+	if (strings.HasPrefix(text, "${") || strings.HasPrefix(text, "#{")) && strings.HasSuffix(text, "}") {
+		text = strings.TrimSpace(text[2 : len(text)-1])
+	}
+	if text == "" {
+		return nil, nil, fmt.Errorf("condition is empty")
+	}
+
+	for _, term := range strings.Split(text, "&&") {
+		match := conditionTermPattern.FindStringSubmatch(strings.TrimSpace(term))
+		if match == nil {
+			return nil, nil, fmt.Errorf("unsupported condition %q: expected comparisons of state or outcome such as outcome == \"APPROVED\", joined with &&", text)
+		}
+		value := match[2] + match[3]
+		target := &state
+		if match[1] == "outcome" {
+			target = &outcome
+		}
+		if *target != nil {
+			return nil, nil, fmt.Errorf("condition %q compares %s more than once", text, match[1])
+		}
+		*target = &value
+	}
+	return state, outcome, nil
+}
+
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseISODuration parses the day and time parts of an ISO 8601 duration (e.g. "P3D", "PT72H",
+// "P1DT12H30M"). Years, months and weeks are rejected because their length is ambiguous.
+func parseISODuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	match := isoDurationPattern.FindStringSubmatch(s)
+	if match == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("unsupported ISO 8601 duration %q: use days, hours, minutes and seconds (e.g. P3D or PT72H)", s)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: %w", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
@@ -0,0 +1,674 @@
+// Package bpmn imports BPMN 2.0 process definitions as workflow templates.
+//
+// A process is converted into a WorkflowTemplate with one WorkflowNodeTemplate per activity:
+//
+//   - userTask becomes a SIMPLE_FORM node
+//   - receiveTask becomes a WAIT_FOR_EVENT node
+//   - intermediateCatchEvent with a timerEventDefinition becomes a TIMER node
+//   - callActivity becomes a SUB_WORKFLOW node, whose config names the workflow template it calls
+//
+// Sequence flows and gateways become the nodes' DependsOn and UnlockConfiguration. A parallel
+// gateway joins its incoming branches with allOf; an exclusive gateway, or several flows entering
+// the same activity, joins them with anyOf. A condition on a flow leaving an activity, or leaving
+// an exclusive gateway that directly follows one, tests that activity's state and/or outcome
+// exactly as written, e.g. ${outcome == "APPROVED"}. The activity that leads into the end event
+// becomes the template's end node.
+//
+// Node configuration and SLAs are read from extension elements, matched by local name so any
+// namespace prefix may be used:
+//
+//	<bpmn:extensionElements>
+//	  <nsw:config>{"formId": "..."}</nsw:config>
+//	  <nsw:sla duration="72h" outcome="TIMED_OUT"/>
+//	</bpmn:extensionElements>
+//
+// Constructs the workflow engine cannot execute, such as loops, inclusive gateways or boundary
+// events, are reported as error diagnostics rather than approximated.
+package bpmn
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// DefaultVersion is the version given to imported workflow templates when Options.Version is empty.
+const DefaultVersion = "1.0"
+
+// Severity is the severity of an import diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "ERROR"   // The process cannot be imported
+	SeverityWarning Severity = "WARNING" // The process was imported but may not behave as modelled
+)
+
+// Diagnostic describes a problem found while importing a BPMN process.
+type Diagnostic struct {
+	Severity  Severity `json:"severity"`
+	ElementID string   `json:"elementId,omitempty"` // ID of the BPMN element the diagnostic refers to, if any
+	Message   string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.ElementID == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.ElementID, d.Message)
+}
+
+// Options controls how a BPMN document is imported.
+type Options struct {
+	ProcessID string    // Process to import when the document defines more than one
+	Version   string    // Version label of the created workflow template. Defaults to DefaultVersion.
+	FamilyID  uuid.UUID // Family the workflow template becomes a new version of. If zero, it starts a new family.
+}
+
+// Result is the outcome of importing a BPMN process. When HasErrors reports true the templates
+// are left empty and Diagnostics explains why.
+type Result struct {
+	WorkflowTemplate model.WorkflowTemplate       `json:"workflowTemplate"`
+	NodeTemplates    []model.WorkflowNodeTemplate `json:"nodeTemplates"`
+	NodeTemplateIDs  map[string]uuid.UUID         `json:"nodeTemplateIds"` // Node template IDs keyed by BPMN element ID
+	Diagnostics      []Diagnostic                 `json:"diagnostics"`
+}
+
+// HasErrors reports whether any error diagnostic was produced.
+func (r *Result) HasErrors() bool {
+	return hasErrors(r.Diagnostics)
+}
+
+func hasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Import parses a BPMN 2.0 document and converts one of its processes into a workflow template
+// and node templates with freshly generated IDs. It returns an error only when the document
+// cannot be read as BPMN; problems with the process itself are reported as diagnostics.
+func Import(r io.Reader, opts Options) (*Result, error) {
+	var defs definitions
+	if err := xml.NewDecoder(r).Decode(&defs); err != nil {
+		return nil, fmt.Errorf("failed to parse BPMN document: %w", err)
+	}
+
+	c := &converter{
+		nodes:             make(map[string]*element),
+		incoming:          make(map[string][]*element),
+		outgoing:          make(map[string][]*element),
+		templateIDs:       make(map[string]uuid.UUID),
+		gatewayConditions: make(map[string]gatewayCondition),
+		diagnostics:       []Diagnostic{},
+	}
+	c.proc = c.selectProcess(defs.Processes, opts.ProcessID)
+	if c.proc != nil {
+		c.convert(opts)
+	}
+
+	result := &Result{
+		NodeTemplateIDs: map[string]uuid.UUID{},
+		Diagnostics:     c.diagnostics,
+	}
+	if !c.hasErrors() {
+		result.WorkflowTemplate = c.template
+		result.NodeTemplates = c.nodeTemplates
+		result.NodeTemplateIDs = c.templateIDs
+	}
+	return result, nil
+}
+
+// activityTypes maps the BPMN activities the workflow engine can execute to their task types.
+var activityTypes = map[string]taskPlugin.Type{
+	"userTask":               taskPlugin.TaskTypeSimpleForm,
+	"receiveTask":            taskPlugin.TaskTypeWaitForEvent,
+	"intermediateCatchEvent": taskPlugin.TaskTypeTimer,
+	"callActivity":           taskPlugin.TaskTypeSubWorkflow,
+}
+
+// ignoredElements are process elements without execution semantics.
+var ignoredElements = map[string]bool{
+	"laneSet":        true,
+	"textAnnotation": true,
+	"association":    true,
+	"group":          true,
+	"category":       true,
+}
+
+// unsupportedHints explains how to replace commonly used but unsupported elements.
+var unsupportedHints = map[string]string{
+	"inclusiveGateway":  "inclusive gateways are not supported; use exclusive or parallel gateways",
+	"eventBasedGateway": "event-based gateways are not supported; use exclusive gateways with conditions on the preceding activity's outcome",
+	"complexGateway":    "complex gateways are not supported; use exclusive or parallel gateways",
+	"boundaryEvent":     "boundary events are not supported; use an sla extension element to act on deadlines",
+	"subProcess":        "sub-processes are not supported; inline the sub-process into the parent process",
+	"serviceTask":       "service tasks are not supported; use a receive task that waits for the service's callback",
+	"sendTask":          "send tasks are not supported; use a receive task that notifies the service and waits for its callback",
+}
+
+type gatewayCondition struct {
+	expr *model.UnlockExpression
+	ok   bool
+}
+
+// converter converts a single BPMN process.
+type converter struct {
+	proc              *process
+	nodes             map[string]*element   // Flow nodes by element ID
+	order             []*element            // Flow nodes in document order
+	incoming          map[string][]*element // Sequence flows by target element ID
+	outgoing          map[string][]*element // Sequence flows by source element ID
+	start             *element
+	ends              []*element
+	activities        []*element           // Activities in the order of nodeTemplates
+	templateIDs       map[string]uuid.UUID // Node template IDs by activity element ID
+	gatewayConditions map[string]gatewayCondition
+	template          model.WorkflowTemplate
+	nodeTemplates     []model.WorkflowNodeTemplate
+	diagnostics       []Diagnostic
+}
+
+func (c *converter) errorf(elementID, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{Severity: SeverityError, ElementID: elementID, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *converter) warnf(elementID, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{Severity: SeverityWarning, ElementID: elementID, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *converter) hasErrors() bool {
+	return hasErrors(c.diagnostics)
+}
+
+func (c *converter) selectProcess(processes []process, processID string) *process {
+	if processID != "" {
+		for i := range processes {
+			if processes[i].ID == processID {
+				return &processes[i]
+			}
+		}
+		c.errorf(processID, "process not found in the BPMN document")
+		return nil
+	}
+
+	switch len(processes) {
+	case 0:
+		c.errorf("", "the BPMN document defines no process")
+		return nil
+	case 1:
+		return &processes[0]
+	default:
+		ids := make([]string, len(processes))
+		for i, p := range processes {
+			ids[i] = p.ID
+		}
+		c.errorf("", "the BPMN document defines %d processes (%s); select the process to import", len(processes), strings.Join(ids, ", "))
+		return nil
+	}
+}
+
+func (c *converter) convert(opts Options) {
+	// Each step relies on the structure validated by the previous ones, so stop at the first
+	// step that reports errors rather than piling up follow-on diagnostics.
+	steps := []func(){c.index, c.validateElements, c.checkReachability, c.buildNodeTemplates, c.deriveUnlockConditions, c.deriveEndNode}
+	for _, step := range steps {
+		step()
+		if c.hasErrors() {
+			return
+		}
+	}
+
+	version := opts.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+	name := c.proc.Name
+	if name == "" {
+		name = c.proc.ID
+	}
+	nodeTemplateIDs := make(model.UUIDArray, len(c.nodeTemplates))
+	for i, nt := range c.nodeTemplates {
+		nodeTemplateIDs[i] = nt.ID
+	}
+	c.template.ID = uuid.New()
+	c.template.Name = name
+	c.template.Description = strings.TrimSpace(c.proc.Documentation)
+	c.template.Version = version
+	c.template.NodeTemplates = nodeTemplateIDs
+	c.template.FamilyID = opts.FamilyID
+	c.template.Status = model.WorkflowTemplateStatusDraft
+}
+
+// index collects the flow nodes and sequence flows of the process and rejects unsupported elements.
+func (c *converter) index() {
+	rejected := make(map[string]bool)
+	var flows []*element
+
+	for i := range c.proc.Elements {
+		el := &c.proc.Elements[i]
+		kind := el.kind()
+		switch {
+		case kind == "sequenceFlow":
+			flows = append(flows, el)
+		case isFlowNode(kind):
+			if el.ID == "" {
+				c.errorf("", "%s has no id", kind)
+				continue
+			}
+			if _, exists := c.nodes[el.ID]; exists {
+				c.errorf(el.ID, "duplicate element id")
+				continue
+			}
+			c.nodes[el.ID] = el
+			c.order = append(c.order, el)
+		case ignoredElements[kind]:
+		default:
+			rejected[el.ID] = true
+			if hint, ok := unsupportedHints[kind]; ok {
+				c.errorf(el.ID, "%s", hint)
+			} else {
+				c.errorf(el.ID, "%s elements are not supported; supported elements are startEvent, endEvent, userTask, receiveTask, "+
+					"timer intermediateCatchEvent, callActivity, exclusiveGateway, parallelGateway and sequenceFlow", kind)
+			}
+		}
+	}
+
+	for _, flow := range flows {
+		if rejected[flow.SourceRef] || rejected[flow.TargetRef] {
+			continue
+		}
+		if c.nodes[flow.SourceRef] == nil || c.nodes[flow.TargetRef] == nil {
+			c.errorf(flow.ID, "sequence flow connects unknown elements %q and %q", flow.SourceRef, flow.TargetRef)
+			continue
+		}
+		c.outgoing[flow.SourceRef] = append(c.outgoing[flow.SourceRef], flow)
+		c.incoming[flow.TargetRef] = append(c.incoming[flow.TargetRef], flow)
+	}
+}
+
+func isFlowNode(kind string) bool {
+	switch kind {
+	case "startEvent", "endEvent", "exclusiveGateway", "parallelGateway":
+		return true
+	}
+	_, ok := activityTypes[kind]
+	return ok
+}
+
+func isGateway(el *element) bool {
+	return el.kind() == "exclusiveGateway" || el.kind() == "parallelGateway"
+}
+
+// validateElements checks each flow node against what the workflow engine can execute.
+func (c *converter) validateElements() {
+	activities := 0
+	for _, el := range c.order {
+		in, out := c.incoming[el.ID], c.outgoing[el.ID]
+
+		switch el.kind() {
+		case "startEvent":
+			if c.start != nil {
+				c.errorf(el.ID, "only one start event is supported")
+			}
+			c.start = el
+			if defs := el.eventDefinitions(); len(defs) > 0 {
+				c.errorf(el.ID, "%s start events are not supported; only none start events are", defs[0])
+			}
+			if len(in) > 0 {
+				c.errorf(el.ID, "start event has incoming sequence flows")
+			}
+			if len(out) == 0 {
+				c.errorf(el.ID, "start event has no outgoing sequence flow")
+			}
+		case "endEvent":
+			c.ends = append(c.ends, el)
+			if defs := el.eventDefinitions(); len(defs) > 0 {
+				c.errorf(el.ID, "%s end events are not supported; only none end events are", defs[0])
+			}
+			if len(out) > 0 {
+				c.errorf(el.ID, "end event has outgoing sequence flows")
+			}
+		case "exclusiveGateway", "parallelGateway":
+			if len(in) == 0 || len(out) == 0 {
+				c.errorf(el.ID, "gateway needs both incoming and outgoing sequence flows")
+			}
+			c.validateGatewayFlows(el, out)
+		default:
+			activities++
+			if el.child("multiInstanceLoopCharacteristics") != nil || el.child("standardLoopCharacteristics") != nil {
+				c.errorf(el.ID, "loop and multi-instance activities are not supported")
+			}
+			if el.kind() == "intermediateCatchEvent" {
+				if defs := el.eventDefinitions(); len(defs) != 1 || defs[0] != "timerEventDefinition" {
+					c.errorf(el.ID, "only timer intermediate catch events are supported; use a receive task to wait for messages")
+				}
+			}
+		}
+	}
+
+	if c.start == nil {
+		c.errorf(c.proc.ID, "process has no start event")
+	}
+	if len(c.ends) == 0 {
+		c.errorf(c.proc.ID, "process has no end event")
+	}
+	if activities == 0 {
+		c.errorf(c.proc.ID, "process has no userTask, receiveTask, callActivity or timer event to import")
+	}
+}
+
+func (c *converter) validateGatewayFlows(gateway *element, out []*element) {
+	for _, flow := range out {
+		switch {
+		case gateway.kind() == "parallelGateway" && flow.ConditionExpression != nil:
+			c.errorf(flow.ID, "sequence flows leaving a parallel gateway cannot have conditions")
+		case gateway.kind() == "exclusiveGateway" && len(out) > 1 && gateway.Default == flow.ID:
+			c.errorf(flow.ID, "default flows are not supported; give the flow an explicit condition")
+		case gateway.kind() == "exclusiveGateway" && len(out) > 1 && flow.ConditionExpression == nil:
+			c.errorf(flow.ID, "every sequence flow leaving a diverging exclusive gateway needs a condition")
+		}
+	}
+}
+
+// checkReachability rejects loops and elements that cannot be reached from the start event.
+// Workflow nodes run at most once, so a loop in the process cannot be executed.
+func (c *converter) checkReachability() {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onPath
+		for _, flow := range c.outgoing[id] {
+			switch state[flow.TargetRef] {
+			case unvisited:
+				visit(flow.TargetRef)
+			case onPath:
+				c.errorf(flow.ID, "sequence flow loops back to %s; loops are not supported because workflow nodes run once (reopen failed nodes instead)", flow.TargetRef)
+			}
+		}
+		state[id] = done
+	}
+	visit(c.start.ID)
+
+	for _, el := range c.order {
+		if state[el.ID] == unvisited {
+			c.errorf(el.ID, "element is not reachable from the start event")
+		}
+	}
+}
+
+// buildNodeTemplates creates a node template for every activity.
+func (c *converter) buildNodeTemplates() {
+	for _, el := range c.order {
+		taskType, ok := activityTypes[el.kind()]
+		if !ok {
+			continue
+		}
+
+		id := uuid.New()
+		c.activities = append(c.activities, el)
+		c.templateIDs[el.ID] = id
+		name := el.Name
+		if name == "" {
+			name = el.ID
+		}
+		c.nodeTemplates = append(c.nodeTemplates, model.WorkflowNodeTemplate{
+			BaseModel:   model.BaseModel{ID: id},
+			Name:        name,
+			Description: strings.TrimSpace(el.Documentation),
+			Type:        taskType,
+			Config:      c.nodeConfig(el, taskType),
+			DependsOn:   model.UUIDArray{},
+			SLA:         c.nodeSLA(el),
+		})
+	}
+}
+
+// nodeConfig returns the task configuration of an activity from its config extension element.
+// Timer events without one are configured from their timer definition.
+func (c *converter) nodeConfig(el *element, taskType taskPlugin.Type) json.RawMessage {
+	ext := el.extension("config")
+	if ext == nil {
+		if taskType == taskPlugin.TaskTypeTimer {
+			return c.timerConfig(el)
+		}
+		c.errorf(el.ID, "%s has no config extension element with its %s configuration", el.kind(), taskType)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	var fields map[string]any
+	raw := []byte(strings.TrimSpace(ext.Text))
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		c.errorf(el.ID, "config extension element is not a JSON object: %v", err)
+		return nil
+	}
+	if err := json.Compact(&buf, raw); err != nil {
+		c.errorf(el.ID, "config extension element is not valid JSON: %v", err)
+		return nil
+	}
+	if taskType == taskPlugin.TaskTypeTimer {
+		if _, err := taskPlugin.NewTimerTask(buf.Bytes()); err != nil {
+			c.errorf(el.ID, "invalid TIMER config: %v", err)
+			return nil
+		}
+	}
+	if taskType == taskPlugin.TaskTypeSubWorkflow {
+		if _, err := taskPlugin.NewSubWorkflowTask(buf.Bytes()); err != nil {
+			c.errorf(el.ID, "invalid SUB_WORKFLOW config: %v", err)
+			return nil
+		}
+	}
+	return buf.Bytes()
+}
+
+// timerConfig converts a timerEventDefinition into a TIMER configuration.
+func (c *converter) timerConfig(el *element) json.RawMessage {
+	def := el.child("timerEventDefinition")
+	var cfg taskPlugin.TimerConfig
+	switch {
+	case def.child("timeDuration") != nil:
+		d, err := parseISODuration(def.child("timeDuration").Text)
+		if err != nil {
+			c.errorf(el.ID, "%v", err)
+			return nil
+		}
+		cfg.Duration = d.String()
+	case def.child("timeDate") != nil:
+		at, err := time.Parse(time.RFC3339, strings.TrimSpace(def.child("timeDate").Text))
+		if err != nil {
+			c.errorf(el.ID, "timeDate must be an RFC 3339 date-time: %v", err)
+			return nil
+		}
+		cfg.At = at.UTC().Format(time.RFC3339)
+	case def.child("timeCycle") != nil:
+		c.errorf(el.ID, "timer cycles are not supported; timers fire once")
+		return nil
+	default:
+		c.errorf(el.ID, "timer has neither a timeDuration nor a timeDate")
+		return nil
+	}
+
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		c.errorf(el.ID, "failed to encode timer config: %v", err)
+		return nil
+	}
+	return config
+}
+
+// nodeSLA returns the SLA of an activity from its sla extension element, if any. The duration
+// may be a Go duration ("72h") or an ISO 8601 duration ("P3D").
+func (c *converter) nodeSLA(el *element) *model.SLAConfig {
+	ext := el.extension("sla")
+	if ext == nil {
+		return nil
+	}
+
+	sla := &model.SLAConfig{Duration: ext.attr("duration"), Outcome: ext.attr("outcome")}
+	if strings.HasPrefix(sla.Duration, "P") {
+		d, err := parseISODuration(sla.Duration)
+		if err != nil {
+			c.errorf(el.ID, "invalid SLA: %v", err)
+			return nil
+		}
+		sla.Duration = d.String()
+	}
+	if err := sla.Validate(); err != nil {
+		c.errorf(el.ID, "%v", err)
+		return nil
+	}
+	return sla
+}
+
+// deriveUnlockConditions sets DependsOn and, where DependsOn alone cannot express it, the
+// UnlockConfiguration of every node template from the flows leading into its activity.
+func (c *converter) deriveUnlockConditions() {
+	for i := range c.nodeTemplates {
+		nt := &c.nodeTemplates[i]
+		el := c.activities[i]
+
+		expr, ok := c.elementCondition(el)
+		if !ok || expr == nil {
+			continue
+		}
+
+		nt.DependsOn = referencedNodeTemplates(expr)
+		if isDependsOnOnly(expr) {
+			continue
+		}
+		unlockConfig := &model.UnlockConfig{Expression: expr}
+		if err := unlockConfig.Validate(); err != nil {
+			c.errorf(el.ID, "derived unlock configuration is invalid: %v", err)
+			continue
+		}
+		nt.UnlockConfiguration = unlockConfig
+	}
+}
+
+// deriveEndNode sets the template's end node to the activity that leads into the end event.
+func (c *converter) deriveEndNode() {
+	var exprs []*model.UnlockExpression
+	for _, end := range c.ends {
+		expr, ok := c.elementCondition(end)
+		if !ok {
+			return
+		}
+		exprs = append(exprs, expr)
+	}
+	expr := anyOf(exprs...)
+
+	endID := c.proc.ID
+	if len(c.ends) == 1 {
+		endID = c.ends[0].ID
+	}
+
+	switch {
+	case isCompletedLeaf(expr):
+		id := expr.NodeTemplateID
+		c.template.EndNodeTemplateID = &id
+	case expr == nil:
+		c.warnf(endID, "the end event can be reached without completing any activity; the workflow completes once all of its nodes are completed")
+	case isDependsOnOnly(expr):
+		c.warnf(endID, "the end event joins several activities; the workflow completes once all of its nodes are completed. "+
+			"Lead the branches into a single final activity to mark the end explicitly")
+	default:
+		c.errorf(endID, "the end is reached on alternative paths, which a workflow template cannot represent: without a single end "+
+			"node the workflow completes only when all of its nodes are completed, so a branch that is not taken would keep it open. "+
+			"Lead all paths into a single final activity before the end event")
+	}
+}
+
+// elementCondition returns the condition under which a token reaches el through any of its
+// incoming sequence flows. ok is false if an error diagnostic was reported.
+func (c *converter) elementCondition(el *element) (expr *model.UnlockExpression, ok bool) {
+	var exprs []*model.UnlockExpression
+	for _, flow := range c.incoming[el.ID] {
+		expr, ok := c.flowCondition(flow)
+		if !ok {
+			return nil, false
+		}
+		exprs = append(exprs, expr)
+	}
+	return anyOf(exprs...), true
+}
+
+// flowCondition returns the condition under which a token travels along a sequence flow.
+func (c *converter) flowCondition(flow *element) (*model.UnlockExpression, bool) {
+	base, ok := c.sourceCondition(c.nodes[flow.SourceRef])
+	if !ok || flow.ConditionExpression == nil {
+		return base, ok
+	}
+
+	state, outcome, err := parseCondition(*flow.ConditionExpression)
+	if err != nil {
+		c.errorf(flow.ID, "%v", err)
+		return nil, false
+	}
+	// The condition tests the activity the flow, or the exclusive gateway it leaves, follows.
+	if !isCompletedLeaf(base) {
+		c.errorf(flow.ID, "the condition cannot be attributed to a single activity; conditional flows must leave an activity "+
+			"or an exclusive gateway that directly follows one")
+		return nil, false
+	}
+	return &model.UnlockExpression{NodeTemplateID: base.NodeTemplateID, State: state, Outcome: outcome}, true
+}
+
+// sourceCondition returns the condition under which el emits tokens on its outgoing flows.
+func (c *converter) sourceCondition(el *element) (*model.UnlockExpression, bool) {
+	switch {
+	case el.kind() == "startEvent":
+		return nil, true
+	case isGateway(el):
+		return c.gatewayCondition(el)
+	default:
+		return completedLeaf(c.templateIDs[el.ID]), true
+	}
+}
+
+// gatewayCondition joins the conditions of a gateway's incoming flows: all of them for a
+// parallel gateway, any of them for an exclusive gateway.
+func (c *converter) gatewayCondition(gateway *element) (*model.UnlockExpression, bool) {
+	if cached, ok := c.gatewayConditions[gateway.ID]; ok {
+		return cached.expr, cached.ok
+	}
+
+	var result gatewayCondition
+	var exprs []*model.UnlockExpression
+	result.ok = true
+	for _, flow := range c.incoming[gateway.ID] {
+		expr, ok := c.flowCondition(flow)
+		if !ok {
+			result.ok = false
+			break
+		}
+		exprs = append(exprs, expr)
+	}
+	if result.ok {
+		if gateway.kind() == "parallelGateway" {
+			result.expr = allOf(exprs...)
+		} else {
+			result.expr = anyOf(exprs...)
+		}
+	}
+
+	c.gatewayConditions[gateway.ID] = result
+	return result.expr, result.ok
+}
@@ -0,0 +1,386 @@
+package bpmn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func bpmnDocument(processes ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:nsw="urn:nsw">` +
+		strings.Join(processes, "") + `</bpmn:definitions>`
+}
+
+func formTask(id string) string {
+	return `<bpmn:userTask id="` + id + `" name="Task ` + id + `">
+  <bpmn:extensionElements><nsw:config>{"formId": "form-` + id + `"}</nsw:config></bpmn:extensionElements>
+</bpmn:userTask>`
+}
+
+func flow(id, source, target string, condition ...string) string {
+	if len(condition) == 0 {
+		return `<bpmn:sequenceFlow id="` + id + `" sourceRef="` + source + `" targetRef="` + target + `"/>`
+	}
+	return `<bpmn:sequenceFlow id="` + id + `" sourceRef="` + source + `" targetRef="` + target + `">` +
+		`<bpmn:conditionExpression>` + condition[0] + `</bpmn:conditionExpression></bpmn:sequenceFlow>`
+}
+
+func importProcess(t *testing.T, elements ...string) *Result {
+	t.Helper()
+	doc := bpmnDocument(`<bpmn:process id="export" name="Export Clearance">` + strings.Join(elements, "\n") + `</bpmn:process>`)
+	result, err := Import(strings.NewReader(doc), Options{})
+	require.NoError(t, err)
+	return result
+}
+
+func nodeTemplate(t *testing.T, result *Result, elementID string) model.WorkflowNodeTemplate {
+	t.Helper()
+	id, ok := result.NodeTemplateIDs[elementID]
+	require.True(t, ok, "no node template for %s", elementID)
+	for _, nt := range result.NodeTemplates {
+		if nt.ID == id {
+			return nt
+		}
+	}
+	t.Fatalf("node template %s not in result", id)
+	return model.WorkflowNodeTemplate{}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestImport(t *testing.T) {
+	result := importProcess(t,
+		`<bpmn:startEvent id="start"/>`,
+		formTask("declaration"),
+		`<bpmn:parallelGateway id="fork"/>`,
+		`<bpmn:receiveTask id="inspection" name="Inspection">
+		  <bpmn:documentation>Waits for the inspection result</bpmn:documentation>
+		  <bpmn:extensionElements>
+		    <nsw:config>{"externalServiceUrl": "http://inspection"}</nsw:config>
+		    <nsw:sla duration="P2D" outcome="OVERDUE"/>
+		  </bpmn:extensionElements>
+		</bpmn:receiveTask>`,
+		`<bpmn:intermediateCatchEvent id="cooling"><bpmn:timerEventDefinition><bpmn:timeDuration>PT72H</bpmn:timeDuration></bpmn:timerEventDefinition></bpmn:intermediateCatchEvent>`,
+		`<bpmn:parallelGateway id="join"/>`,
+		formTask("review"),
+		`<bpmn:exclusiveGateway id="decision"/>`,
+		formTask("release"),
+		formTask("appeal"),
+		`<bpmn:endEvent id="end"/>`,
+		flow("f1", "start", "declaration"),
+		flow("f2", "declaration", "fork"),
+		flow("f3", "fork", "inspection"),
+		flow("f4", "fork", "cooling"),
+		flow("f5", "inspection", "join"),
+		flow("f6", "cooling", "join"),
+		flow("f7", "join", "review"),
+		flow("f8", "review", "decision"),
+		flow("f9", "decision", "release", `${outcome == "APPROVED"}`),
+		flow("f10", "decision", "appeal", `${outcome == 'REJECTED'}`),
+		flow("f11", "release", "end"),
+	)
+
+	require.False(t, result.HasErrors(), "%v", result.Diagnostics)
+	assert.Empty(t, result.Diagnostics)
+
+	wt := result.WorkflowTemplate
+	assert.NotEqual(t, uuid.Nil, wt.ID)
+	assert.Equal(t, "Export Clearance", wt.Name)
+	assert.Equal(t, DefaultVersion, wt.Version)
+	assert.Len(t, wt.NodeTemplates, 6)
+	require.NotNil(t, wt.EndNodeTemplateID)
+	assert.Equal(t, result.NodeTemplateIDs["release"], *wt.EndNodeTemplateID)
+
+	declaration := nodeTemplate(t, result, "declaration")
+	assert.Equal(t, taskPlugin.TaskTypeSimpleForm, declaration.Type)
+	assert.JSONEq(t, `{"formId": "form-declaration"}`, string(declaration.Config))
+	assert.Empty(t, declaration.DependsOn)
+	assert.Nil(t, declaration.UnlockConfiguration)
+
+	inspection := nodeTemplate(t, result, "inspection")
+	assert.Equal(t, taskPlugin.TaskTypeWaitForEvent, inspection.Type)
+	assert.Equal(t, "Waits for the inspection result", inspection.Description)
+	assert.Equal(t, model.UUIDArray{declaration.ID}, inspection.DependsOn)
+	assert.Equal(t, &model.SLAConfig{Duration: "48h0m0s", Outcome: "OVERDUE"}, inspection.SLA)
+
+	cooling := nodeTemplate(t, result, "cooling")
+	assert.Equal(t, taskPlugin.TaskTypeTimer, cooling.Type)
+	assert.JSONEq(t, `{"duration": "72h0m0s"}`, string(cooling.Config))
+	assert.Equal(t, model.UUIDArray{declaration.ID}, cooling.DependsOn)
+
+	review := nodeTemplate(t, result, "review")
+	assert.Equal(t, model.UUIDArray{inspection.ID, cooling.ID}, review.DependsOn)
+	assert.Nil(t, review.UnlockConfiguration, "an AND of completed nodes is expressed by DependsOn alone")
+
+	release := nodeTemplate(t, result, "release")
+	assert.Equal(t, model.UUIDArray{review.ID}, release.DependsOn)
+	require.NotNil(t, release.UnlockConfiguration)
+	assert.Equal(t, &model.UnlockExpression{NodeTemplateID: review.ID, Outcome: strPtr("APPROVED")}, release.UnlockConfiguration.Expression)
+
+	appeal := nodeTemplate(t, result, "appeal")
+	require.NotNil(t, appeal.UnlockConfiguration)
+	assert.Equal(t, &model.UnlockExpression{NodeTemplateID: review.ID, Outcome: strPtr("REJECTED")}, appeal.UnlockConfiguration.Expression)
+}
+
+func TestImport_ExclusiveMerge(t *testing.T) {
+	result := importProcess(t,
+		`<bpmn:startEvent id="start"/>`,
+		formTask("declaration"),
+		formTask("fastTrack"),
+		formTask("inspection"),
+		`<bpmn:exclusiveGateway id="merge"/>`,
+		formTask("release"),
+		`<bpmn:endEvent id="end"/>`,
+		flow("f1", "start", "declaration"),
+		flow("f2", "declaration", "fastTrack", `outcome == "LOW_RISK"`),
+		flow("f3", "declaration", "inspection", `state == "COMPLETED" &amp;&amp; outcome == "HIGH_RISK"`),
+		flow("f4", "fastTrack", "merge"),
+		flow("f5", "inspection", "merge"),
+		flow("f6", "merge", "release"),
+		flow("f7", "release", "end"),
+	)
+
+	require.False(t, result.HasErrors(), "%v", result.Diagnostics)
+
+	declaration := nodeTemplate(t, result, "declaration")
+	inspection := nodeTemplate(t, result, "inspection")
+	assert.Equal(t, &model.UnlockExpression{NodeTemplateID: declaration.ID, State: strPtr("COMPLETED"), Outcome: strPtr("HIGH_RISK")},
+		inspection.UnlockConfiguration.Expression)
+
+	fastTrack := nodeTemplate(t, result, "fastTrack")
+	release := nodeTemplate(t, result, "release")
+	assert.Equal(t, model.UUIDArray{fastTrack.ID, inspection.ID}, release.DependsOn)
+	require.NotNil(t, release.UnlockConfiguration)
+	assert.Equal(t, &model.UnlockExpression{AnyOf: []model.UnlockExpression{
+		*completedLeaf(fastTrack.ID),
+		*completedLeaf(inspection.ID),
+	}}, release.UnlockConfiguration.Expression)
+}
+
+func TestImport_CallActivity(t *testing.T) {
+	result := importProcess(t,
+		`<bpmn:startEvent id="start"/>`,
+		formTask("declaration"),
+		`<bpmn:callActivity id="inspection" name="OGA Inspection and Certificate">
+		  <bpmn:extensionElements>
+		    <nsw:config>{"workflowTemplateId": "22222222-2222-2222-2222-222222222222", "inputMapping": {"commodity": "hsCode"}, "outcomes": ["APPROVED", "REJECTED"]}</nsw:config>
+		  </bpmn:extensionElements>
+		</bpmn:callActivity>`,
+		formTask("release"),
+		`<bpmn:endEvent id="end"/>`,
+		flow("f1", "start", "declaration"),
+		flow("f2", "declaration", "inspection"),
+		flow("f3", "inspection", "release", `${outcome == "APPROVED"}`),
+		flow("f4", "release", "end"),
+	)
+
+	require.False(t, result.HasErrors(), "%v", result.Diagnostics)
+
+	inspection := nodeTemplate(t, result, "inspection")
+	assert.Equal(t, taskPlugin.TaskTypeSubWorkflow, inspection.Type)
+	assert.JSONEq(t, `{"workflowTemplateId": "22222222-2222-2222-2222-222222222222", "inputMapping": {"commodity": "hsCode"}, "outcomes": ["APPROVED", "REJECTED"]}`,
+		string(inspection.Config))
+
+	release := nodeTemplate(t, result, "release")
+	require.NotNil(t, release.UnlockConfiguration)
+	assert.Equal(t, &model.UnlockExpression{NodeTemplateID: inspection.ID, Outcome: strPtr("APPROVED")}, release.UnlockConfiguration.Expression)
+}
+
+func TestImport_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name      string
+		elements  []string
+		elementID string
+		message   string
+	}{
+		{
+			name: "Inclusive Gateway",
+			elements: []string{`<bpmn:startEvent id="start"/>`, `<bpmn:inclusiveGateway id="or"/>`, formTask("a"), `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "or"), flow("f2", "or", "a"), flow("f3", "a", "end")},
+			elementID: "or",
+			message:   "inclusive gateways are not supported",
+		},
+		{
+			name: "Unknown Element",
+			elements: []string{`<bpmn:startEvent id="start"/>`, `<bpmn:scriptTask id="script"/>`, formTask("a"), `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "a"), flow("f2", "a", "end")},
+			elementID: "script",
+			message:   "scriptTask elements are not supported",
+		},
+		{
+			name: "Loop",
+			elements: []string{`<bpmn:startEvent id="start"/>`, `<bpmn:exclusiveGateway id="merge"/>`, formTask("a"), `<bpmn:exclusiveGateway id="split"/>`, `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "merge"), flow("f2", "merge", "a"), flow("f3", "a", "split"),
+				flow("f4", "split", "merge", `${outcome == "REJECTED"}`), flow("f5", "split", "end", `${outcome == "APPROVED"}`)},
+			elementID: "f4",
+			message:   "loops are not supported",
+		},
+		{
+			name: "Missing Config",
+			elements: []string{`<bpmn:startEvent id="start"/>`, `<bpmn:userTask id="a"/>`, `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "a"), flow("f2", "a", "end")},
+			elementID: "a",
+			message:   "no config extension element",
+		},
+		{
+			name: "Default Flow",
+			elements: []string{`<bpmn:startEvent id="start"/>`, formTask("a"), `<bpmn:exclusiveGateway id="split" default="f4"/>`, formTask("b"), formTask("c"), `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "a"), flow("f2", "a", "split"), flow("f3", "split", "b", `${outcome == "OK"}`), flow("f4", "split", "c"), flow("f5", "b", "end")},
+			elementID: "f4",
+			message:   "default flows are not supported",
+		},
+		{
+			name: "Unsupported Condition",
+			elements: []string{`<bpmn:startEvent id="start"/>`, formTask("a"), formTask("b"), `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "a"), flow("f2", "a", "b", `${amount &gt; 100}`), flow("f3", "b", "end")},
+			elementID: "f2",
+			message:   "unsupported condition",
+		},
+		{
+			name: "Condition After Join",
+			elements: []string{`<bpmn:startEvent id="start"/>`, `<bpmn:parallelGateway id="fork"/>`, formTask("a"), formTask("b"), `<bpmn:parallelGateway id="join"/>`,
+				`<bpmn:exclusiveGateway id="split"/>`, formTask("c"), formTask("d"), `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "fork"), flow("f2", "fork", "a"), flow("f3", "fork", "b"), flow("f4", "a", "join"), flow("f5", "b", "join"),
+				flow("f6", "join", "split"), flow("f7", "split", "c", `${outcome == "X"}`), flow("f8", "split", "d", `${outcome == "Y"}`), flow("f9", "c", "end")},
+			elementID: "f7",
+			message:   "cannot be attributed to a single activity",
+		},
+		{
+			name: "Alternative Ends",
+			elements: []string{`<bpmn:startEvent id="start"/>`, formTask("a"), formTask("b"), formTask("c"), `<bpmn:endEvent id="approved"/>`, `<bpmn:endEvent id="rejected"/>`,
+				flow("f1", "start", "a"), flow("f2", "a", "b", `${outcome == "OK"}`), flow("f3", "a", "c", `${outcome == "NOK"}`),
+				flow("f4", "b", "approved"), flow("f5", "c", "rejected")},
+			elementID: "export",
+			message:   "alternative paths",
+		},
+		{
+			name: "Timer Cycle",
+			elements: []string{`<bpmn:startEvent id="start"/>`, `<bpmn:intermediateCatchEvent id="t"><bpmn:timerEventDefinition><bpmn:timeCycle>R3/PT1H</bpmn:timeCycle></bpmn:timerEventDefinition></bpmn:intermediateCatchEvent>`,
+				`<bpmn:endEvent id="end"/>`, flow("f1", "start", "t"), flow("f2", "t", "end")},
+			elementID: "t",
+			message:   "timer cycles are not supported",
+		},
+		{
+			name: "Call Activity Without Workflow Template",
+			elements: []string{`<bpmn:startEvent id="start"/>`,
+				`<bpmn:callActivity id="inspection"><bpmn:extensionElements><nsw:config>{"inputMapping": {"commodity": "hsCode"}}</nsw:config></bpmn:extensionElements></bpmn:callActivity>`,
+				`<bpmn:endEvent id="end"/>`, flow("f1", "start", "inspection"), flow("f2", "inspection", "end")},
+			elementID: "inspection",
+			message:   "invalid SUB_WORKFLOW config",
+		},
+		{
+			name: "Unreachable Activity",
+			elements: []string{`<bpmn:startEvent id="start"/>`, formTask("a"), formTask("orphan"), `<bpmn:endEvent id="end"/>`,
+				flow("f1", "start", "a"), flow("f2", "a", "end")},
+			elementID: "orphan",
+			message:   "not reachable from the start event",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := importProcess(t, tt.elements...)
+
+			require.True(t, result.HasErrors())
+			assert.Empty(t, result.NodeTemplates)
+			found := false
+			for _, d := range result.Diagnostics {
+				if d.Severity == SeverityError && d.ElementID == tt.elementID && strings.Contains(d.Message, tt.message) {
+					found = true
+				}
+			}
+			assert.True(t, found, "expected error %q on %s, got %v", tt.message, tt.elementID, result.Diagnostics)
+		})
+	}
+}
+
+func TestImport_JoinIntoEndWarns(t *testing.T) {
+	result := importProcess(t,
+		`<bpmn:startEvent id="start"/>`, `<bpmn:parallelGateway id="fork"/>`, formTask("a"), formTask("b"), `<bpmn:parallelGateway id="join"/>`, `<bpmn:endEvent id="end"/>`,
+		flow("f1", "start", "fork"), flow("f2", "fork", "a"), flow("f3", "fork", "b"),
+		flow("f4", "a", "join"), flow("f5", "b", "join"), flow("f6", "join", "end"),
+	)
+
+	assert.False(t, result.HasErrors())
+	assert.Nil(t, result.WorkflowTemplate.EndNodeTemplateID)
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, SeverityWarning, result.Diagnostics[0].Severity)
+}
+
+func TestImport_SelectsProcess(t *testing.T) {
+	process := func(id string) string {
+		return `<bpmn:process id="` + id + `"><bpmn:startEvent id="start"/>` + formTask("a") + `<bpmn:endEvent id="end"/>` +
+			flow("f1", "start", "a") + flow("f2", "a", "end") + `</bpmn:process>`
+	}
+	doc := bpmnDocument(process("import"), process("export"))
+
+	result, err := Import(strings.NewReader(doc), Options{})
+	require.NoError(t, err)
+	assert.True(t, result.HasErrors())
+
+	result, err = Import(strings.NewReader(doc), Options{ProcessID: "export", Version: "2.0"})
+	require.NoError(t, err)
+	assert.False(t, result.HasErrors())
+	assert.Equal(t, "export", result.WorkflowTemplate.Name)
+	assert.Equal(t, "2.0", result.WorkflowTemplate.Version)
+}
+
+func TestImport_MalformedDocument(t *testing.T) {
+	_, err := Import(strings.NewReader(`<definitions><process>`), Options{})
+	assert.Error(t, err)
+
+	_, err = Import(strings.NewReader(`<workflow/>`), Options{})
+	assert.Error(t, err)
+}
+
+func TestParseISODuration(t *testing.T) {
+	d, err := parseISODuration("P1DT12H30M")
+	assert.NoError(t, err)
+	assert.Equal(t, "36h30m0s", d.String())
+
+	for _, invalid := range []string{"P", "PT", "P1M", "P2W", "72h"} {
+		_, err := parseISODuration(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWriteSQL(t *testing.T) {
+	result := importProcess(t,
+		`<bpmn:startEvent id="start"/>`, formTask("a"),
+		`<bpmn:userTask id="b" name="Trader's Declaration"><bpmn:extensionElements><nsw:config>{"formId": "b"}</nsw:config></bpmn:extensionElements></bpmn:userTask>`,
+		`<bpmn:endEvent id="end"/>`,
+		flow("f1", "start", "a"), flow("f2", "a", "b", `${outcome == "OK"}`), flow("f3", "b", "end"),
+	)
+	require.False(t, result.HasErrors(), "%v", result.Diagnostics)
+
+	var sql strings.Builder
+	require.NoError(t, WriteSQL(&sql, result))
+
+	out := sql.String()
+	assert.Contains(t, out, "INSERT INTO workflow_node_templates")
+	assert.Contains(t, out, "'Trader''s Declaration'")
+	assert.Contains(t, out, `'{"formId":"b"}'::jsonb`)
+	assert.Contains(t, out, `"outcome":"OK"`)
+	assert.Contains(t, out, "INSERT INTO workflow_templates")
+	assert.Contains(t, out, "'"+result.NodeTemplateIDs["b"].String()+"',")
+	assert.Contains(t, out, "INSERT INTO workflow_template_families")
+	assert.Contains(t, out, "'DRAFT');")
+
+	familyID := uuid.New()
+	result.WorkflowTemplate.FamilyID = familyID
+	sql.Reset()
+	require.NoError(t, WriteSQL(&sql, result))
+	assert.NotContains(t, sql.String(), "INSERT INTO workflow_template_families")
+	assert.Contains(t, sql.String(), "FROM workflow_templates WHERE family_id = '"+familyID.String()+"')")
+
+	assert.ErrorIs(t, WriteSQL(&sql, &Result{Diagnostics: []Diagnostic{{Severity: SeverityError}}}), ErrImportFailed)
+}
@@ -0,0 +1,100 @@
+package bpmn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// ErrImportFailed is returned when writing the result of an import that reported errors.
+var ErrImportFailed = errors.New("BPMN import reported errors")
+
+// WriteSQL writes the imported templates as INSERT statements in the style of the seed
+// migrations, so an imported process can be reviewed and shipped as a migration.
+func WriteSQL(w io.Writer, result *Result) error {
+	if result.HasErrors() {
+		return ErrImportFailed
+	}
+
+	var b strings.Builder
+	wt := result.WorkflowTemplate
+	fmt.Fprintf(&b, "-- Workflow template %q (version %s) imported from BPMN\n\n", wt.Name, wt.Version)
+
+	b.WriteString("INSERT INTO workflow_node_templates (id, name, description, type, config, depends_on, unlock_configuration, sla)\nVALUES\n")
+	for i, nt := range result.NodeTemplates {
+		dependsOn, err := jsonbLiteral(nt.DependsOn)
+		if err != nil {
+			return err
+		}
+		unlockConfiguration, sla := "NULL", "NULL"
+		if nt.UnlockConfiguration != nil {
+			if unlockConfiguration, err = jsonbLiteral(nt.UnlockConfiguration); err != nil {
+				return err
+			}
+		}
+		if nt.SLA != nil {
+			if sla, err = jsonbLiteral(nt.SLA); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(&b, "    (%s,\n     %s,\n     %s,\n     %s,\n     %s::jsonb,\n     %s,\n     %s,\n     %s)",
+			quote(nt.ID.String()), quote(nt.Name), quote(nt.Description), quote(string(nt.Type)),
+			quote(string(nt.Config)), dependsOn, unlockConfiguration, sla)
+		if i < len(result.NodeTemplates)-1 {
+			b.WriteString(",\n\n")
+		} else {
+			b.WriteString(";\n\n")
+		}
+	}
+
+	nodes, err := jsonbLiteral(wt.NodeTemplates)
+	if err != nil {
+		return err
+	}
+	endNode := "NULL"
+	if wt.EndNodeTemplateID != nil {
+		endNode = quote(wt.EndNodeTemplateID.String())
+	}
+	// A template without a family starts a new one, which reuses the template ID like the
+	// families created for the templates that existed before versioning.
+	familyID := quote(wt.ID.String())
+	versionNumber := "1"
+	if wt.FamilyID == uuid.Nil {
+		b.WriteString("INSERT INTO workflow_template_families (id, name, description)\nVALUES\n")
+		fmt.Fprintf(&b, "    (%s,\n     %s,\n     %s);\n\n", familyID, quote(wt.Name), quote(wt.Description))
+	} else {
+		familyID = quote(wt.FamilyID.String())
+		versionNumber = fmt.Sprintf("(SELECT COALESCE(MAX(version_number), 0) + 1 FROM workflow_templates WHERE family_id = %s)", familyID)
+	}
+
+	b.WriteString("INSERT INTO workflow_templates (id, name, description, version, nodes, end_node_template_id, family_id, version_number, status)\nVALUES\n")
+	fmt.Fprintf(&b, "    (%s,\n     %s,\n     %s,\n     %s,\n     %s,\n     %s,\n     %s,\n     %s,\n     %s);\n\n",
+		quote(wt.ID.String()), quote(wt.Name), quote(wt.Description), quote(wt.Version), nodes, endNode,
+		familyID, versionNumber, quote(string(model.WorkflowTemplateStatusDraft)))
+	fmt.Fprintf(&b, "-- Publish the version once reviewed:\n-- UPDATE workflow_templates SET status = 'PUBLISHED', published_at = NOW() WHERE id = %s;\n",
+		quote(wt.ID.String()))
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// quote returns s as a SQL string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// jsonbLiteral returns v encoded as a JSONB literal.
+func jsonbLiteral(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %T: %w", v, err)
+	}
+	return quote(string(data)) + "::jsonb", nil
+}
@@ -0,0 +1,99 @@
+package bpmn
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// definitions is the root element of a BPMN 2.0 document. Elements are matched by local name
+// only, so documents using any namespace prefix (bpmn:, bpmn2:, semantic: or the default
+// namespace) are accepted. Diagram interchange (BPMNDiagram) is ignored.
+type definitions struct {
+	XMLName   xml.Name  `xml:"definitions"`
+	Processes []process `xml:"process"`
+}
+
+// process is a BPMN process. Its flow elements are kept generic so that unsupported constructs
+// can be reported by name instead of being silently dropped.
+type process struct {
+	ID            string    `xml:"id,attr"`
+	Name          string    `xml:"name,attr"`
+	Documentation string    `xml:"documentation"`
+	Elements      []element `xml:",any"`
+}
+
+// element is any BPMN element inside a process: an event, activity, gateway or sequence flow,
+// or a child of one of those such as an event definition.
+type element struct {
+	XMLName             xml.Name
+	ID                  string             `xml:"id,attr"`
+	Name                string             `xml:"name,attr"`
+	SourceRef           string             `xml:"sourceRef,attr"`
+	TargetRef           string             `xml:"targetRef,attr"`
+	Default             string             `xml:"default,attr"`
+	Documentation       string             `xml:"documentation"`
+	ConditionExpression *string            `xml:"conditionExpression"`
+	ExtensionElements   *extensionElements `xml:"extensionElements"`
+	Children            []element          `xml:",any"`
+	Text                string             `xml:",chardata"`
+}
+
+// kind returns the local name of the element, e.g. "userTask".
+func (e *element) kind() string {
+	return e.XMLName.Local
+}
+
+// child returns the first child element with the given local name, or nil.
+func (e *element) child(kind string) *element {
+	for i := range e.Children {
+		if e.Children[i].kind() == kind {
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+// eventDefinitions returns the local names of the event definitions of an event element.
+func (e *element) eventDefinitions() []string {
+	var defs []string
+	for _, c := range e.Children {
+		if strings.HasSuffix(c.kind(), "EventDefinition") {
+			defs = append(defs, c.kind())
+		}
+	}
+	return defs
+}
+
+// extension returns the extension element with the given local name, or nil.
+func (e *element) extension(kind string) *extension {
+	if e.ExtensionElements == nil {
+		return nil
+	}
+	for i := range e.ExtensionElements.Elements {
+		if e.ExtensionElements.Elements[i].XMLName.Local == kind {
+			return &e.ExtensionElements.Elements[i]
+		}
+	}
+	return nil
+}
+
+type extensionElements struct {
+	Elements []extension `xml:",any"`
+}
+
+// extension is a vendor extension element, e.g. <nsw:config>{"formId": "..."}</nsw:config>.
+type extension struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+}
+
+// attr returns the value of the attribute with the given local name.
+func (e *extension) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
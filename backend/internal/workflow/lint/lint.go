@@ -0,0 +1,502 @@
+// Package lint statically checks workflow templates and their node templates for mistakes that
+// would otherwise only show up once consignments are running on them.
+//
+// Every workflow template is checked for:
+//   - node templates that are missing, dependencies on nodes outside the template and an end node
+//     that is not part of the template
+//   - dependency cycles
+//   - nodes that can never become READY, whatever the outcomes of the nodes they depend on, and
+//     in particular an end node that can never become READY so the workflow can never complete
+//   - unlock conditions that expect a state or an outcome the referenced node's plugin can never
+//     produce
+//
+// Every node template is checked for a configuration its plugin rejects and, for SIMPLE_FORM
+// nodes, for form IDs that do not exist in the forms table.
+//
+// The analysis over-approximates what a node can do: the states and outcomes of a node are
+// considered independently and every node that can become READY is assumed to be able to reach
+// each state of its plugin FSM. A finding therefore always points at something that cannot
+// happen, while some impossible combinations may go unreported.
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "ERROR"   // The template is broken
+	SeverityWarning Severity = "WARNING" // The template works but is probably not what was intended
+)
+
+// Code identifies the check that produced a finding.
+type Code string
+
+const (
+	CodeInvalidNodeTemplate   Code = "INVALID_NODE_TEMPLATE"   // The node template fails validation
+	CodeInvalidNodeConfig     Code = "INVALID_NODE_CONFIG"     // The node's plugin rejects its configuration
+	CodeUnknownForm           Code = "UNKNOWN_FORM"            // A SIMPLE_FORM node references a form that does not exist
+	CodeEmptyWorkflowTemplate Code = "EMPTY_WORKFLOW_TEMPLATE" // The workflow template has no nodes
+	CodeMissingNodeTemplate   Code = "MISSING_NODE_TEMPLATE"   // A node of the workflow template does not exist
+	CodeDuplicateNodeTemplate Code = "DUPLICATE_NODE_TEMPLATE" // A node appears more than once in the workflow template
+	CodeDanglingDependency    Code = "DANGLING_DEPENDENCY"     // A node depends on a node outside the workflow template
+	CodeInvalidEndNode        Code = "INVALID_END_NODE"        // The end node is not part of the workflow template
+	CodeDependencyCycle       Code = "DEPENDENCY_CYCLE"        // Nodes depend on each other
+	CodeUnreachableNode       Code = "UNREACHABLE_NODE"        // A node can never become READY
+	CodeUnreachableEndNode    Code = "UNREACHABLE_END_NODE"    // The end node can never become READY
+	CodeImpossibleState       Code = "IMPOSSIBLE_STATE"        // An unlock condition expects a state the node can never be in
+	CodeImpossibleOutcome     Code = "IMPOSSIBLE_OUTCOME"      // An unlock condition expects an outcome the node can never produce
+)
+
+// Finding is a single problem found in a template.
+type Finding struct {
+	Severity           Severity   `json:"severity"`
+	Code               Code       `json:"code"`
+	WorkflowTemplateID *uuid.UUID `json:"workflowTemplateId,omitempty"` // Workflow template the finding is about, if any
+	NodeTemplateID     *uuid.UUID `json:"nodeTemplateId,omitempty"`     // Node template the finding is about, if any
+	Message            string     `json:"message"`
+}
+
+func (f Finding) String() string {
+	var subject []string
+	if f.WorkflowTemplateID != nil {
+		subject = append(subject, "workflow template "+f.WorkflowTemplateID.String())
+	}
+	if f.NodeTemplateID != nil {
+		subject = append(subject, "node template "+f.NodeTemplateID.String())
+	}
+	if len(subject) == 0 {
+		return fmt.Sprintf("%s %s: %s", f.Severity, f.Code, f.Message)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", f.Severity, f.Code, strings.Join(subject, ", "), f.Message)
+}
+
+// HasErrors reports whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Input is the set of templates to check.
+type Input struct {
+	WorkflowTemplates []model.WorkflowTemplate
+	NodeTemplates     []model.WorkflowNodeTemplate
+	FormIDs           map[uuid.UUID]bool // IDs of the existing forms. If nil, form IDs are not checked.
+}
+
+// nodeInfo is what a node of a node template can do once it becomes READY.
+type nodeInfo struct {
+	template *model.WorkflowNodeTemplate
+	known    bool                      // Whether the plugin could be built. If not, states and outcomes are unknown.
+	states   []model.WorkflowNodeState // States the node can reach once READY, including READY itself
+	outcomes []string                  // Outcomes the node can produce
+}
+
+// Lint checks the templates of in and returns the findings, node template findings first, in the
+// order the templates are given.
+func Lint(ctx context.Context, taskFactory taskPlugin.TaskFactory, in Input) []Finding {
+	var findings []Finding
+	nodes := make(map[uuid.UUID]*nodeInfo, len(in.NodeTemplates))
+	for i := range in.NodeTemplates {
+		nt := &in.NodeTemplates[i]
+		info, nodeFindings := inspectNodeTemplate(ctx, taskFactory, nt, in.FormIDs)
+		nodes[nt.ID] = info
+		findings = append(findings, nodeFindings...)
+	}
+
+	for i := range in.WorkflowTemplates {
+		findings = append(findings, lintWorkflowTemplate(&in.WorkflowTemplates[i], nodes)...)
+	}
+	return findings
+}
+
+func inspectNodeTemplate(ctx context.Context, taskFactory taskPlugin.TaskFactory, nt *model.WorkflowNodeTemplate, formIDs map[uuid.UUID]bool) (*nodeInfo, []Finding) {
+	var findings []Finding
+	nodeFinding := func(code Code, format string, args ...any) {
+		findings = append(findings, Finding{Severity: SeverityError, Code: code, NodeTemplateID: &nt.ID, Message: fmt.Sprintf(format, args...)})
+	}
+
+	info := &nodeInfo{template: nt}
+	if err := nt.Validate(); err != nil {
+		nodeFinding(CodeInvalidNodeTemplate, "%v", err)
+	}
+
+	executor, err := taskFactory.BuildExecutor(ctx, nt.Type, nt.Config)
+	if err != nil {
+		nodeFinding(CodeInvalidNodeConfig, "invalid %s configuration: %v", nt.Type, err)
+	} else {
+		info.known = true
+		info.states = []model.WorkflowNodeState{model.WorkflowNodeStateReady}
+		for _, taskState := range executor.FSM.TaskStates() {
+			if state, ok := workflowNodeState(taskState); ok && !slices.Contains(info.states, state) {
+				info.states = append(info.states, state)
+			}
+		}
+		if reporter, ok := executor.Plugin.(taskPlugin.OutcomeReporter); ok {
+			info.outcomes = append(info.outcomes, reporter.Outcomes()...)
+		}
+	}
+	if nt.SLA != nil && !slices.Contains(info.outcomes, nt.SLA.BreachOutcome()) {
+		info.outcomes = append(info.outcomes, nt.SLA.BreachOutcome())
+	}
+
+	if nt.Type == taskPlugin.TaskTypeSimpleForm && formIDs != nil {
+		for _, ref := range simpleFormReferences(nt.Config) {
+			id, err := uuid.Parse(ref.formID)
+			if err != nil {
+				nodeFinding(CodeUnknownForm, "%s %q is not a form ID", ref.field, ref.formID)
+			} else if !formIDs[id] {
+				nodeFinding(CodeUnknownForm, "%s %s does not exist in forms", ref.field, id)
+			}
+		}
+	}
+	return info, findings
+}
+
+// workflowNodeState converts the task state a plugin FSM moves to into the state of its node.
+func workflowNodeState(state taskPlugin.State) (model.WorkflowNodeState, bool) {
+	switch state {
+	case taskPlugin.Initialized:
+		return model.WorkflowNodeStateReady, true
+	case taskPlugin.InProgress:
+		return model.WorkflowNodeStateInProgress, true
+	case taskPlugin.Completed:
+		return model.WorkflowNodeStateCompleted, true
+	case taskPlugin.Failed:
+		return model.WorkflowNodeStateFailed, true
+	default:
+		return "", false
+	}
+}
+
+// formReference is a form ID referenced by a field of a SIMPLE_FORM configuration.
+type formReference struct {
+	field  string
+	formID string
+}
+
+// simpleFormReferences returns the form IDs referenced by a SIMPLE_FORM configuration.
+// Configurations that cannot be parsed are reported by the plugin.
+func simpleFormReferences(raw json.RawMessage) []formReference {
+	var cfg taskPlugin.Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil
+	}
+
+	var refs []formReference
+	add := func(field string, r *taskPlugin.Response) {
+		if r != nil && r.Display != nil && r.Display.FormID != "" {
+			refs = append(refs, formReference{field: field, formID: r.Display.FormID})
+		}
+	}
+	if cfg.FormID != "" {
+		refs = append(refs, formReference{field: "formId", formID: cfg.FormID})
+	}
+	if cfg.Submission != nil {
+		add("submission.response.display.formId", cfg.Submission.Response)
+	}
+	if cfg.Callback != nil {
+		add("callback.response.display.formId", cfg.Callback.Response)
+	}
+	return refs
+}
+
+// workflowAnalysis holds the nodes of one workflow template.
+type workflowAnalysis struct {
+	wt        *model.WorkflowTemplate
+	ids       []uuid.UUID             // Nodes of the template that exist, in template order, without duplicates
+	nodes     map[uuid.UUID]*nodeInfo // All known node templates
+	members   map[uuid.UUID]bool      // Nodes of the template that exist
+	reachable map[uuid.UUID]bool      // Nodes that can become READY
+	findings  []Finding
+}
+
+func lintWorkflowTemplate(wt *model.WorkflowTemplate, nodes map[uuid.UUID]*nodeInfo) []Finding {
+	a := &workflowAnalysis{wt: wt, nodes: nodes, members: make(map[uuid.UUID]bool), reachable: make(map[uuid.UUID]bool)}
+
+	if len(wt.NodeTemplates) == 0 {
+		a.add(SeverityError, CodeEmptyWorkflowTemplate, nil, "workflow template has no nodes")
+		return a.findings
+	}
+	seen := make(map[uuid.UUID]bool, len(wt.NodeTemplates))
+	for _, id := range wt.NodeTemplates {
+		if seen[id] {
+			a.add(SeverityWarning, CodeDuplicateNodeTemplate, &id, "node template appears more than once")
+			continue
+		}
+		seen[id] = true
+		if _, ok := nodes[id]; !ok {
+			a.add(SeverityError, CodeMissingNodeTemplate, &id, "node template does not exist")
+			continue
+		}
+		a.members[id] = true
+		a.ids = append(a.ids, id)
+	}
+	if wt.EndNodeTemplateID != nil && !seen[*wt.EndNodeTemplateID] {
+		a.add(SeverityError, CodeInvalidEndNode, wt.EndNodeTemplateID, "end node is not one of the workflow template's nodes")
+	}
+
+	for _, id := range a.ids {
+		a.checkReferences(a.nodes[id].template)
+	}
+	inCycle := a.checkCycles()
+	a.computeReachable()
+
+	for _, id := range a.ids {
+		if a.reachable[id] {
+			continue
+		}
+		switch {
+		case wt.EndNodeTemplateID != nil && id == *wt.EndNodeTemplateID:
+			a.add(SeverityError, CodeUnreachableEndNode, &id, "end node can never become READY, so the workflow can never complete")
+		case !inCycle[id]:
+			a.add(SeverityError, CodeUnreachableNode, &id, "node can never become READY")
+		}
+	}
+	return a.findings
+}
+
+func (a *workflowAnalysis) add(severity Severity, code Code, nodeTemplateID *uuid.UUID, format string, args ...any) {
+	var nodeID *uuid.UUID
+	if nodeTemplateID != nil {
+		id := *nodeTemplateID
+		nodeID = &id
+	}
+	a.findings = append(a.findings, Finding{
+		Severity:           severity,
+		Code:               code,
+		WorkflowTemplateID: &a.wt.ID,
+		NodeTemplateID:     nodeID,
+		Message:            fmt.Sprintf(format, args...),
+	})
+}
+
+// checkReferences reports dependencies outside the template and unlock conditions that can never
+// hold because the referenced node cannot be in the expected state or produce the expected outcome.
+func (a *workflowAnalysis) checkReferences(nt *model.WorkflowNodeTemplate) {
+	for _, dependencyID := range nt.DependsOn {
+		if !a.members[dependencyID] {
+			a.add(SeverityError, CodeDanglingDependency, &nt.ID, "depends on node template %s, which is not part of the workflow template", dependencyID)
+		}
+	}
+	if nt.UnlockConfiguration == nil {
+		return
+	}
+
+	for _, cond := range nt.UnlockConfiguration.Conditions() {
+		if !a.members[cond.NodeTemplateID] {
+			if !slices.Contains(nt.DependsOn, cond.NodeTemplateID) {
+				a.add(SeverityError, CodeDanglingDependency, &nt.ID, "unlock configuration references node template %s, which is not part of the workflow template", cond.NodeTemplateID)
+			}
+			continue
+		}
+		ref := a.nodes[cond.NodeTemplateID]
+		if !ref.known {
+			continue
+		}
+		if cond.State != nil && !ref.canBeIn(*cond.State) {
+			a.add(SeverityError, CodeImpossibleState, &nt.ID, "unlock configuration expects node template %s to be %s, but its %s plugin never reaches that state",
+				cond.NodeTemplateID, *cond.State, ref.template.Type)
+		}
+		if cond.Outcome != nil && !slices.Contains(ref.outcomes, *cond.Outcome) {
+			a.add(SeverityError, CodeImpossibleOutcome, &nt.ID, "unlock configuration expects outcome %s of node template %s, %s",
+				*cond.Outcome, cond.NodeTemplateID, describeOutcomes(ref.outcomes))
+		}
+	}
+}
+
+func describeOutcomes(outcomes []string) string {
+	if len(outcomes) == 0 {
+		return "which never reports an outcome"
+	}
+	return "which can only report " + strings.Join(outcomes, ", ")
+}
+
+// canBeIn reports whether a node of the template can ever be in state. Any node can be LOCKED, and SKIPPED
+// when its dependencies can no longer be met.
+func (n *nodeInfo) canBeIn(state string) bool {
+	return state == string(model.WorkflowNodeStateLocked) || state == string(model.WorkflowNodeStateSkipped) ||
+		slices.Contains(n.states, model.WorkflowNodeState(state))
+}
+
+// checkCycles reports every group of nodes that depend on each other, found as the strongly
+// connected components of the dependency graph, and returns the nodes that are part of one.
+func (a *workflowAnalysis) checkCycles() map[uuid.UUID]bool {
+	index := make(map[uuid.UUID]int, len(a.ids))
+	lowLink := make(map[uuid.UUID]int, len(a.ids))
+	onStack := make(map[uuid.UUID]bool, len(a.ids))
+	var stack []uuid.UUID
+	inCycle := make(map[uuid.UUID]bool)
+
+	var connect func(id uuid.UUID)
+	connect = func(id uuid.UUID) {
+		index[id] = len(index)
+		lowLink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		selfLoop := false
+		for _, dependencyID := range a.nodes[id].template.Dependencies() {
+			if !a.members[dependencyID] {
+				continue
+			}
+			if dependencyID == id {
+				selfLoop = true
+			}
+			if _, visited := index[dependencyID]; !visited {
+				connect(dependencyID)
+				lowLink[id] = min(lowLink[id], lowLink[dependencyID])
+			} else if onStack[dependencyID] {
+				lowLink[id] = min(lowLink[id], index[dependencyID])
+			}
+		}
+		if lowLink[id] != index[id] {
+			return
+		}
+
+		start := slices.Index(stack, id)
+		component := slices.Clone(stack[start:])
+		stack = stack[:start]
+		for _, member := range component {
+			onStack[member] = false
+		}
+		if len(component) == 1 && !selfLoop {
+			return
+		}
+
+		// Report the component in template order so that findings are stable.
+		slices.SortFunc(component, func(x, y uuid.UUID) int {
+			return slices.Index(a.ids, x) - slices.Index(a.ids, y)
+		})
+		names := make([]string, len(component))
+		for i, member := range component {
+			inCycle[member] = true
+			names[i] = member.String()
+		}
+		a.add(SeverityError, CodeDependencyCycle, &component[0], "dependency cycle between node templates %s", strings.Join(names, ", "))
+	}
+
+	for _, id := range a.ids {
+		if _, visited := index[id]; !visited {
+			connect(id)
+		}
+	}
+	return inCycle
+}
+
+// computeReachable finds the nodes that can become READY, starting from the nodes whose
+// dependencies are met when the workflow starts and adding nodes whose dependencies can be met by
+// the nodes found so far, until no more are found.
+func (a *workflowAnalysis) computeReachable() {
+	for changed := true; changed; {
+		changed = false
+		for _, id := range a.ids {
+			if !a.reachable[id] && a.canUnlock(a.nodes[id].template) {
+				a.reachable[id] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// canUnlock mirrors how the state machine decides whether a node's dependencies are met: by its
+// unlock configuration if it has one and otherwise by all of DependsOn being COMPLETED.
+// Dependencies outside the template are dropped when nodes are created, so they do not block.
+func (a *workflowAnalysis) canUnlock(nt *model.WorkflowNodeTemplate) bool {
+	if nt.UnlockConfiguration != nil {
+		if nt.UnlockConfiguration.Expression != nil {
+			return a.canSatisfyExpression(*nt.UnlockConfiguration.Expression)
+		}
+		for _, group := range nt.UnlockConfiguration.AnyOf {
+			satisfiable := true
+			for _, cond := range group.AllOf {
+				if !a.canSatisfy(cond.NodeTemplateID, cond.State, cond.Outcome) {
+					satisfiable = false
+					break
+				}
+			}
+			if satisfiable {
+				return true
+			}
+		}
+		return false
+	}
+
+	completed := string(model.WorkflowNodeStateCompleted)
+	for _, dependencyID := range nt.DependsOn {
+		if a.members[dependencyID] && !a.canSatisfy(dependencyID, &completed, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *workflowAnalysis) canSatisfyExpression(expr model.UnlockExpression) bool {
+	if len(expr.AnyOf) > 0 {
+		return slices.ContainsFunc(expr.AnyOf, a.canSatisfyExpression)
+	}
+	if len(expr.AllOf) > 0 {
+		for _, child := range expr.AllOf {
+			if !a.canSatisfyExpression(child) {
+				return false
+			}
+		}
+		return true
+	}
+	if expr.AtLeast != nil {
+		satisfiable := 0
+		for _, child := range expr.AtLeast.Of {
+			if a.canSatisfyExpression(child) {
+				satisfiable++
+			}
+		}
+		return satisfiable >= expr.AtLeast.N
+	}
+	if len(expr.None) > 0 {
+		// Negations are not checked; they usually hold until the nodes they refer to have run
+		return true
+	}
+	if expr.Context != nil {
+		// Context values are only known at runtime
+		return true
+	}
+	return a.canSatisfy(expr.NodeTemplateID, expr.State, expr.Outcome)
+}
+
+// canSatisfy reports whether the node of a template can ever be in state with outcome, given the
+// nodes found to be reachable so far. A nil state or outcome matches anything.
+func (a *workflowAnalysis) canSatisfy(nodeTemplateID uuid.UUID, state, outcome *string) bool {
+	if !a.members[nodeTemplateID] {
+		return false
+	}
+	if outcome == nil && state != nil && *state == string(model.WorkflowNodeStateLocked) {
+		return true
+	}
+	if !a.reachable[nodeTemplateID] {
+		return false
+	}
+
+	ref := a.nodes[nodeTemplateID]
+	if !ref.known {
+		return true
+	}
+	if state != nil && !ref.canBeIn(*state) {
+		return false
+	}
+	return outcome == nil || slices.Contains(ref.outcomes, *outcome)
+}
@@ -0,0 +1,202 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func strPtr(s string) *string { return &s }
+
+func formNode(formID string, dependsOn ...uuid.UUID) model.WorkflowNodeTemplate {
+	nt := model.WorkflowNodeTemplate{
+		Name:      "Form",
+		Type:      taskPlugin.TaskTypeSimpleForm,
+		Config:    json.RawMessage(`{"formId": "` + formID + `"}`),
+		DependsOn: dependsOn,
+	}
+	nt.ID = uuid.New()
+	return nt
+}
+
+func timerNode(outcome string, dependsOn ...uuid.UUID) model.WorkflowNodeTemplate {
+	nt := model.WorkflowNodeTemplate{
+		Name:      "Timer",
+		Type:      taskPlugin.TaskTypeTimer,
+		Config:    json.RawMessage(`{"duration": "1h", "outcome": "` + outcome + `"}`),
+		DependsOn: dependsOn,
+	}
+	nt.ID = uuid.New()
+	return nt
+}
+
+func workflowTemplate(end *uuid.UUID, nodes ...model.WorkflowNodeTemplate) model.WorkflowTemplate {
+	wt := model.WorkflowTemplate{Name: "Workflow", Version: "1", EndNodeTemplateID: end}
+	wt.ID = uuid.New()
+	for _, nt := range nodes {
+		wt.NodeTemplates = append(wt.NodeTemplates, nt.ID)
+	}
+	return wt
+}
+
+func codes(findings []Finding) []Code {
+	var cs []Code
+	for _, f := range findings {
+		cs = append(cs, f.Code)
+	}
+	return cs
+}
+
+func lintNodes(end *uuid.UUID, formIDs map[uuid.UUID]bool, nodes ...model.WorkflowNodeTemplate) []Finding {
+	return Lint(context.Background(), taskPlugin.NewTaskFactory(nil, nil), Input{
+		WorkflowTemplates: []model.WorkflowTemplate{workflowTemplate(end, nodes...)},
+		NodeTemplates:     nodes,
+		FormIDs:           formIDs,
+	})
+}
+
+func TestLint(t *testing.T) {
+	formID := uuid.New()
+
+	t.Run("Clean Template", func(t *testing.T) {
+		a := formNode(formID.String())
+		b := timerNode("EXPIRED", a.ID)
+		c := formNode(formID.String())
+		c.UnlockConfiguration = &model.UnlockConfig{Expression: &model.UnlockExpression{AnyOf: []model.UnlockExpression{
+			{NodeTemplateID: a.ID, State: strPtr("FAILED")},
+			{NodeTemplateID: b.ID, Outcome: strPtr("EXPIRED")},
+		}}}
+
+		findings := lintNodes(&c.ID, map[uuid.UUID]bool{formID: true}, a, b, c)
+		assert.Empty(t, findings)
+		assert.False(t, HasErrors(findings))
+	})
+
+	t.Run("Dependency Cycle", func(t *testing.T) {
+		a := formNode(formID.String())
+		b := formNode(formID.String(), a.ID)
+		a.DependsOn = model.UUIDArray{b.ID}
+		c := formNode(formID.String(), b.ID)
+
+		findings := lintNodes(&c.ID, nil, a, b, c)
+		assert.Equal(t, []Code{CodeDependencyCycle, CodeUnreachableEndNode}, codes(findings))
+		assert.Equal(t, a.ID, *findings[0].NodeTemplateID)
+		assert.Contains(t, findings[0].Message, a.ID.String()+", "+b.ID.String())
+		assert.True(t, HasErrors(findings))
+	})
+
+	t.Run("Impossible Outcome Makes End Node Unreachable", func(t *testing.T) {
+		a := formNode(formID.String())
+		end := formNode(formID.String())
+		end.UnlockConfiguration = &model.UnlockConfig{AnyOf: []model.UnlockGroup{
+			{AllOf: []model.UnlockCondition{{NodeTemplateID: a.ID, Outcome: strPtr("APPROVED")}}},
+		}}
+
+		findings := lintNodes(&end.ID, nil, a, end)
+		assert.Equal(t, []Code{CodeImpossibleOutcome, CodeUnreachableEndNode}, codes(findings))
+		assert.Equal(t, end.ID, *findings[0].NodeTemplateID)
+		assert.Equal(t, end.ID, *findings[1].NodeTemplateID)
+	})
+
+	t.Run("SLA Breach Outcome Is Possible", func(t *testing.T) {
+		a := formNode(formID.String())
+		a.SLA = &model.SLAConfig{Duration: "72h"}
+		b := formNode(formID.String())
+		b.UnlockConfiguration = &model.UnlockConfig{Expression: &model.UnlockExpression{
+			NodeTemplateID: a.ID, Outcome: strPtr(model.DefaultSLABreachOutcome),
+		}}
+
+		assert.Empty(t, lintNodes(nil, nil, a, b))
+	})
+
+	t.Run("Impossible State", func(t *testing.T) {
+		a := timerNode("")
+		b := formNode(formID.String())
+		b.UnlockConfiguration = &model.UnlockConfig{Expression: &model.UnlockExpression{AnyOf: []model.UnlockExpression{
+			{NodeTemplateID: a.ID, State: strPtr("FAILED")},
+			{NodeTemplateID: a.ID, State: strPtr("COMPLETED")},
+		}}}
+
+		findings := lintNodes(nil, nil, a, b)
+		assert.Equal(t, []Code{CodeImpossibleState}, codes(findings))
+	})
+
+	t.Run("Quorum Needs Enough Satisfiable Conditions", func(t *testing.T) {
+		a := timerNode("")
+		b := formNode(formID.String())
+		c := formNode(formID.String())
+		quorum := func(n int) *model.UnlockConfig {
+			return &model.UnlockConfig{Expression: &model.UnlockExpression{AtLeast: &model.UnlockQuorum{N: n, Of: []model.UnlockExpression{
+				{NodeTemplateID: a.ID, State: strPtr("COMPLETED")},
+				{NodeTemplateID: b.ID, State: strPtr("COMPLETED")},
+				{NodeTemplateID: a.ID, State: strPtr("FAILED")},
+			}}}}
+		}
+		c.UnlockConfiguration = quorum(2)
+		findings := lintNodes(nil, map[uuid.UUID]bool{formID: true}, a, b, c)
+		assert.Equal(t, []Code{CodeImpossibleState}, codes(findings))
+
+		c.UnlockConfiguration = quorum(3)
+		findings = lintNodes(nil, map[uuid.UUID]bool{formID: true}, a, b, c)
+		assert.Equal(t, []Code{CodeImpossibleState, CodeUnreachableNode}, codes(findings))
+	})
+
+	t.Run("Unreachable Node Behind Unreachable Node", func(t *testing.T) {
+		a := timerNode("")
+		b := formNode(formID.String())
+		b.UnlockConfiguration = &model.UnlockConfig{Expression: &model.UnlockExpression{NodeTemplateID: a.ID, State: strPtr("FAILED")}}
+		c := formNode(formID.String(), b.ID)
+
+		findings := lintNodes(nil, nil, a, b, c)
+		assert.Equal(t, []Code{CodeImpossibleState, CodeUnreachableNode, CodeUnreachableNode}, codes(findings))
+		assert.Equal(t, c.ID, *findings[2].NodeTemplateID)
+	})
+
+	t.Run("Structural Problems", func(t *testing.T) {
+		a := formNode(formID.String())
+		outside := uuid.New()
+		b := formNode(formID.String(), a.ID, outside)
+		missing := uuid.New()
+		wt := workflowTemplate(&outside, a, b)
+		wt.NodeTemplates = append(wt.NodeTemplates, a.ID, missing)
+
+		findings := Lint(context.Background(), taskPlugin.NewTaskFactory(nil, nil), Input{
+			WorkflowTemplates: []model.WorkflowTemplate{wt, {Name: "Empty"}},
+			NodeTemplates:     []model.WorkflowNodeTemplate{a, b},
+		})
+		assert.Equal(t, []Code{CodeDuplicateNodeTemplate, CodeMissingNodeTemplate, CodeInvalidEndNode, CodeDanglingDependency, CodeEmptyWorkflowTemplate}, codes(findings))
+		assert.Equal(t, SeverityWarning, findings[0].Severity)
+		assert.Equal(t, wt.ID, *findings[0].WorkflowTemplateID)
+	})
+
+	t.Run("Invalid Node Config", func(t *testing.T) {
+		a := timerNode("")
+		a.Config = json.RawMessage(`{"duration": "soon"}`)
+		b := formNode(formID.String())
+		b.UnlockConfiguration = &model.UnlockConfig{Expression: &model.UnlockExpression{NodeTemplateID: a.ID, Outcome: strPtr("ANYTHING")}}
+
+		findings := lintNodes(nil, nil, a, b)
+		assert.Equal(t, []Code{CodeInvalidNodeConfig}, codes(findings))
+		assert.Nil(t, findings[0].WorkflowTemplateID)
+	})
+
+	t.Run("Unknown Forms", func(t *testing.T) {
+		a := formNode(uuid.NewString())
+		b := formNode("not-a-form")
+		c := formNode(formID.String())
+		c.Config = json.RawMessage(`{"formId": "` + formID.String() + `", "callback": {"response": {"display": {"formId": "` + uuid.NewString() + `"}}}}`)
+		d := formNode("")
+
+		findings := lintNodes(nil, map[uuid.UUID]bool{formID: true}, a, b, c, d)
+		assert.Equal(t, []Code{CodeUnknownForm, CodeUnknownForm, CodeUnknownForm}, codes(findings))
+		assert.Contains(t, findings[2].Message, "callback.response.display.formId")
+
+		assert.Empty(t, lintNodes(nil, nil, a, b, c, d))
+	})
+}
@@ -0,0 +1,42 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	formModel "github.com/OpenNSW/nsw/internal/form/model"
+)
+
+// Load reads every workflow template and node template from the database, along with the IDs of
+// the existing forms.
+func Load(ctx context.Context, db *gorm.DB) (Input, error) {
+	var in Input
+	if err := db.WithContext(ctx).Order("created_at, id").Find(&in.WorkflowTemplates).Error; err != nil {
+		return Input{}, fmt.Errorf("failed to load workflow templates: %w", err)
+	}
+	if err := db.WithContext(ctx).Order("created_at, id").Find(&in.NodeTemplates).Error; err != nil {
+		return Input{}, fmt.Errorf("failed to load workflow node templates: %w", err)
+	}
+	formIDs, err := LoadFormIDs(ctx, db)
+	if err != nil {
+		return Input{}, err
+	}
+	in.FormIDs = formIDs
+	return in, nil
+}
+
+// LoadFormIDs reads the IDs of the existing forms from the database.
+func LoadFormIDs(ctx context.Context, db *gorm.DB) (map[uuid.UUID]bool, error) {
+	var ids []uuid.UUID
+	if err := db.WithContext(ctx).Model(&formModel.Form{}).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to load form IDs: %w", err)
+	}
+	formIDs := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		formIDs[id] = true
+	}
+	return formIDs, nil
+}
@@ -2,13 +2,17 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
@@ -18,147 +22,324 @@ import (
 
 // Manager is the refactored workflow manager that coordinates between services, routers, and task manager
 type Manager struct {
-	tm                     taskManager.TaskManager
-	hsCodeService          *service.HSCodeService
-	consignmentService     *service.ConsignmentService
-	preConsignmentService  *service.PreConsignmentService
-	workflowNodeService    *service.WorkflowNodeService
-	templateService        *service.TemplateService
-	hsCodeRouter           *router.HSCodeRouter
-	consignmentRouter      *router.ConsignmentRouter
-	preConsignmentRouter   *router.PreConsignmentRouter
-	workflowNodeUpdateChan chan taskManager.WorkflowManagerNotification
-	ctx                    context.Context
-	cancel                 context.CancelFunc
-}
-
-// NewManager creates a new refactored workflow manager
-func NewManager(tm taskManager.TaskManager, ch chan taskManager.WorkflowManagerNotification, db *gorm.DB) *Manager {
+	tm                    taskManager.TaskManager
+	hsCodeService         *service.HSCodeService
+	consignmentService    *service.ConsignmentService
+	preConsignmentService *service.PreConsignmentService
+	workflowNodeService   *service.WorkflowNodeService
+	templateService       *service.TemplateService
+	failedUpdateService   *service.FailedWorkflowUpdateService
+	reopenService         *service.WorkflowNodeReopenService
+	slaService            *service.WorkflowNodeSLAService
+	migrationService      *service.WorkflowTemplateMigrationService
+	templateAdminService  *service.TemplateAdminService
+	hsCodeRouter          *router.HSCodeRouter
+	consignmentRouter     *router.ConsignmentRouter
+	preConsignmentRouter  *router.PreConsignmentRouter
+	failedUpdateRouter    *router.FailedUpdateRouter
+	reconciliationRouter  *router.ReconciliationRouter
+	workflowNodeRouter    *router.WorkflowNodeRouter
+	templateRouter        *router.TemplateRouter
+	migrationRouter       *router.TemplateMigrationRouter
+	templateAdminRouter   *router.TemplateAdminRouter
+	retryWorker           *RetryWorker
+	reconciler            *Reconciler
+	slaMonitor            *SLAMonitor
+	expiryMonitor         *PreConsignmentExpiryMonitor
+	ctx                   context.Context
+	cancel                context.CancelFunc
+}
+
+// NewManager creates a new refactored workflow manager. The task factory is used to validate the
+// node configurations of templates created through the admin API.
+func NewManager(tm taskManager.TaskManager, taskFactory plugin.TaskFactory, db *gorm.DB) *Manager {
 	// Initialize services
 	hsCodeService := service.NewHSCodeService(db)
 	workflowNodeService := service.NewWorkflowNodeService(db)
 	templateService := service.NewTemplateService(db)
 	consignmentService := service.NewConsignmentService(db, templateService, workflowNodeService)
 	preConsignmentService := service.NewPreConsignmentService(db, templateService, workflowNodeService)
+	failedUpdateService := service.NewFailedWorkflowUpdateService(db, service.DefaultRetryPolicy())
+	reopenService := service.NewWorkflowNodeReopenService(db, templateService, workflowNodeService)
+	slaService := service.NewWorkflowNodeSLAService(db, workflowNodeService, consignmentService, preConsignmentService)
+	migrationService := service.NewWorkflowTemplateMigrationService(db, templateService, workflowNodeService)
+	templateAdminService := service.NewTemplateAdminService(db, templateService, taskFactory)
 
 	// Create context for lifecycle management
 	ctx, cancel := context.WithCancel(context.Background())
 
 	m := &Manager{
-		tm:                     tm,
-		hsCodeService:          hsCodeService,
-		consignmentService:     consignmentService,
-		preConsignmentService:  preConsignmentService,
-		workflowNodeService:    workflowNodeService,
-		templateService:        templateService,
-		workflowNodeUpdateChan: ch,
-		ctx:                    ctx,
-		cancel:                 cancel,
+		tm:                    tm,
+		hsCodeService:         hsCodeService,
+		consignmentService:    consignmentService,
+		preConsignmentService: preConsignmentService,
+		workflowNodeService:   workflowNodeService,
+		templateService:       templateService,
+		failedUpdateService:   failedUpdateService,
+		reopenService:         reopenService,
+		slaService:            slaService,
+		migrationService:      migrationService,
+		templateAdminService:  templateAdminService,
+		ctx:                   ctx,
+		cancel:                cancel,
 	}
 
 	// Set pre-commit validation callback to ensure task registration happens within transaction
 	consignmentService.SetPreCommitValidationCallback(m.registerWorkflowNodesWithTaskManager)
 	preConsignmentService.SetPreCommitValidationCallback(m.registerWorkflowNodesWithTaskManager)
+	consignmentService.SetPostCancelCallback(m.cancelWorkflowNodeTasks)
+	consignmentService.SetPreAmendCommitCallback(m.migrateWorkflowNodeTasks)
+	consignmentService.SetPostSubWorkflowCompletionCallback(m.completeSubWorkflowTasks)
+	preConsignmentService.SetPostSubWorkflowCompletionCallback(m.completeSubWorkflowTasks)
+	reopenService.SetPostReopenCallback(m.reopenWorkflowNodeTask)
+	migrationService.SetPreCommitCallback(m.migrateWorkflowNodeTasks)
+	templateService.SetPrePublishValidationCallback(templateAdminService.ValidateWorkflowTemplateInTx)
 
 	// Initialize routers
 	m.hsCodeRouter = router.NewHSCodeRouter(hsCodeService)
 	m.consignmentRouter = router.NewConsignmentRouter(consignmentService, nil) // No longer need callback in router
 	m.preConsignmentRouter = router.NewPreConsignmentRouter(preConsignmentService)
-
-	// Start listening for workflow node updates
-	m.StartWorkflowNodeUpdateListener()
+	m.failedUpdateRouter = router.NewFailedUpdateRouter(failedUpdateService)
+	m.workflowNodeRouter = router.NewWorkflowNodeRouter(reopenService, service.NewWorkflowNodeExplainService(db))
+	m.templateRouter = router.NewTemplateRouter(templateService)
+	m.migrationRouter = router.NewTemplateMigrationRouter(migrationService)
+	m.templateAdminRouter = router.NewTemplateAdminRouter(templateAdminService)
+
+	// Initialize reconciler; it only runs periodically once StartReconciler is called
+	m.reconciler = newReconciler(workflowNodeService.GetWorkflowNodesByStatesUpdatedBefore,
+		failedUpdateService.GetNodesWithOutstandingUpdates, tm, m.registerWorkflowNode,
+		workflowNodeService.GetWorkflowNodesPendingTaskCancellation, workflowNodeService.ClearTaskCancellationPending,
+		reconcileGracePeriod)
+	m.reconciliationRouter = router.NewReconciliationRouter(m.reconciler)
 
 	return m
 }
 
-// StartWorkflowNodeUpdateListener starts a goroutine that listens for workflow node updates
-func (m *Manager) StartWorkflowNodeUpdateListener() {
-	go func() {
-		for {
-			select {
-			case <-m.ctx.Done():
-				slog.Info("workflow node update listener stopped")
-				return
-			case update := <-m.workflowNodeUpdateChan:
-				// Validate and convert plugin state to workflow node state
-				if update.UpdatedState == nil {
-					slog.Error("received nil state in workflow node update",
-						"taskID", update.TaskID)
-					continue
-				}
-
-				workflowState, err := pluginStateToWorkflowNodeState(*update.UpdatedState)
-				if err != nil {
-					slog.Error("invalid state in workflow node update",
-						"taskID", update.TaskID,
-						"pluginState", *update.UpdatedState,
-						"error", err)
-					continue
-				}
-
-				updateReq := model.UpdateWorkflowNodeDTO{
-					WorkflowNodeID:      update.TaskID,
-					State:               workflowState,
-					AppendGlobalContext: update.AppendGlobalContext,
-					ExtendedState:       update.ExtendedState,
-					Outcome:             update.Outcome,
-				}
-
-				// Determine which service should handle the update by looking up the node
-				node, err := m.workflowNodeService.GetWorkflowNodeByID(m.ctx, update.TaskID)
-				if err != nil {
-					slog.Error("failed to look up workflow node for update routing",
-						"taskID", update.TaskID,
-						"error", err)
-					continue
-				}
-
-				var newReadyNodes []model.WorkflowNode
-				var newGlobalContext map[string]any
-
-				if node.PreConsignmentID != nil {
-					newReadyNodes, newGlobalContext, err = m.preConsignmentService.UpdateWorkflowNodeStateAndPropagateChanges(m.ctx, &updateReq)
-				} else {
-					newReadyNodes, newGlobalContext, err = m.consignmentService.UpdateWorkflowNodeStateAndPropagateChanges(m.ctx, &updateReq)
-				}
-
-				if err != nil {
-					slog.Error("failed to handle workflow node update",
-						"taskID", update.TaskID,
-						"state", workflowState,
-						"extendedState", update.ExtendedState,
-						"globalContext", newGlobalContext,
-						"error", err)
-					// TODO: Implement retry mechanism with exponential backoff
-					// - Store failed update in persistent queue (failed_workflow_updates table)
-					// - Add background worker to retry failed updates periodically
-					// - Implement max retry limits and dead-letter queue for permanent failures
-					continue
-				}
-
-				if len(newReadyNodes) > 0 {
-					err := m.registerWorkflowNodesWithTaskManager(newReadyNodes, newGlobalContext)
-					if err != nil {
-						slog.Error("failed to register new ready nodes with task manager",
-							"taskID", update.TaskID,
-							"newReadyNodeCount", len(newReadyNodes),
-							"error", err)
-						// Continue processing even if registration fails
-						// The nodes are already in READY state in DB
-					}
-				}
-			}
-		}
-	}()
-}
-
-// StopWorkflowNodeUpdateListener stops the workflow node update listener
-func (m *Manager) StopWorkflowNodeUpdateListener() {
+// HandleTaskNotification applies a task state notification delivered from the task outbox to the
+// corresponding workflow node and registers any nodes that became READY with the Task Manager.
+// If the update cannot be applied it is recorded as a failed workflow update and retried by the
+// retry worker; while a node has outstanding failed updates, new updates for it are queued behind
+// them. Returning an error leaves the notification in the outbox for redelivery, so this method
+// must tolerate receiving the same notification more than once.
+func (m *Manager) HandleTaskNotification(ctx context.Context, update taskManager.WorkflowManagerNotification) error {
+	// Validate and convert plugin state to workflow node state
+	if update.UpdatedState == nil {
+		return fmt.Errorf("received nil state in workflow node update for task %s", update.TaskID)
+	}
+
+	workflowState, err := pluginStateToWorkflowNodeState(*update.UpdatedState)
+	if err != nil {
+		return fmt.Errorf("invalid state in workflow node update for task %s: %w", update.TaskID, err)
+	}
+
+	actor := update.Actor
+	if actor.Type == "" {
+		actor = auth.SystemActor
+	}
+	ctx = auth.WithActor(ctx, actor)
+
+	// The task transitions happened whether or not the workflow node update can be applied now
+	if err := m.workflowNodeService.RecordTaskTransitions(ctx, update.TaskID, update.Sequence, update.OccurredAt, update.Transitions); err != nil {
+		return err
+	}
+
+	updateReq := model.UpdateWorkflowNodeDTO{
+		WorkflowNodeID:      update.TaskID,
+		State:               workflowState,
+		AppendGlobalContext: update.AppendGlobalContext,
+		ExtendedState:       update.ExtendedState,
+		Outcome:             update.Outcome,
+		Actor:               &actor,
+	}
+
+	// Preserve per-node ordering: never apply an update past an earlier one that is still outstanding
+	outstanding, err := m.failedUpdateService.HasOutstandingUpdates(ctx, update.TaskID)
+	if err != nil {
+		return err
+	}
+	if outstanding {
+		queued, err := m.failedUpdateService.QueueBehindOutstanding(ctx, &updateReq)
+		if err != nil {
+			return err
+		}
+		slog.WarnContext(ctx, "workflow node has outstanding failed updates, queued update for retry",
+			"taskID", update.TaskID,
+			"failedUpdateID", queued.ID)
+		return nil
+	}
+
+	if err := m.applyWorkflowNodeUpdate(ctx, &updateReq); err != nil {
+		failedUpdate, recordErr := m.failedUpdateService.RecordFailure(ctx, &updateReq, err)
+		if recordErr != nil {
+			return fmt.Errorf("%w (and failed to schedule retry: %v)", err, recordErr)
+		}
+		if failedUpdate.Status == model.FailedWorkflowUpdateStatusDeadLettered {
+			slog.ErrorContext(ctx, "failed to apply workflow node update, moved to dead-letter queue",
+				"taskID", update.TaskID,
+				"failedUpdateID", failedUpdate.ID,
+				"error", err)
+			return nil
+		}
+		slog.WarnContext(ctx, "failed to apply workflow node update, scheduled for retry",
+			"taskID", update.TaskID,
+			"failedUpdateID", failedUpdate.ID,
+			"nextAttemptAt", failedUpdate.NextAttemptAt,
+			"error", err)
+	}
+
+	return nil
+}
+
+// applyWorkflowNodeUpdate applies a workflow node update through the owning consignment or
+// pre-consignment service and registers any nodes that became READY with the Task Manager.
+// The changes are attributed to the actor of the update, if set.
+func (m *Manager) applyWorkflowNodeUpdate(ctx context.Context, updateReq *model.UpdateWorkflowNodeDTO) error {
+	if updateReq.Actor != nil {
+		ctx = auth.WithActor(ctx, *updateReq.Actor)
+	}
+
+	// Determine which service should handle the update by looking up the node
+	node, err := m.workflowNodeService.GetWorkflowNodeByID(ctx, updateReq.WorkflowNodeID)
+	if err != nil {
+		return fmt.Errorf("failed to look up workflow node for update routing: %w", err)
+	}
+
+	var newReadyNodes []model.WorkflowNode
+	var newGlobalContext map[string]any
+
+	if node.PreConsignmentID != nil {
+		newReadyNodes, newGlobalContext, err = m.preConsignmentService.UpdateWorkflowNodeStateAndPropagateChanges(ctx, updateReq)
+	} else {
+		newReadyNodes, newGlobalContext, err = m.consignmentService.UpdateWorkflowNodeStateAndPropagateChanges(ctx, updateReq)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to handle workflow node update to state %s: %w", updateReq.State, err)
+	}
+
+	if len(newReadyNodes) > 0 {
+		err := m.registerWorkflowNodesWithTaskManager(newReadyNodes, newGlobalContext)
+		if err != nil {
+			slog.Error("failed to register new ready nodes with task manager",
+				"taskID", updateReq.WorkflowNodeID,
+				"newReadyNodeCount", len(newReadyNodes),
+				"error", err)
+			// Continue processing even if registration fails
+			// The nodes are already in READY state in DB
+		}
+	}
+
+	return nil
+}
+
+// StartRetryWorker starts the background worker that retries failed workflow node updates
+// every interval. The worker is stopped by Stop.
+func (m *Manager) StartRetryWorker(interval time.Duration) {
+	m.retryWorker = newRetryWorker(m.failedUpdateService, m.applyWorkflowNodeUpdate, interval)
+	m.retryWorker.Start()
+}
+
+// StartReconciler runs a reconciliation between workflow nodes and task records immediately
+// and then every interval. The reconciler is stopped by Stop.
+func (m *Manager) StartReconciler(interval time.Duration) {
+	m.reconciler.Start(interval)
+}
+
+// StartSLAMonitor starts the background monitor that applies the SLA breaches of workflow nodes
+// every interval. The monitor is stopped by Stop.
+func (m *Manager) StartSLAMonitor(interval time.Duration) {
+	m.slaMonitor = newSLAMonitor(m.workflowNodeService.GetWorkflowNodesWithExpiredSLA, m.applySLABreach, interval)
+	m.slaMonitor.Start()
+}
+
+// StartPreConsignmentExpiryMonitor starts the background monitor that expires pre-consignments past their
+// validity every interval. The monitor is stopped by Stop.
+func (m *Manager) StartPreConsignmentExpiryMonitor(interval time.Duration) {
+	m.expiryMonitor = newPreConsignmentExpiryMonitor(m.preConsignmentService.ExpirePreConsignments, interval)
+	m.expiryMonitor.Start()
+}
+
+// Stop stops the retry worker, reconciler and SLA and pre-consignment expiry monitors, if started, and cancels
+// the manager's lifecycle context.
+func (m *Manager) Stop() {
+	m.reconciler.Stop()
+	if m.retryWorker != nil {
+		m.retryWorker.Stop()
+	}
+	if m.slaMonitor != nil {
+		m.slaMonitor.Stop()
+	}
+	if m.expiryMonitor != nil {
+		m.expiryMonitor.Stop()
+	}
 	if m.cancel != nil {
 		m.cancel()
 	}
 }
 
+// applySLABreach applies the SLA breach of a workflow node, registers the nodes it unlocked with the
+// Task Manager and completes the tasks of the SUB_WORKFLOW nodes whose child workflow it finished.
+func (m *Manager) applySLABreach(ctx context.Context, nodeID uuid.UUID, now time.Time) error {
+	newReadyNodes, globalContext, completedSubWorkflowNodes, err := m.slaService.ApplySLABreach(ctx, nodeID, now)
+	if err != nil {
+		return err
+	}
+	m.completeSubWorkflowTasks(ctx, completedSubWorkflowNodes)
+
+	if len(newReadyNodes) > 0 {
+		slog.InfoContext(ctx, "workflow node SLA expired, unlocked dependent nodes",
+			"workflowNodeID", nodeID,
+			"newReadyNodeCount", len(newReadyNodes))
+		if err := m.registerWorkflowNodesWithTaskManager(newReadyNodes, globalContext); err != nil {
+			slog.ErrorContext(ctx, "failed to register new ready nodes with task manager",
+				"workflowNodeID", nodeID,
+				"newReadyNodeCount", len(newReadyNodes),
+				"error", err)
+			// The nodes are already in READY state in DB; the reconciler registers them later
+		}
+	}
+	return nil
+}
+
+// registerWorkflowNode registers a single READY workflow node with the Task Manager,
+// using the current global context of the consignment or pre-consignment it belongs to.
+func (m *Manager) registerWorkflowNode(ctx context.Context, node model.WorkflowNode) error {
+	globalContext, err := m.workflowGlobalContext(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	return m.registerWorkflowNodesWithTaskManager([]model.WorkflowNode{node}, globalContext)
+}
+
+// workflowGlobalContext returns the current global context of the consignment or the trader context of the
+// pre-consignment a workflow node belongs to.
+func (m *Manager) workflowGlobalContext(ctx context.Context, node model.WorkflowNode) (map[string]any, error) {
+	switch {
+	case node.ConsignmentID != nil:
+		return m.consignmentService.GetGlobalContext(ctx, *node.ConsignmentID)
+	case node.PreConsignmentID != nil:
+		return m.preConsignmentService.GetTraderContext(ctx, *node.PreConsignmentID)
+	default:
+		return nil, fmt.Errorf("workflow node %s must have exactly one of consignment_id or pre_consignment_id set", node.ID)
+	}
+}
+
+// GetTaskGlobalContext returns the global context the task of a workflow node would be initialized with now,
+// built from the current context of its workflow. It is the source of the global context read by tasks in
+// LIVE global context mode.
+func (m *Manager) GetTaskGlobalContext(ctx context.Context, taskID uuid.UUID) (map[string]any, error) {
+	node, err := m.workflowNodeService.GetWorkflowNodeByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	globalContext, err := m.workflowGlobalContext(ctx, *node)
+	if err != nil {
+		return nil, err
+	}
+	return m.nodeGlobalContext(ctx, *node, globalContext)
+}
+
 // registerWorkflowNodesWithTaskManager registers workflow nodes with the Task Manager
 // This is called when new READY workflow nodes are created
 // Returns an error if any task registration fails
@@ -182,14 +363,21 @@ func (m *Manager) registerWorkflowNodesWithTaskManager(workflowNodes []model.Wor
 		if err != nil {
 			return fmt.Errorf("failed to get workflow node template %s: %w", node.WorkflowNodeTemplateID, err)
 		}
+		nodeContext, err := m.nodeGlobalContext(m.ctx, node, globalContext)
+		if err != nil {
+			return fmt.Errorf("failed to build global context for node %s: %w", node.ID, err)
+		}
 		initTaskRequest := taskManager.InitTaskRequest{
 			TaskID:                 node.ID,
 			WorkflowID:             workflowID,
 			WorkflowNodeTemplateID: node.WorkflowNodeTemplateID,
 			Type:                   nodeTemplate.Type,
-			GlobalState:            globalContext,
+			GlobalState:            nodeContext,
 			Config:                 nodeTemplate.Config,
 		}
+		if nodeTemplate.GlobalContextMode != nil {
+			initTaskRequest.GlobalContextMode = *nodeTemplate.GlobalContextMode
+		}
 		response, err := m.tm.InitTask(m.ctx, initTaskRequest)
 		if err != nil {
 			return fmt.Errorf("failed to initialize task in task manager for node %s: %w", node.ID, err)
@@ -199,6 +387,119 @@ func (m *Manager) registerWorkflowNodesWithTaskManager(workflowNodes []model.Wor
 	return nil
 }
 
+// nodeGlobalContext returns the global context the task of a workflow node starts with. The task of a node of a
+// child workflow gets the context of its SUB_WORKFLOW node mapped by the node's input mapping, and the task of a
+// node of a consignment item also gets the index of its item, under model.ItemIndexGlobalContextKey.
+func (m *Manager) nodeGlobalContext(ctx context.Context, node model.WorkflowNode, globalContext map[string]any) (map[string]any, error) {
+	if node.ParentNodeID != nil {
+		parent, err := m.workflowNodeService.GetWorkflowNodeByID(ctx, *node.ParentNodeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SUB_WORKFLOW node %s: %w", *node.ParentNodeID, err)
+		}
+		parentTemplate, err := m.templateService.GetWorkflowNodeTemplateByID(ctx, parent.WorkflowNodeTemplateID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workflow node template %s: %w", parent.WorkflowNodeTemplateID, err)
+		}
+		cfg, err := plugin.ParseSubWorkflowConfig(parentTemplate.Config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sub-workflow configuration of node template %s: %w", parentTemplate.ID, err)
+		}
+		parentContext, err := m.nodeGlobalContext(ctx, *parent, globalContext)
+		if err != nil {
+			return nil, err
+		}
+		globalContext = cfg.MapContext(parentContext)
+	}
+
+	if node.ItemIndex == nil {
+		return globalContext, nil
+	}
+	nodeContext := maps.Clone(globalContext)
+	if nodeContext == nil {
+		nodeContext = make(map[string]any, 1)
+	}
+	nodeContext[model.ItemIndexGlobalContextKey] = *node.ItemIndex
+	return nodeContext, nil
+}
+
+// completeSubWorkflowTasks completes the tasks of SUB_WORKFLOW nodes whose child workflow finished, with the
+// child workflow's outcome. The nodes are already COMPLETED, so the notification the tasks send in turn changes
+// nothing. Failures are logged.
+func (m *Manager) completeSubWorkflowTasks(ctx context.Context, workflowNodes []model.WorkflowNode) {
+	// The nodes are already completed; finish even if the request that completed them goes away
+	ctx = context.WithoutCancel(ctx)
+	for _, node := range workflowNodes {
+		content := map[string]any{}
+		if node.Outcome != nil {
+			content["outcome"] = *node.Outcome
+		}
+		if err := m.tm.ExecuteAction(ctx, node.ID, &plugin.ExecutionRequest{
+			Action:  plugin.SubWorkflowActionComplete,
+			Content: content,
+		}); err != nil {
+			slog.ErrorContext(ctx, "failed to complete task of finished sub-workflow",
+				"taskID", node.ID,
+				"error", err)
+		}
+	}
+}
+
+// cancelWorkflowNodeTasks cancels the tasks of workflow nodes whose consignment was cancelled, or
+// that were voided by an amendment of their consignment. Failures are logged and do not stop the
+// remaining tasks from being cancelled; any update the task still reports for such a node is dropped.
+func (m *Manager) cancelWorkflowNodeTasks(ctx context.Context, workflowNodes []model.WorkflowNode, reason string) {
+	// The consignment is already cancelled; finish even if the request that cancelled it goes away
+	ctx = context.WithoutCancel(ctx)
+	cancelledNodeIDs := make([]uuid.UUID, 0, len(workflowNodes))
+	for _, node := range workflowNodes {
+		if err := m.tm.CancelTask(ctx, node.ID, reason); err != nil {
+			// The node stays pending task cancellation; the reconciler cancels the task later
+			slog.ErrorContext(ctx, "failed to cancel task of cancelled workflow node",
+				"taskID", node.ID,
+				"error", err)
+			continue
+		}
+		cancelledNodeIDs = append(cancelledNodeIDs, node.ID)
+	}
+	if err := m.workflowNodeService.ClearTaskCancellationPending(ctx, cancelledNodeIDs); err != nil {
+		slog.ErrorContext(ctx, "failed to clear pending task cancellation of workflow nodes",
+			"error", err)
+	}
+}
+
+// reopenWorkflowNodeTask starts a new attempt of the task of a reopened workflow node. If the
+// node has no task record, e.g. because its registration was lost, it is registered afresh.
+func (m *Manager) reopenWorkflowNodeTask(ctx context.Context, node model.WorkflowNode, globalContext map[string]any) error {
+	nodeContext, err := m.nodeGlobalContext(ctx, node, globalContext)
+	if err != nil {
+		return err
+	}
+	err = m.tm.ReopenTask(ctx, node.ID, nodeContext)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.WarnContext(ctx, "reopened workflow node has no task record, registering it",
+			"taskID", node.ID)
+		return m.registerWorkflowNodesWithTaskManager([]model.WorkflowNode{node}, globalContext)
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "workflow node reopened", "taskID", node.ID, "attempt", node.Attempt)
+	return nil
+}
+
+// migrateWorkflowNodeTasks discards the tasks of workflow nodes locked again by a template migration or a
+// consignment amendment and registers the nodes it made READY. A relocked node whose task has already started
+// makes the migration or amendment of its consignment fail. If it is rolled back after the tasks were discarded,
+// the nodes are READY again without a task and the reconciler registers them again.
+func (m *Manager) migrateWorkflowNodeTasks(ctx context.Context, relockedNodes, unlockedNodes []model.WorkflowNode, globalContext map[string]any) error {
+	for _, node := range relockedNodes {
+		if err := m.tm.DiscardTask(ctx, node.ID); err != nil {
+			return err
+		}
+	}
+	return m.registerWorkflowNodesWithTaskManager(unlockedNodes, globalContext)
+}
+
 // HTTP Handler delegation methods
 
 // HandleGetAllHSCodes handles GET /api/v1/hscodes
@@ -216,11 +517,56 @@ func (m *Manager) HandleGetConsignmentsByTraderID(w http.ResponseWriter, r *http
 	m.consignmentRouter.HandleGetConsignmentsByTraderID(w, r)
 }
 
+// HandleSearchConsignments handles GET /api/v1/consignments/search
+func (m *Manager) HandleSearchConsignments(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleSearchConsignments(w, r)
+}
+
 // HandleGetConsignmentByID handles GET /api/v1/consignments/{id}
 func (m *Manager) HandleGetConsignmentByID(w http.ResponseWriter, r *http.Request) {
 	m.consignmentRouter.HandleGetConsignmentByID(w, r)
 }
 
+// HandleCancelConsignment handles POST /api/v1/consignments/{id}/cancel
+func (m *Manager) HandleCancelConsignment(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleCancelConsignment(w, r)
+}
+
+// HandleAmendConsignment handles POST /api/v1/consignments/{id}/amendments
+func (m *Manager) HandleAmendConsignment(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleAmendConsignment(w, r)
+}
+
+// HandleGetConsignmentAmendments handles GET /api/v1/consignments/{id}/amendments
+func (m *Manager) HandleGetConsignmentAmendments(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleGetConsignmentAmendments(w, r)
+}
+
+// HandleAdminGetConsignmentAmendments handles GET /api/v1/admin/consignments/{id}/amendments
+func (m *Manager) HandleAdminGetConsignmentAmendments(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleAdminGetConsignmentAmendments(w, r)
+}
+
+// HandleGetConsignmentEvents handles GET /api/v1/consignments/{id}/events
+func (m *Manager) HandleGetConsignmentEvents(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleGetConsignmentEvents(w, r)
+}
+
+// HandleAdminGetConsignmentEvents handles GET /api/v1/admin/consignments/{id}/events
+func (m *Manager) HandleAdminGetConsignmentEvents(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleAdminGetConsignmentEvents(w, r)
+}
+
+// HandleGetConsignmentGraph handles GET /api/v1/consignments/{id}/graph
+func (m *Manager) HandleGetConsignmentGraph(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleGetConsignmentGraph(w, r)
+}
+
+// HandleAdminGetConsignmentGraph handles GET /api/v1/admin/consignments/{id}/graph
+func (m *Manager) HandleAdminGetConsignmentGraph(w http.ResponseWriter, r *http.Request) {
+	m.consignmentRouter.HandleAdminGetConsignmentGraph(w, r)
+}
+
 // HandleCreatePreConsignment handles POST /api/v1/pre-consignments
 func (m *Manager) HandleCreatePreConsignment(w http.ResponseWriter, r *http.Request) {
 	m.preConsignmentRouter.HandleCreatePreConsignment(w, r)
@@ -236,6 +582,191 @@ func (m *Manager) HandleGetPreConsignmentByID(w http.ResponseWriter, r *http.Req
 	m.preConsignmentRouter.HandleGetPreConsignmentByID(w, r)
 }
 
+// HandleRenewPreConsignment handles POST /api/v1/pre-consignments/{preConsignmentId}/renew
+func (m *Manager) HandleRenewPreConsignment(w http.ResponseWriter, r *http.Request) {
+	m.preConsignmentRouter.HandleRenewPreConsignment(w, r)
+}
+
+// HandleRevokePreConsignment handles POST /api/v1/pre-consignments/{preConsignmentId}/revoke
+func (m *Manager) HandleRevokePreConsignment(w http.ResponseWriter, r *http.Request) {
+	m.preConsignmentRouter.HandleRevokePreConsignment(w, r)
+}
+
+// HandleGetPreConsignmentEvents handles GET /api/v1/pre-consignments/{preConsignmentId}/events
+func (m *Manager) HandleGetPreConsignmentEvents(w http.ResponseWriter, r *http.Request) {
+	m.preConsignmentRouter.HandleGetPreConsignmentEvents(w, r)
+}
+
+// HandleAdminGetPreConsignmentEvents handles GET /api/v1/admin/pre-consignments/{preConsignmentId}/events
+func (m *Manager) HandleAdminGetPreConsignmentEvents(w http.ResponseWriter, r *http.Request) {
+	m.preConsignmentRouter.HandleAdminGetPreConsignmentEvents(w, r)
+}
+
+// HandleGetFailedUpdates handles GET /api/v1/admin/failed-workflow-updates
+func (m *Manager) HandleGetFailedUpdates(w http.ResponseWriter, r *http.Request) {
+	m.failedUpdateRouter.HandleGetFailedUpdates(w, r)
+}
+
+// HandleGetFailedUpdateByID handles GET /api/v1/admin/failed-workflow-updates/{id}
+func (m *Manager) HandleGetFailedUpdateByID(w http.ResponseWriter, r *http.Request) {
+	m.failedUpdateRouter.HandleGetFailedUpdateByID(w, r)
+}
+
+// HandleReplayFailedUpdate handles POST /api/v1/admin/failed-workflow-updates/{id}/replay
+func (m *Manager) HandleReplayFailedUpdate(w http.ResponseWriter, r *http.Request) {
+	m.failedUpdateRouter.HandleReplayFailedUpdate(w, r)
+}
+
+// HandleDiscardFailedUpdate handles POST /api/v1/admin/failed-workflow-updates/{id}/discard
+func (m *Manager) HandleDiscardFailedUpdate(w http.ResponseWriter, r *http.Request) {
+	m.failedUpdateRouter.HandleDiscardFailedUpdate(w, r)
+}
+
+// HandleRunReconciliation handles POST /api/v1/admin/reconciliations
+func (m *Manager) HandleRunReconciliation(w http.ResponseWriter, r *http.Request) {
+	m.reconciliationRouter.HandleRunReconciliation(w, r)
+}
+
+// HandleGetLatestReconciliation handles GET /api/v1/admin/reconciliations/latest
+func (m *Manager) HandleGetLatestReconciliation(w http.ResponseWriter, r *http.Request) {
+	m.reconciliationRouter.HandleGetLatestReconciliation(w, r)
+}
+
+// HandleReopenWorkflowNode handles POST /api/v1/workflow-nodes/{id}/reopen
+func (m *Manager) HandleReopenWorkflowNode(w http.ResponseWriter, r *http.Request) {
+	m.workflowNodeRouter.HandleReopenWorkflowNode(w, r)
+}
+
+// HandleAdminReopenWorkflowNode handles POST /api/v1/admin/workflow-nodes/{id}/reopen
+func (m *Manager) HandleAdminReopenWorkflowNode(w http.ResponseWriter, r *http.Request) {
+	m.workflowNodeRouter.HandleAdminReopenWorkflowNode(w, r)
+}
+
+// HandleExplainWorkflowNodeUnlock handles GET /api/v1/workflow-nodes/{id}/unlock-explanation
+func (m *Manager) HandleExplainWorkflowNodeUnlock(w http.ResponseWriter, r *http.Request) {
+	m.workflowNodeRouter.HandleExplainWorkflowNodeUnlock(w, r)
+}
+
+// HandleAdminExplainWorkflowNodeUnlock handles GET /api/v1/admin/workflow-nodes/{id}/unlock-explanation
+func (m *Manager) HandleAdminExplainWorkflowNodeUnlock(w http.ResponseWriter, r *http.Request) {
+	m.workflowNodeRouter.HandleAdminExplainWorkflowNodeUnlock(w, r)
+}
+
+// HandleImportBPMN handles POST /api/v1/admin/workflow-templates/import/bpmn
+func (m *Manager) HandleImportBPMN(w http.ResponseWriter, r *http.Request) {
+	m.templateRouter.HandleImportBPMN(w, r)
+}
+
+// HandlePublishWorkflowTemplate handles POST /api/v1/admin/workflow-templates/{id}/publish
+func (m *Manager) HandlePublishWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateRouter.HandlePublishWorkflowTemplate(w, r)
+}
+
+// HandleMigrateConsignments handles POST /api/v1/admin/workflow-template-migrations
+func (m *Manager) HandleMigrateConsignments(w http.ResponseWriter, r *http.Request) {
+	m.migrationRouter.HandleMigrateConsignments(w, r)
+}
+
+// HandleListWorkflowNodeTemplates handles GET /api/v1/admin/workflow-node-templates
+func (m *Manager) HandleListWorkflowNodeTemplates(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleListWorkflowNodeTemplates(w, r)
+}
+
+// HandleGetWorkflowNodeTemplate handles GET /api/v1/admin/workflow-node-templates/{id}
+func (m *Manager) HandleGetWorkflowNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleGetWorkflowNodeTemplate(w, r)
+}
+
+// HandleCreateWorkflowNodeTemplate handles POST /api/v1/admin/workflow-node-templates
+func (m *Manager) HandleCreateWorkflowNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleCreateWorkflowNodeTemplate(w, r)
+}
+
+// HandleUpdateWorkflowNodeTemplate handles PUT /api/v1/admin/workflow-node-templates/{id}
+func (m *Manager) HandleUpdateWorkflowNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleUpdateWorkflowNodeTemplate(w, r)
+}
+
+// HandleDeleteWorkflowNodeTemplate handles DELETE /api/v1/admin/workflow-node-templates/{id}
+func (m *Manager) HandleDeleteWorkflowNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleDeleteWorkflowNodeTemplate(w, r)
+}
+
+// HandleListWorkflowTemplates handles GET /api/v1/admin/workflow-templates
+func (m *Manager) HandleListWorkflowTemplates(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleListWorkflowTemplates(w, r)
+}
+
+// HandleGetWorkflowTemplate handles GET /api/v1/admin/workflow-templates/{id}
+func (m *Manager) HandleGetWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleGetWorkflowTemplate(w, r)
+}
+
+// HandleCreateWorkflowTemplate handles POST /api/v1/admin/workflow-templates
+func (m *Manager) HandleCreateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleCreateWorkflowTemplate(w, r)
+}
+
+// HandleUpdateWorkflowTemplate handles PUT /api/v1/admin/workflow-templates/{id}
+func (m *Manager) HandleUpdateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleUpdateWorkflowTemplate(w, r)
+}
+
+// HandleDeleteWorkflowTemplate handles DELETE /api/v1/admin/workflow-templates/{id}
+func (m *Manager) HandleDeleteWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleDeleteWorkflowTemplate(w, r)
+}
+
+// HandleListWorkflowTemplateMaps handles GET /api/v1/admin/workflow-template-maps
+func (m *Manager) HandleListWorkflowTemplateMaps(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleListWorkflowTemplateMaps(w, r)
+}
+
+// HandleGetWorkflowTemplateMap handles GET /api/v1/admin/workflow-template-maps/{id}
+func (m *Manager) HandleGetWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleGetWorkflowTemplateMap(w, r)
+}
+
+// HandleCreateWorkflowTemplateMap handles POST /api/v1/admin/workflow-template-maps
+func (m *Manager) HandleCreateWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleCreateWorkflowTemplateMap(w, r)
+}
+
+// HandleUpdateWorkflowTemplateMap handles PUT /api/v1/admin/workflow-template-maps/{id}
+func (m *Manager) HandleUpdateWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleUpdateWorkflowTemplateMap(w, r)
+}
+
+// HandleDeleteWorkflowTemplateMap handles DELETE /api/v1/admin/workflow-template-maps/{id}
+func (m *Manager) HandleDeleteWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleDeleteWorkflowTemplateMap(w, r)
+}
+
+// HandleListPreConsignmentTemplates handles GET /api/v1/admin/pre-consignment-templates
+func (m *Manager) HandleListPreConsignmentTemplates(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleListPreConsignmentTemplates(w, r)
+}
+
+// HandleGetPreConsignmentTemplate handles GET /api/v1/admin/pre-consignment-templates/{id}
+func (m *Manager) HandleGetPreConsignmentTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleGetPreConsignmentTemplate(w, r)
+}
+
+// HandleCreatePreConsignmentTemplate handles POST /api/v1/admin/pre-consignment-templates
+func (m *Manager) HandleCreatePreConsignmentTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleCreatePreConsignmentTemplate(w, r)
+}
+
+// HandleUpdatePreConsignmentTemplate handles PUT /api/v1/admin/pre-consignment-templates/{id}
+func (m *Manager) HandleUpdatePreConsignmentTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleUpdatePreConsignmentTemplate(w, r)
+}
+
+// HandleDeletePreConsignmentTemplate handles DELETE /api/v1/admin/pre-consignment-templates/{id}
+func (m *Manager) HandleDeletePreConsignmentTemplate(w http.ResponseWriter, r *http.Request) {
+	m.templateAdminRouter.HandleDeletePreConsignmentTemplate(w, r)
+}
+
 // pluginStateToWorkflowNodeState converts a plugin.State to a WorkflowNodeState.
 // Returns an error if the plugin state is not recognized.
 func pluginStateToWorkflowNodeState(state plugin.State) (model.WorkflowNodeState, error) {
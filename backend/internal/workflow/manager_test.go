@@ -4,10 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
@@ -17,6 +18,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/container"
 	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
@@ -59,6 +61,48 @@ func (m *MockTaskManager) HandleGetTask(w http.ResponseWriter, r *http.Request)
 	m.Called(w, r)
 }
 
+func (m *MockTaskManager) GetTaskStates(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID]plugin.State, error) {
+	args := m.Called(ctx, taskIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]plugin.State), args.Error(1)
+}
+
+func (m *MockTaskManager) ResendStateNotification(ctx context.Context, taskID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, taskID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTaskManager) CancelTask(ctx context.Context, taskID uuid.UUID, reason string) error {
+	args := m.Called(ctx, taskID, reason)
+	return args.Error(0)
+}
+
+func (m *MockTaskManager) ReopenTask(ctx context.Context, taskID uuid.UUID, globalState map[string]any) error {
+	args := m.Called(ctx, taskID, globalState)
+	return args.Error(0)
+}
+
+func (m *MockTaskManager) DiscardTask(ctx context.Context, taskID uuid.UUID) error {
+	args := m.Called(ctx, taskID)
+	return args.Error(0)
+}
+
+func (m *MockTaskManager) ExecuteScheduledAction(ctx context.Context, taskID uuid.UUID, action string) error {
+	args := m.Called(ctx, taskID, action)
+	return args.Error(0)
+}
+
+func (m *MockTaskManager) ExecuteAction(ctx context.Context, taskID uuid.UUID, request *plugin.ExecutionRequest) error {
+	args := m.Called(ctx, taskID, request)
+	return args.Error(0)
+}
+
+func (m *MockTaskManager) SetGlobalContextSource(source container.GlobalContextSource) {
+	m.Called(source)
+}
+
 func TestPluginStateToWorkflowNodeState(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -105,40 +149,290 @@ func TestPluginStateToWorkflowNodeState(t *testing.T) {
 	}
 }
 
-func TestManager_StartWorkflowNodeUpdateListener(t *testing.T) {
+func TestManager_HandleTaskNotification(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
 
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
+	defer manager.Stop()
 	sqlMock.MatchExpectationsInOrder(false)
 
-	t.Run("Node Lookup Error", func(t *testing.T) {
-		taskID := uuid.New()
+	t.Run("Nil State", func(t *testing.T) {
+		err := manager.HandleTaskNotification(context.Background(), taskManager.WorkflowManagerNotification{
+			TaskID: uuid.New(),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown State", func(t *testing.T) {
+		state := plugin.State("unknown")
+		err := manager.HandleTaskNotification(context.Background(), taskManager.WorkflowManagerNotification{
+			TaskID:       uuid.New(),
+			UpdatedState: &state,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Apply Error Schedules Retry", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
+
+		pluginState := plugin.Completed
+		notification := taskManager.WorkflowManagerNotification{
+			TaskID:       uuid.New(),
+			UpdatedState: &pluginState,
+		}
+
+		sqlMock.ExpectQuery(`(?i)SELECT count\(\*\) FROM "failed_workflow_updates"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_nodes"`).WillReturnError(gorm.ErrRecordNotFound)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`(?i)INSERT INTO "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		err := manager.HandleTaskNotification(context.Background(), notification)
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Apply Error Without Retry Record", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
+
 		pluginState := plugin.Completed
+		notification := taskManager.WorkflowManagerNotification{
+			TaskID:       uuid.New(),
+			UpdatedState: &pluginState,
+		}
+
+		sqlMock.ExpectQuery(`(?i)SELECT count\(\*\) FROM "failed_workflow_updates"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_nodes"`).WillReturnError(gorm.ErrRecordNotFound)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`(?i)INSERT INTO "failed_workflow_updates"`).WillReturnError(gorm.ErrInvalidDB)
+		sqlMock.ExpectRollback()
+
+		err := manager.HandleTaskNotification(context.Background(), notification)
+		assert.Error(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Queued Behind Outstanding Update", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
 
+		pluginState := plugin.Completed
 		notification := taskManager.WorkflowManagerNotification{
-			TaskID:       taskID,
+			TaskID:       uuid.New(),
 			UpdatedState: &pluginState,
 		}
 
-		sqlMock.ExpectQuery(".*").WillReturnError(gorm.ErrRecordNotFound)
+		sqlMock.ExpectQuery(`(?i)SELECT count\(\*\) FROM "failed_workflow_updates"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`(?i)INSERT INTO "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		err := manager.HandleTaskNotification(context.Background(), notification)
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestManager_ReopenWorkflowNodeTask(t *testing.T) {
+	consignmentID := uuid.New()
+	globalContext := map[string]any{"foo": "bar"}
+
+	t.Run("Reopens Task", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		mockTM := new(MockTaskManager)
+		manager := NewManager(mockTM, nil, db)
+		defer manager.Stop()
+		node := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, Attempt: 2}
+
+		mockTM.On("ReopenTask", mock.Anything, node.ID, globalContext).Return(nil).Once()
+
+		err := manager.reopenWorkflowNodeTask(context.Background(), node, globalContext)
+
+		assert.NoError(t, err)
+		mockTM.AssertExpectations(t)
+	})
+
+	t.Run("Registers Node Without Task Record", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		mockTM := new(MockTaskManager)
+		manager := NewManager(mockTM, nil, db)
+		defer manager.Stop()
+		templateID := uuid.New()
+		node := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateID, Attempt: 2}
+
+		mockTM.On("ReopenTask", mock.Anything, node.ID, globalContext).Return(fmt.Errorf("failed to start new attempt: %w", gorm.ErrRecordNotFound)).Once()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_node_templates\"").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "config"}).AddRow(templateID, "SIMPLE_FORM", []byte(`{}`)))
+		mockTM.On("InitTask", mock.Anything, mock.MatchedBy(func(req taskManager.InitTaskRequest) bool {
+			return req.TaskID == node.ID && req.WorkflowID == consignmentID
+		})).Return(&taskManager.InitTaskResponse{Success: true}, nil).Once()
+
+		err := manager.reopenWorkflowNodeTask(context.Background(), node, globalContext)
+
+		assert.NoError(t, err)
+		mockTM.AssertExpectations(t)
+	})
+
+	t.Run("Task Not Failed", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		mockTM := new(MockTaskManager)
+		manager := NewManager(mockTM, nil, db)
+		defer manager.Stop()
+		node := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, Attempt: 2}
+
+		mockTM.On("ReopenTask", mock.Anything, node.ID, globalContext).Return(errors.New("task has not failed")).Once()
+
+		err := manager.reopenWorkflowNodeTask(context.Background(), node, globalContext)
+
+		assert.Error(t, err)
+		mockTM.AssertNotCalled(t, "InitTask", mock.Anything, mock.Anything)
+	})
+}
+
+func TestNodeGlobalContext(t *testing.T) {
+	globalContext := map[string]any{"consigneeName": "ACME", "hsCode": "0902.10"}
+
+	t.Run("Consignment Node", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
 
-		ch <- notification
+		nodeContext, err := manager.nodeGlobalContext(context.Background(), model.WorkflowNode{}, globalContext)
+		assert.NoError(t, err)
+		assert.Equal(t, globalContext, nodeContext)
+	})
+
+	t.Run("Item Node", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
+		itemIndex := 1
+
+		nodeContext, err := manager.nodeGlobalContext(context.Background(), model.WorkflowNode{ItemIndex: &itemIndex}, globalContext)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"consigneeName": "ACME", "hsCode": "0902.10", model.ItemIndexGlobalContextKey: 1}, nodeContext)
+		assert.NotContains(t, globalContext, model.ItemIndexGlobalContextKey)
+
+		nodeContext, err = manager.nodeGlobalContext(context.Background(), model.WorkflowNode{ItemIndex: &itemIndex}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{model.ItemIndexGlobalContextKey: 1}, nodeContext)
+	})
+
+	t.Run("Child Workflow Node", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
+		parentID := uuid.New()
+		parentTemplateID := uuid.New()
+		itemIndex := 0
+		config := `{"workflowTemplateId": "` + uuid.NewString() + `", "inputMapping": {"commodity": "hsCode"}}`
+
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_nodes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_node_template_id"}).AddRow(parentID, parentTemplateID))
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_node_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "config"}).AddRow(parentTemplateID, "SUB_WORKFLOW", []byte(config)))
+
+		nodeContext, err := manager.nodeGlobalContext(context.Background(), model.WorkflowNode{ParentNodeID: &parentID, ItemIndex: &itemIndex}, globalContext)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"commodity": "0902.10", model.ItemIndexGlobalContextKey: 0}, nodeContext)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestManager_GetTaskGlobalContext(t *testing.T) {
+	t.Run("Consignment Item Node", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
+		nodeID := uuid.New()
+		consignmentID := uuid.New()
+
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_nodes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "consignment_id", "item_index"}).AddRow(nodeID, consignmentID, 1))
+		sqlMock.ExpectQuery(`(?i)SELECT "id","global_context" FROM "consignments"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "global_context"}).AddRow(consignmentID, []byte(`{"permitNo":"PH-42"}`)))
 
-		assert.Eventually(t, func() bool {
-			return sqlMock.ExpectationsWereMet() == nil
-		}, 200*time.Millisecond, 10*time.Millisecond)
+		globalContext, err := manager.GetTaskGlobalContext(context.Background(), nodeID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"permitNo": "PH-42", model.ItemIndexGlobalContextKey: 1}, globalContext)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Pre-Consignment Node", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		manager := NewManager(new(MockTaskManager), nil, db)
+		defer manager.Stop()
+		nodeID := uuid.New()
+		preConsignmentID := uuid.New()
+
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_nodes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_id"}).AddRow(nodeID, preConsignmentID))
+		sqlMock.ExpectQuery(`(?i)SELECT "id","trader_context" FROM "pre_consignments"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_context"}).AddRow(preConsignmentID, []byte(`{"tin":"TIN-1"}`)))
+
+		globalContext, err := manager.GetTaskGlobalContext(context.Background(), nodeID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"tin": "TIN-1"}, globalContext)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
 	})
 
-	manager.StopWorkflowNodeUpdateListener()
+	t.Run("Registers Task With Template Mode", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		mockTM := new(MockTaskManager)
+		manager := NewManager(mockTM, nil, db)
+		defer manager.Stop()
+		consignmentID := uuid.New()
+		templateID := uuid.New()
+		node := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateID}
+
+		sqlMock.ExpectQuery(`(?i)SELECT .* FROM "workflow_node_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type", "config", "global_context_mode"}).AddRow(templateID, "SIMPLE_FORM", []byte(`{}`), "LIVE"))
+		mockTM.On("InitTask", mock.Anything, mock.MatchedBy(func(req taskManager.InitTaskRequest) bool {
+			return req.TaskID == node.ID && req.GlobalContextMode == plugin.GlobalContextLive
+		})).Return(&taskManager.InitTaskResponse{Success: true}, nil).Once()
+
+		err := manager.registerWorkflowNodesWithTaskManager([]model.WorkflowNode{node}, nil)
+
+		assert.NoError(t, err)
+		mockTM.AssertExpectations(t)
+	})
+}
+
+func TestManager_CompleteSubWorkflowTasks(t *testing.T) {
+	db, _ := setupTestDB(t)
+	mockTM := new(MockTaskManager)
+	manager := NewManager(mockTM, nil, db)
+	defer manager.Stop()
+	outcome := "APPROVED"
+	withOutcome := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, Outcome: &outcome}
+	withoutOutcome := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}}
+
+	mockTM.On("ExecuteAction", mock.Anything, withOutcome.ID, &plugin.ExecutionRequest{
+		Action:  plugin.SubWorkflowActionComplete,
+		Content: map[string]any{"outcome": "APPROVED"},
+	}).Return(errors.New("task not found")).Once()
+	mockTM.On("ExecuteAction", mock.Anything, withoutOutcome.ID, &plugin.ExecutionRequest{
+		Action:  plugin.SubWorkflowActionComplete,
+		Content: map[string]any{},
+	}).Return(nil).Once()
+
+	manager.completeSubWorkflowTasks(context.Background(), []model.WorkflowNode{withOutcome, withoutOutcome})
+
+	mockTM.AssertExpectations(t)
 }
 
 func TestManager_HandleGetAllHSCodes(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	sqlMock.ExpectQuery("(?i)SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
@@ -153,8 +447,7 @@ func TestManager_HandleGetAllHSCodes(t *testing.T) {
 func TestManager_HandleGetConsignmentByID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	id := uuid.New()
@@ -170,8 +463,7 @@ func TestManager_HandleGetConsignmentByID(t *testing.T) {
 func TestManager_HandleGetConsignmentsByTraderID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	req, _ := http.NewRequest("GET", "/api/v1/consignments", nil)
@@ -182,8 +474,7 @@ func TestManager_HandleGetConsignmentsByTraderID(t *testing.T) {
 func TestManager_HandleGetPreConsignmentsByTraderID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	req, _ := http.NewRequest("GET", "/api/v1/pre-consignments", nil)
@@ -194,8 +485,7 @@ func TestManager_HandleGetPreConsignmentsByTraderID(t *testing.T) {
 func TestManager_HandleGetPreConsignmentByID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	id := uuid.New()
@@ -227,8 +517,7 @@ func parseHTTPResponse(t *testing.T, handler http.HandlerFunc, req *http.Request
 func TestManager_HandleCreateConsignment(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	traderID := "trader1"
@@ -254,10 +543,12 @@ func TestManager_HandleCreateConsignment(t *testing.T) {
 	sqlMock.ExpectBegin()
 	sqlMock.ExpectExec("(?i)INSERT INTO \"consignments\"").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectExec("(?i)INSERT INTO \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
 
 	for i := 0; i < 5; i++ {
 		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\"").WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_node_template_id"}).AddRow(uuid.New(), nodeTemplateID))
 		sqlMock.ExpectExec("(?i)UPDATE \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
 	}
 
 	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "state"}).AddRow(uuid.New(), "READY"))
@@ -279,8 +570,7 @@ func TestManager_HandleCreateConsignment(t *testing.T) {
 func TestManager_HandleCreatePreConsignment(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTM := new(MockTaskManager)
-	ch := make(chan taskManager.WorkflowManagerNotification, 10)
-	manager := NewManager(mockTM, ch, db)
+	manager := NewManager(mockTM, nil, db)
 	sqlMock.MatchExpectationsInOrder(false)
 
 	traderID := "trader1"
@@ -302,10 +592,12 @@ func TestManager_HandleCreatePreConsignment(t *testing.T) {
 	sqlMock.ExpectBegin()
 	sqlMock.ExpectExec("(?i)INSERT INTO \"pre_consignments\"").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectExec("(?i)INSERT INTO \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
 
 	for i := 0; i < 5; i++ {
 		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\"").WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_node_template_id"}).AddRow(uuid.New(), nodeTemplateID))
 		sqlMock.ExpectExec("(?i)UPDATE \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
 	}
 
 	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_template_id"}).AddRow(uuid.New(), templateID))
@@ -15,10 +15,13 @@ type BaseModel struct {
 }
 
 // BeforeCreate is a GORM hook that is triggered before a new record is created.
+// An ID assigned by the caller is kept, so records that reference each other can be created together.
 func (base *BaseModel) BeforeCreate(tx *gorm.DB) (err error) {
-	base.ID, err = uuid.NewRandom()
-	if err != nil {
-		return
+	if base.ID == uuid.Nil {
+		base.ID, err = uuid.NewRandom()
+		if err != nil {
+			return
+		}
 	}
 	base.CreatedAt = time.Now().UTC()
 	base.UpdatedAt = time.Now().UTC()
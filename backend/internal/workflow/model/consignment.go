@@ -1,6 +1,12 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/utils"
+)
 
 // ConsignmentFlow represents the flow type of a consignment.
 type ConsignmentFlow string
@@ -16,17 +22,21 @@ type ConsignmentState string
 const (
 	ConsignmentStateInProgress ConsignmentState = "IN_PROGRESS"
 	ConsignmentStateFinished   ConsignmentState = "FINISHED"
+	ConsignmentStateCancelled  ConsignmentState = "CANCELLED"
 )
 
 // Consignment represents a consignment in the system.
 type Consignment struct {
 	BaseModel
-	Flow          ConsignmentFlow   `gorm:"type:varchar(50);column:flow;not null" json:"flow"`                              // e.g., IMPORT, EXPORT
-	TraderID      string            `gorm:"type:varchar(100);column:trader_id;not null" json:"traderId"`                    // ID of the trader associated with the consignment
-	State         ConsignmentState  `gorm:"type:varchar(50);column:state;not null" json:"state"`                            // State of the consignment
-	Items         []ConsignmentItem `gorm:"type:jsonb;column:items;serializer:json;not null" json:"items"`                  // Items in the consignment
-	GlobalContext map[string]any    `gorm:"type:jsonb;column:global_context;serializer:json;not null" json:"globalContext"` // Global context for the consignment
-	EndNodeID     *uuid.UUID        `gorm:"type:uuid;column:end_node_id" json:"endNodeId,omitempty"`                        // Optional reference to the end workflow node, used for quick lookup of completion status
+	Flow                ConsignmentFlow   `gorm:"type:varchar(50);column:flow;not null" json:"flow"`                                           // e.g., IMPORT, EXPORT
+	TraderID            string            `gorm:"type:varchar(100);column:trader_id;not null" json:"traderId"`                                 // ID of the trader associated with the consignment
+	State               ConsignmentState  `gorm:"type:varchar(50);column:state;not null" json:"state"`                                         // State of the consignment
+	Items               []ConsignmentItem `gorm:"type:jsonb;column:items;serializer:json;not null" json:"items"`                               // Items in the consignment
+	GlobalContext       map[string]any    `gorm:"type:jsonb;column:global_context;serializer:json;not null" json:"globalContext"`              // Global context for the consignment
+	EndNodeID           *uuid.UUID        `gorm:"type:uuid;column:end_node_id" json:"endNodeId,omitempty"`                                     // Optional reference to the end workflow node, used for quick lookup of completion status
+	WorkflowTemplateIDs UUIDArray         `gorm:"type:jsonb;column:workflow_template_ids;serializer:json;not null" json:"workflowTemplateIds"` // Workflow template versions the consignment's nodes were built from
+	CancellationReason  *string           `gorm:"type:text;column:cancellation_reason" json:"cancellationReason,omitempty"`                    // Reason given by the trader when the consignment was cancelled
+	CancelledAt         *time.Time        `gorm:"type:timestamptz;column:cancelled_at" json:"cancelledAt,omitempty"`                           // When the consignment was cancelled
 
 	// Relationships
 	WorkflowNodes []WorkflowNode `gorm:"foreignKey:ConsignmentID;references:ID" json:"-"` // Associated WorkflowNodes
@@ -38,7 +48,8 @@ func (c *Consignment) TableName() string {
 
 // ConsignmentItem represents an individual item within a consignment.
 type ConsignmentItem struct {
-	HSCodeID uuid.UUID `gorm:"type:uuid;column:hs_code_id;not null" json:"hsCodeId"` // HS Code ID
+	HSCodeID           uuid.UUID  `gorm:"type:uuid;column:hs_code_id;not null" json:"hsCodeId"`                      // HS Code ID
+	WorkflowTemplateID *uuid.UUID `gorm:"type:uuid;column:workflow_template_id" json:"workflowTemplateId,omitempty"` // Workflow template version the item's HS code mapped to; unset for items recorded before it was tracked
 }
 
 // ConsignmentItemResponseDTO represents an individual item in the consignment response.
@@ -65,6 +76,11 @@ type CreateConsignmentDTO struct {
 	Items []CreateConsignmentItemDTO `json:"items" binding:"required,dive,required"`      // Items in the consignment
 }
 
+// CancelConsignmentDTO represents the data required to cancel a consignment.
+type CancelConsignmentDTO struct {
+	Reason string `json:"reason" binding:"required"` // Why the consignment is being withdrawn
+}
+
 // UpdateConsignmentDTO represents the data required to update a consignment.
 type UpdateConsignmentDTO struct {
 	ConsignmentID         uuid.UUID         `json:"consignmentId" binding:"required"` // Consignment ID
@@ -74,14 +90,16 @@ type UpdateConsignmentDTO struct {
 
 // ConsignmentDetailDTO represents the full consignment data returned in detailed responses.
 type ConsignmentDetailDTO struct {
-	ID            uuid.UUID                    `json:"id"`            // Consignment ID
-	Flow          ConsignmentFlow              `json:"flow"`          // e.g., IMPORT, EXPORT
-	TraderID      string                       `json:"traderId"`      // ID of the trader associated with the consignment
-	State         ConsignmentState             `json:"state"`         // State of the consignment
-	Items         []ConsignmentItemResponseDTO `json:"items"`         // Items in the consignment with full HS Code details
-	CreatedAt     string                       `json:"createdAt"`     // Timestamp of consignment creation
-	UpdatedAt     string                       `json:"updatedAt"`     // Timestamp of last consignment update
-	WorkflowNodes []WorkflowNodeResponseDTO    `json:"workflowNodes"` // Associated workflow nodes with template details
+	ID                 uuid.UUID                    `json:"id"`                           // Consignment ID
+	Flow               ConsignmentFlow              `json:"flow"`                         // e.g., IMPORT, EXPORT
+	TraderID           string                       `json:"traderId"`                     // ID of the trader associated with the consignment
+	State              ConsignmentState             `json:"state"`                        // State of the consignment
+	Items              []ConsignmentItemResponseDTO `json:"items"`                        // Items in the consignment with full HS Code details
+	CreatedAt          string                       `json:"createdAt"`                    // Timestamp of consignment creation
+	UpdatedAt          string                       `json:"updatedAt"`                    // Timestamp of last consignment update
+	WorkflowNodes      []WorkflowNodeResponseDTO    `json:"workflowNodes"`                // Associated workflow nodes with template details
+	CancellationReason *string                      `json:"cancellationReason,omitempty"` // Reason given when the consignment was cancelled
+	CancelledAt        *string                      `json:"cancelledAt,omitempty"`        // Timestamp of consignment cancellation
 }
 
 // ConsignmentSummaryDTO represents the consignment data returned in list responses.
@@ -95,14 +113,16 @@ type ConsignmentSummaryDTO struct {
 	UpdatedAt                  string                       `json:"updatedAt"`                  // Timestamp of last consignment update
 	WorkflowNodeCount          int                          `json:"workflowNodeCount"`          // Total number of workflow nodes
 	CompletedWorkflowNodeCount int                          `json:"completedWorkflowNodeCount"` // Number of completed workflow nodes
+	SkippedWorkflowNodeCount   int                          `json:"skippedWorkflowNodeCount"`   // Number of workflow nodes skipped on branches not taken
 }
 
 // ConsignmentListResult represents the result of querying consignments with pagination
 type ConsignmentListResult struct {
-	TotalCount int64                   `json:"totalCount"`
+	TotalCount *int64                  `json:"totalCount,omitempty"` // Number of consignments matched by the filter; absent on pages selected by a cursor
 	Items      []ConsignmentSummaryDTO `json:"items"`
 	Offset     int                     `json:"offset"`
 	Limit      int                     `json:"limit"`
+	NextCursor *string                 `json:"nextCursor,omitempty"` // Cursor of the next page; absent on the last page
 }
 
 // ConsignmentFilter will be used when querying consignments as batch
@@ -112,4 +132,35 @@ type ConsignmentFilter struct {
 	State    *ConsignmentState `json:"state,omitempty"`
 	Offset   *int              `json:"offset,omitempty"`
 	Limit    *int              `json:"limit,omitempty"`
+	Cursor   *utils.Cursor     `json:"-"` // Optional cursor selecting keyset pagination instead of Offset
+}
+
+// ConsignmentSortField is a field consignment search results can be ordered by.
+type ConsignmentSortField string
+
+const (
+	ConsignmentSortCreatedAt ConsignmentSortField = "createdAt"
+	ConsignmentSortUpdatedAt ConsignmentSortField = "updatedAt"
+	ConsignmentSortState     ConsignmentSortField = "state"
+	ConsignmentSortFlow      ConsignmentSortField = "flow"
+)
+
+// ConsignmentSearchFilter is used by agency officers and administrators to search consignments across traders.
+// All filters that are set must match.
+type ConsignmentSearchFilter struct {
+	TraderID      *string              // Optional trader filter
+	Flow          *ConsignmentFlow     // Optional flow filter
+	State         *ConsignmentState    // Optional state filter
+	HSCode        *string              // HS code, or prefix of an HS code, of one of the items
+	CreatedFrom   *time.Time           // Consignments created at or after this time
+	CreatedTo     *time.Time           // Consignments created before this time
+	NodeTemplate  *string              // Name, or part of the name, of the node template of one of the workflow nodes
+	NodeState     *WorkflowNodeState   // State of a workflow node; combined with NodeTemplate if set
+	NodeOutcome   *string              // Outcome of a workflow node; combined with NodeTemplate and NodeState if set
+	GlobalContext map[string]string    // Values the global context must contain, keyed by dot-separated path; numbers and booleans also match as such
+	SortBy        ConsignmentSortField // Field to order by; defaults to createdAt
+	Ascending     bool                 // Whether to order from the lowest value up instead of the highest value down
+	Offset        *int                 // Optional offset for pagination
+	Limit         *int                 // Optional limit for pagination
+	Cursor        *utils.Cursor        // Optional cursor selecting keyset pagination instead of Offset
 }
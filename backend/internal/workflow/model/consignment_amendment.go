@@ -0,0 +1,38 @@
+package model
+
+import "github.com/google/uuid"
+
+// ConsignmentAmendment records a change to the items of a consignment after it was created and how it
+// changed the consignment's workflow nodes.
+type ConsignmentAmendment struct {
+	BaseModel
+	ConsignmentID               uuid.UUID         `gorm:"type:uuid;column:consignment_id;not null" json:"consignmentId"`                                                // Consignment amended
+	TraderID                    string            `gorm:"type:varchar(100);column:trader_id;not null" json:"traderId"`                                                  // Trader who amended the consignment
+	Reason                      *string           `gorm:"type:text;column:reason" json:"reason,omitempty"`                                                              // Reason given for the amendment
+	AddedItems                  []ConsignmentItem `gorm:"type:jsonb;column:added_items;serializer:json;not null" json:"addedItems"`                                     // Items added by the amendment
+	RemovedItems                []ConsignmentItem `gorm:"type:jsonb;column:removed_items;serializer:json;not null" json:"removedItems"`                                 // Items removed by the amendment
+	PreviousWorkflowTemplateIDs UUIDArray         `gorm:"type:jsonb;column:previous_workflow_template_ids;serializer:json;not null" json:"previousWorkflowTemplateIds"` // Workflow templates of the consignment before the amendment
+	WorkflowTemplateIDs         UUIDArray         `gorm:"type:jsonb;column:workflow_template_ids;serializer:json;not null" json:"workflowTemplateIds"`                  // Workflow templates of the consignment after the amendment
+	AddedNodeIDs                UUIDArray         `gorm:"type:jsonb;column:added_node_ids;serializer:json;not null" json:"addedNodeIds"`                                // Nodes created for node templates the new items require
+	VoidedNodeIDs               UUIDArray         `gorm:"type:jsonb;column:voided_node_ids;serializer:json;not null" json:"voidedNodeIds"`                              // Unfinished nodes no longer required, locked as AMENDMENT_VOIDED
+	RelockedNodeIDs             UUIDArray         `gorm:"type:jsonb;column:relocked_node_ids;serializer:json;not null" json:"relockedNodeIds"`                          // READY nodes locked again because their dependencies are no longer met
+	UnlockedNodeIDs             UUIDArray         `gorm:"type:jsonb;column:unlocked_node_ids;serializer:json;not null" json:"unlockedNodeIds"`                          // LOCKED nodes that became READY because their dependencies are now met
+	Finished                    bool              `gorm:"column:finished;not null;default:false" json:"finished"`                                                       // Whether the amendment completed the end node and finished the consignment
+}
+
+func (a *ConsignmentAmendment) TableName() string {
+	return "consignment_amendments"
+}
+
+// AmendConsignmentDTO represents the data required to amend the items of a consignment.
+type AmendConsignmentDTO struct {
+	AddItems    []CreateConsignmentItemDTO `json:"addItems,omitempty"`    // Items to add
+	RemoveItems []CreateConsignmentItemDTO `json:"removeItems,omitempty"` // Items to remove; each removes one item with the same HS code
+	Reason      string                     `json:"reason,omitempty"`      // Optional reason for the amendment
+}
+
+// ConsignmentAmendmentResultDTO is the result of amending a consignment.
+type ConsignmentAmendmentResultDTO struct {
+	Amendment   ConsignmentAmendment `json:"amendment"`   // Recorded amendment
+	Consignment ConsignmentDetailDTO `json:"consignment"` // Consignment after the amendment
+}
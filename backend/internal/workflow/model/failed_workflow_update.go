@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+type FailedWorkflowUpdateStatus string
+
+const (
+	FailedWorkflowUpdateStatusPending      FailedWorkflowUpdateStatus = "PENDING"       // Update is waiting to be retried by the retry worker
+	FailedWorkflowUpdateStatusDeadLettered FailedWorkflowUpdateStatus = "DEAD_LETTERED" // Update exhausted its retry attempts and needs operator action
+	FailedWorkflowUpdateStatusResolved     FailedWorkflowUpdateStatus = "RESOLVED"      // Update was applied successfully on a later attempt
+	FailedWorkflowUpdateStatusDiscarded    FailedWorkflowUpdateStatus = "DISCARDED"     // Update was discarded by an administrator
+)
+
+// FailedWorkflowUpdate records a workflow node state update that could not be applied.
+// Updates for the same workflow node are applied in CreatedAt order, so while an earlier update
+// is PENDING or DEAD_LETTERED, later updates for that node are queued behind it.
+type FailedWorkflowUpdate struct {
+	BaseModel
+	WorkflowNodeID      uuid.UUID                  `gorm:"type:uuid;column:workflow_node_id;not null" json:"workflowNodeId"`                   // Workflow node the update targets
+	State               WorkflowNodeState          `gorm:"type:varchar(50);column:state;not null" json:"state"`                                // Requested workflow node state
+	ExtendedState       *string                    `gorm:"type:text;column:extended_state" json:"extendedState,omitempty"`                     // Requested extended state
+	Outcome             *string                    `gorm:"type:varchar(100);column:outcome" json:"outcome,omitempty"`                          // Requested outcome sub-state
+	AppendGlobalContext map[string]any             `gorm:"type:jsonb;column:append_global_context;serializer:json" json:"appendGlobalContext"` // Global context to append when the update is applied
+	Actor               auth.Actor                 `gorm:"embedded;embeddedPrefix:actor_" json:"actor"`                                        // Who triggered the update
+	Status              FailedWorkflowUpdateStatus `gorm:"type:varchar(50);column:status;not null" json:"status"`                              // Retry status of the update
+	Attempts            int                        `gorm:"column:attempts;not null;default:0" json:"attempts"`                                 // Number of failed attempts so far
+	NextAttemptAt       time.Time                  `gorm:"type:timestamptz;column:next_attempt_at;not null" json:"nextAttemptAt"`              // Earliest time the retry worker may try again
+	LastError           *string                    `gorm:"type:text;column:last_error" json:"lastError,omitempty"`                             // Error returned by the most recent attempt
+	DeadLetteredAt      *time.Time                 `gorm:"type:timestamptz;column:dead_lettered_at" json:"deadLetteredAt,omitempty"`           // When the update was moved to the dead-letter queue
+	ResolvedAt          *time.Time                 `gorm:"type:timestamptz;column:resolved_at" json:"resolvedAt,omitempty"`                    // When the update was applied or discarded
+}
+
+func (f *FailedWorkflowUpdate) TableName() string {
+	return "failed_workflow_updates"
+}
+
+// ToUpdateWorkflowNodeDTO returns the workflow node update described by this record.
+func (f *FailedWorkflowUpdate) ToUpdateWorkflowNodeDTO() *UpdateWorkflowNodeDTO {
+	update := &UpdateWorkflowNodeDTO{
+		WorkflowNodeID:      f.WorkflowNodeID,
+		State:               f.State,
+		AppendGlobalContext: f.AppendGlobalContext,
+		ExtendedState:       f.ExtendedState,
+		Outcome:             f.Outcome,
+	}
+	if f.Actor.Type != "" {
+		actor := f.Actor
+		update.Actor = &actor
+	}
+	return update
+}
+
+// FailedWorkflowUpdateFilter is used to filter failed workflow updates when listing.
+type FailedWorkflowUpdateFilter struct {
+	Status         *FailedWorkflowUpdateStatus // Optional status filter
+	WorkflowNodeID *uuid.UUID                  // Optional workflow node filter
+	Offset         *int                        // Optional offset for pagination
+	Limit          *int                        // Optional limit for pagination
+}
+
+// FailedWorkflowUpdateListResult represents the result of querying failed workflow updates with pagination.
+type FailedWorkflowUpdateListResult struct {
+	TotalCount int64                  `json:"totalCount"`
+	Items      []FailedWorkflowUpdate `json:"items"`
+	Offset     int                    `json:"offset"`
+	Limit      int                    `json:"limit"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "github.com/OpenNSW/nsw/utils"
+
 // HSCode represents the Harmonized System Code used for classifying traded products.
 type HSCode struct {
 	BaseModel
@@ -14,15 +16,17 @@ func (h *HSCode) TableName() string {
 
 // HSCodeFilter will be used when querying as batch
 type HSCodeFilter struct {
-	HSCodeStartsWith *string `json:"hsCodeStartsWith,omitempty"`
-	Offset           *int    `json:"offset,omitempty"`
-	Limit            *int    `json:"limit,omitempty"`
+	HSCodeStartsWith *string       `json:"hsCodeStartsWith,omitempty"`
+	Offset           *int          `json:"offset,omitempty"`
+	Limit            *int          `json:"limit,omitempty"`
+	Cursor           *utils.Cursor `json:"-"` // Optional cursor selecting keyset pagination instead of Offset
 }
 
 // HSCodeListResult represents the result of querying HS codes with pagination
 type HSCodeListResult struct {
-	TotalCount int64    `json:"totalCount"`
+	TotalCount *int64   `json:"totalCount,omitempty"` // Number of HS codes matched by the filter; absent on pages selected by a cursor
 	Items      []HSCode `json:"items"`
 	Offset     int      `json:"offset"`
 	Limit      int      `json:"limit"`
+	NextCursor *string  `json:"nextCursor,omitempty"` // Cursor of the next page; absent on the last page
 }
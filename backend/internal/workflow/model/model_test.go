@@ -3,9 +3,12 @@ package model
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
 )
 
 func TestUUIDArray_MarshalJSON(t *testing.T) {
@@ -120,3 +123,235 @@ func TestModel_BaseHooks(t *testing.T) {
 	err = base.BeforeUpdate(nil)
 	assert.NoError(t, err)
 }
+
+func TestSLAConfig(t *testing.T) {
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, (&SLAConfig{Duration: "72h"}).Validate())
+		assert.NoError(t, (&SLAConfig{Duration: "30m", Outcome: "OGA_OVERDUE"}).Validate())
+		assert.Error(t, (&SLAConfig{Duration: "3 days"}).Validate())
+		assert.Error(t, (&SLAConfig{Duration: "0s"}).Validate())
+		assert.Error(t, (&SLAConfig{Duration: "1h", Outcome: "  "}).Validate())
+	})
+
+	t.Run("Breach Outcome", func(t *testing.T) {
+		assert.Equal(t, DefaultSLABreachOutcome, (&SLAConfig{Duration: "1h"}).BreachOutcome())
+		assert.Equal(t, "OGA_OVERDUE", (&SLAConfig{Duration: "1h", Outcome: "OGA_OVERDUE"}).BreachOutcome())
+	})
+
+	t.Run("Start SLA", func(t *testing.T) {
+		now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+		breachedAt := now.Add(-time.Hour)
+		node := WorkflowNode{SLA: &SLAConfig{Duration: "72h"}, SLABreachedAt: &breachedAt}
+
+		node.StartSLA(now)
+
+		assert.Equal(t, now.Add(72*time.Hour), *node.SLADeadline)
+		assert.Nil(t, node.SLABreachedAt)
+	})
+
+	t.Run("Start Without SLA", func(t *testing.T) {
+		node := WorkflowNode{}
+		node.StartSLA(time.Now())
+		assert.Nil(t, node.SLADeadline)
+	})
+}
+
+func TestWorkflowNodeTemplate_Validate(t *testing.T) {
+	id := uuid.New()
+	valid := func() WorkflowNodeTemplate {
+		return WorkflowNodeTemplate{
+			BaseModel: BaseModel{ID: id},
+			Name:      "Customs Declaration",
+			Type:      "SIMPLE_FORM",
+			DependsOn: UUIDArray{uuid.New()},
+		}
+	}
+
+	nodeTemplate := valid()
+	assert.NoError(t, nodeTemplate.Validate())
+	live := taskPlugin.GlobalContextLive
+	nodeTemplate.GlobalContextMode = &live
+	assert.NoError(t, nodeTemplate.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*WorkflowNodeTemplate)
+	}{
+		{"Blank Name", func(nt *WorkflowNodeTemplate) { nt.Name = " " }},
+		{"Missing Type", func(nt *WorkflowNodeTemplate) { nt.Type = "" }},
+		{"Depends On Itself", func(nt *WorkflowNodeTemplate) { nt.DependsOn = UUIDArray{id} }},
+		{"Duplicate Dependency", func(nt *WorkflowNodeTemplate) { nt.DependsOn = append(nt.DependsOn, nt.DependsOn[0]) }},
+		{"Invalid Unlock Configuration", func(nt *WorkflowNodeTemplate) { nt.UnlockConfiguration = &UnlockConfig{} }},
+		{"Unlocks On Itself", func(nt *WorkflowNodeTemplate) {
+			nt.UnlockConfiguration = &UnlockConfig{AnyOf: []UnlockGroup{{AllOf: []UnlockCondition{{NodeTemplateID: id, State: strPtr("COMPLETED")}}}}}
+		}},
+		{"Invalid Reopen Policy", func(nt *WorkflowNodeTemplate) { policy := ReopenPolicy("ANYONE"); nt.ReopenPolicy = &policy }},
+		{"Invalid SLA", func(nt *WorkflowNodeTemplate) { nt.SLA = &SLAConfig{Duration: "soon"} }},
+		{"Invalid Scope", func(nt *WorkflowNodeTemplate) { nt.Scope = "LINE" }},
+		{"Invalid Context Merge", func(nt *WorkflowNodeTemplate) {
+			nt.ContextMerge = &ContextMergeConfig{ConflictPolicy: ContextConflictNamespaced}
+		}},
+		{"Invalid Global Context Mode", func(nt *WorkflowNodeTemplate) {
+			mode := taskPlugin.GlobalContextMode("EVENTUAL")
+			nt.GlobalContextMode = &mode
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeTemplate := valid()
+			tt.modify(&nodeTemplate)
+			assert.Error(t, nodeTemplate.Validate())
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestContextMergeConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&ContextMergeConfig{ConflictPolicy: ContextConflictLastWriterWins}).Validate())
+	assert.NoError(t, (&ContextMergeConfig{ConflictPolicy: ContextConflictReject}).Validate())
+	assert.NoError(t, (&ContextMergeConfig{ConflictPolicy: ContextConflictNamespaced, Namespace: "phytosanitary"}).Validate())
+	assert.ErrorContains(t, (&ContextMergeConfig{ConflictPolicy: "FIRST_WRITER_WINS"}).Validate(), "invalid context conflict policy")
+	assert.ErrorContains(t, (&ContextMergeConfig{ConflictPolicy: ContextConflictReject, Namespace: "lab"}).Validate(), "only allowed")
+	assert.ErrorContains(t, (&ContextMergeConfig{ConflictPolicy: ContextConflictNamespaced, Namespace: " "}).Validate(), "is required")
+	assert.ErrorContains(t, (&ContextMergeConfig{ConflictPolicy: ContextConflictNamespaced, Namespace: "lab.results"}).Validate(), "must not contain")
+
+	var config *ContextMergeConfig
+	assert.Equal(t, ContextConflictLastWriterWins, config.Policy())
+}
+
+func TestPreConsignmentValidity(t *testing.T) {
+	completedAt := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, (&PreConsignmentValidity{Duration: "8760h"}).Validate())
+		assert.NoError(t, (&PreConsignmentValidity{ExpiresAtContextKey: "epl:expiry"}).Validate())
+		assert.ErrorContains(t, (&PreConsignmentValidity{}).Validate(), "must have a duration or an expiresAtContextKey")
+		assert.ErrorContains(t, (&PreConsignmentValidity{Duration: "1y"}).Validate(), "invalid validity duration")
+		assert.ErrorContains(t, (&PreConsignmentValidity{Duration: "0s"}).Validate(), "must be positive")
+	})
+
+	t.Run("ExpiresAt", func(t *testing.T) {
+		validity := &PreConsignmentValidity{Duration: "24h", ExpiresAtContextKey: "epl:expiry"}
+		tests := []struct {
+			name          string
+			validity      *PreConsignmentValidity
+			traderContext map[string]any
+			want          *time.Time
+		}{
+			{name: "Timestamp From Context", validity: validity, traderContext: map[string]any{"epl:expiry": "2027-03-31T12:00:00+05:30"}, want: timePtr(time.Date(2027, 3, 31, 6, 30, 0, 0, time.UTC))},
+			{name: "Date From Context Is Valid Through The Day", validity: validity, traderContext: map[string]any{"epl:expiry": "2027-03-31"}, want: timePtr(time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC))},
+			{name: "Unparseable Context Value Falls Back To Duration", validity: validity, traderContext: map[string]any{"epl:expiry": "next year"}, want: timePtr(completedAt.Add(24 * time.Hour))},
+			{name: "Missing Context Value Falls Back To Duration", validity: validity, want: timePtr(completedAt.Add(24 * time.Hour))},
+			{name: "Missing Context Value Without Duration", validity: &PreConsignmentValidity{ExpiresAtContextKey: "epl:expiry"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.want, tt.validity.ExpiresAt(completedAt, tt.traderContext))
+			})
+		}
+	})
+
+	t.Run("EffectiveState", func(t *testing.T) {
+		now := completedAt.Add(time.Hour)
+		assert.Equal(t, PreConsignmentStateCompleted, (&PreConsignment{State: PreConsignmentStateCompleted}).EffectiveState(now))
+		assert.Equal(t, PreConsignmentStateCompleted, (&PreConsignment{State: PreConsignmentStateCompleted, ExpiresAt: timePtr(now.Add(time.Second))}).EffectiveState(now))
+		assert.Equal(t, PreConsignmentStateExpired, (&PreConsignment{State: PreConsignmentStateCompleted, ExpiresAt: &now}).EffectiveState(now))
+		assert.Equal(t, PreConsignmentStateRevoked, (&PreConsignment{State: PreConsignmentStateRevoked}).EffectiveState(now))
+		assert.False(t, (&PreConsignment{State: PreConsignmentStateRevoked}).IsValid(now))
+	})
+}
+
+func TestWorkflowTemplate_Validate(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	nodeTemplates := func() []WorkflowNodeTemplate {
+		return []WorkflowNodeTemplate{
+			{BaseModel: BaseModel{ID: a}, Name: "A"},
+			{BaseModel: BaseModel{ID: b}, Name: "B", DependsOn: UUIDArray{a}},
+			{BaseModel: BaseModel{ID: c}, Name: "C", UnlockConfiguration: &UnlockConfig{Expression: &UnlockExpression{
+				AnyOf: []UnlockExpression{{NodeTemplateID: a, Outcome: strPtr("REJECTED")}, {NodeTemplateID: b, State: strPtr("COMPLETED")}},
+			}}},
+		}
+	}
+	workflowTemplate := func() WorkflowTemplate {
+		return WorkflowTemplate{Name: "Export", Version: "1.0", NodeTemplates: UUIDArray{a, b, c}, EndNodeTemplateID: &c}
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		wt := workflowTemplate()
+		assert.NoError(t, wt.Validate(nodeTemplates()))
+	})
+
+	t.Run("No Node Templates", func(t *testing.T) {
+		wt := workflowTemplate()
+		wt.NodeTemplates = nil
+		wt.EndNodeTemplateID = nil
+		assert.ErrorContains(t, wt.Validate(nil), "at least one node template")
+	})
+
+	t.Run("Missing Node Template", func(t *testing.T) {
+		wt := workflowTemplate()
+		assert.ErrorContains(t, wt.Validate(nodeTemplates()[:2]), "does not exist")
+	})
+
+	t.Run("Dependency Outside Template", func(t *testing.T) {
+		wt := workflowTemplate()
+		wt.NodeTemplates = UUIDArray{b, c}
+		wt.EndNodeTemplateID = nil
+		assert.ErrorContains(t, wt.Validate(nodeTemplates()), "depends on node template "+a.String())
+	})
+
+	t.Run("Unlock Configuration Outside Template", func(t *testing.T) {
+		nts := nodeTemplates()
+		nts[1].DependsOn = nil
+		wt := workflowTemplate()
+		wt.NodeTemplates = UUIDArray{b, c}
+		wt.EndNodeTemplateID = nil
+		assert.ErrorContains(t, wt.Validate(nts), "unlock configuration of node template "+c.String())
+	})
+
+	t.Run("End Node Outside Template", func(t *testing.T) {
+		wt := workflowTemplate()
+		other := uuid.New()
+		wt.EndNodeTemplateID = &other
+		assert.ErrorContains(t, wt.Validate(nodeTemplates()), "end node template")
+	})
+
+	t.Run("Dependency Cycle", func(t *testing.T) {
+		nts := nodeTemplates()
+		nts[0].DependsOn = UUIDArray{c}
+		wt := workflowTemplate()
+		err := wt.Validate(nts)
+		assert.ErrorContains(t, err, "dependency cycle")
+		assert.ErrorContains(t, err, a.String())
+		assert.ErrorContains(t, err, c.String())
+	})
+
+	t.Run("Required Pre-Consignments", func(t *testing.T) {
+		br, tin := uuid.New(), uuid.New()
+		tests := []struct {
+			name         string
+			requirements []PreConsignmentRequirement
+			wantErr      string
+		}{
+			{name: "Valid", requirements: []PreConsignmentRequirement{{PreConsignmentTemplateID: br, ContextKey: "br"}, {PreConsignmentTemplateID: tin, ContextKey: "tin"}}},
+			{name: "Missing Template", requirements: []PreConsignmentRequirement{{ContextKey: "br"}}, wantErr: "must have a pre-consignment template ID"},
+			{name: "Repeated Template", requirements: []PreConsignmentRequirement{{PreConsignmentTemplateID: br, ContextKey: "br"}, {PreConsignmentTemplateID: br, ContextKey: "br2"}}, wantErr: "required more than once"},
+			{name: "Blank Context Key", requirements: []PreConsignmentRequirement{{PreConsignmentTemplateID: br, ContextKey: " "}}, wantErr: "must have a context key"},
+			{name: "Dotted Context Key", requirements: []PreConsignmentRequirement{{PreConsignmentTemplateID: br, ContextKey: "roc.br"}}, wantErr: "must not contain '.'"},
+			{name: "Repeated Context Key", requirements: []PreConsignmentRequirement{{PreConsignmentTemplateID: br, ContextKey: "reg"}, {PreConsignmentTemplateID: tin, ContextKey: "reg"}}, wantErr: "used by more than one"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				wt := workflowTemplate()
+				wt.RequiredPreConsignments = tt.requirements
+				err := wt.Validate(nodeTemplates())
+				if tt.wantErr == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorContains(t, err, tt.wantErr)
+				}
+			})
+		}
+	})
+}
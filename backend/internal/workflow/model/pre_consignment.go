@@ -1,6 +1,11 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type PreConsignmentState string
 
@@ -9,14 +14,70 @@ const (
 	PreConsignmentStateReady      PreConsignmentState = "READY"       // Pre-consignment is ready to be processed
 	PreConsignmentStateInProgress PreConsignmentState = "IN_PROGRESS" // Pre-consignment is currently being processed
 	PreConsignmentStateCompleted  PreConsignmentState = "COMPLETED"   // Pre-consignment has been completed
+	PreConsignmentStateExpired    PreConsignmentState = "EXPIRED"     // Pre-consignment was completed but its validity period has ended
+	PreConsignmentStateRevoked    PreConsignmentState = "REVOKED"     // Pre-consignment was completed but an agency revoked it
 )
 
+// PreConsignmentValidity declares how long a completed pre-consignment stays valid. The expiry is read from
+// the trader context under ExpiresAtContextKey, e.g. the expiry date a licence form writes, as an RFC 3339
+// timestamp or a date, which is valid through the end of that day (UTC). If the key is not set, missing or
+// its value cannot be parsed, the pre-consignment expires Duration after it is completed.
+//
+// Example JSON:
+//
+//	{"duration": "8760h", "expiresAtContextKey": "cea:epl:expiry_date"}
+type PreConsignmentValidity struct {
+	Duration            string `json:"duration,omitempty"`            // Validity after completion, as a Go duration (e.g. "8760h")
+	ExpiresAtContextKey string `json:"expiresAtContextKey,omitempty"` // Trader context key holding the expiry timestamp or date
+}
+
+// Validate checks that the validity declares a positive duration, a context key or both.
+func (v *PreConsignmentValidity) Validate() error {
+	if v.Duration == "" && v.ExpiresAtContextKey == "" {
+		return fmt.Errorf("validity must have a duration or an expiresAtContextKey")
+	}
+	if v.Duration != "" {
+		duration, err := time.ParseDuration(v.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid validity duration %q: %w", v.Duration, err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("validity duration must be positive")
+		}
+	}
+	return nil
+}
+
+// ExpiresAt returns when a pre-consignment completed at completedAt with the given trader context expires,
+// or nil if neither the trader context nor the duration gives an expiry.
+func (v *PreConsignmentValidity) ExpiresAt(completedAt time.Time, traderContext map[string]any) *time.Time {
+	if v.ExpiresAtContextKey != "" {
+		if value, ok := traderContext[v.ExpiresAtContextKey].(string); ok {
+			if expiresAt, err := time.Parse(time.RFC3339, value); err == nil {
+				expiresAt = expiresAt.UTC()
+				return &expiresAt
+			}
+			if date, err := time.Parse(time.DateOnly, value); err == nil {
+				expiresAt := date.AddDate(0, 0, 1)
+				return &expiresAt
+			}
+		}
+	}
+	if duration, err := time.ParseDuration(v.Duration); err == nil {
+		expiresAt := completedAt.Add(duration)
+		return &expiresAt
+	}
+	return nil
+}
+
 type PreConsignmentTemplate struct {
 	BaseModel
 	Name               string    `gorm:"type:varchar(255);column:name;not null" json:"name"`            // Human-readable name of the pre-consignment template
 	Description        string    `gorm:"type:text;column:description" json:"description"`               // Optional description of the pre-consignment template
 	WorkflowTemplateID uuid.UUID `json:"workflowTemplateId"`                                            // ID of the workflow template to use for this pre-consignment
 	DependsOn          []string  `gorm:"type:jsonb;column:depends_on;serializer:json" json:"dependsOn"` // List of pre-consignment template IDs that this pre-consignment template depends on
+
+	Validity *PreConsignmentValidity `gorm:"type:jsonb;column:validity;serializer:json" json:"validity,omitempty"` // Optional validity of completed pre-consignments; they never expire if nil
 }
 
 func (pct *PreConsignmentTemplate) TableName() string {
@@ -29,6 +90,10 @@ type PreConsignment struct {
 	PreConsignmentTemplateID uuid.UUID           `gorm:"type:uuid;not null" json:"preConsignmentTemplateId"`
 	State                    PreConsignmentState `gorm:"type:varchar(50);not null" json:"state"`
 	TraderContext            map[string]any      `gorm:"type:jsonb;column:trader_context;serializer:json;not null" json:"traderContext"` // Context specific to the trader
+	ExpiresAt                *time.Time          `gorm:"type:timestamptz;column:expires_at" json:"expiresAt,omitempty"`                  // When the completed pre-consignment stops being valid; nil if it never expires
+	RevokedAt                *time.Time          `gorm:"type:timestamptz;column:revoked_at" json:"revokedAt,omitempty"`                  // When an agency revoked the pre-consignment
+	RevocationReason         *string             `gorm:"type:text;column:revocation_reason" json:"revocationReason,omitempty"`           // Why the pre-consignment was revoked
+	RenewedFromID            *uuid.UUID          `gorm:"type:uuid;column:renewed_from_id" json:"renewedFromId,omitempty"`                // Pre-consignment this one renews
 
 	// Relationships
 	PreConsignmentTemplate PreConsignmentTemplate `gorm:"foreignKey:PreConsignmentTemplateID;references:ID" json:"-"` // Associated PreConsignmentTemplate
@@ -39,11 +104,31 @@ func (pc *PreConsignment) TableName() string {
 	return "pre_consignments"
 }
 
+// EffectiveState returns the state of the pre-consignment at now: a COMPLETED pre-consignment whose expiry has
+// passed is EXPIRED even before the expiry monitor updates it.
+func (pc *PreConsignment) EffectiveState(now time.Time) PreConsignmentState {
+	if pc.State == PreConsignmentStateCompleted && pc.ExpiresAt != nil && !now.Before(*pc.ExpiresAt) {
+		return PreConsignmentStateExpired
+	}
+	return pc.State
+}
+
+// IsValid reports whether the pre-consignment is completed and has neither expired at now nor been revoked,
+// so that it satisfies the pre-consignments and consignments that depend on it.
+func (pc *PreConsignment) IsValid(now time.Time) bool {
+	return pc.EffectiveState(now) == PreConsignmentStateCompleted
+}
+
 // CreatePreConsignmentDTO is used to create a new pre-consignment.
 type CreatePreConsignmentDTO struct {
 	PreConsignmentTemplateID uuid.UUID `json:"preConsignmentTemplateId" validate:"required"` // ID of the pre-consignment template to use
 }
 
+// RevokePreConsignmentDTO is used by an agency to revoke a completed pre-consignment.
+type RevokePreConsignmentDTO struct {
+	Reason string `json:"reason"` // Why the pre-consignment is revoked
+}
+
 // UpdatePreConsignmentStateDTO is used to update the state of a pre-consignment.
 type UpdatePreConsignmentStateDTO struct {
 	State         PreConsignmentState `json:"state" validate:"required"` // New state of the pre-consignment
@@ -58,6 +143,13 @@ type PreConsignmentTemplateResponseDTO struct {
 	DependsOn   []string  `json:"dependsOn"`   // List of dependency template IDs
 }
 
+// MissingPreConsignmentsResponseDTO is returned when a consignment cannot be created because the trader has not
+// completed the pre-consignments its workflow templates require.
+type MissingPreConsignmentsResponseDTO struct {
+	Error                  string                              `json:"error"`                  // Description of the failure
+	MissingPreConsignments []PreConsignmentTemplateResponseDTO `json:"missingPreConsignments"` // Templates of the pre-consignments the trader has to complete first
+}
+
 // TraderPreConsignmentResponseDTO represents a pre-consignment template in the response.
 type TraderPreConsignmentResponseDTO struct {
 	ID               uuid.UUID           `json:"id"`                         // Template ID
@@ -73,20 +165,25 @@ type TraderPreConsignmentResponseDTO struct {
 
 // TraderPreConsignmentsResponseDTO represents a list of pre-consignment templates for a trader in the response.
 type TraderPreConsignmentsResponseDTO struct {
-	TotalCount int64                             `json:"totalCount"` // Total number of pre-consignment templates for the trader
-	Items      []TraderPreConsignmentResponseDTO `json:"items"`      // List of pre-consignment templates for the trader
-	Offset     int64                             `json:"offset"`     // Pagination offset
-	Limit      int64                             `json:"limit"`      // Pagination limit
+	TotalCount *int64                            `json:"totalCount,omitempty"` // Total number of pre-consignment templates for the trader; absent on pages selected by a cursor
+	Items      []TraderPreConsignmentResponseDTO `json:"items"`                // List of pre-consignment templates for the trader
+	Offset     int64                             `json:"offset"`               // Pagination offset
+	Limit      int64                             `json:"limit"`                // Pagination limit
+	NextCursor *string                           `json:"nextCursor,omitempty"` // Cursor of the next page; absent on the last page
 }
 
 // PreConsignmentResponseDTO represents a pre-consignment in the response.
 type PreConsignmentResponseDTO struct {
-	ID                     uuid.UUID                         `json:"id"`                     // Pre-consignment ID
-	TraderID               string                            `json:"traderId"`               // Trader ID associated with the pre-consignment
-	State                  PreConsignmentState               `json:"state"`                  // State of the pre-consignment
-	TraderContext          map[string]any                    `json:"traderContext"`          // Trader-specific context
-	CreatedAt              string                            `json:"createdAt"`              // Timestamp of creation
-	UpdatedAt              string                            `json:"updatedAt"`              // Timestamp of last update
-	PreConsignmentTemplate PreConsignmentTemplateResponseDTO `json:"preConsignmentTemplate"` // Template details
-	WorkflowNodes          []WorkflowNodeResponseDTO         `json:"workflowNodes"`          // Associated workflow nodes
+	ID                     uuid.UUID                         `json:"id"`                         // Pre-consignment ID
+	TraderID               string                            `json:"traderId"`                   // Trader ID associated with the pre-consignment
+	State                  PreConsignmentState               `json:"state"`                      // State of the pre-consignment
+	TraderContext          map[string]any                    `json:"traderContext"`              // Trader-specific context
+	ExpiresAt              *string                           `json:"expiresAt,omitempty"`        // When the pre-consignment stops being valid, if it expires
+	RevokedAt              *string                           `json:"revokedAt,omitempty"`        // When an agency revoked the pre-consignment
+	RevocationReason       *string                           `json:"revocationReason,omitempty"` // Why the pre-consignment was revoked
+	RenewedFromID          *uuid.UUID                        `json:"renewedFromId,omitempty"`    // Pre-consignment this one renews
+	CreatedAt              string                            `json:"createdAt"`                  // Timestamp of creation
+	UpdatedAt              string                            `json:"updatedAt"`                  // Timestamp of last update
+	PreConsignmentTemplate PreConsignmentTemplateResponseDTO `json:"preConsignmentTemplate"`     // Template details
+	WorkflowNodes          []WorkflowNodeResponseDTO         `json:"workflowNodes"`              // Associated workflow nodes
 }
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// ReconciliationIssue describes a divergence between a workflow node and its task that could not be fixed automatically.
+type ReconciliationIssue struct {
+	WorkflowNodeID uuid.UUID         `json:"workflowNodeId"`      // Workflow node with the divergence
+	NodeState      WorkflowNodeState `json:"nodeState"`           // State of the workflow node
+	TaskState      *taskPlugin.State `json:"taskState,omitempty"` // State of the task, nil if the task record is missing
+	Reason         string            `json:"reason"`              // Why the divergence could not be fixed
+}
+
+// ReconciliationReport summarizes one reconciliation run between workflow nodes and task records.
+type ReconciliationReport struct {
+	StartedAt           time.Time             `json:"startedAt"`           // When the run started
+	FinishedAt          time.Time             `json:"finishedAt"`          // When the run finished
+	NodesChecked        int                   `json:"nodesChecked"`        // Number of active workflow nodes inspected
+	RegisteredTasks     []uuid.UUID           `json:"registeredTasks"`     // READY nodes whose missing task was registered again
+	ResentNotifications []uuid.UUID           `json:"resentNotifications"` // Nodes whose task state notification was emitted again
+	CancelledTasks      []uuid.UUID           `json:"cancelledTasks"`      // Nodes locked by a cancellation whose task was cancelled
+	Unresolved          []ReconciliationIssue `json:"unresolved"`          // Divergences that need manual attention
+}
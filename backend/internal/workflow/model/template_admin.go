@@ -0,0 +1,99 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// WorkflowNodeTemplateDTO is used to create or replace a workflow node template.
+type WorkflowNodeTemplateDTO struct {
+	Name                string                        `json:"name"`                          // Human-readable name of the workflow node template
+	Description         string                        `json:"description"`                   // Optional description of the workflow node template
+	Type                taskPlugin.Type               `json:"type"`                          // Type of the workflow node
+	Config              json.RawMessage               `json:"config"`                        // Configuration specific to the workflow node type
+	DependsOn           UUIDArray                     `json:"depends_on"`                    // Workflow node template IDs this node depends on
+	UnlockConfiguration *UnlockConfig                 `json:"unlockConfiguration,omitempty"` // Optional conditional unlock configuration
+	ReopenPolicy        *ReopenPolicy                 `json:"reopenPolicy,omitempty"`        // Who may reopen FAILED nodes of this template
+	SLA                 *SLAConfig                    `json:"sla,omitempty"`                 // Optional SLA of nodes of this template
+	ContextMerge        *ContextMergeConfig           `json:"contextMerge,omitempty"`        // How nodes of this template merge the context they append
+	GlobalContextMode   *taskPlugin.GlobalContextMode `json:"globalContextMode,omitempty"`   // Whether tasks of this template read the context of their workflow live
+}
+
+// WorkflowTemplateDTO is used to create a DRAFT workflow template version or to replace one.
+type WorkflowTemplateDTO struct {
+	Name              string     `json:"name"`                        // Name of the workflow template
+	Description       string     `json:"description"`                 // Description of the workflow template
+	Version           string     `json:"version"`                     // Human-readable version label
+	FamilyID          *uuid.UUID `json:"familyId,omitempty"`          // Family to add the version to; a new family is created if nil. Ignored when replacing a draft.
+	NodeTemplates     UUIDArray  `json:"nodes"`                       // Workflow node template IDs of the workflow
+	EndNodeTemplateID *uuid.UUID `json:"endNodeTemplateId,omitempty"` // Optional end node template ID; must be one of the workflow's node templates
+
+	RequiredPreConsignments []PreConsignmentRequirement `json:"requiredPreConsignments,omitempty"` // Pre-consignments a trader must have completed before creating a consignment
+}
+
+// WorkflowTemplateMapDTO is used to create or replace an HS code to workflow template family mapping.
+type WorkflowTemplateMapDTO struct {
+	HSCodeID                 uuid.UUID       `json:"hsCodeId"`
+	ConsignmentFlow          ConsignmentFlow `json:"consignmentFlow"`
+	WorkflowTemplateFamilyID uuid.UUID       `json:"workflowTemplateFamilyId"`
+}
+
+// PreConsignmentTemplateDTO is used to create or replace a pre-consignment template.
+type PreConsignmentTemplateDTO struct {
+	Name               string    `json:"name"`               // Human-readable name of the pre-consignment template
+	Description        string    `json:"description"`        // Optional description of the pre-consignment template
+	WorkflowTemplateID uuid.UUID `json:"workflowTemplateId"` // Published workflow template version to use
+	DependsOn          []string  `json:"dependsOn"`          // Pre-consignment template IDs this pre-consignment template depends on
+
+	Validity *PreConsignmentValidity `json:"validity,omitempty"` // Optional validity of completed pre-consignments
+}
+
+// WorkflowTemplateFilter is used when listing workflow template versions.
+type WorkflowTemplateFilter struct {
+	FamilyID *uuid.UUID              `json:"familyId,omitempty"`
+	Status   *WorkflowTemplateStatus `json:"status,omitempty"`
+	Offset   *int                    `json:"offset,omitempty"`
+	Limit    *int                    `json:"limit,omitempty"`
+}
+
+// WorkflowTemplateMapFilter is used when listing HS code to workflow template family mappings.
+type WorkflowTemplateMapFilter struct {
+	HSCodeID *uuid.UUID `json:"hsCodeId,omitempty"`
+	Offset   *int       `json:"offset,omitempty"`
+	Limit    *int       `json:"limit,omitempty"`
+}
+
+// WorkflowNodeTemplateListResult represents the result of querying workflow node templates with pagination.
+type WorkflowNodeTemplateListResult struct {
+	TotalCount int64                  `json:"totalCount"`
+	Items      []WorkflowNodeTemplate `json:"items"`
+	Offset     int                    `json:"offset"`
+	Limit      int                    `json:"limit"`
+}
+
+// WorkflowTemplateListResult represents the result of querying workflow template versions with pagination.
+type WorkflowTemplateListResult struct {
+	TotalCount int64              `json:"totalCount"`
+	Items      []WorkflowTemplate `json:"items"`
+	Offset     int                `json:"offset"`
+	Limit      int                `json:"limit"`
+}
+
+// WorkflowTemplateMapListResult represents the result of querying HS code mappings with pagination.
+type WorkflowTemplateMapListResult struct {
+	TotalCount int64                 `json:"totalCount"`
+	Items      []WorkflowTemplateMap `json:"items"`
+	Offset     int                   `json:"offset"`
+	Limit      int                   `json:"limit"`
+}
+
+// PreConsignmentTemplateListResult represents the result of querying pre-consignment templates with pagination.
+type PreConsignmentTemplateListResult struct {
+	TotalCount int64                    `json:"totalCount"`
+	Items      []PreConsignmentTemplate `json:"items"`
+	Offset     int                      `json:"offset"`
+	Limit      int                      `json:"limit"`
+}
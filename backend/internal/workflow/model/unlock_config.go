@@ -8,6 +8,15 @@ import (
 	"github.com/google/uuid"
 )
 
+// UnlockItemQuantifier tells whether a condition on an item-scoped node template must hold for the nodes of
+// all items or of any item.
+type UnlockItemQuantifier string
+
+const (
+	UnlockItemsAll UnlockItemQuantifier = "ALL" // The condition must hold for the node of every item
+	UnlockItemsAny UnlockItemQuantifier = "ANY" // The condition must hold for the node of at least one item
+)
+
 // UnlockCondition represents a single condition that checks a specific dependency node's state and/or outcome.
 // Both State and Outcome are optional.
 // When both are specified, they are combined with AND logic (both must match).
@@ -26,6 +35,10 @@ type UnlockCondition struct {
 	// Outcome is the expected outcome value of the referenced node (e.g., "APPROVED", "REJECTED").
 	// Optional — if nil, the node's outcome is not checked.
 	Outcome *string `json:"outcome,omitempty"`
+
+	// Items tells whether a condition on an item-scoped node template must hold for all items or any item.
+	// Optional — if empty, a node of an item references the node of the same item, and any other node all items.
+	Items UnlockItemQuantifier `json:"items,omitempty"`
 }
 
 // UnlockGroup represents a group of conditions that must ALL be true (AND logic).
@@ -38,17 +51,32 @@ type UnlockGroup struct {
 // Exactly one of the following must be present:
 //   - AnyOf: OR across child expressions
 //   - AllOf: AND across child expressions
-//   - Leaf condition: NodeTemplateID (with optional State and/or Outcome)
+//   - AtLeast: at least N of the child expressions
+//   - None: NOR across child expressions, i.e. none of them holds
+//   - Context: a predicate on a value in the GlobalContext or TraderContext of the workflow
+//   - Leaf condition: NodeTemplateID (with optional State and/or Outcome, and Items for item-scoped node templates)
 //
-// This enables arbitrary nesting of AND/OR expressions.
+// This enables arbitrary nesting of AND/OR expressions. Inside None, a leaf condition without Items that stands
+// for the nodes of several items defaults to UnlockItemsAny instead of UnlockItemsAll, so that
+// none: [{nodeTemplateId, state: FAILED}] holds only if the node of no item failed.
 type UnlockExpression struct {
-	AnyOf []UnlockExpression `json:"anyOf,omitempty"`
-	AllOf []UnlockExpression `json:"allOf,omitempty"`
+	AnyOf   []UnlockExpression      `json:"anyOf,omitempty"`
+	AllOf   []UnlockExpression      `json:"allOf,omitempty"`
+	AtLeast *UnlockQuorum           `json:"atLeast,omitempty"`
+	None    []UnlockExpression      `json:"none,omitempty"`
+	Context *UnlockContextPredicate `json:"context,omitempty"`
+
+	NodeTemplateID uuid.UUID            `json:"nodeTemplateId,omitempty"`
+	NodeID         *uuid.UUID           `json:"nodeId,omitempty"`
+	State          *string              `json:"state,omitempty"`
+	Outcome        *string              `json:"outcome,omitempty"`
+	Items          UnlockItemQuantifier `json:"items,omitempty"`
+}
 
-	NodeTemplateID uuid.UUID  `json:"nodeTemplateId,omitempty"`
-	NodeID         *uuid.UUID `json:"nodeId,omitempty"`
-	State          *string    `json:"state,omitempty"`
-	Outcome        *string    `json:"outcome,omitempty"`
+// UnlockQuorum is satisfied when at least N of the expressions in Of are, e.g. 2 of 3 lab results.
+type UnlockQuorum struct {
+	N  int                `json:"n"`
+	Of []UnlockExpression `json:"of"`
 }
 
 // UnlockConfig represents the unlock configuration for a workflow node.
@@ -136,30 +164,32 @@ func (uc *UnlockConfig) validateCondition(cond UnlockCondition, path string) err
 	if cond.Outcome != nil && len(strings.TrimSpace(*cond.Outcome)) == 0 {
 		return fmt.Errorf("%s has empty outcome", path)
 	}
+	if cond.Items != "" && cond.Items != UnlockItemsAll && cond.Items != UnlockItemsAny {
+		return fmt.Errorf("%s has invalid items %q", path, cond.Items)
+	}
 	return nil
 }
 
 func (uc *UnlockConfig) validateExpression(expr UnlockExpression, path string) error {
 	hasAny := len(expr.AnyOf) > 0
 	hasAll := len(expr.AllOf) > 0
-	hasLeaf := expr.NodeTemplateID != uuid.Nil || expr.State != nil || expr.Outcome != nil
+	hasAtLeast := expr.AtLeast != nil
+	hasNone := len(expr.None) > 0
+	hasContext := expr.Context != nil
+	hasLeaf := expr.NodeTemplateID != uuid.Nil || expr.State != nil || expr.Outcome != nil || expr.Items != ""
 
 	definedCount := 0
-	if hasAny {
-		definedCount++
-	}
-	if hasAll {
-		definedCount++
-	}
-	if hasLeaf {
-		definedCount++
+	for _, defined := range []bool{hasAny, hasAll, hasAtLeast, hasNone, hasContext, hasLeaf} {
+		if defined {
+			definedCount++
+		}
 	}
 
 	if definedCount == 0 {
-		return fmt.Errorf("%s must define one of anyOf, allOf, or a condition", path)
+		return fmt.Errorf("%s must define one of anyOf, allOf, atLeast, none, context, or a condition", path)
 	}
 	if definedCount > 1 {
-		return fmt.Errorf("%s must define exactly one of anyOf, allOf, or a condition", path)
+		return fmt.Errorf("%s must define exactly one of anyOf, allOf, atLeast, none, context, or a condition", path)
 	}
 
 	if hasAny {
@@ -180,24 +210,104 @@ func (uc *UnlockConfig) validateExpression(expr UnlockExpression, path string) e
 		return nil
 	}
 
+	if hasAtLeast {
+		if len(expr.AtLeast.Of) == 0 {
+			return fmt.Errorf("%s.atLeast must have at least one expression in of", path)
+		}
+		if expr.AtLeast.N < 1 || expr.AtLeast.N > len(expr.AtLeast.Of) {
+			return fmt.Errorf("%s.atLeast has n %d, must be between 1 and the %d expressions in of", path, expr.AtLeast.N, len(expr.AtLeast.Of))
+		}
+		for i, child := range expr.AtLeast.Of {
+			if err := uc.validateExpression(child, fmt.Sprintf("%s.atLeast.of[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if hasNone {
+		for i, child := range expr.None {
+			if err := uc.validateExpression(child, fmt.Sprintf("%s.none[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if hasContext {
+		return expr.Context.Validate(path + ".context")
+	}
+
 	return uc.validateCondition(UnlockCondition{
 		NodeTemplateID: expr.NodeTemplateID,
 		State:          expr.State,
 		Outcome:        expr.Outcome,
+		Items:          expr.Items,
 	}, path)
 }
 
+// NodeInstance is a workflow node instantiated from a node template, as referenced when resolving unlock
+// configurations. ItemIndex is set for the nodes of item-scoped node templates.
+type NodeInstance struct {
+	NodeID    uuid.UUID
+	ItemIndex *int
+}
+
+// NodeInstances maps node template IDs to the workflow nodes instantiated from them.
+type NodeInstances map[uuid.UUID][]NodeInstance
+
+// Select returns the nodes a reference to the node template stands for in a node of the item itemIndex, or
+// in a consignment-wide node if nil, and whether a condition on them must hold for all or any of them. A
+// reference without a quantifier from a node of an item stands for the node of the same item if there is
+// one; any other reference stands for all nodes of the template.
+func (ni NodeInstances) Select(nodeTemplateID uuid.UUID, itemIndex *int, items UnlockItemQuantifier) ([]uuid.UUID, UnlockItemQuantifier) {
+	instances := ni[nodeTemplateID]
+	if items == "" && itemIndex != nil {
+		for _, instance := range instances {
+			if instance.ItemIndex != nil && *instance.ItemIndex == *itemIndex {
+				return []uuid.UUID{instance.NodeID}, UnlockItemsAll
+			}
+		}
+	}
+	if items == "" {
+		items = UnlockItemsAll
+	}
+	nodeIDs := make([]uuid.UUID, 0, len(instances))
+	for _, instance := range instances {
+		nodeIDs = append(nodeIDs, instance.NodeID)
+	}
+	return nodeIDs, items
+}
+
 // ResolveToInstanceIDs creates a copy of the UnlockConfig with template IDs replaced by instance node IDs.
 // The templateToNodeID map should contain template ID -> node instance ID mappings.
 func (uc *UnlockConfig) ResolveToInstanceIDs(templateToNodeID map[uuid.UUID]uuid.UUID) (*UnlockConfig, error) {
+	instances := make(NodeInstances, len(templateToNodeID))
+	for templateID, nodeID := range templateToNodeID {
+		instances[templateID] = []NodeInstance{{NodeID: nodeID}}
+	}
+	return uc.ResolveToNodeInstances(instances, nil)
+}
+
+// ResolveToNodeInstances creates a copy of the UnlockConfig with template IDs replaced by the IDs of the nodes
+// instantiated from them, for a node of the item itemIndex, or a consignment-wide node if nil. A condition
+// that stands for the nodes of several items becomes an AND or OR of the condition on each of them, as
+// selected by NodeInstances.Select; a configuration in the legacy format is then resolved to an expression.
+func (uc *UnlockConfig) ResolveToNodeInstances(instances NodeInstances, itemIndex *int) (*UnlockConfig, error) {
 	// Validate the config before resolution
 	if err := uc.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid unlock configuration: %w", err)
 	}
 
-	// If it's an expression-based config, resolve the expression
-	if uc.Expression != nil {
-		resolvedExpr, err := uc.resolveExpressionToInstanceIDs(*uc.Expression, templateToNodeID)
+	// If it's an expression-based config, or a condition stands for several nodes, resolve the expression
+	expand := false
+	for _, cond := range uc.Conditions() {
+		if nodeIDs, _ := instances.Select(cond.NodeTemplateID, itemIndex, cond.Items); len(nodeIDs) > 1 {
+			expand = true
+		}
+	}
+	if uc.Expression != nil || expand {
+		resolvedExpr, err := uc.resolveExpressionToInstanceIDs(uc.expression(), instances, itemIndex, "")
 		if err != nil {
 			return nil, err
 		}
@@ -212,31 +322,36 @@ func (uc *UnlockConfig) ResolveToInstanceIDs(templateToNodeID map[uuid.UUID]uuid
 			AllOf: make([]UnlockCondition, len(group.AllOf)),
 		}
 		for j, cond := range group.AllOf {
-			nodeID, found := templateToNodeID[cond.NodeTemplateID]
-			if !found {
+			nodeIDs, _ := instances.Select(cond.NodeTemplateID, itemIndex, cond.Items)
+			if len(nodeIDs) == 0 {
 				return nil, fmt.Errorf("no instance node found for template ID %s in unlock configuration", cond.NodeTemplateID)
 			}
 			resolved.AnyOf[i].AllOf[j] = UnlockCondition{
 				NodeTemplateID: cond.NodeTemplateID,
-				NodeID:         &nodeID,
+				NodeID:         &nodeIDs[0],
 				State:          cond.State,
 				Outcome:        cond.Outcome,
+				Items:          cond.Items,
 			}
 		}
 	}
 	return resolved, nil
 }
 
-func (uc *UnlockConfig) resolveExpressionToInstanceIDs(expr UnlockExpression, templateToNodeID map[uuid.UUID]uuid.UUID) (UnlockExpression, error) {
+// resolveExpressionToInstanceIDs resolves expr for a node of the item itemIndex. defaultItems, if set, replaces
+// the quantifier of leaf conditions without Items that stand for several nodes; it is UnlockItemsAny inside None.
+func (uc *UnlockConfig) resolveExpressionToInstanceIDs(expr UnlockExpression, instances NodeInstances, itemIndex *int, defaultItems UnlockItemQuantifier) (UnlockExpression, error) {
 	resolved := UnlockExpression{
 		AnyOf:   make([]UnlockExpression, len(expr.AnyOf)),
 		AllOf:   make([]UnlockExpression, len(expr.AllOf)),
+		None:    make([]UnlockExpression, len(expr.None)),
+		Context: expr.Context,
 		State:   expr.State,
 		Outcome: expr.Outcome,
 	}
 
 	for i, child := range expr.AnyOf {
-		childResolved, err := uc.resolveExpressionToInstanceIDs(child, templateToNodeID)
+		childResolved, err := uc.resolveExpressionToInstanceIDs(child, instances, itemIndex, defaultItems)
 		if err != nil {
 			return UnlockExpression{}, err
 		}
@@ -244,30 +359,235 @@ func (uc *UnlockConfig) resolveExpressionToInstanceIDs(expr UnlockExpression, te
 	}
 
 	for i, child := range expr.AllOf {
-		childResolved, err := uc.resolveExpressionToInstanceIDs(child, templateToNodeID)
+		childResolved, err := uc.resolveExpressionToInstanceIDs(child, instances, itemIndex, defaultItems)
 		if err != nil {
 			return UnlockExpression{}, err
 		}
 		resolved.AllOf[i] = childResolved
 	}
 
+	if expr.AtLeast != nil {
+		resolved.AtLeast = &UnlockQuorum{N: expr.AtLeast.N, Of: make([]UnlockExpression, len(expr.AtLeast.Of))}
+		for i, child := range expr.AtLeast.Of {
+			childResolved, err := uc.resolveExpressionToInstanceIDs(child, instances, itemIndex, defaultItems)
+			if err != nil {
+				return UnlockExpression{}, err
+			}
+			resolved.AtLeast.Of[i] = childResolved
+		}
+	}
+
+	for i, child := range expr.None {
+		childResolved, err := uc.resolveExpressionToInstanceIDs(child, instances, itemIndex, UnlockItemsAny)
+		if err != nil {
+			return UnlockExpression{}, err
+		}
+		resolved.None[i] = childResolved
+	}
+
 	if expr.NodeTemplateID != uuid.Nil {
-		nodeID, found := templateToNodeID[expr.NodeTemplateID]
-		if !found {
+		nodeIDs, items := instances.Select(expr.NodeTemplateID, itemIndex, expr.Items)
+		if len(nodeIDs) == 0 {
 			return UnlockExpression{}, fmt.Errorf("no instance node found for template ID %s in unlock configuration", expr.NodeTemplateID)
 		}
-		resolved.NodeID = &nodeID
-		resolved.NodeTemplateID = expr.NodeTemplateID
+		if len(nodeIDs) == 1 {
+			resolved.NodeID = &nodeIDs[0]
+			resolved.NodeTemplateID = expr.NodeTemplateID
+			resolved.Items = expr.Items
+			return resolved, nil
+		}
+
+		// The condition must hold for the nodes of all items, or of any item
+		if expr.Items == "" && defaultItems != "" {
+			items = defaultItems
+		}
+		leaves := make([]UnlockExpression, len(nodeIDs))
+		for i := range nodeIDs {
+			leaves[i] = UnlockExpression{
+				NodeTemplateID: expr.NodeTemplateID,
+				NodeID:         &nodeIDs[i],
+				State:          expr.State,
+				Outcome:        expr.Outcome,
+			}
+		}
+		if items == UnlockItemsAny {
+			return UnlockExpression{AnyOf: leaves}, nil
+		}
+		return UnlockExpression{AllOf: leaves}, nil
 	}
 
 	return resolved, nil
 }
 
+// NodeTemplateIDs returns the node template IDs referenced by the unlock configuration, in order of
+// first appearance.
+func (uc *UnlockConfig) NodeTemplateIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	add := func(id uuid.UUID) {
+		if id != uuid.Nil && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var walk func(expr UnlockExpression)
+	walk = func(expr UnlockExpression) {
+		add(expr.NodeTemplateID)
+		for _, child := range expr.children() {
+			walk(child)
+		}
+	}
+
+	if uc.Expression != nil {
+		walk(*uc.Expression)
+	}
+	for _, group := range uc.AnyOf {
+		for _, cond := range group.AllOf {
+			add(cond.NodeTemplateID)
+		}
+	}
+	return ids
+}
+
+// children returns the child expressions of the expression, whichever operator combines them.
+func (expr UnlockExpression) children() []UnlockExpression {
+	switch {
+	case len(expr.AnyOf) > 0:
+		return expr.AnyOf
+	case len(expr.AllOf) > 0:
+		return expr.AllOf
+	case expr.AtLeast != nil:
+		return expr.AtLeast.Of
+	default:
+		return expr.None
+	}
+}
+
+// Conditions returns the leaf conditions of the unlock configuration, in order of appearance.
+func (uc *UnlockConfig) Conditions() []UnlockCondition {
+	var conds []UnlockCondition
+	var walk func(expr UnlockExpression)
+	walk = func(expr UnlockExpression) {
+		if expr.NodeTemplateID != uuid.Nil || expr.NodeID != nil {
+			conds = append(conds, UnlockCondition{NodeTemplateID: expr.NodeTemplateID, NodeID: expr.NodeID, State: expr.State, Outcome: expr.Outcome, Items: expr.Items})
+		}
+		for _, child := range expr.children() {
+			walk(child)
+		}
+	}
+
+	if uc.Expression != nil {
+		walk(*uc.Expression)
+	}
+	for _, group := range uc.AnyOf {
+		conds = append(conds, group.AllOf...)
+	}
+	return conds
+}
+
+// String formats the condition as a boolean expression on the referenced node,
+// e.g. "state == COMPLETED && outcome == APPROVED".
+func (c UnlockCondition) String() string {
+	var parts []string
+	if c.State != nil {
+		parts = append(parts, "state == "+*c.State)
+	}
+	if c.Outcome != nil {
+		parts = append(parts, "outcome == "+*c.Outcome)
+	}
+	return strings.Join(parts, " && ")
+}
+
+// Format formats the unlock configuration as a boolean expression, naming the node each condition
+// references with name, e.g. "(Review.outcome == APPROVED && Payment.state == COMPLETED) || Review.outcome == FAST_TRACKED".
+func (uc *UnlockConfig) Format(name func(UnlockCondition) string) string {
+	return formatUnlockExpression(uc.expression(), name, "")
+}
+
+// expression returns the unlock configuration as an expression. The legacy format is an OR of AND groups.
+func (uc *UnlockConfig) expression() UnlockExpression {
+	if uc.Expression != nil {
+		return *uc.Expression
+	}
+
+	expr := UnlockExpression{AnyOf: make([]UnlockExpression, len(uc.AnyOf))}
+	for i, group := range uc.AnyOf {
+		for _, cond := range group.AllOf {
+			expr.AnyOf[i].AllOf = append(expr.AnyOf[i].AllOf, UnlockExpression{
+				NodeTemplateID: cond.NodeTemplateID,
+				NodeID:         cond.NodeID,
+				State:          cond.State,
+				Outcome:        cond.Outcome,
+				Items:          cond.Items,
+			})
+		}
+	}
+	return expr
+}
+
+// formatUnlockExpression formats expr, parenthesising it if it combines several terms with an
+// operator other than that of the expression it is nested in.
+func formatUnlockExpression(expr UnlockExpression, name func(UnlockCondition) string, parentOperator string) string {
+	// Quorums and negations read as a function of their operands, e.g. "at least 2 of (A.outcome == PASS, ...)"
+	if expr.AtLeast != nil || len(expr.None) > 0 {
+		prefix, children := "none of ", expr.None
+		if expr.AtLeast != nil {
+			prefix, children = fmt.Sprintf("at least %d of ", expr.AtLeast.N), expr.AtLeast.Of
+		}
+		parts := make([]string, len(children))
+		for i, child := range children {
+			parts[i] = formatUnlockExpression(child, name, ", ")
+		}
+		return prefix + "(" + strings.Join(parts, ", ") + ")"
+	}
+
+	children, operator := expr.AnyOf, " || "
+	if len(expr.AllOf) > 0 {
+		children, operator = expr.AllOf, " && "
+	}
+
+	var parts []string
+	if len(children) > 0 {
+		if len(children) == 1 {
+			return formatUnlockExpression(children[0], name, parentOperator)
+		}
+		for _, child := range children {
+			parts = append(parts, formatUnlockExpression(child, name, operator))
+		}
+	} else if expr.Context != nil {
+		return expr.Context.String()
+	} else {
+		cond := UnlockCondition{NodeTemplateID: expr.NodeTemplateID, NodeID: expr.NodeID, State: expr.State, Outcome: expr.Outcome}
+		prefix := name(cond) + "."
+		if cond.State != nil {
+			parts = append(parts, prefix+"state == "+*cond.State)
+		}
+		if cond.Outcome != nil {
+			parts = append(parts, prefix+"outcome == "+*cond.Outcome)
+		}
+		operator = " && "
+	}
+
+	formatted := strings.Join(parts, operator)
+	if parentOperator != "" && parentOperator != ", " && parentOperator != operator && len(parts) > 1 {
+		return "(" + formatted + ")"
+	}
+	return formatted
+}
+
 // Evaluate checks if the unlock conditions are satisfied given the current node states and outcomes.
-// The nodeMap should contain node ID -> WorkflowNode mappings with current states.
+// The nodeMap should contain node ID -> WorkflowNode mappings with current states. Context predicates
+// are evaluated against an empty context; use EvaluateInContext for configurations that have them.
 func (uc *UnlockConfig) Evaluate(nodeMap map[uuid.UUID]WorkflowNode) bool {
+	return uc.EvaluateInContext(nodeMap, nil)
+}
+
+// EvaluateInContext checks if the unlock conditions are satisfied given the current node states and
+// outcomes, and the context of the workflow (its GlobalContext or TraderContext).
+func (uc *UnlockConfig) EvaluateInContext(nodeMap map[uuid.UUID]WorkflowNode, unlockContext map[string]any) bool {
 	if uc.Expression != nil {
-		return uc.evaluateExpression(*uc.Expression, nodeMap)
+		return uc.evaluateExpression(*uc.Expression, nodeMap, unlockContext)
 	}
 
 	// DNF evaluation: any group being satisfied makes the whole config satisfied (OR)
@@ -282,28 +602,17 @@ func (uc *UnlockConfig) Evaluate(nodeMap map[uuid.UUID]WorkflowNode) bool {
 // evaluateGroup checks if all conditions in a group are satisfied (AND).
 func (uc *UnlockConfig) evaluateGroup(group UnlockGroup, nodeMap map[uuid.UUID]WorkflowNode) bool {
 	for _, cond := range group.AllOf {
-		node, exists := nodeMap[*cond.NodeID]
-		if !exists {
-			return false
-		}
-		// Check state if specified
-		if cond.State != nil && string(node.State) != *cond.State {
+		if !uc.evaluateCondition(cond, nodeMap) {
 			return false
 		}
-		// Check outcome if specified
-		if cond.Outcome != nil {
-			if node.Outcome == nil || *node.Outcome != *cond.Outcome {
-				return false
-			}
-		}
 	}
 	return true
 }
 
-func (uc *UnlockConfig) evaluateExpression(expr UnlockExpression, nodeMap map[uuid.UUID]WorkflowNode) bool {
+func (uc *UnlockConfig) evaluateExpression(expr UnlockExpression, nodeMap map[uuid.UUID]WorkflowNode, unlockContext map[string]any) bool {
 	if len(expr.AnyOf) > 0 {
 		for _, child := range expr.AnyOf {
-			if uc.evaluateExpression(child, nodeMap) {
+			if uc.evaluateExpression(child, nodeMap, unlockContext) {
 				return true
 			}
 		}
@@ -312,13 +621,39 @@ func (uc *UnlockConfig) evaluateExpression(expr UnlockExpression, nodeMap map[uu
 
 	if len(expr.AllOf) > 0 {
 		for _, child := range expr.AllOf {
-			if !uc.evaluateExpression(child, nodeMap) {
+			if !uc.evaluateExpression(child, nodeMap, unlockContext) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if expr.AtLeast != nil {
+		satisfied := 0
+		for _, child := range expr.AtLeast.Of {
+			if uc.evaluateExpression(child, nodeMap, unlockContext) {
+				satisfied++
+				if satisfied >= expr.AtLeast.N {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if len(expr.None) > 0 {
+		for _, child := range expr.None {
+			if uc.evaluateExpression(child, nodeMap, unlockContext) {
 				return false
 			}
 		}
 		return true
 	}
 
+	if expr.Context != nil {
+		return expr.Context.IsSatisfiedBy(unlockContext)
+	}
+
 	return uc.evaluateCondition(UnlockCondition{
 		NodeTemplateID: expr.NodeTemplateID,
 		NodeID:         expr.NodeID,
@@ -332,17 +667,282 @@ func (uc *UnlockConfig) evaluateCondition(cond UnlockCondition, nodeMap map[uuid
 	if !exists {
 		return false
 	}
-	if cond.State != nil && string(node.State) != *cond.State {
+	return cond.IsSatisfiedBy(node)
+}
+
+// IsSatisfiedBy reports whether node is in the state and has the outcome the condition expects.
+func (c UnlockCondition) IsSatisfiedBy(node WorkflowNode) bool {
+	if c.State != nil && string(node.State) != *c.State {
 		return false
 	}
-	if cond.Outcome != nil {
-		if node.Outcome == nil || *node.Outcome != *cond.Outcome {
+	if c.Outcome != nil {
+		if node.Outcome == nil || *node.Outcome != *c.Outcome {
 			return false
 		}
 	}
 	return true
 }
 
+// unlockTruth is the value of an unlock expression that may not be decided yet: it is only true or false
+// once the nodes it references are terminal.
+type unlockTruth int
+
+const (
+	unlockFalse unlockTruth = iota
+	unlockUndecided
+	unlockTrue
+)
+
+// CanBeSatisfied reports whether the unlock conditions are satisfied or may still become satisfied, given
+// the current node states and outcomes. It is false only when the nodes that are terminal (see
+// WorkflowNode.IsTerminal) already decide that the conditions never hold, whatever the other nodes go on
+// to do and whatever the context of the workflow becomes.
+func (uc *UnlockConfig) CanBeSatisfied(nodeMap map[uuid.UUID]WorkflowNode) bool {
+	if uc.Expression != nil {
+		return uc.decideExpression(*uc.Expression, nodeMap) != unlockFalse
+	}
+
+	for _, group := range uc.AnyOf {
+		truth := unlockTrue
+		for _, cond := range group.AllOf {
+			truth = min(truth, decideCondition(cond, nodeMap))
+		}
+		if truth != unlockFalse {
+			return true
+		}
+	}
+	return false
+}
+
+// decideExpression evaluates the expression in three-valued logic: a combination is decided as soon as
+// its decided children determine it, whatever the undecided ones turn out to be.
+func (uc *UnlockConfig) decideExpression(expr UnlockExpression, nodeMap map[uuid.UUID]WorkflowNode) unlockTruth {
+	switch {
+	case len(expr.AnyOf) > 0:
+		truth := unlockFalse
+		for _, child := range expr.AnyOf {
+			truth = max(truth, uc.decideExpression(child, nodeMap))
+		}
+		return truth
+	case len(expr.AllOf) > 0:
+		truth := unlockTrue
+		for _, child := range expr.AllOf {
+			truth = min(truth, uc.decideExpression(child, nodeMap))
+		}
+		return truth
+	case expr.AtLeast != nil:
+		satisfied, undecided := 0, 0
+		for _, child := range expr.AtLeast.Of {
+			switch uc.decideExpression(child, nodeMap) {
+			case unlockTrue:
+				satisfied++
+			case unlockUndecided:
+				undecided++
+			}
+		}
+		if satisfied >= expr.AtLeast.N {
+			return unlockTrue
+		}
+		if satisfied+undecided < expr.AtLeast.N {
+			return unlockFalse
+		}
+		return unlockUndecided
+	case len(expr.None) > 0:
+		truth := unlockFalse
+		for _, child := range expr.None {
+			truth = max(truth, uc.decideExpression(child, nodeMap))
+		}
+		return unlockTrue - truth
+	case expr.Context != nil:
+		// The context may change at any time
+		return unlockUndecided
+	}
+
+	return decideCondition(UnlockCondition{
+		NodeTemplateID: expr.NodeTemplateID,
+		NodeID:         expr.NodeID,
+		State:          expr.State,
+		Outcome:        expr.Outcome,
+	}, nodeMap)
+}
+
+// decideCondition evaluates the condition if the node it references is terminal or does not exist.
+func decideCondition(cond UnlockCondition, nodeMap map[uuid.UUID]WorkflowNode) unlockTruth {
+	node, exists := nodeMap[*cond.NodeID]
+	if exists && !node.IsTerminal() {
+		return unlockUndecided
+	}
+	if exists && cond.IsSatisfiedBy(node) {
+		return unlockTrue
+	}
+	return unlockFalse
+}
+
+// UnlockExplanationKind is the kind of a node of an UnlockExplanation tree.
+type UnlockExplanationKind string
+
+const (
+	UnlockExplanationAnyOf     UnlockExplanationKind = "ANY_OF"    // Satisfied if any child is satisfied
+	UnlockExplanationAllOf     UnlockExplanationKind = "ALL_OF"    // Satisfied if all children are satisfied
+	UnlockExplanationAtLeast   UnlockExplanationKind = "AT_LEAST"  // Satisfied if at least Threshold children are satisfied
+	UnlockExplanationNone      UnlockExplanationKind = "NONE"      // Satisfied if no child is satisfied
+	UnlockExplanationCondition UnlockExplanationKind = "CONDITION" // Satisfied if the referenced node has the expected state and outcome
+	UnlockExplanationContext   UnlockExplanationKind = "CONTEXT"   // Satisfied if the value in the workflow's context meets the predicate
+)
+
+// UnlockExplanation is an unlock expression, or a branch of one, evaluated against the current
+// state of the nodes it references. Unlike Evaluate, every branch is evaluated, so the tree shows
+// each condition that holds and each one that does not.
+type UnlockExplanation struct {
+	Kind      UnlockExplanationKind `json:"kind"`
+	Satisfied bool                  `json:"satisfied"`
+	Children  []UnlockExplanation   `json:"children,omitempty"`  // Branches of ANY_OF, ALL_OF, AT_LEAST and NONE
+	Threshold int                   `json:"threshold,omitempty"` // Number of children AT_LEAST needs satisfied
+
+	// The following are only set for CONDITION.
+	NodeTemplateID  *uuid.UUID         `json:"nodeTemplateId,omitempty"`  // Template of the referenced node
+	NodeID          *uuid.UUID         `json:"nodeId,omitempty"`          // Referenced node
+	NodeName        string             `json:"nodeName,omitempty"`        // Name of the referenced node's template, if loaded
+	ItemIndex       *int               `json:"itemIndex,omitempty"`       // Consignment item of the referenced node, if it is item-scoped
+	ExpectedState   *string            `json:"expectedState,omitempty"`   // State the condition expects, if any
+	ExpectedOutcome *string            `json:"expectedOutcome,omitempty"` // Outcome the condition expects, if any
+	ActualState     *WorkflowNodeState `json:"actualState,omitempty"`     // State of the referenced node; nil if it does not exist
+	ActualOutcome   *string            `json:"actualOutcome,omitempty"`   // Outcome of the referenced node
+
+	// The following are only set for CONTEXT.
+	Context     *UnlockContextPredicate `json:"context,omitempty"`     // Predicate on the workflow's context
+	ActualValue any                     `json:"actualValue,omitempty"` // Value at the predicate's path; nil if there is none
+}
+
+// Explain evaluates the unlock configuration like Evaluate and returns the evaluation of every
+// branch and condition. The legacy format is explained as an ANY_OF of ALL_OF groups. A condition under a
+// NONE is reported as satisfied when the node meets it, which is what makes the NONE unsatisfied.
+func (uc *UnlockConfig) Explain(nodeMap map[uuid.UUID]WorkflowNode) UnlockExplanation {
+	return uc.ExplainInContext(nodeMap, nil)
+}
+
+// ExplainInContext explains the unlock configuration like Explain, evaluating context predicates against
+// the context of the workflow.
+func (uc *UnlockConfig) ExplainInContext(nodeMap map[uuid.UUID]WorkflowNode, unlockContext map[string]any) UnlockExplanation {
+	if uc.Expression != nil {
+		return explainUnlockExpression(*uc.Expression, nodeMap, unlockContext)
+	}
+
+	explanation := UnlockExplanation{Kind: UnlockExplanationAnyOf}
+	for _, group := range uc.AnyOf {
+		groupExplanation := UnlockExplanation{Kind: UnlockExplanationAllOf, Satisfied: true}
+		for _, cond := range group.AllOf {
+			condExplanation := explainUnlockCondition(cond, nodeMap)
+			groupExplanation.Satisfied = groupExplanation.Satisfied && condExplanation.Satisfied
+			groupExplanation.Children = append(groupExplanation.Children, condExplanation)
+		}
+		explanation.Satisfied = explanation.Satisfied || groupExplanation.Satisfied
+		explanation.Children = append(explanation.Children, groupExplanation)
+	}
+	return explanation
+}
+
+// ExplainDependsOn explains the legacy unlock logic of a node without unlock configuration, as an
+// ALL_OF of its dependencies being COMPLETED.
+func ExplainDependsOn(dependsOn []uuid.UUID, nodeMap map[uuid.UUID]WorkflowNode) UnlockExplanation {
+	completed := string(WorkflowNodeStateCompleted)
+	explanation := UnlockExplanation{Kind: UnlockExplanationAllOf, Satisfied: true}
+	for _, depID := range dependsOn {
+		condExplanation := explainUnlockCondition(UnlockCondition{NodeID: &depID, State: &completed}, nodeMap)
+		explanation.Satisfied = explanation.Satisfied && condExplanation.Satisfied
+		explanation.Children = append(explanation.Children, condExplanation)
+	}
+	return explanation
+}
+
+func explainUnlockExpression(expr UnlockExpression, nodeMap map[uuid.UUID]WorkflowNode, unlockContext map[string]any) UnlockExplanation {
+	if expr.AtLeast != nil || len(expr.None) > 0 {
+		explanation := UnlockExplanation{Kind: UnlockExplanationNone}
+		if expr.AtLeast != nil {
+			explanation = UnlockExplanation{Kind: UnlockExplanationAtLeast, Threshold: expr.AtLeast.N}
+		}
+		satisfied := 0
+		for _, child := range expr.children() {
+			childExplanation := explainUnlockExpression(child, nodeMap, unlockContext)
+			if childExplanation.Satisfied {
+				satisfied++
+			}
+			explanation.Children = append(explanation.Children, childExplanation)
+		}
+		if explanation.Kind == UnlockExplanationAtLeast {
+			explanation.Satisfied = satisfied >= explanation.Threshold
+		} else {
+			explanation.Satisfied = satisfied == 0
+		}
+		return explanation
+	}
+
+	if len(expr.AnyOf) > 0 || len(expr.AllOf) > 0 {
+		explanation := UnlockExplanation{Kind: UnlockExplanationAllOf, Satisfied: true}
+		children := expr.AllOf
+		if len(expr.AnyOf) > 0 {
+			explanation = UnlockExplanation{Kind: UnlockExplanationAnyOf}
+			children = expr.AnyOf
+		}
+		for _, child := range children {
+			childExplanation := explainUnlockExpression(child, nodeMap, unlockContext)
+			if explanation.Kind == UnlockExplanationAnyOf {
+				explanation.Satisfied = explanation.Satisfied || childExplanation.Satisfied
+			} else {
+				explanation.Satisfied = explanation.Satisfied && childExplanation.Satisfied
+			}
+			explanation.Children = append(explanation.Children, childExplanation)
+		}
+		return explanation
+	}
+
+	if expr.Context != nil {
+		actual, _ := expr.Context.Lookup(unlockContext)
+		return UnlockExplanation{
+			Kind:        UnlockExplanationContext,
+			Satisfied:   expr.Context.IsSatisfiedBy(unlockContext),
+			Context:     expr.Context,
+			ActualValue: actual,
+		}
+	}
+
+	return explainUnlockCondition(UnlockCondition{
+		NodeTemplateID: expr.NodeTemplateID,
+		NodeID:         expr.NodeID,
+		State:          expr.State,
+		Outcome:        expr.Outcome,
+	}, nodeMap)
+}
+
+func explainUnlockCondition(cond UnlockCondition, nodeMap map[uuid.UUID]WorkflowNode) UnlockExplanation {
+	explanation := UnlockExplanation{
+		Kind:            UnlockExplanationCondition,
+		NodeID:          cond.NodeID,
+		ExpectedState:   cond.State,
+		ExpectedOutcome: cond.Outcome,
+	}
+	if cond.NodeTemplateID != uuid.Nil {
+		explanation.NodeTemplateID = &cond.NodeTemplateID
+	}
+	if cond.NodeID == nil {
+		return explanation
+	}
+	node, exists := nodeMap[*cond.NodeID]
+	if !exists {
+		return explanation
+	}
+
+	if explanation.NodeTemplateID == nil {
+		explanation.NodeTemplateID = &node.WorkflowNodeTemplateID
+	}
+	explanation.NodeName = node.WorkflowNodeTemplate.Name
+	explanation.ItemIndex = node.ItemIndex
+	explanation.ActualState = &node.State
+	explanation.ActualOutcome = node.Outcome
+	explanation.Satisfied = cond.IsSatisfiedBy(node)
+	return explanation
+}
+
 // MarshalJSON implements json.Marshaler for UnlockConfig.
 func (uc UnlockConfig) MarshalJSON() ([]byte, error) {
 	type Alias UnlockConfig
@@ -157,6 +157,135 @@ func TestUnlockConfig_Validate(t *testing.T) {
 	})
 }
 
+func TestUnlockConfig_NodeTemplateIDs(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("Legacy", func(t *testing.T) {
+		uc := &UnlockConfig{AnyOf: []UnlockGroup{
+			{AllOf: []UnlockCondition{{NodeTemplateID: a, State: strPtr("COMPLETED")}, {NodeTemplateID: b, State: strPtr("COMPLETED")}}},
+			{AllOf: []UnlockCondition{{NodeTemplateID: a, Outcome: strPtr("FAST_TRACKED")}}},
+		}}
+		assert.Equal(t, []uuid.UUID{a, b}, uc.NodeTemplateIDs())
+	})
+
+	t.Run("Expression", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{NodeTemplateID: c, State: strPtr("COMPLETED")},
+			{AnyOf: []UnlockExpression{{NodeTemplateID: a, Outcome: strPtr("APPROVED")}, {NodeTemplateID: c, State: strPtr("FAILED")}}},
+		}}}
+		assert.Equal(t, []uuid.UUID{c, a}, uc.NodeTemplateIDs())
+	})
+}
+
+func TestUnlockConfig_Format(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	names := map[uuid.UUID]string{a: "A", b: "B", c: "C"}
+	name := func(cond UnlockCondition) string { return names[cond.NodeTemplateID] }
+
+	t.Run("Legacy", func(t *testing.T) {
+		uc := &UnlockConfig{AnyOf: []UnlockGroup{
+			{AllOf: []UnlockCondition{{NodeTemplateID: a, State: strPtr("COMPLETED"), Outcome: strPtr("APPROVED")}, {NodeTemplateID: b, State: strPtr("COMPLETED")}}},
+			{AllOf: []UnlockCondition{{NodeTemplateID: a, Outcome: strPtr("FAST_TRACKED")}}},
+		}}
+		assert.Equal(t, "(A.state == COMPLETED && A.outcome == APPROVED && B.state == COMPLETED) || A.outcome == FAST_TRACKED", uc.Format(name))
+	})
+
+	t.Run("Expression", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{NodeTemplateID: c, State: strPtr("COMPLETED")},
+			{AnyOf: []UnlockExpression{{NodeTemplateID: a, State: strPtr("COMPLETED"), Outcome: strPtr("APPROVED")}, {NodeTemplateID: b, State: strPtr("FAILED")}}},
+		}}}
+		assert.Equal(t, "C.state == COMPLETED && ((A.state == COMPLETED && A.outcome == APPROVED) || B.state == FAILED)", uc.Format(name))
+	})
+
+	t.Run("Single Condition", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{NodeTemplateID: a, State: strPtr("COMPLETED"), Outcome: strPtr("APPROVED")}}
+		assert.Equal(t, "A.state == COMPLETED && A.outcome == APPROVED", uc.Format(name))
+	})
+
+	t.Run("Quorum And Negation", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{AtLeast: &UnlockQuorum{N: 2, Of: []UnlockExpression{
+				{NodeTemplateID: a, Outcome: strPtr("PASS")},
+				{NodeTemplateID: b, Outcome: strPtr("PASS")},
+				{AnyOf: []UnlockExpression{{NodeTemplateID: c, Outcome: strPtr("PASS")}, {NodeTemplateID: c, Outcome: strPtr("WAIVED")}}},
+			}}},
+			{None: []UnlockExpression{{NodeTemplateID: a, State: strPtr("FAILED")}, {NodeTemplateID: b, State: strPtr("FAILED")}}},
+		}}}
+		assert.Equal(t, "at least 2 of (A.outcome == PASS, B.outcome == PASS, C.outcome == PASS || C.outcome == WAIVED) && "+
+			"none of (A.state == FAILED, B.state == FAILED)", uc.Format(name))
+	})
+}
+
+func TestUnlockConfig_Explain(t *testing.T) {
+	review, payment, missing := uuid.New(), uuid.New(), uuid.New()
+	nodeMap := map[uuid.UUID]WorkflowNode{
+		review:  {State: WorkflowNodeStateCompleted, Outcome: strPtr("REJECTED"), WorkflowNodeTemplate: WorkflowNodeTemplate{Name: "Review"}},
+		payment: {State: WorkflowNodeStateCompleted, WorkflowNodeTemplate: WorkflowNodeTemplate{Name: "Payment"}},
+	}
+	completed := WorkflowNodeStateCompleted
+
+	t.Run("Expression", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{NodeID: &payment, State: strPtr("COMPLETED")},
+			{AnyOf: []UnlockExpression{
+				{NodeID: &review, State: strPtr("COMPLETED"), Outcome: strPtr("APPROVED")},
+				{NodeID: &missing, State: strPtr("COMPLETED")},
+			}},
+		}}}
+
+		explanation := uc.Explain(nodeMap)
+		assert.False(t, explanation.Satisfied)
+		assert.Equal(t, uc.Evaluate(nodeMap), explanation.Satisfied)
+		assert.Equal(t, UnlockExplanationAllOf, explanation.Kind)
+		if !assert.Len(t, explanation.Children, 2) {
+			return
+		}
+
+		assert.True(t, explanation.Children[0].Satisfied)
+		assert.Equal(t, "Payment", explanation.Children[0].NodeName)
+
+		branches := explanation.Children[1]
+		assert.Equal(t, UnlockExplanationAnyOf, branches.Kind)
+		assert.False(t, branches.Satisfied)
+		assert.Equal(t, UnlockExplanation{
+			Kind:            UnlockExplanationCondition,
+			NodeID:          &review,
+			NodeName:        "Review",
+			ExpectedState:   strPtr("COMPLETED"),
+			ExpectedOutcome: strPtr("APPROVED"),
+			ActualState:     &completed,
+			ActualOutcome:   strPtr("REJECTED"),
+			NodeTemplateID:  &uuid.UUID{},
+		}, branches.Children[0])
+		assert.False(t, branches.Children[1].Satisfied)
+		assert.Nil(t, branches.Children[1].ActualState)
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		uc := &UnlockConfig{AnyOf: []UnlockGroup{
+			{AllOf: []UnlockCondition{{NodeID: &review, Outcome: strPtr("APPROVED")}}},
+			{AllOf: []UnlockCondition{{NodeID: &review, State: strPtr("COMPLETED")}, {NodeID: &payment, State: strPtr("COMPLETED")}}},
+		}}
+
+		explanation := uc.Explain(nodeMap)
+		assert.True(t, explanation.Satisfied)
+		assert.Equal(t, UnlockExplanationAnyOf, explanation.Kind)
+		assert.False(t, explanation.Children[0].Satisfied)
+		assert.True(t, explanation.Children[1].Satisfied)
+		assert.Len(t, explanation.Children[1].Children, 2)
+	})
+
+	t.Run("DependsOn", func(t *testing.T) {
+		explanation := ExplainDependsOn([]uuid.UUID{payment, missing}, nodeMap)
+		assert.False(t, explanation.Satisfied)
+		assert.True(t, explanation.Children[0].Satisfied)
+		assert.False(t, explanation.Children[1].Satisfied)
+
+		assert.True(t, ExplainDependsOn(nil, nodeMap).Satisfied)
+	})
+}
+
 func TestUnlockConfig_Evaluate(t *testing.T) {
 	nodeA := uuid.New()
 	nodeB := uuid.New()
@@ -771,6 +900,67 @@ func TestUnlockConfig_Expression_Validate(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "cannot define both expression and anyOf")
 	})
+
+	t.Run("Valid Quorum And Negation", func(t *testing.T) {
+		uc := &UnlockConfig{
+			Expression: &UnlockExpression{
+				AllOf: []UnlockExpression{
+					{AtLeast: &UnlockQuorum{N: 1, Of: []UnlockExpression{{NodeTemplateID: nodeA, Outcome: strPtr("PASS")}}}},
+					{None: []UnlockExpression{{NodeTemplateID: nodeB, State: strPtr("FAILED")}}},
+				},
+			},
+		}
+		assert.NoError(t, uc.Validate())
+	})
+
+	tests := []struct {
+		name string
+		expr UnlockExpression
+		err  string
+	}{
+		{
+			name: "Quorum Without Expressions",
+			expr: UnlockExpression{AtLeast: &UnlockQuorum{N: 1}},
+			err:  "expression.atLeast must have at least one expression in of",
+		},
+		{
+			name: "Quorum Of Zero",
+			expr: UnlockExpression{AtLeast: &UnlockQuorum{N: 0, Of: []UnlockExpression{{NodeTemplateID: nodeA, State: strPtr("COMPLETED")}}}},
+			err:  "expression.atLeast has n 0, must be between 1 and the 1 expressions in of",
+		},
+		{
+			name: "Quorum Larger Than Expressions",
+			expr: UnlockExpression{AtLeast: &UnlockQuorum{N: 3, Of: []UnlockExpression{
+				{NodeTemplateID: nodeA, State: strPtr("COMPLETED")},
+				{NodeTemplateID: nodeB, State: strPtr("COMPLETED")},
+			}}},
+			err: "expression.atLeast has n 3",
+		},
+		{
+			name: "Invalid Quorum Expression",
+			expr: UnlockExpression{AtLeast: &UnlockQuorum{N: 1, Of: []UnlockExpression{{NodeTemplateID: nodeA}}}},
+			err:  "expression.atLeast.of[0] must specify at least state or outcome",
+		},
+		{
+			name: "Invalid Negated Expression",
+			expr: UnlockExpression{None: []UnlockExpression{{NodeTemplateID: nodeA, State: strPtr("FAILED")}, {}}},
+			err:  "expression.none[1] must define one of anyOf, allOf, atLeast, none, context, or a condition",
+		},
+		{
+			name: "Quorum And Negation",
+			expr: UnlockExpression{
+				AtLeast: &UnlockQuorum{N: 1, Of: []UnlockExpression{{NodeTemplateID: nodeA, State: strPtr("COMPLETED")}}},
+				None:    []UnlockExpression{{NodeTemplateID: nodeB, State: strPtr("FAILED")}},
+			},
+			err: "expression must define exactly one of anyOf, allOf, atLeast, none, context, or a condition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UnlockConfig{Expression: &tt.expr}
+			assert.ErrorContains(t, uc.Validate(), tt.err)
+		})
+	}
 }
 
 func TestUnlockConfig_Expression_Evaluate(t *testing.T) {
@@ -814,6 +1004,121 @@ func TestUnlockConfig_Expression_Evaluate(t *testing.T) {
 	}))
 }
 
+func TestUnlockConfig_Expression_EvaluateQuorumAndNegation(t *testing.T) {
+	labA, labB, labC, inspection := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	uc := &UnlockConfig{
+		Expression: &UnlockExpression{
+			AllOf: []UnlockExpression{
+				{AtLeast: &UnlockQuorum{N: 2, Of: []UnlockExpression{
+					{NodeTemplateID: labA, Outcome: strPtr("PASS")},
+					{NodeTemplateID: labB, Outcome: strPtr("PASS")},
+					{NodeTemplateID: labC, Outcome: strPtr("PASS")},
+				}}},
+				{None: []UnlockExpression{
+					{NodeTemplateID: labA, State: strPtr("FAILED")},
+					{NodeTemplateID: inspection, State: strPtr("FAILED")},
+				}},
+			},
+		},
+	}
+
+	resolved := resolveForEvaluate(t, uc, labA, labB, labC, inspection)
+	if assert.NotNil(t, resolved.Expression) {
+		assert.Equal(t, labC, *resolved.Expression.AllOf[0].AtLeast.Of[2].NodeID)
+		assert.Equal(t, inspection, *resolved.Expression.AllOf[1].None[1].NodeID)
+	}
+
+	pass, fail := "PASS", "FAIL"
+	nodes := func(a, b, c *string, inspectionState WorkflowNodeState) map[uuid.UUID]WorkflowNode {
+		return map[uuid.UUID]WorkflowNode{
+			labA:       {State: WorkflowNodeStateCompleted, Outcome: a},
+			labB:       {State: WorkflowNodeStateCompleted, Outcome: b},
+			labC:       {State: WorkflowNodeStateCompleted, Outcome: c},
+			inspection: {State: inspectionState},
+		}
+	}
+	assert.True(t, resolved.Evaluate(nodes(&pass, &fail, &pass, WorkflowNodeStateInProgress)))
+	assert.True(t, resolved.Evaluate(nodes(&pass, &pass, &pass, WorkflowNodeStateCompleted)))
+	assert.False(t, resolved.Evaluate(nodes(&pass, &fail, &fail, WorkflowNodeStateInProgress)))
+	assert.False(t, resolved.Evaluate(nodes(&pass, &pass, &fail, WorkflowNodeStateFailed)))
+
+	explanation := resolved.Explain(nodes(&pass, &fail, &fail, WorkflowNodeStateInProgress))
+	assert.False(t, explanation.Satisfied)
+	quorum := explanation.Children[0]
+	assert.Equal(t, UnlockExplanationAtLeast, quorum.Kind)
+	assert.Equal(t, 2, quorum.Threshold)
+	assert.False(t, quorum.Satisfied)
+	assert.Equal(t, UnlockExplanationNone, explanation.Children[1].Kind)
+	assert.True(t, explanation.Children[1].Satisfied)
+}
+
+func TestUnlockConfig_CanBeSatisfied(t *testing.T) {
+	review, payment, labA, labB, labC := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	approved, rejected, pass, fail := "APPROVED", "REJECTED", "PASS", "FAIL"
+
+	t.Run("Legacy Format", func(t *testing.T) {
+		uc := resolveForEvaluate(t, &UnlockConfig{AnyOf: []UnlockGroup{
+			{AllOf: []UnlockCondition{{NodeTemplateID: review, Outcome: &approved}}},
+			{AllOf: []UnlockCondition{{NodeTemplateID: payment, State: strPtr("COMPLETED")}}},
+		}}, review, payment)
+
+		assert.True(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{
+			review:  {State: WorkflowNodeStateCompleted, Outcome: &rejected},
+			payment: {State: WorkflowNodeStateInProgress},
+		}))
+		assert.False(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{
+			review:  {State: WorkflowNodeStateCompleted, Outcome: &rejected},
+			payment: {State: WorkflowNodeStateSkipped},
+		}))
+	})
+
+	t.Run("Undecided Until Referenced Nodes Are Terminal", func(t *testing.T) {
+		uc := resolveForEvaluate(t, &UnlockConfig{Expression: &UnlockExpression{
+			NodeTemplateID: review, Outcome: &approved,
+		}}, review)
+
+		// A FAILED node may be reopened, and an IN_PROGRESS one may still have its outcome change
+		assert.True(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{review: {State: WorkflowNodeStateFailed}}))
+		assert.True(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{review: {State: WorkflowNodeStateInProgress, Outcome: &rejected}}))
+		assert.False(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{review: {State: WorkflowNodeStateCompleted, Outcome: &rejected}}))
+		assert.False(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{review: {State: WorkflowNodeStateSkipped}}))
+		assert.False(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{}))
+	})
+
+	t.Run("Quorum And Negation", func(t *testing.T) {
+		uc := resolveForEvaluate(t, &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{AtLeast: &UnlockQuorum{N: 2, Of: []UnlockExpression{
+				{NodeTemplateID: labA, Outcome: &pass},
+				{NodeTemplateID: labB, Outcome: &pass},
+				{NodeTemplateID: labC, Outcome: &pass},
+			}}},
+			{None: []UnlockExpression{{NodeTemplateID: review, Outcome: &rejected}}},
+		}}}, labA, labB, labC, review)
+
+		nodes := func(a, b *string, reviewOutcome *string) map[uuid.UUID]WorkflowNode {
+			return map[uuid.UUID]WorkflowNode{
+				labA:   {State: WorkflowNodeStateCompleted, Outcome: a},
+				labB:   {State: WorkflowNodeStateCompleted, Outcome: b},
+				labC:   {State: WorkflowNodeStateReady},
+				review: {State: WorkflowNodeStateCompleted, Outcome: reviewOutcome},
+			}
+		}
+		assert.True(t, uc.CanBeSatisfied(nodes(&pass, &fail, &approved)))
+		assert.False(t, uc.CanBeSatisfied(nodes(&fail, &fail, &approved)))
+		assert.False(t, uc.CanBeSatisfied(nodes(&pass, &pass, &rejected)))
+	})
+
+	t.Run("Context Predicates Stay Undecided", func(t *testing.T) {
+		uc := resolveForEvaluate(t, &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{NodeTemplateID: review, State: strPtr("COMPLETED")},
+			{Context: &UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextGreater, Value: 5000}},
+		}}}, review)
+
+		assert.True(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{review: {State: WorkflowNodeStateCompleted}}))
+		assert.False(t, uc.CanBeSatisfied(map[uuid.UUID]WorkflowNode{review: {State: WorkflowNodeStateSkipped}}))
+	})
+}
+
 func TestUnlockConfig_Expression_ResolveToInstanceIDs(t *testing.T) {
 	templateA := uuid.New()
 	templateB := uuid.New()
@@ -846,6 +1151,106 @@ func TestUnlockConfig_Expression_ResolveToInstanceIDs(t *testing.T) {
 	}
 }
 
+func TestUnlockConfig_ResolveToNodeInstances(t *testing.T) {
+	certificate := uuid.New()
+	inspection := uuid.New()
+	item0, item1 := 0, 1
+	certificate0, certificate1, inspectionNode := uuid.New(), uuid.New(), uuid.New()
+	instances := NodeInstances{
+		certificate: {{NodeID: certificate0, ItemIndex: &item0}, {NodeID: certificate1, ItemIndex: &item1}},
+		inspection:  {{NodeID: inspectionNode}},
+	}
+
+	t.Run("All Items By Default", func(t *testing.T) {
+		uc := &UnlockConfig{AnyOf: []UnlockGroup{{AllOf: []UnlockCondition{
+			{NodeTemplateID: certificate, State: strPtr("COMPLETED")},
+			{NodeTemplateID: inspection, Outcome: strPtr("APPROVED")},
+		}}}}
+
+		resolved, err := uc.ResolveToNodeInstances(instances, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, resolved.AnyOf)
+		if assert.NotNil(t, resolved.Expression) && assert.Len(t, resolved.Expression.AnyOf, 1) {
+			group := resolved.Expression.AnyOf[0].AllOf
+			if assert.Len(t, group, 2) && assert.Len(t, group[0].AllOf, 2) {
+				assert.Equal(t, certificate0, *group[0].AllOf[0].NodeID)
+				assert.Equal(t, certificate1, *group[0].AllOf[1].NodeID)
+				assert.Equal(t, "COMPLETED", *group[0].AllOf[1].State)
+				assert.Equal(t, inspectionNode, *group[1].NodeID)
+			}
+		}
+	})
+
+	t.Run("Any Item", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{NodeTemplateID: certificate, State: strPtr("COMPLETED"), Items: UnlockItemsAny}}
+
+		resolved, err := uc.ResolveToNodeInstances(instances, &item1)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resolved.Expression) && assert.Len(t, resolved.Expression.AnyOf, 2) {
+			assert.Equal(t, certificate0, *resolved.Expression.AnyOf[0].NodeID)
+			assert.Equal(t, certificate1, *resolved.Expression.AnyOf[1].NodeID)
+		}
+	})
+
+	t.Run("Same Item", func(t *testing.T) {
+		uc := &UnlockConfig{AnyOf: []UnlockGroup{{AllOf: []UnlockCondition{{NodeTemplateID: certificate, State: strPtr("COMPLETED")}}}}}
+
+		resolved, err := uc.ResolveToNodeInstances(instances, &item1)
+		assert.NoError(t, err)
+		assert.Nil(t, resolved.Expression)
+		if assert.Len(t, resolved.AnyOf, 1) {
+			assert.Equal(t, certificate1, *resolved.AnyOf[0].AllOf[0].NodeID)
+		}
+	})
+
+	t.Run("Any Item Inside None", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{None: []UnlockExpression{
+			{NodeTemplateID: certificate, State: strPtr("FAILED")},
+			{NodeTemplateID: certificate, Outcome: strPtr("REJECTED"), Items: UnlockItemsAll},
+		}}}
+
+		resolved, err := uc.ResolveToNodeInstances(instances, nil)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resolved.Expression) && assert.Len(t, resolved.Expression.None, 2) {
+			if assert.Len(t, resolved.Expression.None[0].AnyOf, 2) {
+				assert.Equal(t, certificate0, *resolved.Expression.None[0].AnyOf[0].NodeID)
+				assert.Equal(t, certificate1, *resolved.Expression.None[0].AnyOf[1].NodeID)
+			}
+			assert.Len(t, resolved.Expression.None[1].AllOf, 2)
+		}
+	})
+
+	t.Run("Invalid Items", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{NodeTemplateID: certificate, State: strPtr("COMPLETED"), Items: "SOME"}}
+		_, err := uc.ResolveToNodeInstances(instances, nil)
+		assert.ErrorContains(t, err, "invalid items")
+	})
+}
+
+func TestUnlockConfig_Expression_QuorumJSON(t *testing.T) {
+	jsonStr := `{"expression":{"allOf":[` +
+		`{"atLeast":{"n":2,"of":[` +
+		`{"nodeTemplateId":"00000000-0000-0000-0000-000000000001","outcome":"PASS"},` +
+		`{"nodeTemplateId":"00000000-0000-0000-0000-000000000002","outcome":"PASS"},` +
+		`{"nodeTemplateId":"00000000-0000-0000-0000-000000000003","outcome":"PASS"}]}},` +
+		`{"none":[{"nodeTemplateId":"00000000-0000-0000-0000-000000000001","state":"FAILED"}]}]}}`
+
+	var uc UnlockConfig
+	assert.NoError(t, json.Unmarshal([]byte(jsonStr), &uc))
+	assert.NoError(t, uc.Validate())
+	if assert.NotNil(t, uc.Expression) && assert.NotNil(t, uc.Expression.AllOf[0].AtLeast) {
+		assert.Equal(t, 2, uc.Expression.AllOf[0].AtLeast.N)
+		assert.Len(t, uc.Expression.AllOf[0].AtLeast.Of, 3)
+		assert.Equal(t, "FAILED", *uc.Expression.AllOf[1].None[0].State)
+	}
+
+	data, err := json.Marshal(uc)
+	assert.NoError(t, err)
+	var roundTripped UnlockConfig
+	assert.NoError(t, json.Unmarshal(data, &roundTripped))
+	assert.Equal(t, uc, roundTripped)
+}
+
 func TestUnlockConfig_Expression_JSON(t *testing.T) {
 	jsonStr := `{
 		"expression": {
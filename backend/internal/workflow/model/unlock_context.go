@@ -0,0 +1,240 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// UnlockContextOperator is the comparison a context predicate makes.
+type UnlockContextOperator string
+
+const (
+	UnlockContextEqual          UnlockContextOperator = "EQ"     // The value equals Value
+	UnlockContextNotEqual       UnlockContextOperator = "NE"     // The value does not equal Value
+	UnlockContextGreater        UnlockContextOperator = "GT"     // The value is greater than Value
+	UnlockContextGreaterOrEqual UnlockContextOperator = "GTE"    // The value is greater than or equal to Value
+	UnlockContextLess           UnlockContextOperator = "LT"     // The value is less than Value
+	UnlockContextLessOrEqual    UnlockContextOperator = "LTE"    // The value is less than or equal to Value
+	UnlockContextIn             UnlockContextOperator = "IN"     // The value is one of Values
+	UnlockContextNotIn          UnlockContextOperator = "NOT_IN" // The value is none of Values
+	UnlockContextExists         UnlockContextOperator = "EXISTS" // The context has a value at the path
+)
+
+// unlockContextOperatorSymbols are the symbols context predicates are formatted with.
+var unlockContextOperatorSymbols = map[UnlockContextOperator]string{
+	UnlockContextEqual:          "==",
+	UnlockContextNotEqual:       "!=",
+	UnlockContextGreater:        ">",
+	UnlockContextGreaterOrEqual: ">=",
+	UnlockContextLess:           "<",
+	UnlockContextLessOrEqual:    "<=",
+	UnlockContextIn:             "in",
+	UnlockContextNotIn:          "not in",
+	UnlockContextExists:         "exists",
+}
+
+// UnlockContextPredicate is an unlock condition on a value in the context of the workflow: the GlobalContext
+// of a consignment or the TraderContext of a pre-consignment, e.g. {"path": "declaredQuantityKg",
+// "operator": "GT", "value": 5000}. Path is a dot-separated path into nested objects. A predicate on a path
+// the context has no value at is not satisfied, whatever the operator, except that EXISTS tests exactly that.
+type UnlockContextPredicate struct {
+	Path     string                `json:"path"`
+	Operator UnlockContextOperator `json:"operator"`
+	Value    any                   `json:"value,omitempty"`  // Operand of EQ, NE, GT, GTE, LT and LTE
+	Values   []any                 `json:"values,omitempty"` // Operands of IN and NOT_IN
+}
+
+// Validate checks that the predicate is well-formed. Path is the location of the predicate in the unlock
+// configuration, for error messages.
+func (p *UnlockContextPredicate) Validate(path string) error {
+	if strings.TrimSpace(p.Path) == "" {
+		return fmt.Errorf("%s has empty path", path)
+	}
+	if slices.Contains(strings.Split(p.Path, "."), "") {
+		return fmt.Errorf("%s has path %q with an empty segment", path, p.Path)
+	}
+	if _, ok := unlockContextOperatorSymbols[p.Operator]; !ok {
+		return fmt.Errorf("%s has invalid operator %q", path, p.Operator)
+	}
+
+	switch p.Operator {
+	case UnlockContextExists:
+		if p.Value != nil || len(p.Values) > 0 {
+			return fmt.Errorf("%s operator EXISTS takes no value or values", path)
+		}
+	case UnlockContextIn, UnlockContextNotIn:
+		if p.Value != nil {
+			return fmt.Errorf("%s operator %s takes values, not value", path, p.Operator)
+		}
+		if len(p.Values) == 0 {
+			return fmt.Errorf("%s operator %s must have at least one value in values", path, p.Operator)
+		}
+		for i, value := range p.Values {
+			if !isScalarContextValue(value) {
+				return fmt.Errorf("%s values[%d] must be a string, number or boolean", path, i)
+			}
+		}
+	default:
+		if len(p.Values) > 0 {
+			return fmt.Errorf("%s operator %s takes value, not values", path, p.Operator)
+		}
+		if p.Value == nil {
+			return fmt.Errorf("%s operator %s must have a value", path, p.Operator)
+		}
+		if !isScalarContextValue(p.Value) {
+			return fmt.Errorf("%s value must be a string, number or boolean", path)
+		}
+		if p.Operator != UnlockContextEqual && p.Operator != UnlockContextNotEqual {
+			if _, isBool := p.Value.(bool); isBool {
+				return fmt.Errorf("%s operator %s cannot compare a boolean", path, p.Operator)
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the value at the predicate's path in unlockContext, and whether there is one.
+func (p *UnlockContextPredicate) Lookup(unlockContext map[string]any) (any, bool) {
+	var value any = unlockContext
+	for _, key := range strings.Split(p.Path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// IsSatisfiedBy reports whether the value at the predicate's path in unlockContext satisfies the predicate.
+// Numbers compare by value whatever their Go type, and strings compare lexicographically, so that ISO 8601
+// dates can be ordered.
+func (p *UnlockContextPredicate) IsSatisfiedBy(unlockContext map[string]any) bool {
+	actual, exists := p.Lookup(unlockContext)
+	if p.Operator == UnlockContextExists || !exists {
+		return exists
+	}
+
+	switch p.Operator {
+	case UnlockContextEqual:
+		return contextValuesEqual(actual, p.Value)
+	case UnlockContextNotEqual:
+		return !contextValuesEqual(actual, p.Value)
+	case UnlockContextIn:
+		return slices.ContainsFunc(p.Values, func(value any) bool { return contextValuesEqual(actual, value) })
+	case UnlockContextNotIn:
+		return !slices.ContainsFunc(p.Values, func(value any) bool { return contextValuesEqual(actual, value) })
+	}
+
+	cmp, ok := compareContextValues(actual, p.Value)
+	if !ok {
+		return false
+	}
+	switch p.Operator {
+	case UnlockContextGreater:
+		return cmp > 0
+	case UnlockContextGreaterOrEqual:
+		return cmp >= 0
+	case UnlockContextLess:
+		return cmp < 0
+	case UnlockContextLessOrEqual:
+		return cmp <= 0
+	}
+	return false
+}
+
+// String formats the predicate as a boolean expression, e.g. "context.declaredQuantityKg > 5000".
+func (p *UnlockContextPredicate) String() string {
+	formatted := "context." + p.Path + " " + unlockContextOperatorSymbols[p.Operator]
+	switch p.Operator {
+	case UnlockContextExists:
+		return formatted
+	case UnlockContextIn, UnlockContextNotIn:
+		values := make([]string, len(p.Values))
+		for i, value := range p.Values {
+			values[i] = formatContextValue(value)
+		}
+		return formatted + " [" + strings.Join(values, ", ") + "]"
+	default:
+		return formatted + " " + formatContextValue(p.Value)
+	}
+}
+
+func formatContextValue(value any) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(value)
+}
+
+func isScalarContextValue(value any) bool {
+	switch value.(type) {
+	case string, bool:
+		return true
+	}
+	_, isNumber := contextNumber(value)
+	return isNumber
+}
+
+// contextNumber returns value as a float64 if it is a number. Context values decoded from JSON are float64,
+// but values set in code may be of any numeric type.
+func contextNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func contextValuesEqual(a, b any) bool {
+	if cmp, ok := compareContextValues(a, b); ok {
+		return cmp == 0
+	}
+	aBool, aIsBool := a.(bool)
+	bBool, bIsBool := b.(bool)
+	return aIsBool && bIsBool && aBool == bBool
+}
+
+// compareContextValues compares two numbers or two strings, and reports whether they are comparable.
+func compareContextValues(a, b any) (int, bool) {
+	if aNumber, ok := contextNumber(a); ok {
+		bNumber, ok := contextNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case aNumber < bNumber:
+			return -1, true
+		case aNumber > bNumber:
+			return 1, true
+		}
+		return 0, true
+	}
+	aString, aIsString := a.(string)
+	bString, bIsString := b.(string)
+	if !aIsString || !bIsString {
+		return 0, false
+	}
+	return strings.Compare(aString, bString), true
+}
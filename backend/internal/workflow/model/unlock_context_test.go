@@ -0,0 +1,132 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnlockContextPredicate_Validate(t *testing.T) {
+	assert.NoError(t, (&UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextGreater, Value: 5000.0}).Validate("context"))
+	assert.NoError(t, (&UnlockContextPredicate{Path: "destination.country", Operator: UnlockContextIn, Values: []any{"DE", "FR"}}).Validate("context"))
+	assert.NoError(t, (&UnlockContextPredicate{Path: "permitNumber", Operator: UnlockContextExists}).Validate("context"))
+	assert.NoError(t, (&UnlockContextPredicate{Path: "organic", Operator: UnlockContextEqual, Value: true}).Validate("context"))
+
+	tests := []struct {
+		name      string
+		predicate UnlockContextPredicate
+		err       string
+	}{
+		{"Empty Path", UnlockContextPredicate{Path: " ", Operator: UnlockContextExists}, "context has empty path"},
+		{"Empty Path Segment", UnlockContextPredicate{Path: "destination..country", Operator: UnlockContextExists}, "empty segment"},
+		{"Invalid Operator", UnlockContextPredicate{Path: "a", Operator: "LIKE", Value: "x"}, `invalid operator "LIKE"`},
+		{"Exists With Value", UnlockContextPredicate{Path: "a", Operator: UnlockContextExists, Value: "x"}, "EXISTS takes no value or values"},
+		{"In Without Values", UnlockContextPredicate{Path: "a", Operator: UnlockContextIn}, "IN must have at least one value in values"},
+		{"In With Value", UnlockContextPredicate{Path: "a", Operator: UnlockContextNotIn, Value: "x"}, "NOT_IN takes values, not value"},
+		{"In With Object", UnlockContextPredicate{Path: "a", Operator: UnlockContextIn, Values: []any{"x", map[string]any{}}}, "values[1] must be a string, number or boolean"},
+		{"Comparison Without Value", UnlockContextPredicate{Path: "a", Operator: UnlockContextGreater}, "GT must have a value"},
+		{"Comparison With Values", UnlockContextPredicate{Path: "a", Operator: UnlockContextEqual, Values: []any{"x"}}, "EQ takes value, not values"},
+		{"Comparison With List", UnlockContextPredicate{Path: "a", Operator: UnlockContextEqual, Value: []any{"x"}}, "value must be a string, number or boolean"},
+		{"Ordering Booleans", UnlockContextPredicate{Path: "a", Operator: UnlockContextLess, Value: true}, "LT cannot compare a boolean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.predicate.Validate("context"), tt.err)
+		})
+	}
+}
+
+func TestUnlockContextPredicate_IsSatisfiedBy(t *testing.T) {
+	unlockContext := map[string]any{
+		"declaredQuantityKg": 7500.0,
+		"units":              12,
+		"organic":            false,
+		"arrivalDate":        "2026-10-17",
+		"destination":        map[string]any{"country": "DE"},
+		"remarks":            nil,
+	}
+
+	tests := []struct {
+		name      string
+		predicate UnlockContextPredicate
+		satisfied bool
+	}{
+		{"Greater", UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextGreater, Value: 5000}, true},
+		{"Not Greater", UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextGreater, Value: 7500.0}, false},
+		{"Greater Or Equal", UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextGreaterOrEqual, Value: 7500}, true},
+		{"Less Across Numeric Types", UnlockContextPredicate{Path: "units", Operator: UnlockContextLess, Value: 12.5}, true},
+		{"Less Or Equal", UnlockContextPredicate{Path: "units", Operator: UnlockContextLessOrEqual, Value: 11.0}, false},
+		{"String Ordering", UnlockContextPredicate{Path: "arrivalDate", Operator: UnlockContextLess, Value: "2026-11-01"}, true},
+		{"Number Compared To String", UnlockContextPredicate{Path: "units", Operator: UnlockContextGreater, Value: "10"}, false},
+		{"Equal Boolean", UnlockContextPredicate{Path: "organic", Operator: UnlockContextEqual, Value: false}, true},
+		{"Not Equal", UnlockContextPredicate{Path: "destination.country", Operator: UnlockContextNotEqual, Value: "FR"}, true},
+		{"In", UnlockContextPredicate{Path: "destination.country", Operator: UnlockContextIn, Values: []any{"FR", "DE"}}, true},
+		{"Not In", UnlockContextPredicate{Path: "destination.country", Operator: UnlockContextNotIn, Values: []any{"FR", "DE"}}, false},
+		{"Exists", UnlockContextPredicate{Path: "destination.country", Operator: UnlockContextExists}, true},
+		{"Null Does Not Exist", UnlockContextPredicate{Path: "remarks", Operator: UnlockContextExists}, false},
+		{"Missing Path", UnlockContextPredicate{Path: "destination.port", Operator: UnlockContextNotEqual, Value: "HAM"}, false},
+		{"Path Through Scalar", UnlockContextPredicate{Path: "units.count", Operator: UnlockContextExists}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.satisfied, tt.predicate.IsSatisfiedBy(unlockContext))
+		})
+	}
+
+	assert.False(t, (&UnlockContextPredicate{Path: "units", Operator: UnlockContextExists}).IsSatisfiedBy(nil))
+}
+
+func TestUnlockConfig_ContextPredicate(t *testing.T) {
+	labTest := uuid.New()
+	jsonStr := `{"expression": {"anyOf": [
+		{"context": {"path": "declaredQuantityKg", "operator": "GT", "value": 5000}},
+		{"allOf": [
+			{"context": {"path": "destinationCountry", "operator": "IN", "values": ["DE", "FR"]}},
+			{"nodeTemplateId": "` + labTest.String() + `", "outcome": "REQUIRED"}
+		]}
+	]}}`
+
+	var uc UnlockConfig
+	assert.NoError(t, json.Unmarshal([]byte(jsonStr), &uc))
+	assert.NoError(t, uc.Validate())
+	assert.Equal(t, []uuid.UUID{labTest}, uc.NodeTemplateIDs())
+	assert.Len(t, uc.Conditions(), 1)
+	assert.Equal(t, `context.declaredQuantityKg > 5000 || (context.destinationCountry in ["DE", "FR"] && Lab.outcome == REQUIRED)`,
+		uc.Format(func(UnlockCondition) string { return "Lab" }))
+
+	resolved := resolveForEvaluate(t, &uc, labTest)
+	assert.Equal(t, "declaredQuantityKg", resolved.Expression.AnyOf[0].Context.Path)
+
+	required := "REQUIRED"
+	nodeMap := map[uuid.UUID]WorkflowNode{labTest: {State: WorkflowNodeStateCompleted, Outcome: &required}}
+	assert.True(t, resolved.EvaluateInContext(nodeMap, map[string]any{"declaredQuantityKg": 6000.0}))
+	assert.True(t, resolved.EvaluateInContext(nodeMap, map[string]any{"declaredQuantityKg": 100.0, "destinationCountry": "FR"}))
+	assert.False(t, resolved.EvaluateInContext(nodeMap, map[string]any{"declaredQuantityKg": 100.0, "destinationCountry": "US"}))
+	assert.False(t, resolved.Evaluate(nodeMap))
+
+	explanation := resolved.ExplainInContext(nodeMap, map[string]any{"declaredQuantityKg": 100.0, "destinationCountry": "US"})
+	assert.False(t, explanation.Satisfied)
+	quantity := explanation.Children[0]
+	assert.Equal(t, UnlockExplanationContext, quantity.Kind)
+	assert.Equal(t, "declaredQuantityKg", quantity.Context.Path)
+	assert.Equal(t, 100.0, quantity.ActualValue)
+	assert.False(t, quantity.Satisfied)
+	assert.True(t, explanation.Children[1].Children[1].Satisfied)
+
+	t.Run("Invalid Predicate", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{AllOf: []UnlockExpression{
+			{Context: &UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextGreater}},
+		}}}
+		assert.ErrorContains(t, uc.Validate(), "expression.allOf[0].context operator GT must have a value")
+	})
+
+	t.Run("Predicate With Condition", func(t *testing.T) {
+		uc := &UnlockConfig{Expression: &UnlockExpression{
+			NodeTemplateID: labTest, State: strPtr("COMPLETED"),
+			Context: &UnlockContextPredicate{Path: "declaredQuantityKg", Operator: UnlockContextExists},
+		}}
+		assert.ErrorContains(t, uc.Validate(), "must define exactly one of")
+	})
+}
@@ -1,14 +1,55 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkflowTemplateStatus is the lifecycle status of a workflow template version.
+type WorkflowTemplateStatus string
+
+const (
+	WorkflowTemplateStatusDraft     WorkflowTemplateStatus = "DRAFT"     // Version is being prepared and is not used for new consignments
+	WorkflowTemplateStatusPublished WorkflowTemplateStatus = "PUBLISHED" // Version is immutable; the latest published version of a family is used for new consignments
+)
+
+// WorkflowTemplateFamily groups the versions of a workflow template. HS code mappings point at a
+// family, so new consignments pick up a newly published version without being remapped.
+type WorkflowTemplateFamily struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(100);column:name;not null" json:"name"` // Name of the workflow template family
+	Description string `gorm:"type:text;column:description" json:"description"`    // Description of the workflow template family
+}
+
+func (wtf *WorkflowTemplateFamily) TableName() string {
+	return "workflow_template_families"
+}
 
 type WorkflowTemplate struct {
 	BaseModel
-	Name              string     `gorm:"type:varchar(100);column:name;not null" json:"name"`                       // Name of the workflow template
-	Description       string     `gorm:"type:text;column:description" json:"description"`                          // Description of the workflow template
-	Version           string     `gorm:"type:varchar(50);column:version;not null" json:"version"`                  // Version of the workflow template
-	NodeTemplates     UUIDArray  `gorm:"type:jsonb;column:nodes;not null;serializer:json" json:"nodes"`            // Array of workflow node template IDs
-	EndNodeTemplateID *uuid.UUID `gorm:"type:uuid;column:end_node_template_id" json:"endNodeTemplateId,omitempty"` // Optional end node template ID. If set, workflow is complete when this node is completed.
+	Name              string                 `gorm:"type:varchar(100);column:name;not null" json:"name"`                       // Name of the workflow template
+	Description       string                 `gorm:"type:text;column:description" json:"description"`                          // Description of the workflow template
+	Version           string                 `gorm:"type:varchar(50);column:version;not null" json:"version"`                  // Human-readable version label of the workflow template
+	NodeTemplates     UUIDArray              `gorm:"type:jsonb;column:nodes;not null;serializer:json" json:"nodes"`            // Array of workflow node template IDs
+	EndNodeTemplateID *uuid.UUID             `gorm:"type:uuid;column:end_node_template_id" json:"endNodeTemplateId,omitempty"` // Optional end node template ID. If set, workflow is complete when this node is completed.
+	FamilyID          uuid.UUID              `gorm:"type:uuid;column:family_id;not null" json:"familyId"`                      // Family this template is a version of
+	VersionNumber     int                    `gorm:"column:version_number;not null" json:"versionNumber"`                      // Sequential version number within the family, starting at 1
+	Status            WorkflowTemplateStatus `gorm:"type:varchar(20);column:status;not null" json:"status"`                    // Lifecycle status. Published versions and their node templates are immutable.
+	PublishedAt       *time.Time             `gorm:"type:timestamptz;column:published_at" json:"publishedAt,omitempty"`        // When the version was published
+
+	RequiredPreConsignments []PreConsignmentRequirement `gorm:"type:jsonb;column:required_pre_consignments;serializer:json" json:"requiredPreConsignments,omitempty"` // Pre-consignments the trader must have completed before creating a consignment with this template
+}
+
+// PreConsignmentRequirement declares a pre-consignment that a trader must have completed before creating a
+// consignment whose workflow uses the template. The trader context of the completed pre-consignment is seeded
+// into the consignment's global context under ContextKey, so node forms read it with paths like "br.regNo".
+type PreConsignmentRequirement struct {
+	PreConsignmentTemplateID uuid.UUID `json:"preConsignmentTemplateId"` // Pre-consignment template the trader must have completed
+	ContextKey               string    `json:"contextKey"`               // Global context key the pre-consignment's trader context is seeded under
 }
 
 func (wt *WorkflowTemplate) TableName() string {
@@ -18,3 +59,137 @@ func (wt *WorkflowTemplate) TableName() string {
 func (wt *WorkflowTemplate) GetNodeTemplateIDs() []uuid.UUID {
 	return wt.NodeTemplates
 }
+
+// Validate checks that the workflow template forms a valid graph with the given node templates, which
+// must include every node template the workflow template lists: every dependency and unlock
+// configuration must reference a node template of the workflow, the end node template must be one
+// of them, and the dependencies must not form a cycle.
+func (wt *WorkflowTemplate) Validate(nodeTemplates []WorkflowNodeTemplate) error {
+	if len(strings.TrimSpace(wt.Name)) == 0 {
+		return fmt.Errorf("name must not be blank")
+	}
+	if len(strings.TrimSpace(wt.Version)) == 0 {
+		return fmt.Errorf("version must not be blank")
+	}
+	if len(wt.NodeTemplates) == 0 {
+		return fmt.Errorf("workflow template must have at least one node template")
+	}
+
+	nodeTemplateMap := make(map[uuid.UUID]*WorkflowNodeTemplate, len(nodeTemplates))
+	for i := range nodeTemplates {
+		nodeTemplateMap[nodeTemplates[i].ID] = &nodeTemplates[i]
+	}
+	members := make(map[uuid.UUID]bool, len(wt.NodeTemplates))
+	for _, id := range wt.NodeTemplates {
+		if members[id] {
+			return fmt.Errorf("node template %s is listed more than once", id)
+		}
+		if _, ok := nodeTemplateMap[id]; !ok {
+			return fmt.Errorf("node template %s does not exist", id)
+		}
+		members[id] = true
+	}
+
+	for _, id := range wt.NodeTemplates {
+		nodeTemplate := nodeTemplateMap[id]
+		for _, dependencyID := range nodeTemplate.DependsOn {
+			if !members[dependencyID] {
+				return fmt.Errorf("node template %s depends on node template %s, which is not part of the workflow template", id, dependencyID)
+			}
+		}
+		if nodeTemplate.UnlockConfiguration != nil {
+			for _, referencedID := range nodeTemplate.UnlockConfiguration.NodeTemplateIDs() {
+				if !members[referencedID] {
+					return fmt.Errorf("unlock configuration of node template %s references node template %s, which is not part of the workflow template", id, referencedID)
+				}
+			}
+		}
+	}
+
+	if wt.EndNodeTemplateID != nil && !members[*wt.EndNodeTemplateID] {
+		return fmt.Errorf("end node template %s is not part of the workflow template", *wt.EndNodeTemplateID)
+	}
+
+	if cycle := findDependencyCycle(wt.NodeTemplates, nodeTemplateMap); cycle != nil {
+		return fmt.Errorf("dependency cycle between node templates %s", formatCycle(cycle))
+	}
+
+	return validatePreConsignmentRequirements(wt.RequiredPreConsignments)
+}
+
+// validatePreConsignmentRequirements checks that every requirement names a pre-consignment template and a
+// context key, which must be usable as the first segment of a dot-notation path, and that neither repeats.
+func validatePreConsignmentRequirements(requirements []PreConsignmentRequirement) error {
+	templateIDs := make(map[uuid.UUID]bool, len(requirements))
+	contextKeys := make(map[string]bool, len(requirements))
+	for _, requirement := range requirements {
+		if requirement.PreConsignmentTemplateID == uuid.Nil {
+			return fmt.Errorf("required pre-consignment must have a pre-consignment template ID")
+		}
+		if templateIDs[requirement.PreConsignmentTemplateID] {
+			return fmt.Errorf("pre-consignment template %s is required more than once", requirement.PreConsignmentTemplateID)
+		}
+		templateIDs[requirement.PreConsignmentTemplateID] = true
+
+		if len(strings.TrimSpace(requirement.ContextKey)) == 0 {
+			return fmt.Errorf("required pre-consignment template %s must have a context key", requirement.PreConsignmentTemplateID)
+		}
+		if strings.Contains(requirement.ContextKey, ".") {
+			return fmt.Errorf("context key %q of required pre-consignment template %s must not contain '.'", requirement.ContextKey, requirement.PreConsignmentTemplateID)
+		}
+		if contextKeys[requirement.ContextKey] {
+			return fmt.Errorf("context key %q is used by more than one required pre-consignment", requirement.ContextKey)
+		}
+		contextKeys[requirement.ContextKey] = true
+	}
+	return nil
+}
+
+// findDependencyCycle returns the node template IDs of a dependency cycle, with the first ID repeated
+// at the end, or nil if the dependencies are acyclic.
+func findDependencyCycle(ids []uuid.UUID, nodeTemplateMap map[uuid.UUID]*WorkflowNodeTemplate) []uuid.UUID {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[uuid.UUID]int, len(ids))
+	var path []uuid.UUID
+
+	var visit func(id uuid.UUID) []uuid.UUID
+	visit = func(id uuid.UUID) []uuid.UUID {
+		marks[id] = visiting
+		path = append(path, id)
+		for _, dependencyID := range nodeTemplateMap[id].Dependencies() {
+			switch marks[dependencyID] {
+			case visiting:
+				start := slices.Index(path, dependencyID)
+				return append(slices.Clone(path[start:]), dependencyID)
+			case unvisited:
+				if cycle := visit(dependencyID); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		marks[id] = visited
+		return nil
+	}
+
+	for _, id := range ids {
+		if marks[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func formatCycle(cycle []uuid.UUID) string {
+	parts := make([]string, len(cycle))
+	for i, id := range cycle {
+		parts[i] = id.String()
+	}
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// ContextConflictPolicy determines what happens when a workflow node appends a context key that another
+// node of the workflow wrote last.
+type ContextConflictPolicy string
+
+const (
+	ContextConflictLastWriterWins ContextConflictPolicy = "LAST_WRITER_WINS" // The node overwrites the key
+	ContextConflictReject         ContextConflictPolicy = "REJECT"           // The update of the node is rejected
+	ContextConflictNamespaced     ContextConflictPolicy = "NAMESPACED"       // The node's keys are written under its namespace, so they cannot conflict with other templates
+)
+
+// ContextMergeConfig declares how a workflow node merges the context it appends into the context of its
+// workflow: the GlobalContext of a consignment or the TraderContext of a pre-consignment.
+//
+// Example JSON:
+//
+//	{"conflictPolicy": "NAMESPACED", "namespace": "phytosanitary"}
+type ContextMergeConfig struct {
+	ConflictPolicy ContextConflictPolicy `json:"conflictPolicy"`      // What happens when another node wrote a key last
+	Namespace      string                `json:"namespace,omitempty"` // Top-level key the node's keys are written under; required by NAMESPACED
+}
+
+// Validate checks that the context merge configuration is well-formed.
+func (c *ContextMergeConfig) Validate() error {
+	switch c.ConflictPolicy {
+	case ContextConflictLastWriterWins, ContextConflictReject:
+		if c.Namespace != "" {
+			return fmt.Errorf("context namespace is only allowed with the %s conflict policy", ContextConflictNamespaced)
+		}
+	case ContextConflictNamespaced:
+		if strings.TrimSpace(c.Namespace) == "" {
+			return fmt.Errorf("context namespace is required by the %s conflict policy", ContextConflictNamespaced)
+		}
+		if strings.Contains(c.Namespace, ".") {
+			return fmt.Errorf("context namespace %q must not contain '.'", c.Namespace)
+		}
+	default:
+		return fmt.Errorf("invalid context conflict policy %q", c.ConflictPolicy)
+	}
+	return nil
+}
+
+// Policy returns the conflict policy of the configuration, LAST_WRITER_WINS if c is nil.
+func (c *ContextMergeConfig) Policy() ContextConflictPolicy {
+	if c == nil {
+		return ContextConflictLastWriterWins
+	}
+	return c.ConflictPolicy
+}
+
+// WorkflowContextChange records that a workflow node changed a key of the context of its workflow. Changes
+// are never updated or deleted, so together they give the provenance of every key the nodes wrote.
+type WorkflowContextChange struct {
+	Sequence               int64      `gorm:"column:sequence;primaryKey;autoIncrement" json:"sequence"`                          // Monotonic sequence, ordering the changes of a key
+	ConsignmentID          *uuid.UUID `gorm:"type:uuid;column:consignment_id" json:"consignmentId,omitempty"`                    // Consignment whose global context changed
+	PreConsignmentID       *uuid.UUID `gorm:"type:uuid;column:pre_consignment_id" json:"preConsignmentId,omitempty"`             // Pre-consignment whose trader context changed
+	WorkflowNodeID         uuid.UUID  `gorm:"type:uuid;column:workflow_node_id;not null" json:"workflowNodeId"`                  // Node that wrote the key; its task has the same ID
+	WorkflowNodeTemplateID uuid.UUID  `gorm:"type:uuid;column:workflow_node_template_id;not null" json:"workflowNodeTemplateId"` // Template of the node that wrote the key
+	Key                    string     `gorm:"type:varchar(255);column:key;not null" json:"key"`                                  // Dot-separated path of the key, including the namespace of NAMESPACED nodes
+	PreviousValue          any        `gorm:"type:jsonb;column:previous_value;serializer:json" json:"previousValue"`             // Value before the change; null if the key was not set
+	Value                  any        `gorm:"type:jsonb;column:value;serializer:json" json:"value"`                              // Value written by the node
+	Actor                  auth.Actor `gorm:"embedded;embeddedPrefix:actor_" json:"actor"`                                       // Who triggered the update of the node
+	ChangedAt              time.Time  `gorm:"type:timestamptz;column:changed_at;not null" json:"changedAt"`                      // When the key changed
+}
+
+func (c *WorkflowContextChange) TableName() string {
+	return "workflow_context_changes"
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// WorkflowEventType identifies what a workflow event records.
+type WorkflowEventType string
+
+const (
+	WorkflowEventNodeCreated      WorkflowEventType = "NODE_CREATED"       // A workflow node was created
+	WorkflowEventNodeStateChanged WorkflowEventType = "NODE_STATE_CHANGED" // A workflow node moved to another state
+	WorkflowEventNodeOutcomeSet   WorkflowEventType = "NODE_OUTCOME_SET"   // A workflow node's outcome changed without a state change, e.g. on an SLA breach
+	WorkflowEventTaskTransition   WorkflowEventType = "TASK_TRANSITION"    // The plugin FSM of a workflow node's task applied an action
+	WorkflowEventContextAppended  WorkflowEventType = "CONTEXT_APPENDED"   // A workflow node appended to the global context of its workflow
+)
+
+// WorkflowEvent is an append-only record of a change to a consignment or pre-consignment workflow and who
+// triggered it. Events are never updated or deleted, so together they give the history of the workflow.
+type WorkflowEvent struct {
+	Sequence         int64             `gorm:"column:sequence;primaryKey;autoIncrement" json:"sequence"`                    // Monotonic sequence, ordering events recorded at the same time
+	ConsignmentID    *uuid.UUID        `gorm:"type:uuid;column:consignment_id" json:"consignmentId,omitempty"`              // Consignment whose workflow changed
+	PreConsignmentID *uuid.UUID        `gorm:"type:uuid;column:pre_consignment_id" json:"preConsignmentId,omitempty"`       // Pre-consignment whose workflow changed
+	WorkflowNodeID   *uuid.UUID        `gorm:"type:uuid;column:workflow_node_id" json:"workflowNodeId,omitempty"`           // Workflow node the event is about
+	Type             WorkflowEventType `gorm:"type:varchar(50);column:type;not null" json:"type"`                           // What the event records
+	FromState        *string           `gorm:"type:varchar(100);column:from_state" json:"fromState,omitempty"`              // Node or plugin state before the change
+	ToState          *string           `gorm:"type:varchar(100);column:to_state" json:"toState,omitempty"`                  // Node or plugin state after the change
+	Outcome          *string           `gorm:"type:varchar(100);column:outcome" json:"outcome,omitempty"`                   // Node outcome after the change
+	Action           *string           `gorm:"type:varchar(100);column:action" json:"action,omitempty"`                     // FSM action of a TASK_TRANSITION
+	Data             map[string]any    `gorm:"type:jsonb;column:data;serializer:json" json:"data,omitempty"`                // Event details, e.g. the appended context of CONTEXT_APPENDED
+	Actor            auth.Actor        `gorm:"embedded;embeddedPrefix:actor_" json:"actor"`                                 // Who triggered the change
+	TaskSequence     *int64            `gorm:"column:task_sequence" json:"-"`                                               // Task notification a TASK_TRANSITION was recorded from, to skip redeliveries
+	Ordinal          *int              `gorm:"column:ordinal" json:"-"`                                                     // Position of a TASK_TRANSITION among the transitions of its task notification
+	OccurredAt       time.Time         `gorm:"type:timestamptz;column:occurred_at;not null" json:"occurredAt"`              // When the change happened
+	CreatedAt        time.Time         `gorm:"type:timestamptz;column:created_at;not null;autoCreateTime" json:"createdAt"` // When the event was recorded
+}
+
+func (e *WorkflowEvent) TableName() string {
+	return "workflow_events"
+}
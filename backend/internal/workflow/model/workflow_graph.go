@@ -0,0 +1,181 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// WorkflowGraphFormat is a format a workflow graph can be rendered in.
+type WorkflowGraphFormat string
+
+const (
+	WorkflowGraphFormatJSON    WorkflowGraphFormat = "json"    // WorkflowGraph encoded as JSON
+	WorkflowGraphFormatDOT     WorkflowGraphFormat = "dot"     // Graphviz DOT
+	WorkflowGraphFormatMermaid WorkflowGraphFormat = "mermaid" // Mermaid flowchart
+)
+
+// WorkflowGraphEdgeKind tells how an edge of a workflow graph affects its target node.
+type WorkflowGraphEdgeKind string
+
+const (
+	WorkflowGraphEdgeDependsOn WorkflowGraphEdgeKind = "DEPENDS_ON" // The target needs the source COMPLETED, along with all its other dependencies
+	WorkflowGraphEdgeUnlock    WorkflowGraphEdgeKind = "UNLOCK"     // The source is referenced by the target's unlock configuration
+)
+
+// WorkflowGraph is the graph of the workflow nodes of a consignment, with an edge from a node to
+// each node whose unlocking depends on it, one for every condition on it.
+type WorkflowGraph struct {
+	ConsignmentID uuid.UUID           `json:"consignmentId"`
+	Nodes         []WorkflowGraphNode `json:"nodes"` // Nodes in dependency order
+	Edges         []WorkflowGraphEdge `json:"edges"`
+}
+
+// WorkflowGraphNode is a workflow node of a WorkflowGraph.
+type WorkflowGraphNode struct {
+	ID                     uuid.UUID         `json:"id"`                         // Workflow node ID
+	WorkflowNodeTemplateID uuid.UUID         `json:"workflowNodeTemplateId"`     // Workflow node template ID
+	Name                   string            `json:"name"`                       // Name of the workflow node template
+	Type                   string            `json:"type,omitempty"`             // Type of the workflow node template
+	ItemIndex              *int              `json:"itemIndex,omitempty"`        // Index of the consignment item the node is for, if item-scoped
+	IsEndNode              bool              `json:"isEndNode,omitempty"`        // Whether this is the node that completes the consignment
+	State                  WorkflowNodeState `json:"state"`                      // State of the workflow node
+	Outcome                *string           `json:"outcome,omitempty"`          // Outcome of the workflow node
+	ExtendedState          *string           `json:"extendedState,omitempty"`    // Extended state of the workflow node
+	Attempt                int               `json:"attempt"`                    // Current attempt of the node
+	UnlockExpression       string            `json:"unlockExpression,omitempty"` // Unlock configuration formatted as a boolean expression, if the node has one
+	CreatedAt              string            `json:"createdAt"`                  // Timestamp of node creation
+	UpdatedAt              string            `json:"updatedAt"`                  // Timestamp of last node update
+	SLADeadline            *string           `json:"slaDeadline,omitempty"`      // When the SLA of the current attempt expires
+	SLABreachedAt          *string           `json:"slaBreachedAt,omitempty"`    // When the SLA of the current attempt expired
+}
+
+// WorkflowGraphEdge is an edge of a WorkflowGraph from a node to a node depending on it.
+type WorkflowGraphEdge struct {
+	From      uuid.UUID             `json:"from"`      // Workflow node depended on
+	To        uuid.UUID             `json:"to"`        // Workflow node depending on it
+	Kind      WorkflowGraphEdgeKind `json:"kind"`      // How the edge affects the target node
+	Condition string                `json:"condition"` // Condition on the source node, e.g. "state == COMPLETED"
+	Satisfied bool                  `json:"satisfied"` // Whether the source node currently meets the condition
+}
+
+// graphStateColors are the fill colors of nodes in rendered graphs, by state.
+var graphStateColors = map[WorkflowNodeState]string{
+	WorkflowNodeStateLocked:     "#e0e0e0",
+	WorkflowNodeStateReady:      "#bbdefb",
+	WorkflowNodeStateInProgress: "#fff59d",
+	WorkflowNodeStateCompleted:  "#c8e6c9",
+	WorkflowNodeStateFailed:     "#ffcdd2",
+	WorkflowNodeStateSkipped:    "#f5f5f5",
+}
+
+// label returns the lines describing the node in rendered graphs.
+func (n WorkflowGraphNode) label() []string {
+	lines := []string{n.Name}
+	if n.Type != "" {
+		lines = append(lines, n.Type)
+	}
+	state := string(n.State)
+	if n.Outcome != nil {
+		state += " (" + *n.Outcome + ")"
+	}
+	if n.Attempt > 1 {
+		state += fmt.Sprintf(" attempt %d", n.Attempt)
+	}
+	lines = append(lines, state)
+	if n.ExtendedState != nil && *n.ExtendedState != "" {
+		lines = append(lines, *n.ExtendedState)
+	}
+	lines = append(lines, "updated "+n.UpdatedAt)
+	if n.SLABreachedAt != nil {
+		lines = append(lines, "SLA breached "+*n.SLABreachedAt)
+	} else if n.SLADeadline != nil {
+		lines = append(lines, "SLA due "+*n.SLADeadline)
+	}
+	if n.UnlockExpression != "" {
+		lines = append(lines, "unlocks when "+n.UnlockExpression)
+	}
+	return lines
+}
+
+// DOT renders the graph in Graphviz DOT. Edges whose condition is not met are dashed.
+func (g *WorkflowGraph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote("consignment "+g.ConsignmentID.String()))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\"];\n")
+	for _, n := range g.Nodes {
+		lines := n.label()
+		for i, line := range lines {
+			lines[i] = dotEscape(line)
+		}
+		attrs := fmt.Sprintf("label=\"%s\", fillcolor=%s", strings.Join(lines, `\n`), dotQuote(graphStateColors[n.State]))
+		if n.IsEndNode {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.ID.String()), attrs)
+	}
+	for _, e := range g.Edges {
+		style := "solid"
+		if !e.Satisfied {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, style=%s];\n", dotQuote(e.From.String()), dotQuote(e.To.String()), dotQuote(e.Condition), style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Edges whose condition is not met are dotted.
+func (g *WorkflowGraph) Mermaid() string {
+	ids := make(map[uuid.UUID]string, len(g.Nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		lines := n.label()
+		for j, line := range lines {
+			lines[j] = mermaidEscape(line)
+		}
+		shape := `["%s"]`
+		if n.IsEndNode {
+			shape = `(["%s"])`
+		}
+		fmt.Fprintf(&b, "  %s"+shape+"\n", ids[n.ID], strings.Join(lines, "<br/>"))
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if !e.Satisfied {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", ids[e.From], arrow, mermaidEscape(e.Condition), ids[e.To])
+	}
+	for _, state := range []WorkflowNodeState{WorkflowNodeStateLocked, WorkflowNodeStateReady, WorkflowNodeStateInProgress, WorkflowNodeStateCompleted, WorkflowNodeStateFailed, WorkflowNodeStateSkipped} {
+		var members []string
+		for _, n := range g.Nodes {
+			if n.State == state {
+				members = append(members, ids[n.ID])
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		class := strings.ToLower(string(state))
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", class, graphStateColors[state])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(members, ","), class)
+	}
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "|", "#124;", "<", "#lt;", ">", "#gt;", "\n", "<br/>").Replace(s)
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testWorkflowGraph() *WorkflowGraph {
+	review := WorkflowGraphNode{
+		ID:        uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Name:      `Review "A"`,
+		Type:      "SIMPLE_FORM",
+		State:     WorkflowNodeStateCompleted,
+		Outcome:   strPtr("REJECTED"),
+		Attempt:   1,
+		UpdatedAt: "2026-01-02T03:04:05Z",
+	}
+	release := WorkflowGraphNode{
+		ID:               uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		Name:             "Release",
+		IsEndNode:        true,
+		State:            WorkflowNodeStateLocked,
+		Attempt:          1,
+		UnlockExpression: `Review "A".outcome == APPROVED || Review "A".outcome == WAIVED`,
+		UpdatedAt:        "2026-01-02T03:04:05Z",
+	}
+	return &WorkflowGraph{
+		ConsignmentID: uuid.MustParse("00000000-0000-0000-0000-0000000000ff"),
+		Nodes:         []WorkflowGraphNode{review, release},
+		Edges: []WorkflowGraphEdge{
+			{From: review.ID, To: release.ID, Kind: WorkflowGraphEdgeUnlock, Condition: "outcome == APPROVED"},
+			{From: review.ID, To: release.ID, Kind: WorkflowGraphEdgeUnlock, Condition: "state == COMPLETED", Satisfied: true},
+		},
+	}
+}
+
+func TestWorkflowGraph_DOT(t *testing.T) {
+	assert.Equal(t, `digraph "consignment 00000000-0000-0000-0000-0000000000ff" {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled"];
+  "00000000-0000-0000-0000-000000000001" [label="Review \"A\"\nSIMPLE_FORM\nCOMPLETED (REJECTED)\nupdated 2026-01-02T03:04:05Z", fillcolor="#c8e6c9"];
+  "00000000-0000-0000-0000-000000000002" [label="Release\nLOCKED\nupdated 2026-01-02T03:04:05Z\nunlocks when Review \"A\".outcome == APPROVED || Review \"A\".outcome == WAIVED", fillcolor="#e0e0e0", peripheries=2];
+  "00000000-0000-0000-0000-000000000001" -> "00000000-0000-0000-0000-000000000002" [label="outcome == APPROVED", style=dashed];
+  "00000000-0000-0000-0000-000000000001" -> "00000000-0000-0000-0000-000000000002" [label="state == COMPLETED", style=solid];
+}
+`, testWorkflowGraph().DOT())
+}
+
+func TestWorkflowGraph_Mermaid(t *testing.T) {
+	assert.Equal(t, `flowchart LR
+  n0["Review #quot;A#quot;<br/>SIMPLE_FORM<br/>COMPLETED (REJECTED)<br/>updated 2026-01-02T03:04:05Z"]
+  n1(["Release<br/>LOCKED<br/>updated 2026-01-02T03:04:05Z<br/>unlocks when Review #quot;A#quot;.outcome == APPROVED #124;#124; Review #quot;A#quot;.outcome == WAIVED"])
+  n0 -.->|"outcome == APPROVED"| n1
+  n0 -->|"state == COMPLETED"| n1
+  classDef locked fill:#e0e0e0
+  class n1 locked
+  classDef completed fill:#c8e6c9
+  class n0 completed
+`, testWorkflowGraph().Mermaid())
+}
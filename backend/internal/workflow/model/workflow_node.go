@@ -2,9 +2,14 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
 )
 
@@ -13,6 +18,8 @@ type WorkflowNodeType string
 const (
 	WorkflowNodeTypeSimpleForm   WorkflowNodeType = "SIMPLE_FORM"    // Node for simple form submission
 	WorkflowNodeTypeWaitForEvent WorkflowNodeType = "WAIT_FOR_EVENT" // Node that waits for an external event to occur
+	WorkflowNodeTypeTimer        WorkflowNodeType = "TIMER"          // Node that completes after a duration or at a given time
+	WorkflowNodeTypeSubWorkflow  WorkflowNodeType = "SUB_WORKFLOW"   // Node that runs a child workflow created from another workflow template
 )
 
 type WorkflowNodeState string
@@ -23,34 +30,206 @@ const (
 	WorkflowNodeStateInProgress WorkflowNodeState = "IN_PROGRESS" // Node is currently active and in progress
 	WorkflowNodeStateCompleted  WorkflowNodeState = "COMPLETED"   // Node has been completed
 	WorkflowNodeStateFailed     WorkflowNodeState = "FAILED"      // Node has failed
+	WorkflowNodeStateSkipped    WorkflowNodeState = "SKIPPED"     // Node will never run because its dependencies can no longer be met, e.g. on a branch not taken
 )
 
+// WorkflowNodeScope determines how many workflow nodes a node template of a consignment workflow is instantiated as.
+type WorkflowNodeScope string
+
+const (
+	WorkflowNodeScopeConsignment WorkflowNodeScope = "CONSIGNMENT" // A single node for the whole consignment
+	WorkflowNodeScopeItem        WorkflowNodeScope = "ITEM"        // A node for each consignment item whose workflow template lists the node template
+)
+
+// ItemIndexGlobalContextKey is the key under which the tasks of item-scoped nodes find the index of their
+// consignment item in the global context.
+const ItemIndexGlobalContextKey = "itemIndex"
+
+// ReopenPolicy determines who may reopen a FAILED workflow node of a template.
+type ReopenPolicy string
+
+const (
+	ReopenPolicyTrader ReopenPolicy = "TRADER" // The trader who owns the workflow, or an admin, may reopen the node
+	ReopenPolicyAdmin  ReopenPolicy = "ADMIN"  // Only an admin may reopen the node
+)
+
+// DefaultSLABreachOutcome is the outcome set on a node whose SLA expired when the SLA does not configure one.
+const DefaultSLABreachOutcome = "TIMED_OUT"
+
+// SLAConfig declares how long a workflow node may stay READY or IN_PROGRESS. When the SLA
+// expires, the node's outcome is set to Outcome so that dependent nodes (e.g. an escalation)
+// can unlock on it through their UnlockConfiguration; the node itself keeps running.
+//
+// The breach outcome is the node's outcome only until the node completes: the completion's
+// outcome then replaces it. Nodes already unlocked by the breach keep running, while nodes that
+// are still LOCKED are evaluated against the completion's outcome. The breach itself stays
+// recorded in SLABreachedAt and in the NODE_OUTCOME_SET workflow event.
+//
+// Example JSON:
+//
+//	{"duration": "72h", "outcome": "TIMED_OUT"}
+type SLAConfig struct {
+	Duration string `json:"duration"`          // Time allowed once the node becomes READY, as a Go duration (e.g. "72h")
+	Outcome  string `json:"outcome,omitempty"` // Outcome set when the SLA expires. Defaults to TIMED_OUT.
+}
+
+// Validate checks that the SLA configuration is well-formed.
+func (sc *SLAConfig) Validate() error {
+	d, err := time.ParseDuration(sc.Duration)
+	if err != nil {
+		return fmt.Errorf("invalid SLA duration %q: %w", sc.Duration, err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("SLA duration must be positive")
+	}
+	if sc.Outcome != "" && len(strings.TrimSpace(sc.Outcome)) == 0 {
+		return fmt.Errorf("SLA outcome must not be blank")
+	}
+	return nil
+}
+
+// BreachOutcome returns the outcome set on a node when the SLA expires.
+func (sc *SLAConfig) BreachOutcome() string {
+	if sc.Outcome == "" {
+		return DefaultSLABreachOutcome
+	}
+	return sc.Outcome
+}
+
+// WorkflowNodeExtendedStateConsignmentCancelled is the extended state of nodes locked because their consignment was cancelled.
+const WorkflowNodeExtendedStateConsignmentCancelled = "CONSIGNMENT_CANCELLED"
+
+// WorkflowNodeExtendedStateAmendmentVoided is the extended state of nodes locked for good because an amendment of
+// their consignment removed the items that required them.
+const WorkflowNodeExtendedStateAmendmentVoided = "AMENDMENT_VOIDED"
+
+// WorkflowNodeExtendedStateMigrationVoided is the extended state of nodes locked for good because a template migration
+// moved their consignment to a version that no longer requires them.
+const WorkflowNodeExtendedStateMigrationVoided = "MIGRATION_VOIDED"
+
+// VoidedExtendedStates are the extended states of voided nodes, which are no longer part of their workflow.
+var VoidedExtendedStates = []string{WorkflowNodeExtendedStateAmendmentVoided, WorkflowNodeExtendedStateMigrationVoided}
+
 // WorkflowNodeTemplate represents a template for a workflow node.
 type WorkflowNodeTemplate struct {
 	BaseModel
-	Name                string          `gorm:"type:varchar(255);column:name;not null" json:"name"`                                          // Human-readable name of the workflow node template
-	Description         string          `gorm:"type:text;column:description" json:"description"`                                             // Optional description of the workflow node template
-	Type                taskPlugin.Type `gorm:"type:varchar(50);column:type;not null" json:"type"`                                           // Type of the workflow node
-	Config              json.RawMessage `gorm:"type:jsonb;column:config;not null;serializer:json" json:"config"`                             // Configuration specific to the workflow node type
-	DependsOn           UUIDArray       `gorm:"type:jsonb;column:depends_on;not null;serializer:json" json:"depends_on"`                     // Array of workflow node template IDs this node depends on
-	UnlockConfiguration *UnlockConfig   `gorm:"type:jsonb;column:unlock_configuration;serializer:json" json:"unlockConfiguration,omitempty"` // Optional conditional unlock configuration (supports nested AND/OR boolean expressions). If nil, DependsOn uses AND-all logic.
+	Name                string                        `gorm:"type:varchar(255);column:name;not null" json:"name"`                                          // Human-readable name of the workflow node template
+	Description         string                        `gorm:"type:text;column:description" json:"description"`                                             // Optional description of the workflow node template
+	Type                taskPlugin.Type               `gorm:"type:varchar(50);column:type;not null" json:"type"`                                           // Type of the workflow node
+	Config              json.RawMessage               `gorm:"type:jsonb;column:config;not null;serializer:json" json:"config"`                             // Configuration specific to the workflow node type
+	DependsOn           UUIDArray                     `gorm:"type:jsonb;column:depends_on;not null;serializer:json" json:"depends_on"`                     // Array of workflow node template IDs this node depends on
+	UnlockConfiguration *UnlockConfig                 `gorm:"type:jsonb;column:unlock_configuration;serializer:json" json:"unlockConfiguration,omitempty"` // Optional conditional unlock configuration (supports nested AND/OR boolean expressions). If nil, DependsOn uses AND-all logic.
+	ReopenPolicy        *ReopenPolicy                 `gorm:"type:varchar(20);column:reopen_policy" json:"reopenPolicy,omitempty"`                         // Who may reopen FAILED nodes of this template. If nil, they cannot be reopened.
+	SLA                 *SLAConfig                    `gorm:"type:jsonb;column:sla;serializer:json" json:"sla,omitempty"`                                  // Optional SLA of nodes of this template
+	Scope               WorkflowNodeScope             `gorm:"type:varchar(20);column:scope;not null;default:CONSIGNMENT" json:"scope,omitempty"`           // Whether the template is instantiated once per consignment or once per item. Defaults to CONSIGNMENT.
+	ContextMerge        *ContextMergeConfig           `gorm:"type:jsonb;column:context_merge;serializer:json" json:"contextMerge,omitempty"`               // How nodes of this template merge the context they append. If nil, they overwrite keys other nodes wrote.
+	GlobalContextMode   *taskPlugin.GlobalContextMode `gorm:"type:varchar(20);column:global_context_mode" json:"globalContextMode,omitempty"`              // Whether tasks of this template read the context of their workflow live. If nil, they read a snapshot taken when they are initialized.
 }
 
 func (wnt *WorkflowNodeTemplate) TableName() string {
 	return "workflow_node_templates"
 }
 
+// Validate checks that the node template is well-formed on its own. References to other node
+// templates are checked by WorkflowTemplate.Validate, and the task configuration by the task factory.
+func (wnt *WorkflowNodeTemplate) Validate() error {
+	if len(strings.TrimSpace(wnt.Name)) == 0 {
+		return fmt.Errorf("name must not be blank")
+	}
+	if wnt.Type == "" {
+		return fmt.Errorf("type is required")
+	}
+	seen := make(map[uuid.UUID]bool, len(wnt.DependsOn))
+	for _, dependencyID := range wnt.DependsOn {
+		if dependencyID == uuid.Nil {
+			return fmt.Errorf("depends_on has nil node template ID")
+		}
+		if dependencyID == wnt.ID {
+			return fmt.Errorf("node template cannot depend on itself")
+		}
+		if seen[dependencyID] {
+			return fmt.Errorf("depends_on lists node template %s more than once", dependencyID)
+		}
+		seen[dependencyID] = true
+	}
+	if wnt.UnlockConfiguration != nil {
+		if err := wnt.UnlockConfiguration.Validate(); err != nil {
+			return err
+		}
+		if wnt.ID != uuid.Nil && slices.Contains(wnt.UnlockConfiguration.NodeTemplateIDs(), wnt.ID) {
+			return fmt.Errorf("unlock configuration cannot reference the node template itself")
+		}
+	}
+	if wnt.ReopenPolicy != nil && *wnt.ReopenPolicy != ReopenPolicyTrader && *wnt.ReopenPolicy != ReopenPolicyAdmin {
+		return fmt.Errorf("invalid reopen policy %q", *wnt.ReopenPolicy)
+	}
+	if wnt.SLA != nil {
+		if err := wnt.SLA.Validate(); err != nil {
+			return err
+		}
+	}
+	if wnt.Scope != "" && wnt.Scope != WorkflowNodeScopeConsignment && wnt.Scope != WorkflowNodeScopeItem {
+		return fmt.Errorf("invalid scope %q", wnt.Scope)
+	}
+	if wnt.ContextMerge != nil {
+		if err := wnt.ContextMerge.Validate(); err != nil {
+			return err
+		}
+	}
+	if wnt.GlobalContextMode != nil && *wnt.GlobalContextMode != taskPlugin.GlobalContextSnapshot && *wnt.GlobalContextMode != taskPlugin.GlobalContextLive {
+		return fmt.Errorf("invalid global context mode %q", *wnt.GlobalContextMode)
+	}
+	return nil
+}
+
+// IsItemScoped reports whether the template is instantiated once per consignment item.
+func (wnt *WorkflowNodeTemplate) IsItemScoped() bool {
+	return wnt.Scope == WorkflowNodeScopeItem
+}
+
+// Dependencies returns the node templates the node template waits for: those referenced by its unlock
+// configuration if it has one, and its DependsOn otherwise.
+func (wnt *WorkflowNodeTemplate) Dependencies() []uuid.UUID {
+	if wnt.UnlockConfiguration != nil {
+		return wnt.UnlockConfiguration.NodeTemplateIDs()
+	}
+	return wnt.DependsOn
+}
+
+// AllowsReopenBy reports whether the template's reopen policy lets the trader (admin false)
+// or an admin (admin true) reopen a FAILED node.
+func (wnt *WorkflowNodeTemplate) AllowsReopenBy(admin bool) bool {
+	if wnt.ReopenPolicy == nil {
+		return false
+	}
+	switch *wnt.ReopenPolicy {
+	case ReopenPolicyTrader:
+		return true
+	case ReopenPolicyAdmin:
+		return admin
+	default:
+		return false
+	}
+}
+
 // WorkflowNode represents an instance of a workflow node within a workflow.
 type WorkflowNode struct {
 	BaseModel
-	ConsignmentID          *uuid.UUID        `gorm:"type:uuid;column:consignment_id" json:"consignmentId"`                                        // Reference to the Consignment, Null if PreConsignment nodes
-	PreConsignmentID       *uuid.UUID        `gorm:"type:uuid;column:pre_consignment_id" json:"preConsignmentId"`                                 // Reference to the PreConsignment, Null if Consignment nodes
-	WorkflowNodeTemplateID uuid.UUID         `gorm:"type:uuid;column:workflow_node_template_id;not null" json:"workflowNodeTemplateId"`           // Reference to the WorkflowNodeTemplate
-	State                  WorkflowNodeState `gorm:"type:varchar(50);column:state;not null" json:"state"`                                         // State of the workflow node
-	ExtendedState          *string           `gorm:"type:text;column:extended_state" json:"extendedState"`                                        // Optional extended state information (e.g., error details)
-	Outcome                *string           `gorm:"type:varchar(100);column:outcome" json:"outcome,omitempty"`                                   // Outcome sub-state when COMPLETED (e.g., APPROVED, REJECTED)
-	DependsOn              UUIDArray         `gorm:"type:jsonb;column:depends_on;not null;serializer:json" json:"depends_on"`                     // Array of workflow node IDs this node depends on
-	UnlockConfiguration    *UnlockConfig     `gorm:"type:jsonb;column:unlock_configuration;serializer:json" json:"unlockConfiguration,omitempty"` // Resolved instance-level unlock configuration
+	ConsignmentID           *uuid.UUID        `gorm:"type:uuid;column:consignment_id" json:"consignmentId"`                                        // Reference to the Consignment, Null if PreConsignment nodes
+	PreConsignmentID        *uuid.UUID        `gorm:"type:uuid;column:pre_consignment_id" json:"preConsignmentId"`                                 // Reference to the PreConsignment, Null if Consignment nodes
+	WorkflowNodeTemplateID  uuid.UUID         `gorm:"type:uuid;column:workflow_node_template_id;not null" json:"workflowNodeTemplateId"`           // Reference to the WorkflowNodeTemplate
+	State                   WorkflowNodeState `gorm:"type:varchar(50);column:state;not null" json:"state"`                                         // State of the workflow node
+	ExtendedState           *string           `gorm:"type:text;column:extended_state" json:"extendedState"`                                        // Optional extended state information (e.g., error details)
+	Outcome                 *string           `gorm:"type:varchar(100);column:outcome" json:"outcome,omitempty"`                                   // Outcome sub-state when COMPLETED (e.g., APPROVED, REJECTED)
+	DependsOn               UUIDArray         `gorm:"type:jsonb;column:depends_on;not null;serializer:json" json:"depends_on"`                     // Array of workflow node IDs this node depends on
+	UnlockConfiguration     *UnlockConfig     `gorm:"type:jsonb;column:unlock_configuration;serializer:json" json:"unlockConfiguration,omitempty"` // Resolved instance-level unlock configuration
+	Attempt                 int               `gorm:"column:attempt;not null;default:1" json:"attempt"`                                            // Current attempt, incremented each time the node is reopened after failing
+	SLA                     *SLAConfig        `gorm:"type:jsonb;column:sla;serializer:json" json:"sla,omitempty"`                                  // SLA copied from the template when the node is created
+	SLADeadline             *time.Time        `gorm:"type:timestamptz;column:sla_deadline" json:"slaDeadline,omitempty"`                           // When the SLA of the current attempt expires, set when the node becomes READY
+	SLABreachedAt           *time.Time        `gorm:"type:timestamptz;column:sla_breached_at" json:"slaBreachedAt,omitempty"`                      // When the SLA of the current attempt expired, if it did
+	ItemIndex               *int              `gorm:"column:item_index" json:"itemIndex,omitempty"`                                                // Index of the consignment item the node is for, if its template is item-scoped
+	ParentNodeID            *uuid.UUID        `gorm:"type:uuid;column:parent_node_id" json:"parentNodeId,omitempty"`                               // SUB_WORKFLOW node whose child workflow the node belongs to, if any
+	TaskCancellationPending bool              `gorm:"column:task_cancellation_pending;not null;default:false" json:"-"`                            // Whether the task of the node, locked by a cancellation, has yet to be cancelled
 
 	// Relationships
 	Consignment          *Consignment         `gorm:"foreignKey:ConsignmentID;references:ID" json:"-"`                             // Associated Consignment
@@ -62,6 +241,59 @@ func (wn *WorkflowNode) TableName() string {
 	return "workflow_nodes"
 }
 
+// StartSLA starts the SLA clock of the node at now, if the node has an SLA.
+// Any breach of an earlier attempt is cleared.
+func (wn *WorkflowNode) StartSLA(now time.Time) {
+	if wn.SLA == nil {
+		return
+	}
+	d, err := time.ParseDuration(wn.SLA.Duration)
+	if err != nil {
+		return
+	}
+	deadline := now.Add(d)
+	wn.SLADeadline = &deadline
+	wn.SLABreachedAt = nil
+}
+
+// DisplayName returns the name of the node's template, followed by the item the node is for if it is
+// item-scoped, e.g. "Health Certificate [item 1]". Empty if the template is not loaded.
+func (wn *WorkflowNode) DisplayName() string {
+	if wn.WorkflowNodeTemplate.Name == "" {
+		return ""
+	}
+	if wn.ItemIndex != nil {
+		return fmt.Sprintf("%s [item %d]", wn.WorkflowNodeTemplate.Name, *wn.ItemIndex)
+	}
+	return wn.WorkflowNodeTemplate.Name
+}
+
+// IsVoided reports whether the node was voided by an amendment of its consignment or a template migration.
+// Voided nodes are no longer part of the workflow and are never unlocked.
+func (wn *WorkflowNode) IsVoided() bool {
+	return wn.State == WorkflowNodeStateLocked &&
+		wn.ExtendedState != nil &&
+		slices.Contains(VoidedExtendedStates, *wn.ExtendedState)
+}
+
+// InSubWorkflow reports whether the node belongs to the child workflow of a SUB_WORKFLOW node rather than to
+// the workflow of its consignment or pre-consignment.
+func (wn *WorkflowNode) InSubWorkflow() bool {
+	return wn.ParentNodeID != nil
+}
+
+// IsTerminal reports whether the node's state and outcome can no longer change: it is COMPLETED or SKIPPED,
+// or was voided. FAILED nodes are not terminal, since they may be reopened.
+func (wn *WorkflowNode) IsTerminal() bool {
+	return wn.State == WorkflowNodeStateCompleted || wn.State == WorkflowNodeStateSkipped || wn.IsVoided()
+}
+
+// IsFinished reports whether the node counts as done for the completion of its workflow: it is COMPLETED
+// or SKIPPED.
+func (wn *WorkflowNode) IsFinished() bool {
+	return wn.State == WorkflowNodeStateCompleted || wn.State == WorkflowNodeStateSkipped
+}
+
 // UpdateWorkflowNodeDTO is used to update the state of a workflow node.
 type UpdateWorkflowNodeDTO struct {
 	WorkflowNodeID      uuid.UUID         `json:"workflowNodeId" binding:"required"` // Workflow Node ID
@@ -69,6 +301,28 @@ type UpdateWorkflowNodeDTO struct {
 	AppendGlobalContext map[string]any    `json:"appendGlobalContext,omitempty"`     // Additional global context to append to the consignment (optional)
 	ExtendedState       *string           `json:"extendedState,omitempty"`           // Optional extended state information (e.g., error details)
 	Outcome             *string           `json:"outcome,omitempty"`                 // Outcome sub-state for COMPLETED transitions (e.g., APPROVED, REJECTED)
+	Actor               *auth.Actor       `json:"actor,omitempty"`                   // Who triggered the update; the party of the request if nil
+}
+
+// ReopenWorkflowNodeResponseDTO represents a workflow node that was reopened after failing.
+type ReopenWorkflowNodeResponseDTO struct {
+	WorkflowNodeID uuid.UUID         `json:"workflowNodeId"` // Workflow Node ID
+	State          WorkflowNodeState `json:"state"`          // New state of the workflow node (READY)
+	Attempt        int               `json:"attempt"`        // Attempt that was started by the reopen
+}
+
+// WorkflowNodeUnlockExplanationDTO explains whether the dependencies of a workflow node are met and,
+// if they are not, which conditions keep it locked.
+type WorkflowNodeUnlockExplanationDTO struct {
+	WorkflowNodeID   uuid.UUID         `json:"workflowNodeId"`             // Workflow Node ID
+	Name             string            `json:"name"`                       // Name of the workflow node template
+	State            WorkflowNodeState `json:"state"`                      // Current state of the workflow node
+	ExtendedState    *string           `json:"extendedState,omitempty"`    // Extended state, e.g. why a node stays LOCKED although its dependencies are met
+	ItemIndex        *int              `json:"itemIndex,omitempty"`        // Index of the consignment item the node is for, if item-scoped
+	UsesDependsOn    bool              `json:"usesDependsOn"`              // Whether the node has no unlock configuration and needs all of DependsOn COMPLETED
+	UnlockExpression string            `json:"unlockExpression,omitempty"` // Unlock logic formatted as a boolean expression; empty if the node has no dependencies
+	Satisfied        bool              `json:"satisfied"`                  // Whether the dependencies are currently met
+	Explanation      UnlockExplanation `json:"explanation"`                // Evaluation of every branch and condition of the unlock logic
 }
 
 // WorkflowNodeResponseDTO represents a workflow node in the response.
@@ -80,6 +334,11 @@ type WorkflowNodeResponseDTO struct {
 	State                WorkflowNodeState               `json:"state"`                   // State of the workflow node
 	ExtendedState        *string                         `json:"extendedState,omitempty"` // Optional extended state information (e.g., error details)
 	Outcome              *string                         `json:"outcome,omitempty"`       // Outcome sub-state when COMPLETED
+	Attempt              int                             `json:"attempt"`                 // Current attempt of the node
+	SLADeadline          *string                         `json:"slaDeadline,omitempty"`   // When the SLA of the current attempt expires
+	SLABreachedAt        *string                         `json:"slaBreachedAt,omitempty"` // When the SLA of the current attempt expired
+	ItemIndex            *int                            `json:"itemIndex,omitempty"`     // Index of the consignment item the node is for, if item-scoped
+	ParentNodeID         *uuid.UUID                      `json:"parentNodeId,omitempty"`  // SUB_WORKFLOW node whose child workflow the node belongs to, if any
 	DependsOn            []uuid.UUID                     `json:"depends_on"`              // Array of workflow node IDs this node depends on
 }
 
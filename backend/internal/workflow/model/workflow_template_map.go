@@ -2,16 +2,17 @@ package model
 
 import "github.com/google/uuid"
 
-// WorkflowTemplateMap represents the mapping between HSCode and Workflow.
+// WorkflowTemplateMap represents the mapping between HSCode and a workflow template family.
+// New consignments use the latest published version of the family.
 type WorkflowTemplateMap struct {
 	BaseModel
-	HSCodeID           uuid.UUID       `gorm:"type:uuid;column:hs_code_id;not null" json:"hsCodeId"`
-	ConsignmentFlow    ConsignmentFlow `gorm:"type:varchar(50);column:consignment_flow;not null" json:"consignmentFlow"` // e.g., IMPORT, EXPORT
-	WorkflowTemplateID uuid.UUID       `gorm:"type:uuid;column:workflow_template_id;not null" json:"workflowTemplateId"`
+	HSCodeID                 uuid.UUID       `gorm:"type:uuid;column:hs_code_id;not null" json:"hsCodeId"`
+	ConsignmentFlow          ConsignmentFlow `gorm:"type:varchar(50);column:consignment_flow;not null" json:"consignmentFlow"` // e.g., IMPORT, EXPORT
+	WorkflowTemplateFamilyID uuid.UUID       `gorm:"type:uuid;column:workflow_template_family_id;not null" json:"workflowTemplateFamilyId"`
 
 	// Relationships
-	HSCode           HSCode           `gorm:"foreignKey:HSCodeID;references:ID" json:"hsCode"`
-	WorkflowTemplate WorkflowTemplate `gorm:"foreignKey:WorkflowTemplateID;references:ID" json:"workflowTemplate"`
+	HSCode                 HSCode                 `gorm:"foreignKey:HSCodeID;references:ID" json:"hsCode"`
+	WorkflowTemplateFamily WorkflowTemplateFamily `gorm:"foreignKey:WorkflowTemplateFamilyID;references:ID" json:"workflowTemplateFamily"`
 }
 
 func (w *WorkflowTemplateMap) TableName() string {
@@ -0,0 +1,57 @@
+package model
+
+import "github.com/google/uuid"
+
+// TemplateMigrationRequest asks to move in-flight consignments from one published version of a workflow
+// template family to a later one.
+type TemplateMigrationRequest struct {
+	FromTemplateID      uuid.UUID               `json:"fromTemplateId" binding:"required"` // Version the consignments currently use
+	ToTemplateID        uuid.UUID               `json:"toTemplateId" binding:"required"`   // Published version to move them to
+	NodeTemplateMapping map[uuid.UUID]uuid.UUID `json:"nodeTemplateMapping,omitempty"`     // Node templates of the old version replaced by one of the new version; their nodes keep their state
+	ConsignmentIDs      []uuid.UUID             `json:"consignmentIds,omitempty"`          // Optional subset of consignments to migrate; all in-progress consignments using FromTemplateID if empty
+	DryRun              bool                    `json:"dryRun"`                            // Report what would change without changing anything
+}
+
+// ConsignmentMigrationStatus is the outcome of migrating a single consignment.
+type ConsignmentMigrationStatus string
+
+const (
+	ConsignmentMigrationStatusPlanned  ConsignmentMigrationStatus = "PLANNED"  // Dry run: the consignment can be migrated as reported
+	ConsignmentMigrationStatusMigrated ConsignmentMigrationStatus = "MIGRATED" // The consignment was migrated as reported
+	ConsignmentMigrationStatusSkipped  ConsignmentMigrationStatus = "SKIPPED"  // The consignment cannot be migrated safely (see conflicts) and was left unchanged
+	ConsignmentMigrationStatusFailed   ConsignmentMigrationStatus = "FAILED"   // Migrating the consignment failed (see error) and it was left unchanged
+)
+
+// NodeMigrationChange describes how a workflow node is affected by a template migration.
+type NodeMigrationChange struct {
+	WorkflowNodeID         uuid.UUID         `json:"workflowNodeId"`                   // Workflow node affected
+	NodeTemplateID         uuid.UUID         `json:"nodeTemplateId"`                   // Node template of the node after the migration
+	PreviousNodeTemplateID *uuid.UUID        `json:"previousNodeTemplateId,omitempty"` // Node template of the node before the migration, if remapped
+	State                  WorkflowNodeState `json:"state"`                            // State of the node after the migration
+}
+
+// ConsignmentMigrationReport describes the migration of a single consignment.
+type ConsignmentMigrationReport struct {
+	ConsignmentID uuid.UUID                  `json:"consignmentId"`
+	Status        ConsignmentMigrationStatus `json:"status"`
+	Added         []NodeMigrationChange      `json:"added"`               // Nodes created for node templates new in the target version
+	Voided        []NodeMigrationChange      `json:"voided"`              // LOCKED or SKIPPED nodes voided because their template is not in the target version
+	Remapped      []NodeMigrationChange      `json:"remapped"`            // Nodes moved to the node template they were mapped to
+	Relocked      []NodeMigrationChange      `json:"relocked"`            // READY nodes locked again because their dependencies are no longer met
+	Unlocked      []NodeMigrationChange      `json:"unlocked"`            // LOCKED nodes that became READY because their dependencies are now met
+	Finished      bool                       `json:"finished"`            // Whether the end node was completed, finishing the consignment
+	Conflicts     []string                   `json:"conflicts,omitempty"` // Why the consignment cannot be migrated safely
+	Error         string                     `json:"error,omitempty"`     // Why migrating the consignment failed
+}
+
+// TemplateMigrationReport summarizes a template migration run.
+type TemplateMigrationReport struct {
+	FromTemplateID uuid.UUID                    `json:"fromTemplateId"`
+	ToTemplateID   uuid.UUID                    `json:"toTemplateId"`
+	DryRun         bool                         `json:"dryRun"`
+	Planned        int                          `json:"planned"`  // Consignments that would be migrated (dry run)
+	Migrated       int                          `json:"migrated"` // Consignments migrated
+	Skipped        int                          `json:"skipped"`  // Consignments left unchanged because of conflicts
+	Failed         int                          `json:"failed"`   // Consignments left unchanged because of errors
+	Consignments   []ConsignmentMigrationReport `json:"consignments"`
+}
@@ -0,0 +1,71 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// preConsignmentExpirer marks the COMPLETED pre-consignments whose expiry passed at now as EXPIRED
+// and returns how many expired.
+type preConsignmentExpirer func(ctx context.Context, now time.Time) (int64, error)
+
+// PreConsignmentExpiryMonitor periodically moves COMPLETED pre-consignments past their expiry to EXPIRED.
+// Validity checks compare expires_at directly, so a pre-consignment stops satisfying its dependents as soon
+// as it expires; the monitor only keeps the persisted state in line with it.
+type PreConsignmentExpiryMonitor struct {
+	expire   preConsignmentExpirer
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newPreConsignmentExpiryMonitor(expire preConsignmentExpirer, interval time.Duration) *PreConsignmentExpiryMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PreConsignmentExpiryMonitor{
+		expire:   expire,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the monitor loop in a background goroutine.
+func (m *PreConsignmentExpiryMonitor) Start() {
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.expireDue(m.ctx)
+			select {
+			case <-m.ctx.Done():
+				slog.Info("pre-consignment expiry monitor stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the monitor loop and waits for the in-flight cycle to finish.
+func (m *PreConsignmentExpiryMonitor) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// expireDue expires the pre-consignments that are past their expiry.
+// Returns the number of pre-consignments that expired.
+func (m *PreConsignmentExpiryMonitor) expireDue(ctx context.Context) int64 {
+	expired, err := m.expire(ctx, time.Now().UTC())
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to expire pre-consignments, will retry", "error", err)
+		return 0
+	}
+	if expired > 0 {
+		slog.InfoContext(ctx, "expired pre-consignments", "count", expired)
+	}
+	return expired
+}
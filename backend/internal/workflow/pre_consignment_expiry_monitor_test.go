@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreConsignmentExpiryMonitor_ExpireDue(t *testing.T) {
+	t.Run("Expires Due Pre-Consignments", func(t *testing.T) {
+		var calledAt time.Time
+		expire := func(_ context.Context, now time.Time) (int64, error) {
+			calledAt = now
+			return 3, nil
+		}
+
+		m := newPreConsignmentExpiryMonitor(expire, time.Second)
+		count := m.expireDue(context.Background())
+
+		assert.Equal(t, int64(3), count)
+		assert.Equal(t, time.UTC, calledAt.Location())
+	})
+
+	t.Run("Expire Error", func(t *testing.T) {
+		expire := func(context.Context, time.Time) (int64, error) {
+			return 0, errors.New("db error")
+		}
+
+		m := newPreConsignmentExpiryMonitor(expire, time.Second)
+		assert.Equal(t, int64(0), m.expireDue(context.Background()))
+	})
+
+	t.Run("Start And Stop", func(t *testing.T) {
+		cycles := make(chan struct{}, 1)
+		expire := func(context.Context, time.Time) (int64, error) {
+			select {
+			case cycles <- struct{}{}:
+			default:
+			}
+			return 0, nil
+		}
+
+		m := newPreConsignmentExpiryMonitor(expire, time.Hour)
+		m.Start()
+		<-cycles
+		m.Stop()
+	})
+}
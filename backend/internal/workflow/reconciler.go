@@ -0,0 +1,315 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+const (
+	// reconcileBatchSize is the number of workflow nodes inspected per query.
+	reconcileBatchSize = 500
+
+	// reconcileGracePeriod leaves recently updated nodes alone, since their task
+	// registration or state notification may still be in flight.
+	reconcileGracePeriod = time.Minute
+
+	// reconcileCancelReason is the reason given to tasks cancelled by the reconciler.
+	reconcileCancelReason = "workflow node was locked by a cancellation"
+)
+
+// reconciledNodeStates are the workflow node states that must be backed by a live task.
+var reconciledNodeStates = []model.WorkflowNodeState{
+	model.WorkflowNodeStateReady,
+	model.WorkflowNodeStateInProgress,
+}
+
+// workflowNodeStateRank orders workflow node states by lifecycle progress.
+var workflowNodeStateRank = map[model.WorkflowNodeState]int{
+	model.WorkflowNodeStateReady:      0,
+	model.WorkflowNodeStateInProgress: 1,
+	model.WorkflowNodeStateCompleted:  2,
+	model.WorkflowNodeStateFailed:     2,
+}
+
+// nodeLister pages through workflow nodes in the given states last updated before the cutoff.
+type nodeLister func(ctx context.Context, states []model.WorkflowNodeState, cutoff time.Time, afterID uuid.UUID, limit int) ([]model.WorkflowNode, error)
+
+// outstandingUpdateLookup returns the workflow nodes that have failed updates awaiting retry or operator action.
+type outstandingUpdateLookup func(ctx context.Context, workflowNodeIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+
+// nodeRegistrar registers a READY workflow node with the Task Manager.
+type nodeRegistrar func(ctx context.Context, node model.WorkflowNode) error
+
+// cancellationLister pages through workflow nodes last updated before the cutoff whose task has yet to be cancelled.
+type cancellationLister func(ctx context.Context, cutoff time.Time, afterID uuid.UUID, limit int) ([]model.WorkflowNode, error)
+
+// cancellationClearer records that the tasks of the given workflow nodes have been cancelled.
+type cancellationClearer func(ctx context.Context, workflowNodeIDs []uuid.UUID) error
+
+// Reconciler detects and repairs divergences between workflow nodes and task records that
+// can be left behind by a crash:
+//   - READY nodes without a task record are registered with the Task Manager again.
+//   - Nodes whose task has progressed further get the task's state notification re-emitted.
+//   - Nodes locked by a consignment cancellation or an amendment get their task cancelled, if that did not
+//     happen after the cancellation was committed.
+//
+// Nodes with outstanding failed updates are skipped, since the retry worker owns them.
+// Anything else is reported as unresolved for manual attention.
+type Reconciler struct {
+	listNodes   nodeLister
+	outstanding outstandingUpdateLookup
+	tm          taskManager.TaskManager
+	register    nodeRegistrar
+	gracePeriod time.Duration
+
+	listCancellations  cancellationLister
+	clearCancellations cancellationClearer
+
+	runMu      sync.Mutex // Serializes reconciliation runs
+	reportMu   sync.Mutex // Protects lastReport
+	lastReport *model.ReconciliationReport
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newReconciler(listNodes nodeLister, outstanding outstandingUpdateLookup, tm taskManager.TaskManager, register nodeRegistrar,
+	listCancellations cancellationLister, clearCancellations cancellationClearer, gracePeriod time.Duration) *Reconciler {
+	return &Reconciler{
+		listNodes:          listNodes,
+		outstanding:        outstanding,
+		tm:                 tm,
+		register:           register,
+		gracePeriod:        gracePeriod,
+		listCancellations:  listCancellations,
+		clearCancellations: clearCancellations,
+	}
+}
+
+// Start runs a reconciliation immediately and then every interval in a background goroutine.
+func (r *Reconciler) Start(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := r.Run(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "workflow reconciliation failed", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				slog.Info("workflow reconciler stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop, if started, and waits for the in-flight run to finish.
+func (r *Reconciler) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// LastReport returns the report of the most recent completed run, or nil if none has completed.
+func (r *Reconciler) LastReport() *model.ReconciliationReport {
+	r.reportMu.Lock()
+	defer r.reportMu.Unlock()
+	return r.lastReport
+}
+
+// Run performs one reconciliation pass over all active workflow nodes and returns what it fixed.
+func (r *Reconciler) Run(ctx context.Context) (*model.ReconciliationReport, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	report := &model.ReconciliationReport{
+		StartedAt:           time.Now().UTC(),
+		RegisteredTasks:     []uuid.UUID{},
+		ResentNotifications: []uuid.UUID{},
+		CancelledTasks:      []uuid.UUID{},
+		Unresolved:          []model.ReconciliationIssue{},
+	}
+	cutoff := report.StartedAt.Add(-r.gracePeriod)
+
+	afterID := uuid.Nil
+	for {
+		nodes, err := r.listNodes(ctx, reconciledNodeStates, cutoff, afterID, reconcileBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflow nodes: %w", err)
+		}
+		if len(nodes) == 0 {
+			break
+		}
+		if err := r.reconcileBatch(ctx, nodes, report); err != nil {
+			return nil, err
+		}
+		if len(nodes) < reconcileBatchSize {
+			break
+		}
+		afterID = nodes[len(nodes)-1].ID
+	}
+
+	afterID = uuid.Nil
+	for {
+		nodes, err := r.listCancellations(ctx, cutoff, afterID, reconcileBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflow nodes pending task cancellation: %w", err)
+		}
+		if len(nodes) == 0 {
+			break
+		}
+		if err := r.cancelBatch(ctx, nodes, report); err != nil {
+			return nil, err
+		}
+		if len(nodes) < reconcileBatchSize {
+			break
+		}
+		afterID = nodes[len(nodes)-1].ID
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	r.reportMu.Lock()
+	r.lastReport = report
+	r.reportMu.Unlock()
+
+	if len(report.RegisteredTasks) > 0 || len(report.ResentNotifications) > 0 || len(report.CancelledTasks) > 0 || len(report.Unresolved) > 0 {
+		slog.WarnContext(ctx, "workflow reconciliation found divergences",
+			"nodesChecked", report.NodesChecked,
+			"registeredTasks", len(report.RegisteredTasks),
+			"resentNotifications", len(report.ResentNotifications),
+			"cancelledTasks", len(report.CancelledTasks),
+			"unresolved", len(report.Unresolved))
+	} else {
+		slog.DebugContext(ctx, "workflow reconciliation found no divergences",
+			"nodesChecked", report.NodesChecked)
+	}
+
+	return report, nil
+}
+
+// reconcileBatch compares one batch of workflow nodes with their task records and records the outcome in report.
+func (r *Reconciler) reconcileBatch(ctx context.Context, nodes []model.WorkflowNode, report *model.ReconciliationReport) error {
+	nodeIDs := make([]uuid.UUID, len(nodes))
+	for i, node := range nodes {
+		nodeIDs[i] = node.ID
+	}
+
+	outstanding, err := r.outstanding(ctx, nodeIDs)
+	if err != nil {
+		return fmt.Errorf("failed to look up outstanding workflow updates: %w", err)
+	}
+
+	taskStates, err := r.tm.GetTaskStates(ctx, nodeIDs)
+	if err != nil {
+		return fmt.Errorf("failed to look up task states: %w", err)
+	}
+
+	for _, node := range nodes {
+		report.NodesChecked++
+		if outstanding[node.ID] {
+			continue
+		}
+
+		taskState, found := taskStates[node.ID]
+		if !found {
+			if node.State != model.WorkflowNodeStateReady {
+				report.Unresolved = append(report.Unresolved, model.ReconciliationIssue{
+					WorkflowNodeID: node.ID,
+					NodeState:      node.State,
+					Reason:         "task record is missing for a workflow node that is already in progress",
+				})
+				continue
+			}
+			if err := r.register(ctx, node); err != nil {
+				report.Unresolved = append(report.Unresolved, model.ReconciliationIssue{
+					WorkflowNodeID: node.ID,
+					NodeState:      node.State,
+					Reason:         "failed to register missing task: " + err.Error(),
+				})
+				continue
+			}
+			report.RegisteredTasks = append(report.RegisteredTasks, node.ID)
+			continue
+		}
+
+		taskNodeState, err := pluginStateToWorkflowNodeState(taskState)
+		if err != nil {
+			report.Unresolved = append(report.Unresolved, model.ReconciliationIssue{
+				WorkflowNodeID: node.ID,
+				NodeState:      node.State,
+				TaskState:      &taskState,
+				Reason:         err.Error(),
+			})
+			continue
+		}
+
+		switch progress := workflowNodeStateRank[taskNodeState] - workflowNodeStateRank[node.State]; {
+		case progress > 0:
+			resent, err := r.tm.ResendStateNotification(ctx, node.ID)
+			if err != nil {
+				report.Unresolved = append(report.Unresolved, model.ReconciliationIssue{
+					WorkflowNodeID: node.ID,
+					NodeState:      node.State,
+					TaskState:      &taskState,
+					Reason:         "failed to resend task state notification: " + err.Error(),
+				})
+				continue
+			}
+			// A pending notification is already on its way; nothing to fix.
+			if resent {
+				report.ResentNotifications = append(report.ResentNotifications, node.ID)
+			}
+		case progress < 0:
+			report.Unresolved = append(report.Unresolved, model.ReconciliationIssue{
+				WorkflowNodeID: node.ID,
+				NodeState:      node.State,
+				TaskState:      &taskState,
+				Reason:         "task state is behind the workflow node state",
+			})
+		}
+	}
+
+	return nil
+}
+
+// cancelBatch cancels the tasks of one batch of workflow nodes pending task cancellation and records the outcome
+// in report. Cancelling a task that is already finished, or that has no record, is a no-op, so every node whose
+// cancellation succeeds is cleared.
+func (r *Reconciler) cancelBatch(ctx context.Context, nodes []model.WorkflowNode, report *model.ReconciliationReport) error {
+	cancelledNodeIDs := make([]uuid.UUID, 0, len(nodes))
+	for _, node := range nodes {
+		report.NodesChecked++
+		if err := r.tm.CancelTask(ctx, node.ID, reconcileCancelReason); err != nil {
+			report.Unresolved = append(report.Unresolved, model.ReconciliationIssue{
+				WorkflowNodeID: node.ID,
+				NodeState:      node.State,
+				Reason:         "failed to cancel task: " + err.Error(),
+			})
+			continue
+		}
+		cancelledNodeIDs = append(cancelledNodeIDs, node.ID)
+	}
+
+	if err := r.clearCancellations(ctx, cancelledNodeIDs); err != nil {
+		return fmt.Errorf("failed to clear pending task cancellations: %w", err)
+	}
+	report.CancelledTasks = append(report.CancelledTasks, cancelledNodeIDs...)
+	return nil
+}
@@ -0,0 +1,183 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func staticNodeLister(nodes []model.WorkflowNode) nodeLister {
+	return func(_ context.Context, _ []model.WorkflowNodeState, _ time.Time, afterID uuid.UUID, _ int) ([]model.WorkflowNode, error) {
+		if afterID != uuid.Nil {
+			return nil, nil
+		}
+		return nodes, nil
+	}
+}
+
+func noOutstandingUpdates(context.Context, []uuid.UUID) (map[uuid.UUID]bool, error) {
+	return map[uuid.UUID]bool{}, nil
+}
+
+func noPendingCancellations(context.Context, time.Time, uuid.UUID, int) ([]model.WorkflowNode, error) {
+	return nil, nil
+}
+
+func newTestNode(state model.WorkflowNodeState) model.WorkflowNode {
+	consignmentID := uuid.New()
+	return model.WorkflowNode{
+		BaseModel:     model.BaseModel{ID: uuid.New()},
+		ConsignmentID: &consignmentID,
+		State:         state,
+	}
+}
+
+func TestReconciler_Run(t *testing.T) {
+	t.Run("Registers Missing Task For Ready Node", func(t *testing.T) {
+		mockTM := new(MockTaskManager)
+		node := newTestNode(model.WorkflowNodeStateReady)
+		mockTM.On("GetTaskStates", mock.Anything, []uuid.UUID{node.ID}).Return(map[uuid.UUID]plugin.State{}, nil)
+
+		var registered []uuid.UUID
+		r := newReconciler(staticNodeLister([]model.WorkflowNode{node}), noOutstandingUpdates, mockTM,
+			func(_ context.Context, n model.WorkflowNode) error {
+				registered = append(registered, n.ID)
+				return nil
+			}, noPendingCancellations, nil, time.Minute)
+
+		report, err := r.Run(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.NodesChecked)
+		assert.Equal(t, []uuid.UUID{node.ID}, report.RegisteredTasks)
+		assert.Equal(t, []uuid.UUID{node.ID}, registered)
+		assert.Empty(t, report.Unresolved)
+		assert.Same(t, report, r.LastReport())
+	})
+
+	t.Run("Resends Notification When Task Is Ahead", func(t *testing.T) {
+		mockTM := new(MockTaskManager)
+		ahead := newTestNode(model.WorkflowNodeStateInProgress)
+		alreadyPending := newTestNode(model.WorkflowNodeStateReady)
+		inSync := newTestNode(model.WorkflowNodeStateInProgress)
+		nodes := []model.WorkflowNode{ahead, alreadyPending, inSync}
+		mockTM.On("GetTaskStates", mock.Anything, []uuid.UUID{ahead.ID, alreadyPending.ID, inSync.ID}).Return(map[uuid.UUID]plugin.State{
+			ahead.ID:          plugin.Completed,
+			alreadyPending.ID: plugin.InProgress,
+			inSync.ID:         plugin.InProgress,
+		}, nil)
+		mockTM.On("ResendStateNotification", mock.Anything, ahead.ID).Return(true, nil).Once()
+		mockTM.On("ResendStateNotification", mock.Anything, alreadyPending.ID).Return(false, nil).Once()
+
+		r := newReconciler(staticNodeLister(nodes), noOutstandingUpdates, mockTM,
+			func(context.Context, model.WorkflowNode) error {
+				t.Fatal("register must not be called")
+				return nil
+			}, noPendingCancellations, nil, time.Minute)
+
+		report, err := r.Run(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, report.NodesChecked)
+		assert.Equal(t, []uuid.UUID{ahead.ID}, report.ResentNotifications)
+		assert.Empty(t, report.Unresolved)
+		mockTM.AssertExpectations(t)
+	})
+
+	t.Run("Reports Unresolved Divergences", func(t *testing.T) {
+		mockTM := new(MockTaskManager)
+		missingInProgress := newTestNode(model.WorkflowNodeStateInProgress)
+		taskBehind := newTestNode(model.WorkflowNodeStateInProgress)
+		registrationFails := newTestNode(model.WorkflowNodeStateReady)
+		nodes := []model.WorkflowNode{missingInProgress, taskBehind, registrationFails}
+		mockTM.On("GetTaskStates", mock.Anything, mock.Anything).Return(map[uuid.UUID]plugin.State{
+			taskBehind.ID: plugin.Initialized,
+		}, nil)
+
+		r := newReconciler(staticNodeLister(nodes), noOutstandingUpdates, mockTM,
+			func(context.Context, model.WorkflowNode) error {
+				return errors.New("template not found")
+			}, noPendingCancellations, nil, time.Minute)
+
+		report, err := r.Run(context.Background())
+		assert.NoError(t, err)
+		assert.Empty(t, report.RegisteredTasks)
+		assert.Len(t, report.Unresolved, 3)
+		assert.Equal(t, missingInProgress.ID, report.Unresolved[0].WorkflowNodeID)
+		assert.Nil(t, report.Unresolved[0].TaskState)
+		assert.Equal(t, plugin.Initialized, *report.Unresolved[1].TaskState)
+		assert.Contains(t, report.Unresolved[2].Reason, "template not found")
+	})
+
+	t.Run("Skips Nodes With Outstanding Updates", func(t *testing.T) {
+		mockTM := new(MockTaskManager)
+		node := newTestNode(model.WorkflowNodeStateReady)
+		mockTM.On("GetTaskStates", mock.Anything, mock.Anything).Return(map[uuid.UUID]plugin.State{}, nil)
+
+		r := newReconciler(staticNodeLister([]model.WorkflowNode{node}),
+			func(context.Context, []uuid.UUID) (map[uuid.UUID]bool, error) {
+				return map[uuid.UUID]bool{node.ID: true}, nil
+			}, mockTM,
+			func(context.Context, model.WorkflowNode) error {
+				t.Fatal("register must not be called")
+				return nil
+			}, noPendingCancellations, nil, time.Minute)
+
+		report, err := r.Run(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.NodesChecked)
+		assert.Empty(t, report.RegisteredTasks)
+		assert.Empty(t, report.Unresolved)
+	})
+
+	t.Run("Task Lookup Error", func(t *testing.T) {
+		mockTM := new(MockTaskManager)
+		mockTM.On("GetTaskStates", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+
+		r := newReconciler(staticNodeLister([]model.WorkflowNode{newTestNode(model.WorkflowNodeStateReady)}),
+			noOutstandingUpdates, mockTM, nil, noPendingCancellations, nil, time.Minute)
+
+		report, err := r.Run(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, report)
+		assert.Nil(t, r.LastReport())
+	})
+
+	t.Run("Cancels Tasks Of Nodes Pending Task Cancellation", func(t *testing.T) {
+		mockTM := new(MockTaskManager)
+		cancelled := newTestNode(model.WorkflowNodeStateLocked)
+		failing := newTestNode(model.WorkflowNodeStateLocked)
+		mockTM.On("CancelTask", mock.Anything, cancelled.ID, reconcileCancelReason).Return(nil).Once()
+		mockTM.On("CancelTask", mock.Anything, failing.ID, reconcileCancelReason).Return(errors.New("db error")).Once()
+
+		var cleared []uuid.UUID
+		r := newReconciler(staticNodeLister(nil), noOutstandingUpdates, mockTM, nil,
+			func(_ context.Context, _ time.Time, afterID uuid.UUID, _ int) ([]model.WorkflowNode, error) {
+				if afterID != uuid.Nil {
+					return nil, nil
+				}
+				return []model.WorkflowNode{cancelled, failing}, nil
+			},
+			func(_ context.Context, ids []uuid.UUID) error {
+				cleared = append(cleared, ids...)
+				return nil
+			}, time.Minute)
+
+		report, err := r.Run(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.NodesChecked)
+		assert.Equal(t, []uuid.UUID{cancelled.ID}, report.CancelledTasks)
+		assert.Equal(t, []uuid.UUID{cancelled.ID}, cleared)
+		if assert.Len(t, report.Unresolved, 1) {
+			assert.Equal(t, failing.ID, report.Unresolved[0].WorkflowNodeID)
+			assert.Contains(t, report.Unresolved[0].Reason, "failed to cancel task")
+		}
+		mockTM.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,137 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// retryBatchSize is the maximum number of failed updates processed per retry cycle.
+const retryBatchSize = 100
+
+// failedUpdateStore is the subset of FailedWorkflowUpdateService used by the retry worker.
+type failedUpdateStore interface {
+	GetRetryableUpdates(ctx context.Context, limit int) ([]model.FailedWorkflowUpdate, error)
+	MarkResolved(ctx context.Context, failedUpdate *model.FailedWorkflowUpdate) error
+	RecordRetryFailure(ctx context.Context, failedUpdate *model.FailedWorkflowUpdate, cause error) error
+}
+
+// updateApplier applies a workflow node update and propagates its effects.
+type updateApplier func(ctx context.Context, update *model.UpdateWorkflowNodeDTO) error
+
+// RetryWorker periodically re-applies failed workflow node updates.
+// Updates are retried in creation order per workflow node; when an update is not yet due or
+// fails again, later updates for the same node are held back so they are never applied out of order.
+type RetryWorker struct {
+	store    failedUpdateStore
+	apply    updateApplier
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newRetryWorker(store failedUpdateStore, apply updateApplier, interval time.Duration) *RetryWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RetryWorker{
+		store:    store,
+		apply:    apply,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the retry loop in a background goroutine.
+func (w *RetryWorker) Start() {
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.ctx.Done():
+				slog.Info("workflow update retry worker stopped")
+				return
+			case <-ticker.C:
+				w.retryDue(w.ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the retry loop and waits for the in-flight cycle to finish.
+func (w *RetryWorker) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// retryDue retries one batch of pending failed updates whose backoff has elapsed.
+// Returns the number of updates applied successfully.
+func (w *RetryWorker) retryDue(ctx context.Context) int {
+	updates, err := w.store.GetRetryableUpdates(ctx, retryBatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load retryable workflow updates", "error", err)
+		return 0
+	}
+
+	now := time.Now().UTC()
+	resolved := 0
+	blockedNodes := make(map[uuid.UUID]struct{})
+	for i := range updates {
+		if ctx.Err() != nil {
+			return resolved
+		}
+
+		failedUpdate := &updates[i]
+		if _, blocked := blockedNodes[failedUpdate.WorkflowNodeID]; blocked {
+			continue
+		}
+		if failedUpdate.NextAttemptAt.After(now) {
+			blockedNodes[failedUpdate.WorkflowNodeID] = struct{}{}
+			continue
+		}
+
+		if err := w.apply(ctx, failedUpdate.ToUpdateWorkflowNodeDTO()); err != nil {
+			blockedNodes[failedUpdate.WorkflowNodeID] = struct{}{}
+			if recordErr := w.store.RecordRetryFailure(ctx, failedUpdate, err); recordErr != nil {
+				slog.ErrorContext(ctx, "failed to record workflow update retry failure",
+					"failedUpdateID", failedUpdate.ID,
+					"error", recordErr)
+				continue
+			}
+			if failedUpdate.Status == model.FailedWorkflowUpdateStatusDeadLettered {
+				slog.ErrorContext(ctx, "workflow update moved to dead-letter queue",
+					"failedUpdateID", failedUpdate.ID,
+					"workflowNodeID", failedUpdate.WorkflowNodeID,
+					"attempts", failedUpdate.Attempts,
+					"error", err)
+			} else {
+				slog.WarnContext(ctx, "workflow update retry failed",
+					"failedUpdateID", failedUpdate.ID,
+					"workflowNodeID", failedUpdate.WorkflowNodeID,
+					"attempts", failedUpdate.Attempts,
+					"nextAttemptAt", failedUpdate.NextAttemptAt,
+					"error", err)
+			}
+			continue
+		}
+
+		if err := w.store.MarkResolved(ctx, failedUpdate); err != nil {
+			// The update will be applied again; the state machine tolerates duplicates.
+			blockedNodes[failedUpdate.WorkflowNodeID] = struct{}{}
+			slog.ErrorContext(ctx, "failed to mark workflow update as resolved",
+				"failedUpdateID", failedUpdate.ID,
+				"error", err)
+			continue
+		}
+		resolved++
+	}
+
+	return resolved
+}
@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// MockFailedUpdateStore is a mock implementation of failedUpdateStore
+type MockFailedUpdateStore struct {
+	mock.Mock
+}
+
+func (m *MockFailedUpdateStore) GetRetryableUpdates(ctx context.Context, limit int) ([]model.FailedWorkflowUpdate, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.FailedWorkflowUpdate), args.Error(1)
+}
+
+func (m *MockFailedUpdateStore) MarkResolved(ctx context.Context, failedUpdate *model.FailedWorkflowUpdate) error {
+	args := m.Called(ctx, failedUpdate)
+	return args.Error(0)
+}
+
+func (m *MockFailedUpdateStore) RecordRetryFailure(ctx context.Context, failedUpdate *model.FailedWorkflowUpdate, cause error) error {
+	args := m.Called(ctx, failedUpdate, cause)
+	return args.Error(0)
+}
+
+func newFailedUpdate(nodeID uuid.UUID, nextAttemptAt time.Time) model.FailedWorkflowUpdate {
+	return model.FailedWorkflowUpdate{
+		BaseModel:      model.BaseModel{ID: uuid.New()},
+		WorkflowNodeID: nodeID,
+		State:          model.WorkflowNodeStateCompleted,
+		Status:         model.FailedWorkflowUpdateStatusPending,
+		NextAttemptAt:  nextAttemptAt,
+	}
+}
+
+func TestRetryWorker_RetryDue(t *testing.T) {
+	past := time.Now().UTC().Add(-time.Minute)
+	future := time.Now().UTC().Add(time.Hour)
+
+	t.Run("Applies Due Updates In Order", func(t *testing.T) {
+		store := new(MockFailedUpdateStore)
+		nodeID := uuid.New()
+		updates := []model.FailedWorkflowUpdate{newFailedUpdate(nodeID, past), newFailedUpdate(nodeID, past)}
+		store.On("GetRetryableUpdates", mock.Anything, retryBatchSize).Return(updates, nil).Once()
+		store.On("MarkResolved", mock.Anything, mock.Anything).Return(nil).Twice()
+
+		var applied []uuid.UUID
+		worker := newRetryWorker(store, func(_ context.Context, update *model.UpdateWorkflowNodeDTO) error {
+			applied = append(applied, update.WorkflowNodeID)
+			return nil
+		}, time.Second)
+
+		assert.Equal(t, 2, worker.retryDue(context.Background()))
+		assert.Equal(t, []uuid.UUID{nodeID, nodeID}, applied)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Not Due Holds Back Same Node Only", func(t *testing.T) {
+		store := new(MockFailedUpdateStore)
+		waitingNode := uuid.New()
+		otherNode := uuid.New()
+		updates := []model.FailedWorkflowUpdate{
+			newFailedUpdate(waitingNode, future),
+			newFailedUpdate(otherNode, past),
+			newFailedUpdate(waitingNode, past),
+		}
+		store.On("GetRetryableUpdates", mock.Anything, retryBatchSize).Return(updates, nil).Once()
+		store.On("MarkResolved", mock.Anything, &updates[1]).Return(nil).Once()
+
+		var applied []uuid.UUID
+		worker := newRetryWorker(store, func(_ context.Context, update *model.UpdateWorkflowNodeDTO) error {
+			applied = append(applied, update.WorkflowNodeID)
+			return nil
+		}, time.Second)
+
+		assert.Equal(t, 1, worker.retryDue(context.Background()))
+		assert.Equal(t, []uuid.UUID{otherNode}, applied)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Failure Records Attempt And Blocks Node", func(t *testing.T) {
+		store := new(MockFailedUpdateStore)
+		nodeID := uuid.New()
+		updates := []model.FailedWorkflowUpdate{newFailedUpdate(nodeID, past), newFailedUpdate(nodeID, past)}
+		applyErr := errors.New("db unavailable")
+		store.On("GetRetryableUpdates", mock.Anything, retryBatchSize).Return(updates, nil).Once()
+		store.On("RecordRetryFailure", mock.Anything, &updates[0], applyErr).Return(nil).Once()
+
+		calls := 0
+		worker := newRetryWorker(store, func(context.Context, *model.UpdateWorkflowNodeDTO) error {
+			calls++
+			return applyErr
+		}, time.Second)
+
+		assert.Equal(t, 0, worker.retryDue(context.Background()))
+		assert.Equal(t, 1, calls)
+		store.AssertNotCalled(t, "MarkResolved", mock.Anything, mock.Anything)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Store Error", func(t *testing.T) {
+		store := new(MockFailedUpdateStore)
+		store.On("GetRetryableUpdates", mock.Anything, retryBatchSize).Return(nil, errors.New("db error")).Once()
+
+		worker := newRetryWorker(store, func(context.Context, *model.UpdateWorkflowNodeDTO) error {
+			t.Fatal("apply must not be called")
+			return nil
+		}, time.Second)
+
+		assert.Equal(t, 0, worker.retryDue(context.Background()))
+	})
+}
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// requireAdmin writes an error response and returns false unless the request is made by an administrator.
+func requireAdmin(w http.ResponseWriter, req *http.Request) bool {
+	authCtx := auth.GetAuthContext(req.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if !authCtx.HasRole(auth.RoleAdmin) {
+		http.Error(w, "Forbidden - admin access required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireAgency writes an error response and returns false unless the request is made by an agency officer
+// or an administrator.
+func requireAgency(w http.ResponseWriter, req *http.Request) bool {
+	authCtx := auth.GetAuthContext(req.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if !authCtx.HasRole(auth.RoleAgency) && !authCtx.HasRole(auth.RoleAdmin) {
+		http.Error(w, "Forbidden - agency access required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// writeJSON encodes body as the JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,7 +2,13 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -24,7 +30,8 @@ func NewConsignmentRouter(cs *service.ConsignmentService, _ interface{}) *Consig
 
 // HandleCreateConsignment handles POST /api/v1/consignments
 // Request body: CreateConsignmentDTO
-// Response: ConsignmentDetailDTO
+// Response: ConsignmentDetailDTO; MissingPreConsignmentsResponseDTO with 422 if the trader has not completed
+// the pre-consignments the consignment's workflow templates require
 func (c *ConsignmentRouter) HandleCreateConsignment(w http.ResponseWriter, r *http.Request) {
 	// Require authentication
 	authCtx := auth.GetAuthContext(r.Context())
@@ -55,6 +62,27 @@ func (c *ConsignmentRouter) HandleCreateConsignment(w http.ResponseWriter, r *ht
 	// Task registration happens within the transaction via pre-commit callback
 	consignment, _, err := c.cs.InitializeConsignment(r.Context(), &req, traderId, globalContext)
 	if err != nil {
+		var missingErr *service.MissingPreConsignmentsError
+		if errors.As(err, &missingErr) {
+			missing := make([]model.PreConsignmentTemplateResponseDTO, len(missingErr.Templates))
+			for i, template := range missingErr.Templates {
+				dependsOn := template.DependsOn
+				if dependsOn == nil {
+					dependsOn = []string{}
+				}
+				missing[i] = model.PreConsignmentTemplateResponseDTO{
+					ID:          template.ID,
+					Name:        template.Name,
+					Description: template.Description,
+					DependsOn:   dependsOn,
+				}
+			}
+			writeJSON(w, http.StatusUnprocessableEntity, model.MissingPreConsignmentsResponseDTO{
+				Error:                  service.ErrPreConsignmentsRequired.Error(),
+				MissingPreConsignments: missing,
+			})
+			return
+		}
 		http.Error(w, "failed to create consignment: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -70,7 +98,8 @@ func (c *ConsignmentRouter) HandleCreateConsignment(w http.ResponseWriter, r *ht
 
 // HandleGetConsignmentsByTraderID handles GET /api/v1/consignments
 // No query params required for traderId - uses traderId from auth context
-// Pagination query params: offset (optional), limit (optional)
+// Pagination query params: offset (optional), limit (optional), cursor (optional; nextCursor of the previous
+// page, instead of offset)
 // Response: ConsignmentListResult (containing ConsignmentSummaryDTO)
 func (c *ConsignmentRouter) HandleGetConsignmentsByTraderID(w http.ResponseWriter, r *http.Request) {
 	// Require authentication
@@ -88,9 +117,14 @@ func (c *ConsignmentRouter) HandleGetConsignmentsByTraderID(w http.ResponseWrite
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	cursor, err := utils.ParseCursorParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Parse optional filters
-	var filter model.ConsignmentFilter
+	filter := model.ConsignmentFilter{Cursor: cursor}
 	if stateStr := r.URL.Query().Get("state"); stateStr != "" {
 		state := model.ConsignmentState(stateStr)
 		filter.State = &state
@@ -103,6 +137,10 @@ func (c *ConsignmentRouter) HandleGetConsignmentsByTraderID(w http.ResponseWrite
 	// Get consignments from service
 	consignments, err := c.cs.GetConsignmentsByTraderID(r.Context(), traderID, offset, limit, filter)
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			http.Error(w, "invalid 'cursor' query parameter", http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "failed to retrieve consignments: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -116,6 +154,134 @@ func (c *ConsignmentRouter) HandleGetConsignmentsByTraderID(w http.ResponseWrite
 	}
 }
 
+// HandleSearchConsignments handles GET /api/v1/consignments/search
+// Restricted to agency officers and administrators; searches the consignments of all traders.
+// Query params (all optional): traderId, state, flow, hsCode (code or prefix), createdFrom and createdTo (RFC 3339
+// timestamps, or dates; createdTo includes the whole day), nodeTemplate (part of a node template name),
+// nodeState, nodeOutcome, context.<path> (global context value at a dot-separated path), sort (createdAt,
+// updatedAt, state or flow), order (asc or desc, default desc), offset, limit, cursor (nextCursor of the previous
+// page of the same search, instead of offset)
+// Response: ConsignmentListResult (containing ConsignmentSummaryDTO)
+func (c *ConsignmentRouter) HandleSearchConsignments(w http.ResponseWriter, r *http.Request) {
+	if !requireAgency(w, r) {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseConsignmentSearchFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Offset = offset
+	filter.Limit = limit
+	if filter.Cursor, err = utils.ParseCursorParam(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := c.cs.SearchConsignments(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidConsignmentSearch) || errors.Is(err, utils.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to search consignments: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseConsignmentSearchFilter parses the query parameters of a consignment search, except for pagination.
+func parseConsignmentSearchFilter(query url.Values) (model.ConsignmentSearchFilter, error) {
+	var filter model.ConsignmentSearchFilter
+	optional := func(name string) *string {
+		if value := strings.TrimSpace(query.Get(name)); value != "" {
+			return &value
+		}
+		return nil
+	}
+
+	filter.TraderID = optional("traderId")
+	if state := optional("state"); state != nil {
+		consignmentState := model.ConsignmentState(*state)
+		filter.State = &consignmentState
+	}
+	if flow := optional("flow"); flow != nil {
+		consignmentFlow := model.ConsignmentFlow(*flow)
+		filter.Flow = &consignmentFlow
+	}
+	filter.HSCode = optional("hsCode")
+	filter.NodeTemplate = optional("nodeTemplate")
+	if nodeState := optional("nodeState"); nodeState != nil {
+		state := model.WorkflowNodeState(*nodeState)
+		switch state {
+		case model.WorkflowNodeStateLocked, model.WorkflowNodeStateReady, model.WorkflowNodeStateInProgress,
+			model.WorkflowNodeStateCompleted, model.WorkflowNodeStateFailed, model.WorkflowNodeStateSkipped:
+		default:
+			return filter, fmt.Errorf("invalid nodeState: %s", *nodeState)
+		}
+		filter.NodeState = &state
+	}
+	filter.NodeOutcome = optional("nodeOutcome")
+
+	if createdFrom := optional("createdFrom"); createdFrom != nil {
+		from, _, err := parseSearchTime(*createdFrom)
+		if err != nil {
+			return filter, fmt.Errorf("invalid createdFrom: %w", err)
+		}
+		filter.CreatedFrom = &from
+	}
+	if createdTo := optional("createdTo"); createdTo != nil {
+		to, isDate, err := parseSearchTime(*createdTo)
+		if err != nil {
+			return filter, fmt.Errorf("invalid createdTo: %w", err)
+		}
+		if isDate {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.CreatedTo = &to
+	}
+
+	for name, values := range query {
+		path, ok := strings.CutPrefix(name, "context.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if filter.GlobalContext == nil {
+			filter.GlobalContext = make(map[string]string)
+		}
+		filter.GlobalContext[path] = values[0]
+	}
+
+	filter.SortBy = model.ConsignmentSortField(query.Get("sort"))
+	switch order := query.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, fmt.Errorf("invalid order: %s", order)
+	}
+	return filter, nil
+}
+
+// parseSearchTime parses an RFC 3339 timestamp or a date, reporting whether value was a date.
+func parseSearchTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a date", value)
+	}
+	return t, true, nil
+}
+
 // HandleGetConsignmentByID handles GET /api/v1/consignments/{id}
 // Path param: id (required)
 // Response: ConsignmentDetailDTO
@@ -149,3 +315,261 @@ func (c *ConsignmentRouter) HandleGetConsignmentByID(w http.ResponseWriter, r *h
 		return
 	}
 }
+
+// HandleGetConsignmentGraph handles GET /api/v1/consignments/{id}/graph
+// Renders the workflow node graph of one of the trader's consignments.
+// Path param: id (required)
+// Query param: format (optional) - json (default), dot or mermaid
+// Response: WorkflowGraph, a Graphviz DOT document or a Mermaid flowchart
+func (c *ConsignmentRouter) HandleGetConsignmentGraph(w http.ResponseWriter, r *http.Request) {
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	c.writeConsignmentGraph(w, r, &authCtx.TraderID)
+}
+
+// HandleAdminGetConsignmentGraph handles GET /api/v1/admin/consignments/{id}/graph
+// Renders the workflow node graph of a consignment of any trader.
+// Path param: id (required)
+// Query param: format (optional) - json (default), dot or mermaid
+// Response: WorkflowGraph, a Graphviz DOT document or a Mermaid flowchart
+func (c *ConsignmentRouter) HandleAdminGetConsignmentGraph(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	c.writeConsignmentGraph(w, r, nil)
+}
+
+func (c *ConsignmentRouter) writeConsignmentGraph(w http.ResponseWriter, r *http.Request, traderID *string) {
+	consignmentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := model.WorkflowGraphFormat(r.URL.Query().Get("format"))
+	switch format {
+	case "":
+		format = model.WorkflowGraphFormatJSON
+	case model.WorkflowGraphFormatJSON, model.WorkflowGraphFormatDOT, model.WorkflowGraphFormatMermaid:
+	default:
+		http.Error(w, "invalid format: must be json, dot or mermaid", http.StatusBadRequest)
+		return
+	}
+
+	graph, err := c.cs.GetConsignmentGraph(r.Context(), consignmentID, traderID)
+	if err != nil {
+		if errors.Is(err, service.ErrConsignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to retrieve consignment graph: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch format {
+	case model.WorkflowGraphFormatDOT:
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		_, _ = io.WriteString(w, graph.DOT())
+	case model.WorkflowGraphFormatMermaid:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, graph.Mermaid())
+	default:
+		writeJSON(w, http.StatusOK, graph)
+	}
+}
+
+// HandleCancelConsignment handles POST /api/v1/consignments/{id}/cancel
+// Path param: id (required)
+// Request body: CancelConsignmentDTO
+// Response: ConsignmentDetailDTO
+func (c *ConsignmentRouter) HandleCancelConsignment(w http.ResponseWriter, r *http.Request) {
+	// Require authentication
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Extract consignment ID from path
+	consignmentIDStr := r.PathValue("id")
+	if consignmentIDStr == "" {
+		http.Error(w, "consignment ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Parse UUID
+	consignmentID, err := uuid.Parse(consignmentIDStr)
+	if err != nil {
+		http.Error(w, "invalid consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req model.CancelConsignmentDTO
+
+	// Parse request body
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		http.Error(w, "cancellation reason is required", http.StatusBadRequest)
+		return
+	}
+
+	// Cancel consignment through service
+	// Tasks of active workflow nodes are cancelled via post-cancel callback
+	consignment, err := c.cs.CancelConsignment(r.Context(), consignmentID, authCtx.TraderID, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrConsignmentNotFound):
+			http.Error(w, "failed to cancel consignment: "+err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrConsignmentNotCancellable):
+			http.Error(w, "failed to cancel consignment: "+err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "failed to cancel consignment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Return response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(consignment); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleAmendConsignment handles POST /api/v1/consignments/{id}/amendments
+// Adds items to and removes items from an in-progress consignment; its workflow nodes are changed to
+// match the workflow templates the new set of items requires.
+// Path param: id (required)
+// Request body: AmendConsignmentDTO
+// Response: ConsignmentAmendmentResultDTO; 404 if the consignment does not exist, 409 if it is no longer
+// in progress and 400 if the amendment is invalid
+func (c *ConsignmentRouter) HandleAmendConsignment(w http.ResponseWriter, r *http.Request) {
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	consignmentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req model.AmendConsignmentDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Tasks of voided workflow nodes are cancelled via post-cancel callback
+	result, err := c.cs.AmendConsignment(r.Context(), consignmentID, authCtx.TraderID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrConsignmentNotFound):
+			http.Error(w, "failed to amend consignment: "+err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrConsignmentNotAmendable):
+			http.Error(w, "failed to amend consignment: "+err.Error(), http.StatusConflict)
+		case errors.Is(err, service.ErrInvalidConsignmentAmendment):
+			http.Error(w, "failed to amend consignment: "+err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "failed to amend consignment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleGetConsignmentAmendments handles GET /api/v1/consignments/{id}/amendments
+// Path param: id (required)
+// Response: array of ConsignmentAmendment, oldest first
+func (c *ConsignmentRouter) HandleGetConsignmentAmendments(w http.ResponseWriter, r *http.Request) {
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	c.writeConsignmentAmendments(w, r, &authCtx.TraderID)
+}
+
+// HandleAdminGetConsignmentAmendments handles GET /api/v1/admin/consignments/{id}/amendments
+// Lists the amendments of a consignment of any trader.
+// Path param: id (required)
+// Response: array of ConsignmentAmendment, oldest first
+func (c *ConsignmentRouter) HandleAdminGetConsignmentAmendments(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	c.writeConsignmentAmendments(w, r, nil)
+}
+
+func (c *ConsignmentRouter) writeConsignmentAmendments(w http.ResponseWriter, r *http.Request, traderID *string) {
+	consignmentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	amendments, err := c.cs.GetConsignmentAmendments(r.Context(), consignmentID, traderID)
+	if err != nil {
+		if errors.Is(err, service.ErrConsignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to retrieve consignment amendments: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, amendments)
+}
+
+// HandleGetConsignmentEvents handles GET /api/v1/consignments/{id}/events
+// Path param: id (required)
+// Response: array of WorkflowEvent, in the order they occurred
+func (c *ConsignmentRouter) HandleGetConsignmentEvents(w http.ResponseWriter, r *http.Request) {
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	c.writeConsignmentEvents(w, r, &authCtx.TraderID)
+}
+
+// HandleAdminGetConsignmentEvents handles GET /api/v1/admin/consignments/{id}/events
+// Lists the events of a consignment of any trader.
+// Path param: id (required)
+// Response: array of WorkflowEvent, in the order they occurred
+func (c *ConsignmentRouter) HandleAdminGetConsignmentEvents(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	c.writeConsignmentEvents(w, r, nil)
+}
+
+func (c *ConsignmentRouter) writeConsignmentEvents(w http.ResponseWriter, r *http.Request, traderID *string) {
+	consignmentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := c.cs.GetConsignmentEvents(r.Context(), consignmentID, traderID)
+	if err != nil {
+		if errors.Is(err, service.ErrConsignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to retrieve consignment events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+// FailedUpdateRouter handles HTTP routing for the administrative failed workflow update endpoints.
+type FailedUpdateRouter struct {
+	fus *service.FailedWorkflowUpdateService
+}
+
+// NewFailedUpdateRouter creates a new FailedUpdateRouter.
+func NewFailedUpdateRouter(fus *service.FailedWorkflowUpdateService) *FailedUpdateRouter {
+	return &FailedUpdateRouter{
+		fus: fus,
+	}
+}
+
+// HandleGetFailedUpdates handles GET /api/v1/admin/failed-workflow-updates
+// Query params: status (optional, defaults to DEAD_LETTERED), workflowNodeId (optional),
+// offset (optional), limit (optional)
+// Response: FailedWorkflowUpdateListResult
+func (r *FailedUpdateRouter) HandleGetFailedUpdates(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := model.FailedWorkflowUpdateStatusDeadLettered
+	if statusStr := req.URL.Query().Get("status"); statusStr != "" {
+		status = model.FailedWorkflowUpdateStatus(statusStr)
+		switch status {
+		case model.FailedWorkflowUpdateStatusPending, model.FailedWorkflowUpdateStatusDeadLettered,
+			model.FailedWorkflowUpdateStatusResolved, model.FailedWorkflowUpdateStatusDiscarded:
+		default:
+			http.Error(w, "invalid status: "+statusStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := model.FailedWorkflowUpdateFilter{
+		Status: &status,
+		Offset: offset,
+		Limit:  limit,
+	}
+
+	if nodeIDStr := req.URL.Query().Get("workflowNodeId"); nodeIDStr != "" {
+		nodeID, err := uuid.Parse(nodeIDStr)
+		if err != nil {
+			http.Error(w, "invalid workflow node ID format: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.WorkflowNodeID = &nodeID
+	}
+
+	result, err := r.fus.ListFailedUpdates(req.Context(), filter)
+	if err != nil {
+		http.Error(w, "failed to retrieve failed workflow updates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleGetFailedUpdateByID handles GET /api/v1/admin/failed-workflow-updates/{id}
+// Path param: id (required)
+// Response: FailedWorkflowUpdate
+func (r *FailedUpdateRouter) HandleGetFailedUpdateByID(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	id, ok := parseFailedUpdateID(w, req)
+	if !ok {
+		return
+	}
+
+	failedUpdate, err := r.fus.GetFailedUpdateByID(req.Context(), id)
+	if err != nil {
+		writeFailedUpdateError(w, "failed to retrieve failed workflow update", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, failedUpdate)
+}
+
+// HandleReplayFailedUpdate handles POST /api/v1/admin/failed-workflow-updates/{id}/replay
+// Moves a dead-lettered update back to the retry queue with a fresh attempt budget.
+// Response: FailedWorkflowUpdate
+func (r *FailedUpdateRouter) HandleReplayFailedUpdate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	id, ok := parseFailedUpdateID(w, req)
+	if !ok {
+		return
+	}
+
+	failedUpdate, err := r.fus.ReplayFailedUpdate(req.Context(), id)
+	if err != nil {
+		writeFailedUpdateError(w, "failed to replay failed workflow update", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, failedUpdate)
+}
+
+// HandleDiscardFailedUpdate handles POST /api/v1/admin/failed-workflow-updates/{id}/discard
+// Drops a dead-lettered update without applying it.
+// Response: FailedWorkflowUpdate
+func (r *FailedUpdateRouter) HandleDiscardFailedUpdate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	id, ok := parseFailedUpdateID(w, req)
+	if !ok {
+		return
+	}
+
+	failedUpdate, err := r.fus.DiscardFailedUpdate(req.Context(), id)
+	if err != nil {
+		writeFailedUpdateError(w, "failed to discard failed workflow update", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, failedUpdate)
+}
+
+func parseFailedUpdateID(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	idStr := req.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "failed workflow update ID is required", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid failed workflow update ID format: "+err.Error(), http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeFailedUpdateError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrFailedUpdateNotFound):
+		http.Error(w, message+": "+err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrFailedUpdateNotDeadLettered):
+		http.Error(w, message+": "+err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
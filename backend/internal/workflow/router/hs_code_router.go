@@ -7,6 +7,7 @@ import (
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/internal/workflow/service"
+	"github.com/OpenNSW/nsw/utils"
 )
 
 type HSCodeRouter struct {
@@ -20,7 +21,8 @@ func NewHSCodeRouter(hscs *service.HSCodeService) *HSCodeRouter {
 }
 
 // HandleGetAllHSCodes handles GET /api/v1/hscodes
-// Optional Query Params: hsCodeStartsWith, offset, limit
+// Optional Query Params: hsCodeStartsWith, offset, limit, cursor (nextCursor of the previous page, instead of
+// offset)
 func (h *HSCodeRouter) HandleGetAllHSCodes(w http.ResponseWriter, r *http.Request) {
 	var filter model.HSCodeFilter
 
@@ -47,6 +49,13 @@ func (h *HSCodeRouter) HandleGetAllHSCodes(w http.ResponseWriter, r *http.Reques
 		filter.Offset = &offset
 	}
 
+	cursor, err := utils.ParseCursorParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Cursor = cursor
+
 	// Get HS codes from service
 	hsCodes, err := h.hscs.GetAllHSCodes(r.Context(), filter)
 	if err != nil {
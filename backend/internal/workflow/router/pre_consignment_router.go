@@ -2,7 +2,9 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 
@@ -26,7 +28,8 @@ func NewPreConsignmentRouter(pcs *service.PreConsignmentService) *PreConsignment
 
 // HandleGetTraderPreConsignments handles GET /api/v1/pre-consignments
 // No query params required for traderId - uses traderId from auth context
-// Pagination query params: offset (optional), limit (optional)
+// Pagination query params: offset (optional), limit (optional), cursor (optional; nextCursor of the previous
+// page, instead of offset)
 // Response: TraderPreConsignmentsResponseDTO
 func (r *PreConsignmentRouter) HandleGetTraderPreConsignments(w http.ResponseWriter, req *http.Request) {
 	// Require authentication
@@ -44,8 +47,13 @@ func (r *PreConsignmentRouter) HandleGetTraderPreConsignments(w http.ResponseWri
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	cursor, err := utils.ParseCursorParam(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	templates, err := r.pcs.GetTraderPreConsignments(req.Context(), traderID, offset, limit)
+	templates, err := r.pcs.GetTraderPreConsignments(req.Context(), traderID, offset, limit, cursor)
 	if err != nil {
 		http.Error(w, "failed to retrieve pre-consignment templates: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -152,3 +160,126 @@ func (r *PreConsignmentRouter) HandleGetPreConsignmentByID(w http.ResponseWriter
 		return
 	}
 }
+
+// HandleRenewPreConsignment handles POST /api/v1/pre-consignments/{preConsignmentId}/renew
+// Starts a new pre-consignment from a completed, expired or revoked pre-consignment of the authenticated trader,
+// pre-filled with its trader context.
+// Response: PreConsignmentResponseDTO of the new pre-consignment
+func (r *PreConsignmentRouter) HandleRenewPreConsignment(w http.ResponseWriter, req *http.Request) {
+	authCtx := auth.GetAuthContext(req.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preConsignmentID, err := uuid.Parse(req.PathValue("preConsignmentId"))
+	if err != nil {
+		http.Error(w, "invalid pre-consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	traderContext, err := authCtx.GetTraderContextMap()
+	if err != nil {
+		http.Error(w, "failed to parse trader context", http.StatusInternalServerError)
+		return
+	}
+
+	preConsignment, _, err := r.pcs.RenewPreConsignment(req.Context(), preConsignmentID, authCtx.TraderID, traderContext)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPreConsignmentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrPreConsignmentNotRenewable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "failed to renew pre-consignment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, preConsignment)
+}
+
+// HandleRevokePreConsignment handles POST /api/v1/pre-consignments/{preConsignmentId}/revoke
+// Restricted to agency officers and administrators.
+// Request body: RevokePreConsignmentDTO
+// Response: PreConsignmentResponseDTO
+func (r *PreConsignmentRouter) HandleRevokePreConsignment(w http.ResponseWriter, req *http.Request) {
+	if !requireAgency(w, req) {
+		return
+	}
+
+	preConsignmentID, err := uuid.Parse(req.PathValue("preConsignmentId"))
+	if err != nil {
+		http.Error(w, "invalid pre-consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var revokeReq model.RevokePreConsignmentDTO
+	if err := json.NewDecoder(req.Body).Decode(&revokeReq); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(revokeReq.Reason) == "" {
+		http.Error(w, "revocation reason is required", http.StatusBadRequest)
+		return
+	}
+
+	preConsignment, err := r.pcs.RevokePreConsignment(req.Context(), preConsignmentID, revokeReq.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPreConsignmentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrPreConsignmentNotRevocable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "failed to revoke pre-consignment: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preConsignment)
+}
+
+// HandleGetPreConsignmentEvents handles GET /api/v1/pre-consignments/{preConsignmentId}/events
+// Path param: preConsignmentId (required)
+// Response: array of WorkflowEvent, in the order they occurred
+func (r *PreConsignmentRouter) HandleGetPreConsignmentEvents(w http.ResponseWriter, req *http.Request) {
+	authCtx := auth.GetAuthContext(req.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.writePreConsignmentEvents(w, req, &authCtx.TraderID)
+}
+
+// HandleAdminGetPreConsignmentEvents handles GET /api/v1/admin/pre-consignments/{preConsignmentId}/events
+// Lists the events of a pre-consignment of any trader.
+// Path param: preConsignmentId (required)
+// Response: array of WorkflowEvent, in the order they occurred
+func (r *PreConsignmentRouter) HandleAdminGetPreConsignmentEvents(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	r.writePreConsignmentEvents(w, req, nil)
+}
+
+func (r *PreConsignmentRouter) writePreConsignmentEvents(w http.ResponseWriter, req *http.Request, traderID *string) {
+	preConsignmentID, err := uuid.Parse(req.PathValue("preConsignmentId"))
+	if err != nil {
+		http.Error(w, "invalid pre-consignment ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := r.pcs.GetPreConsignmentEvents(req.Context(), preConsignmentID, traderID)
+	if err != nil {
+		if errors.Is(err, service.ErrPreConsignmentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to retrieve pre-consignment events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// Reconciler runs reconciliation passes between workflow nodes and task records.
+type Reconciler interface {
+	Run(ctx context.Context) (*model.ReconciliationReport, error)
+	LastReport() *model.ReconciliationReport
+}
+
+// ReconciliationRouter handles HTTP routing for the administrative reconciliation endpoints.
+type ReconciliationRouter struct {
+	reconciler Reconciler
+}
+
+// NewReconciliationRouter creates a new ReconciliationRouter.
+func NewReconciliationRouter(reconciler Reconciler) *ReconciliationRouter {
+	return &ReconciliationRouter{
+		reconciler: reconciler,
+	}
+}
+
+// HandleRunReconciliation handles POST /api/v1/admin/reconciliations
+// Runs a reconciliation pass immediately and returns what it fixed.
+// Response: ReconciliationReport
+func (r *ReconciliationRouter) HandleRunReconciliation(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	report, err := r.reconciler.Run(req.Context())
+	if err != nil {
+		http.Error(w, "failed to run reconciliation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// HandleGetLatestReconciliation handles GET /api/v1/admin/reconciliations/latest
+// Response: ReconciliationReport of the most recent completed run
+func (r *ReconciliationRouter) HandleGetLatestReconciliation(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	report := r.reconciler.LastReport()
+	if report == nil {
+		http.Error(w, "no reconciliation has completed yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
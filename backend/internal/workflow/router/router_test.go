@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
@@ -18,8 +20,10 @@ import (
 	"gorm.io/gorm/logger"
 
 	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/internal/workflow/service"
+	"github.com/OpenNSW/nsw/utils"
 )
 
 type MockTemplateProvider struct {
@@ -170,6 +174,39 @@ func TestConsignmentRouter_HandleCreateConsignment(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, w.Code)
 }
 
+func TestConsignmentRouter_HandleCreateConsignment_MissingPreConsignments(t *testing.T) {
+	db, sqlMock := setupRouterTestDB(t)
+	tp := new(MockTemplateProvider)
+	svc := service.NewConsignmentService(db, tp, nil)
+	r := NewConsignmentRouter(svc, nil)
+
+	brTemplateID := uuid.New()
+	tp.On("GetWorkflowTemplateByHSCodeIDAndFlow", mock.Anything, mock.Anything, mock.Anything).Return(&model.WorkflowTemplate{
+		BaseModel:               model.BaseModel{ID: uuid.New()},
+		NodeTemplates:           []uuid.UUID{uuid.New()},
+		RequiredPreConsignments: []model.PreConsignmentRequirement{{PreConsignmentTemplateID: brTemplateID, ContextKey: "br"}},
+	}, nil)
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id IN \(\$1\)`).
+		WithArgs(brTemplateID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(brTemplateID, "Business Registration"))
+
+	body, _ := json.Marshal(model.CreateConsignmentDTO{Flow: model.ConsignmentFlowExport, Items: []model.CreateConsignmentItemDTO{{HSCodeID: uuid.New()}}})
+	req, _ := http.NewRequest("POST", "/api/v1/consignments", bytes.NewBuffer(body))
+	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+	w := httptest.NewRecorder()
+	r.HandleCreateConsignment(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp model.MissingPreConsignmentsResponseDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.MissingPreConsignments, 1) {
+		assert.Equal(t, brTemplateID, resp.MissingPreConsignments[0].ID)
+		assert.Equal(t, "Business Registration", resp.MissingPreConsignments[0].Name)
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
 func TestHSCodeRouter_HandleGetAllHSCodes(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	svc := service.NewHSCodeService(db)
@@ -253,10 +290,12 @@ func TestPreConsignmentRouter_HandleCreatePreConsignment(t *testing.T) {
 	sqlMock.ExpectBegin()
 	sqlMock.ExpectExec("(?i)INSERT INTO \"pre_consignments\"").WillReturnResult(sqlmock.NewResult(1, 1))
 	sqlMock.ExpectExec("(?i)INSERT INTO \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
 
 	// State machine initialization
 	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\"").WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_node_template_id"}).AddRow(uuid.New(), nodeTemplateID))
 	sqlMock.ExpectExec("(?i)UPDATE \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
 
 	sqlMock.ExpectCommit()
 
@@ -311,6 +350,31 @@ func TestConsignmentRouter_HandleGetConsignmentsByTraderID_PaginationError(t *te
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestConsignmentRouter_HandleGetConsignmentsByTraderID_CursorError(t *testing.T) {
+	db, _ := setupRouterTestDB(t)
+	r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+	cursor := utils.Cursor{Key: "2026-10-17T09:30:00Z", ID: uuid.New()}.Encode()
+
+	for _, query := range []string{"cursor=invalid", "offset=10&cursor=" + cursor} {
+		req, _ := http.NewRequest("GET", "/api/v1/consignments?"+query, nil)
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+
+		w := httptest.NewRecorder()
+		r.HandleGetConsignmentsByTraderID(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// A cursor of another list, whose sort key is not a timestamp
+	req, _ := http.NewRequest("GET", "/api/v1/consignments?cursor="+utils.Cursor{Key: "0902.10", ID: uuid.New()}.Encode(), nil)
+	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+
+	w := httptest.NewRecorder()
+	r.HandleGetConsignmentsByTraderID(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestConsignmentRouter_HandleGetConsignmentByID_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	svc := service.NewConsignmentService(db, nil, nil)
@@ -379,6 +443,304 @@ func TestConsignmentRouter_HandleCreateConsignment_InvalidPayload(t *testing.T)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestConsignmentRouter_HandleGetConsignmentGraph(t *testing.T) {
+	consignmentID := uuid.New()
+	nodeID := uuid.New()
+	newRequest := func(format string, ctx func(context.Context) context.Context) *http.Request {
+		req, _ := http.NewRequest("GET", "/api/v1/consignments/"+consignmentID.String()+"/graph?format="+format, nil)
+		req.SetPathValue("id", consignmentID.String())
+		if ctx != nil {
+			req = req.WithContext(ctx(req.Context()))
+		}
+		return req
+	}
+	asTrader := func(traderID string) func(context.Context) context.Context {
+		return func(ctx context.Context) context.Context { return withAuthContext(ctx, traderID) }
+	}
+	newRouter := func(t *testing.T) *ConsignmentRouter {
+		db, sqlMock := setupRouterTestDB(t)
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(consignmentID, "trader1", "IN_PROGRESS"))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\"").WillReturnRows(sqlmock.NewRows([]string{"id", "consignment_id", "state", "depends_on"}).AddRow(nodeID, consignmentID, "READY", []byte(`[]`)))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_node_templates\"").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		return NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleGetConsignmentGraph(w, newRequest("", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleGetConsignmentGraph(w, newRequest("svg", asTrader("trader1")))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).HandleGetConsignmentGraph(w, newRequest("", asTrader("trader1")))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var graph model.WorkflowGraph
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+		assert.Equal(t, consignmentID, graph.ConsignmentID)
+		assert.Len(t, graph.Nodes, 1)
+	})
+
+	t.Run("DOT", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).HandleGetConsignmentGraph(w, newRequest("dot", asTrader("trader1")))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/vnd.graphviz; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"`+nodeID.String()+`" [label=`)
+	})
+
+	t.Run("Not Owned", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).HandleGetConsignmentGraph(w, newRequest("mermaid", asTrader("trader2")))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(t).HandleAdminGetConsignmentGraph(w, newRequest("mermaid", func(ctx context.Context) context.Context { return withAdminAuthContext(ctx, "admin") }))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Body.String(), "flowchart LR\n"))
+	})
+
+	t.Run("Admin Forbidden", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleAdminGetConsignmentGraph(w, newRequest("", asTrader("trader1")))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestConsignmentRouter_HandleCancelConsignment(t *testing.T) {
+	consignmentID := uuid.New()
+	newRequest := func(body string, traderID string) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/v1/consignments/"+consignmentID.String()+"/cancel", bytes.NewBufferString(body))
+		req.SetPathValue("id", consignmentID.String())
+		if traderID != "" {
+			req = req.WithContext(withAuthContext(req.Context(), traderID))
+		}
+		return req
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleCancelConsignment(w, newRequest(`{"reason":"created by mistake"}`, ""))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Missing Reason", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleCancelConsignment(w, newRequest(`{}`, "trader1"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Not Owned", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(consignmentID, "trader2", "IN_PROGRESS"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleCancelConsignment(w, newRequest(`{"reason":"created by mistake"}`, "trader1"))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Already Finished", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(consignmentID, "trader1", "FINISHED"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleCancelConsignment(w, newRequest(`{"reason":"created by mistake"}`, "trader1"))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestConsignmentRouter_HandleAmendConsignment(t *testing.T) {
+	consignmentID := uuid.New()
+	newRequest := func(body string, traderID string) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/v1/consignments/"+consignmentID.String()+"/amendments", bytes.NewBufferString(body))
+		req.SetPathValue("id", consignmentID.String())
+		if traderID != "" {
+			req = req.WithContext(withAuthContext(req.Context(), traderID))
+		}
+		return req
+	}
+	addItem := `{"addItems":[{"hsCodeId":"` + uuid.New().String() + `"}]}`
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleAmendConsignment(w, newRequest(addItem, ""))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Nothing To Amend", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleAmendConsignment(w, newRequest(`{}`, "trader1"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Not Owned", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(consignmentID, "trader2", "IN_PROGRESS"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleAmendConsignment(w, newRequest(addItem, "trader1"))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Already Finished", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(consignmentID, "trader1", "FINISHED"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleAmendConsignment(w, newRequest(addItem, "trader1"))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Admin History Forbidden For Non Admin", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		req := newRequest("", "trader1")
+		w := httptest.NewRecorder()
+		r.HandleAdminGetConsignmentAmendments(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id"}).AddRow(consignmentID, "trader1"))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignment_amendments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "consignment_id", "trader_id", "finished"}).AddRow(uuid.New(), consignmentID, "trader1", false))
+
+		w := httptest.NewRecorder()
+		r.HandleGetConsignmentAmendments(w, newRequest("", "trader1"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var amendments []model.ConsignmentAmendment
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &amendments))
+		assert.Len(t, amendments, 1)
+	})
+}
+
+func TestConsignmentRouter_HandleGetConsignmentEvents(t *testing.T) {
+	consignmentID := uuid.New()
+	newRequest := func(withAuth func(context.Context, string) context.Context, traderID string) *http.Request {
+		req, _ := http.NewRequest("GET", "/api/v1/consignments/"+consignmentID.String()+"/events", nil)
+		req.SetPathValue("id", consignmentID.String())
+		return req.WithContext(withAuth(req.Context(), traderID))
+	}
+
+	t.Run("Not Found For Other Trader", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id"}).AddRow(consignmentID, "trader2"))
+
+		w := httptest.NewRecorder()
+		r.HandleGetConsignmentEvents(w, newRequest(withAuthContext, "trader1"))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Admin Forbidden For Non Admin", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleAdminGetConsignmentEvents(w, newRequest(withAuthContext, "trader1"))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Timeline", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		nodeID := uuid.New()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id"}).AddRow(consignmentID, "trader2"))
+		sqlMock.ExpectQuery(`(?i)SELECT \* FROM "workflow_events" WHERE consignment_id = \$1 ORDER BY occurred_at ASC, sequence ASC`).
+			WithArgs(consignmentID).
+			WillReturnRows(sqlmock.NewRows([]string{"sequence", "consignment_id", "workflow_node_id", "type", "from_state", "to_state", "actor_type", "actor_id"}).
+				AddRow(1, consignmentID, nodeID, "NODE_CREATED", nil, "LOCKED", "TRADER", "trader2").
+				AddRow(2, consignmentID, nodeID, "NODE_STATE_CHANGED", "LOCKED", "READY", "SYSTEM", ""))
+
+		w := httptest.NewRecorder()
+		r.HandleAdminGetConsignmentEvents(w, newRequest(withAdminAuthContext, "admin1"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var events []model.WorkflowEvent
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+		assert.Len(t, events, 2)
+		assert.Equal(t, model.WorkflowEventNodeStateChanged, events[1].Type)
+		assert.Equal(t, auth.Actor{Type: auth.ActorTypeTrader, ID: "trader2"}, events[0].Actor)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestConsignmentRouter_HandleSearchConsignments(t *testing.T) {
+	newRequest := func(query string, withAuth func(context.Context, string) context.Context, userID string) *http.Request {
+		req, _ := http.NewRequest("GET", "/api/v1/consignments/search?"+query, nil)
+		return req.WithContext(withAuth(req.Context(), userID))
+	}
+
+	t.Run("Forbidden For Traders", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleSearchConsignments(w, newRequest("", withAuthContext, "trader1"))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Invalid Filters", func(t *testing.T) {
+		r := NewConsignmentRouter(service.NewConsignmentService(nil, nil, nil), nil)
+		for _, query := range []string{"createdFrom=yesterday", "nodeState=PENDING", "order=up", "sort=traderId", "context.invoice..number=1"} {
+			w := httptest.NewRecorder()
+			r.HandleSearchConsignments(w, newRequest(query, withAgencyAuthContext, "officer1"))
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+		consignmentID := uuid.New()
+		createdFrom := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		createdTo := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+		sqlMock.ExpectQuery(`(?i)SELECT count\(\*\) FROM "consignments" WHERE created_at >= \$1 AND created_at < \$2 AND global_context @> \$3::jsonb`).
+			WithArgs(createdFrom, createdTo, `{"invoiceNumber":"INV-7"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		sqlMock.ExpectQuery(`(?i)SELECT \* FROM "consignments" .* ORDER BY created_at DESC, id DESC`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "trader_id", "state", "created_at", "updated_at", "items"}).
+				AddRow(consignmentID, "IMPORT", "trader2", "IN_PROGRESS", createdFrom, createdFrom, []byte(`[]`)))
+		sqlMock.ExpectQuery(`(?i)SELECT consignment_id, count\(\*\) as total`).
+			WillReturnRows(sqlmock.NewRows([]string{"consignment_id", "total", "completed", "skipped"}))
+
+		w := httptest.NewRecorder()
+		r.HandleSearchConsignments(w, newRequest("createdFrom=2026-10-01&createdTo=2026-10-31&context.invoiceNumber=INV-7", withAgencyAuthContext, "officer1"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result model.ConsignmentListResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(1), *result.TotalCount)
+		}
+		assert.Equal(t, "trader2", result.Items[0].TraderID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
 func TestPreConsignmentRouter_HandleGetTraderPreConsignments_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	svc := service.NewPreConsignmentService(db, nil, nil)
@@ -444,3 +806,740 @@ func TestPreConsignmentRouter_HandleCreatePreConsignment_ServiceError(t *testing
 	r.HandleCreatePreConsignment(w, req)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func withAdminAuthContext(ctx context.Context, traderID string) context.Context {
+	authCtx := &auth.AuthContext{
+		TraderContext: &auth.TraderContext{
+			TraderID:      traderID,
+			TraderContext: json.RawMessage(`{"role": "admin"}`),
+		},
+	}
+	return context.WithValue(ctx, auth.AuthContextKey, authCtx)
+}
+
+func withAgencyAuthContext(ctx context.Context, traderID string) context.Context {
+	authCtx := &auth.AuthContext{
+		TraderContext: &auth.TraderContext{
+			TraderID:      traderID,
+			TraderContext: json.RawMessage(`{"role": "agency"}`),
+		},
+	}
+	return context.WithValue(ctx, auth.AuthContextKey, authCtx)
+}
+
+func TestPreConsignmentRouter_HandleRenewPreConsignment(t *testing.T) {
+	newRequest := func(id uuid.UUID) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/v1/pre-consignments/"+id.String()+"/renew", nil)
+		req.SetPathValue("preConsignmentId", id.String())
+		return req.WithContext(withAuthContext(req.Context(), "trader1"))
+	}
+
+	t.Run("Not Found For Other Trader", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+
+		id := uuid.New()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(id, "trader2", "COMPLETED"))
+
+		w := httptest.NewRecorder()
+		r.HandleRenewPreConsignment(w, newRequest(id))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("In Progress Is Not Renewable", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+
+		id := uuid.New()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(id, "trader1", "IN_PROGRESS"))
+
+		w := httptest.NewRecorder()
+		r.HandleRenewPreConsignment(w, newRequest(id))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestPreConsignmentRouter_HandleRevokePreConsignment(t *testing.T) {
+	id := uuid.New()
+	newRequest := func(body string, withAuth func(context.Context) context.Context) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/v1/pre-consignments/"+id.String()+"/revoke", bytes.NewBufferString(body))
+		req.SetPathValue("preConsignmentId", id.String())
+		return req.WithContext(withAuth(req.Context()))
+	}
+	asTrader := func(ctx context.Context) context.Context { return withAuthContext(ctx, "trader1") }
+	asAgency := func(ctx context.Context) context.Context { return withAgencyAuthContext(ctx, "officer1") }
+
+	t.Run("Forbidden For Traders", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+
+		w := httptest.NewRecorder()
+		r.HandleRevokePreConsignment(w, newRequest(`{"reason":"licence suspended"}`, asTrader))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Reason Required", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+
+		w := httptest.NewRecorder()
+		r.HandleRevokePreConsignment(w, newRequest(`{"reason":"  "}`, asAgency))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("In Progress Is Not Revocable", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\" .* FOR UPDATE").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "state"}).AddRow(id, "IN_PROGRESS"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleRevokePreConsignment(w, newRequest(`{"reason":"licence suspended"}`, asAgency))
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestPreConsignmentRouter_HandleGetPreConsignmentEvents(t *testing.T) {
+	id := uuid.New()
+	newRequest := func() *http.Request {
+		req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/"+id.String()+"/events", nil)
+		req.SetPathValue("preConsignmentId", id.String())
+		return req.WithContext(withAuthContext(req.Context(), "trader1"))
+	}
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id"}))
+
+		w := httptest.NewRecorder()
+		r.HandleGetPreConsignmentEvents(w, newRequest())
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Timeline", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id"}).AddRow(id, "trader1"))
+		sqlMock.ExpectQuery(`(?i)SELECT \* FROM "workflow_events" WHERE pre_consignment_id = \$1`).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"sequence", "pre_consignment_id", "type", "action", "actor_type", "actor_id"}).
+				AddRow(7, id, "TASK_TRANSITION", "OGA_VERIFICATION_APPROVED", "AGENCY", "officer1"))
+
+		w := httptest.NewRecorder()
+		r.HandleGetPreConsignmentEvents(w, newRequest())
+		assert.Equal(t, http.StatusOK, w.Code)
+		var events []model.WorkflowEvent
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+		assert.Len(t, events, 1)
+		assert.Equal(t, auth.ActorTypeAgency, events[0].Actor.Type)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestFailedUpdateRouter_HandleGetFailedUpdates(t *testing.T) {
+	t.Run("Forbidden For Non Admin", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewFailedUpdateRouter(service.NewFailedWorkflowUpdateService(db, service.DefaultRetryPolicy()))
+
+		req, _ := http.NewRequest("GET", "/api/v1/admin/failed-workflow-updates", nil)
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleGetFailedUpdates(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Invalid Status", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewFailedUpdateRouter(service.NewFailedWorkflowUpdateService(db, service.DefaultRetryPolicy()))
+
+		req, _ := http.NewRequest("GET", "/api/v1/admin/failed-workflow-updates?status=UNKNOWN", nil)
+		req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+		w := httptest.NewRecorder()
+		r.HandleGetFailedUpdates(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Lists Dead Lettered By Default", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewFailedUpdateRouter(service.NewFailedWorkflowUpdateService(db, service.DefaultRetryPolicy()))
+
+		sqlMock.ExpectQuery(`(?i)SELECT count\(\*\) FROM "failed_workflow_updates" WHERE status = \$1`).
+			WithArgs(model.FailedWorkflowUpdateStatusDeadLettered).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		sqlMock.ExpectQuery(`(?i)SELECT \* FROM "failed_workflow_updates" WHERE status = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(uuid.New(), "DEAD_LETTERED"))
+
+		req, _ := http.NewRequest("GET", "/api/v1/admin/failed-workflow-updates", nil)
+		req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+		w := httptest.NewRecorder()
+		r.HandleGetFailedUpdates(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result model.FailedWorkflowUpdateListResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, int64(1), result.TotalCount)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestFailedUpdateRouter_HandleReplayFailedUpdate(t *testing.T) {
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewFailedUpdateRouter(service.NewFailedWorkflowUpdateService(db, service.DefaultRetryPolicy()))
+		id := uuid.New()
+
+		sqlMock.ExpectQuery(`(?i)SELECT \* FROM "failed_workflow_updates"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		req, _ := http.NewRequest("POST", "/api/v1/admin/failed-workflow-updates/"+id.String()+"/replay", nil)
+		req.SetPathValue("id", id.String())
+		req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+		w := httptest.NewRecorder()
+		r.HandleReplayFailedUpdate(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Conflict When Not Dead Lettered", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewFailedUpdateRouter(service.NewFailedWorkflowUpdateService(db, service.DefaultRetryPolicy()))
+		id := uuid.New()
+
+		sqlMock.ExpectQuery(`(?i)SELECT \* FROM "failed_workflow_updates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(id, "RESOLVED"))
+
+		req, _ := http.NewRequest("POST", "/api/v1/admin/failed-workflow-updates/"+id.String()+"/replay", nil)
+		req.SetPathValue("id", id.String())
+		req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+		w := httptest.NewRecorder()
+		r.HandleReplayFailedUpdate(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+type stubReconciler struct {
+	report *model.ReconciliationReport
+}
+
+func (s *stubReconciler) Run(context.Context) (*model.ReconciliationReport, error) {
+	s.report = &model.ReconciliationReport{NodesChecked: 3}
+	return s.report, nil
+}
+
+func (s *stubReconciler) LastReport() *model.ReconciliationReport {
+	return s.report
+}
+
+func TestReconciliationRouter(t *testing.T) {
+	r := NewReconciliationRouter(&stubReconciler{})
+
+	req, _ := http.NewRequest("GET", "/api/v1/admin/reconciliations/latest", nil)
+	req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+	w := httptest.NewRecorder()
+	r.HandleGetLatestReconciliation(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/v1/admin/reconciliations", nil)
+	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+	w = httptest.NewRecorder()
+	r.HandleRunReconciliation(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/v1/admin/reconciliations", nil)
+	req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+	w = httptest.NewRecorder()
+	r.HandleRunReconciliation(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report model.ReconciliationReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 3, report.NodesChecked)
+}
+
+func TestWorkflowNodeRouter_HandleReopenWorkflowNode(t *testing.T) {
+	nodeID := uuid.New()
+	newRequest := func(path string) *http.Request {
+		req, _ := http.NewRequest("POST", path, nil)
+		req.SetPathValue("id", nodeID.String())
+		return req
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		r := NewWorkflowNodeRouter(service.NewWorkflowNodeReopenService(nil, nil, nil), nil)
+		w := httptest.NewRecorder()
+		r.HandleReopenWorkflowNode(w, newRequest("/api/v1/workflow-nodes/"+nodeID.String()+"/reopen"))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		r := NewWorkflowNodeRouter(service.NewWorkflowNodeReopenService(nil, nil, nil), nil)
+		req, _ := http.NewRequest("POST", "/api/v1/workflow-nodes/invalid/reopen", nil)
+		req.SetPathValue("id", "invalid")
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleReopenWorkflowNode(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Node Not Found", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewWorkflowNodeRouter(service.NewWorkflowNodeReopenService(db, nil, nil), nil)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\"").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		req := newRequest("/api/v1/workflow-nodes/" + nodeID.String() + "/reopen")
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleReopenWorkflowNode(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Admin Route Forbidden For Non Admin", func(t *testing.T) {
+		r := NewWorkflowNodeRouter(service.NewWorkflowNodeReopenService(nil, nil, nil), nil)
+		req := newRequest("/api/v1/admin/workflow-nodes/" + nodeID.String() + "/reopen")
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleAdminReopenWorkflowNode(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Admin Reopens Node Of Any Trader", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		sqlMock.MatchExpectationsInOrder(false)
+		consignmentID := uuid.New()
+		templateID := uuid.New()
+		policy := model.ReopenPolicyAdmin
+		templates := &MockTemplateProvider{}
+		templates.On("GetWorkflowNodeTemplateByID", mock.Anything, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &policy}, nil)
+		r := NewWorkflowNodeRouter(service.NewWorkflowNodeReopenService(db, templates, service.NewWorkflowNodeService(db)), nil)
+
+		nodeRow := func() *sqlmock.Rows {
+			return sqlmock.NewRows([]string{"id", "consignment_id", "workflow_node_template_id", "state", "attempt"}).
+				AddRow(nodeID, consignmentID, templateID, "FAILED", 1)
+		}
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\" .* FOR UPDATE").WillReturnRows(nodeRow())
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\"").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state"}).AddRow(consignmentID, "trader2", "IN_PROGRESS"))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\" WHERE id = \\$1 ORDER BY").WillReturnRows(nodeRow())
+		sqlMock.ExpectExec("(?i)UPDATE \"workflow_nodes\"").WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectQuery("(?i)INSERT INTO \"workflow_events\"").
+			WithArgs(consignmentID, nil, nodeID, model.WorkflowEventNodeStateChanged, "FAILED", "READY", nil, nil, sqlmock.AnyArg(), auth.ActorTypeAdmin, "admin1", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
+		sqlMock.ExpectCommit()
+
+		req := newRequest("/api/v1/admin/workflow-nodes/" + nodeID.String() + "/reopen")
+		req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+		w := httptest.NewRecorder()
+		r.HandleAdminReopenWorkflowNode(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp model.ReopenWorkflowNodeResponseDTO
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, model.WorkflowNodeStateReady, resp.State)
+		assert.Equal(t, 2, resp.Attempt)
+	})
+}
+
+func TestWorkflowNodeRouter_HandleExplainWorkflowNodeUnlock(t *testing.T) {
+	nodeID := uuid.New()
+	newRequest := func(path string) *http.Request {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.SetPathValue("id", nodeID.String())
+		return req
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		r := NewWorkflowNodeRouter(nil, service.NewWorkflowNodeExplainService(nil))
+		w := httptest.NewRecorder()
+		r.HandleExplainWorkflowNodeUnlock(w, newRequest("/api/v1/workflow-nodes/"+nodeID.String()+"/unlock-explanation"))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		r := NewWorkflowNodeRouter(nil, service.NewWorkflowNodeExplainService(nil))
+		req, _ := http.NewRequest("GET", "/api/v1/workflow-nodes/invalid/unlock-explanation", nil)
+		req.SetPathValue("id", "invalid")
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleExplainWorkflowNodeUnlock(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Node Not Found", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewWorkflowNodeRouter(nil, service.NewWorkflowNodeExplainService(db))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\"").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		req := newRequest("/api/v1/workflow-nodes/" + nodeID.String() + "/unlock-explanation")
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleExplainWorkflowNodeUnlock(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Admin Route Forbidden For Non Admin", func(t *testing.T) {
+		r := NewWorkflowNodeRouter(nil, service.NewWorkflowNodeExplainService(nil))
+		req := newRequest("/api/v1/admin/workflow-nodes/" + nodeID.String() + "/unlock-explanation")
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleAdminExplainWorkflowNodeUnlock(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Admin Explains Node Of Any Trader", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewWorkflowNodeRouter(nil, service.NewWorkflowNodeExplainService(db))
+		consignmentID, depID, templateID := uuid.New(), uuid.New(), uuid.New()
+		columns := []string{"id", "consignment_id", "workflow_node_template_id", "state", "depends_on"}
+		templateRows := func() *sqlmock.Rows {
+			return sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Review")
+		}
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\" WHERE id = \\$1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(nodeID, consignmentID, templateID, "LOCKED", `["`+depID.String()+`"]`))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_node_templates\"").WillReturnRows(templateRows())
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"consignments\" WHERE id = \\$1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id"}).AddRow(consignmentID, "trader2"))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_nodes\" WHERE consignment_id = \\$1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(depID, consignmentID, templateID, "IN_PROGRESS", "[]"))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_node_templates\"").WillReturnRows(templateRows())
+
+		req := newRequest("/api/v1/admin/workflow-nodes/" + nodeID.String() + "/unlock-explanation")
+		req = req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+		w := httptest.NewRecorder()
+		r.HandleAdminExplainWorkflowNodeUnlock(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp model.WorkflowNodeUnlockExplanationDTO
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.UsesDependsOn)
+		assert.False(t, resp.Satisfied)
+		if assert.Len(t, resp.Explanation.Children, 1) {
+			assert.Equal(t, model.WorkflowNodeStateInProgress, *resp.Explanation.Children[0].ActualState)
+		}
+	})
+}
+
+func TestTemplateRouter_HandleImportBPMN(t *testing.T) {
+	const document = `<definitions xmlns="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:nsw="urn:nsw">
+  <process id="export" name="Export">
+    <startEvent id="start"/>
+    <userTask id="declaration"><extensionElements><nsw:config>{"formId": "f"}</nsw:config></extensionElements></userTask>
+    <endEvent id="end"/>
+    <sequenceFlow id="f1" sourceRef="start" targetRef="declaration"/>
+    <sequenceFlow id="f2" sourceRef="declaration" targetRef="end"/>
+  </process>
+</definitions>`
+
+	newRequest := func(url, body string, ctx func(context.Context, string) context.Context) *http.Request {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		return req.WithContext(ctx(req.Context(), "admin1"))
+	}
+
+	t.Run("Forbidden For Non Admin", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+
+		w := httptest.NewRecorder()
+		r.HandleImportBPMN(w, newRequest("/api/v1/admin/workflow-templates/import/bpmn", document, withAuthContext))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Malformed Document", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+
+		w := httptest.NewRecorder()
+		r.HandleImportBPMN(w, newRequest("/api/v1/admin/workflow-templates/import/bpmn", "<definitions>", withAdminAuthContext))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unsupported Process", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+		unsupported := bytes.Replace([]byte(document), []byte("userTask"), []byte("scriptTask"), -1)
+
+		w := httptest.NewRecorder()
+		r.HandleImportBPMN(w, newRequest("/api/v1/admin/workflow-templates/import/bpmn", string(unsupported), withAdminAuthContext))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "scriptTask elements are not supported")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+
+		w := httptest.NewRecorder()
+		r.HandleImportBPMN(w, newRequest("/api/v1/admin/workflow-templates/import/bpmn?dryRun=true", document, withAdminAuthContext))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Creates Templates", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "workflow_template_families"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_node_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		w := httptest.NewRecorder()
+		r.HandleImportBPMN(w, newRequest("/api/v1/admin/workflow-templates/import/bpmn?version=2.1", document, withAdminAuthContext))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var result struct {
+			WorkflowTemplate model.WorkflowTemplate `json:"workflowTemplate"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "2.1", result.WorkflowTemplate.Version)
+		assert.NotNil(t, result.WorkflowTemplate.EndNodeTemplateID)
+		assert.Equal(t, model.WorkflowTemplateStatusDraft, result.WorkflowTemplate.Status)
+		assert.Equal(t, 1, result.WorkflowTemplate.VersionNumber)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown Family", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_template_families"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleImportBPMN(w, newRequest("/api/v1/admin/workflow-templates/import/bpmn?familyId="+uuid.NewString(), document, withAdminAuthContext))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateRouter_HandlePublishWorkflowTemplate(t *testing.T) {
+	newRequest := func(id string) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/v1/admin/workflow-templates/"+id+"/publish", nil)
+		req.SetPathValue("id", id)
+		return req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+	}
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		db, _ := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+
+		w := httptest.NewRecorder()
+		r.HandlePublishWorkflowTemplate(w, newRequest("not-a-uuid"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Already Published", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+		id := uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(id, "PUBLISHED"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandlePublishWorkflowTemplate(w, newRequest(id.String()))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Publishes", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		r := NewTemplateRouter(service.NewTemplateService(db))
+		id := uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(id, "DRAFT"))
+		sqlMock.ExpectExec(`UPDATE "workflow_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		w := httptest.NewRecorder()
+		r.HandlePublishWorkflowTemplate(w, newRequest(id.String()))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result model.WorkflowTemplate
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, model.WorkflowTemplateStatusPublished, result.Status)
+	})
+}
+
+func TestTemplateMigrationRouter_HandleMigrateConsignments(t *testing.T) {
+	newRouter := func(t *testing.T) (*TemplateMigrationRouter, sqlmock.Sqlmock) {
+		db, sqlMock := setupRouterTestDB(t)
+		templateService := service.NewTemplateService(db)
+		return NewTemplateMigrationRouter(service.NewWorkflowTemplateMigrationService(db, templateService, service.NewWorkflowNodeService(db))), sqlMock
+	}
+	newRequest := func(body string, ctx func(context.Context, string) context.Context) *http.Request {
+		req, _ := http.NewRequest("POST", "/api/v1/admin/workflow-template-migrations", bytes.NewBufferString(body))
+		return req.WithContext(ctx(req.Context(), "admin1"))
+	}
+
+	t.Run("Forbidden For Non Admin", func(t *testing.T) {
+		r, _ := newRouter(t)
+
+		w := httptest.NewRecorder()
+		r.HandleMigrateConsignments(w, newRequest(`{}`, withAuthContext))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Missing Templates", func(t *testing.T) {
+		r, _ := newRouter(t)
+
+		w := httptest.NewRecorder()
+		r.HandleMigrateConsignments(w, newRequest(`{"dryRun": true}`, withAdminAuthContext))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown Template", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		w := httptest.NewRecorder()
+		body := `{"fromTemplateId": "` + uuid.NewString() + `", "toTemplateId": "` + uuid.NewString() + `"}`
+		r.HandleMigrateConsignments(w, newRequest(body, withAdminAuthContext))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Different Families", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+		fromID, toID := uuid.New(), uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "family_id", "version_number", "status"}).AddRow(fromID, uuid.New(), 1, "PUBLISHED"))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "family_id", "version_number", "status"}).AddRow(toID, uuid.New(), 2, "PUBLISHED"))
+
+		w := httptest.NewRecorder()
+		body := `{"fromTemplateId": "` + fromID.String() + `", "toTemplateId": "` + toID.String() + `"}`
+		r.HandleMigrateConsignments(w, newRequest(body, withAdminAuthContext))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "different families")
+	})
+}
+
+func TestTemplateAdminRouter(t *testing.T) {
+	newRouter := func(t *testing.T) (*TemplateAdminRouter, sqlmock.Sqlmock) {
+		db, sqlMock := setupRouterTestDB(t)
+		return NewTemplateAdminRouter(service.NewTemplateAdminService(db, service.NewTemplateService(db), plugin.NewTaskFactory(nil, nil))), sqlMock
+	}
+	newRequest := func(method, path, id, body string) *http.Request {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if id != "" {
+			req.SetPathValue("id", id)
+		}
+		return req.WithContext(withAdminAuthContext(req.Context(), "admin1"))
+	}
+
+	t.Run("Forbidden", func(t *testing.T) {
+		r, _ := newRouter(t)
+		req, _ := http.NewRequest("GET", "/api/v1/admin/workflow-node-templates", nil)
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+
+		w := httptest.NewRecorder()
+		r.HandleListWorkflowNodeTemplates(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Invalid Node Template Config", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+
+		w := httptest.NewRecorder()
+		r.HandleCreateWorkflowNodeTemplate(w, newRequest("POST", "/api/v1/admin/workflow-node-templates", "",
+			`{"name":"Wait","type":"TIMER","config":{"duration":"soon"}}`))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid timer duration")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Creates Node Template", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "workflow_node_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		w := httptest.NewRecorder()
+		r.HandleCreateWorkflowNodeTemplate(w, newRequest("POST", "/api/v1/admin/workflow-node-templates", "",
+			`{"name":"Wait","type":"TIMER","config":{"duration":"1h"}}`))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var result model.WorkflowNodeTemplate
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.NotEqual(t, uuid.Nil, result.ID)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		r, _ := newRouter(t)
+
+		w := httptest.NewRecorder()
+		r.HandleGetWorkflowTemplate(w, newRequest("GET", "/api/v1/admin/workflow-templates/nope", "nope", ""))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Update Published Template", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+		id := uuid.New()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(id, "PUBLISHED"))
+		sqlMock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		r.HandleUpdateWorkflowTemplate(w, newRequest("PUT", "/api/v1/admin/workflow-templates/"+id.String(), id.String(),
+			`{"name":"Export","version":"1.1","nodes":[]}`))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Delete Unknown Map", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+		id := uuid.New()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`DELETE FROM "workflow_template_maps"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		sqlMock.ExpectCommit()
+
+		w := httptest.NewRecorder()
+		r.HandleDeleteWorkflowTemplateMap(w, newRequest("DELETE", "/api/v1/admin/workflow-template-maps/"+id.String(), id.String(), ""))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Delete Pre-Consignment Template", func(t *testing.T) {
+		r, sqlMock := newRouter(t)
+		id := uuid.New()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "pre_consignments"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "pre_consignment_templates" WHERE depends_on @> \$1::jsonb`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_templates" WHERE required_pre_consignments @> \$1::jsonb`).
+			WithArgs(fmt.Sprintf(`[{"preConsignmentTemplateId":%q}]`, id)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		sqlMock.ExpectExec(`DELETE FROM "pre_consignment_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		w := httptest.NewRecorder()
+		r.HandleDeletePreConsignmentTemplate(w, newRequest("DELETE", "/api/v1/admin/pre-consignment-templates/"+id.String(), id.String(), ""))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,554 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+// TemplateAdminRouter handles HTTP routing for the administrative endpoints that manage workflow node
+// templates, workflow template versions, HS code mappings and pre-consignment templates.
+type TemplateAdminRouter struct {
+	as *service.TemplateAdminService
+}
+
+// NewTemplateAdminRouter creates a new TemplateAdminRouter.
+func NewTemplateAdminRouter(as *service.TemplateAdminService) *TemplateAdminRouter {
+	return &TemplateAdminRouter{
+		as: as,
+	}
+}
+
+// HandleListWorkflowNodeTemplates handles GET /api/v1/admin/workflow-node-templates
+// Query params: offset (optional), limit (optional)
+// Response: WorkflowNodeTemplateListResult
+func (r *TemplateAdminRouter) HandleListWorkflowNodeTemplates(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.as.ListWorkflowNodeTemplates(req.Context(), offset, limit)
+	if err != nil {
+		http.Error(w, "failed to retrieve workflow node templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleGetWorkflowNodeTemplate handles GET /api/v1/admin/workflow-node-templates/{id}
+// Response: WorkflowNodeTemplate
+func (r *TemplateAdminRouter) HandleGetWorkflowNodeTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow node template")
+	if !ok {
+		return
+	}
+
+	nodeTemplate, err := r.as.GetWorkflowNodeTemplate(req.Context(), id)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to retrieve workflow node template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nodeTemplate)
+}
+
+// HandleCreateWorkflowNodeTemplate handles POST /api/v1/admin/workflow-node-templates
+// Request body: WorkflowNodeTemplateDTO
+// Response: the created WorkflowNodeTemplate; 422 if it is invalid or its config does not build a task
+func (r *TemplateAdminRouter) HandleCreateWorkflowNodeTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	var dto model.WorkflowNodeTemplateDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nodeTemplate, err := r.as.CreateWorkflowNodeTemplate(req.Context(), &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to create workflow node template")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, nodeTemplate)
+}
+
+// HandleUpdateWorkflowNodeTemplate handles PUT /api/v1/admin/workflow-node-templates/{id}
+// Request body: WorkflowNodeTemplateDTO
+// Response: the updated WorkflowNodeTemplate; 409 if a published workflow template uses it and 422 if it
+// is invalid or would make a draft workflow template using it invalid
+func (r *TemplateAdminRouter) HandleUpdateWorkflowNodeTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow node template")
+	if !ok {
+		return
+	}
+
+	var dto model.WorkflowNodeTemplateDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nodeTemplate, err := r.as.UpdateWorkflowNodeTemplate(req.Context(), id, &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to update workflow node template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nodeTemplate)
+}
+
+// HandleDeleteWorkflowNodeTemplate handles DELETE /api/v1/admin/workflow-node-templates/{id}
+// Response: 204; 409 if a workflow template uses it
+func (r *TemplateAdminRouter) HandleDeleteWorkflowNodeTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow node template")
+	if !ok {
+		return
+	}
+
+	if err := r.as.DeleteWorkflowNodeTemplate(req.Context(), id); err != nil {
+		writeTemplateAdminError(w, err, "failed to delete workflow node template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListWorkflowTemplates handles GET /api/v1/admin/workflow-templates
+// Query params: familyId (optional), status (optional), offset (optional), limit (optional)
+// Response: WorkflowTemplateListResult
+func (r *TemplateAdminRouter) HandleListWorkflowTemplates(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := model.WorkflowTemplateFilter{
+		Offset: offset,
+		Limit:  limit,
+	}
+
+	if familyIDStr := req.URL.Query().Get("familyId"); familyIDStr != "" {
+		familyID, err := uuid.Parse(familyIDStr)
+		if err != nil {
+			http.Error(w, "invalid familyId: "+familyIDStr, http.StatusBadRequest)
+			return
+		}
+		filter.FamilyID = &familyID
+	}
+
+	if statusStr := req.URL.Query().Get("status"); statusStr != "" {
+		status := model.WorkflowTemplateStatus(statusStr)
+		switch status {
+		case model.WorkflowTemplateStatusDraft, model.WorkflowTemplateStatusPublished:
+		default:
+			http.Error(w, "invalid status: "+statusStr, http.StatusBadRequest)
+			return
+		}
+		filter.Status = &status
+	}
+
+	result, err := r.as.ListWorkflowTemplates(req.Context(), filter)
+	if err != nil {
+		http.Error(w, "failed to retrieve workflow templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleGetWorkflowTemplate handles GET /api/v1/admin/workflow-templates/{id}
+// Response: WorkflowTemplate
+func (r *TemplateAdminRouter) HandleGetWorkflowTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow template")
+	if !ok {
+		return
+	}
+
+	workflowTemplate, err := r.as.GetWorkflowTemplate(req.Context(), id)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to retrieve workflow template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflowTemplate)
+}
+
+// HandleCreateWorkflowTemplate handles POST /api/v1/admin/workflow-templates
+// Creates a DRAFT version of a new family, or of the family given as familyId.
+// Request body: WorkflowTemplateDTO
+// Response: the created WorkflowTemplate; 404 if the family does not exist and 422 if the workflow
+// graph or a node configuration is invalid
+func (r *TemplateAdminRouter) HandleCreateWorkflowTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	var dto model.WorkflowTemplateDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflowTemplate, err := r.as.CreateWorkflowTemplate(req.Context(), &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to create workflow template")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, workflowTemplate)
+}
+
+// HandleUpdateWorkflowTemplate handles PUT /api/v1/admin/workflow-templates/{id}
+// Request body: WorkflowTemplateDTO (familyId is ignored)
+// Response: the updated WorkflowTemplate; 409 if the version is published and 422 if the workflow graph
+// or a node configuration is invalid
+func (r *TemplateAdminRouter) HandleUpdateWorkflowTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow template")
+	if !ok {
+		return
+	}
+
+	var dto model.WorkflowTemplateDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflowTemplate, err := r.as.UpdateWorkflowTemplate(req.Context(), id, &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to update workflow template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflowTemplate)
+}
+
+// HandleDeleteWorkflowTemplate handles DELETE /api/v1/admin/workflow-templates/{id}
+// Response: 204; 409 if the version is published or a pre-consignment template uses it
+func (r *TemplateAdminRouter) HandleDeleteWorkflowTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow template")
+	if !ok {
+		return
+	}
+
+	if err := r.as.DeleteWorkflowTemplate(req.Context(), id); err != nil {
+		writeTemplateAdminError(w, err, "failed to delete workflow template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListWorkflowTemplateMaps handles GET /api/v1/admin/workflow-template-maps
+// Query params: hsCodeId (optional), offset (optional), limit (optional)
+// Response: WorkflowTemplateMapListResult
+func (r *TemplateAdminRouter) HandleListWorkflowTemplateMaps(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := model.WorkflowTemplateMapFilter{
+		Offset: offset,
+		Limit:  limit,
+	}
+
+	if hsCodeIDStr := req.URL.Query().Get("hsCodeId"); hsCodeIDStr != "" {
+		hsCodeID, err := uuid.Parse(hsCodeIDStr)
+		if err != nil {
+			http.Error(w, "invalid hsCodeId: "+hsCodeIDStr, http.StatusBadRequest)
+			return
+		}
+		filter.HSCodeID = &hsCodeID
+	}
+
+	result, err := r.as.ListWorkflowTemplateMaps(req.Context(), filter)
+	if err != nil {
+		http.Error(w, "failed to retrieve workflow template maps: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleGetWorkflowTemplateMap handles GET /api/v1/admin/workflow-template-maps/{id}
+// Response: WorkflowTemplateMap
+func (r *TemplateAdminRouter) HandleGetWorkflowTemplateMap(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow template map")
+	if !ok {
+		return
+	}
+
+	workflowTemplateMap, err := r.as.GetWorkflowTemplateMap(req.Context(), id)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to retrieve workflow template map")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflowTemplateMap)
+}
+
+// HandleCreateWorkflowTemplateMap handles POST /api/v1/admin/workflow-template-maps
+// Request body: WorkflowTemplateMapDTO
+// Response: the created WorkflowTemplateMap; 409 if the HS code and flow are already mapped and 422 if
+// the HS code or family does not exist
+func (r *TemplateAdminRouter) HandleCreateWorkflowTemplateMap(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	var dto model.WorkflowTemplateMapDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflowTemplateMap, err := r.as.CreateWorkflowTemplateMap(req.Context(), &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to create workflow template map")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, workflowTemplateMap)
+}
+
+// HandleUpdateWorkflowTemplateMap handles PUT /api/v1/admin/workflow-template-maps/{id}
+// Request body: WorkflowTemplateMapDTO
+// Response: the updated WorkflowTemplateMap; 409 if the HS code and flow are mapped elsewhere and 422 if
+// the HS code or family does not exist
+func (r *TemplateAdminRouter) HandleUpdateWorkflowTemplateMap(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow template map")
+	if !ok {
+		return
+	}
+
+	var dto model.WorkflowTemplateMapDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflowTemplateMap, err := r.as.UpdateWorkflowTemplateMap(req.Context(), id, &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to update workflow template map")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflowTemplateMap)
+}
+
+// HandleDeleteWorkflowTemplateMap handles DELETE /api/v1/admin/workflow-template-maps/{id}
+// Response: 204
+func (r *TemplateAdminRouter) HandleDeleteWorkflowTemplateMap(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "workflow template map")
+	if !ok {
+		return
+	}
+
+	if err := r.as.DeleteWorkflowTemplateMap(req.Context(), id); err != nil {
+		writeTemplateAdminError(w, err, "failed to delete workflow template map")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListPreConsignmentTemplates handles GET /api/v1/admin/pre-consignment-templates
+// Query params: offset (optional), limit (optional)
+// Response: PreConsignmentTemplateListResult
+func (r *TemplateAdminRouter) HandleListPreConsignmentTemplates(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.as.ListPreConsignmentTemplates(req.Context(), offset, limit)
+	if err != nil {
+		http.Error(w, "failed to retrieve pre-consignment templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleGetPreConsignmentTemplate handles GET /api/v1/admin/pre-consignment-templates/{id}
+// Response: PreConsignmentTemplate
+func (r *TemplateAdminRouter) HandleGetPreConsignmentTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "pre-consignment template")
+	if !ok {
+		return
+	}
+
+	preConsignmentTemplate, err := r.as.GetPreConsignmentTemplate(req.Context(), id)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to retrieve pre-consignment template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preConsignmentTemplate)
+}
+
+// HandleCreatePreConsignmentTemplate handles POST /api/v1/admin/pre-consignment-templates
+// Request body: PreConsignmentTemplateDTO
+// Response: the created PreConsignmentTemplate; 422 if its workflow template is not published or its
+// dependencies do not exist or form a cycle
+func (r *TemplateAdminRouter) HandleCreatePreConsignmentTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	var dto model.PreConsignmentTemplateDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	preConsignmentTemplate, err := r.as.CreatePreConsignmentTemplate(req.Context(), &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to create pre-consignment template")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, preConsignmentTemplate)
+}
+
+// HandleUpdatePreConsignmentTemplate handles PUT /api/v1/admin/pre-consignment-templates/{id}
+// Request body: PreConsignmentTemplateDTO
+// Response: the updated PreConsignmentTemplate; 422 if its workflow template is not published or its
+// dependencies do not exist or form a cycle
+func (r *TemplateAdminRouter) HandleUpdatePreConsignmentTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "pre-consignment template")
+	if !ok {
+		return
+	}
+
+	var dto model.PreConsignmentTemplateDTO
+	if err := json.NewDecoder(req.Body).Decode(&dto); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	preConsignmentTemplate, err := r.as.UpdatePreConsignmentTemplate(req.Context(), id, &dto)
+	if err != nil {
+		writeTemplateAdminError(w, err, "failed to update pre-consignment template")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preConsignmentTemplate)
+}
+
+// HandleDeletePreConsignmentTemplate handles DELETE /api/v1/admin/pre-consignment-templates/{id}
+// Response: 204; 409 if it has pre-consignments or other pre-consignment templates depend on it
+func (r *TemplateAdminRouter) HandleDeletePreConsignmentTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	id, ok := parseTemplateID(w, req, "pre-consignment template")
+	if !ok {
+		return
+	}
+
+	if err := r.as.DeletePreConsignmentTemplate(req.Context(), id); err != nil {
+		writeTemplateAdminError(w, err, "failed to delete pre-consignment template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTemplateID parses the id path value, writing a 400 response and returning false if it is not a UUID.
+func parseTemplateID(w http.ResponseWriter, req *http.Request, kind string) (uuid.UUID, bool) {
+	idStr := req.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid "+kind+" ID: "+idStr, http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeTemplateAdminError writes the response for an error returned by TemplateAdminService.
+func writeTemplateAdminError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrWorkflowNodeTemplateNotFound),
+		errors.Is(err, service.ErrWorkflowTemplateNotFound),
+		errors.Is(err, service.ErrWorkflowTemplateFamilyNotFound),
+		errors.Is(err, service.ErrWorkflowTemplateMapNotFound),
+		errors.Is(err, service.ErrPreConsignmentTemplateNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidWorkflowNodeTemplate),
+		errors.Is(err, service.ErrInvalidWorkflowTemplate),
+		errors.Is(err, service.ErrInvalidWorkflowTemplateMap),
+		errors.Is(err, service.ErrInvalidPreConsignmentTemplate):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrWorkflowTemplateNotDraft),
+		errors.Is(err, service.ErrWorkflowNodeTemplatePublished),
+		errors.Is(err, service.ErrTemplateInUse),
+		errors.Is(err, service.ErrWorkflowTemplateMapExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
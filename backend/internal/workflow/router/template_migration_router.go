@@ -0,0 +1,61 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
+// TemplateMigrationRouter handles HTTP routing for the administrative workflow template migration endpoints.
+type TemplateMigrationRouter struct {
+	ms *service.WorkflowTemplateMigrationService
+}
+
+// NewTemplateMigrationRouter creates a new TemplateMigrationRouter.
+func NewTemplateMigrationRouter(ms *service.WorkflowTemplateMigrationService) *TemplateMigrationRouter {
+	return &TemplateMigrationRouter{
+		ms: ms,
+	}
+}
+
+// HandleMigrateConsignments handles POST /api/v1/admin/workflow-template-migrations
+// Moves in-flight consignments from one published workflow template version to a later one.
+// Request body: TemplateMigrationRequest; set dryRun to report which nodes would be added,
+// removed, remapped, relocked or unlocked without changing anything
+// Response: TemplateMigrationReport; 404 if a template does not exist and 400 if the request
+// does not describe a move to a later published version of the same family
+func (r *TemplateMigrationRouter) HandleMigrateConsignments(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	var migrationReq model.TemplateMigrationRequest
+	if err := json.NewDecoder(req.Body).Decode(&migrationReq); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if migrationReq.FromTemplateID == uuid.Nil || migrationReq.ToTemplateID == uuid.Nil {
+		http.Error(w, "fromTemplateId and toTemplateId are required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := r.ms.MigrateConsignments(req.Context(), &migrationReq)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWorkflowTemplateNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidTemplateMigration):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "failed to migrate consignments: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/bpmn"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
+// maxBPMNDocumentSize is the largest BPMN document accepted by the import endpoint.
+const maxBPMNDocumentSize = 5 << 20
+
+// TemplateRouter handles HTTP routing for the administrative workflow template endpoints.
+type TemplateRouter struct {
+	ts *service.TemplateService
+}
+
+// NewTemplateRouter creates a new TemplateRouter.
+func NewTemplateRouter(ts *service.TemplateService) *TemplateRouter {
+	return &TemplateRouter{
+		ts: ts,
+	}
+}
+
+// HandleImportBPMN handles POST /api/v1/admin/workflow-templates/import/bpmn
+// Request body: BPMN 2.0 XML document
+// Query params: processId (optional, required when the document defines several processes),
+// version (optional, defaults to 1.0), familyId (optional, imports the process as the next version
+// of that family instead of starting a new one), dryRun (optional, converts without saving)
+// Response: bpmn.Result; 201 when a DRAFT template version was created, 200 for a dry run and
+// 422 when the process cannot be imported (see diagnostics)
+func (r *TemplateRouter) HandleImportBPMN(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	dryRun := false
+	if dryRunStr := req.URL.Query().Get("dryRun"); dryRunStr != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			http.Error(w, "invalid dryRun: "+dryRunStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var familyID uuid.UUID
+	if familyIDStr := req.URL.Query().Get("familyId"); familyIDStr != "" {
+		var err error
+		familyID, err = uuid.Parse(familyIDStr)
+		if err != nil {
+			http.Error(w, "invalid familyId: "+familyIDStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, req.Body, maxBPMNDocumentSize)
+	result, err := bpmn.Import(body, bpmn.Options{
+		ProcessID: req.URL.Query().Get("processId"),
+		Version:   req.URL.Query().Get("version"),
+		FamilyID:  familyID,
+	})
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "BPMN document is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid BPMN document: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if result.HasErrors() {
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if dryRun {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	if err := r.ts.CreateWorkflowTemplate(req.Context(), &result.WorkflowTemplate, result.NodeTemplates); err != nil {
+		if errors.Is(err, service.ErrWorkflowTemplateFamilyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to create workflow template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// HandlePublishWorkflowTemplate handles POST /api/v1/admin/workflow-templates/{id}/publish
+// Publishes a DRAFT workflow template version. New consignments mapped to its family use the
+// latest published version; in-flight consignments are moved with a template migration.
+// Response: the published model.WorkflowTemplate; 404 if it does not exist, 409 if it is
+// already published and 422 if its workflow graph or a node configuration is invalid
+func (r *TemplateRouter) HandlePublishWorkflowTemplate(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+
+	idStr := req.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid workflow template ID: "+idStr, http.StatusBadRequest)
+		return
+	}
+
+	workflowTemplate, err := r.ts.PublishWorkflowTemplate(req.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWorkflowTemplateNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrWorkflowTemplateNotDraft):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, service.ErrInvalidWorkflowTemplate):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "failed to publish workflow template: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflowTemplate)
+}
@@ -0,0 +1,130 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
+// WorkflowNodeRouter handles HTTP routing for operations on individual workflow nodes.
+type WorkflowNodeRouter struct {
+	rs *service.WorkflowNodeReopenService
+	es *service.WorkflowNodeExplainService
+}
+
+// NewWorkflowNodeRouter creates a new WorkflowNodeRouter.
+func NewWorkflowNodeRouter(rs *service.WorkflowNodeReopenService, es *service.WorkflowNodeExplainService) *WorkflowNodeRouter {
+	return &WorkflowNodeRouter{
+		rs: rs,
+		es: es,
+	}
+}
+
+// HandleReopenWorkflowNode handles POST /api/v1/workflow-nodes/{id}/reopen
+// Reopens a FAILED node of one of the trader's consignments or pre-consignments, if its
+// template allows traders to reopen it.
+// Path param: id (required)
+// Response: ReopenWorkflowNodeResponseDTO
+func (r *WorkflowNodeRouter) HandleReopenWorkflowNode(w http.ResponseWriter, req *http.Request) {
+	authCtx := auth.GetAuthContext(req.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.reopen(w, req, service.ReopenRequester{TraderID: authCtx.TraderID})
+}
+
+// HandleAdminReopenWorkflowNode handles POST /api/v1/admin/workflow-nodes/{id}/reopen
+// Reopens a FAILED node of any trader, if its template allows traders or admins to reopen it.
+// Path param: id (required)
+// Response: ReopenWorkflowNodeResponseDTO
+func (r *WorkflowNodeRouter) HandleAdminReopenWorkflowNode(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	authCtx := auth.GetAuthContext(req.Context())
+	r.reopen(w, req, service.ReopenRequester{TraderID: authCtx.TraderID, IsAdmin: true})
+}
+
+func (r *WorkflowNodeRouter) reopen(w http.ResponseWriter, req *http.Request, requester service.ReopenRequester) {
+	idStr := req.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "workflow node ID is required", http.StatusBadRequest)
+		return
+	}
+	nodeID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "invalid workflow node ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	node, err := r.rs.ReopenFailedNode(req.Context(), nodeID, requester)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWorkflowNodeNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrWorkflowNodeReopenNotPermitted):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrWorkflowNodeNotReopenable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "failed to reopen workflow node: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.ReopenWorkflowNodeResponseDTO{
+		WorkflowNodeID: node.ID,
+		State:          node.State,
+		Attempt:        node.Attempt,
+	})
+}
+
+// HandleExplainWorkflowNodeUnlock handles GET /api/v1/workflow-nodes/{id}/unlock-explanation
+// Explains why a node of one of the trader's consignments or pre-consignments is (still) locked.
+// Path param: id (required)
+// Response: WorkflowNodeUnlockExplanationDTO
+func (r *WorkflowNodeRouter) HandleExplainWorkflowNodeUnlock(w http.ResponseWriter, req *http.Request) {
+	authCtx := auth.GetAuthContext(req.Context())
+	if authCtx == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.explainUnlock(w, req, &authCtx.TraderID)
+}
+
+// HandleAdminExplainWorkflowNodeUnlock handles GET /api/v1/admin/workflow-nodes/{id}/unlock-explanation
+// Explains why a node of any trader is (still) locked.
+// Path param: id (required)
+// Response: WorkflowNodeUnlockExplanationDTO
+func (r *WorkflowNodeRouter) HandleAdminExplainWorkflowNodeUnlock(w http.ResponseWriter, req *http.Request) {
+	if !requireAdmin(w, req) {
+		return
+	}
+	r.explainUnlock(w, req, nil)
+}
+
+func (r *WorkflowNodeRouter) explainUnlock(w http.ResponseWriter, req *http.Request, traderID *string) {
+	nodeID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid workflow node ID format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := r.es.ExplainUnlock(req.Context(), nodeID, traderID)
+	if err != nil {
+		if errors.Is(err, service.ErrWorkflowNodeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to explain workflow node unlock: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, explanation)
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// amendmentVoidReason is the reason the tasks of nodes voided by an amendment are cancelled with.
+const amendmentVoidReason = "workflow node is no longer required after the consignment was amended"
+
+// AmendConsignment adds items to and removes items from an in-progress consignment owned by the trader, and
+// changes its workflow nodes to match the workflow templates the new set of items requires.
+//
+// Items that were already part of the consignment keep the workflow template version the consignment uses.
+// Node templates that become required get a LOCKED node; nodes that are no longer required are voided, i.e.
+// locked for good with the AMENDMENT_VOIDED extended state, unless they are already COMPLETED. Dependencies
+// are then resolved again, so nodes can become READY or, if a new prerequisite was added before them, be
+// locked again; if the end node becomes READY, the consignment is finished. All of this, and the amendment
+// record, is committed in one transaction. The tasks of voided nodes that were READY or IN_PROGRESS are
+// cancelled through the post-cancel callback, if set, once the amendment is committed.
+func (s *ConsignmentService) AmendConsignment(ctx context.Context, consignmentID uuid.UUID, traderID string, req *model.AmendConsignmentDTO) (*model.ConsignmentAmendmentResultDTO, error) {
+	if req == nil {
+		return nil, fmt.Errorf("amendment request cannot be nil")
+	}
+	if len(req.AddItems) == 0 && len(req.RemoveItems) == 0 {
+		return nil, fmt.Errorf("%w: no items to add or remove", ErrInvalidConsignmentAmendment)
+	}
+
+	// Start a transaction
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the consignment so that node updates for it wait until the amendment is committed
+	var consignment model.Consignment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
+	}
+	if consignment.TraderID != traderID {
+		tx.Rollback()
+		return nil, ErrConsignmentNotFound
+	}
+	if consignment.State != model.ConsignmentStateInProgress {
+		tx.Rollback()
+		return nil, ErrConsignmentNotAmendable
+	}
+
+	amended, err := amendConsignmentItems(consignment.Items, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	targetTemplates, err := s.resolveAmendedWorkflowTemplates(ctx, consignment, amended.items)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	targetTemplateIDs := make(model.UUIDArray, 0, len(targetTemplates))
+	var nodeTemplateIDs []uuid.UUID
+	for _, wt := range targetTemplates {
+		targetTemplateIDs = append(targetTemplateIDs, wt.ID)
+		for _, id := range wt.NodeTemplates {
+			if !slices.Contains(nodeTemplateIDs, id) {
+				nodeTemplateIDs = append(nodeTemplateIDs, id)
+			}
+		}
+	}
+	nodeTemplates, err := s.templateProvider.GetWorkflowNodeTemplatesByIDs(ctx, nodeTemplateIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to retrieve workflow node templates: %w", err)
+	}
+
+	nodes, err := s.nodeRepo.GetWorkflowNodesByConsignmentIDInTx(ctx, tx, consignmentID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to retrieve workflow nodes for consignment %s: %w", consignmentID, err)
+	}
+
+	plan, err := s.planAmendment(&consignment, amended.items, amended.indexes, nodes, targetTemplates, nodeTemplates, time.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if _, err := s.nodeRepo.CreateWorkflowNodesInTx(ctx, tx, plan.addedNodes); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create workflow nodes: %w", err)
+	}
+	if err := s.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, plan.updatedNodes); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update workflow nodes: %w", err)
+	}
+
+	previousTemplateIDs := consignment.WorkflowTemplateIDs
+	consignment.Items = amended.items
+	consignment.WorkflowTemplateIDs = targetTemplateIDs
+	consignment.EndNodeID = plan.endNodeID
+	if plan.finished {
+		consignment.State = model.ConsignmentStateFinished
+	}
+	if err := tx.Model(&consignment).Select("items", "workflow_template_ids", "end_node_id", "state").Updates(&consignment).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update consignment %s: %w", consignmentID, err)
+	}
+
+	amendment := model.ConsignmentAmendment{
+		ConsignmentID:               consignment.ID,
+		TraderID:                    traderID,
+		AddedItems:                  amended.added,
+		RemovedItems:                amended.removed,
+		PreviousWorkflowTemplateIDs: previousTemplateIDs,
+		WorkflowTemplateIDs:         targetTemplateIDs,
+		AddedNodeIDs:                nodeIDs(plan.addedNodes),
+		VoidedNodeIDs:               nodeIDs(plan.voidedNodes),
+		RelockedNodeIDs:             nodeIDs(plan.relockedNodes),
+		UnlockedNodeIDs:             nodeIDs(plan.unlockedNodes),
+		Finished:                    plan.finished,
+	}
+	if req.Reason != "" {
+		amendment.Reason = &req.Reason
+	}
+	if err := tx.Create(&amendment).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record consignment amendment: %w", err)
+	}
+
+	if s.preAmendCommitCallback != nil && (len(plan.relockedNodes) > 0 || len(plan.unlockedNodes) > 0) {
+		if err := s.preAmendCommitCallback(ctx, plan.relockedNodes, plan.unlockedNodes, consignment.GlobalContext); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update tasks of amended workflow nodes: %w", err)
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if s.postCancelCallback != nil && len(plan.voidedActiveNodes) > 0 {
+		s.postCancelCallback(ctx, plan.voidedActiveNodes, amendmentVoidReason)
+	}
+
+	responseDTO, err := s.GetConsignmentByID(ctx, consignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ConsignmentAmendmentResultDTO{
+		Amendment:   amendment,
+		Consignment: *responseDTO,
+	}, nil
+}
+
+// GetConsignmentAmendments returns the amendments of a consignment, oldest first.
+// If traderID is set, the consignment must belong to that trader.
+func (s *ConsignmentService) GetConsignmentAmendments(ctx context.Context, consignmentID uuid.UUID, traderID *string) ([]model.ConsignmentAmendment, error) {
+	var consignment model.Consignment
+	if err := s.db.WithContext(ctx).Select("id", "trader_id").First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
+	}
+	if traderID != nil && consignment.TraderID != *traderID {
+		return nil, ErrConsignmentNotFound
+	}
+
+	amendments := make([]model.ConsignmentAmendment, 0)
+	if err := s.db.WithContext(ctx).Where("consignment_id = ?", consignmentID).Order("created_at ASC").Find(&amendments).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve amendments of consignment %s: %w", consignmentID, err)
+	}
+	return amendments, nil
+}
+
+// amendedItems is the result of applying an amendment to the items of a consignment.
+type amendedItems struct {
+	items   []model.ConsignmentItem // Items after the amendment
+	added   []model.ConsignmentItem // Items added
+	removed []model.ConsignmentItem // Items removed
+	indexes []int                   // Index after the amendment of each item before it, -1 if the item was removed
+}
+
+// amendConsignmentItems applies an amendment to the items of a consignment. Each removed item removes one
+// item with the same HS code; added items are appended.
+func amendConsignmentItems(current []model.ConsignmentItem, req *model.AmendConsignmentDTO) (*amendedItems, error) {
+	amended := &amendedItems{
+		items:   slices.Clone(current),
+		added:   make([]model.ConsignmentItem, 0, len(req.AddItems)),
+		removed: make([]model.ConsignmentItem, 0, len(req.RemoveItems)),
+	}
+	// Track which item before the amendment each remaining item is
+	previousIndexes := make([]int, len(current))
+	for i := range previousIndexes {
+		previousIndexes[i] = i
+	}
+	for _, itemDTO := range req.RemoveItems {
+		i := slices.IndexFunc(amended.items, func(item model.ConsignmentItem) bool { return item.HSCodeID == itemDTO.HSCodeID })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: consignment has no item with HS code %s", ErrInvalidConsignmentAmendment, itemDTO.HSCodeID)
+		}
+		amended.removed = append(amended.removed, amended.items[i])
+		amended.items = slices.Delete(amended.items, i, i+1)
+		previousIndexes = slices.Delete(previousIndexes, i, i+1)
+	}
+	for _, itemDTO := range req.AddItems {
+		if itemDTO.HSCodeID == uuid.Nil {
+			return nil, fmt.Errorf("%w: item HS code ID is required", ErrInvalidConsignmentAmendment)
+		}
+		amended.added = append(amended.added, model.ConsignmentItem{HSCodeID: itemDTO.HSCodeID})
+	}
+	amended.items = append(amended.items, amended.added...)
+	if len(amended.items) == 0 {
+		return nil, fmt.Errorf("%w: consignment must have at least one item", ErrInvalidConsignmentAmendment)
+	}
+
+	amended.indexes = make([]int, len(current))
+	for i := range amended.indexes {
+		amended.indexes[i] = -1
+	}
+	for newIndex, previousIndex := range previousIndexes {
+		amended.indexes[previousIndex] = newIndex
+	}
+	return amended, nil
+}
+
+// resolveAmendedWorkflowTemplates returns the workflow templates the given items of a consignment require, and
+// records on each item the one it uses. An item whose workflow template family the consignment already uses
+// keeps the version the consignment is on; other items use the latest published version of their family.
+func (s *ConsignmentService) resolveAmendedWorkflowTemplates(ctx context.Context, consignment model.Consignment, items []model.ConsignmentItem) ([]model.WorkflowTemplate, error) {
+	currentByFamily := make(map[uuid.UUID]model.WorkflowTemplate, len(consignment.WorkflowTemplateIDs))
+	for _, id := range consignment.WorkflowTemplateIDs {
+		workflowTemplate, err := s.templateProvider.GetWorkflowTemplateByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve workflow template %s: %w", id, err)
+		}
+		currentByFamily[workflowTemplate.FamilyID] = *workflowTemplate
+	}
+
+	var workflowTemplates []model.WorkflowTemplate
+	for i, item := range items {
+		workflowTemplate, err := s.templateProvider.GetWorkflowTemplateByHSCodeIDAndFlow(ctx, item.HSCodeID, consignment.Flow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workflow template for HS code %s and flow %s: %w", item.HSCodeID, consignment.Flow, err)
+		}
+		if current, ok := currentByFamily[workflowTemplate.FamilyID]; ok {
+			workflowTemplate = &current
+		}
+		items[i].WorkflowTemplateID = &workflowTemplate.ID
+		if !slices.ContainsFunc(workflowTemplates, func(wt model.WorkflowTemplate) bool { return wt.ID == workflowTemplate.ID }) {
+			workflowTemplates = append(workflowTemplates, *workflowTemplate)
+		}
+	}
+	return workflowTemplates, nil
+}
+
+// amendmentPlan is the set of changes that moves the nodes of a consignment to the workflow templates its
+// amended items require.
+type amendmentPlan struct {
+	addedNodes        []model.WorkflowNode // New nodes, with their IDs, dependencies and state already set
+	updatedNodes      []model.WorkflowNode // Existing nodes that are kept or voided, or whose item index changed
+	voidedNodes       []model.WorkflowNode // Nodes no longer required, locked as AMENDMENT_VOIDED
+	voidedActiveNodes []model.WorkflowNode // Voided nodes that were READY or IN_PROGRESS, whose tasks must be cancelled
+	relockedNodes     []model.WorkflowNode // Nodes moved from READY back to LOCKED, whose tasks must be discarded
+	unlockedNodes     []model.WorkflowNode // Nodes moved to READY, which must be registered with the Task Manager
+	endNodeID         *uuid.UUID           // End node of the consignment after the amendment
+	finished          bool                 // Whether the end node was completed
+}
+
+// planAmendment computes how the nodes of a consignment change when its items become items and its workflow
+// templates targetTemplates. Nodes are matched by node template and, for item-scoped node templates, item;
+// itemIndexes gives the new index of each item before the amendment, or -1 if it was removed, and nodes of
+// removed items lose their item index. Voided nodes are never reused, and nodes of
+// child workflows are only voided along with their SUB_WORKFLOW node. Unlocks are evaluated against the
+// consignment's GlobalContext.
+func (s *ConsignmentService) planAmendment(
+	consignment *model.Consignment,
+	items []model.ConsignmentItem,
+	itemIndexes []int,
+	nodes []model.WorkflowNode,
+	targetTemplates []model.WorkflowTemplate,
+	nodeTemplates []model.WorkflowNodeTemplate,
+	now time.Time,
+) (*amendmentPlan, error) {
+	plan := &amendmentPlan{}
+	endNodeTemplateID := uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+
+	nodeTemplateByID, endDependencyTemplateIDs, err := indexTargetNodeTemplates(targetTemplates, nodeTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	void := func(node model.WorkflowNode) {
+		if node.State == model.WorkflowNodeStateReady || node.State == model.WorkflowNodeStateInProgress {
+			plan.voidedActiveNodes = append(plan.voidedActiveNodes, node)
+			node.TaskCancellationPending = true
+		}
+		extendedState := model.WorkflowNodeExtendedStateAmendmentVoided
+		node.State = model.WorkflowNodeStateLocked
+		node.ExtendedState = &extendedState
+		node.SLADeadline = nil
+		node.SLABreachedAt = nil
+		plan.voidedNodes = append(plan.voidedNodes, node)
+	}
+
+	// Match existing nodes to the required nodes
+	requiredNodes := requiredNodeInstances(targetTemplates, nodeTemplateByID, items)
+	var kept, detached, children []model.WorkflowNode
+	reindexedChildren := make(map[uuid.UUID]bool)
+	var endNode *model.WorkflowNode
+	nodeByKey := make(map[nodeInstanceKey]uuid.UUID)
+	for _, node := range nodes {
+		// Nodes of items follow their item to its new index
+		reindexed := false
+		if node.ItemIndex != nil {
+			newIndex := -1
+			if *node.ItemIndex >= 0 && *node.ItemIndex < len(itemIndexes) {
+				newIndex = itemIndexes[*node.ItemIndex]
+			}
+			if newIndex != *node.ItemIndex {
+				node.ItemIndex = &newIndex
+				if newIndex < 0 {
+					node.ItemIndex = nil
+				}
+				reindexed = true
+			}
+		}
+
+		if node.IsVoided() {
+			if reindexed {
+				detached = append(detached, node)
+			}
+			continue
+		}
+		// Nodes of child workflows follow their SUB_WORKFLOW node
+		if node.InSubWorkflow() {
+			if reindexed {
+				reindexedChildren[node.ID] = true
+			}
+			children = append(children, node)
+			continue
+		}
+		if node.WorkflowNodeTemplateID == endNodeTemplateID {
+			endNode = &node
+			continue
+		}
+		key := instanceKey(node.WorkflowNodeTemplateID, node.ItemIndex)
+		if !slices.Contains(requiredNodes, key) {
+			// Finished work is kept as it is, so it is reused if the items requiring it are added back
+			if node.State != model.WorkflowNodeStateCompleted {
+				void(node)
+			} else if reindexed {
+				detached = append(detached, node)
+			}
+			continue
+		}
+		if _, duplicate := nodeByKey[key]; duplicate {
+			continue
+		}
+		nodeByKey[key] = node.ID
+		kept = append(kept, node)
+	}
+
+	// Unfinished nodes of the child workflows of voided SUB_WORKFLOW nodes are voided too
+	voidedNodeIDs := make(map[uuid.UUID]bool, len(plan.voidedNodes))
+	for _, node := range plan.voidedNodes {
+		voidedNodeIDs[node.ID] = true
+	}
+	// Repeat until nothing changes, since child workflows can be nested
+	for changed := true; changed; {
+		changed = false
+		for _, node := range children {
+			if !voidedNodeIDs[node.ID] && voidedNodeIDs[*node.ParentNodeID] && !node.IsTerminal() {
+				void(node)
+				voidedNodeIDs[node.ID] = true
+				changed = true
+			}
+		}
+	}
+	for _, node := range children {
+		if !voidedNodeIDs[node.ID] && reindexedChildren[node.ID] {
+			detached = append(detached, node)
+		}
+	}
+
+	// The end node follows the end nodes of the required workflow templates
+	if len(endDependencyTemplateIDs) == 0 && endNode != nil {
+		void(*endNode)
+		endNode = nil
+	}
+
+	replan, err := s.stateMachine.replanConsignmentNodes(consignment, kept, endNode, requiredNodes, nodeByKey,
+		nodeTemplateByID, endDependencyTemplateIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	plan.addedNodes = replan.addedNodes
+	plan.updatedNodes = append(append(replan.keptNodes, plan.voidedNodes...), detached...)
+	plan.relockedNodes = replan.relockedNodes
+	plan.unlockedNodes = replan.unlockedNodes
+	plan.endNodeID = replan.endNodeID
+	plan.finished = replan.finished
+
+	return plan, nil
+}
+
+// nodeIDs returns the IDs of the given nodes.
+func nodeIDs(nodes []model.WorkflowNode) model.UUIDArray {
+	ids := make(model.UUIDArray, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestConsignmentService_PlanAmendment(t *testing.T) {
+	s := NewConsignmentService(nil, nil, nil)
+	now := time.Now().UTC()
+	consignmentID := uuid.New()
+	consignment := &model.Consignment{BaseModel: model.BaseModel{ID: consignmentID}}
+	endNodeTemplateID := uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+
+	nodeTemplate := func(id uuid.UUID, dependsOn ...uuid.UUID) model.WorkflowNodeTemplate {
+		return model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: id}, DependsOn: dependsOn}
+	}
+	node := func(templateID uuid.UUID, state model.WorkflowNodeState, dependsOn ...uuid.UUID) model.WorkflowNode {
+		return model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: templateID,
+			State:                  state,
+			DependsOn:              dependsOn,
+		}
+	}
+	findNode := func(nodes []model.WorkflowNode, id uuid.UUID) *model.WorkflowNode {
+		for i := range nodes {
+			if nodes[i].ID == id {
+				return &nodes[i]
+			}
+		}
+		return nil
+	}
+
+	templateA, templateB, templateC := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("Adds Item With New Prerequisite", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateReady)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeA.ID)
+		// The new item's workflow makes B a prerequisite of A and C a new end node
+		workflowA := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA}, EndNodeTemplateID: &templateA}
+		workflowB := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateB, templateA, templateC}, EndNodeTemplateID: &templateC}
+
+		plan, err := s.planAmendment(consignment, nil, nil, []model.WorkflowNode{nodeA, endNode}, []model.WorkflowTemplate{workflowA, workflowB},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA, templateB), nodeTemplate(templateB), nodeTemplate(templateC, templateA)}, now)
+
+		require.NoError(t, err)
+		require.Len(t, plan.addedNodes, 2)
+		nodeB, nodeC := plan.addedNodes[0], plan.addedNodes[1]
+		assert.Equal(t, templateB, nodeB.WorkflowNodeTemplateID)
+		assert.Equal(t, model.WorkflowNodeStateReady, nodeB.State)
+		assert.Equal(t, model.WorkflowNodeStateLocked, nodeC.State)
+		assert.Equal(t, []model.WorkflowNode{nodeB}, plan.unlockedNodes)
+
+		// A now waits for B
+		relockedA := findNode(plan.updatedNodes, nodeA.ID)
+		require.NotNil(t, relockedA)
+		assert.Equal(t, model.WorkflowNodeStateLocked, relockedA.State)
+		assert.Equal(t, model.UUIDArray{nodeB.ID}, relockedA.DependsOn)
+		require.Len(t, plan.relockedNodes, 1)
+		assert.Equal(t, nodeA.ID, plan.relockedNodes[0].ID)
+
+		// The end node follows the end nodes of both workflows
+		assert.Equal(t, endNode.ID, *plan.endNodeID)
+		assert.Equal(t, model.UUIDArray{nodeA.ID, nodeC.ID}, findNode(plan.updatedNodes, endNode.ID).DependsOn)
+		assert.Empty(t, plan.voidedNodes)
+		assert.False(t, plan.finished)
+	})
+
+	t.Run("Removes Item And Finishes Consignment", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateCompleted)
+		nodeB := node(templateB, model.WorkflowNodeStateInProgress)
+		nodeC := node(templateC, model.WorkflowNodeStateCompleted)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeA.ID, nodeB.ID)
+		workflowA := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA}, EndNodeTemplateID: &templateA}
+
+		plan, err := s.planAmendment(consignment, nil, nil, []model.WorkflowNode{nodeA, nodeB, nodeC, endNode}, []model.WorkflowTemplate{workflowA},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA)}, now)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.addedNodes)
+
+		// B was in progress and is voided; C is completed and kept as it is
+		require.Len(t, plan.voidedNodes, 1)
+		voidedB := plan.voidedNodes[0]
+		assert.Equal(t, nodeB.ID, voidedB.ID)
+		assert.True(t, voidedB.IsVoided())
+		assert.Equal(t, []model.WorkflowNode{nodeB}, plan.voidedActiveNodes)
+		assert.Nil(t, findNode(plan.updatedNodes, nodeC.ID))
+
+		// Only A is left, so the end node completes
+		assert.True(t, plan.finished)
+		assert.Equal(t, model.WorkflowNodeStateCompleted, findNode(plan.updatedNodes, endNode.ID).State)
+		assert.Empty(t, plan.unlockedNodes)
+	})
+
+	t.Run("Voided Node Is Not Reused", func(t *testing.T) {
+		extendedState := model.WorkflowNodeExtendedStateAmendmentVoided
+		voidedA := node(templateA, model.WorkflowNodeStateLocked)
+		voidedA.ExtendedState = &extendedState
+		workflowA := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA}}
+
+		plan, err := s.planAmendment(consignment, nil, nil, []model.WorkflowNode{voidedA}, []model.WorkflowTemplate{workflowA},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA)}, now)
+
+		require.NoError(t, err)
+		require.Len(t, plan.addedNodes, 1)
+		assert.NotEqual(t, voidedA.ID, plan.addedNodes[0].ID)
+		assert.Equal(t, model.WorkflowNodeStateReady, plan.addedNodes[0].State)
+		assert.Empty(t, plan.updatedNodes)
+		assert.Nil(t, plan.endNodeID)
+	})
+
+	t.Run("Item Nodes Follow Their Items", func(t *testing.T) {
+		workflowID := uuid.New()
+		workflowA := model.WorkflowTemplate{BaseModel: model.BaseModel{ID: workflowID}, NodeTemplates: model.UUIDArray{templateA}, EndNodeTemplateID: &templateA}
+		itemTemplate := nodeTemplate(templateA)
+		itemTemplate.Scope = model.WorkflowNodeScopeItem
+		itemNode := func(state model.WorkflowNodeState, itemIndex int) model.WorkflowNode {
+			n := node(templateA, state)
+			n.ItemIndex = &itemIndex
+			return n
+		}
+		// Item 0 is removed, item 1 becomes item 0 and a new item 1 is added
+		nodeA0 := itemNode(model.WorkflowNodeStateInProgress, 0)
+		nodeA1 := itemNode(model.WorkflowNodeStateCompleted, 1)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeA0.ID, nodeA1.ID)
+		items := []model.ConsignmentItem{{HSCodeID: uuid.New(), WorkflowTemplateID: &workflowID}, {HSCodeID: uuid.New(), WorkflowTemplateID: &workflowID}}
+
+		plan, err := s.planAmendment(consignment, items, []int{-1, 0}, []model.WorkflowNode{nodeA0, nodeA1, endNode},
+			[]model.WorkflowTemplate{workflowA}, []model.WorkflowNodeTemplate{itemTemplate}, now)
+
+		require.NoError(t, err)
+		require.Len(t, plan.voidedNodes, 1)
+		assert.Equal(t, nodeA0.ID, plan.voidedNodes[0].ID)
+		assert.Nil(t, plan.voidedNodes[0].ItemIndex)
+
+		movedA := findNode(plan.updatedNodes, nodeA1.ID)
+		require.NotNil(t, movedA)
+		assert.Equal(t, 0, *movedA.ItemIndex)
+
+		require.Len(t, plan.addedNodes, 1)
+		assert.Equal(t, 1, *plan.addedNodes[0].ItemIndex)
+		assert.Equal(t, model.WorkflowNodeStateReady, plan.addedNodes[0].State)
+		assert.Equal(t, model.UUIDArray{nodeA1.ID, plan.addedNodes[0].ID}, findNode(plan.updatedNodes, endNode.ID).DependsOn)
+		assert.False(t, plan.finished)
+	})
+
+	t.Run("Child Workflow Is Voided With Its Node", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateCompleted)
+		subWorkflowB := node(templateB, model.WorkflowNodeStateInProgress)
+		child := func(templateID uuid.UUID, state model.WorkflowNodeState) model.WorkflowNode {
+			n := node(templateID, state)
+			n.ParentNodeID = &subWorkflowB.ID
+			return n
+		}
+		inspection := child(uuid.New(), model.WorkflowNodeStateCompleted)
+		certificate := child(uuid.New(), model.WorkflowNodeStateReady)
+		childEnd := child(endNodeTemplateID, model.WorkflowNodeStateLocked)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeA.ID, subWorkflowB.ID)
+		workflowA := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA}, EndNodeTemplateID: &templateA}
+
+		plan, err := s.planAmendment(consignment, nil, nil, []model.WorkflowNode{nodeA, subWorkflowB, inspection, certificate, childEnd, endNode},
+			[]model.WorkflowTemplate{workflowA}, []model.WorkflowNodeTemplate{nodeTemplate(templateA)}, now)
+
+		require.NoError(t, err)
+		voided := make([]uuid.UUID, 0, len(plan.voidedNodes))
+		for _, n := range plan.voidedNodes {
+			voided = append(voided, n.ID)
+		}
+		assert.ElementsMatch(t, []uuid.UUID{subWorkflowB.ID, certificate.ID, childEnd.ID}, voided)
+		assert.ElementsMatch(t, []model.WorkflowNode{subWorkflowB, certificate}, plan.voidedActiveNodes)
+		assert.Nil(t, findNode(plan.updatedNodes, inspection.ID), "finished child nodes are kept as they are")
+		assert.Equal(t, endNode.ID, *plan.endNodeID, "the end node of a child workflow is not the consignment's")
+		assert.True(t, plan.finished)
+	})
+}
+
+func TestAmendConsignmentItems(t *testing.T) {
+	hsCodeA, hsCodeB := uuid.New(), uuid.New()
+	current := []model.ConsignmentItem{{HSCodeID: hsCodeA}, {HSCodeID: hsCodeA}}
+
+	amended, err := amendConsignmentItems(current, &model.AmendConsignmentDTO{
+		AddItems:    []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeB}},
+		RemoveItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeA}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.ConsignmentItem{{HSCodeID: hsCodeA}, {HSCodeID: hsCodeB}}, amended.items)
+	assert.Equal(t, []model.ConsignmentItem{{HSCodeID: hsCodeB}}, amended.added)
+	assert.Equal(t, []model.ConsignmentItem{{HSCodeID: hsCodeA}}, amended.removed)
+	assert.Equal(t, []int{-1, 0}, amended.indexes)
+	assert.Len(t, current, 2)
+
+	_, err = amendConsignmentItems(current, &model.AmendConsignmentDTO{
+		RemoveItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeB}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidConsignmentAmendment)
+
+	_, err = amendConsignmentItems(current, &model.AmendConsignmentDTO{
+		RemoveItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeA}, {HSCodeID: hsCodeA}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidConsignmentAmendment)
+}
+
+func TestConsignmentService_AmendConsignment(t *testing.T) {
+	ctx := context.Background()
+	consignmentColumns := []string{"id", "flow", "trader_id", "state", "items", "global_context", "workflow_template_ids"}
+
+	t.Run("Removes Last Item", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewConsignmentService(db, templateProvider, nodeRepo)
+		consignmentID, hsCodeA := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(
+				consignmentID, "IMPORT", "trader1", "IN_PROGRESS", []byte(`[{"hsCodeId":"`+hsCodeA.String()+`"}]`), []byte(`{}`), []byte(`[]`)))
+		sqlMock.ExpectRollback()
+
+		_, err := service.AmendConsignment(ctx, consignmentID, "trader1", &model.AmendConsignmentDTO{
+			RemoveItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeA}},
+		})
+
+		assert.ErrorIs(t, err, ErrInvalidConsignmentAmendment)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Other Trader", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewConsignmentService(db, templateProvider, nodeRepo)
+		consignmentID, hsCodeA, hsCodeB := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(
+				consignmentID, "IMPORT", "trader1", "IN_PROGRESS", []byte(`[{"hsCodeId":"`+hsCodeA.String()+`"}]`), []byte(`{}`), []byte(`[]`)))
+		sqlMock.ExpectRollback()
+
+		_, err := service.AmendConsignment(ctx, consignmentID, "trader2", &model.AmendConsignmentDTO{
+			AddItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeB}},
+		})
+
+		assert.ErrorIs(t, err, ErrConsignmentNotFound)
+	})
+
+	t.Run("Consignment Finished", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewConsignmentService(db, templateProvider, nodeRepo)
+		consignmentID, hsCodeA, hsCodeB := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(
+				consignmentID, "IMPORT", "trader1", "FINISHED", []byte(`[{"hsCodeId":"`+hsCodeA.String()+`"}]`), []byte(`{}`), []byte(`[]`)))
+		sqlMock.ExpectRollback()
+
+		_, err := service.AmendConsignment(ctx, consignmentID, "trader1", &model.AmendConsignmentDTO{
+			AddItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeB}},
+		})
+
+		assert.ErrorIs(t, err, ErrConsignmentNotAmendable)
+	})
+
+	t.Run("Nothing To Amend", func(t *testing.T) {
+		s := NewConsignmentService(nil, nil, nil)
+
+		_, err := s.AmendConsignment(ctx, uuid.New(), "trader1", &model.AmendConsignmentDTO{})
+
+		assert.ErrorIs(t, err, ErrInvalidConsignmentAmendment)
+	})
+
+	t.Run("Callback Error Rolls Back", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewConsignmentService(db, templateProvider, nodeRepo)
+		consignmentID, hsCodeA, hsCodeB := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(
+				consignmentID, "IMPORT", "trader1", "IN_PROGRESS", []byte(`[{"hsCodeId":"`+hsCodeA.String()+`"}]`), []byte(`{}`), []byte(`[]`)))
+		workflowB := &model.WorkflowTemplate{BaseModel: model.BaseModel{ID: uuid.New()}, FamilyID: uuid.New()}
+		templateB := uuid.New()
+		workflowB.NodeTemplates = model.UUIDArray{templateB}
+		workflowA := &model.WorkflowTemplate{BaseModel: model.BaseModel{ID: uuid.New()}, FamilyID: uuid.New()}
+		templateProvider.On("GetWorkflowTemplateByHSCodeIDAndFlow", ctx, hsCodeA, model.ConsignmentFlowImport).Return(workflowA, nil)
+		templateProvider.On("GetWorkflowTemplateByHSCodeIDAndFlow", ctx, hsCodeB, model.ConsignmentFlowImport).Return(workflowB, nil)
+		templateProvider.On("GetWorkflowNodeTemplatesByIDs", ctx, []uuid.UUID{templateB}).
+			Return([]model.WorkflowNodeTemplate{{BaseModel: model.BaseModel{ID: templateB}}}, nil)
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).Return([]model.WorkflowNode{}, nil)
+		nodeRepo.On("CreateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].WorkflowNodeTemplateID == templateB && nodes[0].State == model.WorkflowNodeStateReady
+		})).Return([]model.WorkflowNode{}, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil)
+		sqlMock.ExpectExec(`UPDATE "consignments" SET .*"items"=.*"workflow_template_ids"=`).WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectExec(`INSERT INTO "consignment_amendments"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectRollback()
+
+		var unlocked []model.WorkflowNode
+		service.SetPreAmendCommitCallback(func(_ context.Context, _, unlockedNodes []model.WorkflowNode, _ map[string]any) error {
+			unlocked = unlockedNodes
+			return assert.AnError
+		})
+
+		_, err := service.AmendConsignment(ctx, consignmentID, "trader1", &model.AmendConsignmentDTO{
+			AddItems: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeB}},
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		require.Len(t, unlocked, 1)
+		assert.Equal(t, templateB, unlocked[0].WorkflowNodeTemplateID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+}
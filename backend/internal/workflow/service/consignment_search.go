@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+var (
+	// ErrInvalidConsignmentSearch is returned when a consignment search filter is malformed.
+	ErrInvalidConsignmentSearch = errors.New("invalid consignment search")
+)
+
+// consignmentSortColumns maps the fields consignment search results can be ordered by to their columns.
+var consignmentSortColumns = map[model.ConsignmentSortField]string{
+	model.ConsignmentSortCreatedAt: "created_at",
+	model.ConsignmentSortUpdatedAt: "updated_at",
+	model.ConsignmentSortState:     "state",
+	model.ConsignmentSortFlow:      "flow",
+}
+
+// SearchConsignments searches consignments of all traders, for agency officers and administrators. Global context
+// values are matched by JSONB containment, so the search uses the GIN indexes of the items and global_context
+// columns.
+func (s *ConsignmentService) SearchConsignments(ctx context.Context, filter model.ConsignmentSearchFilter) (*model.ConsignmentListResult, error) {
+	page, err := consignmentSearchPage(filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return nil, fmt.Errorf("%w: createdFrom must be before createdTo", ErrInvalidConsignmentSearch)
+	}
+	globalContext, err := globalContextContainments(filter.GlobalContext)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Model(&model.Consignment{})
+	if filter.TraderID != nil {
+		query = query.Where("trader_id = ?", *filter.TraderID)
+	}
+	if filter.State != nil {
+		query = query.Where("state = ?", *filter.State)
+	}
+	if filter.Flow != nil {
+		query = query.Where("flow = ?", *filter.Flow)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.HSCode != nil {
+		query = query.Where(`EXISTS (SELECT 1 FROM hs_codes WHERE hs_codes.hs_code LIKE ? ESCAPE '\' `+
+			`AND consignments.items @> jsonb_build_array(jsonb_build_object('hsCodeId', hs_codes.id)))`,
+			escapeLike(*filter.HSCode)+"%")
+	}
+	if filter.NodeTemplate != nil || filter.NodeState != nil || filter.NodeOutcome != nil {
+		nodes := s.db.Table("workflow_nodes").Select("1").
+			Where("workflow_nodes.consignment_id = consignments.id").
+			Where("workflow_nodes.extended_state IS NULL OR workflow_nodes.extended_state NOT IN ?", model.VoidedExtendedStates)
+		if filter.NodeTemplate != nil {
+			nodes = nodes.Joins("JOIN workflow_node_templates ON workflow_node_templates.id = workflow_nodes.workflow_node_template_id").
+				Where(`workflow_node_templates.name ILIKE ? ESCAPE '\'`, "%"+escapeLike(*filter.NodeTemplate)+"%")
+		}
+		if filter.NodeState != nil {
+			nodes = nodes.Where("workflow_nodes.state = ?", *filter.NodeState)
+		}
+		if filter.NodeOutcome != nil {
+			nodes = nodes.Where("workflow_nodes.outcome = ?", *filter.NodeOutcome)
+		}
+		query = query.Where("EXISTS (?)", nodes)
+	}
+	for _, alternatives := range globalContext {
+		conditions := make([]string, len(alternatives))
+		vars := make([]any, len(alternatives))
+		for i, alternative := range alternatives {
+			conditions[i] = "global_context @> ?::jsonb"
+			vars[i] = alternative
+		}
+		query = query.Where(strings.Join(conditions, " OR "), vars...)
+	}
+
+	return s.listConsignmentSummaries(ctx, query, page)
+}
+
+// consignmentSearchPage returns the page of a consignment search. The ID breaks ties so that pages do not
+// overlap. If filter.Cursor is set, the page starts after it in the order of the search.
+func consignmentSearchPage(filter model.ConsignmentSearchFilter) (consignmentPage, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = model.ConsignmentSortCreatedAt
+	}
+	column, ok := consignmentSortColumns[sortBy]
+	if !ok {
+		return consignmentPage{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalidConsignmentSearch, sortBy)
+	}
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	finalOffset, finalLimit := utils.GetPaginationParams(filter.Offset, filter.Limit)
+	page := consignmentPage{
+		order:  column + " " + direction + ", id " + direction,
+		offset: finalOffset,
+		limit:  finalLimit,
+		cursorOf: func(c *model.Consignment) utils.Cursor {
+			switch sortBy {
+			case model.ConsignmentSortUpdatedAt:
+				return utils.NewTimeCursor(c.UpdatedAt, c.ID)
+			case model.ConsignmentSortState:
+				return utils.Cursor{Key: string(c.State), ID: c.ID}
+			case model.ConsignmentSortFlow:
+				return utils.Cursor{Key: string(c.Flow), ID: c.ID}
+			default:
+				return utils.NewTimeCursor(c.CreatedAt, c.ID)
+			}
+		},
+	}
+	if filter.Cursor != nil {
+		var key any = filter.Cursor.Key
+		if sortBy == model.ConsignmentSortCreatedAt || sortBy == model.ConsignmentSortUpdatedAt {
+			timestamp, err := filter.Cursor.Time()
+			if err != nil {
+				return consignmentPage{}, err
+			}
+			key = timestamp
+		}
+		page.offset = 0
+		page.after = &clause.Expr{SQL: "(" + column + ", id) " + comparison + " (?, ?)", Vars: []any{key, filter.Cursor.ID}}
+	}
+	return page, nil
+}
+
+// globalContextContainments returns, for every dot-separated path of values in order, the JSON objects of which a
+// global context must contain one to hold the value at the path. Query values are strings, so a value that reads
+// as a JSON number or boolean also matches that number or boolean, as well as the string.
+func globalContextContainments(values map[string]string) ([][]string, error) {
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		if slices.Contains(strings.Split(path, "."), "") {
+			return nil, fmt.Errorf("%w: invalid global context path %q", ErrInvalidConsignmentSearch, path)
+		}
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	containments := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		// A path conflicts with another if the value at one would have to be an object holding the other
+		for prefix := path; strings.Contains(prefix, "."); {
+			prefix = prefix[:strings.LastIndex(prefix, ".")]
+			if _, exists := values[prefix]; exists {
+				return nil, fmt.Errorf("%w: global context path %q conflicts with another path", ErrInvalidConsignmentSearch, path)
+			}
+		}
+
+		keys := strings.Split(path, ".")
+		var alternatives []string
+		for _, value := range globalContextValues(values[path]) {
+			var contained any = value
+			for i := len(keys) - 1; i >= 0; i-- {
+				contained = map[string]any{keys[i]: contained}
+			}
+			data, err := json.Marshal(contained)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal global context filter: %w", err)
+			}
+			alternatives = append(alternatives, string(data))
+		}
+		containments = append(containments, alternatives)
+	}
+	return containments, nil
+}
+
+// globalContextValues returns the JSON values a query value matches: the string itself, and the number or boolean
+// it reads as, if any.
+func globalContextValues(value string) []any {
+	values := []any{value}
+	switch value {
+	case "true", "false":
+		values = append(values, value == "true")
+	default:
+		if _, err := strconv.ParseFloat(value, 64); err == nil && json.Valid([]byte(value)) {
+			values = append(values, json.Number(value))
+		}
+	}
+	return values
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern in value, so that it matches literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+func TestConsignmentService_SearchConsignments(t *testing.T) {
+	t.Run("Combines Filters", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		createdFrom := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		createdTo := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+		hsCode := "0902"
+		nodeTemplate := "Phytosanitary"
+		nodeState := model.WorkflowNodeStateInProgress
+		filter := model.ConsignmentSearchFilter{
+			HSCode:        &hsCode,
+			CreatedFrom:   &createdFrom,
+			CreatedTo:     &createdTo,
+			NodeTemplate:  &nodeTemplate,
+			NodeState:     &nodeState,
+			GlobalContext: map[string]string{"invoice.number": "INV-7"},
+			SortBy:        model.ConsignmentSortUpdatedAt,
+			Ascending:     true,
+		}
+
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "consignments" WHERE created_at >= \$1 AND created_at < \$2 `+
+			`AND \(EXISTS \(SELECT 1 FROM hs_codes WHERE hs_codes.hs_code LIKE \$3 ESCAPE '\\' AND consignments.items @> jsonb_build_array\(jsonb_build_object\('hsCodeId', hs_codes.id\)\)\)\) `+
+			`AND EXISTS \(SELECT 1 FROM "workflow_nodes" JOIN workflow_node_templates ON workflow_node_templates.id = workflow_nodes.workflow_node_template_id `+
+			`WHERE workflow_nodes.consignment_id = consignments.id AND \(workflow_nodes.extended_state IS NULL OR workflow_nodes.extended_state NOT IN \(\$4,\$5\)\) `+
+			`AND workflow_node_templates.name ILIKE \$6 ESCAPE '\\' AND workflow_nodes.state = \$7\) `+
+			`AND global_context @> \$8::jsonb`).
+			WithArgs(createdFrom, createdTo, "0902%", model.WorkflowNodeExtendedStateAmendmentVoided, model.WorkflowNodeExtendedStateMigrationVoided,
+				"%Phytosanitary%", nodeState, `{"invoice":{"number":"INV-7"}}`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		consignmentID := uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE .* ORDER BY updated_at ASC, id ASC LIMIT \$9`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "trader_id", "state", "created_at", "updated_at", "items"}).
+				AddRow(consignmentID, "EXPORT", "trader1", "IN_PROGRESS", createdFrom, createdFrom, []byte(`[]`)))
+		sqlMock.ExpectQuery(`SELECT consignment_id, count\(\*\) as total`).
+			WillReturnRows(sqlmock.NewRows([]string{"consignment_id", "total", "completed", "skipped"}).AddRow(consignmentID, 4, 2, 0))
+
+		result, err := service.SearchConsignments(context.Background(), filter)
+
+		require.NoError(t, err)
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(1), *result.TotalCount)
+		}
+		assert.Len(t, result.Items, 1)
+		assert.Equal(t, "trader1", result.Items[0].TraderID)
+		assert.Equal(t, 2, result.Items[0].CompletedWorkflowNodeCount)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Numeric And Boolean Context Values", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		filter := model.ConsignmentSearchFilter{
+			GlobalContext: map[string]string{"invoice.total": "1250.5", "organic": "true"},
+		}
+
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "consignments" WHERE `+
+			`\(global_context @> \$1::jsonb OR global_context @> \$2::jsonb\) `+
+			`AND \(global_context @> \$3::jsonb OR global_context @> \$4::jsonb\)`).
+			WithArgs(`{"invoice":{"total":"1250.5"}}`, `{"invoice":{"total":1250.5}}`, `{"organic":"true"}`, `{"organic":true}`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		result, err := service.SearchConsignments(context.Background(), filter)
+
+		require.NoError(t, err)
+		assert.Empty(t, result.Items)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("No Matches", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		traderID := "trader1"
+		outcome := "REJECTED"
+
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "consignments" WHERE trader_id = \$1 `+
+			`AND EXISTS \(SELECT 1 FROM "workflow_nodes" WHERE workflow_nodes.consignment_id = consignments.id `+
+			`AND \(workflow_nodes.extended_state IS NULL OR workflow_nodes.extended_state NOT IN \(\$2,\$3\)\) AND workflow_nodes.outcome = \$4\)`).
+			WithArgs(traderID, model.WorkflowNodeExtendedStateAmendmentVoided, model.WorkflowNodeExtendedStateMigrationVoided, outcome).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		result, err := service.SearchConsignments(context.Background(), model.ConsignmentSearchFilter{TraderID: &traderID, NodeOutcome: &outcome})
+
+		require.NoError(t, err)
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(0), *result.TotalCount)
+		}
+		assert.Empty(t, result.Items)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		cursor := utils.Cursor{Key: string(model.ConsignmentStateInProgress), ID: uuid.New()}
+		filter := model.ConsignmentSearchFilter{SortBy: model.ConsignmentSortState, Ascending: true, Cursor: &cursor}
+
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE \(state, id\) > \(\$1, \$2\) ORDER BY state ASC, id ASC LIMIT \$3`).
+			WithArgs(cursor.Key, cursor.ID, 51).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		result, err := service.SearchConsignments(context.Background(), filter)
+
+		require.NoError(t, err)
+		assert.Empty(t, result.Items)
+		assert.Nil(t, result.NextCursor)
+		assert.Nil(t, result.TotalCount)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid Filters", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		createdAt := time.Now()
+
+		tests := []struct {
+			name   string
+			filter model.ConsignmentSearchFilter
+		}{
+			{"Unknown Sort Field", model.ConsignmentSearchFilter{SortBy: "traderId"}},
+			{"Empty Date Range", model.ConsignmentSearchFilter{CreatedFrom: &createdAt, CreatedTo: &createdAt}},
+			{"Empty Path Segment", model.ConsignmentSearchFilter{GlobalContext: map[string]string{"invoice..number": "INV-7"}}},
+			{"Conflicting Paths", model.ConsignmentSearchFilter{GlobalContext: map[string]string{"invoice": "INV-7", "invoice.number": "INV-7"}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := service.SearchConsignments(context.Background(), tt.filter)
+				assert.ErrorIs(t, err, ErrInvalidConsignmentSearch)
+			})
+		}
+
+		cursor := utils.Cursor{Key: string(model.ConsignmentStateInProgress), ID: uuid.New()}
+		_, err := service.SearchConsignments(context.Background(), model.ConsignmentSearchFilter{Cursor: &cursor})
+		assert.ErrorIs(t, err, utils.ErrInvalidCursor)
+	})
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `50\% off\_now\\`, escapeLike(`50% off_now\`))
+}
+
+func TestGlobalContextValues(t *testing.T) {
+	assert.Equal(t, []any{"INV-7"}, globalContextValues("INV-7"))
+	assert.Equal(t, []any{"-12.5e2", json.Number("-12.5e2")}, globalContextValues("-12.5e2"))
+	assert.Equal(t, []any{"false", false}, globalContextValues("false"))
+	for _, value := range []string{"NaN", "Inf", "0x10", "1_000", "True", "null"} {
+		assert.Equal(t, []any{value}, globalContextValues(value), value)
+	}
+}
@@ -2,17 +2,54 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/utils"
 )
 
+var (
+	// ErrConsignmentNotFound is returned when a consignment does not exist or is not owned by the trader.
+	ErrConsignmentNotFound = errors.New("consignment not found")
+	// ErrConsignmentNotCancellable is returned when cancelling a consignment that is no longer in progress.
+	ErrConsignmentNotCancellable = errors.New("consignment is not in progress and cannot be cancelled")
+	// ErrConsignmentNotAmendable is returned when amending a consignment that is no longer in progress.
+	ErrConsignmentNotAmendable = errors.New("consignment is not in progress and cannot be amended")
+	// ErrInvalidConsignmentAmendment is returned when an amendment adds and removes nothing, removes an item the
+	// consignment does not have or would leave the consignment without items.
+	ErrInvalidConsignmentAmendment = errors.New("invalid consignment amendment")
+	// ErrPreConsignmentsRequired is returned when creating a consignment while pre-consignments its workflow
+	// templates require are not completed, have expired or were revoked.
+	ErrPreConsignmentsRequired = errors.New("required pre-consignments are not completed and valid")
+)
+
+// MissingPreConsignmentsError lists the pre-consignment templates the trader still has to complete, or renew,
+// before the consignment can be created. It matches ErrPreConsignmentsRequired.
+type MissingPreConsignmentsError struct {
+	Templates []model.PreConsignmentTemplate
+}
+
+func (e *MissingPreConsignmentsError) Error() string {
+	names := make([]string, len(e.Templates))
+	for i, template := range e.Templates {
+		names[i] = template.Name
+	}
+	return fmt.Sprintf("%s: %s", ErrPreConsignmentsRequired, strings.Join(names, ", "))
+}
+
+func (e *MissingPreConsignmentsError) Unwrap() error {
+	return ErrPreConsignmentsRequired
+}
+
 // ConsignmentService handles consignment-related operations.
 // It coordinates between workflow templates, nodes, and the state machine.
 type ConsignmentService struct {
@@ -21,6 +58,9 @@ type ConsignmentService struct {
 	nodeRepo                    WorkflowNodeRepository
 	stateMachine                *WorkflowNodeStateMachine
 	preCommitValidationCallback func([]model.WorkflowNode, map[string]any) error
+	postCancelCallback          func(context.Context, []model.WorkflowNode, string)
+	preAmendCommitCallback      func(ctx context.Context, relockedNodes, unlockedNodes []model.WorkflowNode, globalContext map[string]any) error
+	subWorkflowCallback         func(context.Context, []model.WorkflowNode)
 }
 
 // SetPreCommitValidationCallback sets a callback to be executed before transaction commit
@@ -29,6 +69,27 @@ func (s *ConsignmentService) SetPreCommitValidationCallback(callback func([]mode
 	s.preCommitValidationCallback = callback
 }
 
+// SetPostCancelCallback sets a callback to be executed after a consignment cancellation or amendment is committed
+// This allows the tasks of the nodes that were active (e.g., in the task manager) to be cancelled as well
+func (s *ConsignmentService) SetPostCancelCallback(callback func(context.Context, []model.WorkflowNode, string)) {
+	s.postCancelCallback = callback
+}
+
+// SetPreAmendCommitCallback sets a callback to be executed before an amendment is committed, with the nodes that
+// were locked again, the nodes that became READY and the consignment's global context.
+// This lets the tasks of relocked nodes be discarded and the nodes that became READY be registered within the
+// transaction; if the callback fails, the amendment is rolled back.
+func (s *ConsignmentService) SetPreAmendCommitCallback(callback func(ctx context.Context, relockedNodes, unlockedNodes []model.WorkflowNode, globalContext map[string]any) error) {
+	s.preAmendCommitCallback = callback
+}
+
+// SetPostSubWorkflowCompletionCallback sets a callback to be executed after a node update is committed, with the
+// SUB_WORKFLOW nodes that were completed because their child workflow finished
+// This allows the tasks of those nodes to be completed as well
+func (s *ConsignmentService) SetPostSubWorkflowCompletionCallback(callback func(context.Context, []model.WorkflowNode)) {
+	s.subWorkflowCallback = callback
+}
+
 // NewConsignmentService creates a new instance of ConsignmentService with interface dependencies.
 // This constructor allows for dependency injection and easier testing.
 func NewConsignmentService(db *gorm.DB, templateProvider TemplateProvider, nodeRepo WorkflowNodeRepository) *ConsignmentService {
@@ -78,16 +139,28 @@ func (s *ConsignmentService) initializeConsignmentInTx(ctx context.Context, crea
 
 	var items []model.ConsignmentItem
 	var workflowTemplates []model.WorkflowTemplate
+	workflowTemplateIDs := make(model.UUIDArray, 0, len(createReq.Items))
 	for _, itemDTO := range createReq.Items {
-		item := model.ConsignmentItem(itemDTO)
-		items = append(items, item)
 		workflowTemplate, err := s.templateProvider.GetWorkflowTemplateByHSCodeIDAndFlow(ctx, itemDTO.HSCodeID, createReq.Flow)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to get workflow template for HS code %s and flow %s: %w", itemDTO.HSCodeID, createReq.Flow, err)
 		}
+		// Record the item's template so that item-scoped nodes are created for the items whose workflow lists them
+		items = append(items, model.ConsignmentItem{HSCodeID: itemDTO.HSCodeID, WorkflowTemplateID: &workflowTemplate.ID})
 		workflowTemplates = append(workflowTemplates, *workflowTemplate)
+		if !slices.Contains(workflowTemplateIDs, workflowTemplate.ID) {
+			workflowTemplateIDs = append(workflowTemplateIDs, workflowTemplate.ID)
+		}
 	}
 	consignment.Items = items
+	// Record the template versions so that in-flight consignments can be migrated to a newer version
+	consignment.WorkflowTemplateIDs = workflowTemplateIDs
+
+	seededContext, err := s.seedPreConsignmentContext(ctx, traderId, workflowTemplates, globalContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	consignment.GlobalContext = seededContext
 
 	// Initiate Transaction
 	tx := s.db.WithContext(ctx).Begin()
@@ -104,7 +177,7 @@ func (s *ConsignmentService) initializeConsignmentInTx(ctx context.Context, crea
 	}
 
 	// Create Workflow Nodes
-	_, newReadyWorkflowNodes, endNode, err := s.createWorkflowNodesInTx(ctx, tx, consignment.ID, workflowTemplates)
+	_, newReadyWorkflowNodes, endNode, err := s.createWorkflowNodesInTx(ctx, tx, consignment, workflowTemplates)
 	if err != nil {
 		tx.Rollback()
 		return nil, nil, fmt.Errorf("failed to create workflow nodes: %w", err)
@@ -153,8 +226,59 @@ func (s *ConsignmentService) initializeConsignmentInTx(ctx context.Context, crea
 	return responseDTO, newReadyWorkflowNodes, nil
 }
 
+// seedPreConsignmentContext checks that the trader has completed the pre-consignments the workflow templates
+// require and that none of them has expired or been revoked, and returns a copy of globalContext with the trader context of each of them under the context key
+// of its requirement. If any is missing, it returns a *MissingPreConsignmentsError listing their templates.
+func (s *ConsignmentService) seedPreConsignmentContext(ctx context.Context, traderID string, workflowTemplates []model.WorkflowTemplate, globalContext map[string]any) (map[string]any, error) {
+	var requirements []model.PreConsignmentRequirement
+	var templateIDs []uuid.UUID
+	for _, wt := range workflowTemplates {
+		for _, requirement := range wt.RequiredPreConsignments {
+			if !slices.Contains(requirements, requirement) {
+				requirements = append(requirements, requirement)
+			}
+			if !slices.Contains(templateIDs, requirement.PreConsignmentTemplateID) {
+				templateIDs = append(templateIDs, requirement.PreConsignmentTemplateID)
+			}
+		}
+	}
+	if len(requirements) == 0 {
+		return globalContext, nil
+	}
+
+	// The latest finished pre-consignment of each template is used, and only if it is still valid
+	completed, err := latestFinishedPreConsignments(ctx, s.db, traderID, templateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var missingIDs []uuid.UUID
+	for _, id := range templateIDs {
+		if pc := completed[id]; pc == nil || !pc.IsValid(now) {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	if len(missingIDs) > 0 {
+		var missing []model.PreConsignmentTemplate
+		if err := s.db.WithContext(ctx).Where("id IN ?", missingIDs).Order("name ASC, id ASC").Find(&missing).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve required pre-consignment templates: %w", err)
+		}
+		return nil, &MissingPreConsignmentsError{Templates: missing}
+	}
+
+	seeded := maps.Clone(globalContext)
+	if seeded == nil {
+		seeded = make(map[string]any, len(requirements))
+	}
+	for _, requirement := range requirements {
+		seeded[requirement.ContextKey] = completed[requirement.PreConsignmentTemplateID].TraderContext
+	}
+	return seeded, nil
+}
+
 // createWorkflowNodesInTx builds workflow nodes for the consignment within a transaction.
-func (s *ConsignmentService) createWorkflowNodesInTx(ctx context.Context, tx *gorm.DB, consignmentID uuid.UUID, workflowTemplates []model.WorkflowTemplate) ([]model.WorkflowNode, []model.WorkflowNode, *model.WorkflowNode, error) {
+func (s *ConsignmentService) createWorkflowNodesInTx(ctx context.Context, tx *gorm.DB, consignment *model.Consignment, workflowTemplates []model.WorkflowTemplate) ([]model.WorkflowNode, []model.WorkflowNode, *model.WorkflowNode, error) {
 	// Collect unique node template IDs from all workflow templates
 	uniqueNodeTemplateIDs := make(map[uuid.UUID]bool)
 	for _, wt := range workflowTemplates {
@@ -176,7 +300,11 @@ func (s *ConsignmentService) createWorkflowNodesInTx(ctx context.Context, tx *go
 	}
 
 	// Delegate to the state machine for node initialization
-	return s.stateMachine.InitializeNodesFromTemplates(ctx, tx, ParentRef{ConsignmentID: &consignmentID}, nodeTemplates, workflowTemplates)
+	return s.stateMachine.InitializeNodesFromTemplates(ctx, tx, ParentRef{
+		ConsignmentID: &consignment.ID,
+		Items:         consignment.Items,
+		Context:       consignment.GlobalContext,
+	}, nodeTemplates, workflowTemplates)
 }
 
 // GetConsignmentByID retrieves a consignment by its ID from the database.
@@ -204,10 +332,45 @@ func (s *ConsignmentService) GetConsignmentByID(ctx context.Context, consignment
 	return responseDTO, nil
 }
 
+// GetConsignmentGraph returns the graph of the workflow nodes of a consignment.
+// If traderID is set, the consignment must belong to that trader.
+func (s *ConsignmentService) GetConsignmentGraph(ctx context.Context, consignmentID uuid.UUID, traderID *string) (*model.WorkflowGraph, error) {
+	var consignment model.Consignment
+	if err := s.db.WithContext(ctx).Preload("WorkflowNodes.WorkflowNodeTemplate").First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
+	}
+	if traderID != nil && consignment.TraderID != *traderID {
+		return nil, ErrConsignmentNotFound
+	}
+
+	return buildWorkflowGraph(consignment.ID, consignment.WorkflowNodes, consignment.EndNodeID), nil
+}
+
 // GetConsignmentsByTraderID retrieves consignments associated with a specific trader ID with optional filtering.
+// The consignments are ordered from the newest; if filter.Cursor is set, the page starts after it instead of at
+// offset.
 func (s *ConsignmentService) GetConsignmentsByTraderID(ctx context.Context, traderID string, offset *int, limit *int, filter model.ConsignmentFilter) (*model.ConsignmentListResult, error) {
 	// Apply pagination with defaults and limits
 	finalOffset, finalLimit := utils.GetPaginationParams(offset, limit)
+	page := consignmentPage{
+		order:  "created_at DESC, id DESC",
+		offset: finalOffset,
+		limit:  finalLimit,
+		cursorOf: func(c *model.Consignment) utils.Cursor {
+			return utils.NewTimeCursor(c.CreatedAt, c.ID)
+		},
+	}
+	if filter.Cursor != nil {
+		createdAt, err := filter.Cursor.Time()
+		if err != nil {
+			return nil, err
+		}
+		page.offset = 0
+		page.after = &clause.Expr{SQL: "(created_at, id) < (?, ?)", Vars: []any{createdAt, filter.Cursor.ID}}
+	}
 
 	// Base query for this trader
 	baseQuery := s.db.WithContext(ctx).Model(&model.Consignment{}).Where("trader_id = ?", traderID)
@@ -221,32 +384,66 @@ func (s *ConsignmentService) GetConsignmentsByTraderID(ctx context.Context, trad
 		query = query.Where("flow = ?", *filter.Flow)
 	}
 
+	return s.listConsignmentSummaries(ctx, query, page)
+}
+
+// consignmentPage selects a page of a consignment list, either by offset or, in keyset mode, after a cursor.
+type consignmentPage struct {
+	order    string                                  // ORDER BY clause; ends with the ID so that the order is total
+	offset   int                                     // Number of consignments to skip; zero in keyset mode
+	limit    int                                     // Maximum number of consignments on the page
+	after    *clause.Expr                            // Condition selecting the consignments after the cursor in keyset mode
+	cursorOf func(c *model.Consignment) utils.Cursor // Cursor of a consignment in the order of the list
+}
+
+// listConsignmentSummaries returns a page of the consignments matched by query, with the workflow node counts
+// and HS codes of each. In offset mode the page includes the total count of the consignments matched by query;
+// in keyset mode it does not, since counting would scan all of them for every page.
+func (s *ConsignmentService) listConsignmentSummaries(ctx context.Context, query *gorm.DB, page consignmentPage) (*model.ConsignmentListResult, error) {
 	// Get total count of FILTERED records
-	var totalCount int64
-	if err := query.Count(&totalCount).Error; err != nil {
-		return nil, fmt.Errorf("failed to count filtered consignments: %w", err)
-	}
+	var totalCount *int64
+	if page.after == nil {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count filtered consignments: %w", err)
+		}
 
-	if totalCount == 0 {
-		return &model.ConsignmentListResult{
-			TotalCount: 0,
-			Items:      []model.ConsignmentSummaryDTO{},
-			Offset:     finalOffset,
-			Limit:      finalLimit,
-		}, nil
+		if count == 0 {
+			return &model.ConsignmentListResult{
+				TotalCount: &count,
+				Items:      []model.ConsignmentSummaryDTO{},
+				Offset:     page.offset,
+				Limit:      page.limit,
+			}, nil
+		}
+		totalCount = &count
 	}
 
 	var consignments []model.Consignment
 	// Apply Pagination and Ordering to the filtered query
+	// One consignment more than the limit is fetched to tell whether there is a next page
 	// NOTE: We do NOT preload WorkflowNodes here to improve performance
+	if page.after != nil {
+		query = query.Where(*page.after)
+	}
 	query = query.
-		Offset(finalOffset).
-		Limit(finalLimit).
-		Order("created_at DESC")
+		Offset(page.offset).
+		Limit(page.limit + 1).
+		Order(page.order)
 
 	if err := query.Find(&consignments).Error; err != nil {
 		return nil, fmt.Errorf("failed to retrieve consignments: %w", err)
 	}
+	consignments, nextCursor := utils.KeysetPage(consignments, page.limit, page.cursorOf)
+	if len(consignments) == 0 {
+		// The offset or cursor is past the last consignment
+		return &model.ConsignmentListResult{
+			TotalCount: totalCount,
+			Items:      []model.ConsignmentSummaryDTO{},
+			Offset:     page.offset,
+			Limit:      page.limit,
+		}, nil
+	}
 
 	// Collect Consignment IDs to fetch workflow node counts
 	consignmentIDs := make([]uuid.UUID, len(consignments))
@@ -255,19 +452,23 @@ func (s *ConsignmentService) GetConsignmentsByTraderID(ctx context.Context, trad
 	}
 
 	// Fetch workflow node counts in batch
-	// We need counts of ALL nodes and COMPLETED nodes per consignment
+	// We need counts of ALL nodes, COMPLETED nodes and SKIPPED nodes per consignment
 	type NodeCounts struct {
 		ConsignmentID uuid.UUID
 		Total         int
 		Completed     int
+		Skipped       int
 	}
 
 	var nodeCounts []NodeCounts
-	// This query groups by consignment_id and counts total and completed nodes
+	// This query groups by consignment_id and counts total, completed and skipped nodes
 	// It assumes workflow_nodes table has a consignment_id column and state column
+	// Voided nodes are no longer part of the workflow and are not counted
 	err := s.db.WithContext(ctx).Model(&model.WorkflowNode{}).
-		Select("consignment_id, count(*) as total, count(case when state = ? then 1 end) as completed", model.WorkflowNodeStateCompleted).
+		Select("consignment_id, count(*) as total, count(case when state = ? then 1 end) as completed, count(case when state = ? then 1 end) as skipped",
+			model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped).
 		Where("consignment_id IN ?", consignmentIDs).
+		Where("extended_state IS NULL OR extended_state NOT IN ?", model.VoidedExtendedStates).
 		Group("consignment_id").
 		Scan(&nodeCounts).Error
 
@@ -291,7 +492,7 @@ func (s *ConsignmentService) GetConsignmentsByTraderID(ctx context.Context, trad
 	}
 
 	// Build Summary DTOs for all consignments
-	var consignmentDTOs []model.ConsignmentSummaryDTO
+	consignmentDTOs := make([]model.ConsignmentSummaryDTO, 0, len(consignments))
 	for i := range consignments {
 		c := consignments[i]
 		counts := countsMap[c.ID]
@@ -321,14 +522,16 @@ func (s *ConsignmentService) GetConsignmentsByTraderID(ctx context.Context, trad
 			UpdatedAt:                  c.UpdatedAt.Format(time.RFC3339),
 			WorkflowNodeCount:          counts.Total,
 			CompletedWorkflowNodeCount: counts.Completed,
+			SkippedWorkflowNodeCount:   counts.Skipped,
 		})
 	}
 
 	return &model.ConsignmentListResult{
 		TotalCount: totalCount,
 		Items:      consignmentDTOs,
-		Offset:     finalOffset,
-		Limit:      finalLimit,
+		Offset:     page.offset,
+		Limit:      page.limit,
+		NextCursor: nextCursor,
 	}, nil
 }
 
@@ -390,6 +593,100 @@ func (s *ConsignmentService) UpdateConsignment(ctx context.Context, updateReq *m
 	return responseDTO, nil
 }
 
+// CancelConsignment cancels an in-progress consignment owned by the trader and locks all of its
+// unfinished workflow nodes. The tasks of nodes that were READY or IN_PROGRESS are cancelled
+// through the post-cancel callback, if set, once the cancellation is committed. Those nodes are
+// flagged as pending task cancellation, so the reconciler cancels the tasks if the callback does not.
+func (s *ConsignmentService) CancelConsignment(ctx context.Context, consignmentID uuid.UUID, traderID string, reason string) (*model.ConsignmentDetailDTO, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("cancellation reason cannot be empty")
+	}
+
+	// Start a transaction
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the consignment so that a concurrent completion or amendment waits for the cancellation
+	var consignment model.Consignment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
+	}
+	if consignment.TraderID != traderID {
+		tx.Rollback()
+		return nil, ErrConsignmentNotFound
+	}
+	if consignment.State != model.ConsignmentStateInProgress {
+		tx.Rollback()
+		return nil, ErrConsignmentNotCancellable
+	}
+
+	nodes, err := s.nodeRepo.GetWorkflowNodesByConsignmentIDInTx(ctx, tx, consignmentID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to retrieve workflow nodes for consignment %s: %w", consignmentID, err)
+	}
+
+	extendedState := model.WorkflowNodeExtendedStateConsignmentCancelled
+	var lockedNodes, activeTaskNodes []model.WorkflowNode
+	for _, node := range nodes {
+		switch node.State {
+		case model.WorkflowNodeStateReady, model.WorkflowNodeStateInProgress:
+			activeTaskNodes = append(activeTaskNodes, node)
+			node.TaskCancellationPending = true
+		case model.WorkflowNodeStateLocked:
+			if node.IsVoided() {
+				continue
+			}
+		case model.WorkflowNodeStateFailed:
+			// Locking the node keeps it from being reopened; its task has no running attempt to cancel
+		default:
+			continue
+		}
+		node.State = model.WorkflowNodeStateLocked
+		node.ExtendedState = &extendedState
+		lockedNodes = append(lockedNodes, node)
+	}
+
+	if err := s.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, lockedNodes); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to lock workflow nodes for consignment %s: %w", consignmentID, err)
+	}
+
+	if err := tx.Model(&consignment).Updates(map[string]any{
+		"state":               model.ConsignmentStateCancelled,
+		"cancellation_reason": reason,
+		"cancelled_at":        time.Now().UTC(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update consignment %s state to CANCELLED: %w", consignmentID, err)
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if s.postCancelCallback != nil && len(activeTaskNodes) > 0 {
+		s.postCancelCallback(ctx, activeTaskNodes, reason)
+	}
+
+	responseDTO, err := s.GetConsignmentByID(ctx, consignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseDTO, nil
+}
+
 // UpdateWorkflowNodeStateAndPropagateChanges updates the state of a workflow node and propagates changes to dependent nodes and consignment state, returns the new READY nodes and newGlobalContext.
 func (s *ConsignmentService) UpdateWorkflowNodeStateAndPropagateChanges(ctx context.Context, updateReq *model.UpdateWorkflowNodeDTO) ([]model.WorkflowNode, map[string]any, error) {
 	if updateReq == nil {
@@ -405,7 +702,7 @@ func (s *ConsignmentService) UpdateWorkflowNodeStateAndPropagateChanges(ctx cont
 	}()
 
 	// Update the workflow node state and propagate changes, getting new READY nodes
-	newReadyNodes, newGlobalContext, err := s.updateWorkflowNodeStateAndPropagateChangesInTx(ctx, tx, updateReq)
+	newReadyNodes, newGlobalContext, completedSubWorkflowNodes, err := s.updateWorkflowNodeStateAndPropagateChangesInTx(ctx, tx, updateReq)
 	if err != nil {
 		tx.Rollback()
 		return nil, nil, fmt.Errorf("failed to update workflow node state and propagate changes: %w", err)
@@ -417,67 +714,112 @@ func (s *ConsignmentService) UpdateWorkflowNodeStateAndPropagateChanges(ctx cont
 		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	if s.subWorkflowCallback != nil && len(completedSubWorkflowNodes) > 0 {
+		s.subWorkflowCallback(ctx, completedSubWorkflowNodes)
+	}
+
 	return newReadyNodes, newGlobalContext, nil
 }
 
-// updateWorkflowNodeStateAndPropagateChangesInTx updates the workflow node state and propagates changes within a transaction, and returns the new READY nodes.
-func (s *ConsignmentService) updateWorkflowNodeStateAndPropagateChangesInTx(ctx context.Context, tx *gorm.DB, updateReq *model.UpdateWorkflowNodeDTO) ([]model.WorkflowNode, map[string]any, error) {
+// updateWorkflowNodeStateAndPropagateChangesInTx updates the workflow node state and propagates changes within a transaction, and returns the new READY nodes
+// and the SUB_WORKFLOW nodes that were completed.
+func (s *ConsignmentService) updateWorkflowNodeStateAndPropagateChangesInTx(ctx context.Context, tx *gorm.DB, updateReq *model.UpdateWorkflowNodeDTO) ([]model.WorkflowNode, map[string]any, []model.WorkflowNode, error) {
 	// Get the workflow node
 	workflowNode, err := s.nodeRepo.GetWorkflowNodeByIDInTx(ctx, tx, updateReq.WorkflowNodeID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to retrieve workflow node with ID %s: %w", updateReq.WorkflowNodeID, err)
+		return nil, nil, nil, fmt.Errorf("failed to retrieve workflow node with ID %s: %w", updateReq.WorkflowNodeID, err)
+	}
+
+	// Task updates still in flight when the consignment was cancelled or the node voided are dropped
+	if isLockedByCancellation(workflowNode) || workflowNode.IsVoided() {
+		return nil, nil, nil, nil
+	}
+
+	// Merge the global context first, so that unlocks are evaluated against the global context as updated by
+	// this node
+	consignment, err := s.appendToConsignmentGlobalContext(ctx, tx, workflowNode, updateReq.AppendGlobalContext)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	var newReadyNodes []model.WorkflowNode
+	var newReadyNodes, completedSubWorkflowNodes []model.WorkflowNode
+	completed := false
+	started := false
 
 	// Handle state transitions using the state machine
 	switch updateReq.State {
 	case model.WorkflowNodeStateFailed:
 		if workflowNode.State != model.WorkflowNodeStateFailed {
 			if err := s.stateMachine.TransitionToFailed(ctx, tx, workflowNode, updateReq); err != nil {
-				return nil, nil, fmt.Errorf("failed to transition node to FAILED: %w", err)
+				return nil, nil, nil, fmt.Errorf("failed to transition node to FAILED: %w", err)
 			}
 		}
 
 	case model.WorkflowNodeStateInProgress:
+		started = workflowNode.State == model.WorkflowNodeStateReady
 		if err := s.stateMachine.TransitionToInProgress(ctx, tx, workflowNode, updateReq); err != nil {
-			return nil, nil, fmt.Errorf("failed to transition node to IN_PROGRESS: %w", err)
+			return nil, nil, nil, fmt.Errorf("failed to transition node to IN_PROGRESS: %w", err)
 		}
 
 	case model.WorkflowNodeStateCompleted:
 		if workflowNode.State != model.WorkflowNodeStateCompleted {
-			// Load the consignment to get the HS code and workflow template
-			var consignment model.Consignment
-			if err := tx.WithContext(ctx).First(&consignment, "id = ?", workflowNode.ConsignmentID).Error; err != nil {
-				return nil, nil, fmt.Errorf("failed to retrieve consignment %s: %w", workflowNode.ConsignmentID, err)
-			}
-
 			completionConfig := WorkflowCompletionConfig{
 				EndNodeID: consignment.EndNodeID,
 			}
-			result, err := s.stateMachine.TransitionToCompleted(ctx, tx, workflowNode, updateReq, &completionConfig)
+			unlockContext := consignment.GlobalContext
+			if unlockContext == nil {
+				unlockContext = make(map[string]any)
+			}
+
+			result, err := s.stateMachine.TransitionToCompleted(ctx, tx, workflowNode, updateReq, unlockContext, &completionConfig)
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to transition node to COMPLETED: %w", err)
+				return nil, nil, nil, fmt.Errorf("failed to transition node to COMPLETED: %w", err)
 			}
 			newReadyNodes = result.NewReadyNodes
+			completedSubWorkflowNodes = result.CompletedSubWorkflowNodes
+			completed = true
 
 			// Update consignment state if all nodes are completed
 			if result.WorkflowFinished {
 				if err := s.markConsignmentAsFinished(ctx, tx, *workflowNode.ConsignmentID); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 			}
 		}
 	}
 
-	// Handle global context updates
-	var globalContext map[string]any
-	globalContext, err = s.appendToConsignmentGlobalContext(ctx, tx, *workflowNode.ConsignmentID, updateReq.AppendGlobalContext)
-	if err != nil {
-		return nil, nil, err
+	// A SUB_WORKFLOW node that started creates its child workflow
+	if started {
+		childReadyNodes, err := startSubWorkflowInTx(ctx, tx, s.templateProvider, s.stateMachine, workflowNode, ParentRef{
+			ConsignmentID: workflowNode.ConsignmentID,
+			Context:       consignment.GlobalContext,
+		})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to start sub-workflow of node %s: %w", workflowNode.ID, err)
+		}
+		newReadyNodes = append(newReadyNodes, childReadyNodes...)
+	}
+
+	// Unlock conditions may test the global context, so a change to it can unlock nodes even if no node completed
+	if len(updateReq.AppendGlobalContext) > 0 && !completed {
+		completionConfig := WorkflowCompletionConfig{
+			EndNodeID: consignment.EndNodeID,
+		}
+		result, err := s.stateMachine.ReevaluateUnlocks(ctx, tx, workflowNode, consignment.GlobalContext, &completionConfig)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to re-evaluate unlocks after global context update: %w", err)
+		}
+		newReadyNodes = append(newReadyNodes, result.NewReadyNodes...)
+		completedSubWorkflowNodes = append(completedSubWorkflowNodes, result.CompletedSubWorkflowNodes...)
+
+		if result.WorkflowFinished && consignment.State == model.ConsignmentStateInProgress {
+			if err := s.markConsignmentAsFinished(ctx, tx, consignment.ID); err != nil {
+				return nil, nil, nil, err
+			}
+		}
 	}
 
-	return newReadyNodes, globalContext, nil
+	return newReadyNodes, consignment.GlobalContext, completedSubWorkflowNodes, nil
 }
 
 // markConsignmentAsFinished updates the consignment state to FINISHED.
@@ -488,32 +830,64 @@ func (s *ConsignmentService) markConsignmentAsFinished(ctx context.Context, tx *
 		return fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, result.Error)
 	}
 
-	consignment.State = model.ConsignmentStateFinished
-	if err := tx.WithContext(ctx).Save(&consignment).Error; err != nil {
+	// Only the state is written, so that a global context appended in parallel is not overwritten
+	if err := tx.WithContext(ctx).Model(&consignment).Update("state", model.ConsignmentStateFinished).Error; err != nil {
 		return fmt.Errorf("failed to update consignment %s state to FINISHED: %w", consignmentID, err)
 	}
 
 	return nil
 }
 
-// appendToConsignmentGlobalContext appends key-value pairs to the consignment's global context.
-func (s *ConsignmentService) appendToConsignmentGlobalContext(ctx context.Context, tx *gorm.DB, consignmentID uuid.UUID, appendContext map[string]any) (map[string]any, error) {
+// isLockedByCancellation reports whether a workflow node was locked because its consignment was cancelled.
+func isLockedByCancellation(node *model.WorkflowNode) bool {
+	return node.State == model.WorkflowNodeStateLocked &&
+		node.ExtendedState != nil &&
+		*node.ExtendedState == model.WorkflowNodeExtendedStateConsignmentCancelled
+}
+
+// GetGlobalContext retrieves the global context of a consignment.
+func (s *ConsignmentService) GetGlobalContext(ctx context.Context, consignmentID uuid.UUID) (map[string]any, error) {
 	var consignment model.Consignment
-	result := tx.WithContext(ctx).First(&consignment, "id = ?", consignmentID)
+	result := s.db.WithContext(ctx).Select("id", "global_context").First(&consignment, "id = ?", consignmentID)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, result.Error)
 	}
+	return consignment.GlobalContext, nil
+}
 
-	if consignment.GlobalContext == nil {
-		consignment.GlobalContext = make(map[string]any)
+// appendToConsignmentGlobalContext merges key-value pairs from a workflow node into the global context of its
+// consignment and returns the consignment. The consignment is locked while the keys are merged, so that nodes
+// appending in parallel do not overwrite each other's keys.
+func (s *ConsignmentService) appendToConsignmentGlobalContext(ctx context.Context, tx *gorm.DB, workflowNode *model.WorkflowNode, appendContext map[string]any) (*model.Consignment, error) {
+	consignmentID := *workflowNode.ConsignmentID
+	query := tx.WithContext(ctx)
+	if len(appendContext) > 0 {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var consignment model.Consignment
+	if err := query.First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
+	}
+	if len(appendContext) == 0 {
+		return &consignment, nil
 	}
-	maps.Copy(consignment.GlobalContext, appendContext)
 
-	if err := tx.WithContext(ctx).Save(&consignment).Error; err != nil {
-		return nil, fmt.Errorf("failed to update consignment %s global context: %w", consignmentID, err)
+	merge, err := appendWorkflowContextInTx(ctx, tx, s.templateProvider, workflowNode, consignment.GlobalContext, appendContext)
+	if err != nil {
+		return nil, err
+	}
+	if len(merge.patch) > 0 {
+		expr, err := jsonbMerge("global_context", merge.patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.WithContext(ctx).Model(&consignment).Update("global_context", expr).Error; err != nil {
+			return nil, fmt.Errorf("failed to update consignment %s global context: %w", consignmentID, err)
+		}
 	}
+	consignment.GlobalContext = merge.context
 
-	return consignment.GlobalContext, nil
+	return &consignment, nil
 }
 
 // hsCodeBatchLoader handles batch loading of HS codes for JSONB items
@@ -583,6 +957,10 @@ func (s *ConsignmentService) buildConsignmentDetailDTO(_ context.Context, consig
 		if consignment.EndNodeID != nil && node.ID == *consignment.EndNodeID {
 			continue
 		}
+		// The end nodes of child workflows are as well
+		if node.InSubWorkflow() && node.WorkflowNodeTemplateID == uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID) {
+			continue
+		}
 		nodeResponseDTOs = append(nodeResponseDTOs, model.WorkflowNodeResponseDTO{
 			ID:        node.ID,
 			CreatedAt: node.CreatedAt.Format(time.RFC3339),
@@ -595,6 +973,11 @@ func (s *ConsignmentService) buildConsignmentDetailDTO(_ context.Context, consig
 			State:         node.State,
 			ExtendedState: node.ExtendedState,
 			Outcome:       node.Outcome,
+			Attempt:       node.Attempt,
+			SLADeadline:   formatOptionalTime(node.SLADeadline),
+			SLABreachedAt: formatOptionalTime(node.SLABreachedAt),
+			ItemIndex:     node.ItemIndex,
+			ParentNodeID:  node.ParentNodeID,
 			DependsOn:     node.DependsOn,
 		})
 	}
@@ -610,6 +993,13 @@ func (s *ConsignmentService) buildConsignmentDetailDTO(_ context.Context, consig
 		UpdatedAt:     consignment.UpdatedAt.Format(time.RFC3339),
 		WorkflowNodes: nodeResponseDTOs,
 	}
+	if consignment.State == model.ConsignmentStateCancelled {
+		responseDTO.CancellationReason = consignment.CancellationReason
+		if consignment.CancelledAt != nil {
+			cancelledAt := consignment.CancelledAt.Format(time.RFC3339)
+			responseDTO.CancelledAt = &cancelledAt
+		}
+	}
 
 	return responseDTO, nil
 }
@@ -633,3 +1023,145 @@ func (s *ConsignmentService) buildConsignmentItemResponseDTOs(items []model.Cons
 	}
 	return itemResponseDTOs, nil
 }
+
+// buildWorkflowGraph builds the graph of the given workflow nodes. A node with an unlock
+// configuration gets an edge for every condition of it, from the node the condition is on; any
+// other node gets an edge from each node in its DependsOn, which must be COMPLETED.
+func buildWorkflowGraph(consignmentID uuid.UUID, nodes []model.WorkflowNode, endNodeID *uuid.UUID) *model.WorkflowGraph {
+	nodeMap := make(map[uuid.UUID]model.WorkflowNode, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.ID] = node
+	}
+	name := func(node model.WorkflowNode) string {
+		if displayName := node.DisplayName(); displayName != "" {
+			return displayName
+		}
+		if endNodeID != nil && node.ID == *endNodeID {
+			return "End"
+		}
+		return node.ID.String()
+	}
+	conditionName := func(cond model.UnlockCondition) string {
+		if cond.NodeID != nil {
+			if node, ok := nodeMap[*cond.NodeID]; ok {
+				return name(node)
+			}
+		}
+		return cond.NodeTemplateID.String()
+	}
+
+	graph := &model.WorkflowGraph{
+		ConsignmentID: consignmentID,
+		Nodes:         make([]model.WorkflowGraphNode, 0, len(nodes)),
+		Edges:         make([]model.WorkflowGraphEdge, 0),
+	}
+	for _, node := range sortNodesByDependencies(nodes) {
+		graphNode := model.WorkflowGraphNode{
+			ID:                     node.ID,
+			WorkflowNodeTemplateID: node.WorkflowNodeTemplateID,
+			Name:                   name(node),
+			Type:                   string(node.WorkflowNodeTemplate.Type),
+			ItemIndex:              node.ItemIndex,
+			IsEndNode:              endNodeID != nil && node.ID == *endNodeID,
+			State:                  node.State,
+			Outcome:                node.Outcome,
+			ExtendedState:          node.ExtendedState,
+			Attempt:                node.Attempt,
+			CreatedAt:              node.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:              node.UpdatedAt.Format(time.RFC3339),
+			SLADeadline:            formatOptionalTime(node.SLADeadline),
+			SLABreachedAt:          formatOptionalTime(node.SLABreachedAt),
+		}
+
+		if node.UnlockConfiguration == nil {
+			for _, depID := range node.DependsOn {
+				dep, ok := nodeMap[depID]
+				if !ok {
+					continue
+				}
+				graph.Edges = append(graph.Edges, model.WorkflowGraphEdge{
+					From:      depID,
+					To:        node.ID,
+					Kind:      model.WorkflowGraphEdgeDependsOn,
+					Condition: "state == " + string(model.WorkflowNodeStateCompleted),
+					Satisfied: dep.State == model.WorkflowNodeStateCompleted,
+				})
+			}
+		} else {
+			graphNode.UnlockExpression = node.UnlockConfiguration.Format(conditionName)
+
+			// One edge per distinct condition; how they combine is given by the node's unlock expression.
+			for _, cond := range node.UnlockConfiguration.Conditions() {
+				if cond.NodeID == nil {
+					continue
+				}
+				dep, ok := nodeMap[*cond.NodeID]
+				if !ok {
+					continue
+				}
+				edge := model.WorkflowGraphEdge{
+					From:      dep.ID,
+					To:        node.ID,
+					Kind:      model.WorkflowGraphEdgeUnlock,
+					Condition: cond.String(),
+					Satisfied: cond.IsSatisfiedBy(dep),
+				}
+				if !slices.Contains(graph.Edges, edge) {
+					graph.Edges = append(graph.Edges, edge)
+				}
+			}
+		}
+		graph.Nodes = append(graph.Nodes, graphNode)
+	}
+	return graph
+}
+
+// sortNodesByDependencies orders nodes so that every node comes after the nodes it depends on,
+// taking nodes that are ready to be placed by name. Nodes caught in a cycle are placed last.
+func sortNodesByDependencies(nodes []model.WorkflowNode) []model.WorkflowNode {
+	remaining := slices.Clone(nodes)
+	slices.SortFunc(remaining, func(a, b model.WorkflowNode) int {
+		if c := strings.Compare(a.WorkflowNodeTemplate.Name, b.WorkflowNodeTemplate.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	present := make(map[uuid.UUID]bool, len(nodes))
+	for _, node := range nodes {
+		present[node.ID] = true
+	}
+	placed := make(map[uuid.UUID]bool, len(nodes))
+	sorted := make([]model.WorkflowNode, 0, len(nodes))
+	for len(remaining) > 0 {
+		next := slices.IndexFunc(remaining, func(node model.WorkflowNode) bool {
+			for _, depID := range workflowNodeDependencies(node) {
+				if present[depID] && !placed[depID] {
+					return false
+				}
+			}
+			return true
+		})
+		if next < 0 {
+			return append(sorted, remaining...)
+		}
+		placed[remaining[next].ID] = true
+		sorted = append(sorted, remaining[next])
+		remaining = slices.Delete(remaining, next, next+1)
+	}
+	return sorted
+}
+
+// workflowNodeDependencies returns the IDs of the nodes whose state decides whether node unlocks.
+func workflowNodeDependencies(node model.WorkflowNode) []uuid.UUID {
+	if node.UnlockConfiguration == nil {
+		return node.DependsOn
+	}
+	var ids []uuid.UUID
+	for _, cond := range node.UnlockConfiguration.Conditions() {
+		if cond.NodeID != nil && !slices.Contains(ids, *cond.NodeID) {
+			ids = append(ids, *cond.NodeID)
+		}
+	}
+	return ids
+}
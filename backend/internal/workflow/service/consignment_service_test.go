@@ -12,7 +12,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
 )
 
 // MockTemplateProvider
@@ -105,7 +107,8 @@ func TestConsignmentService_InitializeConsignment(t *testing.T) {
 	// Create Consignment
 	// GORM might use Exec if it doesn't need to return generated values (since we calculate UUID in BeforeCreate)
 	sqlMock.ExpectExec(`INSERT INTO "consignments"`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			`["`+workflowTemplate.ID.String()+`"]`, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Create Workflow Nodes
@@ -251,23 +254,92 @@ func TestConsignmentService_UpdateWorkflowNodeStateAndPropagateChanges(t *testin
 		return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateInProgress
 	})).Return(nil)
 
-	// Append Global Context
-	// First(consignment)
-	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+	// Load the consignment; nothing is appended, so it is neither locked nor updated
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 ORDER BY "consignments"."id" LIMIT \$2$`).
 		WithArgs(consignmentID, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "global_context"}).AddRow(consignmentID, []byte("{}")))
 
-	// Save(consignment)
-	// Save updates all fields
-	sqlMock.ExpectExec(`UPDATE "consignments" SET "created_at"=\$1,"updated_at"=\$2,"flow"=\$3,"trader_id"=\$4,"state"=\$5,"items"=\$6,"global_context"=\$7,"end_node_id"=\$8 WHERE "id" = \$9`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), consignmentID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// The node started, but is not a SUB_WORKFLOW node
+	mockTemplateProvider.On("GetWorkflowNodeTemplateByID", ctx, node.WorkflowNodeTemplateID).
+		Return(&model.WorkflowNodeTemplate{Type: "SIMPLE_FORM"}, nil).Once()
 
 	sqlMock.ExpectCommit()
 
 	newReadyNodes, _, err := service.UpdateWorkflowNodeStateAndPropagateChanges(ctx, updateReq)
 	assert.NoError(t, err)
 	assert.Empty(t, newReadyNodes) // Transition to InProgress doesn't unlock dependent nodes
+	mockTemplateProvider.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestConsignmentService_UpdateWorkflowNodeState_GlobalContextUnlocksNode(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	mockNodeRepo := new(MockWorkflowNodeRepository)
+	mockTemplateProvider := new(MockTemplateProvider)
+	service := NewConsignmentService(db, mockTemplateProvider, mockNodeRepo)
+	ctx := context.Background()
+	nodeID, labTestID := uuid.New(), uuid.New()
+	consignmentID := uuid.New()
+
+	node := &model.WorkflowNode{
+		BaseModel:     model.BaseModel{ID: nodeID},
+		ConsignmentID: &consignmentID,
+		State:         model.WorkflowNodeStateReady,
+	}
+	labTest := model.WorkflowNode{
+		BaseModel:     model.BaseModel{ID: labTestID},
+		ConsignmentID: &consignmentID,
+		State:         model.WorkflowNodeStateLocked,
+		UnlockConfiguration: &model.UnlockConfig{Expression: &model.UnlockExpression{
+			Context: &model.UnlockContextPredicate{Path: "declaredQuantityKg", Operator: model.UnlockContextGreater, Value: 5000},
+		}},
+	}
+
+	sqlMock.ExpectBegin()
+	mockNodeRepo.On("GetWorkflowNodeByIDInTx", ctx, mock.Anything, nodeID).Return(node, nil).Once()
+	mockNodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+		return len(nodes) == 1 && nodes[0].ID == nodeID && nodes[0].State == model.WorkflowNodeStateInProgress
+	})).Return(nil).Once()
+
+	// Append Global Context: the consignment is locked, the changed key recorded and merged into the row
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+		WithArgs(consignmentID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "state", "global_context"}).AddRow(consignmentID, "IN_PROGRESS", []byte(`{"hsCode":"0901"}`)))
+	sqlMock.ExpectQuery(`INSERT INTO "workflow_context_changes"`).
+		WithArgs(consignmentID, nil, nodeID, uuid.Nil, "declaredQuantityKg", nil, "7500", auth.ActorTypeSystem, "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
+	expectWorkflowEvents(sqlMock).
+		WithArgs(consignmentID, nil, nodeID, model.WorkflowEventContextAppended, nil, nil, nil, nil, `{"declaredQuantityKg":7500}`,
+			auth.ActorTypeSystem, "", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg())
+	sqlMock.ExpectExec(`UPDATE "consignments" SET "global_context"=COALESCE\(global_context, '\{\}'::jsonb\) \|\| \$1::jsonb,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(`{"declaredQuantityKg":7500}`, sqlmock.AnyArg(), consignmentID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	// The template gives the merge configuration, and whether the node is a SUB_WORKFLOW node
+	mockTemplateProvider.On("GetWorkflowNodeTemplateByID", ctx, node.WorkflowNodeTemplateID).
+		Return(&model.WorkflowNodeTemplate{Type: "SIMPLE_FORM"}, nil).Twice()
+
+	// Re-evaluate unlocks against the updated global context
+	mockNodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).Return([]model.WorkflowNode{*node, labTest}, nil).Once()
+	mockNodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+		return len(nodes) == 1 && nodes[0].ID == labTestID && nodes[0].State == model.WorkflowNodeStateReady
+	})).Return(nil).Once()
+
+	sqlMock.ExpectCommit()
+
+	newReadyNodes, globalContext, err := service.UpdateWorkflowNodeStateAndPropagateChanges(ctx, &model.UpdateWorkflowNodeDTO{
+		WorkflowNodeID:      nodeID,
+		State:               model.WorkflowNodeStateInProgress,
+		AppendGlobalContext: map[string]any{"declaredQuantityKg": 7500},
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, newReadyNodes, 1) {
+		assert.Equal(t, labTestID, newReadyNodes[0].ID)
+	}
+	assert.Equal(t, 7500, globalContext["declaredQuantityKg"])
+	assert.Equal(t, "0901", globalContext["hsCode"])
+	mockNodeRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestConsignmentService_GetConsignmentByID(t *testing.T) {
@@ -343,15 +415,15 @@ func TestConsignmentService_GetConsignmentsByTraderID(t *testing.T) {
 	consignmentID := uuid.New()
 	hsCodeID := uuid.New()
 	// Select Consignments
-	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE trader_id = \$1 ORDER BY created_at DESC LIMIT \$2`).
-		WithArgs(traderID, limit).
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE trader_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2`).
+		WithArgs(traderID, limit+1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "trader_id", "state", "created_at", "updated_at", "items"}).
 			AddRow(consignmentID, "IMPORT", traderID, "IN_PROGRESS", time.Now(), time.Now(), []byte(`[{"hsCodeId":"`+hsCodeID.String()+`"}]`)))
 
 	// Select WorkflowNodes (Preload)
-	sqlMock.ExpectQuery(`SELECT consignment_id, count\(\*\) as total, count\(case when state = \$1 then 1 end\) as completed FROM "workflow_nodes" WHERE consignment_id IN \(\$2\) GROUP BY "consignment_id"`).
-		WithArgs(sqlmock.AnyArg(), consignmentID).
-		WillReturnRows(sqlmock.NewRows([]string{"consignment_id", "total", "completed"}).AddRow(consignmentID, 1, 0))
+	sqlMock.ExpectQuery(`SELECT consignment_id, count\(\*\) as total, count\(case when state = \$1 then 1 end\) as completed, count\(case when state = \$2 then 1 end\) as skipped FROM "workflow_nodes" WHERE consignment_id IN \(\$3\) AND \(extended_state IS NULL OR extended_state NOT IN \(\$4,\$5\)\) GROUP BY "consignment_id"`).
+		WithArgs(model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped, consignmentID, model.WorkflowNodeExtendedStateAmendmentVoided, model.WorkflowNodeExtendedStateMigrationVoided).
+		WillReturnRows(sqlmock.NewRows([]string{"consignment_id", "total", "completed", "skipped"}).AddRow(consignmentID, 3, 1, 1))
 
 	// Expectation for Batch Load HS Codes
 	sqlMock.ExpectQuery(`SELECT \* FROM "hs_codes" WHERE id IN \(\$1\)`).
@@ -364,9 +436,14 @@ func TestConsignmentService_GetConsignmentsByTraderID(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
-	assert.Equal(t, int64(1), result.TotalCount)
+	if assert.NotNil(t, result.TotalCount) {
+		assert.Equal(t, int64(1), *result.TotalCount)
+	}
 	assert.Len(t, result.Items, 1)
 	assert.Equal(t, consignmentID, result.Items[0].ID)
+	assert.Equal(t, 3, result.Items[0].WorkflowNodeCount)
+	assert.Equal(t, 1, result.Items[0].CompletedWorkflowNodeCount)
+	assert.Equal(t, 1, result.Items[0].SkippedWorkflowNodeCount)
 	// Check WorkflowNodes is not asserted as it's not present in SummaryDTO
 
 }
@@ -397,7 +474,7 @@ func TestConsignmentService_UpdateWorkflowNodeState_Completion(t *testing.T) {
 	// Get Workflow Node (In Tx)
 	mockNodeRepo.On("GetWorkflowNodeByIDInTx", ctx, mock.Anything, nodeID).Return(node, nil).Once()
 
-	// The new SELECT consignment for EndNodeID
+	// Load the consignment for its global context and EndNodeID
 	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
 		WithArgs(consignmentID, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "state"}).AddRow(consignmentID, "IN_PROGRESS"))
@@ -418,20 +495,9 @@ func TestConsignmentService_UpdateWorkflowNodeState_Completion(t *testing.T) {
 		WithArgs(consignmentID, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "state"}).AddRow(consignmentID, "IN_PROGRESS"))
 
-	// Save(consignment) -> State = FINISHED
-	sqlMock.ExpectExec(`UPDATE "consignments" SET "created_at"=\$1,"updated_at"=\$2,"flow"=\$3,"trader_id"=\$4,"state"=\$5,"items"=\$6,"global_context"=\$7,"end_node_id"=\$8 WHERE "id" = \$9`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "FINISHED", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), consignmentID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	// Append Global Context
-	// First(consignment) - Returns FINISHED (simulating DB state after previous update)
-	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
-		WithArgs(consignmentID, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "state", "global_context"}).AddRow(consignmentID, "FINISHED", []byte("{}")))
-
-	// Save(consignment) - Updates Global Context, State should remain FINISHED
-	sqlMock.ExpectExec(`UPDATE "consignments" SET "created_at"=\$1,"updated_at"=\$2,"flow"=\$3,"trader_id"=\$4,"state"=\$5,"items"=\$6,"global_context"=\$7,"end_node_id"=\$8 WHERE "id" = \$9`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "FINISHED", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), consignmentID).
+	// Only the state is written, leaving the global context as it is
+	sqlMock.ExpectExec(`UPDATE "consignments" SET "state"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs("FINISHED", sqlmock.AnyArg(), consignmentID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	sqlMock.ExpectCommit()
@@ -505,6 +571,115 @@ func TestConsignmentService_InitializeConsignment_Failure(t *testing.T) {
 	})
 }
 
+func TestConsignmentService_InitializeConsignment_RequiredPreConsignments(t *testing.T) {
+	ctx := context.Background()
+	hsCodeID := uuid.New()
+	brTemplateID, tinTemplateID := uuid.New(), uuid.New()
+	createReq := &model.CreateConsignmentDTO{
+		Flow:  model.ConsignmentFlowExport,
+		Items: []model.CreateConsignmentItemDTO{{HSCodeID: hsCodeID}},
+	}
+	workflowTemplate := &model.WorkflowTemplate{
+		BaseModel:     model.BaseModel{ID: uuid.New()},
+		NodeTemplates: model.UUIDArray{uuid.New()},
+		RequiredPreConsignments: []model.PreConsignmentRequirement{
+			{PreConsignmentTemplateID: brTemplateID, ContextKey: "br"},
+			{PreConsignmentTemplateID: tinTemplateID, ContextKey: "tin"},
+		},
+	}
+	preConsignmentColumns := []string{"id", "pre_consignment_template_id", "state", "trader_context"}
+
+	t.Run("Missing", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		mockTemplateProvider := new(MockTemplateProvider)
+		service := NewConsignmentService(db, mockTemplateProvider, new(MockWorkflowNodeRepository))
+		mockTemplateProvider.On("GetWorkflowTemplateByHSCodeIDAndFlow", ctx, hsCodeID, model.ConsignmentFlowExport).Return(workflowTemplate, nil).Once()
+
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE trader_id = \$1 AND pre_consignment_template_id IN \(\$2,\$3\) AND state IN \(\$4,\$5,\$6\) ORDER BY created_at DESC, id DESC`).
+			WithArgs("trader1", brTemplateID, tinTemplateID, model.PreConsignmentStateCompleted, model.PreConsignmentStateExpired, model.PreConsignmentStateRevoked).
+			WillReturnRows(sqlmock.NewRows(preConsignmentColumns).
+				AddRow(uuid.New(), brTemplateID, "COMPLETED", []byte(`{"regNo":"PV-1"}`)))
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id IN \(\$1\) ORDER BY name ASC, id ASC`).
+			WithArgs(tinTemplateID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(tinTemplateID, "TIN"))
+
+		_, _, err := service.InitializeConsignment(ctx, createReq, "trader1", nil)
+
+		assert.ErrorIs(t, err, ErrPreConsignmentsRequired)
+		var missingErr *MissingPreConsignmentsError
+		if assert.ErrorAs(t, err, &missingErr) && assert.Len(t, missingErr.Templates, 1) {
+			assert.Equal(t, tinTemplateID, missingErr.Templates[0].ID)
+		}
+		assert.ErrorContains(t, err, "TIN")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Revoked Renewal", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		mockTemplateProvider := new(MockTemplateProvider)
+		service := NewConsignmentService(db, mockTemplateProvider, new(MockWorkflowNodeRepository))
+		mockTemplateProvider.On("GetWorkflowTemplateByHSCodeIDAndFlow", ctx, hsCodeID, model.ConsignmentFlowExport).Return(workflowTemplate, nil).Once()
+
+		// The renewal of the BR was revoked; its predecessor, although still valid, no longer counts
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments"`).
+			WillReturnRows(sqlmock.NewRows(preConsignmentColumns).
+				AddRow(uuid.New(), tinTemplateID, "COMPLETED", []byte(`{"tinNo":"T-1"}`)).
+				AddRow(uuid.New(), brTemplateID, "REVOKED", []byte(`{"regNo":"PV-2"}`)).
+				AddRow(uuid.New(), brTemplateID, "COMPLETED", []byte(`{"regNo":"PV-1"}`)))
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id IN \(\$1\) ORDER BY name ASC, id ASC`).
+			WithArgs(brTemplateID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(brTemplateID, "BR"))
+
+		_, _, err := service.InitializeConsignment(ctx, createReq, "trader1", nil)
+
+		var missingErr *MissingPreConsignmentsError
+		if assert.ErrorAs(t, err, &missingErr) && assert.Len(t, missingErr.Templates, 1) {
+			assert.Equal(t, brTemplateID, missingErr.Templates[0].ID)
+		}
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Seeds Global Context", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		mockTemplateProvider := new(MockTemplateProvider)
+		mockNodeRepo := new(MockWorkflowNodeRepository)
+		service := NewConsignmentService(db, mockTemplateProvider, mockNodeRepo)
+		mockTemplateProvider.On("GetWorkflowTemplateByHSCodeIDAndFlow", ctx, hsCodeID, model.ConsignmentFlowExport).Return(workflowTemplate, nil).Once()
+		nodeTemplate := model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: workflowTemplate.NodeTemplates[0]}, Type: "SIMPLE_FORM"}
+		mockTemplateProvider.On("GetWorkflowNodeTemplatesByIDs", ctx, mock.Anything).Return([]model.WorkflowNodeTemplate{nodeTemplate}, nil).Once()
+		mockNodeRepo.On("CreateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return([]model.WorkflowNode{
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: nodeTemplate.ID, State: model.WorkflowNodeStateLocked},
+		}, nil).Once()
+		mockNodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+
+		// The latest completed pre-consignment of a template is used
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments"`).
+			WillReturnRows(sqlmock.NewRows(preConsignmentColumns).
+				AddRow(uuid.New(), tinTemplateID, "COMPLETED", []byte(`{"tinNo":"T-2"}`)).
+				AddRow(uuid.New(), brTemplateID, "COMPLETED", []byte(`{"regNo":"PV-2"}`)).
+				AddRow(uuid.New(), brTemplateID, "COMPLETED", []byte(`{"regNo":"PV-1"}`)))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "consignments"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectRollback()
+
+		var seeded map[string]any
+		service.SetPreCommitValidationCallback(func(_ []model.WorkflowNode, globalContext map[string]any) error {
+			seeded = globalContext
+			return errors.New("stop")
+		})
+
+		_, _, err := service.InitializeConsignment(ctx, createReq, "trader1", map[string]any{"traderName": "ACME"})
+
+		assert.ErrorContains(t, err, "stop")
+		assert.Equal(t, map[string]any{
+			"traderName": "ACME",
+			"br":         map[string]any{"regNo": "PV-2"},
+			"tin":        map[string]any{"tinNo": "T-2"},
+		}, seeded)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
 func TestConsignmentService_UpdateConsignment_Failure(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTemplateProvider := new(MockTemplateProvider)
@@ -556,7 +731,7 @@ func TestConsignmentService_GetConsignmentsByTraderID_EdgeCases(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
 		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE trader_id = \$1`).
-			WithArgs(traderID, 10).
+			WithArgs(traderID, 11).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 		limit := 10
@@ -564,7 +739,9 @@ func TestConsignmentService_GetConsignmentsByTraderID_EdgeCases(t *testing.T) {
 		result, err := service.GetConsignmentsByTraderID(ctx, traderID, &offset, &limit, model.ConsignmentFilter{})
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
-		assert.Equal(t, int64(0), result.TotalCount)
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(0), *result.TotalCount)
+		}
 		assert.Empty(t, result.Items)
 	})
 
@@ -579,3 +756,297 @@ func TestConsignmentService_GetConsignmentsByTraderID_EdgeCases(t *testing.T) {
 		assert.Nil(t, result)
 	})
 }
+
+func TestConsignmentService_GetConsignmentsByTraderID_Cursor(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	service := NewConsignmentService(db, nil, nil)
+	ctx := context.Background()
+	traderID := "trader1"
+	limit := 1
+	columns := []string{"id", "flow", "trader_id", "state", "created_at", "updated_at", "items"}
+	newerID, olderID := uuid.New(), uuid.New()
+	newer := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	older := newer.Add(-time.Hour)
+
+	// First page: one consignment more than the limit tells that there is a next page
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "consignments" WHERE trader_id = \$1`).
+		WithArgs(traderID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE trader_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2`).
+		WithArgs(traderID, limit+1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(newerID, "IMPORT", traderID, "IN_PROGRESS", newer, newer, []byte(`[]`)).
+			AddRow(olderID, "IMPORT", traderID, "IN_PROGRESS", older, older, []byte(`[]`)))
+	sqlMock.ExpectQuery(`SELECT consignment_id, count\(\*\) as total`).
+		WithArgs(model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped, newerID, model.WorkflowNodeExtendedStateAmendmentVoided, model.WorkflowNodeExtendedStateMigrationVoided).
+		WillReturnRows(sqlmock.NewRows([]string{"consignment_id", "total", "completed", "skipped"}))
+
+	first, err := service.GetConsignmentsByTraderID(ctx, traderID, nil, &limit, model.ConsignmentFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, first.Items, 1)
+	assert.Equal(t, newerID, first.Items[0].ID)
+	if assert.NotNil(t, first.NextCursor) {
+		cursor, err := utils.DecodeCursor(*first.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, utils.NewTimeCursor(newer, newerID), *cursor)
+	}
+
+	// Next page: starts after the cursor, whatever was inserted before it, without counting all the consignments
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE trader_id = \$1 AND \(created_at, id\) < \(\$2, \$3\) ORDER BY created_at DESC, id DESC LIMIT \$4`).
+		WithArgs(traderID, newer, newerID, limit+1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(olderID, "IMPORT", traderID, "IN_PROGRESS", older, older, []byte(`[]`)))
+	sqlMock.ExpectQuery(`SELECT consignment_id, count\(\*\) as total`).
+		WillReturnRows(sqlmock.NewRows([]string{"consignment_id", "total", "completed", "skipped"}))
+
+	cursor := utils.NewTimeCursor(newer, newerID)
+	next, err := service.GetConsignmentsByTraderID(ctx, traderID, nil, &limit, model.ConsignmentFilter{Cursor: &cursor})
+	assert.NoError(t, err)
+	assert.Nil(t, next.TotalCount)
+	assert.Len(t, next.Items, 1)
+	assert.Equal(t, olderID, next.Items[0].ID)
+	assert.Nil(t, next.NextCursor)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestConsignmentService_CancelConsignment(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"id", "trader_id", "state"}
+
+	t.Run("Success", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		mockNodeRepo := new(MockWorkflowNodeRepository)
+		service := NewConsignmentService(db, new(MockTemplateProvider), mockNodeRepo)
+		consignmentID := uuid.New()
+		hsCodeID := uuid.New()
+
+		completedNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateCompleted}
+		inProgressNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateInProgress}
+		readyNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateReady}
+		lockedNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateLocked}
+		failedNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateFailed}
+
+		var cancelledTasks []uuid.UUID
+		var cancelReason string
+		service.SetPostCancelCallback(func(_ context.Context, nodes []model.WorkflowNode, reason string) {
+			for _, node := range nodes {
+				cancelledTasks = append(cancelledTasks, node.ID)
+			}
+			cancelReason = reason
+		})
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(consignmentID, "trader1", "IN_PROGRESS"))
+		mockNodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{completedNode, inProgressNode, readyNode, lockedNode, failedNode}, nil).Once()
+		mockNodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			if len(nodes) != 4 {
+				return false
+			}
+			for _, node := range nodes {
+				if node.State != model.WorkflowNodeStateLocked || *node.ExtendedState != model.WorkflowNodeExtendedStateConsignmentCancelled {
+					return false
+				}
+				// Only the nodes whose task is cancelled are pending task cancellation
+				if node.TaskCancellationPending != (node.ID == inProgressNode.ID || node.ID == readyNode.ID) {
+					return false
+				}
+			}
+			return true
+		})).Return(nil).Once()
+		sqlMock.ExpectExec(`UPDATE "consignments" SET "cancellation_reason"=\$1,"cancelled_at"=\$2,"state"=\$3,"updated_at"=\$4 WHERE "id" = \$5`).
+			WithArgs("created by mistake", sqlmock.AnyArg(), "CANCELLED", sqlmock.AnyArg(), consignmentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		// Reload (Preload)
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "trader_id", "state", "items", "cancellation_reason", "cancelled_at"}).
+				AddRow(consignmentID, "IMPORT", "trader1", "CANCELLED", []byte(`[{"hsCodeId":"`+hsCodeID.String()+`"}]`), "created by mistake", time.Now()))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE "workflow_nodes"."consignment_id" = \$1`).
+			WithArgs(consignmentID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectQuery(`SELECT \* FROM "hs_codes" WHERE id IN \(\$1\)`).
+			WithArgs(hsCodeID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hs_code"}).AddRow(hsCodeID, "1234.56"))
+
+		resp, err := service.CancelConsignment(ctx, consignmentID, "trader1", "created by mistake")
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.ConsignmentStateCancelled, resp.State)
+		assert.Equal(t, "created by mistake", *resp.CancellationReason)
+		assert.NotNil(t, resp.CancelledAt)
+		assert.ElementsMatch(t, []uuid.UUID{inProgressNode.ID, readyNode.ID}, cancelledTasks)
+		assert.Equal(t, "created by mistake", cancelReason)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		mockNodeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not Owned By Trader", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		consignmentID := uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(consignmentID, "trader2", "IN_PROGRESS"))
+		sqlMock.ExpectRollback()
+
+		_, err := service.CancelConsignment(ctx, consignmentID, "trader1", "created by mistake")
+
+		assert.ErrorIs(t, err, ErrConsignmentNotFound)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not In Progress", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		consignmentID := uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(consignmentID, "trader1", "FINISHED"))
+		sqlMock.ExpectRollback()
+
+		_, err := service.CancelConsignment(ctx, consignmentID, "trader1", "created by mistake")
+
+		assert.ErrorIs(t, err, ErrConsignmentNotCancellable)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestConsignmentService_UpdateWorkflowNodeState_CancelledConsignment(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	mockNodeRepo := new(MockWorkflowNodeRepository)
+	service := NewConsignmentService(db, new(MockTemplateProvider), mockNodeRepo)
+	ctx := context.Background()
+	nodeID := uuid.New()
+	consignmentID := uuid.New()
+	extendedState := model.WorkflowNodeExtendedStateConsignmentCancelled
+
+	node := &model.WorkflowNode{
+		BaseModel:     model.BaseModel{ID: nodeID},
+		ConsignmentID: &consignmentID,
+		State:         model.WorkflowNodeStateLocked,
+		ExtendedState: &extendedState,
+	}
+
+	sqlMock.ExpectBegin()
+	mockNodeRepo.On("GetWorkflowNodeByIDInTx", ctx, mock.Anything, nodeID).Return(node, nil).Once()
+	sqlMock.ExpectCommit()
+
+	newReadyNodes, globalContext, err := service.UpdateWorkflowNodeStateAndPropagateChanges(ctx, &model.UpdateWorkflowNodeDTO{
+		WorkflowNodeID: nodeID,
+		State:          model.WorkflowNodeStateCompleted,
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, newReadyNodes)
+	assert.Nil(t, globalContext)
+	mockNodeRepo.AssertNotCalled(t, "UpdateWorkflowNodesInTx", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestConsignmentService_GetConsignmentGraph(t *testing.T) {
+	ctx := context.Background()
+	consignmentID := uuid.New()
+	reviewID, paymentID := uuid.New(), uuid.New()
+	reviewTemplateID, paymentTemplateID := uuid.New(), uuid.New()
+	traderID := "trader1"
+
+	expectConsignment := func(sqlMock sqlmock.Sqlmock) {
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 ORDER BY "consignments"."id" LIMIT \$2`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "trader_id", "state"}).
+				AddRow(consignmentID, "IMPORT", traderID, "IN_PROGRESS"))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE "workflow_nodes"."consignment_id" = \$1`).
+			WithArgs(consignmentID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_node_template_id", "state", "outcome", "consignment_id", "depends_on", "unlock_configuration"}).
+				AddRow(paymentID, paymentTemplateID, "LOCKED", nil, consignmentID, []byte(`["`+reviewID.String()+`"]`),
+					[]byte(`{"expression": {"nodeTemplateId": "`+reviewTemplateID.String()+`", "nodeId": "`+reviewID.String()+`", "outcome": "APPROVED"}}`)).
+				AddRow(reviewID, reviewTemplateID, "COMPLETED", "REJECTED", consignmentID, []byte(`[]`), nil))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE "workflow_node_templates"."id" IN \(\$1,\$2\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type"}).
+				AddRow(reviewTemplateID, "Review", "SIMPLE_FORM").
+				AddRow(paymentTemplateID, "Payment", "WAIT_FOR_EVENT"))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		expectConsignment(sqlMock)
+
+		graph, err := service.GetConsignmentGraph(ctx, consignmentID, &traderID)
+		assert.NoError(t, err)
+		if assert.Len(t, graph.Nodes, 2) {
+			assert.Equal(t, "Review", graph.Nodes[0].Name)
+			assert.Equal(t, "Payment", graph.Nodes[1].Name)
+			assert.Equal(t, "Review.outcome == APPROVED", graph.Nodes[1].UnlockExpression)
+		}
+		assert.Equal(t, []model.WorkflowGraphEdge{{
+			From: reviewID, To: paymentID, Kind: model.WorkflowGraphEdgeUnlock, Condition: "outcome == APPROVED", Satisfied: false,
+		}}, graph.Edges)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Other Trader", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		expectConsignment(sqlMock)
+
+		otherTrader := "trader2"
+		_, err := service.GetConsignmentGraph(ctx, consignmentID, &otherTrader)
+		assert.ErrorIs(t, err, ErrConsignmentNotFound)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := service.GetConsignmentGraph(ctx, consignmentID, nil)
+		assert.ErrorIs(t, err, ErrConsignmentNotFound)
+	})
+}
+
+func TestBuildWorkflowGraph(t *testing.T) {
+	completed, approved := "COMPLETED", "APPROVED"
+	declaration := model.WorkflowNode{WorkflowNodeTemplate: model.WorkflowNodeTemplate{Name: "Declaration"}, State: model.WorkflowNodeStateCompleted}
+	declaration.ID = uuid.New()
+	review := model.WorkflowNode{WorkflowNodeTemplate: model.WorkflowNodeTemplate{Name: "Review"}, State: model.WorkflowNodeStateInProgress, DependsOn: model.UUIDArray{declaration.ID}}
+	review.ID = uuid.New()
+	release := model.WorkflowNode{WorkflowNodeTemplate: model.WorkflowNodeTemplate{Name: "Release"}, State: model.WorkflowNodeStateLocked}
+	release.ID = uuid.New()
+	release.UnlockConfiguration = &model.UnlockConfig{Expression: &model.UnlockExpression{AnyOf: []model.UnlockExpression{
+		{AllOf: []model.UnlockExpression{
+			{NodeID: &review.ID, State: &completed},
+			{NodeID: &review.ID, Outcome: &approved},
+		}},
+		{NodeID: &declaration.ID, State: &completed},
+	}}}
+	end := model.WorkflowNode{State: model.WorkflowNodeStateLocked, DependsOn: model.UUIDArray{release.ID}}
+	end.ID = uuid.New()
+
+	graph := buildWorkflowGraph(uuid.New(), []model.WorkflowNode{end, release, review, declaration}, &end.ID)
+
+	var names []string
+	for _, node := range graph.Nodes {
+		names = append(names, node.Name)
+	}
+	assert.Equal(t, []string{"Declaration", "Review", "Release", "End"}, names)
+	assert.True(t, graph.Nodes[3].IsEndNode)
+	assert.Equal(t, "(Review.state == COMPLETED && Review.outcome == APPROVED) || Declaration.state == COMPLETED", graph.Nodes[2].UnlockExpression)
+
+	assert.Equal(t, []model.WorkflowGraphEdge{
+		{From: declaration.ID, To: review.ID, Kind: model.WorkflowGraphEdgeDependsOn, Condition: "state == COMPLETED", Satisfied: true},
+		{From: review.ID, To: release.ID, Kind: model.WorkflowGraphEdgeUnlock, Condition: "state == COMPLETED", Satisfied: false},
+		{From: review.ID, To: release.ID, Kind: model.WorkflowGraphEdgeUnlock, Condition: "outcome == APPROVED", Satisfied: false},
+		{From: declaration.ID, To: release.ID, Kind: model.WorkflowGraphEdgeUnlock, Condition: "state == COMPLETED", Satisfied: true},
+		{From: release.ID, To: end.ID, Kind: model.WorkflowGraphEdgeDependsOn, Condition: "state == COMPLETED", Satisfied: false},
+	}, graph.Edges)
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+var (
+	// ErrFailedUpdateNotFound is returned when a failed workflow update does not exist.
+	ErrFailedUpdateNotFound = errors.New("failed workflow update not found")
+	// ErrFailedUpdateNotDeadLettered is returned when an operator action requires a dead-lettered update.
+	ErrFailedUpdateNotDeadLettered = errors.New("failed workflow update is not dead-lettered")
+)
+
+// RetryPolicy controls how failed workflow node updates are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts (including the first) before an update is dead-lettered
+	BaseDelay   time.Duration // Delay before the first retry; doubled for every further attempt
+	MaxDelay    time.Duration // Upper bound for the delay between attempts
+}
+
+// DefaultRetryPolicy returns the retry policy used by the workflow manager.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 8,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    5 * time.Minute,
+	}
+}
+
+// Backoff returns the delay to wait after the given number of failed attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// FailedWorkflowUpdateService persists workflow node updates that could not be applied
+// and manages their retry and dead-letter lifecycle.
+type FailedWorkflowUpdateService struct {
+	db     *gorm.DB
+	policy RetryPolicy
+}
+
+// NewFailedWorkflowUpdateService creates a new instance of FailedWorkflowUpdateService.
+func NewFailedWorkflowUpdateService(db *gorm.DB, policy RetryPolicy) *FailedWorkflowUpdateService {
+	return &FailedWorkflowUpdateService{
+		db:     db,
+		policy: policy,
+	}
+}
+
+// HasOutstandingUpdates reports whether the workflow node has updates that are still pending
+// or dead-lettered. New updates for such a node must be queued behind them to preserve ordering.
+func (s *FailedWorkflowUpdateService) HasOutstandingUpdates(ctx context.Context, workflowNodeID uuid.UUID) (bool, error) {
+	var count int64
+	result := s.db.WithContext(ctx).Model(&model.FailedWorkflowUpdate{}).
+		Where("workflow_node_id = ? AND status IN ?", workflowNodeID, outstandingStatuses()).
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to count outstanding workflow updates: %w", result.Error)
+	}
+	return count > 0, nil
+}
+
+// GetNodesWithOutstandingUpdates returns the subset of workflowNodeIDs that have pending or dead-lettered updates.
+func (s *FailedWorkflowUpdateService) GetNodesWithOutstandingUpdates(ctx context.Context, workflowNodeIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	outstanding := make(map[uuid.UUID]bool)
+	if len(workflowNodeIDs) == 0 {
+		return outstanding, nil
+	}
+
+	var nodeIDs []uuid.UUID
+	result := s.db.WithContext(ctx).Model(&model.FailedWorkflowUpdate{}).
+		Distinct("workflow_node_id").
+		Where("workflow_node_id IN ? AND status IN ?", workflowNodeIDs, outstandingStatuses()).
+		Pluck("workflow_node_id", &nodeIDs)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow nodes with outstanding updates: %w", result.Error)
+	}
+
+	for _, nodeID := range nodeIDs {
+		outstanding[nodeID] = true
+	}
+	return outstanding, nil
+}
+
+// RecordFailure stores an update whose first attempt failed and schedules its first retry.
+// Updates that failed with a permanent error are dead-lettered right away instead.
+func (s *FailedWorkflowUpdateService) RecordFailure(ctx context.Context, update *model.UpdateWorkflowNodeDTO, cause error) (*model.FailedWorkflowUpdate, error) {
+	now := time.Now().UTC()
+	reason := cause.Error()
+	failedUpdate := newFailedWorkflowUpdate(ctx, update)
+	failedUpdate.Attempts = 1
+	failedUpdate.LastError = &reason
+	if isPermanentUpdateError(cause) {
+		failedUpdate.Status = model.FailedWorkflowUpdateStatusDeadLettered
+		failedUpdate.NextAttemptAt = now
+		failedUpdate.DeadLetteredAt = &now
+	} else {
+		s.scheduleNextAttempt(failedUpdate, now)
+	}
+
+	if err := s.db.WithContext(ctx).Create(failedUpdate).Error; err != nil {
+		return nil, fmt.Errorf("failed to record failed workflow update: %w", err)
+	}
+	return failedUpdate, nil
+}
+
+// QueueBehindOutstanding stores an update that has not been attempted yet because an earlier
+// update for the same workflow node is still outstanding.
+func (s *FailedWorkflowUpdateService) QueueBehindOutstanding(ctx context.Context, update *model.UpdateWorkflowNodeDTO) (*model.FailedWorkflowUpdate, error) {
+	failedUpdate := newFailedWorkflowUpdate(ctx, update)
+	failedUpdate.NextAttemptAt = time.Now().UTC()
+
+	if err := s.db.WithContext(ctx).Create(failedUpdate).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue workflow update: %w", err)
+	}
+	return failedUpdate, nil
+}
+
+// GetRetryableUpdates retrieves pending updates in the order they must be applied.
+// Updates of workflow nodes that have a dead-lettered update are excluded until an
+// administrator replays or discards it.
+func (s *FailedWorkflowUpdateService) GetRetryableUpdates(ctx context.Context, limit int) ([]model.FailedWorkflowUpdate, error) {
+	var updates []model.FailedWorkflowUpdate
+	deadLetteredNodes := s.db.Model(&model.FailedWorkflowUpdate{}).
+		Select("workflow_node_id").
+		Where("status = ?", model.FailedWorkflowUpdateStatusDeadLettered)
+
+	result := s.db.WithContext(ctx).
+		Where("status = ?", model.FailedWorkflowUpdateStatusPending).
+		Where("workflow_node_id NOT IN (?)", deadLetteredNodes).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve retryable workflow updates: %w", result.Error)
+	}
+	return updates, nil
+}
+
+// MarkResolved records that a failed update was applied successfully.
+func (s *FailedWorkflowUpdateService) MarkResolved(ctx context.Context, failedUpdate *model.FailedWorkflowUpdate) error {
+	now := time.Now().UTC()
+	failedUpdate.Status = model.FailedWorkflowUpdateStatusResolved
+	failedUpdate.ResolvedAt = &now
+
+	result := s.db.WithContext(ctx).Model(failedUpdate).Updates(map[string]any{
+		"status":      failedUpdate.Status,
+		"resolved_at": failedUpdate.ResolvedAt,
+		"updated_at":  now,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark workflow update %s as resolved: %w", failedUpdate.ID, result.Error)
+	}
+	return nil
+}
+
+// RecordRetryFailure records a failed retry attempt. The update is rescheduled with exponential
+// backoff, or moved to the dead-letter queue once the policy's maximum attempts is reached or
+// the attempt failed with a permanent error.
+func (s *FailedWorkflowUpdateService) RecordRetryFailure(ctx context.Context, failedUpdate *model.FailedWorkflowUpdate, cause error) error {
+	now := time.Now().UTC()
+	reason := cause.Error()
+	failedUpdate.Attempts++
+	failedUpdate.LastError = &reason
+
+	if failedUpdate.Attempts >= s.policy.MaxAttempts || isPermanentUpdateError(cause) {
+		failedUpdate.Status = model.FailedWorkflowUpdateStatusDeadLettered
+		failedUpdate.DeadLetteredAt = &now
+	} else {
+		s.scheduleNextAttempt(failedUpdate, now)
+	}
+
+	result := s.db.WithContext(ctx).Model(failedUpdate).Updates(map[string]any{
+		"status":           failedUpdate.Status,
+		"attempts":         failedUpdate.Attempts,
+		"last_error":       failedUpdate.LastError,
+		"next_attempt_at":  failedUpdate.NextAttemptAt,
+		"dead_lettered_at": failedUpdate.DeadLetteredAt,
+		"updated_at":       now,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to record retry failure for workflow update %s: %w", failedUpdate.ID, result.Error)
+	}
+	return nil
+}
+
+// ListFailedUpdates retrieves failed workflow updates matching the filter, newest first.
+func (s *FailedWorkflowUpdateService) ListFailedUpdates(ctx context.Context, filter model.FailedWorkflowUpdateFilter) (*model.FailedWorkflowUpdateListResult, error) {
+	applyFilter := func(query *gorm.DB) *gorm.DB {
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if filter.WorkflowNodeID != nil {
+			query = query.Where("workflow_node_id = ?", *filter.WorkflowNodeID)
+		}
+		return query
+	}
+
+	var totalCount int64
+	if err := applyFilter(s.db.WithContext(ctx).Model(&model.FailedWorkflowUpdate{})).Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count failed workflow updates: %w", err)
+	}
+
+	finalOffset, finalLimit := utils.GetPaginationParams(filter.Offset, filter.Limit)
+	updates := make([]model.FailedWorkflowUpdate, 0)
+	if totalCount > 0 {
+		if err := applyFilter(s.db.WithContext(ctx)).Order("created_at DESC").Offset(finalOffset).Limit(finalLimit).Find(&updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve failed workflow updates: %w", err)
+		}
+	}
+
+	return &model.FailedWorkflowUpdateListResult{
+		TotalCount: totalCount,
+		Items:      updates,
+		Offset:     finalOffset,
+		Limit:      finalLimit,
+	}, nil
+}
+
+// GetFailedUpdateByID retrieves a failed workflow update by its ID.
+func (s *FailedWorkflowUpdateService) GetFailedUpdateByID(ctx context.Context, id uuid.UUID) (*model.FailedWorkflowUpdate, error) {
+	var failedUpdate model.FailedWorkflowUpdate
+	result := s.db.WithContext(ctx).First(&failedUpdate, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrFailedUpdateNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve failed workflow update: %w", result.Error)
+	}
+	return &failedUpdate, nil
+}
+
+// ReplayFailedUpdate moves a dead-lettered update back to PENDING with a fresh attempt budget,
+// so the retry worker applies it on its next cycle.
+func (s *FailedWorkflowUpdateService) ReplayFailedUpdate(ctx context.Context, id uuid.UUID) (*model.FailedWorkflowUpdate, error) {
+	now := time.Now().UTC()
+	return s.resolveDeadLettered(ctx, id, map[string]any{
+		"status":           model.FailedWorkflowUpdateStatusPending,
+		"attempts":         0,
+		"next_attempt_at":  now,
+		"dead_lettered_at": nil,
+		"updated_at":       now,
+	})
+}
+
+// DiscardFailedUpdate drops a dead-lettered update without applying it.
+// Updates queued behind it for the same workflow node become eligible for retry.
+func (s *FailedWorkflowUpdateService) DiscardFailedUpdate(ctx context.Context, id uuid.UUID) (*model.FailedWorkflowUpdate, error) {
+	now := time.Now().UTC()
+	return s.resolveDeadLettered(ctx, id, map[string]any{
+		"status":      model.FailedWorkflowUpdateStatusDiscarded,
+		"resolved_at": now,
+		"updated_at":  now,
+	})
+}
+
+// resolveDeadLettered applies an operator action to a dead-lettered update.
+// The status guard in the UPDATE prevents racing actions from both succeeding.
+func (s *FailedWorkflowUpdateService) resolveDeadLettered(ctx context.Context, id uuid.UUID, updates map[string]any) (*model.FailedWorkflowUpdate, error) {
+	failedUpdate, err := s.GetFailedUpdateByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if failedUpdate.Status != model.FailedWorkflowUpdateStatusDeadLettered {
+		return nil, fmt.Errorf("%w: %s is %s", ErrFailedUpdateNotDeadLettered, id, failedUpdate.Status)
+	}
+
+	result := s.db.WithContext(ctx).Model(&model.FailedWorkflowUpdate{}).
+		Where("id = ? AND status = ?", id, model.FailedWorkflowUpdateStatusDeadLettered).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update failed workflow update %s: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: %s was modified concurrently", ErrFailedUpdateNotDeadLettered, id)
+	}
+
+	return s.GetFailedUpdateByID(ctx, id)
+}
+
+func (s *FailedWorkflowUpdateService) scheduleNextAttempt(failedUpdate *model.FailedWorkflowUpdate, now time.Time) {
+	failedUpdate.NextAttemptAt = now.Add(s.policy.Backoff(failedUpdate.Attempts))
+}
+
+// isPermanentUpdateError reports whether an update failed in a way that retrying cannot fix,
+// such as a context key conflict under the REJECT policy.
+func isPermanentUpdateError(err error) bool {
+	return errors.Is(err, ErrContextConflict)
+}
+
+func newFailedWorkflowUpdate(ctx context.Context, update *model.UpdateWorkflowNodeDTO) *model.FailedWorkflowUpdate {
+	actor := auth.ActorFromContext(ctx)
+	if update.Actor != nil {
+		actor = *update.Actor
+	}
+	return &model.FailedWorkflowUpdate{
+		WorkflowNodeID:      update.WorkflowNodeID,
+		State:               update.State,
+		ExtendedState:       update.ExtendedState,
+		Outcome:             update.Outcome,
+		AppendGlobalContext: update.AppendGlobalContext,
+		Actor:               actor,
+		Status:              model.FailedWorkflowUpdateStatusPending,
+	}
+}
+
+func outstandingStatuses() []model.FailedWorkflowUpdateStatus {
+	return []model.FailedWorkflowUpdateStatus{
+		model.FailedWorkflowUpdateStatusPending,
+		model.FailedWorkflowUpdateStatusDeadLettered,
+	}
+}
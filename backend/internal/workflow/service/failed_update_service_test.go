@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 0, expected: 0},
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 3, expected: 4 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 5, expected: 10 * time.Second},
+		{attempts: 50, expected: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, policy.Backoff(tt.attempts), "attempts=%d", tt.attempts)
+	}
+}
+
+func TestFailedWorkflowUpdateService_RecordFailure(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	service := NewFailedWorkflowUpdateService(db, policy)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`INSERT INTO "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	before := time.Now().UTC()
+	update := &model.UpdateWorkflowNodeDTO{WorkflowNodeID: uuid.New(), State: model.WorkflowNodeStateCompleted}
+	failedUpdate, err := service.RecordFailure(context.Background(), update, errors.New("db unavailable"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.FailedWorkflowUpdateStatusPending, failedUpdate.Status)
+	assert.Equal(t, 1, failedUpdate.Attempts)
+	assert.Equal(t, "db unavailable", *failedUpdate.LastError)
+	assert.False(t, failedUpdate.NextAttemptAt.Before(before.Add(time.Minute)))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFailedWorkflowUpdateService_RecordFailure_ContextConflict(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	service := NewFailedWorkflowUpdateService(db, policy)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`INSERT INTO "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	update := &model.UpdateWorkflowNodeDTO{WorkflowNodeID: uuid.New(), State: model.WorkflowNodeStateCompleted}
+	cause := fmt.Errorf("failed to merge global context: %w", ErrContextConflict)
+	failedUpdate, err := service.RecordFailure(context.Background(), update, cause)
+
+	// A REJECT conflict fails the same way on every attempt, so it skips the backoff loop
+	assert.NoError(t, err)
+	assert.Equal(t, model.FailedWorkflowUpdateStatusDeadLettered, failedUpdate.Status)
+	assert.Equal(t, 1, failedUpdate.Attempts)
+	assert.NotNil(t, failedUpdate.DeadLetteredAt)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFailedWorkflowUpdateService_RecordRetryFailure(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	t.Run("Reschedules With Backoff", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewFailedWorkflowUpdateService(db, policy)
+		failedUpdate := &model.FailedWorkflowUpdate{BaseModel: model.BaseModel{ID: uuid.New()}, Status: model.FailedWorkflowUpdateStatusPending, Attempts: 1}
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`UPDATE "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		before := time.Now().UTC()
+		err := service.RecordRetryFailure(context.Background(), failedUpdate, errors.New("still failing"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.FailedWorkflowUpdateStatusPending, failedUpdate.Status)
+		assert.Equal(t, 2, failedUpdate.Attempts)
+		assert.False(t, failedUpdate.NextAttemptAt.Before(before.Add(2*time.Minute)))
+		assert.Nil(t, failedUpdate.DeadLetteredAt)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Dead Letters After Max Attempts", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewFailedWorkflowUpdateService(db, policy)
+		failedUpdate := &model.FailedWorkflowUpdate{BaseModel: model.BaseModel{ID: uuid.New()}, Status: model.FailedWorkflowUpdateStatusPending, Attempts: 2}
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`UPDATE "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		err := service.RecordRetryFailure(context.Background(), failedUpdate, errors.New("still failing"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.FailedWorkflowUpdateStatusDeadLettered, failedUpdate.Status)
+		assert.Equal(t, 3, failedUpdate.Attempts)
+		assert.NotNil(t, failedUpdate.DeadLetteredAt)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Dead Letters Context Conflict", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewFailedWorkflowUpdateService(db, policy)
+		failedUpdate := &model.FailedWorkflowUpdate{BaseModel: model.BaseModel{ID: uuid.New()}, Status: model.FailedWorkflowUpdateStatusPending}
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`UPDATE "failed_workflow_updates"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		err := service.RecordRetryFailure(context.Background(), failedUpdate, fmt.Errorf("failed to merge global context: %w", ErrContextConflict))
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.FailedWorkflowUpdateStatusDeadLettered, failedUpdate.Status)
+		assert.Equal(t, 1, failedUpdate.Attempts)
+		assert.NotNil(t, failedUpdate.DeadLetteredAt)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestFailedWorkflowUpdateService_ReplayFailedUpdate(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"id", "workflow_node_id", "state", "status", "attempts"}
+
+	t.Run("Success", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewFailedWorkflowUpdateService(db, DefaultRetryPolicy())
+		id := uuid.New()
+		nodeID := uuid.New()
+
+		sqlMock.ExpectQuery(`SELECT \* FROM "failed_workflow_updates" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(id, nodeID, "COMPLETED", "DEAD_LETTERED", 8))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`UPDATE "failed_workflow_updates" SET .* WHERE id = \$\d+ AND status = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+		sqlMock.ExpectQuery(`SELECT \* FROM "failed_workflow_updates" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(id, nodeID, "COMPLETED", "PENDING", 0))
+
+		failedUpdate, err := service.ReplayFailedUpdate(ctx, id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.FailedWorkflowUpdateStatusPending, failedUpdate.Status)
+		assert.Equal(t, 0, failedUpdate.Attempts)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Dead Lettered", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewFailedWorkflowUpdateService(db, DefaultRetryPolicy())
+		id := uuid.New()
+
+		sqlMock.ExpectQuery(`SELECT \* FROM "failed_workflow_updates" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(id, uuid.New(), "COMPLETED", "PENDING", 1))
+
+		_, err := service.ReplayFailedUpdate(ctx, id)
+
+		assert.ErrorIs(t, err, ErrFailedUpdateNotDeadLettered)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewFailedWorkflowUpdateService(db, DefaultRetryPolicy())
+
+		sqlMock.ExpectQuery(`SELECT \* FROM "failed_workflow_updates" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := service.ReplayFailedUpdate(ctx, uuid.New())
+
+		assert.ErrorIs(t, err, ErrFailedUpdateNotFound)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestFailedWorkflowUpdateService_DiscardFailedUpdate(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	service := NewFailedWorkflowUpdateService(db, DefaultRetryPolicy())
+	id := uuid.New()
+	nodeID := uuid.New()
+	columns := []string{"id", "workflow_node_id", "state", "status"}
+
+	sqlMock.ExpectQuery(`SELECT \* FROM "failed_workflow_updates" WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(id, nodeID, "COMPLETED", "DEAD_LETTERED"))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`UPDATE "failed_workflow_updates" SET .* WHERE id = \$\d+ AND status = \$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectCommit()
+
+	_, err := service.DiscardFailedUpdate(context.Background(), id)
+
+	assert.ErrorIs(t, err, ErrFailedUpdateNotDeadLettered)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
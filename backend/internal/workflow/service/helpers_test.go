@@ -29,3 +29,15 @@ func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
 
 	return gdb, mock
 }
+
+// expectWorkflowEvents expects the workflow events of one change to be recorded.
+func expectWorkflowEvents(sqlMock sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+	return sqlMock.ExpectQuery(`INSERT INTO "workflow_events"`).
+		WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
+}
+
+// expectContextChanges expects the context keys changed by one append to be recorded.
+func expectContextChanges(sqlMock sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+	return sqlMock.ExpectQuery(`INSERT INTO "workflow_context_changes"`).
+		WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
+}
@@ -23,30 +23,35 @@ func NewHSCodeService(db *gorm.DB) *HSCodeService {
 	}
 }
 
-// GetAllHSCodes retrieves all HS codes from the database
+// GetAllHSCodes retrieves all HS codes from the database, ordered by code. If filter.Cursor is set, the page
+// starts after it instead of at the offset, and the total count is not computed.
 func (s *HSCodeService) GetAllHSCodes(ctx context.Context, filter model.HSCodeFilter) (*model.HSCodeListResult, error) {
-	// Get total count first for pagination (with filter applied)
-	var totalCount int64
-	countQuery := s.db.WithContext(ctx).Model(&model.HSCode{})
-
-	// Apply the same filter to the count query
-	if filter.HSCodeStartsWith != nil && *filter.HSCodeStartsWith != "" {
-		countQuery = countQuery.Where("hs_code LIKE ?", *filter.HSCodeStartsWith+"%")
-	}
+	// Get total count first for pagination (with filter applied); keyset pages skip it to avoid a full scan
+	var totalCount *int64
+	if filter.Cursor == nil {
+		var count int64
+		countQuery := s.db.WithContext(ctx).Model(&model.HSCode{})
+
+		// Apply the same filter to the count query
+		if filter.HSCodeStartsWith != nil && *filter.HSCodeStartsWith != "" {
+			countQuery = countQuery.Where("hs_code LIKE ?", *filter.HSCodeStartsWith+"%")
+		}
 
-	countResult := countQuery.Count(&totalCount)
-	if countResult.Error != nil {
-		return nil, fmt.Errorf("failed to count HS codes: %w", countResult.Error)
-	}
+		countResult := countQuery.Count(&count)
+		if countResult.Error != nil {
+			return nil, fmt.Errorf("failed to count HS codes: %w", countResult.Error)
+		}
 
-	// If no HS codes found, return early
-	if totalCount == 0 {
-		return &model.HSCodeListResult{
-			TotalCount: 0,
-			Items:      []model.HSCode{},
-			Offset:     0,
-			Limit:      0,
-		}, nil
+		// If no HS codes found, return early
+		if count == 0 {
+			return &model.HSCodeListResult{
+				TotalCount: &count,
+				Items:      []model.HSCode{},
+				Offset:     0,
+				Limit:      0,
+			}, nil
+		}
+		totalCount = &count
 	}
 
 	var hsCodes []model.HSCode
@@ -58,8 +63,14 @@ func (s *HSCodeService) GetAllHSCodes(ctx context.Context, filter model.HSCodeFi
 	}
 
 	// Apply pagination with defaults and limits
+	// HS codes are unique, so the code alone orders them totally and positions the cursor
 	finalOffset, finalLimit := utils.GetPaginationParams(filter.Offset, filter.Limit)
-	query = query.Offset(finalOffset).Limit(finalLimit)
+	if filter.Cursor != nil {
+		finalOffset = 0
+		query = query.Where("hs_code > ?", filter.Cursor.Key)
+	}
+	// One HS code more than the limit is fetched to tell whether there is a next page
+	query = query.Offset(finalOffset).Limit(finalLimit + 1)
 
 	// Add ordering for consistent pagination
 	query = query.Order("hs_code ASC")
@@ -68,6 +79,9 @@ func (s *HSCodeService) GetAllHSCodes(ctx context.Context, filter model.HSCodeFi
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to retrieve HS codes: %w", result.Error)
 	}
+	hsCodes, nextCursor := utils.KeysetPage(hsCodes, finalLimit, func(h *model.HSCode) utils.Cursor {
+		return utils.Cursor{Key: h.HSCode, ID: h.ID}
+	})
 
 	// Prepare the result
 	hsCodeListResult := &model.HSCodeListResult{
@@ -75,6 +89,7 @@ func (s *HSCodeService) GetAllHSCodes(ctx context.Context, filter model.HSCodeFi
 		Items:      hsCodes,
 		Offset:     finalOffset,
 		Limit:      finalLimit,
+		NextCursor: nextCursor,
 	}
 
 	return hsCodeListResult, nil
@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
 )
 
 func TestHSCodeService_GetAllHSCodes(t *testing.T) {
@@ -26,14 +27,16 @@ func TestHSCodeService_GetAllHSCodes(t *testing.T) {
 
 		// Find query
 		sqlMock.ExpectQuery(`SELECT \* FROM "hs_codes" ORDER BY hs_code ASC LIMIT \$1`).
-			WithArgs(50). // Default limit
+			WithArgs(51). // Default limit, and one more to tell whether there is a next page
 			WillReturnRows(sqlmock.NewRows([]string{"id", "hs_code"}).
 				AddRow(uuid.New(), "1234.56").
 				AddRow(uuid.New(), "7890.12"))
 
 		result, err := service.GetAllHSCodes(ctx, filter)
 		assert.NoError(t, err)
-		assert.Equal(t, int64(2), result.TotalCount)
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(2), *result.TotalCount)
+		}
 		assert.Len(t, result.Items, 2)
 	})
 
@@ -50,14 +53,40 @@ func TestHSCodeService_GetAllHSCodes(t *testing.T) {
 
 		// Find query with filter
 		sqlMock.ExpectQuery(`SELECT \* FROM "hs_codes" WHERE hs_code LIKE \$1 ORDER BY hs_code ASC LIMIT \$2`).
-			WithArgs("12%", 50).
+			WithArgs("12%", 51).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "hs_code"}).
 				AddRow(uuid.New(), "1234.56"))
 
 		result, err := service.GetAllHSCodes(ctx, filter)
 		assert.NoError(t, err)
-		assert.Equal(t, int64(1), result.TotalCount)
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(1), *result.TotalCount)
+		}
+		assert.Len(t, result.Items, 1)
+	})
+
+	t.Run("Success - With Cursor", func(t *testing.T) {
+		limit := 1
+		cursor := utils.Cursor{Key: "1234.56", ID: uuid.New()}
+		filter := model.HSCodeFilter{Limit: &limit, Cursor: &cursor}
+
+		// Find query after the cursor, with one HS code more than the limit; keyset pages are not counted
+		nextID := uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "hs_codes" WHERE hs_code > \$1 ORDER BY hs_code ASC LIMIT \$2`).
+			WithArgs("1234.56", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hs_code"}).
+				AddRow(nextID, "7890.12").
+				AddRow(uuid.New(), "9012.34"))
+
+		result, err := service.GetAllHSCodes(ctx, filter)
+		assert.NoError(t, err)
 		assert.Len(t, result.Items, 1)
+		assert.Nil(t, result.TotalCount)
+		if assert.NotNil(t, result.NextCursor) {
+			next, err := utils.DecodeCursor(*result.NextCursor)
+			assert.NoError(t, err)
+			assert.Equal(t, utils.Cursor{Key: "7890.12", ID: nextID}, *next)
+		}
 	})
 
 	t.Run("Success - Empty Result", func(t *testing.T) {
@@ -71,7 +100,9 @@ func TestHSCodeService_GetAllHSCodes(t *testing.T) {
 
 		result, err := service.GetAllHSCodes(ctx, filter)
 		assert.NoError(t, err)
-		assert.Equal(t, int64(0), result.TotalCount)
+		if assert.NotNil(t, result.TotalCount) {
+			assert.Equal(t, int64(0), *result.TotalCount)
+		}
 		assert.Empty(t, result.Items)
 	})
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +19,16 @@ import (
 	"github.com/OpenNSW/nsw/utils"
 )
 
+var (
+	// ErrPreConsignmentNotFound is returned when a pre-consignment does not exist or is not owned by the trader.
+	ErrPreConsignmentNotFound = errors.New("pre-consignment not found")
+	// ErrPreConsignmentNotRenewable is returned when renewing a pre-consignment that is not completed, expired or
+	// revoked, or that was already renewed.
+	ErrPreConsignmentNotRenewable = errors.New("pre-consignment cannot be renewed")
+	// ErrPreConsignmentNotRevocable is returned when revoking a pre-consignment that is not completed or expired.
+	ErrPreConsignmentNotRevocable = errors.New("pre-consignment is not completed and cannot be revoked")
+)
+
 // PreConsignmentService provides operations related to pre-consignments.
 type PreConsignmentService struct {
 	db                          *gorm.DB
@@ -24,6 +36,7 @@ type PreConsignmentService struct {
 	nodeRepo                    WorkflowNodeRepository
 	stateMachine                *WorkflowNodeStateMachine
 	preCommitValidationCallback func([]model.WorkflowNode, map[string]any) error
+	subWorkflowCallback         func(context.Context, []model.WorkflowNode)
 }
 
 // SetPreCommitValidationCallback sets a callback to be executed before transaction commit
@@ -32,6 +45,13 @@ func (s *PreConsignmentService) SetPreCommitValidationCallback(callback func([]m
 	s.preCommitValidationCallback = callback
 }
 
+// SetPostSubWorkflowCompletionCallback sets a callback to be executed after a node update is committed, with the
+// SUB_WORKFLOW nodes that were completed because their child workflow finished
+// This allows the tasks of those nodes to be completed as well
+func (s *PreConsignmentService) SetPostSubWorkflowCompletionCallback(callback func(context.Context, []model.WorkflowNode)) {
+	s.subWorkflowCallback = callback
+}
+
 // NewPreConsignmentService creates a new instance of PreConsignmentService with the provided dependencies.
 func NewPreConsignmentService(db *gorm.DB, templateProvider TemplateProvider, nodeRepo WorkflowNodeRepository) *PreConsignmentService {
 	return &PreConsignmentService{
@@ -43,49 +63,71 @@ func NewPreConsignmentService(db *gorm.DB, templateProvider TemplateProvider, no
 }
 
 // GetTraderPreConsignments retrieves a paginated list of pre-consignment templates and computes their state
-// based on the trader's existing pre-consignments and their dependencies.
-func (s *PreConsignmentService) GetTraderPreConsignments(ctx context.Context, traderID string, offset *int, limit *int) (model.TraderPreConsignmentsResponseDTO, error) {
+// based on the trader's existing pre-consignments and their dependencies. The templates are ordered by name; if
+// cursor is set, the page starts after it instead of at offset, and the total count is not computed.
+func (s *PreConsignmentService) GetTraderPreConsignments(ctx context.Context, traderID string, offset *int, limit *int, cursor *utils.Cursor) (model.TraderPreConsignmentsResponseDTO, error) {
 	// Apply pagination with defaults and limits
 	finalOffset, finalLimit := utils.GetPaginationParams(offset, limit)
-
-	// Get total count of templates first for pagination
-	var totalCount int64
-	if err := s.db.WithContext(ctx).Model(&model.PreConsignmentTemplate{}).Count(&totalCount).Error; err != nil {
-		return model.TraderPreConsignmentsResponseDTO{}, fmt.Errorf("failed to count pre-consignment templates: %w", err)
+	if cursor != nil {
+		finalOffset = 0
 	}
 
-	if totalCount == 0 {
-		return model.TraderPreConsignmentsResponseDTO{
-			TotalCount: 0,
-			Items:      []model.TraderPreConsignmentResponseDTO{},
-			Offset:     int64(finalOffset),
-			Limit:      int64(finalLimit),
-		}, nil
+	// Get total count of templates first for pagination; keyset pages skip it to avoid a full scan
+	var totalCount *int64
+	if cursor == nil {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&model.PreConsignmentTemplate{}).Count(&count).Error; err != nil {
+			return model.TraderPreConsignmentsResponseDTO{}, fmt.Errorf("failed to count pre-consignment templates: %w", err)
+		}
+
+		if count == 0 {
+			return model.TraderPreConsignmentsResponseDTO{
+				TotalCount: &count,
+				Items:      []model.TraderPreConsignmentResponseDTO{},
+				Offset:     int64(finalOffset),
+				Limit:      int64(finalLimit),
+			}, nil
+		}
+		totalCount = &count
 	}
 
-	// Fetch pre-consignment templates for the current page
+	// Fetch pre-consignment templates for the current page, and one more to tell whether there is a next page
 	var templates []model.PreConsignmentTemplate
-	if err := s.db.WithContext(ctx).
-		Order("name ASC").
+	templateQuery := s.db.WithContext(ctx)
+	if cursor != nil {
+		templateQuery = templateQuery.Where("(name, id) > (?, ?)", cursor.Key, cursor.ID)
+	}
+	if err := templateQuery.
+		Order("name ASC, id ASC").
 		Offset(finalOffset).
-		Limit(finalLimit).
+		Limit(finalLimit + 1).
 		Find(&templates).Error; err != nil {
 		return model.TraderPreConsignmentsResponseDTO{}, fmt.Errorf("failed to retrieve pre-consignment templates: %w", err)
 	}
+	templates, nextCursor := utils.KeysetPage(templates, finalLimit, func(t *model.PreConsignmentTemplate) utils.Cursor {
+		return utils.Cursor{Key: t.Name, ID: t.ID}
+	})
 
 	// Fetch all existing pre-consignments for this trader to determine dependency satisfaction and current states
 	var preConsignments []model.PreConsignment
 	if err := s.db.WithContext(ctx).
 		Where("trader_id = ?", traderID).
+		Order("created_at ASC").
 		Find(&preConsignments).Error; err != nil {
 		return model.TraderPreConsignmentsResponseDTO{}, fmt.Errorf("failed to retrieve completed pre-consignments for trader %s: %w", traderID, err)
 	}
 
-	// Build a set of template IDs to PreConsignment for quick lookup
+	// Build a set of template IDs to the latest PreConsignment for quick lookup; renewals replace earlier ones.
+	// Dependencies are satisfied by the latest finished one, since a renewal in progress does not replace it yet.
 	templateIDToPreConsignment := make(map[uuid.UUID]model.PreConsignment)
+	templateIDToFinished := make(map[uuid.UUID]model.PreConsignment)
 	for _, pc := range preConsignments {
 		templateIDToPreConsignment[pc.PreConsignmentTemplateID] = pc
+		if slices.Contains(finishedPreConsignmentStates, pc.State) {
+			templateIDToFinished[pc.PreConsignmentTemplateID] = pc
+		}
 	}
+	now := time.Now().UTC()
 
 	// Build response DTOs with computed state ONLY for the fetched templates (the current page)
 	responseDTOs := make([]model.TraderPreConsignmentResponseDTO, 0, len(templates))
@@ -96,7 +138,7 @@ func (s *PreConsignmentService) GetTraderPreConsignments(ctx context.Context, tr
 				Name:           template.Name,
 				Description:    template.Description,
 				DependsOn:      template.DependsOn,
-				State:          pc.State,
+				State:          pc.EffectiveState(now),
 				PreConsignment: &pc,
 			})
 			continue
@@ -110,7 +152,7 @@ func (s *PreConsignmentService) GetTraderPreConsignments(ctx context.Context, tr
 					state = model.PreConsignmentStateLocked
 					break
 				}
-				if depPC, exists := templateIDToPreConsignment[depID]; !exists || depPC.State != model.PreConsignmentStateCompleted {
+				if depPC, exists := templateIDToFinished[depID]; !exists || !depPC.IsValid(now) {
 					state = model.PreConsignmentStateLocked
 					break
 				}
@@ -136,6 +178,7 @@ func (s *PreConsignmentService) GetTraderPreConsignments(ctx context.Context, tr
 		Items:      responseDTOs,
 		Offset:     int64(finalOffset),
 		Limit:      int64(finalLimit),
+		NextCursor: nextCursor,
 	}, nil
 }
 
@@ -158,15 +201,16 @@ func (s *PreConsignmentService) InitializePreConsignment(
 		initialTraderContext = make(map[string]any)
 	}
 
-	return s.initializePreConsignmentInTx(ctx, createReq, traderId, initialTraderContext)
+	return s.initializePreConsignmentInTx(ctx, createReq, traderId, initialTraderContext, nil)
 }
 
-// initializePreConsignmentInTx initializes the pre-consignment within a transaction.
+// initializePreConsignmentInTx initializes the pre-consignment, or the renewal of renewedFromID, within a transaction.
 func (s *PreConsignmentService) initializePreConsignmentInTx(
 	ctx context.Context,
 	createReq *model.CreatePreConsignmentDTO,
 	traderId string,
 	initialTraderContext map[string]any,
+	renewedFromID *uuid.UUID,
 ) (*model.PreConsignmentResponseDTO, []model.WorkflowNode, error) {
 	// Get pre-consignment template
 	var pcTemplate model.PreConsignmentTemplate
@@ -174,17 +218,26 @@ func (s *PreConsignmentService) initializePreConsignmentInTx(
 		return nil, nil, fmt.Errorf("pre-consignment template %s not found: %w", createReq.PreConsignmentTemplateID, err)
 	}
 
-	// Validate dependencies are met
+	// Validate dependencies are met by the latest finished pre-consignments, which must not have expired or
+	// been revoked
 	if len(pcTemplate.DependsOn) > 0 {
-		var completedCount int64
-		if err := s.db.WithContext(ctx).Model(&model.PreConsignment{}).
-			Where("trader_id = ? AND pre_consignment_template_id IN ? AND state = ?",
-				traderId, pcTemplate.DependsOn, model.PreConsignmentStateCompleted).
-			Count(&completedCount).Error; err != nil {
+		dependencyIDs := make([]uuid.UUID, 0, len(pcTemplate.DependsOn))
+		for _, idStr := range pcTemplate.DependsOn {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("pre-consignment template %s has invalid dependency %q", pcTemplate.ID, idStr)
+			}
+			dependencyIDs = append(dependencyIDs, id)
+		}
+		dependencies, err := latestFinishedPreConsignments(ctx, s.db, traderId, dependencyIDs)
+		if err != nil {
 			return nil, nil, fmt.Errorf("failed to check dependency completion: %w", err)
 		}
-		if int(completedCount) < len(pcTemplate.DependsOn) {
-			return nil, nil, fmt.Errorf("dependency pre-consignments are not all completed")
+		now := time.Now().UTC()
+		for _, id := range dependencyIDs {
+			if pc := dependencies[id]; pc == nil || !pc.IsValid(now) {
+				return nil, nil, fmt.Errorf("dependency pre-consignments are not all completed and valid")
+			}
 		}
 	}
 
@@ -209,12 +262,20 @@ func (s *PreConsignmentService) initializePreConsignmentInTx(
 		}
 	}()
 
+	if renewedFromID != nil {
+		if err := checkNotRenewedInTx(ctx, tx, *renewedFromID); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+
 	// Create pre-consignment record
 	preConsignment := &model.PreConsignment{
 		TraderID:                 traderId,
 		PreConsignmentTemplateID: createReq.PreConsignmentTemplateID,
 		State:                    model.PreConsignmentStateInProgress,
 		TraderContext:            initialTraderContext,
+		RenewedFromID:            renewedFromID,
 	}
 	if err := tx.Create(preConsignment).Error; err != nil {
 		tx.Rollback()
@@ -223,7 +284,7 @@ func (s *PreConsignmentService) initializePreConsignmentInTx(
 
 	// Create workflow nodes using the state machine
 	_, newReadyWorkflowNodes, _, err := s.stateMachine.InitializeNodesFromTemplates(
-		ctx, tx, ParentRef{PreConsignmentID: &preConsignment.ID}, nodeTemplates, []model.WorkflowTemplate{*workflowTemplate},
+		ctx, tx, ParentRef{PreConsignmentID: &preConsignment.ID, Context: preConsignment.TraderContext}, nodeTemplates, []model.WorkflowTemplate{*workflowTemplate},
 	)
 	if err != nil {
 		tx.Rollback()
@@ -312,7 +373,7 @@ func (s *PreConsignmentService) UpdateWorkflowNodeStateAndPropagateChanges(ctx c
 		}
 	}()
 
-	newReadyNodes, traderContext, err := s.updateWorkflowNodeStateAndPropagateChangesInTx(ctx, tx, updateReq)
+	newReadyNodes, traderContext, completedSubWorkflowNodes, err := s.updateWorkflowNodeStateAndPropagateChangesInTx(ctx, tx, updateReq)
 	if err != nil {
 		tx.Rollback()
 		return nil, nil, fmt.Errorf("failed to update workflow node state: %w", err)
@@ -323,29 +384,36 @@ func (s *PreConsignmentService) UpdateWorkflowNodeStateAndPropagateChanges(ctx c
 		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	if s.subWorkflowCallback != nil && len(completedSubWorkflowNodes) > 0 {
+		s.subWorkflowCallback(ctx, completedSubWorkflowNodes)
+	}
+
 	return newReadyNodes, traderContext, nil
 }
 
-// updateWorkflowNodeStateAndPropagateChangesInTx handles state transitions within a transaction.
-func (s *PreConsignmentService) updateWorkflowNodeStateAndPropagateChangesInTx(ctx context.Context, tx *gorm.DB, updateReq *model.UpdateWorkflowNodeDTO) ([]model.WorkflowNode, map[string]any, error) {
+// updateWorkflowNodeStateAndPropagateChangesInTx handles state transitions within a transaction. Returns the new
+// READY nodes, the updated trader context and the SUB_WORKFLOW nodes that were completed.
+func (s *PreConsignmentService) updateWorkflowNodeStateAndPropagateChangesInTx(ctx context.Context, tx *gorm.DB, updateReq *model.UpdateWorkflowNodeDTO) ([]model.WorkflowNode, map[string]any, []model.WorkflowNode, error) {
 	workflowNode, err := s.nodeRepo.GetWorkflowNodeByIDInTx(ctx, tx, updateReq.WorkflowNodeID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to retrieve workflow node with ID %s: %w", updateReq.WorkflowNodeID, err)
+		return nil, nil, nil, fmt.Errorf("failed to retrieve workflow node with ID %s: %w", updateReq.WorkflowNodeID, err)
 	}
 
-	var newReadyNodes []model.WorkflowNode
+	var newReadyNodes, completedSubWorkflowNodes []model.WorkflowNode
+	started := false
 
 	switch updateReq.State {
 	case model.WorkflowNodeStateFailed:
 		if workflowNode.State != model.WorkflowNodeStateFailed {
 			if err := s.stateMachine.TransitionToFailed(ctx, tx, workflowNode, updateReq); err != nil {
-				return nil, nil, fmt.Errorf("failed to transition node to FAILED: %w", err)
+				return nil, nil, nil, fmt.Errorf("failed to transition node to FAILED: %w", err)
 			}
 		}
 
 	case model.WorkflowNodeStateInProgress:
+		started = workflowNode.State == model.WorkflowNodeStateReady
 		if err := s.stateMachine.TransitionToInProgress(ctx, tx, workflowNode, updateReq); err != nil {
-			return nil, nil, fmt.Errorf("failed to transition node to IN_PROGRESS: %w", err)
+			return nil, nil, nil, fmt.Errorf("failed to transition node to IN_PROGRESS: %w", err)
 		}
 
 	case model.WorkflowNodeStateCompleted:
@@ -353,54 +421,89 @@ func (s *PreConsignmentService) updateWorkflowNodeStateAndPropagateChangesInTx(c
 		// This must happen BEFORE marking pre-consignment as complete
 		// to ensure the latest context is synced to auth
 		var traderContext map[string]any
-		traderContext, err = s.appendToPreConsignmentTraderContext(ctx, tx, *workflowNode.PreConsignmentID, updateReq.AppendGlobalContext)
+		traderContext, err = s.appendToPreConsignmentTraderContext(ctx, tx, workflowNode, updateReq.AppendGlobalContext)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		if workflowNode.State != model.WorkflowNodeStateCompleted {
 			if workflowNode.PreConsignmentID == nil {
-				return nil, nil, fmt.Errorf("node %s is not associated with a pre-consignment", workflowNode.ID)
+				return nil, nil, nil, fmt.Errorf("node %s is not associated with a pre-consignment", workflowNode.ID)
 			}
 
-			result, err := s.stateMachine.TransitionToCompleted(ctx, tx, workflowNode, updateReq)
+			result, err := s.stateMachine.TransitionToCompleted(ctx, tx, workflowNode, updateReq, traderContext)
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to transition node to COMPLETED: %w", err)
+				return nil, nil, nil, fmt.Errorf("failed to transition node to COMPLETED: %w", err)
 			}
 			newReadyNodes = result.NewReadyNodes
+			completedSubWorkflowNodes = result.CompletedSubWorkflowNodes
 
 			// Mark pre-consignment as completed if all nodes are done
 			// This will sync the updated trader context to auth
 			if result.WorkflowFinished {
 				if err := s.markPreConsignmentAsCompleted(ctx, tx, *workflowNode.PreConsignmentID); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 			}
 		}
 
-		return newReadyNodes, traderContext, nil
+		return newReadyNodes, traderContext, completedSubWorkflowNodes, nil
 	}
 
 	// Handle trader context updates for non-completed states
 	var traderContext map[string]any
-	traderContext, err = s.appendToPreConsignmentTraderContext(ctx, tx, *workflowNode.PreConsignmentID, updateReq.AppendGlobalContext)
+	traderContext, err = s.appendToPreConsignmentTraderContext(ctx, tx, workflowNode, updateReq.AppendGlobalContext)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	return newReadyNodes, traderContext, nil
+	// A SUB_WORKFLOW node that started creates its child workflow
+	if started {
+		childReadyNodes, err := startSubWorkflowInTx(ctx, tx, s.templateProvider, s.stateMachine, workflowNode, ParentRef{
+			PreConsignmentID: workflowNode.PreConsignmentID,
+			Context:          traderContext,
+		})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to start sub-workflow of node %s: %w", workflowNode.ID, err)
+		}
+		newReadyNodes = append(newReadyNodes, childReadyNodes...)
+	}
+
+	// Unlock conditions may test the trader context, so a change to it can unlock nodes even if no node completed
+	if len(updateReq.AppendGlobalContext) > 0 {
+		result, err := s.stateMachine.ReevaluateUnlocks(ctx, tx, workflowNode, traderContext)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to re-evaluate unlocks after trader context update: %w", err)
+		}
+		newReadyNodes = append(newReadyNodes, result.NewReadyNodes...)
+		completedSubWorkflowNodes = append(completedSubWorkflowNodes, result.CompletedSubWorkflowNodes...)
+
+		if result.WorkflowFinished {
+			if err := s.markPreConsignmentAsCompleted(ctx, tx, *workflowNode.PreConsignmentID); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+
+	return newReadyNodes, traderContext, completedSubWorkflowNodes, nil
 }
 
-// markPreConsignmentAsCompleted updates the pre-consignment state to COMPLETED.
+// markPreConsignmentAsCompleted updates the pre-consignment state to COMPLETED and sets its expiry from the
+// validity of its template.
 func (s *PreConsignmentService) markPreConsignmentAsCompleted(ctx context.Context, tx *gorm.DB, preConsignmentID uuid.UUID) error {
 	var preConsignment model.PreConsignment
-	result := tx.WithContext(ctx).First(&preConsignment, "id = ?", preConsignmentID)
+	result := tx.WithContext(ctx).Preload("PreConsignmentTemplate").First(&preConsignment, "id = ?", preConsignmentID)
 	if result.Error != nil {
 		return fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, result.Error)
 	}
 
 	preConsignment.State = model.PreConsignmentStateCompleted
-	if err := tx.WithContext(ctx).Save(&preConsignment).Error; err != nil {
+	if validity := preConsignment.PreConsignmentTemplate.Validity; validity != nil {
+		preConsignment.ExpiresAt = validity.ExpiresAt(time.Now().UTC(), preConsignment.TraderContext)
+	}
+	// Only the state and expiry are written, so that a trader context appended in parallel is not overwritten
+	if err := tx.WithContext(ctx).Model(&preConsignment).Omit(clause.Associations).
+		Select("state", "expires_at").Updates(&preConsignment).Error; err != nil {
 		return fmt.Errorf("failed to update pre-consignment %s state to COMPLETED: %w", preConsignmentID, err)
 	}
 
@@ -471,24 +574,196 @@ func (s *PreConsignmentService) syncTraderContextToAuth(ctx context.Context, tx
 	return nil
 }
 
-// appendToPreConsignmentTraderContext appends key-value pairs to the pre-consignment's trader context.
-func (s *PreConsignmentService) appendToPreConsignmentTraderContext(ctx context.Context, tx *gorm.DB, preConsignmentID uuid.UUID, appendContext map[string]any) (map[string]any, error) {
+// checkNotRenewedInTx locks the pre-consignment being renewed and checks that no later pre-consignment of its
+// template exists. Concurrent renewals of the same pre-consignment wait for the lock, so only the first succeeds.
+func checkNotRenewedInTx(ctx context.Context, tx *gorm.DB, preConsignmentID uuid.UUID) error {
+	var previous model.PreConsignment
+	if err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, "id = ?", preConsignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPreConsignmentNotFound
+		}
+		return fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, err)
+	}
+
+	var laterCount int64
+	if err := tx.WithContext(ctx).Model(&model.PreConsignment{}).
+		Where("trader_id = ? AND pre_consignment_template_id = ? AND created_at > ?", previous.TraderID, previous.PreConsignmentTemplateID, previous.CreatedAt).
+		Count(&laterCount).Error; err != nil {
+		return fmt.Errorf("failed to check for later pre-consignments: %w", err)
+	}
+	if laterCount > 0 {
+		return fmt.Errorf("%w: pre-consignment %s was already renewed", ErrPreConsignmentNotRenewable, preConsignmentID)
+	}
+	return nil
+}
+
+// finishedPreConsignmentStates are the states of pre-consignments whose workflow has completed.
+var finishedPreConsignmentStates = []model.PreConsignmentState{
+	model.PreConsignmentStateCompleted,
+	model.PreConsignmentStateExpired,
+	model.PreConsignmentStateRevoked,
+}
+
+// latestFinishedPreConsignments returns the latest finished pre-consignment of each of the given templates of the
+// trader, keyed by template ID. Only that one decides whether the trader holds a valid pre-consignment of the
+// template: a renewal supersedes its predecessor once it completes, so that revoking the renewal is not undone
+// by the predecessor still being valid.
+func latestFinishedPreConsignments(ctx context.Context, db *gorm.DB, traderID string, templateIDs []uuid.UUID) (map[uuid.UUID]*model.PreConsignment, error) {
+	var preConsignments []model.PreConsignment
+	if err := db.WithContext(ctx).
+		Where("trader_id = ? AND pre_consignment_template_id IN ? AND state IN ?", traderID, templateIDs, finishedPreConsignmentStates).
+		Order("created_at DESC, id DESC").
+		Find(&preConsignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve finished pre-consignments: %w", err)
+	}
+	latest := make(map[uuid.UUID]*model.PreConsignment, len(templateIDs))
+	for i := range preConsignments {
+		if _, ok := latest[preConsignments[i].PreConsignmentTemplateID]; !ok {
+			latest[preConsignments[i].PreConsignmentTemplateID] = &preConsignments[i]
+		}
+	}
+	return latest, nil
+}
+
+// ExpirePreConsignments marks the COMPLETED pre-consignments whose expiry passed at now as EXPIRED.
+// Returns the number of pre-consignments that expired.
+func (s *PreConsignmentService) ExpirePreConsignments(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&model.PreConsignment{}).
+		Where("state = ? AND expires_at <= ?", model.PreConsignmentStateCompleted, now).
+		Updates(map[string]any{
+			"state":      model.PreConsignmentStateExpired,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to expire pre-consignments: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RenewPreConsignment starts a new pre-consignment from the template of one of the trader's completed, expired
+// or revoked pre-consignments. Its trader context is the trader's context with the previous pre-consignment's
+// trader context on top, so that forms are pre-filled with what was submitted before. The previous
+// pre-consignment keeps its state, and keeps satisfying its dependents until the renewal completes; only the
+// latest pre-consignment of a template can be renewed.
+// Returns the new pre-consignment and its READY workflow nodes.
+func (s *PreConsignmentService) RenewPreConsignment(ctx context.Context, preConsignmentID uuid.UUID, traderID string, traderContext map[string]any) (*model.PreConsignmentResponseDTO, []model.WorkflowNode, error) {
+	var previous model.PreConsignment
+	if err := s.db.WithContext(ctx).First(&previous, "id = ?", preConsignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPreConsignmentNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, err)
+	}
+	if previous.TraderID != traderID {
+		return nil, nil, ErrPreConsignmentNotFound
+	}
+
+	switch previous.State {
+	case model.PreConsignmentStateCompleted, model.PreConsignmentStateExpired, model.PreConsignmentStateRevoked:
+	default:
+		return nil, nil, fmt.Errorf("%w: pre-consignment %s is %s", ErrPreConsignmentNotRenewable, preConsignmentID, previous.State)
+	}
+
+	initialTraderContext := maps.Clone(traderContext)
+	if initialTraderContext == nil {
+		initialTraderContext = make(map[string]any, len(previous.TraderContext))
+	}
+	maps.Copy(initialTraderContext, previous.TraderContext)
+
+	return s.initializePreConsignmentInTx(ctx, &model.CreatePreConsignmentDTO{
+		PreConsignmentTemplateID: previous.PreConsignmentTemplateID,
+	}, traderID, initialTraderContext, &previous.ID)
+}
+
+// RevokePreConsignment revokes a completed or expired pre-consignment, e.g. because the agency that issued the
+// licence suspended it. The pre-consignment no longer satisfies the pre-consignments and consignments that
+// depend on it until the trader renews it.
+func (s *PreConsignmentService) RevokePreConsignment(ctx context.Context, preConsignmentID uuid.UUID, reason string) (*model.PreConsignmentResponseDTO, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("revocation reason cannot be empty")
+	}
+
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var preConsignment model.PreConsignment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&preConsignment, "id = ?", preConsignmentID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPreConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, err)
+	}
+	if preConsignment.State != model.PreConsignmentStateCompleted && preConsignment.State != model.PreConsignmentStateExpired {
+		tx.Rollback()
+		return nil, ErrPreConsignmentNotRevocable
+	}
+
+	now := time.Now().UTC()
+	if err := tx.Model(&preConsignment).Updates(map[string]any{
+		"state":             model.PreConsignmentStateRevoked,
+		"revoked_at":        now,
+		"revocation_reason": reason,
+		"updated_at":        now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to revoke pre-consignment %s: %w", preConsignmentID, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetPreConsignmentByID(ctx, preConsignmentID)
+}
+
+// GetTraderContext retrieves the trader context of a pre-consignment.
+func (s *PreConsignmentService) GetTraderContext(ctx context.Context, preConsignmentID uuid.UUID) (map[string]any, error) {
 	var preConsignment model.PreConsignment
-	result := tx.WithContext(ctx).First(&preConsignment, "id = ?", preConsignmentID)
+	result := s.db.WithContext(ctx).Select("id", "trader_context").First(&preConsignment, "id = ?", preConsignmentID)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, result.Error)
 	}
+	return preConsignment.TraderContext, nil
+}
 
-	if preConsignment.TraderContext == nil {
-		preConsignment.TraderContext = make(map[string]any)
+// appendToPreConsignmentTraderContext merges key-value pairs from a workflow node into the trader context of
+// its pre-consignment and returns the trader context. The pre-consignment is locked while the keys are merged,
+// so that nodes appending in parallel do not overwrite each other's keys.
+func (s *PreConsignmentService) appendToPreConsignmentTraderContext(ctx context.Context, tx *gorm.DB, workflowNode *model.WorkflowNode, appendContext map[string]any) (map[string]any, error) {
+	preConsignmentID := *workflowNode.PreConsignmentID
+	query := tx.WithContext(ctx)
+	if len(appendContext) > 0 {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var preConsignment model.PreConsignment
+	if err := query.First(&preConsignment, "id = ?", preConsignmentID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, err)
+	}
+	if len(appendContext) == 0 {
+		return preConsignment.TraderContext, nil
 	}
-	maps.Copy(preConsignment.TraderContext, appendContext)
 
-	if err := tx.WithContext(ctx).Save(&preConsignment).Error; err != nil {
-		return nil, fmt.Errorf("failed to update pre-consignment %s trader context: %w", preConsignmentID, err)
+	merge, err := appendWorkflowContextInTx(ctx, tx, s.templateProvider, workflowNode, preConsignment.TraderContext, appendContext)
+	if err != nil {
+		return nil, err
+	}
+	if len(merge.patch) > 0 {
+		expr, err := jsonbMerge("trader_context", merge.patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.WithContext(ctx).Model(&preConsignment).Update("trader_context", expr).Error; err != nil {
+			return nil, fmt.Errorf("failed to update pre-consignment %s trader context: %w", preConsignmentID, err)
+		}
 	}
 
-	return preConsignment.TraderContext, nil
+	return merge.context, nil
 }
 
 // buildPreConsignmentResponseDTO builds a PreConsignmentResponseDTO from a PreConsignment with preloaded relationships.
@@ -508,6 +783,9 @@ func (s *PreConsignmentService) buildPreConsignmentResponseDTO(preConsignment *m
 			State:         node.State,
 			ExtendedState: node.ExtendedState,
 			Outcome:       node.Outcome,
+			Attempt:       node.Attempt,
+			SLADeadline:   formatOptionalTime(node.SLADeadline),
+			SLABreachedAt: formatOptionalTime(node.SLABreachedAt),
 			DependsOn:     node.DependsOn,
 		})
 	}
@@ -518,12 +796,16 @@ func (s *PreConsignmentService) buildPreConsignmentResponseDTO(preConsignment *m
 	}
 
 	return &model.PreConsignmentResponseDTO{
-		ID:            preConsignment.ID,
-		TraderID:      preConsignment.TraderID,
-		State:         preConsignment.State,
-		TraderContext: preConsignment.TraderContext,
-		CreatedAt:     preConsignment.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:     preConsignment.UpdatedAt.Format(time.RFC3339),
+		ID:               preConsignment.ID,
+		TraderID:         preConsignment.TraderID,
+		State:            preConsignment.EffectiveState(time.Now().UTC()),
+		TraderContext:    preConsignment.TraderContext,
+		ExpiresAt:        formatOptionalTime(preConsignment.ExpiresAt),
+		RevokedAt:        formatOptionalTime(preConsignment.RevokedAt),
+		RevocationReason: preConsignment.RevocationReason,
+		RenewedFromID:    preConsignment.RenewedFromID,
+		CreatedAt:        preConsignment.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        preConsignment.UpdatedAt.Format(time.RFC3339),
 		PreConsignmentTemplate: model.PreConsignmentTemplateResponseDTO{
 			ID:          preConsignment.PreConsignmentTemplate.ID,
 			Name:        preConsignment.PreConsignmentTemplate.Name,
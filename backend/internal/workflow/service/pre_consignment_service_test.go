@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql/driver"
 	"errors"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
 )
 
 func TestPreConsignmentService_InitializePreConsignment(t *testing.T) {
@@ -55,7 +57,8 @@ func TestPreConsignmentService_InitializePreConsignment(t *testing.T) {
 
 	// Create PreConsignment (Insert)
 	sqlMock.ExpectExec(`INSERT INTO "pre_consignments"`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			nil, nil, nil, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Initialize Nodes (StateMachine) -> CreateWorkflowNodesInTx
@@ -104,8 +107,8 @@ func TestPreConsignmentService_GetTraderPreConsignments(t *testing.T) {
 
 	// Find Templates
 	templateID := uuid.New()
-	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" ORDER BY name ASC LIMIT \$1`).
-		WithArgs(limit).
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" ORDER BY name ASC, id ASC LIMIT \$1`).
+		WithArgs(limit + 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Test Template"))
 
 	// Find PreConsignments for Trader
@@ -114,12 +117,46 @@ func TestPreConsignmentService_GetTraderPreConsignments(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state", "pre_consignment_template_id"}).
 			AddRow(uuid.New(), traderID, "IN_PROGRESS", templateID))
 
-	result, err := service.GetTraderPreConsignments(ctx, traderID, &offset, &limit)
+	result, err := service.GetTraderPreConsignments(ctx, traderID, &offset, &limit, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1), result.TotalCount)
+	if assert.NotNil(t, result.TotalCount) {
+		assert.Equal(t, int64(1), *result.TotalCount)
+	}
 	assert.Len(t, result.Items, 1)
 }
 
+func TestPreConsignmentService_GetTraderPreConsignments_Cursor(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	service := NewPreConsignmentService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+	ctx := context.Background()
+	traderID := "trader1"
+	limit := 1
+	cursor := utils.Cursor{Key: "Export License", ID: uuid.New()}
+
+	// Keyset pages are not counted
+	templateID := uuid.New()
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE \(name, id\) > \(\$1, \$2\) ORDER BY name ASC, id ASC LIMIT \$3`).
+		WithArgs(cursor.Key, cursor.ID, limit+1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(templateID, "Importer Registration").
+			AddRow(uuid.New(), "Tax Clearance"))
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE trader_id = \$1`).
+		WithArgs(traderID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state", "pre_consignment_template_id"}))
+
+	result, err := service.GetTraderPreConsignments(ctx, traderID, nil, &limit, &cursor)
+	assert.NoError(t, err)
+	assert.Len(t, result.Items, 1)
+	assert.Equal(t, templateID, result.Items[0].ID)
+	assert.Nil(t, result.TotalCount)
+	if assert.NotNil(t, result.NextCursor) {
+		next, err := utils.DecodeCursor(*result.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, utils.Cursor{Key: "Importer Registration", ID: templateID}, *next)
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
 func TestPreConsignmentService_GetPreConsignmentByID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTemplateProvider := new(MockTemplateProvider)
@@ -202,15 +239,14 @@ func TestPreConsignmentService_UpdateWorkflowNodeStateAndPropagateChanges(t *tes
 			return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateInProgress
 		})).Return(nil).Once()
 
-		// Append Context (Success)
-		// First(pre_consignment) for append context
-		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 ORDER BY "pre_consignments"."id" LIMIT \$2`).
+		// Load the trader context; nothing is appended, so the pre-consignment is neither locked nor updated
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 ORDER BY "pre_consignments"."id" LIMIT \$2$`).
 			WithArgs(pcID, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_context"}).AddRow(pcID, []byte("{}")))
 
-		// Save(pre_consignment)
-		sqlMock.ExpectExec(`UPDATE "pre_consignments"`).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+		// The node started, but is not a SUB_WORKFLOW node
+		mockTemplateProvider.On("GetWorkflowNodeTemplateByID", ctx, node.WorkflowNodeTemplateID).
+			Return(&model.WorkflowNodeTemplate{Type: "SIMPLE_FORM"}, nil).Once()
 
 		sqlMock.ExpectCommit()
 
@@ -218,6 +254,7 @@ func TestPreConsignmentService_UpdateWorkflowNodeStateAndPropagateChanges(t *tes
 		assert.NoError(t, err)
 
 		mockNodeRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
 	})
 
 	t.Run("Failure - Node Not Found", func(t *testing.T) {
@@ -257,13 +294,18 @@ func TestPreConsignmentService_UpdateWorkflowNodeStateAndPropagateChanges(t *tes
 		mockNodeRepo.On("GetWorkflowNodeByIDInTx", ctx, mock.Anything, nodeID).Return(node, nil).Once()
 
 		// Append Context (Pre-transition)
-		// First(pre_consignment)
-		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 ORDER BY "pre_consignments"."id" LIMIT \$2`).
+		// First(pre_consignment) FOR UPDATE
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 ORDER BY "pre_consignments"."id" LIMIT \$2 FOR UPDATE`).
 			WithArgs(pcID, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_context", "trader_id"}).AddRow(pcID, []byte(`{"initial": "val"}`), "trader1"))
-
-		// Save(pre_consignment) - Update TraderContext
-		sqlMock.ExpectExec(`UPDATE "pre_consignments"`).
+		mockTemplateProvider.On("GetWorkflowNodeTemplateByID", ctx, node.WorkflowNodeTemplateID).
+			Return(&model.WorkflowNodeTemplate{Type: "SIMPLE_FORM"}, nil).Once()
+		expectContextChanges(sqlMock)
+		expectWorkflowEvents(sqlMock)
+
+		// Merge the changed key into the trader context
+		sqlMock.ExpectExec(`UPDATE "pre_consignments" SET "trader_context"=COALESCE\(trader_context, '\{\}'::jsonb\) \|\| \$1::jsonb`).
+			WithArgs(`{"newKey":"newValue"}`, sqlmock.AnyArg(), pcID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		// Transition To Completed (StateMachine)
@@ -394,12 +436,15 @@ func TestPreConsignmentService_SyncTraderContext(t *testing.T) {
 	mockNodeRepo.On("GetWorkflowNodeByIDInTx", ctx, mock.Anything, nodeID).Return(node, nil).Once()
 
 	// Append Context
-	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1`).
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 .* FOR UPDATE`).
 		WithArgs(pcID, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "trader_context", "trader_id"}).
 			AddRow(pcID, []byte(`{"initial": "val"}`), traderID))
-
-	sqlMock.ExpectExec(`UPDATE "pre_consignments"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mockTemplateProvider.On("GetWorkflowNodeTemplateByID", ctx, node.WorkflowNodeTemplateID).
+		Return(&model.WorkflowNodeTemplate{Type: "SIMPLE_FORM"}, nil).Once()
+	expectContextChanges(sqlMock)
+	expectWorkflowEvents(sqlMock)
+	sqlMock.ExpectExec(`UPDATE "pre_consignments" SET "trader_context"=`).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Transition (Completed)
 	mockNodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil).Once()
@@ -407,13 +452,20 @@ func TestPreConsignmentService_SyncTraderContext(t *testing.T) {
 	// Sibling nodes (return just this one, so all completed)
 	mockNodeRepo.On("GetWorkflowNodesByPreConsignmentIDInTx", ctx, mock.Anything, pcID).Return([]model.WorkflowNode{*node}, nil).Once()
 
-	// Mark PreConsignment Completed
+	// Mark PreConsignment Completed, expiring after the validity of its template
+	templateID := uuid.New()
 	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1`).
 		WithArgs(pcID, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "state", "trader_id", "trader_context"}).
-			AddRow(pcID, "IN_PROGRESS", traderID, []byte(`{"initial": "val", "newKey": "newValue"}`)))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "state", "trader_id", "pre_consignment_template_id", "trader_context"}).
+			AddRow(pcID, "IN_PROGRESS", traderID, templateID, []byte(`{"initial": "val", "newKey": "newValue"}`)))
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE "pre_consignment_templates"."id" = \$1`).
+		WithArgs(templateID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "validity"}).AddRow(templateID, []byte(`{"duration": "24h"}`)))
 
-	sqlMock.ExpectExec(`UPDATE "pre_consignments"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	// Only the state and expiry are written, leaving the trader context as it is
+	sqlMock.ExpectExec(`UPDATE "pre_consignments" SET "updated_at"=\$1,"state"=\$2,"expires_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(sqlmock.AnyArg(), model.PreConsignmentStateCompleted, expiresWithin(24*time.Hour), pcID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Sync Trader Context (The part we really want to test)
 	// Query TraderContext FOR UPDATE
@@ -430,6 +482,15 @@ func TestPreConsignmentService_SyncTraderContext(t *testing.T) {
 
 	_, _, err := service.UpdateWorkflowNodeStateAndPropagateChanges(ctx, updateReq)
 	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// expiresWithin matches an expiry the given duration from now.
+type expiresWithin time.Duration
+
+func (d expiresWithin) Match(v driver.Value) bool {
+	expiresAt, ok := v.(time.Time)
+	return ok && time.Until(expiresAt) > time.Duration(d)-time.Minute && time.Until(expiresAt) <= time.Duration(d)
 }
 
 func TestPreConsignmentService_InitializePreConsignment_Failure(t *testing.T) {
@@ -470,6 +531,63 @@ func TestPreConsignmentService_InitializePreConsignment_Failure(t *testing.T) {
 		assert.Nil(t, resp)
 		assert.Nil(t, nodes)
 	})
+
+	t.Run("Dependency Renewal Revoked", func(t *testing.T) {
+		dependencyID := uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1 ORDER BY "pre_consignment_templates"."id" LIMIT \$2`).
+			WithArgs(templateID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "depends_on"}).
+				AddRow(templateID, uuid.New(), []byte(`["`+dependencyID.String()+`"]`)))
+		// The renewal of the dependency was revoked; its still valid predecessor does not satisfy the dependency
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE trader_id = \$1 AND pre_consignment_template_id IN \(\$2\) AND state IN \(\$3,\$4,\$5\) ORDER BY created_at DESC, id DESC`).
+			WithArgs("trader1", dependencyID, model.PreConsignmentStateCompleted, model.PreConsignmentStateExpired, model.PreConsignmentStateRevoked).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_template_id", "state"}).
+				AddRow(uuid.New(), dependencyID, "REVOKED").
+				AddRow(uuid.New(), dependencyID, "COMPLETED"))
+
+		resp, nodes, err := service.InitializePreConsignment(ctx, createReq, "trader1", nil)
+		assert.ErrorContains(t, err, "dependency pre-consignments are not all completed and valid")
+		assert.Nil(t, resp)
+		assert.Nil(t, nodes)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestPreConsignmentService_RenewPreConsignment_AlreadyRenewed(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	mockTemplateProvider := new(MockTemplateProvider)
+	service := NewPreConsignmentService(db, mockTemplateProvider, new(MockWorkflowNodeRepository))
+	ctx := context.Background()
+	previousID, templateID, workflowTemplateID := uuid.New(), uuid.New(), uuid.New()
+	previousColumns := []string{"id", "trader_id", "pre_consignment_template_id", "state", "created_at"}
+	createdAt := time.Now().Add(-time.Hour)
+
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1`).
+		WithArgs(previousID, 1).
+		WillReturnRows(sqlmock.NewRows(previousColumns).AddRow(previousID, "trader1", templateID, "COMPLETED", createdAt))
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1`).
+		WithArgs(templateID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "depends_on"}).
+			AddRow(templateID, workflowTemplateID, []byte("[]")))
+	mockTemplateProvider.On("GetWorkflowTemplateByID", ctx, workflowTemplateID).
+		Return(&model.WorkflowTemplate{BaseModel: model.BaseModel{ID: workflowTemplateID}}, nil).Once()
+	mockTemplateProvider.On("GetWorkflowNodeTemplatesByIDs", ctx, mock.Anything).Return([]model.WorkflowNodeTemplate{}, nil).Once()
+
+	// A concurrent renewal committed first: the check runs under the lock of the renewed pre-consignment
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 .* FOR UPDATE`).
+		WithArgs(previousID, 1).
+		WillReturnRows(sqlmock.NewRows(previousColumns).AddRow(previousID, "trader1", templateID, "COMPLETED", createdAt))
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "pre_consignments" WHERE trader_id = \$1 AND pre_consignment_template_id = \$2 AND created_at > \$3`).
+		WithArgs("trader1", templateID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	sqlMock.ExpectRollback()
+
+	resp, nodes, err := service.RenewPreConsignment(ctx, previousID, "trader1", nil)
+	assert.ErrorIs(t, err, ErrPreConsignmentNotRenewable)
+	assert.Nil(t, resp)
+	assert.Nil(t, nodes)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestPreConsignmentService_GetTraderPreConsignments_Failure(t *testing.T) {
@@ -482,7 +600,7 @@ func TestPreConsignmentService_GetTraderPreConsignments_Failure(t *testing.T) {
 		sqlMock.ExpectQuery(`SELECT count\\(\*\\) FROM "pre_consignment_templates"`).
 			WillReturnError(errors.New("db error"))
 
-		result, err := service.GetTraderPreConsignments(ctx, traderID, nil, nil)
+		result, err := service.GetTraderPreConsignments(ctx, traderID, nil, nil, nil)
 		assert.Error(t, err)
 		assert.Equal(t, model.TraderPreConsignmentsResponseDTO{}, result)
 	})
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -24,6 +25,10 @@ type StateTransitionResult struct {
 
 	// WorkflowFinished indicates whether all requirement met to Finish a consignment
 	WorkflowFinished bool
+
+	// CompletedSubWorkflowNodes contains the SUB_WORKFLOW nodes that were completed because their child
+	// workflow finished. Their tasks are still running and must be completed too.
+	CompletedSubWorkflowNodes []model.WorkflowNode
 }
 
 // WorkflowCompletionConfig holds configuration for determining workflow completion.
@@ -34,10 +39,15 @@ type WorkflowCompletionConfig struct {
 }
 
 // ParentRef identifies the parent entity (consignment or pre-consignment) that owns workflow nodes.
-// Exactly one of ConsignmentID or PreConsignmentID must be set.
+// Exactly one of ConsignmentID or PreConsignmentID must be set. ParentNodeID is set as well for the nodes
+// of the child workflow of a SUB_WORKFLOW node.
 type ParentRef struct {
 	ConsignmentID    *uuid.UUID
 	PreConsignmentID *uuid.UUID
+	ParentNodeID     *uuid.UUID              // SUB_WORKFLOW node the nodes are the child workflow of
+	ItemIndex        *int                    // Item of the SUB_WORKFLOW node, which the nodes of its child workflow are for too
+	Items            []model.ConsignmentItem // Items of the consignment, for which item-scoped node templates are instantiated
+	Context          map[string]any          // GlobalContext of the consignment or TraderContext of the pre-consignment
 }
 
 // WorkflowNodeStateMachine handles workflow node state transitions and dependency propagation.
@@ -57,12 +67,16 @@ func NewWorkflowNodeStateMachine(nodeRepo WorkflowNodeRepository) *WorkflowNodeS
 // TransitionToCompleted transitions a workflow node to COMPLETED state and propagates
 // the change to dependent nodes, unlocking them if all their dependencies are met.
 // Returns a StateTransitionResult containing all updated nodes and newly ready nodes.
+// The node's outcome is replaced by the one of updateReq, including a breach outcome set by
+// ApplySLABreach; SLABreachedAt is kept.
+// The unlockContext is the context of the node's workflow that unlock configurations are evaluated against.
 // The completionConfig determines how workflow completion is evaluated.
 func (sm *WorkflowNodeStateMachine) TransitionToCompleted(
 	ctx context.Context,
 	tx *gorm.DB,
 	node *model.WorkflowNode,
 	updateReq *model.UpdateWorkflowNodeDTO,
+	unlockContext map[string]any,
 	completionConfig ...*WorkflowCompletionConfig,
 ) (*StateTransitionResult, error) {
 	if node == nil {
@@ -106,10 +120,93 @@ func (sm *WorkflowNodeStateMachine) TransitionToCompleted(
 	nodeStateMap[node.ID] = *node
 
 	// Find and unlock dependent nodes.
-	unlockedNodes := sm.unlockDependentNodes(allNodes, nodeStateMap)
+	unlockedNodes, completedSubWorkflowNodes := sm.propagateChanges(allNodes, nodeStateMap, unlockContext)
+	nodesToUpdate, newReadyNodes := sm.completeEndNode(nodesToUpdate, updatedNodeIndex, unlockedNodes, nodeStateMap, cfg)
+
+	// Sort nodes by ID to prevent deadlocks
+	sm.sortNodesByID(nodesToUpdate)
+
+	// Check if workflow is completed
+	allCompleted := sm.evaluateWorkflowCompletion(allNodes, nodeStateMap, cfg)
+
+	// Persist the updates
+	if err := sm.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, nodesToUpdate); err != nil {
+		return nil, fmt.Errorf("failed to update workflow nodes: %w", err)
+	}
+
+	return &StateTransitionResult{
+		UpdatedNodes:              nodesToUpdate,
+		NewReadyNodes:             newReadyNodes,
+		WorkflowFinished:          allCompleted,
+		CompletedSubWorkflowNodes: completedSubWorkflowNodes,
+	}, nil
+}
+
+// ReevaluateUnlocks unlocks the LOCKED nodes of the workflow of node whose dependencies are met after
+// the context of the workflow changed to unlockContext, for unlock configurations that test it. Nodes
+// that are already READY stay READY, even if the context no longer satisfies their unlock configuration.
+// Returns a StateTransitionResult containing all updated nodes and newly ready nodes.
+func (sm *WorkflowNodeStateMachine) ReevaluateUnlocks(
+	ctx context.Context,
+	tx *gorm.DB,
+	node *model.WorkflowNode,
+	unlockContext map[string]any,
+	completionConfig ...*WorkflowCompletionConfig,
+) (*StateTransitionResult, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node cannot be nil")
+	}
+
+	allNodes, err := sm.getSiblingNodes(ctx, tx, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sibling workflow nodes: %w", err)
+	}
+
+	var cfg *WorkflowCompletionConfig
+	if len(completionConfig) > 0 {
+		cfg = completionConfig[0]
+	}
+
+	nodeStateMap := sm.buildNodeStateMap(allNodes)
+	unlockedNodes, completedSubWorkflowNodes := sm.propagateChanges(allNodes, nodeStateMap, unlockContext)
+	nodesToUpdate, newReadyNodes := sm.completeEndNode(nil, map[uuid.UUID]int{}, unlockedNodes, nodeStateMap, cfg)
+	if len(nodesToUpdate) == 0 {
+		return &StateTransitionResult{
+			UpdatedNodes:  []model.WorkflowNode{},
+			NewReadyNodes: []model.WorkflowNode{},
+		}, nil
+	}
+
+	// Sort nodes by ID to prevent deadlocks
+	sm.sortNodesByID(nodesToUpdate)
+
+	if err := sm.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, nodesToUpdate); err != nil {
+		return nil, fmt.Errorf("failed to update workflow nodes: %w", err)
+	}
+
+	return &StateTransitionResult{
+		UpdatedNodes:              nodesToUpdate,
+		NewReadyNodes:             newReadyNodes,
+		WorkflowFinished:          sm.evaluateWorkflowCompletion(allNodes, nodeStateMap, cfg),
+		CompletedSubWorkflowNodes: completedSubWorkflowNodes,
+	}, nil
+}
+
+// completeEndNode adds the unlocked nodes, in their current state, to nodesToUpdate, whose positions
+// updatedNodeIndex records, and completes the end node if it became READY. Returns the nodes to update and
+// the unlocked nodes that are still READY, i.e. all but the end node.
+func (sm *WorkflowNodeStateMachine) completeEndNode(
+	nodesToUpdate []model.WorkflowNode,
+	updatedNodeIndex map[uuid.UUID]int,
+	unlockedNodes []model.WorkflowNode,
+	nodeStateMap map[uuid.UUID]model.WorkflowNode,
+	cfg *WorkflowCompletionConfig,
+) ([]model.WorkflowNode, []model.WorkflowNode) {
 	for _, unlockedNode := range unlockedNodes {
-		if _, exists := updatedNodeIndex[unlockedNode.ID]; !exists {
-			nodesToUpdate = append(nodesToUpdate, unlockedNode)
+		if index, exists := updatedNodeIndex[unlockedNode.ID]; exists {
+			nodesToUpdate[index] = nodeStateMap[unlockedNode.ID]
+		} else {
+			nodesToUpdate = append(nodesToUpdate, nodeStateMap[unlockedNode.ID])
 			updatedNodeIndex[unlockedNode.ID] = len(nodesToUpdate) - 1
 		}
 	}
@@ -131,27 +228,11 @@ func (sm *WorkflowNodeStateMachine) TransitionToCompleted(
 
 	newReadyNodes := make([]model.WorkflowNode, 0, len(unlockedNodes))
 	for _, unlockedNode := range unlockedNodes {
-		if nodeStateMap[unlockedNode.ID].State == model.WorkflowNodeStateReady {
-			newReadyNodes = append(newReadyNodes, unlockedNode)
+		if current := nodeStateMap[unlockedNode.ID]; current.State == model.WorkflowNodeStateReady {
+			newReadyNodes = append(newReadyNodes, current)
 		}
 	}
-
-	// Sort nodes by ID to prevent deadlocks
-	sm.sortNodesByID(nodesToUpdate)
-
-	// Check if workflow is completed
-	allCompleted := sm.evaluateWorkflowCompletion(allNodes, nodeStateMap, cfg)
-
-	// Persist the updates
-	if err := sm.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, nodesToUpdate); err != nil {
-		return nil, fmt.Errorf("failed to update workflow nodes: %w", err)
-	}
-
-	return &StateTransitionResult{
-		UpdatedNodes:     nodesToUpdate,
-		NewReadyNodes:    newReadyNodes,
-		WorkflowFinished: allCompleted,
-	}, nil
+	return nodesToUpdate, newReadyNodes
 }
 
 // TransitionToFailed transitions a workflow node to FAILED state.
@@ -212,9 +293,113 @@ func (sm *WorkflowNodeStateMachine) TransitionToInProgress(
 	return nil
 }
 
+// ReopenFailed transitions a FAILED workflow node back to READY and increments its attempt.
+// The extended state and outcome of the failed attempt are cleared and the SLA clock, if any,
+// restarts. Nothing propagates to dependent nodes, since a FAILED node never unlocked them.
+func (sm *WorkflowNodeStateMachine) ReopenFailed(
+	ctx context.Context,
+	tx *gorm.DB,
+	node *model.WorkflowNode,
+) error {
+	if node == nil {
+		return fmt.Errorf("node cannot be nil")
+	}
+
+	if node.State != model.WorkflowNodeStateFailed {
+		return fmt.Errorf("cannot reopen node %s in state %s, only FAILED nodes can be reopened", node.ID, node.State)
+	}
+
+	node.State = model.WorkflowNodeStateReady
+	node.ExtendedState = nil
+	node.Outcome = nil
+	node.Attempt++
+	node.StartSLA(time.Now().UTC())
+	if err := sm.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, []model.WorkflowNode{*node}); err != nil {
+		return fmt.Errorf("failed to update workflow node %s to READY state: %w", node.ID, err)
+	}
+
+	return nil
+}
+
+// ApplySLABreach records that the SLA of a READY or IN_PROGRESS workflow node expired at now.
+// The node's outcome is set to the SLA's breach outcome while its state is left unchanged, and the
+// change is propagated like a completion: dependent nodes whose unlock configuration is now satisfied
+// (e.g. an escalation branching on TIMED_OUT) are unlocked, evaluated against unlockContext, nodes on
+// branches that can no longer be taken are skipped, and the end node and finished sub-workflows are
+// completed. Returns a StateTransitionResult containing all updated nodes and newly ready nodes.
+// The completionConfig determines how workflow completion is evaluated.
+func (sm *WorkflowNodeStateMachine) ApplySLABreach(
+	ctx context.Context,
+	tx *gorm.DB,
+	node *model.WorkflowNode,
+	now time.Time,
+	unlockContext map[string]any,
+	completionConfig ...*WorkflowCompletionConfig,
+) (*StateTransitionResult, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node cannot be nil")
+	}
+
+	if node.SLA == nil || node.SLADeadline == nil {
+		return nil, fmt.Errorf("workflow node %s has no SLA", node.ID)
+	}
+	if node.SLABreachedAt != nil {
+		// Already breached, no transition needed
+		return &StateTransitionResult{
+			UpdatedNodes:  []model.WorkflowNode{},
+			NewReadyNodes: []model.WorkflowNode{},
+		}, nil
+	}
+	if node.State != model.WorkflowNodeStateReady && node.State != model.WorkflowNodeStateInProgress {
+		return nil, fmt.Errorf("cannot apply SLA breach to node %s in state %s", node.ID, node.State)
+	}
+	if now.Before(*node.SLADeadline) {
+		return nil, fmt.Errorf("SLA of node %s has not expired yet", node.ID)
+	}
+
+	outcome := node.SLA.BreachOutcome()
+	node.Outcome = &outcome
+	node.SLABreachedAt = &now
+	nodesToUpdate := []model.WorkflowNode{*node}
+	updatedNodeIndex := map[uuid.UUID]int{node.ID: 0}
+
+	// Get all sibling nodes to check dependencies
+	allNodes, err := sm.getSiblingNodes(ctx, tx, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sibling workflow nodes: %w", err)
+	}
+
+	var cfg *WorkflowCompletionConfig
+	if len(completionConfig) > 0 {
+		cfg = completionConfig[0]
+	}
+
+	nodeStateMap := sm.buildNodeStateMap(allNodes)
+	nodeStateMap[node.ID] = *node
+
+	unlockedNodes, completedSubWorkflowNodes := sm.propagateChanges(allNodes, nodeStateMap, unlockContext)
+	nodesToUpdate, newReadyNodes := sm.completeEndNode(nodesToUpdate, updatedNodeIndex, unlockedNodes, nodeStateMap, cfg)
+
+	// Sort nodes by ID to prevent deadlocks
+	sm.sortNodesByID(nodesToUpdate)
+
+	if err := sm.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, nodesToUpdate); err != nil {
+		return nil, fmt.Errorf("failed to update workflow nodes: %w", err)
+	}
+
+	return &StateTransitionResult{
+		UpdatedNodes:              nodesToUpdate,
+		NewReadyNodes:             newReadyNodes,
+		WorkflowFinished:          sm.evaluateWorkflowCompletion(allNodes, nodeStateMap, cfg),
+		CompletedSubWorkflowNodes: completedSubWorkflowNodes,
+	}, nil
+}
+
 // InitializeNodesFromTemplates creates workflow nodes from templates and sets up their dependencies.
 // Nodes without dependencies are automatically set to READY state.
-// The parentRef determines whether nodes belong to a consignment or pre-consignment.
+// The parentRef determines whether nodes belong to a consignment or pre-consignment. Item-scoped node
+// templates get a node for each of its items whose workflow template lists them. The nodes of a child
+// workflow, whose parentRef has a ParentNodeID, are all for the item of their SUB_WORKFLOW node instead.
 func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 	ctx context.Context,
 	tx *gorm.DB,
@@ -233,16 +418,40 @@ func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 	}
 
 	// Create initial nodes in LOCKED state
+	itemIndexes := itemIndexesByNodeTemplate(parentRef.Items, workflowTemplates)
 	workflowNodes := make([]model.WorkflowNode, 0, len(nodeTemplates))
 	for _, template := range nodeTemplates {
-		workflowNode := model.WorkflowNode{
-			ConsignmentID:          parentRef.ConsignmentID,
-			PreConsignmentID:       parentRef.PreConsignmentID,
-			WorkflowNodeTemplateID: template.ID,
-			State:                  model.WorkflowNodeStateLocked,
-			DependsOn:              model.UUIDArray(make([]uuid.UUID, 0)),
+		if template.SLA != nil {
+			if err := template.SLA.Validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid SLA configuration for node template %s: %w", template.ID, err)
+			}
+		}
+		nodeItemIndexes := []*int{nil}
+		if parentRef.ParentNodeID != nil {
+			nodeItemIndexes = []*int{parentRef.ItemIndex}
+		} else if template.IsItemScoped() {
+			if parentRef.ConsignmentID == nil {
+				return nil, nil, nil, fmt.Errorf("node template %s is item-scoped, but only consignments have items", template.ID)
+			}
+			nodeItemIndexes = nil
+			for _, itemIndex := range itemIndexes[template.ID] {
+				nodeItemIndexes = append(nodeItemIndexes, &itemIndex)
+			}
+		}
+		for _, itemIndex := range nodeItemIndexes {
+			workflowNode := model.WorkflowNode{
+				ConsignmentID:          parentRef.ConsignmentID,
+				PreConsignmentID:       parentRef.PreConsignmentID,
+				ParentNodeID:           parentRef.ParentNodeID,
+				WorkflowNodeTemplateID: template.ID,
+				State:                  model.WorkflowNodeStateLocked,
+				DependsOn:              model.UUIDArray(make([]uuid.UUID, 0)),
+				Attempt:                1,
+				SLA:                    template.SLA,
+				ItemIndex:              itemIndex,
+			}
+			workflowNodes = append(workflowNodes, workflowNode)
 		}
-		workflowNodes = append(workflowNodes, workflowNode)
 	}
 
 	// Create UUIDArray of all DepEndNodeTemplateIDs from workflow templates
@@ -253,13 +462,17 @@ func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 		}
 	}
 
-	// Add a endNode if parent ref is consignment (not pre-consignment)
-	if parentRef.ConsignmentID != nil && len(depEndNodeTemplateIDs) > 0 {
+	// Add a endNode if parent ref is consignment (not pre-consignment) or a child workflow, whose end node
+	// reports its outcome to the SUB_WORKFLOW node
+	if (parentRef.ConsignmentID != nil || parentRef.ParentNodeID != nil) && len(depEndNodeTemplateIDs) > 0 {
 		endNode := model.WorkflowNode{
 			ConsignmentID:          parentRef.ConsignmentID,
 			PreConsignmentID:       parentRef.PreConsignmentID,
+			ParentNodeID:           parentRef.ParentNodeID,
 			WorkflowNodeTemplateID: uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID), // Use the default end node template ID
 			State:                  model.WorkflowNodeStateLocked,
+			Attempt:                1,
+			ItemIndex:              parentRef.ItemIndex,
 		}
 		workflowNodes = append(workflowNodes, endNode)
 	}
@@ -270,21 +483,17 @@ func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 		return nil, nil, nil, fmt.Errorf("failed to create workflow nodes: %w", err)
 	}
 
-	nodeByTemplateID := make(map[uuid.UUID]model.WorkflowNode)
-	for _, node := range createdNodes {
-		nodeByTemplateID[node.WorkflowNodeTemplateID] = node
-	}
-
 	// Resolve dependencies from template IDs to node IDs and collect nodes that need updates
-	var nodesToUpdate []model.WorkflowNode
+	var updatedIndexes []int
 	var newReadyNodes []model.WorkflowNode
 
-	// Build template ID -> node instance ID mapping for UnlockConfiguration resolution
-	// Note: Within a workflow, each template ID should correspond to exactly one node instance, so this mapping is valid.
-	templateToNodeID := make(map[uuid.UUID]uuid.UUID)
-	for templateID, node := range nodeByTemplateID {
-		templateToNodeID[templateID] = node.ID
+	// Index the nodes by template for DependsOn and UnlockConfiguration resolution. A template has a
+	// single node unless it is item-scoped.
+	nodePtrs := make([]*model.WorkflowNode, len(createdNodes))
+	for i := range createdNodes {
+		nodePtrs[i] = &createdNodes[i]
 	}
+	instances := newNodeInstances(nodePtrs)
 
 	var endNode_ *model.WorkflowNode
 	for i, node := range createdNodes {
@@ -292,36 +501,18 @@ func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 		if !exists {
 			// If node is the end node (which has no template), skip dependency resolution
 			if node.WorkflowNodeTemplateID == uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID) {
-				dependsOnNodeID := make([]uuid.UUID, 0)
-				for _, endNodeTemplateID := range depEndNodeTemplateIDs {
-					if depNode, found := nodeByTemplateID[endNodeTemplateID]; found {
-						dependsOnNodeID = append(dependsOnNodeID, depNode.ID)
-					}
-				}
-				createdNodes[i].DependsOn = dependsOnNodeID
-				nodesToUpdate = append(nodesToUpdate, createdNodes[i])
+				resolveEndNodeDependencies(&createdNodes[i], depEndNodeTemplateIDs, instances)
+				updatedIndexes = append(updatedIndexes, i)
 				endNode_ = &createdNodes[i]
 				continue
 			}
 			return nil, nil, nil, fmt.Errorf("workflow node template with ID %s not found", node.WorkflowNodeTemplateID)
 		}
 
-		dependsOnNodeIDs := make([]uuid.UUID, 0)
-		for _, dependsOnTemplateID := range template.DependsOn {
-			if depNode, found := nodeByTemplateID[dependsOnTemplateID]; found {
-				dependsOnNodeIDs = append(dependsOnNodeIDs, depNode.ID)
-			}
-		}
-		createdNodes[i].DependsOn = dependsOnNodeIDs
-
-		// Resolve UnlockConfiguration from template-level (template IDs) to instance-level (node IDs)
-		if template.UnlockConfiguration != nil {
-			resolvedConfig, err := template.UnlockConfiguration.ResolveToInstanceIDs(templateToNodeID)
-			if err != nil {
-				return nil, nil, nil, fmt.Errorf("failed to resolve unlock configuration for node template %s: %w", template.ID, err)
-			}
-			createdNodes[i].UnlockConfiguration = resolvedConfig
+		if err := resolveNodeDependencies(&createdNodes[i], template, instances); err != nil {
+			return nil, nil, nil, err
 		}
+		dependsOnNodeIDs := createdNodes[i].DependsOn
 
 		// Determine if this node needs to be updated
 		needsUpdate := false
@@ -339,15 +530,35 @@ func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 		// Node needs update if it has no dependencies and no unlock config (will be set to READY)
 		if len(dependsOnNodeIDs) == 0 && createdNodes[i].UnlockConfiguration == nil {
 			createdNodes[i].State = model.WorkflowNodeStateReady
+			createdNodes[i].StartSLA(time.Now().UTC())
 			newReadyNodes = append(newReadyNodes, createdNodes[i])
 			needsUpdate = true
 		}
 
 		if needsUpdate {
-			nodesToUpdate = append(nodesToUpdate, createdNodes[i])
+			updatedIndexes = append(updatedIndexes, i)
 		}
 	}
 
+	// Nodes whose unlock configuration already holds, e.g. because it only tests the context, start READY too
+	nodeStateMap := sm.buildNodeStateMap(createdNodes)
+	for i := range createdNodes {
+		node := &createdNodes[i]
+		if node.State != model.WorkflowNodeStateLocked || node.UnlockConfiguration == nil ||
+			!node.UnlockConfiguration.EvaluateInContext(nodeStateMap, parentRef.Context) {
+			continue
+		}
+		node.State = model.WorkflowNodeStateReady
+		node.StartSLA(time.Now().UTC())
+		nodeStateMap[node.ID] = *node
+		newReadyNodes = append(newReadyNodes, *node)
+	}
+
+	nodesToUpdate := make([]model.WorkflowNode, 0, len(updatedIndexes))
+	for _, i := range updatedIndexes {
+		nodesToUpdate = append(nodesToUpdate, createdNodes[i])
+	}
+
 	// Persist updates only for nodes that changed
 	if len(nodesToUpdate) > 0 {
 		if err := sm.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, nodesToUpdate); err != nil {
@@ -358,49 +569,207 @@ func (sm *WorkflowNodeStateMachine) InitializeNodesFromTemplates(
 	return createdNodes, newReadyNodes, endNode_, nil
 }
 
-// unlockDependentNodes finds all locked nodes whose dependencies are now met and unlocks them.
-// Returns the nodes that were unlocked in this transition.
-func (sm *WorkflowNodeStateMachine) unlockDependentNodes(
+// StartSubWorkflow creates the nodes of the child workflow of the SUB_WORKFLOW node from workflowTemplate
+// and its nodeTemplates, under the same consignment or pre-consignment. parentRef gives the parent entity and
+// the context the child workflow's nodes are unlocked against; the nodes are for the node's item. Nothing is
+// created if the node already has a child workflow, e.g. because its task was reopened.
+// Returns the created nodes that are READY.
+func (sm *WorkflowNodeStateMachine) StartSubWorkflow(
+	ctx context.Context,
+	tx *gorm.DB,
+	node *model.WorkflowNode,
+	parentRef ParentRef,
+	nodeTemplates []model.WorkflowNodeTemplate,
+	workflowTemplate model.WorkflowTemplate,
+) ([]model.WorkflowNode, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node cannot be nil")
+	}
+
+	allNodes, err := sm.getSiblingNodes(ctx, tx, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sibling workflow nodes: %w", err)
+	}
+	for _, sibling := range allNodes {
+		if sibling.ParentNodeID != nil && *sibling.ParentNodeID == node.ID {
+			return []model.WorkflowNode{}, nil
+		}
+	}
+
+	parentRef.ParentNodeID = &node.ID
+	parentRef.ItemIndex = node.ItemIndex
+	_, newReadyNodes, _, err := sm.InitializeNodesFromTemplates(ctx, tx, parentRef, nodeTemplates, []model.WorkflowTemplate{workflowTemplate})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nodes of child workflow: %w", err)
+	}
+	return newReadyNodes, nil
+}
+
+// propagateChanges unlocks and skips dependent nodes, and completes the IN_PROGRESS SUB_WORKFLOW nodes whose
+// child workflow finished, until nothing changes, since a completed SUB_WORKFLOW node can unlock others and
+// vice versa. Returns the nodes that changed, i.e. were unlocked, skipped or completed, and the SUB_WORKFLOW
+// nodes that were completed.
+func (sm *WorkflowNodeStateMachine) propagateChanges(
 	allNodes []model.WorkflowNode,
 	nodeStateMap map[uuid.UUID]model.WorkflowNode,
-) []model.WorkflowNode {
-	var unlockedNodes []model.WorkflowNode
+	unlockContext map[string]any,
+) ([]model.WorkflowNode, []model.WorkflowNode) {
+	var changedNodes, completedSubWorkflowNodes []model.WorkflowNode
+	for {
+		changedNodes = append(changedNodes, sm.unlockDependentNodes(allNodes, nodeStateMap, unlockContext)...)
+		completed, endNodes := sm.completeSubWorkflows(allNodes, nodeStateMap)
+		if len(completed) == 0 {
+			return changedNodes, completedSubWorkflowNodes
+		}
+		changedNodes = append(append(changedNodes, endNodes...), completed...)
+		completedSubWorkflowNodes = append(completedSubWorkflowNodes, completed...)
+	}
+}
 
-	// Check each locked node to see if its dependencies are now met
+// completeSubWorkflows completes the IN_PROGRESS SUB_WORKFLOW nodes whose child workflow finished. A child
+// workflow with an end node finishes when its end node becomes READY, which is completed too, and the
+// SUB_WORKFLOW node takes the outcome of the end node's completed dependency. One without an end node
+// finishes when all its nodes are finished. Returns the completed SUB_WORKFLOW nodes and child end nodes.
+func (sm *WorkflowNodeStateMachine) completeSubWorkflows(
+	allNodes []model.WorkflowNode,
+	nodeStateMap map[uuid.UUID]model.WorkflowNode,
+) ([]model.WorkflowNode, []model.WorkflowNode) {
+	childrenByParent := make(map[uuid.UUID][]uuid.UUID)
 	for _, node := range allNodes {
-		if node.State != model.WorkflowNodeStateLocked {
+		if node.ParentNodeID != nil {
+			childrenByParent[*node.ParentNodeID] = append(childrenByParent[*node.ParentNodeID], node.ID)
+		}
+	}
+
+	var completedNodes, completedEndNodes []model.WorkflowNode
+	endNodeTemplateID := uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+	for parentID, childIDs := range childrenByParent {
+		parent, exists := nodeStateMap[parentID]
+		if !exists || parent.State != model.WorkflowNodeStateInProgress {
+			continue
+		}
+
+		var endNode *model.WorkflowNode
+		allFinished := true
+		for _, childID := range childIDs {
+			child := nodeStateMap[childID]
+			if child.WorkflowNodeTemplateID == endNodeTemplateID && !child.IsVoided() {
+				endNode = &child
+			}
+			if !child.IsVoided() && !child.IsFinished() {
+				allFinished = false
+			}
+		}
+
+		var outcome *string
+		switch {
+		case endNode != nil && endNode.State == model.WorkflowNodeStateReady:
+			endNode.State = model.WorkflowNodeStateCompleted
+			nodeStateMap[endNode.ID] = *endNode
+			completedEndNodes = append(completedEndNodes, *endNode)
+			for _, depID := range endNode.DependsOn {
+				if dep := nodeStateMap[depID]; dep.State == model.WorkflowNodeStateCompleted && dep.Outcome != nil {
+					outcome = dep.Outcome
+				}
+			}
+		case endNode == nil && allFinished:
+		default:
 			continue
 		}
 
-		if sm.areDependenciesMet(node, nodeStateMap) {
-			node.State = model.WorkflowNodeStateReady
-			unlockedNodes = append(unlockedNodes, node)
+		parent.State = model.WorkflowNodeStateCompleted
+		parent.Outcome = outcome
+		nodeStateMap[parent.ID] = parent
+		completedNodes = append(completedNodes, parent)
+	}
+
+	// Sort nodes by ID so that the result does not depend on map iteration order
+	sm.sortNodesByID(completedNodes)
+	sm.sortNodesByID(completedEndNodes)
+	return completedNodes, completedEndNodes
+}
+
+// unlockDependentNodes finds all locked nodes whose dependencies are now met and unlocks them, and skips
+// those whose dependencies can never be met, e.g. because they are on a branch that was not taken. Skips
+// propagate: a node depending on a SKIPPED node is skipped in turn, unless its unlock configuration allows
+// for it. Returns the nodes that were unlocked or skipped in this transition.
+func (sm *WorkflowNodeStateMachine) unlockDependentNodes(
+	allNodes []model.WorkflowNode,
+	nodeStateMap map[uuid.UUID]model.WorkflowNode,
+	unlockContext map[string]any,
+) []model.WorkflowNode {
+	var changedNodes []model.WorkflowNode
+
+	// Repeat until nothing changes, since a skipped node can decide the dependencies of others
+	for changed := true; changed; {
+		changed = false
+		for _, node := range allNodes {
+			node = nodeStateMap[node.ID]
+			if node.State != model.WorkflowNodeStateLocked || node.IsVoided() {
+				continue
+			}
+
+			switch {
+			case sm.areDependenciesMet(node, nodeStateMap, unlockContext):
+				node.State = model.WorkflowNodeStateReady
+				node.StartSLA(time.Now().UTC())
+			case !sm.canDependenciesBeMet(node, nodeStateMap):
+				node.State = model.WorkflowNodeStateSkipped
+			default:
+				continue
+			}
+			changedNodes = append(changedNodes, node)
 			nodeStateMap[node.ID] = node
+			changed = true
 		}
 	}
 
-	return unlockedNodes
+	return changedNodes
 }
 
 // areDependenciesMet checks if all dependencies for a node are satisfied.
-// If the node has an UnlockConfiguration, it evaluates its boolean expression.
-// Otherwise, it uses the legacy AND-all logic on the DependsOn list.
+// If the node has an UnlockConfiguration, it evaluates its boolean expression against the nodes and
+// unlockContext. Otherwise, it uses the legacy AND-all logic on the DependsOn list.
 func (sm *WorkflowNodeStateMachine) areDependenciesMet(
 	node model.WorkflowNode,
 	nodeMap map[uuid.UUID]model.WorkflowNode,
+	unlockContext map[string]any,
 ) bool {
 	// If the node has a conditional unlock configuration, use boolean expression evaluation
 	if node.UnlockConfiguration != nil {
-		return node.UnlockConfiguration.Evaluate(nodeMap)
+		return node.UnlockConfiguration.EvaluateInContext(nodeMap, unlockContext)
 	}
 
-	// Legacy behavior: all dependencies must be COMPLETED (AND-all)
+	// Legacy behavior: all dependencies must be COMPLETED (AND-all). The end node only waits for the
+	// workflow's last nodes to finish, so a SKIPPED one counts too.
+	isEndNode := node.WorkflowNodeTemplateID == uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
 	for _, depID := range node.DependsOn {
 		depNode, exists := nodeMap[depID]
 		if !exists {
 			return false
 		}
-		if depNode.State != model.WorkflowNodeStateCompleted {
+		if depNode.State != model.WorkflowNodeStateCompleted && !(isEndNode && depNode.State == model.WorkflowNodeStateSkipped) {
+			return false
+		}
+	}
+	return true
+}
+
+// canDependenciesBeMet reports whether the dependencies of a node may still be met, given the terminal
+// nodes among them. The end node is never given up on.
+func (sm *WorkflowNodeStateMachine) canDependenciesBeMet(
+	node model.WorkflowNode,
+	nodeMap map[uuid.UUID]model.WorkflowNode,
+) bool {
+	if node.WorkflowNodeTemplateID == uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID) {
+		return true
+	}
+	if node.UnlockConfiguration != nil {
+		return node.UnlockConfiguration.CanBeSatisfied(nodeMap)
+	}
+
+	for _, depID := range node.DependsOn {
+		if depNode, exists := nodeMap[depID]; exists && depNode.IsTerminal() && depNode.State != model.WorkflowNodeStateCompleted {
 			return false
 		}
 	}
@@ -427,13 +796,15 @@ func (sm *WorkflowNodeStateMachine) evaluateWorkflowCompletion(
 		return endNode.State == model.WorkflowNodeStateCompleted
 	}
 
-	// Legacy behavior: all nodes must be COMPLETED
+	// Legacy behavior: all nodes must be COMPLETED or SKIPPED, except voided ones
 	for _, node := range allNodes {
-		state := node.State
+		if node.IsVoided() {
+			continue
+		}
 		if current, exists := nodeStateMap[node.ID]; exists {
-			state = current.State
+			node = current
 		}
-		if state != model.WorkflowNodeStateCompleted {
+		if !node.IsFinished() {
 			return false
 		}
 	}
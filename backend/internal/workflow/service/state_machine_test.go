@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
@@ -91,7 +93,7 @@ func TestTransitionToCompleted(t *testing.T) {
 			BaseModel: model.BaseModel{ID: uuid.New()},
 			State:     model.WorkflowNodeStateCompleted,
 		}
-		result, err := sm.TransitionToCompleted(ctx, nil, node, nil)
+		result, err := sm.TransitionToCompleted(ctx, nil, node, nil, nil)
 		assert.NoError(t, err)
 		assert.Empty(t, result.UpdatedNodes)
 		assert.Empty(t, result.NewReadyNodes)
@@ -103,7 +105,7 @@ func TestTransitionToCompleted(t *testing.T) {
 			BaseModel: model.BaseModel{ID: uuid.New()},
 			State:     model.WorkflowNodeStateLocked,
 		}
-		_, err := sm.TransitionToCompleted(ctx, nil, node, nil)
+		_, err := sm.TransitionToCompleted(ctx, nil, node, nil, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "cannot transition")
 	})
@@ -124,7 +126,7 @@ func TestTransitionToCompleted(t *testing.T) {
 		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*node}, nil).Once()
 		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.AnythingOfType("[]model.WorkflowNode")).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, node, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, node, updateReq, nil)
 		assert.NoError(t, err)
 		assert.Len(t, result.UpdatedNodes, 1)
 		assert.Equal(t, model.WorkflowNodeStateCompleted, result.UpdatedNodes[0].State)
@@ -154,7 +156,7 @@ func TestTransitionToCompleted(t *testing.T) {
 			return len(nodes) == 2
 		})).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, node, &model.UpdateWorkflowNodeDTO{})
+		result, err := sm.TransitionToCompleted(ctx, nil, node, &model.UpdateWorkflowNodeDTO{}, nil)
 		assert.NoError(t, err)
 		assert.Len(t, result.UpdatedNodes, 2)
 		assert.Len(t, result.NewReadyNodes, 1)
@@ -253,6 +255,75 @@ func TestInitializeNodesFromTemplates(t *testing.T) {
 		assert.Len(t, newReadyNodes, 1)
 		assert.Equal(t, model.WorkflowNodeStateReady, createdNodes[0].State)
 	})
+
+	t.Run("Create Nodes Per Item For Item-Scoped Templates", func(t *testing.T) {
+		certificateID := uuid.New()
+		inspectionID := uuid.New()
+		releaseID := uuid.New()
+		workflowTemplate := model.WorkflowTemplate{
+			BaseModel:         model.BaseModel{ID: uuid.New()},
+			NodeTemplates:     model.UUIDArray{certificateID, inspectionID, releaseID},
+			EndNodeTemplateID: &releaseID,
+		}
+		completed := "COMPLETED"
+		templates := []model.WorkflowNodeTemplate{
+			{BaseModel: model.BaseModel{ID: certificateID}, Scope: model.WorkflowNodeScopeItem},
+			{BaseModel: model.BaseModel{ID: inspectionID}, DependsOn: model.UUIDArray{certificateID}},
+			{
+				BaseModel: model.BaseModel{ID: releaseID},
+				UnlockConfiguration: &model.UnlockConfig{Expression: &model.UnlockExpression{
+					NodeTemplateID: certificateID, State: &completed, Items: model.UnlockItemsAny,
+				}},
+			},
+		}
+		parentRef := ParentRef{
+			ConsignmentID: &uuid.UUID{},
+			Items: []model.ConsignmentItem{
+				{HSCodeID: uuid.New(), WorkflowTemplateID: &workflowTemplate.ID},
+				{HSCodeID: uuid.New(), WorkflowTemplateID: &workflowTemplate.ID},
+			},
+		}
+
+		item0, item1 := 0, 1
+		created := []model.WorkflowNode{
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: certificateID, State: model.WorkflowNodeStateLocked, ItemIndex: &item0},
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: certificateID, State: model.WorkflowNodeStateLocked, ItemIndex: &item1},
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: inspectionID, State: model.WorkflowNodeStateLocked},
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: releaseID, State: model.WorkflowNodeStateLocked},
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID), State: model.WorkflowNodeStateLocked},
+		}
+		mockRepo.On("CreateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 5 &&
+				nodes[0].ItemIndex != nil && *nodes[0].ItemIndex == 0 &&
+				nodes[1].ItemIndex != nil && *nodes[1].ItemIndex == 1 &&
+				nodes[2].ItemIndex == nil
+		})).Return(created, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		createdNodes, newReadyNodes, _, err := sm.InitializeNodesFromTemplates(ctx, nil, parentRef, templates, []model.WorkflowTemplate{workflowTemplate})
+		require.NoError(t, err)
+		require.Len(t, createdNodes, 5)
+		assert.Len(t, newReadyNodes, 2)
+
+		// The consignment-wide inspection waits for the certificates of both items
+		assert.Equal(t, model.UUIDArray{created[0].ID, created[1].ID}, createdNodes[2].DependsOn)
+		// The release needs the certificate of any item
+		release := createdNodes[3].UnlockConfiguration
+		require.NotNil(t, release)
+		require.NotNil(t, release.Expression)
+		require.Len(t, release.Expression.AnyOf, 2)
+		assert.Equal(t, created[0].ID, *release.Expression.AnyOf[0].NodeID)
+		assert.Equal(t, created[1].ID, *release.Expression.AnyOf[1].NodeID)
+		assert.Equal(t, model.UUIDArray{created[3].ID}, createdNodes[4].DependsOn)
+	})
+
+	t.Run("Item-Scoped Template In Pre-Consignment", func(t *testing.T) {
+		templates := []model.WorkflowNodeTemplate{
+			{BaseModel: model.BaseModel{ID: uuid.New()}, Scope: model.WorkflowNodeScopeItem},
+		}
+		_, _, _, err := sm.InitializeNodesFromTemplates(ctx, nil, ParentRef{PreConsignmentID: &uuid.UUID{}}, templates, nil)
+		assert.ErrorContains(t, err, "item-scoped")
+	})
 }
 
 func TestTransitionToFailed(t *testing.T) {
@@ -298,6 +369,235 @@ func TestTransitionToFailed(t *testing.T) {
 	})
 }
 
+func TestReopenFailed(t *testing.T) {
+	mockRepo := new(MockWorkflowNodeRepository)
+	sm := NewWorkflowNodeStateMachine(mockRepo)
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		node := &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			State:         model.WorkflowNodeStateFailed,
+			ExtendedState: strPtr("OGA_REVIEWED"),
+			Outcome:       strPtr("REJECTED"),
+			Attempt:       1,
+		}
+
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].ID == node.ID && nodes[0].State == model.WorkflowNodeStateReady && nodes[0].Attempt == 2
+		})).Return(nil).Once()
+
+		err := sm.ReopenFailed(ctx, nil, node)
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowNodeStateReady, node.State)
+		assert.Nil(t, node.ExtendedState)
+		assert.Nil(t, node.Outcome)
+		assert.Equal(t, 2, node.Attempt)
+	})
+
+	t.Run("Not Failed", func(t *testing.T) {
+		node := &model.WorkflowNode{
+			BaseModel: model.BaseModel{ID: uuid.New()},
+			State:     model.WorkflowNodeStateCompleted,
+			Attempt:   1,
+		}
+
+		err := sm.ReopenFailed(ctx, nil, node)
+		assert.Error(t, err)
+		assert.Equal(t, model.WorkflowNodeStateCompleted, node.State)
+		assert.Equal(t, 1, node.Attempt)
+	})
+}
+
+func TestApplySLABreach(t *testing.T) {
+	mockRepo := new(MockWorkflowNodeRepository)
+	sm := NewWorkflowNodeStateMachine(mockRepo)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+
+	t.Run("Unlocks Escalation On Breach Outcome", func(t *testing.T) {
+		consignmentID := uuid.New()
+		reviewTemplateID := uuid.New()
+		reviewNode := &model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: reviewTemplateID,
+			State:                  model.WorkflowNodeStateInProgress,
+			SLA:                    &model.SLAConfig{Duration: "72h"},
+			SLADeadline:            &expired,
+		}
+		reviewNodeID := reviewNode.ID
+		escalationNode := model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateLocked,
+			DependsOn:     model.UUIDArray{reviewNode.ID},
+			UnlockConfiguration: &model.UnlockConfig{
+				AnyOf: []model.UnlockGroup{{AllOf: []model.UnlockCondition{
+					{NodeTemplateID: reviewTemplateID, NodeID: &reviewNodeID, Outcome: strPtr(model.DefaultSLABreachOutcome)},
+				}}},
+			},
+		}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).
+			Return([]model.WorkflowNode{*reviewNode, escalationNode}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 2
+		})).Return(nil).Once()
+
+		result, err := sm.ApplySLABreach(ctx, nil, reviewNode, now, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowNodeStateInProgress, reviewNode.State)
+		assert.Equal(t, model.DefaultSLABreachOutcome, *reviewNode.Outcome)
+		assert.Equal(t, now, *reviewNode.SLABreachedAt)
+		assert.Len(t, result.NewReadyNodes, 1)
+		assert.Equal(t, escalationNode.ID, result.NewReadyNodes[0].ID)
+		assert.Equal(t, model.WorkflowNodeStateReady, result.NewReadyNodes[0].State)
+	})
+
+	t.Run("Completes End Node Unlocked By Breach", func(t *testing.T) {
+		consignmentID := uuid.New()
+		reviewTemplateID := uuid.New()
+		reviewNode := &model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: reviewTemplateID,
+			State:                  model.WorkflowNodeStateInProgress,
+			SLA:                    &model.SLAConfig{Duration: "72h"},
+			SLADeadline:            &expired,
+		}
+		reviewNodeID := reviewNode.ID
+		endNode := model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID),
+			State:                  model.WorkflowNodeStateLocked,
+			DependsOn:              model.UUIDArray{reviewNode.ID},
+			UnlockConfiguration: &model.UnlockConfig{
+				AnyOf: []model.UnlockGroup{{AllOf: []model.UnlockCondition{
+					{NodeTemplateID: reviewTemplateID, NodeID: &reviewNodeID, Outcome: strPtr(model.DefaultSLABreachOutcome)},
+				}}},
+			},
+		}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).
+			Return([]model.WorkflowNode{*reviewNode, endNode}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			for _, node := range nodes {
+				if node.ID == endNode.ID {
+					return len(nodes) == 2 && node.State == model.WorkflowNodeStateCompleted
+				}
+			}
+			return false
+		})).Return(nil).Once()
+
+		result, err := sm.ApplySLABreach(ctx, nil, reviewNode, now, nil, &WorkflowCompletionConfig{EndNodeID: &endNode.ID})
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.NewReadyNodes, "the completed end node must not be registered as a task")
+		assert.True(t, result.WorkflowFinished)
+		assert.Equal(t, model.WorkflowNodeStateInProgress, reviewNode.State)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Configured Outcome", func(t *testing.T) {
+		consignmentID := uuid.New()
+		node := &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateReady,
+			SLA:           &model.SLAConfig{Duration: "1h", Outcome: "OGA_OVERDUE"},
+			SLADeadline:   &expired,
+		}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*node}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.AnythingOfType("[]model.WorkflowNode")).Return(nil).Once()
+
+		result, err := sm.ApplySLABreach(ctx, nil, node, now, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "OGA_OVERDUE", *node.Outcome)
+		assert.Empty(t, result.NewReadyNodes)
+	})
+
+	t.Run("Already Breached", func(t *testing.T) {
+		node := &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			State:         model.WorkflowNodeStateInProgress,
+			SLA:           &model.SLAConfig{Duration: "1h"},
+			SLADeadline:   &expired,
+			SLABreachedAt: &expired,
+		}
+
+		result, err := sm.ApplySLABreach(ctx, nil, node, now, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.UpdatedNodes)
+	})
+
+	t.Run("Not Expired Yet", func(t *testing.T) {
+		deadline := now.Add(time.Hour)
+		node := &model.WorkflowNode{
+			BaseModel:   model.BaseModel{ID: uuid.New()},
+			State:       model.WorkflowNodeStateInProgress,
+			SLA:         &model.SLAConfig{Duration: "1h"},
+			SLADeadline: &deadline,
+		}
+
+		_, err := sm.ApplySLABreach(ctx, nil, node, now, nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, node.Outcome)
+	})
+
+	t.Run("Completed Node", func(t *testing.T) {
+		node := &model.WorkflowNode{
+			BaseModel:   model.BaseModel{ID: uuid.New()},
+			State:       model.WorkflowNodeStateCompleted,
+			SLA:         &model.SLAConfig{Duration: "1h"},
+			SLADeadline: &expired,
+		}
+
+		_, err := sm.ApplySLABreach(ctx, nil, node, now, nil)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSLAStartsWhenNodeBecomesReady(t *testing.T) {
+	mockRepo := new(MockWorkflowNodeRepository)
+	sm := NewWorkflowNodeStateMachine(mockRepo)
+	ctx := context.Background()
+
+	consignmentID := uuid.New()
+	node := &model.WorkflowNode{
+		BaseModel:     model.BaseModel{ID: uuid.New()},
+		ConsignmentID: &consignmentID,
+		State:         model.WorkflowNodeStateInProgress,
+	}
+	dependentNode := model.WorkflowNode{
+		BaseModel:     model.BaseModel{ID: uuid.New()},
+		ConsignmentID: &consignmentID,
+		State:         model.WorkflowNodeStateLocked,
+		DependsOn:     model.UUIDArray{node.ID},
+		SLA:           &model.SLAConfig{Duration: "72h"},
+	}
+
+	mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*node, dependentNode}, nil).Once()
+	mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.AnythingOfType("[]model.WorkflowNode")).Return(nil).Once()
+
+	before := time.Now().UTC()
+	result, err := sm.TransitionToCompleted(ctx, nil, node, &model.UpdateWorkflowNodeDTO{}, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.NewReadyNodes, 1)
+	deadline := result.NewReadyNodes[0].SLADeadline
+	assert.NotNil(t, deadline)
+	assert.WithinDuration(t, before.Add(72*time.Hour), *deadline, time.Minute)
+}
+
 func TestTransitionToInProgress(t *testing.T) {
 	mockRepo := new(MockWorkflowNodeRepository)
 	sm := NewWorkflowNodeStateMachine(mockRepo)
@@ -364,13 +664,57 @@ func TestTransitionToCompletedWithOutcome(t *testing.T) {
 			return len(nodes) == 1 && nodes[0].Outcome != nil && *nodes[0].Outcome == "APPROVED"
 		})).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, node, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, node, updateReq, nil)
 		assert.NoError(t, err)
 		assert.Len(t, result.UpdatedNodes, 1)
 		assert.Equal(t, model.WorkflowNodeStateCompleted, result.UpdatedNodes[0].State)
 		assert.NotNil(t, result.UpdatedNodes[0].Outcome)
 		assert.Equal(t, "APPROVED", *result.UpdatedNodes[0].Outcome)
 	})
+
+	t.Run("Completion Replaces Breach Outcome", func(t *testing.T) {
+		consignmentID := uuid.New()
+		reviewTemplateID := uuid.New()
+		breachedAt := time.Now().UTC().Add(-time.Hour)
+		breachOutcome := model.DefaultSLABreachOutcome
+		reviewNode := &model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: reviewTemplateID,
+			State:                  model.WorkflowNodeStateInProgress,
+			Outcome:                &breachOutcome,
+			SLA:                    &model.SLAConfig{Duration: "72h"},
+			SLABreachedAt:          &breachedAt,
+		}
+		reviewNodeID := reviewNode.ID
+		// The escalation unlocked by the breach keeps running after the node completes
+		escalationNode := model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateReady,
+			DependsOn:     model.UUIDArray{reviewNode.ID},
+			UnlockConfiguration: &model.UnlockConfig{
+				AnyOf: []model.UnlockGroup{{AllOf: []model.UnlockCondition{
+					{NodeTemplateID: reviewTemplateID, NodeID: &reviewNodeID, Outcome: strPtr(model.DefaultSLABreachOutcome)},
+				}}},
+			},
+		}
+		outcome := "APPROVED"
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).
+			Return([]model.WorkflowNode{*reviewNode, escalationNode}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].ID == reviewNodeID
+		})).Return(nil).Once()
+
+		result, err := sm.TransitionToCompleted(ctx, nil, reviewNode, &model.UpdateWorkflowNodeDTO{Outcome: &outcome}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "APPROVED", *reviewNode.Outcome)
+		assert.Equal(t, breachedAt, *reviewNode.SLABreachedAt)
+		assert.Empty(t, result.NewReadyNodes)
+		assert.Len(t, result.UpdatedNodes, 1)
+	})
 }
 
 func TestUnlockWithUnlockConfiguration(t *testing.T) {
@@ -416,7 +760,7 @@ func TestUnlockWithUnlockConfiguration(t *testing.T) {
 			return len(nodes) == 2
 		})).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil)
 		assert.NoError(t, err)
 		assert.Len(t, result.NewReadyNodes, 1)
 		assert.Equal(t, nodeBID, result.NewReadyNodes[0].ID)
@@ -456,13 +800,18 @@ func TestUnlockWithUnlockConfiguration(t *testing.T) {
 
 		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*nodeA, nodeB}, nil).Once()
 		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
-			// Only node A should be updated (to COMPLETED), node B stays LOCKED
-			return len(nodes) == 1
+			// Node A is COMPLETED, and node B is SKIPPED since A can no longer be APPROVED
+			return len(nodes) == 2
 		})).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil)
 		assert.NoError(t, err)
 		assert.Empty(t, result.NewReadyNodes, "node B should not be unlocked with wrong outcome")
+		for _, node := range result.UpdatedNodes {
+			if node.ID == nodeBID {
+				assert.Equal(t, model.WorkflowNodeStateSkipped, node.State)
+			}
+		}
 	})
 
 	t.Run("OR Condition - Second Group Met", func(t *testing.T) {
@@ -508,7 +857,7 @@ func TestUnlockWithUnlockConfiguration(t *testing.T) {
 			return len(nodes) == 2
 		})).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil)
 		assert.NoError(t, err)
 		assert.Len(t, result.NewReadyNodes, 1)
 		assert.Equal(t, nodeBID, result.NewReadyNodes[0].ID)
@@ -550,7 +899,7 @@ func TestUnlockWithUnlockConfiguration(t *testing.T) {
 			return len(nodes) == 2
 		})).Return(nil).Once()
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil)
 		assert.NoError(t, err)
 		assert.Len(t, result.NewReadyNodes, 1, "node B should unlock when A is COMPLETED regardless of outcome")
 	})
@@ -594,7 +943,7 @@ func TestEndNodeWorkflowCompletion(t *testing.T) {
 			EndNodeID: &endNodeID,
 		}
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, completionConfig)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil, completionConfig)
 		assert.NoError(t, err)
 		assert.True(t, result.WorkflowFinished, "workflow should be complete when end node is completed")
 	})
@@ -632,7 +981,7 @@ func TestEndNodeWorkflowCompletion(t *testing.T) {
 			EndNodeID: &endNodeID,
 		}
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, completionConfig)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil, completionConfig)
 		assert.NoError(t, err)
 		assert.False(t, result.WorkflowFinished, "workflow should not be complete when end node is still locked")
 	})
@@ -682,7 +1031,7 @@ func TestEndNodeWorkflowCompletion(t *testing.T) {
 			EndNodeID: &endNodeID,
 		}
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, completionConfig)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil, completionConfig)
 		assert.NoError(t, err)
 		assert.True(t, result.WorkflowFinished, "workflow should be complete when end node auto-completes")
 		assert.Empty(t, result.NewReadyNodes, "end node should not remain READY after auto-completion")
@@ -712,7 +1061,7 @@ func TestEndNodeWorkflowCompletion(t *testing.T) {
 		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.AnythingOfType("[]model.WorkflowNode")).Return(nil).Once()
 
 		// No completion config (nil) — should fall back to all-nodes-completed check
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil)
 		assert.NoError(t, err)
 		assert.False(t, result.WorkflowFinished, "workflow should not be complete when not all nodes are completed (legacy behavior)")
 	})
@@ -736,7 +1085,7 @@ func TestEndNodeWorkflowCompletion(t *testing.T) {
 			EndNodeID: nil, // Explicitly nil
 		}
 
-		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, completionConfig)
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, updateReq, nil, completionConfig)
 		assert.NoError(t, err)
 		assert.True(t, result.WorkflowFinished, "single node completed = all nodes completed (legacy behavior)")
 	})
@@ -835,3 +1184,414 @@ func TestInitializeNodesWithUnlockConfiguration(t *testing.T) {
 		assert.NotNil(t, node2.UnlockConfiguration)
 	})
 }
+
+func TestUnlockWithContextPredicate(t *testing.T) {
+	ctx := context.Background()
+	quantityOver5000 := &model.UnlockConfig{
+		Expression: &model.UnlockExpression{
+			Context: &model.UnlockContextPredicate{Path: "declaredQuantityKg", Operator: model.UnlockContextGreater, Value: 5000},
+		},
+	}
+
+	t.Run("Initialize Unlocks Node Whose Predicate Holds", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		templateID := uuid.New()
+		nodeID := uuid.New()
+
+		templates := []model.WorkflowNodeTemplate{
+			{BaseModel: model.BaseModel{ID: templateID}, UnlockConfiguration: quantityOver5000},
+		}
+		parentRef := ParentRef{
+			ConsignmentID: &uuid.UUID{},
+			Context:       map[string]any{"declaredQuantityKg": 7500.0},
+		}
+
+		mockRepo.On("CreateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return([]model.WorkflowNode{
+			{BaseModel: model.BaseModel{ID: nodeID}, WorkflowNodeTemplateID: templateID, State: model.WorkflowNodeStateLocked},
+		}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateReady
+		})).Return(nil).Once()
+
+		_, newReadyNodes, _, err := sm.InitializeNodesFromTemplates(ctx, nil, parentRef, templates, nil)
+
+		assert.NoError(t, err)
+		if assert.Len(t, newReadyNodes, 1) {
+			assert.Equal(t, nodeID, newReadyNodes[0].ID)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Initialize Keeps Node Locked Whose Predicate Fails", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		templateID := uuid.New()
+
+		templates := []model.WorkflowNodeTemplate{
+			{BaseModel: model.BaseModel{ID: templateID}, UnlockConfiguration: quantityOver5000},
+		}
+		parentRef := ParentRef{
+			ConsignmentID: &uuid.UUID{},
+			Context:       map[string]any{"declaredQuantityKg": 100.0},
+		}
+
+		mockRepo.On("CreateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return([]model.WorkflowNode{
+			{BaseModel: model.BaseModel{ID: uuid.New()}, WorkflowNodeTemplateID: templateID, State: model.WorkflowNodeStateLocked},
+		}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateLocked
+		})).Return(nil).Once()
+
+		_, newReadyNodes, _, err := sm.InitializeNodesFromTemplates(ctx, nil, parentRef, templates, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, newReadyNodes)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Completion Evaluates Predicate Against Context", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		nodeAID, nodeBID := uuid.New(), uuid.New()
+		consignmentID := uuid.New()
+
+		nodeA := &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: nodeAID},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateInProgress,
+		}
+		nodeB := model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: nodeBID},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateLocked,
+			UnlockConfiguration: &model.UnlockConfig{
+				Expression: &model.UnlockExpression{AllOf: []model.UnlockExpression{
+					{NodeTemplateID: nodeAID, NodeID: &nodeAID, State: strPtr("COMPLETED")},
+					*quantityOver5000.Expression,
+				}},
+			},
+		}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*nodeA, nodeB}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		result, err := sm.TransitionToCompleted(ctx, nil, nodeA, &model.UpdateWorkflowNodeDTO{}, map[string]any{"declaredQuantityKg": 100.0})
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.NewReadyNodes)
+	})
+
+	t.Run("Reevaluate Unlocks Node After Context Change", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		nodeAID, nodeBID := uuid.New(), uuid.New()
+		consignmentID := uuid.New()
+
+		nodeA := &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: nodeAID},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateInProgress,
+		}
+		nodeB := model.WorkflowNode{
+			BaseModel:           model.BaseModel{ID: nodeBID},
+			ConsignmentID:       &consignmentID,
+			State:               model.WorkflowNodeStateLocked,
+			UnlockConfiguration: quantityOver5000,
+		}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*nodeA, nodeB}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].ID == nodeBID && nodes[0].State == model.WorkflowNodeStateReady
+		})).Return(nil).Once()
+
+		result, err := sm.ReevaluateUnlocks(ctx, nil, nodeA, map[string]any{"declaredQuantityKg": 7500.0})
+
+		assert.NoError(t, err)
+		if assert.Len(t, result.NewReadyNodes, 1) {
+			assert.Equal(t, nodeBID, result.NewReadyNodes[0].ID)
+		}
+		assert.False(t, result.WorkflowFinished)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Reevaluate Without Change Updates Nothing", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		consignmentID := uuid.New()
+
+		nodeA := &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateInProgress,
+		}
+		nodeB := model.WorkflowNode{
+			BaseModel:           model.BaseModel{ID: uuid.New()},
+			ConsignmentID:       &consignmentID,
+			State:               model.WorkflowNodeStateLocked,
+			UnlockConfiguration: quantityOver5000,
+		}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{*nodeA, nodeB}, nil).Once()
+
+		result, err := sm.ReevaluateUnlocks(ctx, nil, nodeA, map[string]any{"declaredQuantityKg": 100.0})
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.NewReadyNodes)
+		mockRepo.AssertNotCalled(t, "UpdateWorkflowNodesInTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSkipNodesOnBranchNotTaken(t *testing.T) {
+	ctx := context.Background()
+	approved, fastTracked := "APPROVED", "FAST_TRACKED"
+
+	// Review branches on its outcome: Inspection and then Release on APPROVED, Fast Release on FAST_TRACKED.
+	// The end node waits for Release and Fast Release.
+	type workflow struct {
+		consignmentID                                    uuid.UUID
+		reviewID, inspectionID, releaseID, fastReleaseID uuid.UUID
+		endNodeID                                        uuid.UUID
+		nodes                                            []model.WorkflowNode
+	}
+	newWorkflow := func() workflow {
+		w := workflow{
+			consignmentID: uuid.New(),
+			reviewID:      uuid.New(), inspectionID: uuid.New(), releaseID: uuid.New(), fastReleaseID: uuid.New(),
+			endNodeID: uuid.New(),
+		}
+		node := func(id uuid.UUID, state model.WorkflowNodeState) model.WorkflowNode {
+			return model.WorkflowNode{BaseModel: model.BaseModel{ID: id}, ConsignmentID: &w.consignmentID, WorkflowNodeTemplateID: uuid.New(), State: state}
+		}
+		onReview := func(outcome *string) *model.UnlockConfig {
+			return &model.UnlockConfig{Expression: &model.UnlockExpression{NodeTemplateID: w.reviewID, NodeID: &w.reviewID, Outcome: outcome}}
+		}
+
+		review := node(w.reviewID, model.WorkflowNodeStateInProgress)
+		inspection := node(w.inspectionID, model.WorkflowNodeStateLocked)
+		inspection.UnlockConfiguration = onReview(&approved)
+		release := node(w.releaseID, model.WorkflowNodeStateLocked)
+		release.DependsOn = model.UUIDArray{w.inspectionID}
+		fastRelease := node(w.fastReleaseID, model.WorkflowNodeStateLocked)
+		fastRelease.UnlockConfiguration = onReview(&fastTracked)
+		endNode := node(w.endNodeID, model.WorkflowNodeStateLocked)
+		endNode.WorkflowNodeTemplateID = uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+		endNode.DependsOn = model.UUIDArray{w.releaseID, w.fastReleaseID}
+
+		w.nodes = []model.WorkflowNode{review, inspection, release, fastRelease, endNode}
+		return w
+	}
+	statesOf := func(nodes []model.WorkflowNode) map[uuid.UUID]model.WorkflowNodeState {
+		states := make(map[uuid.UUID]model.WorkflowNodeState, len(nodes))
+		for _, node := range nodes {
+			states[node.ID] = node.State
+		}
+		return states
+	}
+
+	t.Run("Skips Propagate Transitively", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		w := newWorkflow()
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), w.consignmentID).Return(w.nodes, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		review := w.nodes[0]
+		result, err := sm.TransitionToCompleted(ctx, nil, &review, &model.UpdateWorkflowNodeDTO{Outcome: &fastTracked}, nil,
+			&WorkflowCompletionConfig{EndNodeID: &w.endNodeID})
+
+		assert.NoError(t, err)
+		states := statesOf(result.UpdatedNodes)
+		assert.Equal(t, model.WorkflowNodeStateSkipped, states[w.inspectionID])
+		assert.Equal(t, model.WorkflowNodeStateSkipped, states[w.releaseID])
+		assert.Equal(t, model.WorkflowNodeStateReady, states[w.fastReleaseID])
+		assert.NotContains(t, states, w.endNodeID, "the end node is never skipped")
+		if assert.Len(t, result.NewReadyNodes, 1) {
+			assert.Equal(t, w.fastReleaseID, result.NewReadyNodes[0].ID)
+		}
+		assert.False(t, result.WorkflowFinished)
+	})
+
+	t.Run("End Node Completes With Skipped Dependencies", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		w := newWorkflow()
+		w.nodes[0].State, w.nodes[0].Outcome = model.WorkflowNodeStateCompleted, &fastTracked
+		w.nodes[1].State = model.WorkflowNodeStateSkipped
+		w.nodes[2].State = model.WorkflowNodeStateSkipped
+		w.nodes[3].State = model.WorkflowNodeStateInProgress
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), w.consignmentID).Return(w.nodes, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		fastRelease := w.nodes[3]
+		result, err := sm.TransitionToCompleted(ctx, nil, &fastRelease, &model.UpdateWorkflowNodeDTO{}, nil,
+			&WorkflowCompletionConfig{EndNodeID: &w.endNodeID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowNodeStateCompleted, statesOf(result.UpdatedNodes)[w.endNodeID])
+		assert.True(t, result.WorkflowFinished)
+	})
+
+	t.Run("Skipped Nodes Count As Finished Without End Node", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		w := newWorkflow()
+		nodes := w.nodes[:4]
+		nodes[3].UnlockConfiguration = nil
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), w.consignmentID).Return(nodes, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		// Fast Release, left without dependencies, already ran
+		nodes[3].State = model.WorkflowNodeStateCompleted
+		review := nodes[0]
+		result, err := sm.TransitionToCompleted(ctx, nil, &review, &model.UpdateWorkflowNodeDTO{Outcome: &fastTracked}, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.NewReadyNodes)
+		assert.True(t, result.WorkflowFinished)
+	})
+}
+
+func TestSubWorkflow(t *testing.T) {
+	ctx := context.Background()
+	consignmentID := uuid.New()
+	endNodeTemplateID := uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+	approved := "APPROVED"
+	itemIndex := 1
+
+	t.Run("Starts Child Workflow", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		inspectionTemplateID, certificateTemplateID := uuid.New(), uuid.New()
+		nodeTemplates := []model.WorkflowNodeTemplate{
+			{BaseModel: model.BaseModel{ID: inspectionTemplateID}},
+			{BaseModel: model.BaseModel{ID: certificateTemplateID}, DependsOn: model.UUIDArray{inspectionTemplateID}},
+		}
+		workflowTemplate := model.WorkflowTemplate{EndNodeTemplateID: &certificateTemplateID}
+		parent := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateInProgress, ItemIndex: &itemIndex}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{parent}, nil).Once()
+		mockRepo.On("CreateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			for _, node := range nodes {
+				if node.ParentNodeID == nil || *node.ParentNodeID != parent.ID || node.ItemIndex == nil || *node.ItemIndex != itemIndex {
+					return false
+				}
+			}
+			return len(nodes) == 3
+		})).Return([]model.WorkflowNode{
+			{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, ParentNodeID: &parent.ID, WorkflowNodeTemplateID: inspectionTemplateID, State: model.WorkflowNodeStateLocked},
+			{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, ParentNodeID: &parent.ID, WorkflowNodeTemplateID: certificateTemplateID, State: model.WorkflowNodeStateLocked},
+			{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, ParentNodeID: &parent.ID, WorkflowNodeTemplateID: endNodeTemplateID, State: model.WorkflowNodeStateLocked},
+		}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		newReadyNodes, err := sm.StartSubWorkflow(ctx, nil, &parent, ParentRef{ConsignmentID: &consignmentID}, nodeTemplates, workflowTemplate)
+
+		assert.NoError(t, err)
+		if assert.Len(t, newReadyNodes, 1) {
+			assert.Equal(t, inspectionTemplateID, newReadyNodes[0].WorkflowNodeTemplateID)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Child Workflow Already Started", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		parent := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateInProgress}
+		child := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, ParentNodeID: &parent.ID, State: model.WorkflowNodeStateReady}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).Return([]model.WorkflowNode{parent, child}, nil).Once()
+
+		newReadyNodes, err := sm.StartSubWorkflow(ctx, nil, &parent, ParentRef{ConsignmentID: &consignmentID},
+			[]model.WorkflowNodeTemplate{{BaseModel: model.BaseModel{ID: uuid.New()}}}, model.WorkflowTemplate{})
+
+		assert.NoError(t, err)
+		assert.Empty(t, newReadyNodes)
+		mockRepo.AssertNotCalled(t, "CreateWorkflowNodesInTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Child End Node Completes Parent With Outcome", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		parentID, certificateID, childEndID, releaseID, endNodeID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+		parent := model.WorkflowNode{BaseModel: model.BaseModel{ID: parentID}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: uuid.New(), State: model.WorkflowNodeStateInProgress}
+		certificate := model.WorkflowNode{BaseModel: model.BaseModel{ID: certificateID}, ConsignmentID: &consignmentID, ParentNodeID: &parentID, WorkflowNodeTemplateID: uuid.New(), State: model.WorkflowNodeStateInProgress}
+		childEnd := model.WorkflowNode{BaseModel: model.BaseModel{ID: childEndID}, ConsignmentID: &consignmentID, ParentNodeID: &parentID, WorkflowNodeTemplateID: endNodeTemplateID, State: model.WorkflowNodeStateLocked, DependsOn: model.UUIDArray{certificateID}}
+		release := model.WorkflowNode{BaseModel: model.BaseModel{ID: releaseID}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: uuid.New(), State: model.WorkflowNodeStateLocked,
+			UnlockConfiguration: &model.UnlockConfig{Expression: &model.UnlockExpression{NodeTemplateID: parent.WorkflowNodeTemplateID, NodeID: &parentID, Outcome: &approved}}}
+		endNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: endNodeID}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: endNodeTemplateID, State: model.WorkflowNodeStateLocked, DependsOn: model.UUIDArray{releaseID}}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).
+			Return([]model.WorkflowNode{parent, certificate, childEnd, release, endNode}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		result, err := sm.TransitionToCompleted(ctx, nil, &certificate, &model.UpdateWorkflowNodeDTO{Outcome: &approved}, nil,
+			&WorkflowCompletionConfig{EndNodeID: &endNodeID})
+
+		assert.NoError(t, err)
+		updated := make(map[uuid.UUID]model.WorkflowNode, len(result.UpdatedNodes))
+		for _, node := range result.UpdatedNodes {
+			updated[node.ID] = node
+		}
+		assert.Len(t, result.UpdatedNodes, 4)
+		assert.Equal(t, model.WorkflowNodeStateCompleted, updated[childEndID].State)
+		assert.Equal(t, model.WorkflowNodeStateCompleted, updated[parentID].State)
+		if assert.NotNil(t, updated[parentID].Outcome) {
+			assert.Equal(t, approved, *updated[parentID].Outcome)
+		}
+		assert.Equal(t, model.WorkflowNodeStateReady, updated[releaseID].State)
+		assert.NotContains(t, updated, endNodeID)
+		if assert.Len(t, result.CompletedSubWorkflowNodes, 1) {
+			assert.Equal(t, parentID, result.CompletedSubWorkflowNodes[0].ID)
+		}
+		if assert.Len(t, result.NewReadyNodes, 1) {
+			assert.Equal(t, releaseID, result.NewReadyNodes[0].ID)
+		}
+		assert.False(t, result.WorkflowFinished)
+	})
+
+	t.Run("Child Workflow Without End Node Completes Parent When Finished", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		preConsignmentID := uuid.New()
+		parentID, inspectionID, skippedID := uuid.New(), uuid.New(), uuid.New()
+		parent := model.WorkflowNode{BaseModel: model.BaseModel{ID: parentID}, PreConsignmentID: &preConsignmentID, State: model.WorkflowNodeStateInProgress}
+		inspection := model.WorkflowNode{BaseModel: model.BaseModel{ID: inspectionID}, PreConsignmentID: &preConsignmentID, ParentNodeID: &parentID, State: model.WorkflowNodeStateInProgress}
+		skipped := model.WorkflowNode{BaseModel: model.BaseModel{ID: skippedID}, PreConsignmentID: &preConsignmentID, ParentNodeID: &parentID, State: model.WorkflowNodeStateSkipped}
+
+		mockRepo.On("GetWorkflowNodesByPreConsignmentIDInTx", ctx, (*gorm.DB)(nil), preConsignmentID).
+			Return([]model.WorkflowNode{parent, inspection, skipped}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		result, err := sm.TransitionToCompleted(ctx, nil, &inspection, &model.UpdateWorkflowNodeDTO{Outcome: &approved}, nil)
+
+		assert.NoError(t, err)
+		if assert.Len(t, result.CompletedSubWorkflowNodes, 1) {
+			assert.Equal(t, parentID, result.CompletedSubWorkflowNodes[0].ID)
+			assert.Nil(t, result.CompletedSubWorkflowNodes[0].Outcome)
+		}
+		assert.True(t, result.WorkflowFinished)
+	})
+
+	t.Run("Failed Child Leaves Parent In Progress", func(t *testing.T) {
+		mockRepo := new(MockWorkflowNodeRepository)
+		sm := NewWorkflowNodeStateMachine(mockRepo)
+		parentID, inspectionID, certificateID := uuid.New(), uuid.New(), uuid.New()
+		parent := model.WorkflowNode{BaseModel: model.BaseModel{ID: parentID}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateInProgress}
+		inspection := model.WorkflowNode{BaseModel: model.BaseModel{ID: inspectionID}, ConsignmentID: &consignmentID, ParentNodeID: &parentID, State: model.WorkflowNodeStateInProgress}
+		certificate := model.WorkflowNode{BaseModel: model.BaseModel{ID: certificateID}, ConsignmentID: &consignmentID, ParentNodeID: &parentID, State: model.WorkflowNodeStateFailed}
+
+		mockRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, (*gorm.DB)(nil), consignmentID).
+			Return([]model.WorkflowNode{parent, inspection, certificate}, nil).Once()
+		mockRepo.On("UpdateWorkflowNodesInTx", ctx, (*gorm.DB)(nil), mock.Anything).Return(nil).Once()
+
+		result, err := sm.TransitionToCompleted(ctx, nil, &inspection, &model.UpdateWorkflowNodeDTO{}, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.CompletedSubWorkflowNodes)
+		assert.Len(t, result.UpdatedNodes, 1)
+	})
+}
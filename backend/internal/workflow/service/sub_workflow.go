@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// startSubWorkflowInTx creates the child workflow of node if it is a SUB_WORKFLOW node, from the workflow
+// template its configuration references, under the parent entity of parentRef. Returns the nodes of the
+// child workflow that are READY, or none if node is of another type.
+func startSubWorkflowInTx(
+	ctx context.Context,
+	tx *gorm.DB,
+	templateProvider TemplateProvider,
+	stateMachine *WorkflowNodeStateMachine,
+	node *model.WorkflowNode,
+	parentRef ParentRef,
+) ([]model.WorkflowNode, error) {
+	nodeTemplate, err := templateProvider.GetWorkflowNodeTemplateByID(ctx, node.WorkflowNodeTemplateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow node template %s: %w", node.WorkflowNodeTemplateID, err)
+	}
+	if nodeTemplate.Type != taskPlugin.TaskTypeSubWorkflow {
+		return nil, nil
+	}
+
+	cfg, err := taskPlugin.ParseSubWorkflowConfig(nodeTemplate.Config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sub-workflow configuration of node template %s: %w", nodeTemplate.ID, err)
+	}
+	workflowTemplate, err := templateProvider.GetWorkflowTemplateByID(ctx, cfg.WorkflowTemplateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow template %s of sub-workflow: %w", cfg.WorkflowTemplateID, err)
+	}
+	nodeTemplates, err := templateProvider.GetWorkflowNodeTemplatesByIDs(ctx, workflowTemplate.GetNodeTemplateIDs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow node templates of sub-workflow: %w", err)
+	}
+
+	return stateMachine.StartSubWorkflow(ctx, tx, node, parentRef, nodeTemplates, *workflowTemplate)
+}
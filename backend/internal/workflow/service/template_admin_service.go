@@ -0,0 +1,866 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+var (
+	// ErrWorkflowNodeTemplateNotFound is returned when a workflow node template does not exist.
+	ErrWorkflowNodeTemplateNotFound = errors.New("workflow node template not found")
+	// ErrWorkflowTemplateMapNotFound is returned when an HS code mapping does not exist.
+	ErrWorkflowTemplateMapNotFound = errors.New("workflow template map not found")
+	// ErrPreConsignmentTemplateNotFound is returned when a pre-consignment template does not exist.
+	ErrPreConsignmentTemplateNotFound = errors.New("pre-consignment template not found")
+	// ErrInvalidWorkflowNodeTemplate is returned when a workflow node template fails validation.
+	ErrInvalidWorkflowNodeTemplate = errors.New("invalid workflow node template")
+	// ErrInvalidWorkflowTemplate is returned when a workflow template version fails validation.
+	ErrInvalidWorkflowTemplate = errors.New("invalid workflow template")
+	// ErrInvalidWorkflowTemplateMap is returned when an HS code mapping fails validation.
+	ErrInvalidWorkflowTemplateMap = errors.New("invalid workflow template map")
+	// ErrInvalidPreConsignmentTemplate is returned when a pre-consignment template fails validation.
+	ErrInvalidPreConsignmentTemplate = errors.New("invalid pre-consignment template")
+	// ErrWorkflowNodeTemplatePublished is returned when changing a node template used by a published workflow template.
+	ErrWorkflowNodeTemplatePublished = errors.New("workflow node template is used by a published workflow template")
+	// ErrTemplateInUse is returned when deleting a template that other templates or instances still reference.
+	ErrTemplateInUse = errors.New("template is in use")
+	// ErrWorkflowTemplateMapExists is returned when an HS code and consignment flow are already mapped.
+	ErrWorkflowTemplateMapExists = errors.New("HS code and consignment flow are already mapped to a workflow template family")
+)
+
+// TemplateAdminService manages workflow node templates, workflow template versions, HS code mappings and
+// pre-consignment templates on behalf of administrators, validating them before they are saved.
+type TemplateAdminService struct {
+	db              *gorm.DB
+	templateService *TemplateService
+	taskFactory     taskPlugin.TaskFactory
+}
+
+// NewTemplateAdminService creates a new instance of TemplateAdminService. The task factory is used to check
+// that node configurations can be built into task executors.
+func NewTemplateAdminService(db *gorm.DB, templateService *TemplateService, taskFactory taskPlugin.TaskFactory) *TemplateAdminService {
+	return &TemplateAdminService{
+		db:              db,
+		templateService: templateService,
+		taskFactory:     taskFactory,
+	}
+}
+
+// ListWorkflowNodeTemplates retrieves workflow node templates ordered by name.
+func (s *TemplateAdminService) ListWorkflowNodeTemplates(ctx context.Context, offset, limit *int) (*model.WorkflowNodeTemplateListResult, error) {
+	var totalCount int64
+	if err := s.db.WithContext(ctx).Model(&model.WorkflowNodeTemplate{}).Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count workflow node templates: %w", err)
+	}
+
+	finalOffset, finalLimit := utils.GetPaginationParams(offset, limit)
+	nodeTemplates := make([]model.WorkflowNodeTemplate, 0)
+	if totalCount > 0 {
+		if err := s.db.WithContext(ctx).Order("name ASC, id ASC").Offset(finalOffset).Limit(finalLimit).Find(&nodeTemplates).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve workflow node templates: %w", err)
+		}
+	}
+
+	return &model.WorkflowNodeTemplateListResult{
+		TotalCount: totalCount,
+		Items:      nodeTemplates,
+		Offset:     finalOffset,
+		Limit:      finalLimit,
+	}, nil
+}
+
+// GetWorkflowNodeTemplate retrieves a workflow node template by its ID.
+func (s *TemplateAdminService) GetWorkflowNodeTemplate(ctx context.Context, id uuid.UUID) (*model.WorkflowNodeTemplate, error) {
+	return getWorkflowNodeTemplate(s.db.WithContext(ctx), id)
+}
+
+// CreateWorkflowNodeTemplate validates and creates a workflow node template. References to other node
+// templates are checked when the node template is added to a workflow template.
+func (s *TemplateAdminService) CreateWorkflowNodeTemplate(ctx context.Context, dto *model.WorkflowNodeTemplateDTO) (*model.WorkflowNodeTemplate, error) {
+	nodeTemplate := model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: uuid.New()}}
+	applyWorkflowNodeTemplateDTO(&nodeTemplate, dto)
+	if err := s.validateWorkflowNodeTemplate(ctx, &nodeTemplate); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(&nodeTemplate).Error; err != nil {
+		return nil, fmt.Errorf("failed to create workflow node template: %w", err)
+	}
+	return &nodeTemplate, nil
+}
+
+// UpdateWorkflowNodeTemplate validates and replaces a workflow node template. Node templates used by a
+// published workflow template cannot be changed; the DRAFT workflow templates using it must stay valid.
+func (s *TemplateAdminService) UpdateWorkflowNodeTemplate(ctx context.Context, id uuid.UUID, dto *model.WorkflowNodeTemplateDTO) (*model.WorkflowNodeTemplate, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	nodeTemplate, err := getWorkflowNodeTemplate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	workflowTemplates, err := getWorkflowTemplatesContainingNode(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, workflowTemplate := range workflowTemplates {
+		if workflowTemplate.Status != model.WorkflowTemplateStatusDraft {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowNodeTemplatePublished, workflowTemplate.ID)
+		}
+	}
+
+	applyWorkflowNodeTemplateDTO(nodeTemplate, dto)
+	if err := s.validateWorkflowNodeTemplate(ctx, nodeTemplate); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for i := range workflowTemplates {
+		nodeTemplates, err := getWorkflowNodeTemplates(tx, workflowTemplates[i].NodeTemplates)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		for j := range nodeTemplates {
+			if nodeTemplates[j].ID == id {
+				nodeTemplates[j] = *nodeTemplate
+			}
+		}
+		if err := workflowTemplates[i].Validate(nodeTemplates); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: draft workflow template %s would become invalid: %w", ErrInvalidWorkflowNodeTemplate, workflowTemplates[i].ID, err)
+		}
+	}
+
+	if err := tx.Save(nodeTemplate).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update workflow node template %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nodeTemplate, nil
+}
+
+// DeleteWorkflowNodeTemplate deletes a workflow node template that no workflow template uses.
+func (s *TemplateAdminService) DeleteWorkflowNodeTemplate(ctx context.Context, id uuid.UUID) error {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := getWorkflowNodeTemplate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	workflowTemplates, err := getWorkflowTemplatesContainingNode(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(workflowTemplates) > 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: workflow node template %s is used by workflow template %s", ErrTemplateInUse, id, workflowTemplates[0].ID)
+	}
+
+	if err := tx.Delete(&model.WorkflowNodeTemplate{}, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete workflow node template %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// validateWorkflowNodeTemplate checks the node template on its own and that its configuration builds a
+// task executor.
+func (s *TemplateAdminService) validateWorkflowNodeTemplate(ctx context.Context, nodeTemplate *model.WorkflowNodeTemplate) error {
+	if err := nodeTemplate.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWorkflowNodeTemplate, err)
+	}
+	if _, err := s.taskFactory.BuildExecutor(ctx, nodeTemplate.Type, nodeTemplate.Config); err != nil {
+		return fmt.Errorf("%w: invalid %s configuration: %w", ErrInvalidWorkflowNodeTemplate, nodeTemplate.Type, err)
+	}
+	return nil
+}
+
+func applyWorkflowNodeTemplateDTO(nodeTemplate *model.WorkflowNodeTemplate, dto *model.WorkflowNodeTemplateDTO) {
+	nodeTemplate.Name = dto.Name
+	nodeTemplate.Description = dto.Description
+	nodeTemplate.Type = dto.Type
+	nodeTemplate.Config = dto.Config
+	nodeTemplate.DependsOn = dto.DependsOn
+	if nodeTemplate.DependsOn == nil {
+		nodeTemplate.DependsOn = model.UUIDArray{}
+	}
+	nodeTemplate.UnlockConfiguration = dto.UnlockConfiguration
+	nodeTemplate.ReopenPolicy = dto.ReopenPolicy
+	nodeTemplate.SLA = dto.SLA
+	nodeTemplate.ContextMerge = dto.ContextMerge
+	nodeTemplate.GlobalContextMode = dto.GlobalContextMode
+}
+
+// ListWorkflowTemplates retrieves workflow template versions matching the filter, ordered by family and
+// newest version first.
+func (s *TemplateAdminService) ListWorkflowTemplates(ctx context.Context, filter model.WorkflowTemplateFilter) (*model.WorkflowTemplateListResult, error) {
+	applyFilter := func(query *gorm.DB) *gorm.DB {
+		if filter.FamilyID != nil {
+			query = query.Where("family_id = ?", *filter.FamilyID)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		return query
+	}
+
+	var totalCount int64
+	if err := applyFilter(s.db.WithContext(ctx).Model(&model.WorkflowTemplate{})).Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count workflow templates: %w", err)
+	}
+
+	finalOffset, finalLimit := utils.GetPaginationParams(filter.Offset, filter.Limit)
+	workflowTemplates := make([]model.WorkflowTemplate, 0)
+	if totalCount > 0 {
+		if err := applyFilter(s.db.WithContext(ctx)).Order("family_id ASC, version_number DESC").Offset(finalOffset).Limit(finalLimit).Find(&workflowTemplates).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve workflow templates: %w", err)
+		}
+	}
+
+	return &model.WorkflowTemplateListResult{
+		TotalCount: totalCount,
+		Items:      workflowTemplates,
+		Offset:     finalOffset,
+		Limit:      finalLimit,
+	}, nil
+}
+
+// GetWorkflowTemplate retrieves a workflow template version by its ID.
+func (s *TemplateAdminService) GetWorkflowTemplate(ctx context.Context, id uuid.UUID) (*model.WorkflowTemplate, error) {
+	return getWorkflowTemplate(s.db.WithContext(ctx), id)
+}
+
+// CreateWorkflowTemplate validates the workflow template against its node templates and creates it as a
+// DRAFT version, either of a new family or of the family given in the DTO.
+func (s *TemplateAdminService) CreateWorkflowTemplate(ctx context.Context, dto *model.WorkflowTemplateDTO) (*model.WorkflowTemplate, error) {
+	workflowTemplate := model.WorkflowTemplate{}
+	if dto.FamilyID != nil {
+		workflowTemplate.FamilyID = *dto.FamilyID
+	}
+	applyWorkflowTemplateDTO(&workflowTemplate, dto)
+	if err := s.ValidateWorkflowTemplateInTx(ctx, s.db.WithContext(ctx), &workflowTemplate); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateService.CreateWorkflowTemplate(ctx, &workflowTemplate, nil); err != nil {
+		return nil, err
+	}
+	return &workflowTemplate, nil
+}
+
+// UpdateWorkflowTemplate validates and replaces a DRAFT workflow template version. Its family and version
+// number do not change.
+func (s *TemplateAdminService) UpdateWorkflowTemplate(ctx context.Context, id uuid.UUID, dto *model.WorkflowTemplateDTO) (*model.WorkflowTemplate, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	workflowTemplate, err := getWorkflowTemplate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if workflowTemplate.Status != model.WorkflowTemplateStatusDraft {
+		tx.Rollback()
+		return nil, ErrWorkflowTemplateNotDraft
+	}
+
+	applyWorkflowTemplateDTO(workflowTemplate, dto)
+	if err := s.ValidateWorkflowTemplateInTx(ctx, tx, workflowTemplate); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Save(workflowTemplate).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update workflow template %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return workflowTemplate, nil
+}
+
+// DeleteWorkflowTemplate deletes a DRAFT workflow template version that no pre-consignment template uses.
+// Its node templates and family are kept.
+func (s *TemplateAdminService) DeleteWorkflowTemplate(ctx context.Context, id uuid.UUID) error {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	workflowTemplate, err := getWorkflowTemplate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if workflowTemplate.Status != model.WorkflowTemplateStatusDraft {
+		tx.Rollback()
+		return ErrWorkflowTemplateNotDraft
+	}
+
+	var usageCount int64
+	if err := tx.Model(&model.PreConsignmentTemplate{}).Where("workflow_template_id = ?", id).Count(&usageCount).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to count pre-consignment templates using workflow template %s: %w", id, err)
+	}
+	if usageCount > 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: workflow template %s is used by %d pre-consignment templates", ErrTemplateInUse, id, usageCount)
+	}
+
+	if err := tx.Delete(&model.WorkflowTemplate{}, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete workflow template %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ValidateWorkflowTemplateInTx checks that the workflow template forms a valid graph with its node
+// templates and that every node configuration builds a task executor. The node templates are locked in
+// share mode so that they cannot change before the transaction ends.
+func (s *TemplateAdminService) ValidateWorkflowTemplateInTx(ctx context.Context, tx *gorm.DB, workflowTemplate *model.WorkflowTemplate) error {
+	nodeTemplates, err := getWorkflowNodeTemplates(tx.Clauses(clause.Locking{Strength: "SHARE"}), workflowTemplate.NodeTemplates)
+	if err != nil {
+		return err
+	}
+	if err := workflowTemplate.Validate(nodeTemplates); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWorkflowTemplate, err)
+	}
+	for i := range nodeTemplates {
+		if _, err := s.taskFactory.BuildExecutor(ctx, nodeTemplates[i].Type, nodeTemplates[i].Config); err != nil {
+			return fmt.Errorf("%w: node template %s has an invalid %s configuration: %w", ErrInvalidWorkflowTemplate, nodeTemplates[i].ID, nodeTemplates[i].Type, err)
+		}
+		if nodeTemplates[i].Type == taskPlugin.TaskTypeSubWorkflow {
+			if err := validateSubWorkflowInTx(tx, &nodeTemplates[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, requirement := range workflowTemplate.RequiredPreConsignments {
+		if _, err := getPreConsignmentTemplate(tx, requirement.PreConsignmentTemplateID); err != nil {
+			if errors.Is(err, ErrPreConsignmentTemplateNotFound) {
+				return fmt.Errorf("%w: required %v", ErrInvalidWorkflowTemplate, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSubWorkflowInTx checks that a SUB_WORKFLOW node template references a published workflow template
+// version. Published versions cannot change, and a template can only reference versions published before it,
+// so child workflows never form a cycle.
+func validateSubWorkflowInTx(tx *gorm.DB, nodeTemplate *model.WorkflowNodeTemplate) error {
+	cfg, err := taskPlugin.ParseSubWorkflowConfig(nodeTemplate.Config)
+	if err != nil {
+		return fmt.Errorf("%w: node template %s has an invalid %s configuration: %w", ErrInvalidWorkflowTemplate, nodeTemplate.ID, nodeTemplate.Type, err)
+	}
+	workflowTemplate, err := getWorkflowTemplate(tx, cfg.WorkflowTemplateID)
+	if err != nil {
+		if errors.Is(err, ErrWorkflowTemplateNotFound) {
+			return fmt.Errorf("%w: node template %s: %v", ErrInvalidWorkflowTemplate, nodeTemplate.ID, err)
+		}
+		return err
+	}
+	if workflowTemplate.Status != model.WorkflowTemplateStatusPublished {
+		return fmt.Errorf("%w: node template %s uses workflow template %s, which is not published", ErrInvalidWorkflowTemplate, nodeTemplate.ID, workflowTemplate.ID)
+	}
+	return nil
+}
+
+func applyWorkflowTemplateDTO(workflowTemplate *model.WorkflowTemplate, dto *model.WorkflowTemplateDTO) {
+	workflowTemplate.Name = dto.Name
+	workflowTemplate.Description = dto.Description
+	workflowTemplate.Version = dto.Version
+	workflowTemplate.NodeTemplates = dto.NodeTemplates
+	workflowTemplate.EndNodeTemplateID = dto.EndNodeTemplateID
+	workflowTemplate.RequiredPreConsignments = dto.RequiredPreConsignments
+}
+
+// ListWorkflowTemplateMaps retrieves HS code mappings matching the filter.
+func (s *TemplateAdminService) ListWorkflowTemplateMaps(ctx context.Context, filter model.WorkflowTemplateMapFilter) (*model.WorkflowTemplateMapListResult, error) {
+	applyFilter := func(query *gorm.DB) *gorm.DB {
+		if filter.HSCodeID != nil {
+			query = query.Where("hs_code_id = ?", *filter.HSCodeID)
+		}
+		return query
+	}
+
+	var totalCount int64
+	if err := applyFilter(s.db.WithContext(ctx).Model(&model.WorkflowTemplateMap{})).Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count workflow template maps: %w", err)
+	}
+
+	finalOffset, finalLimit := utils.GetPaginationParams(filter.Offset, filter.Limit)
+	maps := make([]model.WorkflowTemplateMap, 0)
+	if totalCount > 0 {
+		if err := applyFilter(s.db.WithContext(ctx)).Preload("HSCode").Preload("WorkflowTemplateFamily").
+			Order("created_at ASC, id ASC").Offset(finalOffset).Limit(finalLimit).Find(&maps).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve workflow template maps: %w", err)
+		}
+	}
+
+	return &model.WorkflowTemplateMapListResult{
+		TotalCount: totalCount,
+		Items:      maps,
+		Offset:     finalOffset,
+		Limit:      finalLimit,
+	}, nil
+}
+
+// GetWorkflowTemplateMap retrieves an HS code mapping by its ID.
+func (s *TemplateAdminService) GetWorkflowTemplateMap(ctx context.Context, id uuid.UUID) (*model.WorkflowTemplateMap, error) {
+	var workflowTemplateMap model.WorkflowTemplateMap
+	result := s.db.WithContext(ctx).Preload("HSCode").Preload("WorkflowTemplateFamily").First(&workflowTemplateMap, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowTemplateMapNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow template map %s: %w", id, result.Error)
+	}
+	return &workflowTemplateMap, nil
+}
+
+// CreateWorkflowTemplateMap maps an HS code and consignment flow to a workflow template family.
+func (s *TemplateAdminService) CreateWorkflowTemplateMap(ctx context.Context, dto *model.WorkflowTemplateMapDTO) (*model.WorkflowTemplateMap, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	workflowTemplateMap := model.WorkflowTemplateMap{
+		HSCodeID:                 dto.HSCodeID,
+		ConsignmentFlow:          dto.ConsignmentFlow,
+		WorkflowTemplateFamilyID: dto.WorkflowTemplateFamilyID,
+	}
+	if err := validateWorkflowTemplateMapInTx(tx, &workflowTemplateMap); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Omit(clause.Associations).Create(&workflowTemplateMap).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create workflow template map: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &workflowTemplateMap, nil
+}
+
+// UpdateWorkflowTemplateMap replaces an HS code mapping. Consignments created before the change keep the
+// workflow they were created with.
+func (s *TemplateAdminService) UpdateWorkflowTemplateMap(ctx context.Context, id uuid.UUID, dto *model.WorkflowTemplateMapDTO) (*model.WorkflowTemplateMap, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var workflowTemplateMap model.WorkflowTemplateMap
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&workflowTemplateMap, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowTemplateMapNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow template map %s: %w", id, err)
+	}
+
+	workflowTemplateMap.HSCodeID = dto.HSCodeID
+	workflowTemplateMap.ConsignmentFlow = dto.ConsignmentFlow
+	workflowTemplateMap.WorkflowTemplateFamilyID = dto.WorkflowTemplateFamilyID
+	if err := validateWorkflowTemplateMapInTx(tx, &workflowTemplateMap); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Omit(clause.Associations).Save(&workflowTemplateMap).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update workflow template map %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &workflowTemplateMap, nil
+}
+
+// DeleteWorkflowTemplateMap deletes an HS code mapping.
+func (s *TemplateAdminService) DeleteWorkflowTemplateMap(ctx context.Context, id uuid.UUID) error {
+	result := s.db.WithContext(ctx).Delete(&model.WorkflowTemplateMap{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete workflow template map %s: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrWorkflowTemplateMapNotFound, id)
+	}
+	return nil
+}
+
+// validateWorkflowTemplateMapInTx checks that the mapping references an existing HS code and workflow
+// template family, and that no other mapping exists for the HS code and consignment flow.
+func validateWorkflowTemplateMapInTx(tx *gorm.DB, workflowTemplateMap *model.WorkflowTemplateMap) error {
+	switch workflowTemplateMap.ConsignmentFlow {
+	case model.ConsignmentFlowImport, model.ConsignmentFlowExport:
+	default:
+		return fmt.Errorf("%w: invalid consignment flow %q", ErrInvalidWorkflowTemplateMap, workflowTemplateMap.ConsignmentFlow)
+	}
+
+	var count int64
+	if err := tx.Model(&model.HSCode{}).Where("id = ?", workflowTemplateMap.HSCodeID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to retrieve HS code %s: %w", workflowTemplateMap.HSCodeID, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: HS code %s does not exist", ErrInvalidWorkflowTemplateMap, workflowTemplateMap.HSCodeID)
+	}
+
+	if err := tx.Model(&model.WorkflowTemplateFamily{}).Where("id = ?", workflowTemplateMap.WorkflowTemplateFamilyID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to retrieve workflow template family %s: %w", workflowTemplateMap.WorkflowTemplateFamilyID, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: workflow template family %s does not exist", ErrInvalidWorkflowTemplateMap, workflowTemplateMap.WorkflowTemplateFamilyID)
+	}
+
+	query := tx.Model(&model.WorkflowTemplateMap{}).
+		Where("hs_code_id = ? AND consignment_flow = ?", workflowTemplateMap.HSCodeID, workflowTemplateMap.ConsignmentFlow)
+	if workflowTemplateMap.ID != uuid.Nil {
+		query = query.Where("id <> ?", workflowTemplateMap.ID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check existing workflow template maps: %w", err)
+	}
+	if count > 0 {
+		return ErrWorkflowTemplateMapExists
+	}
+	return nil
+}
+
+// ListPreConsignmentTemplates retrieves pre-consignment templates ordered by name.
+func (s *TemplateAdminService) ListPreConsignmentTemplates(ctx context.Context, offset, limit *int) (*model.PreConsignmentTemplateListResult, error) {
+	var totalCount int64
+	if err := s.db.WithContext(ctx).Model(&model.PreConsignmentTemplate{}).Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count pre-consignment templates: %w", err)
+	}
+
+	finalOffset, finalLimit := utils.GetPaginationParams(offset, limit)
+	preConsignmentTemplates := make([]model.PreConsignmentTemplate, 0)
+	if totalCount > 0 {
+		if err := s.db.WithContext(ctx).Order("name ASC, id ASC").Offset(finalOffset).Limit(finalLimit).Find(&preConsignmentTemplates).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve pre-consignment templates: %w", err)
+		}
+	}
+
+	return &model.PreConsignmentTemplateListResult{
+		TotalCount: totalCount,
+		Items:      preConsignmentTemplates,
+		Offset:     finalOffset,
+		Limit:      finalLimit,
+	}, nil
+}
+
+// GetPreConsignmentTemplate retrieves a pre-consignment template by its ID.
+func (s *TemplateAdminService) GetPreConsignmentTemplate(ctx context.Context, id uuid.UUID) (*model.PreConsignmentTemplate, error) {
+	return getPreConsignmentTemplate(s.db.WithContext(ctx), id)
+}
+
+// CreatePreConsignmentTemplate validates and creates a pre-consignment template.
+func (s *TemplateAdminService) CreatePreConsignmentTemplate(ctx context.Context, dto *model.PreConsignmentTemplateDTO) (*model.PreConsignmentTemplate, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	preConsignmentTemplate := model.PreConsignmentTemplate{BaseModel: model.BaseModel{ID: uuid.New()}}
+	applyPreConsignmentTemplateDTO(&preConsignmentTemplate, dto)
+	if err := validatePreConsignmentTemplateInTx(tx, &preConsignmentTemplate); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Create(&preConsignmentTemplate).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create pre-consignment template: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &preConsignmentTemplate, nil
+}
+
+// UpdatePreConsignmentTemplate validates and replaces a pre-consignment template. Pre-consignments already
+// created from it keep their workflow.
+func (s *TemplateAdminService) UpdatePreConsignmentTemplate(ctx context.Context, id uuid.UUID, dto *model.PreConsignmentTemplateDTO) (*model.PreConsignmentTemplate, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	preConsignmentTemplate, err := getPreConsignmentTemplate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	applyPreConsignmentTemplateDTO(preConsignmentTemplate, dto)
+	if err := validatePreConsignmentTemplateInTx(tx, preConsignmentTemplate); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Save(preConsignmentTemplate).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update pre-consignment template %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return preConsignmentTemplate, nil
+}
+
+// DeletePreConsignmentTemplate deletes a pre-consignment template that has no pre-consignments, that no
+// other pre-consignment template depends on and that no workflow template requires.
+func (s *TemplateAdminService) DeletePreConsignmentTemplate(ctx context.Context, id uuid.UUID) error {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := getPreConsignmentTemplate(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var usageCount int64
+	if err := tx.Model(&model.PreConsignment{}).Where("pre_consignment_template_id = ?", id).Count(&usageCount).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to count pre-consignments of template %s: %w", id, err)
+	}
+	if usageCount > 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: pre-consignment template %s has %d pre-consignments", ErrTemplateInUse, id, usageCount)
+	}
+
+	if err := tx.Model(&model.PreConsignmentTemplate{}).Where("depends_on @> ?::jsonb", fmt.Sprintf("[%q]", id)).Count(&usageCount).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to count pre-consignment templates depending on %s: %w", id, err)
+	}
+	if usageCount > 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: %d pre-consignment templates depend on pre-consignment template %s", ErrTemplateInUse, usageCount, id)
+	}
+
+	if err := tx.Model(&model.WorkflowTemplate{}).Where("required_pre_consignments @> ?::jsonb", fmt.Sprintf(`[{"preConsignmentTemplateId":%q}]`, id)).Count(&usageCount).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to count workflow templates requiring pre-consignment template %s: %w", id, err)
+	}
+	if usageCount > 0 {
+		tx.Rollback()
+		return fmt.Errorf("%w: %d workflow templates require pre-consignment template %s", ErrTemplateInUse, usageCount, id)
+	}
+
+	if err := tx.Delete(&model.PreConsignmentTemplate{}, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete pre-consignment template %s: %w", id, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// validatePreConsignmentTemplateInTx checks that the pre-consignment template uses a published workflow
+// template version, has a well-formed validity, if any, and depends only on existing pre-consignment
+// templates without forming a cycle.
+func validatePreConsignmentTemplateInTx(tx *gorm.DB, preConsignmentTemplate *model.PreConsignmentTemplate) error {
+	if len(strings.TrimSpace(preConsignmentTemplate.Name)) == 0 {
+		return fmt.Errorf("%w: name must not be blank", ErrInvalidPreConsignmentTemplate)
+	}
+	if preConsignmentTemplate.Validity != nil {
+		if err := preConsignmentTemplate.Validity.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPreConsignmentTemplate, err)
+		}
+	}
+
+	workflowTemplate, err := getWorkflowTemplate(tx, preConsignmentTemplate.WorkflowTemplateID)
+	if err != nil {
+		if errors.Is(err, ErrWorkflowTemplateNotFound) {
+			return fmt.Errorf("%w: %v", ErrInvalidPreConsignmentTemplate, err)
+		}
+		return err
+	}
+	if workflowTemplate.Status != model.WorkflowTemplateStatusPublished {
+		return fmt.Errorf("%w: workflow template %s is not published", ErrInvalidPreConsignmentTemplate, workflowTemplate.ID)
+	}
+
+	for i, dependencyID := range preConsignmentTemplate.DependsOn {
+		id, err := uuid.Parse(dependencyID)
+		if err != nil {
+			return fmt.Errorf("%w: dependsOn has invalid pre-consignment template ID %q", ErrInvalidPreConsignmentTemplate, dependencyID)
+		}
+		if id == preConsignmentTemplate.ID {
+			return fmt.Errorf("%w: pre-consignment template cannot depend on itself", ErrInvalidPreConsignmentTemplate)
+		}
+		if slices.Contains(preConsignmentTemplate.DependsOn[:i], dependencyID) {
+			return fmt.Errorf("%w: dependsOn lists pre-consignment template %s more than once", ErrInvalidPreConsignmentTemplate, dependencyID)
+		}
+	}
+
+	// Walk the dependencies of the other templates to find a path back to this one
+	var others []model.PreConsignmentTemplate
+	if err := tx.Select("id", "depends_on").Where("id <> ?", preConsignmentTemplate.ID).Find(&others).Error; err != nil {
+		return fmt.Errorf("failed to retrieve pre-consignment templates: %w", err)
+	}
+	dependsOn := make(map[string][]string, len(others)+1)
+	for _, other := range others {
+		dependsOn[other.ID.String()] = other.DependsOn
+	}
+	self := preConsignmentTemplate.ID.String()
+	dependsOn[self] = preConsignmentTemplate.DependsOn
+
+	visited := make(map[string]bool)
+	queue := slices.Clone(preConsignmentTemplate.DependsOn)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		dependencies, exists := dependsOn[current]
+		if !exists {
+			return fmt.Errorf("%w: pre-consignment template %s does not exist", ErrInvalidPreConsignmentTemplate, current)
+		}
+		if slices.Contains(dependencies, self) {
+			return fmt.Errorf("%w: dependency cycle through pre-consignment template %s", ErrInvalidPreConsignmentTemplate, current)
+		}
+		queue = append(queue, dependencies...)
+	}
+	return nil
+}
+
+func applyPreConsignmentTemplateDTO(preConsignmentTemplate *model.PreConsignmentTemplate, dto *model.PreConsignmentTemplateDTO) {
+	preConsignmentTemplate.Name = dto.Name
+	preConsignmentTemplate.Description = dto.Description
+	preConsignmentTemplate.WorkflowTemplateID = dto.WorkflowTemplateID
+	preConsignmentTemplate.DependsOn = dto.DependsOn
+	if preConsignmentTemplate.DependsOn == nil {
+		preConsignmentTemplate.DependsOn = []string{}
+	}
+	preConsignmentTemplate.Validity = dto.Validity
+}
+
+// getWorkflowNodeTemplate retrieves a workflow node template, or ErrWorkflowNodeTemplateNotFound if it does not exist.
+func getWorkflowNodeTemplate(db *gorm.DB, id uuid.UUID) (*model.WorkflowNodeTemplate, error) {
+	var nodeTemplate model.WorkflowNodeTemplate
+	if err := db.First(&nodeTemplate, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowNodeTemplateNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow node template %s: %w", id, err)
+	}
+	return &nodeTemplate, nil
+}
+
+// getWorkflowNodeTemplates retrieves the node templates with the given IDs. Missing node templates are
+// reported by WorkflowTemplate.Validate.
+func getWorkflowNodeTemplates(db *gorm.DB, ids []uuid.UUID) ([]model.WorkflowNodeTemplate, error) {
+	nodeTemplates := make([]model.WorkflowNodeTemplate, 0, len(ids))
+	if len(ids) == 0 {
+		return nodeTemplates, nil
+	}
+	if err := db.Where("id IN ?", ids).Find(&nodeTemplates).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow node templates: %w", err)
+	}
+	return nodeTemplates, nil
+}
+
+// getWorkflowTemplate retrieves a workflow template version, or ErrWorkflowTemplateNotFound if it does not exist.
+func getWorkflowTemplate(db *gorm.DB, id uuid.UUID) (*model.WorkflowTemplate, error) {
+	var workflowTemplate model.WorkflowTemplate
+	if err := db.First(&workflowTemplate, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowTemplateNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow template %s: %w", id, err)
+	}
+	return &workflowTemplate, nil
+}
+
+// getWorkflowTemplatesContainingNode retrieves the workflow template versions that list the node template.
+func getWorkflowTemplatesContainingNode(db *gorm.DB, nodeTemplateID uuid.UUID) ([]model.WorkflowTemplate, error) {
+	var workflowTemplates []model.WorkflowTemplate
+	if err := db.Where("nodes @> ?::jsonb", fmt.Sprintf("[%q]", nodeTemplateID)).Find(&workflowTemplates).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow templates using node template %s: %w", nodeTemplateID, err)
+	}
+	return workflowTemplates, nil
+}
+
+// getPreConsignmentTemplate retrieves a pre-consignment template, or ErrPreConsignmentTemplateNotFound if it does
+// not exist.
+func getPreConsignmentTemplate(db *gorm.DB, id uuid.UUID) (*model.PreConsignmentTemplate, error) {
+	var preConsignmentTemplate model.PreConsignmentTemplate
+	if err := db.First(&preConsignmentTemplate, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrPreConsignmentTemplateNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve pre-consignment template %s: %w", id, err)
+	}
+	return &preConsignmentTemplate, nil
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+type MockTaskFactory struct {
+	mock.Mock
+}
+
+func (m *MockTaskFactory) BuildExecutor(ctx context.Context, taskType taskPlugin.Type, config json.RawMessage) (taskPlugin.Executor, error) {
+	args := m.Called(ctx, taskType, config)
+	return taskPlugin.Executor{}, args.Error(0)
+}
+
+func newTestTemplateAdminService(t *testing.T) (*TemplateAdminService, sqlmock.Sqlmock, *MockTaskFactory) {
+	db, sqlMock := setupTestDB(t)
+	factory := new(MockTaskFactory)
+	return NewTemplateAdminService(db, NewTemplateService(db), factory), sqlMock, factory
+}
+
+var nodeTemplateColumns = []string{"id", "name", "type", "config", "depends_on", "unlock_configuration"}
+
+func TestTemplateAdminService_CreateWorkflowNodeTemplate(t *testing.T) {
+	ctx := context.Background()
+	dto := func() *model.WorkflowNodeTemplateDTO {
+		return &model.WorkflowNodeTemplateDTO{
+			Name:   "Customs Declaration",
+			Type:   taskPlugin.TaskTypeSimpleForm,
+			Config: json.RawMessage(`{"formId":"11111111-1111-1111-1111-111111111111"}`),
+		}
+	}
+
+	t.Run("Creates", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		factory.On("BuildExecutor", ctx, taskPlugin.TaskTypeSimpleForm, mock.Anything).Return(nil).Once()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "workflow_node_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		result, err := service.CreateWorkflowNodeTemplate(ctx, dto())
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, result.ID)
+		assert.Equal(t, model.UUIDArray{}, result.DependsOn)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid Definition", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		d := dto()
+		d.Name = ""
+
+		_, err := service.CreateWorkflowNodeTemplate(ctx, d)
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowNodeTemplate)
+		factory.AssertNotCalled(t, "BuildExecutor", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Config Does Not Build", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		factory.On("BuildExecutor", ctx, taskPlugin.TaskTypeSimpleForm, mock.Anything).Return(errors.New("failed to unmarshal config")).Once()
+
+		_, err := service.CreateWorkflowNodeTemplate(ctx, dto())
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowNodeTemplate)
+		assert.ErrorContains(t, err, "failed to unmarshal config")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateAdminService_CreateWorkflowTemplate(t *testing.T) {
+	ctx := context.Background()
+	a, b := uuid.New(), uuid.New()
+	dto := func() *model.WorkflowTemplateDTO {
+		return &model.WorkflowTemplateDTO{Name: "Export", Version: "1.0", NodeTemplates: model.UUIDArray{a, b}, EndNodeTemplateID: &b}
+	}
+	expectNodeTemplates := func(sqlMock sqlmock.Sqlmock, aDependsOn string) {
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE id IN \(\$1,\$2\) FOR SHARE`).
+			WithArgs(a, b).
+			WillReturnRows(sqlmock.NewRows(nodeTemplateColumns).
+				AddRow(a, "A", "SIMPLE_FORM", []byte(`{}`), []byte(aDependsOn), nil).
+				AddRow(b, "B", "TIMER", []byte(`{"duration":"1h"}`), []byte(fmt.Sprintf(`[%q]`, a)), nil))
+	}
+
+	t.Run("Creates Draft", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		expectNodeTemplates(sqlMock, `[]`)
+		factory.On("BuildExecutor", ctx, mock.Anything, mock.Anything).Return(nil).Twice()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "workflow_template_families"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		result, err := service.CreateWorkflowTemplate(ctx, dto())
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowTemplateStatusDraft, result.Status)
+		assert.Equal(t, 1, result.VersionNumber)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		factory.AssertExpectations(t)
+	})
+
+	t.Run("Dependency Cycle", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		expectNodeTemplates(sqlMock, fmt.Sprintf(`[%q]`, b))
+
+		_, err := service.CreateWorkflowTemplate(ctx, dto())
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplate)
+		assert.ErrorContains(t, err, "dependency cycle")
+		factory.AssertNotCalled(t, "BuildExecutor", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("End Node Outside Template", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		expectNodeTemplates(sqlMock, `[]`)
+		d := dto()
+		other := uuid.New()
+		d.EndNodeTemplateID = &other
+
+		_, err := service.CreateWorkflowTemplate(ctx, d)
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplate)
+		assert.ErrorContains(t, err, "end node template")
+	})
+
+	t.Run("Config Does Not Build", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		expectNodeTemplates(sqlMock, `[]`)
+		factory.On("BuildExecutor", ctx, taskPlugin.TaskTypeSimpleForm, mock.Anything).Return(nil).Once()
+		factory.On("BuildExecutor", ctx, taskPlugin.TaskTypeTimer, mock.Anything).Return(errors.New("invalid timer duration")).Once()
+
+		_, err := service.CreateWorkflowTemplate(ctx, dto())
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplate)
+		assert.ErrorContains(t, err, b.String())
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Sub-Workflow Not Published", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		childID := uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE id IN \(\$1,\$2\) FOR SHARE`).
+			WithArgs(a, b).
+			WillReturnRows(sqlmock.NewRows(nodeTemplateColumns).
+				AddRow(a, "A", "SIMPLE_FORM", []byte(`{}`), []byte(`[]`), nil).
+				AddRow(b, "B", "SUB_WORKFLOW", []byte(fmt.Sprintf(`{"workflowTemplateId":%q}`, childID)), []byte(fmt.Sprintf(`[%q]`, a)), nil))
+		factory.On("BuildExecutor", ctx, mock.Anything, mock.Anything).Return(nil).Twice()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(childID, "DRAFT"))
+
+		_, err := service.CreateWorkflowTemplate(ctx, dto())
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplate)
+		assert.ErrorContains(t, err, "not published")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Required Pre-Consignment Template Missing", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		expectNodeTemplates(sqlMock, `[]`)
+		factory.On("BuildExecutor", ctx, mock.Anything, mock.Anything).Return(nil).Twice()
+		preConsignmentTemplateID := uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1`).
+			WithArgs(preConsignmentTemplateID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		d := dto()
+		d.RequiredPreConsignments = []model.PreConsignmentRequirement{{PreConsignmentTemplateID: preConsignmentTemplateID, ContextKey: "br"}}
+
+		_, err := service.CreateWorkflowTemplate(ctx, d)
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplate)
+		assert.ErrorContains(t, err, preConsignmentTemplateID.String())
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateAdminService_UpdateWorkflowTemplate(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	t.Run("Published", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(id, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(id, "PUBLISHED"))
+		sqlMock.ExpectRollback()
+
+		_, err := service.UpdateWorkflowTemplate(ctx, id, &model.WorkflowTemplateDTO{Name: "Export", Version: "1.1"})
+
+		assert.ErrorIs(t, err, ErrWorkflowTemplateNotDraft)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		_, err := service.UpdateWorkflowTemplate(ctx, id, &model.WorkflowTemplateDTO{})
+
+		assert.ErrorIs(t, err, ErrWorkflowTemplateNotFound)
+	})
+}
+
+func TestTemplateAdminService_UpdateWorkflowNodeTemplate(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	dto := &model.WorkflowNodeTemplateDTO{Name: "Wait", Type: taskPlugin.TaskTypeTimer, Config: json.RawMessage(`{"duration":"2h"}`)}
+	expectNodeTemplate := func(sqlMock sqlmock.Sqlmock) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(id, 1).
+			WillReturnRows(sqlmock.NewRows(nodeTemplateColumns).AddRow(id, "Wait", "TIMER", []byte(`{"duration":"1h"}`), []byte(`[]`), nil))
+	}
+
+	t.Run("Used By Published Template", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		expectNodeTemplate(sqlMock)
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates" WHERE nodes @> \$1::jsonb`).
+			WithArgs(fmt.Sprintf(`[%q]`, id)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "nodes"}).AddRow(uuid.New(), "PUBLISHED", []byte(fmt.Sprintf(`[%q]`, id))))
+		sqlMock.ExpectRollback()
+
+		_, err := service.UpdateWorkflowNodeTemplate(ctx, id, dto)
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeTemplatePublished)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		service, sqlMock, factory := newTestTemplateAdminService(t)
+		expectNodeTemplate(sqlMock)
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates" WHERE nodes @> \$1::jsonb`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+		factory.On("BuildExecutor", ctx, taskPlugin.TaskTypeTimer, json.RawMessage(`{"duration":"2h"}`)).Return(nil).Once()
+		sqlMock.ExpectExec(`UPDATE "workflow_node_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		result, err := service.UpdateWorkflowNodeTemplate(ctx, id, dto)
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"duration":"2h"}`, string(result.Config))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateAdminService_DeleteWorkflowNodeTemplate(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	service, sqlMock, _ := newTestTemplateAdminService(t)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates" WHERE nodes @> \$1::jsonb`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(uuid.New(), "DRAFT"))
+	sqlMock.ExpectRollback()
+
+	err := service.DeleteWorkflowNodeTemplate(ctx, id)
+
+	assert.ErrorIs(t, err, ErrTemplateInUse)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTemplateAdminService_CreateWorkflowTemplateMap(t *testing.T) {
+	ctx := context.Background()
+	dto := &model.WorkflowTemplateMapDTO{HSCodeID: uuid.New(), ConsignmentFlow: model.ConsignmentFlowExport, WorkflowTemplateFamilyID: uuid.New()}
+	expectCount := func(sqlMock sqlmock.Sqlmock, table string, count int) {
+		sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "` + table + `"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+	}
+
+	t.Run("Creates", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		expectCount(sqlMock, "hs_codes", 1)
+		expectCount(sqlMock, "workflow_template_families", 1)
+		expectCount(sqlMock, "workflow_template_maps", 0)
+		sqlMock.ExpectExec(`INSERT INTO "workflow_template_maps"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		result, err := service.CreateWorkflowTemplateMap(ctx, dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, dto.WorkflowTemplateFamilyID, result.WorkflowTemplateFamilyID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Mapped", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		expectCount(sqlMock, "hs_codes", 1)
+		expectCount(sqlMock, "workflow_template_families", 1)
+		expectCount(sqlMock, "workflow_template_maps", 1)
+		sqlMock.ExpectRollback()
+
+		_, err := service.CreateWorkflowTemplateMap(ctx, dto)
+
+		assert.ErrorIs(t, err, ErrWorkflowTemplateMapExists)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown HS Code", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		expectCount(sqlMock, "hs_codes", 0)
+		sqlMock.ExpectRollback()
+
+		_, err := service.CreateWorkflowTemplateMap(ctx, dto)
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplateMap)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid Flow", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+
+		_, err := service.CreateWorkflowTemplateMap(ctx, &model.WorkflowTemplateMapDTO{ConsignmentFlow: "TRANSIT"})
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplateMap)
+	})
+}
+
+func TestTemplateAdminService_UpdatePreConsignmentTemplate(t *testing.T) {
+	ctx := context.Background()
+	id, other, workflowTemplateID := uuid.New(), uuid.New(), uuid.New()
+	expectTemplates := func(sqlMock sqlmock.Sqlmock, workflowTemplateStatus string) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(id, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "depends_on"}).AddRow(id, "VAT", []byte(`[]`)))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates" WHERE id = \$1`).
+			WithArgs(workflowTemplateID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(workflowTemplateID, workflowTemplateStatus))
+	}
+	dto := &model.PreConsignmentTemplateDTO{Name: "VAT", WorkflowTemplateID: workflowTemplateID, DependsOn: []string{other.String()}}
+
+	t.Run("Dependency Cycle", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		expectTemplates(sqlMock, "PUBLISHED")
+		sqlMock.ExpectQuery(`SELECT "id","depends_on" FROM "pre_consignment_templates" WHERE id <> \$1`).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "depends_on"}).AddRow(other, []byte(fmt.Sprintf(`[%q]`, id))))
+		sqlMock.ExpectRollback()
+
+		_, err := service.UpdatePreConsignmentTemplate(ctx, id, dto)
+
+		assert.ErrorIs(t, err, ErrInvalidPreConsignmentTemplate)
+		assert.ErrorContains(t, err, "dependency cycle")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown Dependency", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		expectTemplates(sqlMock, "PUBLISHED")
+		sqlMock.ExpectQuery(`SELECT "id","depends_on" FROM "pre_consignment_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "depends_on"}))
+		sqlMock.ExpectRollback()
+
+		_, err := service.UpdatePreConsignmentTemplate(ctx, id, dto)
+
+		assert.ErrorIs(t, err, ErrInvalidPreConsignmentTemplate)
+		assert.ErrorContains(t, err, "does not exist")
+	})
+
+	t.Run("Workflow Template Not Published", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		expectTemplates(sqlMock, "DRAFT")
+		sqlMock.ExpectRollback()
+
+		_, err := service.UpdatePreConsignmentTemplate(ctx, id, dto)
+
+		assert.ErrorIs(t, err, ErrInvalidPreConsignmentTemplate)
+		assert.ErrorContains(t, err, "not published")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid Validity", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1 .* FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "depends_on"}).AddRow(id, "VAT", []byte(`[]`)))
+		sqlMock.ExpectRollback()
+		d := *dto
+		d.Validity = &model.PreConsignmentValidity{Duration: "-1h"}
+
+		_, err := service.UpdatePreConsignmentTemplate(ctx, id, &d)
+
+		assert.ErrorIs(t, err, ErrInvalidPreConsignmentTemplate)
+		assert.ErrorContains(t, err, "validity duration must be positive")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Updates", func(t *testing.T) {
+		service, sqlMock, _ := newTestTemplateAdminService(t)
+		expectTemplates(sqlMock, "PUBLISHED")
+		sqlMock.ExpectQuery(`SELECT "id","depends_on" FROM "pre_consignment_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "depends_on"}).AddRow(other, []byte(`[]`)))
+		sqlMock.ExpectExec(`UPDATE "pre_consignment_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		result, err := service.UpdatePreConsignmentTemplate(ctx, id, dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{other.String()}, result.DependsOn)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
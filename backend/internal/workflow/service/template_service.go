@@ -2,15 +2,35 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 )
 
+var (
+	// ErrWorkflowTemplateNotFound is returned when a workflow template version does not exist.
+	ErrWorkflowTemplateNotFound = errors.New("workflow template not found")
+	// ErrWorkflowTemplateFamilyNotFound is returned when adding a version to a workflow template family that does not exist.
+	ErrWorkflowTemplateFamilyNotFound = errors.New("workflow template family not found")
+	// ErrWorkflowTemplateNotDraft is returned when publishing a workflow template version that is already published.
+	ErrWorkflowTemplateNotDraft = errors.New("workflow template is not a draft")
+)
+
 type TemplateService struct {
-	db *gorm.DB
+	db                           *gorm.DB
+	prePublishValidationCallback func(context.Context, *gorm.DB, *model.WorkflowTemplate) error
+}
+
+// SetPrePublishValidationCallback sets a callback to be executed before a workflow template version is published
+// This allows the version to be validated (e.g., its node configurations) within the publishing transaction
+func (s *TemplateService) SetPrePublishValidationCallback(callback func(context.Context, *gorm.DB, *model.WorkflowTemplate) error) {
+	s.prePublishValidationCallback = callback
 }
 
 // NewTemplateService creates a new instance of TemplateService.
@@ -20,14 +40,17 @@ func NewTemplateService(db *gorm.DB) *TemplateService {
 	}
 }
 
-// GetWorkflowTemplateByHSCodeIDAndFlow retrieves the workflow template associated with a given HS code and consignment flow.
+// GetWorkflowTemplateByHSCodeIDAndFlow retrieves the latest published version of the workflow template family
+// associated with a given HS code and consignment flow.
 func (s *TemplateService) GetWorkflowTemplateByHSCodeIDAndFlow(ctx context.Context, hsCodeID uuid.UUID, flow model.ConsignmentFlow) (*model.WorkflowTemplate, error) {
 	var workflowTemplate model.WorkflowTemplate
 	result := s.db.WithContext(ctx).Table("workflow_templates").
 		Select("workflow_templates.*").
-		Joins("JOIN workflow_template_maps ON workflow_templates.id = workflow_template_maps.workflow_template_id").
-		Where("workflow_template_maps.hs_code_id = ? AND workflow_template_maps.consignment_flow = ?", hsCodeID, flow).
-		First(&workflowTemplate)
+		Joins("JOIN workflow_template_maps ON workflow_templates.family_id = workflow_template_maps.workflow_template_family_id").
+		Where("workflow_template_maps.hs_code_id = ? AND workflow_template_maps.consignment_flow = ? AND workflow_templates.status = ?",
+			hsCodeID, flow, model.WorkflowTemplateStatusPublished).
+		Order("workflow_templates.version_number DESC").
+		Take(&workflowTemplate)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -64,3 +87,117 @@ func (s *TemplateService) GetWorkflowNodeTemplateByID(ctx context.Context, id uu
 	}
 	return &template, nil
 }
+
+// CreateWorkflowTemplate creates a DRAFT workflow template version together with its node templates in a
+// single transaction. If the template has no FamilyID, a new family is created and the template becomes its
+// version 1; otherwise it becomes the next version of the given family. IDs already set on the templates are
+// kept so that DependsOn, UnlockConfiguration and EndNodeTemplateID references between them stay valid.
+// The version is not used for new consignments until it is published.
+func (s *TemplateService) CreateWorkflowTemplate(ctx context.Context, workflowTemplate *model.WorkflowTemplate, nodeTemplates []model.WorkflowNodeTemplate) error {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if workflowTemplate.FamilyID == uuid.Nil {
+		family := model.WorkflowTemplateFamily{
+			Name:        workflowTemplate.Name,
+			Description: workflowTemplate.Description,
+		}
+		if err := tx.Create(&family).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to create workflow template family: %w", err)
+		}
+		workflowTemplate.FamilyID = family.ID
+		workflowTemplate.VersionNumber = 1
+	} else {
+		// Lock the family so that concurrent drafts cannot get the same version number
+		var family model.WorkflowTemplateFamily
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&family, "id = ?", workflowTemplate.FamilyID).Error; err != nil {
+			tx.Rollback()
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWorkflowTemplateFamilyNotFound
+			}
+			return fmt.Errorf("failed to retrieve workflow template family %s: %w", workflowTemplate.FamilyID, err)
+		}
+
+		var latestVersion int
+		if err := tx.Model(&model.WorkflowTemplate{}).
+			Where("family_id = ?", family.ID).
+			Select("COALESCE(MAX(version_number), 0)").
+			Scan(&latestVersion).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to retrieve latest version of workflow template family %s: %w", family.ID, err)
+		}
+		workflowTemplate.VersionNumber = latestVersion + 1
+	}
+	workflowTemplate.Status = model.WorkflowTemplateStatusDraft
+	workflowTemplate.PublishedAt = nil
+
+	if len(nodeTemplates) > 0 {
+		if err := tx.Create(&nodeTemplates).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to create workflow node templates: %w", err)
+		}
+	}
+	if err := tx.Create(workflowTemplate).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to create workflow template: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// PublishWorkflowTemplate publishes a DRAFT workflow template version. Once published, the version and the
+// node templates it references are immutable, and new consignments mapped to its family use it unless a
+// higher version is published.
+func (s *TemplateService) PublishWorkflowTemplate(ctx context.Context, id uuid.UUID) (*model.WorkflowTemplate, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var workflowTemplate model.WorkflowTemplate
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&workflowTemplate, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow template %s: %w", id, err)
+	}
+	if workflowTemplate.Status != model.WorkflowTemplateStatusDraft {
+		tx.Rollback()
+		return nil, ErrWorkflowTemplateNotDraft
+	}
+
+	// Node templates of a draft may have changed since it was created; they are frozen from here on
+	if s.prePublishValidationCallback != nil {
+		if err := s.prePublishValidationCallback(ctx, tx, &workflowTemplate); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	publishedAt := time.Now().UTC()
+	if err := tx.Model(&workflowTemplate).Updates(map[string]any{
+		"status":       model.WorkflowTemplateStatusPublished,
+		"published_at": publishedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to publish workflow template %s: %w", id, err)
+	}
+	workflowTemplate.Status = model.WorkflowTemplateStatusPublished
+	workflowTemplate.PublishedAt = &publishedAt
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &workflowTemplate, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 )
@@ -21,8 +22,8 @@ func TestTemplateService_GetWorkflowTemplateByHSCodeIDAndFlow(t *testing.T) {
 	templateID := uuid.New()
 
 	// Expectation
-	sqlMock.ExpectQuery(`SELECT workflow_templates\.\* FROM "workflow_templates" JOIN workflow_template_maps ON workflow_templates\.id = workflow_template_maps\.workflow_template_id WHERE workflow_template_maps\.hs_code_id = \$1 AND workflow_template_maps\.consignment_flow = \$2 ORDER BY "workflow_templates"."id" LIMIT \$3`).
-		WithArgs(hsCodeID, flow, 1). // Checking matches exact args
+	sqlMock.ExpectQuery(`SELECT workflow_templates\.\* FROM "workflow_templates" JOIN workflow_template_maps ON workflow_templates\.family_id = workflow_template_maps\.workflow_template_family_id WHERE workflow_template_maps\.hs_code_id = \$1 AND workflow_template_maps\.consignment_flow = \$2 AND workflow_templates\.status = \$3 ORDER BY workflow_templates\.version_number DESC LIMIT \$4`).
+		WithArgs(hsCodeID, flow, model.WorkflowTemplateStatusPublished, 1). // Checking matches exact args
 		WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "name"}).
 			AddRow(templateID, flow, "Test Template"))
 
@@ -90,3 +91,183 @@ func TestTemplateService_GetWorkflowNodeTemplateByID(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Equal(t, id, result.ID)
 }
+
+func TestTemplateService_CreateWorkflowTemplate(t *testing.T) {
+	ctx := context.Background()
+	nodeTemplateID := uuid.New()
+	templateID := uuid.New()
+	newTemplates := func() (*model.WorkflowTemplate, []model.WorkflowNodeTemplate) {
+		return &model.WorkflowTemplate{
+			BaseModel:         model.BaseModel{ID: templateID},
+			Name:              "Imported",
+			Version:           "1.0",
+			NodeTemplates:     model.UUIDArray{nodeTemplateID},
+			EndNodeTemplateID: &nodeTemplateID,
+		}, []model.WorkflowNodeTemplate{{
+			BaseModel: model.BaseModel{ID: nodeTemplateID},
+			Name:      "Declaration",
+			Type:      "SIMPLE_FORM",
+			Config:    []byte(`{"formId":"f"}`),
+			DependsOn: model.UUIDArray{},
+		}}
+	}
+
+	t.Run("Creates Family For First Version", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		workflowTemplate, nodeTemplates := newTemplates()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "workflow_template_families"`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "Imported", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_node_templates"`).
+			WithArgs(nodeTemplateID, sqlmock.AnyArg(), sqlmock.AnyArg(), "Declaration", "", "SIMPLE_FORM",
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "CONSIGNMENT", nil, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_templates"`).
+			WithArgs(templateID, sqlmock.AnyArg(), sqlmock.AnyArg(), "Imported", "", "1.0", sqlmock.AnyArg(), &nodeTemplateID,
+				sqlmock.AnyArg(), 1, "DRAFT", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		err := service.CreateWorkflowTemplate(ctx, workflowTemplate, nodeTemplates)
+
+		assert.NoError(t, err)
+		assert.Equal(t, templateID, workflowTemplate.ID)
+		assert.NotEqual(t, uuid.Nil, workflowTemplate.FamilyID)
+		assert.Equal(t, 1, workflowTemplate.VersionNumber)
+		assert.Equal(t, model.WorkflowTemplateStatusDraft, workflowTemplate.Status)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Adds Next Version To Family", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		workflowTemplate, nodeTemplates := newTemplates()
+		familyID := uuid.New()
+		workflowTemplate.FamilyID = familyID
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_template_families" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(familyID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(familyID, "Imported"))
+		sqlMock.ExpectQuery(`SELECT COALESCE\(MAX\(version_number\), 0\) FROM "workflow_templates" WHERE family_id = \$1`).
+			WithArgs(familyID).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(2))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_node_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_templates"`).
+			WithArgs(templateID, sqlmock.AnyArg(), sqlmock.AnyArg(), "Imported", "", "1.0", sqlmock.AnyArg(), &nodeTemplateID,
+				familyID, 3, "DRAFT", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		err := service.CreateWorkflowTemplate(ctx, workflowTemplate, nodeTemplates)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, workflowTemplate.VersionNumber)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown Family", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		workflowTemplate, nodeTemplates := newTemplates()
+		workflowTemplate.FamilyID = uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_template_families"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		err := service.CreateWorkflowTemplate(ctx, workflowTemplate, nodeTemplates)
+
+		assert.ErrorIs(t, err, ErrWorkflowTemplateFamilyNotFound)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Rolls Back On Error", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		workflowTemplate, nodeTemplates := newTemplates()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(`INSERT INTO "workflow_template_families"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_node_templates"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectExec(`INSERT INTO "workflow_templates"`).WillReturnError(assert.AnError)
+		sqlMock.ExpectRollback()
+
+		err := service.CreateWorkflowTemplate(ctx, workflowTemplate, nodeTemplates)
+
+		assert.Error(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestTemplateService_PublishWorkflowTemplate(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	expectTemplate := func(sqlMock sqlmock.Sqlmock, status string) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(id, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "version_number"}).AddRow(id, status, 2))
+	}
+
+	t.Run("Publishes Draft", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		expectTemplate(sqlMock, "DRAFT")
+		sqlMock.ExpectExec(`UPDATE "workflow_templates" SET "published_at"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+			WithArgs(sqlmock.AnyArg(), "PUBLISHED", sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		result, err := service.PublishWorkflowTemplate(ctx, id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowTemplateStatusPublished, result.Status)
+		assert.NotNil(t, result.PublishedAt)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Published", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		expectTemplate(sqlMock, "PUBLISHED")
+		sqlMock.ExpectRollback()
+
+		_, err := service.PublishWorkflowTemplate(ctx, id)
+
+		assert.ErrorIs(t, err, ErrWorkflowTemplateNotDraft)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Validation Fails", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		service.SetPrePublishValidationCallback(func(_ context.Context, _ *gorm.DB, wt *model.WorkflowTemplate) error {
+			assert.Equal(t, id, wt.ID)
+			return ErrInvalidWorkflowTemplate
+		})
+		expectTemplate(sqlMock, "DRAFT")
+		sqlMock.ExpectRollback()
+
+		_, err := service.PublishWorkflowTemplate(ctx, id)
+
+		assert.ErrorIs(t, err, ErrInvalidWorkflowTemplate)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewTemplateService(db)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_templates"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		_, err := service.PublishWorkflowTemplate(ctx, id)
+
+		assert.ErrorIs(t, err, ErrWorkflowTemplateNotFound)
+	})
+}
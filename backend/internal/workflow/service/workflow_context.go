@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+var (
+	// ErrContextConflict is returned when a workflow node with the REJECT conflict policy changes a context key
+	// that another node of the workflow wrote last.
+	ErrContextConflict = errors.New("context key conflict")
+)
+
+// contextMerge is the result of merging the context a workflow node appends into the context of its workflow.
+type contextMerge struct {
+	context map[string]any                // Context after the merge
+	patch   map[string]any                // Top-level keys that changed, with their values after the merge
+	changes []model.WorkflowContextChange // Keys the node changed
+}
+
+// appendWorkflowContextInTx merges appendContext from node into current, the context of its workflow, which
+// the caller must have locked. The merge follows the context merge configuration of the node's template. The
+// keys that changed are recorded with their previous values, along with a CONTEXT_APPENDED event; the caller
+// writes the returned patch with jsonbMerge.
+func appendWorkflowContextInTx(ctx context.Context, tx *gorm.DB, templateProvider TemplateProvider, node *model.WorkflowNode, current, appendContext map[string]any) (*contextMerge, error) {
+	nodeTemplate, err := templateProvider.GetWorkflowNodeTemplateByID(ctx, node.WorkflowNodeTemplateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow node template %s: %w", node.WorkflowNodeTemplateID, err)
+	}
+	config := nodeTemplate.ContextMerge
+
+	var lastWriters map[string]uuid.UUID
+	if config.Policy() == model.ContextConflictReject {
+		lastWriters, err = loadContextLastWritersInTx(ctx, tx, node, slices.Collect(maps.Keys(appendContext)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merge, err := mergeWorkflowContext(current, appendContext, node, config, lastWriters)
+	if err != nil {
+		return nil, err
+	}
+	if len(merge.changes) == 0 {
+		return merge, nil
+	}
+
+	actor := auth.ActorFromContext(ctx)
+	now := time.Now().UTC()
+	appended := make(map[string]any, len(merge.changes))
+	for i := range merge.changes {
+		change := &merge.changes[i]
+		change.ConsignmentID = node.ConsignmentID
+		change.PreConsignmentID = node.PreConsignmentID
+		change.WorkflowNodeID = node.ID
+		change.WorkflowNodeTemplateID = node.WorkflowNodeTemplateID
+		change.Actor = actor
+		change.ChangedAt = now
+		appended[change.Key] = change.Value
+	}
+	if err := tx.WithContext(ctx).Create(&merge.changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to record context changes: %w", err)
+	}
+	if err := recordContextAppendedInTx(ctx, tx, node, appended); err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// mergeWorkflowContext merges appendContext from node into current according to config, without modifying
+// current. lastWriters maps keys to the node that changed them last; it is only consulted by the REJECT
+// policy. Keys whose value does not change are left out of the patch and the changes.
+func mergeWorkflowContext(current, appendContext map[string]any, node *model.WorkflowNode, config *model.ContextMergeConfig, lastWriters map[string]uuid.UUID) (*contextMerge, error) {
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = make(map[string]any)
+	}
+
+	// NAMESPACED nodes write their keys into an object under their namespace
+	target, prefix := merged, ""
+	if config.Policy() == model.ContextConflictNamespaced {
+		namespace, _ := merged[config.Namespace].(map[string]any)
+		if existing, ok := merged[config.Namespace]; ok && existing != nil && namespace == nil {
+			return nil, fmt.Errorf("%w: key %q is not an object and cannot hold the namespace of workflow node %s", ErrContextConflict, config.Namespace, node.ID)
+		}
+		target = maps.Clone(namespace)
+		if target == nil {
+			target = make(map[string]any)
+		}
+		prefix = config.Namespace + "."
+	}
+
+	merge := &contextMerge{context: merged, patch: make(map[string]any)}
+	for _, key := range slices.Sorted(maps.Keys(appendContext)) {
+		value := appendContext[key]
+		previous, exists := target[key]
+		if exists && jsonEqual(previous, value) {
+			continue
+		}
+		path := prefix + key
+		if writer, ok := lastWriters[path]; ok && writer != node.ID && config.Policy() == model.ContextConflictReject {
+			return nil, fmt.Errorf("%w: key %q was written by workflow node %s", ErrContextConflict, path, writer)
+		}
+		target[key] = value
+		merge.changes = append(merge.changes, model.WorkflowContextChange{Key: path, PreviousValue: previous, Value: value})
+		if prefix == "" {
+			merge.patch[key] = value
+		}
+	}
+	if prefix != "" && len(merge.changes) > 0 {
+		merged[config.Namespace] = target
+		merge.patch[config.Namespace] = target
+	}
+	return merge, nil
+}
+
+// loadContextLastWritersInTx returns the node that changed each of keys last in the context of node's
+// workflow. Keys no node changed are left out.
+func loadContextLastWritersInTx(ctx context.Context, tx *gorm.DB, node *model.WorkflowNode, keys []string) (map[string]uuid.UUID, error) {
+	query := tx.WithContext(ctx).Model(&model.WorkflowContextChange{}).Select("key", "workflow_node_id").Where("key IN ?", keys)
+	if node.ConsignmentID != nil {
+		query = query.Where("consignment_id = ?", *node.ConsignmentID)
+	} else {
+		query = query.Where("pre_consignment_id = ?", node.PreConsignmentID)
+	}
+
+	var changes []model.WorkflowContextChange
+	if err := query.Order("sequence ASC").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve context changes: %w", err)
+	}
+	lastWriters := make(map[string]uuid.UUID, len(changes))
+	for _, change := range changes {
+		lastWriters[change.Key] = change.WorkflowNodeID
+	}
+	return lastWriters, nil
+}
+
+// jsonbMerge returns an expression that sets the top-level keys of patch in the JSONB column, leaving its
+// other keys as they are in the row.
+func jsonbMerge(column string, patch map[string]any) (clause.Expr, error) {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return clause.Expr{}, fmt.Errorf("failed to marshal %s patch: %w", column, err)
+	}
+	return gorm.Expr("COALESCE("+column+", '{}'::jsonb) || ?::jsonb", string(data)), nil
+}
+
+// jsonEqual reports whether a and b have the same JSON encoding, so that numbers decoded from JSON compare
+// equal to the values they were encoded from.
+func jsonEqual(a, b any) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestMergeWorkflowContext(t *testing.T) {
+	node := &model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}}
+	otherNodeID := uuid.New()
+	current := map[string]any{"declaredQuantityKg": float64(7500), "inspectionResult": "PASS"}
+
+	t.Run("Last Writer Wins", func(t *testing.T) {
+		merge, err := mergeWorkflowContext(current, map[string]any{"inspectionResult": "FAIL", "declaredQuantityKg": 7500},
+			node, nil, map[string]uuid.UUID{"inspectionResult": otherNodeID})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"inspectionResult": "FAIL"}, merge.patch)
+		assert.Equal(t, "FAIL", merge.context["inspectionResult"])
+		assert.Equal(t, "PASS", current["inspectionResult"], "current must not be modified")
+		if assert.Len(t, merge.changes, 1) {
+			assert.Equal(t, "inspectionResult", merge.changes[0].Key)
+			assert.Equal(t, "PASS", merge.changes[0].PreviousValue)
+			assert.Equal(t, "FAIL", merge.changes[0].Value)
+		}
+	})
+
+	t.Run("Reject Key Written By Another Node", func(t *testing.T) {
+		config := &model.ContextMergeConfig{ConflictPolicy: model.ContextConflictReject}
+		_, err := mergeWorkflowContext(current, map[string]any{"inspectionResult": "FAIL"},
+			node, config, map[string]uuid.UUID{"inspectionResult": otherNodeID})
+		assert.True(t, errors.Is(err, ErrContextConflict))
+	})
+
+	t.Run("Reject Allows Own Keys And Unchanged Values", func(t *testing.T) {
+		config := &model.ContextMergeConfig{ConflictPolicy: model.ContextConflictReject}
+		merge, err := mergeWorkflowContext(current, map[string]any{"inspectionResult": "FAIL", "declaredQuantityKg": 7500},
+			node, config, map[string]uuid.UUID{"inspectionResult": node.ID, "declaredQuantityKg": otherNodeID})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"inspectionResult": "FAIL"}, merge.patch)
+	})
+
+	t.Run("Namespaced", func(t *testing.T) {
+		config := &model.ContextMergeConfig{ConflictPolicy: model.ContextConflictNamespaced, Namespace: "lab"}
+		withNamespace := map[string]any{"inspectionResult": "PASS", "lab": map[string]any{"sampleId": "S-1"}}
+		merge, err := mergeWorkflowContext(withNamespace, map[string]any{"inspectionResult": "FAIL"}, node, config, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"lab": map[string]any{"sampleId": "S-1", "inspectionResult": "FAIL"}}, merge.patch)
+		assert.Equal(t, "PASS", merge.context["inspectionResult"])
+		if assert.Len(t, merge.changes, 1) {
+			assert.Equal(t, "lab.inspectionResult", merge.changes[0].Key)
+			assert.Nil(t, merge.changes[0].PreviousValue)
+		}
+	})
+
+	t.Run("Namespace Is Not An Object", func(t *testing.T) {
+		config := &model.ContextMergeConfig{ConflictPolicy: model.ContextConflictNamespaced, Namespace: "inspectionResult"}
+		_, err := mergeWorkflowContext(current, map[string]any{"sampleId": "S-1"}, node, config, nil)
+		assert.True(t, errors.Is(err, ErrContextConflict))
+	})
+}
+
+func TestAppendWorkflowContextInTx_Reject(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	mockTemplateProvider := new(MockTemplateProvider)
+	ctx := context.Background()
+	consignmentID, otherNodeID := uuid.New(), uuid.New()
+	node := &model.WorkflowNode{
+		BaseModel:              model.BaseModel{ID: uuid.New()},
+		ConsignmentID:          &consignmentID,
+		WorkflowNodeTemplateID: uuid.New(),
+	}
+
+	mockTemplateProvider.On("GetWorkflowNodeTemplateByID", ctx, node.WorkflowNodeTemplateID).
+		Return(&model.WorkflowNodeTemplate{ContextMerge: &model.ContextMergeConfig{ConflictPolicy: model.ContextConflictReject}}, nil)
+	sqlMock.ExpectQuery(`SELECT "key","workflow_node_id" FROM "workflow_context_changes" WHERE key IN \(\$1\) AND consignment_id = \$2 ORDER BY sequence ASC`).
+		WithArgs("inspectionResult", consignmentID).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "workflow_node_id"}).
+			AddRow("inspectionResult", node.ID).
+			AddRow("inspectionResult", otherNodeID))
+
+	_, err := appendWorkflowContextInTx(ctx, db, mockTemplateProvider, node,
+		map[string]any{"inspectionResult": "PASS"}, map[string]any{"inspectionResult": "FAIL"})
+	assert.ErrorIs(t, err, ErrContextConflict)
+	assert.ErrorContains(t, err, otherNodeID.String())
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// recordWorkflowEventsInTx appends events to the workflow event log within tx, so they are only recorded if
+// the changes they describe are committed. Events are attributed to the actor of ctx, and events without an
+// OccurredAt are stamped with the current time.
+func recordWorkflowEventsInTx(ctx context.Context, tx *gorm.DB, events []model.WorkflowEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	actor := auth.ActorFromContext(ctx)
+	now := time.Now().UTC()
+	for i := range events {
+		events[i].Actor = actor
+		if events[i].OccurredAt.IsZero() {
+			events[i].OccurredAt = now
+		}
+	}
+
+	if err := tx.WithContext(ctx).Create(&events).Error; err != nil {
+		return fmt.Errorf("failed to record workflow events: %w", err)
+	}
+	return nil
+}
+
+// newWorkflowNodeEvent returns an event about node, in the workflow of its consignment or pre-consignment.
+func newWorkflowNodeEvent(node *model.WorkflowNode, eventType model.WorkflowEventType) model.WorkflowEvent {
+	nodeID := node.ID
+	return model.WorkflowEvent{
+		ConsignmentID:    node.ConsignmentID,
+		PreConsignmentID: node.PreConsignmentID,
+		WorkflowNodeID:   &nodeID,
+		Type:             eventType,
+	}
+}
+
+// workflowNodeChangeEvents returns the events describing how a workflow node changed from before to after:
+// a NODE_STATE_CHANGED event if its state changed, or a NODE_OUTCOME_SET event if only its outcome did.
+func workflowNodeChangeEvents(before, after *model.WorkflowNode) []model.WorkflowEvent {
+	switch {
+	case before.State != after.State:
+		event := newWorkflowNodeEvent(after, model.WorkflowEventNodeStateChanged)
+		fromState, toState := string(before.State), string(after.State)
+		event.FromState = &fromState
+		event.ToState = &toState
+		event.Outcome = after.Outcome
+		if after.ExtendedState != nil {
+			event.Data = map[string]any{"extendedState": *after.ExtendedState}
+		}
+		if after.Attempt != before.Attempt {
+			if event.Data == nil {
+				event.Data = make(map[string]any, 1)
+			}
+			event.Data["attempt"] = after.Attempt
+		}
+		return []model.WorkflowEvent{event}
+	case !equalOptionalString(before.Outcome, after.Outcome):
+		event := newWorkflowNodeEvent(after, model.WorkflowEventNodeOutcomeSet)
+		event.Outcome = after.Outcome
+		return []model.WorkflowEvent{event}
+	default:
+		return nil
+	}
+}
+
+// recordContextAppendedInTx records that a workflow node appended appendContext to the context of its
+// workflow. Nothing is recorded if appendContext is empty.
+func recordContextAppendedInTx(ctx context.Context, tx *gorm.DB, node *model.WorkflowNode, appendContext map[string]any) error {
+	if len(appendContext) == 0 {
+		return nil
+	}
+	event := newWorkflowNodeEvent(node, model.WorkflowEventContextAppended)
+	event.Data = appendContext
+	return recordWorkflowEventsInTx(ctx, tx, []model.WorkflowEvent{event})
+}
+
+// RecordTaskTransitions records the FSM transitions the task of a workflow node applied, as reported by the
+// task notification with the given sequence. Each transition is recorded at most once, since notifications
+// may be delivered more than once: a redelivered transition conflicts with the unique index on the node,
+// task sequence and ordinal, and is skipped.
+func (s *WorkflowNodeService) RecordTaskTransitions(ctx context.Context, nodeID uuid.UUID, taskSequence int64, occurredAt time.Time, transitions []taskPlugin.TransitionRecord) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	node, err := s.GetWorkflowNodeByID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+
+	events := make([]model.WorkflowEvent, 0, len(transitions))
+	for i, transition := range transitions {
+		event := newWorkflowNodeEvent(node, model.WorkflowEventTaskTransition)
+		action, fromState, toState := transition.Action, transition.FromState, transition.ToState
+		event.Action = &action
+		if fromState != "" {
+			event.FromState = &fromState
+		}
+		event.ToState = &toState
+		if transition.TaskState != "" {
+			event.Data = map[string]any{"taskState": string(transition.TaskState)}
+		}
+		ordinal := i
+		event.TaskSequence = &taskSequence
+		event.Ordinal = &ordinal
+		event.OccurredAt = occurredAt.UTC()
+		events = append(events, event)
+	}
+
+	return recordWorkflowEventsInTx(ctx, s.db.Clauses(clause.OnConflict{DoNothing: true}), events)
+}
+
+// GetConsignmentEvents returns the event timeline of a consignment's workflow, in the order the events occurred.
+// If traderID is set, the consignment must belong to that trader.
+func (s *ConsignmentService) GetConsignmentEvents(ctx context.Context, consignmentID uuid.UUID, traderID *string) ([]model.WorkflowEvent, error) {
+	var consignment model.Consignment
+	if err := s.db.WithContext(ctx).Select("id", "trader_id").First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
+	}
+	if traderID != nil && consignment.TraderID != *traderID {
+		return nil, ErrConsignmentNotFound
+	}
+	return listWorkflowEvents(ctx, s.db, "consignment_id", consignmentID)
+}
+
+// GetPreConsignmentEvents returns the event timeline of a pre-consignment's workflow, in the order the events
+// occurred. If traderID is set, the pre-consignment must belong to that trader.
+func (s *PreConsignmentService) GetPreConsignmentEvents(ctx context.Context, preConsignmentID uuid.UUID, traderID *string) ([]model.WorkflowEvent, error) {
+	var preConsignment model.PreConsignment
+	if err := s.db.WithContext(ctx).Select("id", "trader_id").First(&preConsignment, "id = ?", preConsignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPreConsignmentNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve pre-consignment %s: %w", preConsignmentID, err)
+	}
+	if traderID != nil && preConsignment.TraderID != *traderID {
+		return nil, ErrPreConsignmentNotFound
+	}
+	return listWorkflowEvents(ctx, s.db, "pre_consignment_id", preConsignmentID)
+}
+
+// listWorkflowEvents returns the events of the workflow whose events have the given owner column set to
+// ownerID, in the order they occurred.
+func listWorkflowEvents(ctx context.Context, db *gorm.DB, ownerColumn string, ownerID uuid.UUID) ([]model.WorkflowEvent, error) {
+	events := make([]model.WorkflowEvent, 0)
+	if err := db.WithContext(ctx).
+		Where(ownerColumn+" = ?", ownerID).
+		Order("occurred_at ASC, sequence ASC").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow events: %w", err)
+	}
+	return events, nil
+}
+
+// equalOptionalString reports whether a and b are both nil or point to equal strings.
+func equalOptionalString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	taskPlugin "github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestWorkflowNodeChangeEvents(t *testing.T) {
+	consignmentID := uuid.New()
+	newNode := func(state model.WorkflowNodeState, outcome *string) *model.WorkflowNode {
+		return &model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			ConsignmentID: &consignmentID,
+			State:         state,
+			Outcome:       outcome,
+			Attempt:       1,
+		}
+	}
+	approved := "APPROVED"
+	timedOut := "TIMED_OUT"
+
+	t.Run("State Change", func(t *testing.T) {
+		before := newNode(model.WorkflowNodeStateInProgress, nil)
+		after := *before
+		after.State = model.WorkflowNodeStateCompleted
+		after.Outcome = &approved
+
+		events := workflowNodeChangeEvents(before, &after)
+		assert.Len(t, events, 1)
+		assert.Equal(t, model.WorkflowEventNodeStateChanged, events[0].Type)
+		assert.Equal(t, "IN_PROGRESS", *events[0].FromState)
+		assert.Equal(t, "COMPLETED", *events[0].ToState)
+		assert.Equal(t, &approved, events[0].Outcome)
+		assert.Equal(t, &consignmentID, events[0].ConsignmentID)
+		assert.Equal(t, before.ID, *events[0].WorkflowNodeID)
+		assert.Nil(t, events[0].Data)
+	})
+
+	t.Run("Reopen Records Attempt", func(t *testing.T) {
+		before := newNode(model.WorkflowNodeStateFailed, nil)
+		after := *before
+		after.State = model.WorkflowNodeStateReady
+		after.Attempt = 2
+
+		events := workflowNodeChangeEvents(before, &after)
+		assert.Len(t, events, 1)
+		assert.Equal(t, map[string]any{"attempt": 2}, events[0].Data)
+	})
+
+	t.Run("Outcome Only", func(t *testing.T) {
+		before := newNode(model.WorkflowNodeStateInProgress, nil)
+		after := *before
+		after.Outcome = &timedOut
+
+		events := workflowNodeChangeEvents(before, &after)
+		assert.Len(t, events, 1)
+		assert.Equal(t, model.WorkflowEventNodeOutcomeSet, events[0].Type)
+		assert.Nil(t, events[0].FromState)
+		assert.Equal(t, &timedOut, events[0].Outcome)
+	})
+
+	t.Run("No Change", func(t *testing.T) {
+		before := newNode(model.WorkflowNodeStateInProgress, &approved)
+		after := *before
+		outcome := approved
+		after.Outcome = &outcome
+
+		assert.Empty(t, workflowNodeChangeEvents(before, &after))
+	})
+}
+
+func TestWorkflowNodeService_RecordTaskTransitions(t *testing.T) {
+	nodeID := uuid.New()
+	consignmentID := uuid.New()
+	occurredAt := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	transitions := []taskPlugin.TransitionRecord{
+		{Action: "OGA_VERIFICATION_APPROVED", FromState: "OGA_REVIEW", ToState: "APPROVED", TaskState: taskPlugin.Completed},
+	}
+	ctx := auth.WithActor(context.Background(), auth.Actor{Type: auth.ActorTypeAgency, ID: "officer1"})
+
+	t.Run("Records Each Transition", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeService(db)
+
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "consignment_id", "state"}).AddRow(nodeID, consignmentID, "COMPLETED"))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`INSERT INTO "workflow_events" .* ON CONFLICT DO NOTHING`).
+			WithArgs(consignmentID, nil, nodeID, model.WorkflowEventTaskTransition, "OGA_REVIEW", "APPROVED", nil, "OGA_VERIFICATION_APPROVED",
+				`{"taskState":"COMPLETED"}`, auth.ActorTypeAgency, "officer1", int64(42), 0, occurredAt, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"sequence"}).AddRow(1))
+		sqlMock.ExpectCommit()
+
+		err := service.RecordTaskTransitions(ctx, nodeID, 42, occurredAt, transitions)
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Skips Redelivered Notification", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeService(db)
+
+		// The transitions were already recorded, so the insert conflicts and returns no rows
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "consignment_id", "state"}).AddRow(nodeID, consignmentID, "COMPLETED"))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`INSERT INTO "workflow_events" .* ON CONFLICT DO NOTHING`).
+			WillReturnRows(sqlmock.NewRows([]string{"sequence"}))
+		sqlMock.ExpectCommit()
+
+		err := service.RecordTaskTransitions(ctx, nodeID, 42, occurredAt, transitions)
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
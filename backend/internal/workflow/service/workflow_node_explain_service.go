@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// WorkflowNodeExplainService explains why workflow nodes are still locked.
+type WorkflowNodeExplainService struct {
+	db *gorm.DB
+}
+
+// NewWorkflowNodeExplainService creates a new instance of WorkflowNodeExplainService.
+func NewWorkflowNodeExplainService(db *gorm.DB) *WorkflowNodeExplainService {
+	return &WorkflowNodeExplainService{
+		db: db,
+	}
+}
+
+// ExplainUnlock evaluates the unlock logic of a workflow node against the current state of the
+// other nodes of its consignment or pre-consignment and its global or trader context, the same way the
+// state machine does.
+// If traderID is set, the node's workflow must belong to that trader.
+func (s *WorkflowNodeExplainService) ExplainUnlock(ctx context.Context, nodeID uuid.UUID, traderID *string) (*model.WorkflowNodeUnlockExplanationDTO, error) {
+	db := s.db.WithContext(ctx)
+
+	var node model.WorkflowNode
+	if err := db.Preload("WorkflowNodeTemplate").First(&node, "id = ?", nodeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowNodeNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow node %s: %w", nodeID, err)
+	}
+
+	parent, err := loadWorkflowNodeParent(db, &node)
+	if err != nil {
+		return nil, err
+	}
+	if traderID != nil && parent.traderID != *traderID {
+		return nil, ErrWorkflowNodeNotFound
+	}
+
+	siblings := db.Preload("WorkflowNodeTemplate")
+	switch {
+	case node.ConsignmentID != nil:
+		siblings = siblings.Where("consignment_id = ?", *node.ConsignmentID)
+	case node.PreConsignmentID != nil:
+		siblings = siblings.Where("pre_consignment_id = ?", *node.PreConsignmentID)
+	default:
+		return nil, fmt.Errorf("workflow node %s has neither consignment nor pre-consignment parent", node.ID)
+	}
+	var nodes []model.WorkflowNode
+	if err := siblings.Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve sibling workflow nodes: %w", err)
+	}
+	nodeMap := make(map[uuid.UUID]model.WorkflowNode, len(nodes))
+	for _, sibling := range nodes {
+		nodeMap[sibling.ID] = sibling
+	}
+
+	dto := &model.WorkflowNodeUnlockExplanationDTO{
+		WorkflowNodeID: node.ID,
+		Name:           node.WorkflowNodeTemplate.Name,
+		State:          node.State,
+		ExtendedState:  node.ExtendedState,
+		ItemIndex:      node.ItemIndex,
+	}
+	name := func(cond model.UnlockCondition) string {
+		if cond.NodeID != nil {
+			if dep, ok := nodeMap[*cond.NodeID]; ok && dep.DisplayName() != "" {
+				return dep.DisplayName()
+			}
+			return cond.NodeID.String()
+		}
+		return cond.NodeTemplateID.String()
+	}
+	if node.UnlockConfiguration != nil {
+		dto.UnlockExpression = node.UnlockConfiguration.Format(name)
+		dto.Explanation = node.UnlockConfiguration.ExplainInContext(nodeMap, parent.context)
+	} else {
+		dto.UsesDependsOn = true
+		completed := string(model.WorkflowNodeStateCompleted)
+		group := model.UnlockGroup{}
+		for _, depID := range node.DependsOn {
+			group.AllOf = append(group.AllOf, model.UnlockCondition{NodeID: &depID, State: &completed})
+		}
+		if len(group.AllOf) > 0 {
+			dto.UnlockExpression = (&model.UnlockConfig{AnyOf: []model.UnlockGroup{group}}).Format(name)
+		}
+		dto.Explanation = model.ExplainDependsOn(node.DependsOn, nodeMap)
+	}
+	dto.Satisfied = dto.Explanation.Satisfied
+
+	return dto, nil
+}
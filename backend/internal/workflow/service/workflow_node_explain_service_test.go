@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestWorkflowNodeExplainService_ExplainUnlock(t *testing.T) {
+	ctx := context.Background()
+	nodeColumns := []string{"id", "consignment_id", "workflow_node_template_id", "state", "outcome", "depends_on", "unlock_configuration"}
+	templateColumns := []string{"id", "name"}
+
+	nodeID, depID := uuid.New(), uuid.New()
+	consignmentID := uuid.New()
+	nodeTemplateID, depTemplateID := uuid.New(), uuid.New()
+	unlockConfig := `{"anyOf":[{"allOf":[{"nodeId":"` + depID.String() + `","nodeTemplateId":"` + depTemplateID.String() + `","outcome":"APPROVED"}]},{"allOf":[{"nodeId":"` + depID.String() + `","nodeTemplateId":"` + depTemplateID.String() + `","state":"FAILED"}]}]}`
+
+	expectNode := func(sqlMock sqlmock.Sqlmock, dependsOn string, config any) {
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 ORDER BY "workflow_nodes"."id" LIMIT \$2`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, nodeTemplateID, "LOCKED", nil, dependsOn, config))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE "workflow_node_templates"."id" = \$1`).
+			WithArgs(nodeTemplateID).
+			WillReturnRows(sqlmock.NewRows(templateColumns).AddRow(nodeTemplateID, "Release"))
+	}
+	expectConsignment := func(sqlMock sqlmock.Sqlmock, globalContext string) {
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "global_context"}).AddRow(consignmentID, "trader1", globalContext))
+	}
+	expectSiblings := func(sqlMock sqlmock.Sqlmock, dependsOn string, config any) {
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE consignment_id = \$1`).
+			WithArgs(consignmentID).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).
+				AddRow(nodeID, consignmentID, nodeTemplateID, "LOCKED", nil, dependsOn, config).
+				AddRow(depID, consignmentID, depTemplateID, "COMPLETED", "REJECTED", "[]", nil))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE "workflow_node_templates"."id" IN \(\$1,\$2\)`).
+			WillReturnRows(sqlmock.NewRows(templateColumns).AddRow(nodeTemplateID, "Release").AddRow(depTemplateID, "Review"))
+	}
+
+	t.Run("Unlock Configuration", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeExplainService(db)
+		expectNode(sqlMock, "[]", unlockConfig)
+		expectConsignment(sqlMock, "{}")
+		expectSiblings(sqlMock, "[]", unlockConfig)
+
+		dto, err := service.ExplainUnlock(ctx, nodeID, strPtr("trader1"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Release", dto.Name)
+		assert.False(t, dto.UsesDependsOn)
+		assert.False(t, dto.Satisfied)
+		assert.Equal(t, "Review.outcome == APPROVED || Review.state == FAILED", dto.UnlockExpression)
+		if assert.Len(t, dto.Explanation.Children, 2) {
+			leaf := dto.Explanation.Children[0].Children[0]
+			assert.Equal(t, "Review", leaf.NodeName)
+			assert.Equal(t, "APPROVED", *leaf.ExpectedOutcome)
+			assert.Equal(t, "REJECTED", *leaf.ActualOutcome)
+		}
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Depends On", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeExplainService(db)
+		dependsOn := `["` + depID.String() + `"]`
+		expectNode(sqlMock, dependsOn, nil)
+		expectConsignment(sqlMock, "{}")
+		expectSiblings(sqlMock, dependsOn, nil)
+
+		dto, err := service.ExplainUnlock(ctx, nodeID, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, dto.UsesDependsOn)
+		assert.True(t, dto.Satisfied)
+		assert.Equal(t, model.UnlockExplanationAllOf, dto.Explanation.Kind)
+		assert.Len(t, dto.Explanation.Children, 1)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Context Predicate", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeExplainService(db)
+		contextConfig := `{"expression":{"allOf":[{"nodeId":"` + depID.String() + `","nodeTemplateId":"` + depTemplateID.String() + `","state":"COMPLETED"},{"context":{"path":"declaredQuantityKg","operator":"GT","value":5000}}]}}`
+		expectNode(sqlMock, "[]", contextConfig)
+		expectConsignment(sqlMock, `{"declaredQuantityKg": 7500}`)
+		expectSiblings(sqlMock, "[]", contextConfig)
+
+		dto, err := service.ExplainUnlock(ctx, nodeID, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, dto.Satisfied)
+		assert.Equal(t, "Review.state == COMPLETED && context.declaredQuantityKg > 5000", dto.UnlockExpression)
+		if assert.Len(t, dto.Explanation.Children, 2) {
+			predicate := dto.Explanation.Children[1]
+			assert.Equal(t, model.UnlockExplanationContext, predicate.Kind)
+			assert.Equal(t, 7500.0, predicate.ActualValue)
+		}
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Other Trader", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeExplainService(db)
+		expectNode(sqlMock, "[]", unlockConfig)
+		expectConsignment(sqlMock, "{}")
+
+		_, err := service.ExplainUnlock(ctx, nodeID, strPtr("trader2"))
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeNotFound)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeExplainService(db)
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1`).
+			WithArgs(nodeID, 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := service.ExplainUnlock(ctx, nodeID, nil)
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeNotFound)
+	})
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// nodeInstanceKey identifies a workflow node of a consignment by its node template and, for an item-scoped
+// node template, the index of its consignment item. Consignment-wide nodes have item index -1.
+type nodeInstanceKey struct {
+	nodeTemplateID uuid.UUID
+	itemIndex      int
+}
+
+func instanceKey(nodeTemplateID uuid.UUID, itemIndex *int) nodeInstanceKey {
+	if itemIndex == nil {
+		return nodeInstanceKey{nodeTemplateID: nodeTemplateID, itemIndex: -1}
+	}
+	return nodeInstanceKey{nodeTemplateID: nodeTemplateID, itemIndex: *itemIndex}
+}
+
+// itemIndexPtr returns the item index of the key as set on a workflow node, nil for consignment-wide nodes.
+func (k nodeInstanceKey) itemIndexPtr() *int {
+	if k.itemIndex < 0 {
+		return nil
+	}
+	itemIndex := k.itemIndex
+	return &itemIndex
+}
+
+// itemIndexesByNodeTemplate returns the indexes of the consignment items whose workflow template lists each
+// node template. Items without a recorded workflow template, or with one that is not among
+// workflowTemplates, are taken to use all of workflowTemplates.
+func itemIndexesByNodeTemplate(items []model.ConsignmentItem, workflowTemplates []model.WorkflowTemplate) map[uuid.UUID][]int {
+	workflowTemplateByID := make(map[uuid.UUID]model.WorkflowTemplate, len(workflowTemplates))
+	for _, wt := range workflowTemplates {
+		workflowTemplateByID[wt.ID] = wt
+	}
+
+	itemIndexes := make(map[uuid.UUID][]int)
+	for i, item := range items {
+		itemTemplates := workflowTemplates
+		if item.WorkflowTemplateID != nil {
+			if wt, ok := workflowTemplateByID[*item.WorkflowTemplateID]; ok {
+				itemTemplates = []model.WorkflowTemplate{wt}
+			}
+		}
+		for _, wt := range itemTemplates {
+			for _, nodeTemplateID := range wt.NodeTemplates {
+				if !slices.Contains(itemIndexes[nodeTemplateID], i) {
+					itemIndexes[nodeTemplateID] = append(itemIndexes[nodeTemplateID], i)
+				}
+			}
+		}
+	}
+	return itemIndexes
+}
+
+// requiredNodeInstances returns the workflow nodes the workflow templates require for the consignment items,
+// in the order the workflow templates list their node templates: one for each consignment-wide node
+// template, and one for each item whose workflow template lists an item-scoped node template.
+func requiredNodeInstances(workflowTemplates []model.WorkflowTemplate, nodeTemplateByID map[uuid.UUID]model.WorkflowNodeTemplate, items []model.ConsignmentItem) []nodeInstanceKey {
+	itemIndexes := itemIndexesByNodeTemplate(items, workflowTemplates)
+	var keys []nodeInstanceKey
+	for _, wt := range workflowTemplates {
+		for _, id := range wt.NodeTemplates {
+			nt := nodeTemplateByID[id]
+			if !nt.IsItemScoped() {
+				if key := instanceKey(id, nil); !slices.Contains(keys, key) {
+					keys = append(keys, key)
+				}
+				continue
+			}
+			for _, itemIndex := range itemIndexes[id] {
+				if key := instanceKey(id, &itemIndex); !slices.Contains(keys, key) {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// newNodeInstances indexes the given nodes by node template for resolving dependencies between them.
+func newNodeInstances(nodes []*model.WorkflowNode) model.NodeInstances {
+	instances := make(model.NodeInstances)
+	for _, node := range nodes {
+		instances[node.WorkflowNodeTemplateID] = append(instances[node.WorkflowNodeTemplateID], model.NodeInstance{
+			NodeID:    node.ID,
+			ItemIndex: node.ItemIndex,
+		})
+	}
+	return instances
+}
+
+// resolveNodeDependencies sets the DependsOn and UnlockConfiguration of a node from its node template,
+// referencing the nodes in instances. A dependency of a node of an item on an item-scoped node template is on
+// the node of the same item; any other dependency on an item-scoped node template is on the nodes of all items.
+func resolveNodeDependencies(node *model.WorkflowNode, nt model.WorkflowNodeTemplate, instances model.NodeInstances) error {
+	node.DependsOn = model.UUIDArray{}
+	for _, id := range nt.DependsOn {
+		depNodeIDs, _ := instances.Select(id, node.ItemIndex, "")
+		node.DependsOn = append(node.DependsOn, depNodeIDs...)
+	}
+	node.UnlockConfiguration = nil
+	if nt.UnlockConfiguration != nil {
+		resolved, err := nt.UnlockConfiguration.ResolveToNodeInstances(instances, node.ItemIndex)
+		if err != nil {
+			return fmt.Errorf("failed to resolve unlock configuration for node template %s: %w", nt.ID, err)
+		}
+		node.UnlockConfiguration = resolved
+	}
+	return nil
+}
+
+// resolveEndNodeDependencies makes the end node depend on the nodes of the end node templates of the
+// workflow templates, including the node of every item for an item-scoped one.
+func resolveEndNodeDependencies(endNode *model.WorkflowNode, endDependencyTemplateIDs []uuid.UUID, instances model.NodeInstances) {
+	endNode.DependsOn = model.UUIDArray{}
+	for _, id := range endDependencyTemplateIDs {
+		depNodeIDs, _ := instances.Select(id, nil, "")
+		endNode.DependsOn = append(endNode.DependsOn, depNodeIDs...)
+	}
+	endNode.UnlockConfiguration = nil
+}
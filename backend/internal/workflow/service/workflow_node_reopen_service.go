@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+var (
+	// ErrWorkflowNodeNotFound is returned when a workflow node does not exist or does not belong to the trader.
+	ErrWorkflowNodeNotFound = errors.New("workflow node not found")
+	// ErrWorkflowNodeNotReopenable is returned when reopening a node that has not failed, or whose workflow is no longer in progress.
+	ErrWorkflowNodeNotReopenable = errors.New("workflow node cannot be reopened")
+	// ErrWorkflowNodeReopenNotPermitted is returned when the node template's reopen policy does not allow the requester to reopen it.
+	ErrWorkflowNodeReopenNotPermitted = errors.New("reopening this workflow node is not permitted")
+)
+
+// ReopenRequester identifies who asks to reopen a workflow node.
+// An admin may reopen nodes of any trader; a trader only nodes of their own workflows.
+type ReopenRequester struct {
+	TraderID string
+	IsAdmin  bool
+}
+
+// WorkflowNodeReopenService reopens FAILED workflow nodes so that their task can be retried.
+type WorkflowNodeReopenService struct {
+	db                 *gorm.DB
+	templateProvider   TemplateProvider
+	nodeRepo           WorkflowNodeRepository
+	stateMachine       *WorkflowNodeStateMachine
+	postReopenCallback func(context.Context, model.WorkflowNode, map[string]any) error
+}
+
+// NewWorkflowNodeReopenService creates a new instance of WorkflowNodeReopenService.
+func NewWorkflowNodeReopenService(db *gorm.DB, templateProvider TemplateProvider, nodeRepo WorkflowNodeRepository) *WorkflowNodeReopenService {
+	return &WorkflowNodeReopenService{
+		db:               db,
+		templateProvider: templateProvider,
+		nodeRepo:         nodeRepo,
+		stateMachine:     NewWorkflowNodeStateMachine(nodeRepo),
+	}
+}
+
+// SetPostReopenCallback sets a callback to be executed after the reopen is committed, with the
+// reopened node and the global context of its workflow, to start the new attempt of the node's task.
+// The task lives in its own transaction, so it is only touched once the node is READY for good. If the
+// callback fails, the node is returned to FAILED.
+func (s *WorkflowNodeReopenService) SetPostReopenCallback(callback func(context.Context, model.WorkflowNode, map[string]any) error) {
+	s.postReopenCallback = callback
+}
+
+// ReopenFailedNode moves a FAILED workflow node back to READY and increments its attempt, provided
+// that its consignment or pre-consignment is still in progress and the reopen policy of its
+// template allows the requester to reopen it. If the new attempt of its task cannot be started, the
+// node is returned to FAILED and an error is returned.
+func (s *WorkflowNodeReopenService) ReopenFailedNode(ctx context.Context, nodeID uuid.UUID, requester ReopenRequester) (*model.WorkflowNode, error) {
+	// Start a transaction
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the node so that concurrent reopens cannot both start a new attempt
+	var node model.WorkflowNode
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&node, "id = ?", nodeID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowNodeNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow node %s: %w", nodeID, err)
+	}
+
+	parent, err := loadWorkflowNodeParent(tx, &node)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if !requester.IsAdmin && parent.traderID != requester.TraderID {
+		tx.Rollback()
+		return nil, ErrWorkflowNodeNotFound
+	}
+
+	nodeTemplate, err := s.templateProvider.GetWorkflowNodeTemplateByID(ctx, node.WorkflowNodeTemplateID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to retrieve workflow node template %s: %w", node.WorkflowNodeTemplateID, err)
+	}
+	if !nodeTemplate.AllowsReopenBy(requester.IsAdmin) {
+		tx.Rollback()
+		return nil, ErrWorkflowNodeReopenNotPermitted
+	}
+
+	if !parent.inProgress || node.State != model.WorkflowNodeStateFailed {
+		tx.Rollback()
+		return nil, ErrWorkflowNodeNotReopenable
+	}
+
+	failedNode := node
+	if err := s.stateMachine.ReopenFailed(ctx, tx, &node); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to reopen workflow node: %w", err)
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if s.postReopenCallback != nil {
+		if err := s.postReopenCallback(ctx, node, parent.context); err != nil {
+			if restoreErr := s.restoreFailedNode(ctx, failedNode, node.Attempt); restoreErr != nil {
+				// The task still reports FAILED, so the reconciler returns the node to FAILED
+				slog.ErrorContext(ctx, "failed to return reopened workflow node to FAILED",
+					"workflowNodeID", node.ID,
+					"error", restoreErr)
+			}
+			return nil, fmt.Errorf("failed to start new attempt of workflow node %s: %w", node.ID, err)
+		}
+	}
+
+	return &node, nil
+}
+
+// restoreFailedNode undoes a reopen whose task could not start a new attempt by writing back failedNode, the
+// node as it was before the reopen. Nothing is changed if the node has moved on from the reopened attempt.
+func (s *WorkflowNodeReopenService) restoreFailedNode(ctx context.Context, failedNode model.WorkflowNode, reopenedAttempt int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var node model.WorkflowNode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&node, "id = ?", failedNode.ID).Error; err != nil {
+			return fmt.Errorf("failed to retrieve workflow node %s: %w", failedNode.ID, err)
+		}
+		if node.State != model.WorkflowNodeStateReady || node.Attempt != reopenedAttempt {
+			return nil
+		}
+		if err := s.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, []model.WorkflowNode{failedNode}); err != nil {
+			return fmt.Errorf("failed to return workflow node %s to FAILED state: %w", failedNode.ID, err)
+		}
+		return nil
+	})
+}
+
+// workflowParent is the consignment or pre-consignment that owns a workflow node.
+type workflowParent struct {
+	traderID   string         // Trader the workflow belongs to
+	inProgress bool           // Whether the workflow is still in progress
+	context    map[string]any // GlobalContext of the consignment or TraderContext of the pre-consignment
+	endNodeID  *uuid.UUID     // End node of the consignment's workflow; nil for pre-consignments
+}
+
+// loadWorkflowNodeParent returns the consignment or pre-consignment that owns the node.
+func loadWorkflowNodeParent(tx *gorm.DB, node *model.WorkflowNode) (*workflowParent, error) {
+	switch {
+	case node.ConsignmentID != nil:
+		var consignment model.Consignment
+		if err := tx.First(&consignment, "id = ?", *node.ConsignmentID).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve consignment %s: %w", *node.ConsignmentID, err)
+		}
+		return &workflowParent{
+			traderID:   consignment.TraderID,
+			inProgress: consignment.State == model.ConsignmentStateInProgress,
+			context:    consignment.GlobalContext,
+			endNodeID:  consignment.EndNodeID,
+		}, nil
+	case node.PreConsignmentID != nil:
+		var preConsignment model.PreConsignment
+		if err := tx.First(&preConsignment, "id = ?", *node.PreConsignmentID).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve pre-consignment %s: %w", *node.PreConsignmentID, err)
+		}
+		return &workflowParent{
+			traderID:   preConsignment.TraderID,
+			inProgress: preConsignment.State == model.PreConsignmentStateInProgress,
+			context:    preConsignment.TraderContext,
+		}, nil
+	default:
+		return nil, fmt.Errorf("workflow node %s has neither consignment nor pre-consignment parent", node.ID)
+	}
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestWorkflowNodeReopenService_ReopenFailedNode(t *testing.T) {
+	ctx := context.Background()
+	nodeColumns := []string{"id", "consignment_id", "workflow_node_template_id", "state", "outcome", "attempt"}
+	consignmentColumns := []string{"id", "trader_id", "state", "global_context"}
+	traderPolicy := model.ReopenPolicyTrader
+	adminPolicy := model.ReopenPolicyAdmin
+
+	t.Run("Trader Reopens Failed Node", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &traderPolicy}, nil).Once()
+
+		var callbackNode model.WorkflowNode
+		var callbackContext map[string]any
+		service.SetPostReopenCallback(func(_ context.Context, node model.WorkflowNode, globalContext map[string]any) error {
+			// The task only starts its new attempt once the reopen is committed
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+			callbackNode = node
+			callbackContext = globalContext
+			return nil
+		})
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateReady && nodes[0].Attempt == 2
+		})).Return(nil).Once()
+		sqlMock.ExpectCommit()
+
+		node, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowNodeStateReady, node.State)
+		assert.Equal(t, 2, node.Attempt)
+		assert.Nil(t, node.Outcome)
+		assert.Equal(t, nodeID, callbackNode.ID)
+		assert.Equal(t, map[string]any{"foo": "bar"}, callbackContext)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Admin Reopens Admin Only Node", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &adminPolicy}, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+		sqlMock.ExpectCommit()
+
+		node, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "admin", IsAdmin: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WorkflowNodeStateReady, node.State)
+	})
+
+	t.Run("Trader Cannot Reopen Admin Only Node", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &adminPolicy}, nil).Once()
+		sqlMock.ExpectRollback()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeReopenNotPermitted)
+		nodeRepo.AssertNotCalled(t, "UpdateWorkflowNodesInTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Template Without Policy", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: nil}, nil).Once()
+		sqlMock.ExpectRollback()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "admin", IsAdmin: true})
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeReopenNotPermitted)
+	})
+
+	t.Run("Other Trader", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		sqlMock.ExpectRollback()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader2"})
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeNotFound)
+	})
+
+	t.Run("Node Not Failed", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "IN_PROGRESS", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &traderPolicy}, nil).Once()
+		sqlMock.ExpectRollback()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeNotReopenable)
+	})
+
+	t.Run("Consignment Cancelled", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "CANCELLED", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &traderPolicy}, nil).Once()
+		sqlMock.ExpectRollback()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeNotReopenable)
+	})
+
+	t.Run("Callback Error Returns Node To Failed", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &traderPolicy}, nil).Once()
+		service.SetPostReopenCallback(func(context.Context, model.WorkflowNode, map[string]any) error {
+			return errors.New("task manager unavailable")
+		})
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateReady
+		})).Return(nil).Once()
+		sqlMock.ExpectCommit()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "READY", nil, 2))
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].State == model.WorkflowNodeStateFailed && nodes[0].Attempt == 1 &&
+				nodes[0].Outcome != nil && *nodes[0].Outcome == "REJECTED"
+		})).Return(nil).Once()
+		sqlMock.ExpectCommit()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.ErrorContains(t, err, "task manager unavailable")
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Callback Error After Node Moved On", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templateProvider := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeReopenService(db, templateProvider, nodeRepo)
+		nodeID, consignmentID, templateID := uuid.New(), uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "FAILED", "REJECTED", 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		templateProvider.On("GetWorkflowNodeTemplateByID", ctx, templateID).
+			Return(&model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: templateID}, ReopenPolicy: &traderPolicy}, nil).Once()
+		service.SetPostReopenCallback(func(context.Context, model.WorkflowNode, map[string]any) error {
+			return errors.New("task manager unavailable")
+		})
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+		sqlMock.ExpectCommit()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).AddRow(nodeID, consignmentID, templateID, "IN_PROGRESS", nil, 2))
+		sqlMock.ExpectCommit()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.Error(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertNumberOfCalls(t, "UpdateWorkflowNodesInTx", 1)
+	})
+
+	t.Run("Node Not Found", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		service := NewWorkflowNodeReopenService(db, new(MockTemplateProvider), new(MockWorkflowNodeRepository))
+		nodeID := uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns))
+		sqlMock.ExpectRollback()
+
+		_, err := service.ReopenFailedNode(ctx, nodeID, ReopenRequester{TraderID: "trader1"})
+
+		assert.ErrorIs(t, err, ErrWorkflowNodeNotFound)
+	})
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// indexTargetNodeTemplates indexes nodeTemplates by ID and returns the end node templates of targetTemplates.
+// Every node template the workflow templates list must be among nodeTemplates.
+func indexTargetNodeTemplates(targetTemplates []model.WorkflowTemplate, nodeTemplates []model.WorkflowNodeTemplate) (map[uuid.UUID]model.WorkflowNodeTemplate, []uuid.UUID, error) {
+	nodeTemplateByID := make(map[uuid.UUID]model.WorkflowNodeTemplate, len(nodeTemplates))
+	for _, nt := range nodeTemplates {
+		nodeTemplateByID[nt.ID] = nt
+	}
+	var endDependencyTemplateIDs []uuid.UUID
+	for _, wt := range targetTemplates {
+		for _, id := range wt.NodeTemplates {
+			if _, ok := nodeTemplateByID[id]; !ok {
+				return nil, nil, fmt.Errorf("workflow node template with ID %s not found", id)
+			}
+		}
+		if wt.EndNodeTemplateID != nil {
+			endDependencyTemplateIDs = append(endDependencyTemplateIDs, *wt.EndNodeTemplateID)
+		}
+	}
+	return nodeTemplateByID, endDependencyTemplateIDs, nil
+}
+
+// nodeReplan is the result of resolving the nodes of a consignment against a new set of workflow templates.
+type nodeReplan struct {
+	addedNodes    []model.WorkflowNode // New nodes, including a new end node, with their IDs, dependencies and state set
+	keptNodes     []model.WorkflowNode // The given kept nodes and end node, with their dependencies and state updated
+	relockedNodes []model.WorkflowNode // Nodes moved from READY back to LOCKED, whose tasks must be discarded
+	unlockedNodes []model.WorkflowNode // Nodes moved to READY, which must be registered with the Task Manager
+	endNodeID     *uuid.UUID           // End node of the consignment after the change
+	finished      bool                 // Whether the end node was completed
+}
+
+// replanConsignmentNodes resolves the nodes of a consignment after its workflow templates changed, as shared by
+// template migrations and amendments, which differ in how they match existing nodes to requiredNodes.
+//
+// kept are the existing nodes that remain part of the workflow, and nodeByKey maps the node instances they fill
+// to their IDs; every other required node instance gets a new LOCKED node. endNode is the existing end node the
+// consignment keeps, if any; one is created if the workflow templates have end nodes and endNode is nil.
+// Dependencies are then resolved against the new set of nodes, READY nodes whose dependencies are no longer
+// met are locked again, and LOCKED nodes whose dependencies are now met become READY, or are completed for the
+// end node. Unlocks are evaluated against the consignment's GlobalContext.
+func (sm *WorkflowNodeStateMachine) replanConsignmentNodes(
+	consignment *model.Consignment,
+	kept []model.WorkflowNode,
+	endNode *model.WorkflowNode,
+	requiredNodes []nodeInstanceKey,
+	nodeByKey map[nodeInstanceKey]uuid.UUID,
+	nodeTemplateByID map[uuid.UUID]model.WorkflowNodeTemplate,
+	endDependencyTemplateIDs []uuid.UUID,
+	now time.Time,
+) (*nodeReplan, error) {
+	replan := &nodeReplan{}
+
+	// Create LOCKED nodes for the required nodes that have none yet
+	for _, key := range requiredNodes {
+		if _, exists := nodeByKey[key]; exists {
+			continue
+		}
+		nt := nodeTemplateByID[key.nodeTemplateID]
+		if nt.SLA != nil {
+			if err := nt.SLA.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid SLA configuration for node template %s: %w", nt.ID, err)
+			}
+		}
+		node := model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignment.ID,
+			WorkflowNodeTemplateID: key.nodeTemplateID,
+			State:                  model.WorkflowNodeStateLocked,
+			DependsOn:              model.UUIDArray{},
+			Attempt:                1,
+			SLA:                    nt.SLA,
+			ItemIndex:              key.itemIndexPtr(),
+		}
+		nodeByKey[key] = node.ID
+		replan.addedNodes = append(replan.addedNodes, node)
+	}
+
+	// The end node follows the end nodes of the workflow templates
+	addedEndNode := false
+	if len(endDependencyTemplateIDs) > 0 && endNode == nil {
+		endNode = &model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignment.ID,
+			WorkflowNodeTemplateID: uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID),
+			State:                  model.WorkflowNodeStateLocked,
+			DependsOn:              model.UUIDArray{},
+			Attempt:                1,
+		}
+		addedEndNode = true
+	}
+
+	// Resolve dependencies against the new set of nodes
+	allNodes := make([]*model.WorkflowNode, 0, len(kept)+len(replan.addedNodes)+1)
+	for i := range kept {
+		allNodes = append(allNodes, &kept[i])
+	}
+	for i := range replan.addedNodes {
+		allNodes = append(allNodes, &replan.addedNodes[i])
+	}
+	instances := newNodeInstances(allNodes)
+	if endNode != nil {
+		allNodes = append(allNodes, endNode)
+		resolveEndNodeDependencies(endNode, endDependencyTemplateIDs, instances)
+	}
+	for _, node := range allNodes {
+		if node == endNode {
+			continue
+		}
+		if err := resolveNodeDependencies(node, nodeTemplateByID[node.WorkflowNodeTemplateID], instances); err != nil {
+			return nil, err
+		}
+	}
+
+	// READY nodes whose dependencies are no longer met are locked again; their tasks have not started yet.
+	// Then LOCKED nodes whose dependencies are now met become READY, and a READY end node is completed.
+	nodeStateMap := make(map[uuid.UUID]model.WorkflowNode, len(allNodes))
+	for _, node := range allNodes {
+		nodeStateMap[node.ID] = *node
+	}
+	relocked := make(map[uuid.UUID]bool)
+	for _, node := range allNodes {
+		if node.State == model.WorkflowNodeStateReady && !sm.areDependenciesMet(*node, nodeStateMap, consignment.GlobalContext) {
+			node.State = model.WorkflowNodeStateLocked
+			node.SLADeadline = nil
+			node.SLABreachedAt = nil
+			relocked[node.ID] = true
+			nodeStateMap[node.ID] = *node
+			replan.relockedNodes = append(replan.relockedNodes, *node)
+		}
+	}
+	for _, node := range allNodes {
+		if node.State != model.WorkflowNodeStateLocked || relocked[node.ID] || !sm.areDependenciesMet(*node, nodeStateMap, consignment.GlobalContext) {
+			continue
+		}
+		if node == endNode {
+			node.State = model.WorkflowNodeStateCompleted
+			replan.finished = true
+		} else {
+			node.State = model.WorkflowNodeStateReady
+			node.StartSLA(now)
+			replan.unlockedNodes = append(replan.unlockedNodes, *node)
+		}
+		nodeStateMap[node.ID] = *node
+	}
+
+	replan.keptNodes = kept
+	if endNode != nil {
+		if addedEndNode {
+			replan.addedNodes = append(replan.addedNodes, *endNode)
+		} else {
+			replan.keptNodes = append(replan.keptNodes, *endNode)
+		}
+		replan.endNodeID = &endNode.ID
+	}
+
+	return replan, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -49,7 +50,8 @@ func (s *WorkflowNodeService) GetWorkflowNodesByIDsInTx(ctx context.Context, tx
 	return nodes, nil
 }
 
-// CreateWorkflowNodesInTx creates multiple workflow nodes within a transaction.
+// CreateWorkflowNodesInTx creates multiple workflow nodes within a transaction and records their creation
+// in the workflow event log.
 func (s *WorkflowNodeService) CreateWorkflowNodesInTx(ctx context.Context, tx *gorm.DB, nodes []model.WorkflowNode) ([]model.WorkflowNode, error) {
 	if len(nodes) == 0 {
 		return []model.WorkflowNode{}, nil
@@ -60,10 +62,22 @@ func (s *WorkflowNodeService) CreateWorkflowNodesInTx(ctx context.Context, tx *g
 		return nil, fmt.Errorf("failed to create workflow nodes in transaction: %w", result.Error)
 	}
 
+	events := make([]model.WorkflowEvent, 0, len(nodes))
+	for i := range nodes {
+		event := newWorkflowNodeEvent(&nodes[i], model.WorkflowEventNodeCreated)
+		state := string(nodes[i].State)
+		event.ToState = &state
+		events = append(events, event)
+	}
+	if err := recordWorkflowEventsInTx(ctx, tx, events); err != nil {
+		return nil, err
+	}
+
 	return nodes, nil
 }
 
-// UpdateWorkflowNodesInTx updates multiple workflow nodes within a transaction.
+// UpdateWorkflowNodesInTx updates multiple workflow nodes within a transaction and records their state and
+// outcome changes in the workflow event log.
 func (s *WorkflowNodeService) UpdateWorkflowNodesInTx(ctx context.Context, tx *gorm.DB, nodes []model.WorkflowNode) error {
 	if len(nodes) == 0 {
 		return nil
@@ -71,6 +85,7 @@ func (s *WorkflowNodeService) UpdateWorkflowNodesInTx(ctx context.Context, tx *g
 
 	// Update each node individually to avoid duplicate inserts
 	// First fetch the existing record, then update it to ensure GORM tracks it properly
+	var events []model.WorkflowEvent
 	for _, node := range nodes {
 		// Fetch the existing node from database
 		var existingNode model.WorkflowNode
@@ -78,22 +93,31 @@ func (s *WorkflowNodeService) UpdateWorkflowNodesInTx(ctx context.Context, tx *g
 		if result.Error != nil {
 			return fmt.Errorf("failed to find workflow node %s for update: %w", node.ID, result.Error)
 		}
+		previousNode := existingNode
 
 		// Update the fields
 		existingNode.State = node.State
 		existingNode.ExtendedState = node.ExtendedState
+		existingNode.Outcome = node.Outcome
+		existingNode.Attempt = node.Attempt
+		existingNode.SLADeadline = node.SLADeadline
+		existingNode.SLABreachedAt = node.SLABreachedAt
 		existingNode.DependsOn = node.DependsOn
 		if existingNode.DependsOn == nil {
 			existingNode.DependsOn = model.UUIDArray{}
 		}
+		existingNode.UnlockConfiguration = node.UnlockConfiguration
+		existingNode.WorkflowNodeTemplateID = node.WorkflowNodeTemplateID
+		existingNode.TaskCancellationPending = node.TaskCancellationPending
 
 		// Save the updated node
 		result = tx.WithContext(ctx).Save(&existingNode)
 		if result.Error != nil {
 			return fmt.Errorf("failed to update workflow node %s in transaction: %w", node.ID, result.Error)
 		}
+		events = append(events, workflowNodeChangeEvents(&previousNode, &existingNode)...)
 	}
-	return nil
+	return recordWorkflowEventsInTx(ctx, tx, events)
 }
 
 // GetWorkflowNodesByConsignmentIDInTx retrieves all workflow nodes associated with a given consignment ID within a transaction.
@@ -121,13 +145,14 @@ func (s *WorkflowNodeService) GetWorkflowNodesByConsignmentIDsInTx(ctx context.C
 	return nodes, nil
 }
 
-// CountIncompleteNodesByConsignmentID counts the number of incomplete workflow nodes for a given consignment.
+// CountIncompleteNodesByConsignmentID counts the number of incomplete workflow nodes for a given consignment,
+// i.e. those neither COMPLETED nor SKIPPED.
 // This is more efficient than fetching all nodes when only checking completion status.
 func (s *WorkflowNodeService) CountIncompleteNodesByConsignmentID(ctx context.Context, tx *gorm.DB, consignmentID uuid.UUID) (int64, error) {
 	var count int64
 	err := tx.WithContext(ctx).
 		Model(&model.WorkflowNode{}).
-		Where("consignment_id = ? AND state != ?", consignmentID, model.WorkflowNodeStateCompleted).
+		Where("consignment_id = ? AND state NOT IN ?", consignmentID, []model.WorkflowNodeState{model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped}).
 		Count(&count).Error
 	if err != nil {
 		return 0, fmt.Errorf("failed to count incomplete nodes for consignment %s: %w", consignmentID, err)
@@ -145,15 +170,86 @@ func (s *WorkflowNodeService) GetWorkflowNodesByPreConsignmentIDInTx(ctx context
 	return nodes, nil
 }
 
-// CountIncompleteNodesByPreConsignmentID counts the number of incomplete workflow nodes for a given pre-consignment.
+// CountIncompleteNodesByPreConsignmentID counts the number of incomplete workflow nodes for a given pre-consignment,
+// i.e. those neither COMPLETED nor SKIPPED.
 func (s *WorkflowNodeService) CountIncompleteNodesByPreConsignmentID(ctx context.Context, tx *gorm.DB, preConsignmentID uuid.UUID) (int64, error) {
 	var count int64
 	err := tx.WithContext(ctx).
 		Model(&model.WorkflowNode{}).
-		Where("pre_consignment_id = ? AND state != ?", preConsignmentID, model.WorkflowNodeStateCompleted).
+		Where("pre_consignment_id = ? AND state NOT IN ?", preConsignmentID, []model.WorkflowNodeState{model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped}).
 		Count(&count).Error
 	if err != nil {
 		return 0, fmt.Errorf("failed to count incomplete nodes for pre-consignment %s: %w", preConsignmentID, err)
 	}
 	return count, nil
 }
+
+// GetWorkflowNodesByStatesUpdatedBefore retrieves up to limit workflow nodes in any of the given states
+// that were last updated before the cutoff, ordered by ID and starting after afterID.
+// Passing the last returned ID as afterID pages through all matching nodes.
+func (s *WorkflowNodeService) GetWorkflowNodesByStatesUpdatedBefore(ctx context.Context, states []model.WorkflowNodeState, cutoff time.Time, afterID uuid.UUID, limit int) ([]model.WorkflowNode, error) {
+	var nodes []model.WorkflowNode
+	result := s.db.WithContext(ctx).
+		Where("state IN ? AND updated_at < ? AND id > ?", states, cutoff, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&nodes)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow nodes by state: %w", result.Error)
+	}
+	return nodes, nil
+}
+
+// GetWorkflowNodesPendingTaskCancellation retrieves up to limit workflow nodes, last updated before the cutoff,
+// whose task has yet to be cancelled, ordered by ID and starting after afterID.
+func (s *WorkflowNodeService) GetWorkflowNodesPendingTaskCancellation(ctx context.Context, cutoff time.Time, afterID uuid.UUID, limit int) ([]model.WorkflowNode, error) {
+	var nodes []model.WorkflowNode
+	result := s.db.WithContext(ctx).
+		Where("task_cancellation_pending AND updated_at < ? AND id > ?", cutoff, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&nodes)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow nodes pending task cancellation: %w", result.Error)
+	}
+	return nodes, nil
+}
+
+// ClearTaskCancellationPending records that the tasks of the given workflow nodes have been cancelled.
+func (s *WorkflowNodeService) ClearTaskCancellationPending(ctx context.Context, nodeIDs []uuid.UUID) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	result := s.db.WithContext(ctx).Model(&model.WorkflowNode{}).
+		Where("id IN ?", nodeIDs).
+		Update("task_cancellation_pending", false)
+	if result.Error != nil {
+		return fmt.Errorf("failed to clear pending task cancellation of workflow nodes: %w", result.Error)
+	}
+	return nil
+}
+
+// GetWorkflowNodesWithExpiredSLA retrieves up to limit READY or IN_PROGRESS workflow nodes whose SLA
+// deadline passed at now and whose breach has not been applied yet, earliest deadline first.
+func (s *WorkflowNodeService) GetWorkflowNodesWithExpiredSLA(ctx context.Context, now time.Time, limit int) ([]model.WorkflowNode, error) {
+	var nodes []model.WorkflowNode
+	result := s.db.WithContext(ctx).
+		Where("state IN ? AND sla_deadline <= ? AND sla_breached_at IS NULL",
+			[]model.WorkflowNodeState{model.WorkflowNodeStateReady, model.WorkflowNodeStateInProgress}, now).
+		Order("sla_deadline ASC").
+		Limit(limit).
+		Find(&nodes)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow nodes with expired SLA: %w", result.Error)
+	}
+	return nodes, nil
+}
+
+// formatOptionalTime formats t as RFC 3339, or returns nil if t is nil.
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
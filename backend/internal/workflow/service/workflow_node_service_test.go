@@ -3,12 +3,14 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 
+	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 )
 
@@ -47,12 +49,15 @@ func TestWorkflowNodeService_CreateWorkflowNodesInTx(t *testing.T) {
 
 	// Expectation: Create
 	sqlMock.ExpectExec(`INSERT INTO "workflow_nodes"`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Expectation: Creation recorded in the event log
+	expectWorkflowEvents(sqlMock)
 
 	result, err := service.CreateWorkflowNodesInTx(ctx, tx, nodes)
 	assert.NoError(t, err)
 	assert.Len(t, result, 1)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestWorkflowNodeService_UpdateWorkflowNodesInTx(t *testing.T) {
@@ -76,12 +81,18 @@ func TestWorkflowNodeService_UpdateWorkflowNodesInTx(t *testing.T) {
 
 	// Expectation: Save (Update)
 	// Save updates all fields
-	sqlMock.ExpectExec(`UPDATE "workflow_nodes" SET "created_at"=\$1,"updated_at"=\$2,"consignment_id"=\$3,"pre_consignment_id"=\$4,"workflow_node_template_id"=\$5,"state"=\$6,"extended_state"=\$7,"outcome"=\$8,"depends_on"=\$9,"unlock_configuration"=\$10 WHERE "id" = \$11`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "COMPLETED", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nodeID).
+	sqlMock.ExpectExec(`UPDATE "workflow_nodes" SET "created_at"=\$1,"updated_at"=\$2,"consignment_id"=\$3,"pre_consignment_id"=\$4,"workflow_node_template_id"=\$5,"state"=\$6,"extended_state"=\$7,"outcome"=\$8,"depends_on"=\$9,"unlock_configuration"=\$10,"attempt"=\$11,"sla"=\$12,"sla_deadline"=\$13,"sla_breached_at"=\$14,"item_index"=\$15,"parent_node_id"=\$16,"task_cancellation_pending"=\$17 WHERE "id" = \$18`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "COMPLETED", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false, nodeID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
+	// Expectation: State change recorded in the event log
+	expectWorkflowEvents(sqlMock).
+		WithArgs(nil, nil, nodeID, model.WorkflowEventNodeStateChanged, "IN_PROGRESS", "COMPLETED", nil, nil, nil,
+			auth.ActorTypeSystem, "", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg())
+
 	err := service.UpdateWorkflowNodesInTx(ctx, tx, nodes)
 	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestWorkflowNodeService_GetWorkflowNodesByConsignmentIDInTx(t *testing.T) {
@@ -111,8 +122,8 @@ func TestWorkflowNodeService_CountIncompleteNodesByConsignmentID(t *testing.T) {
 
 	consignmentID := uuid.New()
 
-	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_nodes" WHERE consignment_id = \$1 AND state != \$2`).
-		WithArgs(consignmentID, model.WorkflowNodeStateCompleted).
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_nodes" WHERE consignment_id = \$1 AND state NOT IN \(\$2,\$3\)`).
+		WithArgs(consignmentID, model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
 	count, err := service.CountIncompleteNodesByConsignmentID(ctx, tx, consignmentID)
@@ -149,8 +160,8 @@ func TestWorkflowNodeService_CountIncompleteNodesByPreConsignmentID(t *testing.T
 
 	pcID := uuid.New()
 
-	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_nodes" WHERE pre_consignment_id = \$1 AND state != \$2`).
-		WithArgs(pcID, model.WorkflowNodeStateCompleted).
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_nodes" WHERE pre_consignment_id = \$1 AND state NOT IN \(\$2,\$3\)`).
+		WithArgs(pcID, model.WorkflowNodeStateCompleted, model.WorkflowNodeStateSkipped).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
 	count, err := service.CountIncompleteNodesByPreConsignmentID(ctx, tx, pcID)
@@ -213,6 +224,33 @@ func TestWorkflowNodeService_GetWorkflowNodeByID(t *testing.T) {
 	assert.Equal(t, id, node.ID)
 }
 
+func TestWorkflowNodeService_PendingTaskCancellation(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	service := NewWorkflowNodeService(db)
+	ctx := context.Background()
+	id := uuid.New()
+	cutoff := time.Now()
+
+	sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE task_cancellation_pending AND updated_at < \$1 AND id > \$2 ORDER BY id ASC LIMIT \$3`).
+		WithArgs(cutoff, uuid.Nil, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "state", "task_cancellation_pending"}).AddRow(id, "LOCKED", true))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`UPDATE "workflow_nodes" SET "task_cancellation_pending"=\$1,"updated_at"=\$2 WHERE id IN \(\$3\)`).
+		WithArgs(false, sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	nodes, err := service.GetWorkflowNodesPendingTaskCancellation(ctx, cutoff, uuid.Nil, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, nodes, 1) {
+		assert.True(t, nodes[0].TaskCancellationPending)
+	}
+
+	assert.NoError(t, service.ClearTaskCancellationPending(ctx, []uuid.UUID{id}))
+	assert.NoError(t, service.ClearTaskCancellationPending(ctx, nil))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
 func TestWorkflowNodeService_UpdateWorkflowNodesInTx_Failure(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	service := NewWorkflowNodeService(db)
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// WorkflowNodeSLAService applies the SLA breaches of workflow nodes whose SLA expired.
+type WorkflowNodeSLAService struct {
+	db                    *gorm.DB
+	stateMachine          *WorkflowNodeStateMachine
+	consignmentService    *ConsignmentService
+	preConsignmentService *PreConsignmentService
+}
+
+// NewWorkflowNodeSLAService creates a new instance of WorkflowNodeSLAService. The consignment and
+// pre-consignment services finish the workflows that a breach completes.
+func NewWorkflowNodeSLAService(db *gorm.DB, nodeRepo WorkflowNodeRepository, consignmentService *ConsignmentService, preConsignmentService *PreConsignmentService) *WorkflowNodeSLAService {
+	return &WorkflowNodeSLAService{
+		db:                    db,
+		stateMachine:          NewWorkflowNodeStateMachine(nodeRepo),
+		consignmentService:    consignmentService,
+		preConsignmentService: preConsignmentService,
+	}
+}
+
+// ApplySLABreach sets the breach outcome on a workflow node whose SLA expired at now and unlocks
+// the dependent nodes that branch on it; if that completes the end node, the consignment or
+// pre-consignment is finished. Returns the nodes that became READY and the global context of the
+// node's workflow, so that the caller can register them with the Task Manager, and the SUB_WORKFLOW
+// nodes that were completed, whose tasks the caller must complete.
+// Nothing is changed, and no nodes are returned, if the node finished, its breach was already
+// applied, its deadline moved past now (e.g. because it was reopened), or its workflow is no
+// longer in progress.
+func (s *WorkflowNodeSLAService) ApplySLABreach(ctx context.Context, nodeID uuid.UUID, now time.Time) ([]model.WorkflowNode, map[string]any, []model.WorkflowNode, error) {
+	// Start a transaction
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the node so that the breach is not applied concurrently with a task update
+	var node model.WorkflowNode
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&node, "id = ?", nodeID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, ErrWorkflowNodeNotFound
+		}
+		return nil, nil, nil, fmt.Errorf("failed to retrieve workflow node %s: %w", nodeID, err)
+	}
+
+	if node.SLA == nil || node.SLADeadline == nil || node.SLABreachedAt != nil || now.Before(*node.SLADeadline) ||
+		(node.State != model.WorkflowNodeStateReady && node.State != model.WorkflowNodeStateInProgress) {
+		tx.Rollback()
+		return nil, nil, nil, nil
+	}
+
+	parent, err := loadWorkflowNodeParent(tx, &node)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, nil, err
+	}
+	if !parent.inProgress {
+		tx.Rollback()
+		return nil, nil, nil, nil
+	}
+
+	completionConfig := WorkflowCompletionConfig{EndNodeID: parent.endNodeID}
+	result, err := s.stateMachine.ApplySLABreach(ctx, tx, &node, now, parent.context, &completionConfig)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, nil, fmt.Errorf("failed to apply SLA breach: %w", err)
+	}
+
+	if result.WorkflowFinished {
+		if node.ConsignmentID != nil {
+			err = s.consignmentService.markConsignmentAsFinished(ctx, tx, *node.ConsignmentID)
+		} else {
+			err = s.preConsignmentService.markPreConsignmentAsCompleted(ctx, tx, *node.PreConsignmentID)
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, nil, err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result.NewReadyNodes, parent.context, result.CompletedSubWorkflowNodes, nil
+}
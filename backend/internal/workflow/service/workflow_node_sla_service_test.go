@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestWorkflowNodeSLAService_ApplySLABreach(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	nodeColumns := []string{"id", "consignment_id", "state", "sla", "sla_deadline", "sla_breached_at"}
+	consignmentColumns := []string{"id", "trader_id", "state", "global_context"}
+
+	t.Run("Applies Breach", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeSLAService(db, nodeRepo, NewConsignmentService(db, nil, nodeRepo), NewPreConsignmentService(db, nil, nodeRepo))
+		nodeID, consignmentID := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).
+				AddRow(nodeID, consignmentID, "IN_PROGRESS", []byte(`{"duration":"72h"}`), now.Add(-time.Minute), nil))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{"foo":"bar"}`)))
+		escalationNode := model.WorkflowNode{
+			BaseModel:     model.BaseModel{ID: uuid.New()},
+			ConsignmentID: &consignmentID,
+			State:         model.WorkflowNodeStateLocked,
+			UnlockConfiguration: &model.UnlockConfig{
+				AnyOf: []model.UnlockGroup{{AllOf: []model.UnlockCondition{
+					{NodeTemplateID: uuid.New(), NodeID: &nodeID, Outcome: strPtr(model.DefaultSLABreachOutcome)},
+				}}},
+			},
+		}
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{{BaseModel: model.BaseModel{ID: nodeID}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateInProgress}, escalationNode}, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 2
+		})).Return(nil).Once()
+		sqlMock.ExpectCommit()
+
+		newReadyNodes, globalContext, _, err := service.ApplySLABreach(ctx, nodeID, now)
+
+		assert.NoError(t, err)
+		assert.Len(t, newReadyNodes, 1)
+		assert.Equal(t, escalationNode.ID, newReadyNodes[0].ID)
+		assert.Equal(t, map[string]any{"foo": "bar"}, globalContext)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Finishes Consignment", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeSLAService(db, nodeRepo, NewConsignmentService(db, nil, nodeRepo), NewPreConsignmentService(db, nil, nodeRepo))
+		nodeID, consignmentID := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).
+				AddRow(nodeID, consignmentID, "IN_PROGRESS", []byte(`{"duration":"72h"}`), now.Add(-time.Minute), nil))
+		endNodeID := uuid.New()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(append(consignmentColumns, "end_node_id")).
+				AddRow(consignmentID, "trader1", "IN_PROGRESS", []byte(`{}`), endNodeID))
+		endNode := model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: endNodeID},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID),
+			State:                  model.WorkflowNodeStateLocked,
+			UnlockConfiguration: &model.UnlockConfig{
+				AnyOf: []model.UnlockGroup{{AllOf: []model.UnlockCondition{
+					{NodeTemplateID: uuid.New(), NodeID: &nodeID, Outcome: strPtr(model.DefaultSLABreachOutcome)},
+				}}},
+			},
+		}
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{{BaseModel: model.BaseModel{ID: nodeID}, ConsignmentID: &consignmentID, State: model.WorkflowNodeStateInProgress}, endNode}, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "state"}).AddRow(consignmentID, "IN_PROGRESS"))
+		sqlMock.ExpectExec(`UPDATE "consignments" SET "state"=\$1`).
+			WithArgs(model.ConsignmentStateFinished, sqlmock.AnyArg(), consignmentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		newReadyNodes, _, _, err := service.ApplySLABreach(ctx, nodeID, now)
+
+		assert.NoError(t, err)
+		assert.Empty(t, newReadyNodes)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Already Breached", func(t *testing.T) {
+		breachedAt := now.Add(-time.Second)
+		db, sqlMock := setupTestDB(t)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeSLAService(db, nodeRepo, NewConsignmentService(db, nil, nodeRepo), NewPreConsignmentService(db, nil, nodeRepo))
+		nodeID, consignmentID := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).
+				AddRow(nodeID, consignmentID, "IN_PROGRESS", []byte(`{"duration":"72h"}`), now.Add(-time.Minute), &breachedAt))
+		sqlMock.ExpectRollback()
+
+		newReadyNodes, _, _, err := service.ApplySLABreach(ctx, nodeID, now)
+
+		assert.NoError(t, err)
+		assert.Empty(t, newReadyNodes)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertNotCalled(t, "UpdateWorkflowNodesInTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Deadline Moved By Reopen", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeSLAService(db, nodeRepo, NewConsignmentService(db, nil, nodeRepo), NewPreConsignmentService(db, nil, nodeRepo))
+		nodeID, consignmentID := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).
+				AddRow(nodeID, consignmentID, "READY", []byte(`{"duration":"72h"}`), now.Add(time.Hour), nil))
+		sqlMock.ExpectRollback()
+
+		newReadyNodes, _, _, err := service.ApplySLABreach(ctx, nodeID, now)
+
+		assert.NoError(t, err)
+		assert.Empty(t, newReadyNodes)
+	})
+
+	t.Run("Consignment Cancelled", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowNodeSLAService(db, nodeRepo, NewConsignmentService(db, nil, nodeRepo), NewPreConsignmentService(db, nil, nodeRepo))
+		nodeID, consignmentID := uuid.New(), uuid.New()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_nodes" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(nodeID, 1).
+			WillReturnRows(sqlmock.NewRows(nodeColumns).
+				AddRow(nodeID, consignmentID, "IN_PROGRESS", []byte(`{"duration":"72h"}`), now.Add(-time.Minute), nil))
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows(consignmentColumns).AddRow(consignmentID, "trader1", "CANCELLED", []byte(`{"foo":"bar"}`)))
+		sqlMock.ExpectRollback()
+
+		newReadyNodes, _, _, err := service.ApplySLABreach(ctx, nodeID, now)
+
+		assert.NoError(t, err)
+		assert.Empty(t, newReadyNodes)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// ErrInvalidTemplateMigration is returned when a template migration request does not describe a move to a later
+// published version of the same family.
+var ErrInvalidTemplateMigration = errors.New("invalid workflow template migration")
+
+// WorkflowTemplateMigrationService moves in-flight consignments from one published version of a workflow
+// template family to a later one.
+//
+// The nodes of a consignment are matched to the target version by node template: nodes whose template is in
+// the target version are kept, nodes whose template is mapped to one of the target version are moved to it,
+// and LOCKED or SKIPPED nodes whose template is in neither are voided, i.e. locked for good with the
+// MIGRATION_VOIDED extended state, so that their events and context changes keep pointing at them. Node templates
+// new in the target version get a LOCKED node. Dependencies are then resolved again, so nodes can become READY
+// or, if a new prerequisite was added before them, be locked again; if the end node becomes READY, the
+// consignment is finished. Nodes that were already started keep their state and are never voided; a
+// consignment where that would be needed is skipped and reported as a conflict.
+type WorkflowTemplateMigrationService struct {
+	db                *gorm.DB
+	templateProvider  TemplateProvider
+	nodeRepo          WorkflowNodeRepository
+	stateMachine      *WorkflowNodeStateMachine
+	preCommitCallback func(ctx context.Context, relockedNodes, unlockedNodes []model.WorkflowNode, globalContext map[string]any) error
+}
+
+// NewWorkflowTemplateMigrationService creates a new instance of WorkflowTemplateMigrationService.
+func NewWorkflowTemplateMigrationService(db *gorm.DB, templateProvider TemplateProvider, nodeRepo WorkflowNodeRepository) *WorkflowTemplateMigrationService {
+	return &WorkflowTemplateMigrationService{
+		db:               db,
+		templateProvider: templateProvider,
+		nodeRepo:         nodeRepo,
+		stateMachine:     NewWorkflowNodeStateMachine(nodeRepo),
+	}
+}
+
+// SetPreCommitCallback sets a callback to be executed before the migration of a consignment is committed,
+// with the nodes that were locked again, the nodes that became READY and the consignment's global context.
+// This lets the tasks of relocked nodes be discarded and the nodes that became READY be registered within
+// the transaction; if the callback fails, the consignment is left unchanged.
+func (s *WorkflowTemplateMigrationService) SetPreCommitCallback(callback func(ctx context.Context, relockedNodes, unlockedNodes []model.WorkflowNode, globalContext map[string]any) error) {
+	s.preCommitCallback = callback
+}
+
+// MigrateConsignments migrates the in-progress consignments using req.FromTemplateID to req.ToTemplateID,
+// or only those in req.ConsignmentIDs if set. Each consignment is migrated in its own transaction, so a
+// consignment that is skipped or fails does not affect the others. With req.DryRun, nothing is changed and
+// the report describes what would be done.
+func (s *WorkflowTemplateMigrationService) MigrateConsignments(ctx context.Context, req *model.TemplateMigrationRequest) (*model.TemplateMigrationReport, error) {
+	fromTemplate, toTemplate, err := s.validateMigration(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Model(&model.Consignment{}).
+		Where("state = ? AND workflow_template_ids @> ?::jsonb", model.ConsignmentStateInProgress, fmt.Sprintf(`[%q]`, fromTemplate.ID))
+	if len(req.ConsignmentIDs) > 0 {
+		query = query.Where("id IN ?", req.ConsignmentIDs)
+	}
+	var consignmentIDs []uuid.UUID
+	if err := query.Order("created_at ASC").Pluck("id", &consignmentIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve consignments using workflow template %s: %w", fromTemplate.ID, err)
+	}
+
+	report := &model.TemplateMigrationReport{
+		FromTemplateID: fromTemplate.ID,
+		ToTemplateID:   toTemplate.ID,
+		DryRun:         req.DryRun,
+		Consignments:   make([]model.ConsignmentMigrationReport, 0, len(consignmentIDs)),
+	}
+	workflowTemplates := map[uuid.UUID]*model.WorkflowTemplate{fromTemplate.ID: fromTemplate, toTemplate.ID: toTemplate}
+	for _, consignmentID := range consignmentIDs {
+		consignmentReport := s.migrateConsignment(ctx, consignmentID, fromTemplate.ID, toTemplate.ID, req.NodeTemplateMapping, workflowTemplates, req.DryRun)
+		switch consignmentReport.Status {
+		case model.ConsignmentMigrationStatusPlanned:
+			report.Planned++
+		case model.ConsignmentMigrationStatusMigrated:
+			report.Migrated++
+		case model.ConsignmentMigrationStatusSkipped:
+			report.Skipped++
+		case model.ConsignmentMigrationStatusFailed:
+			report.Failed++
+			slog.WarnContext(ctx, "failed to migrate consignment to workflow template version",
+				"consignmentID", consignmentID,
+				"toTemplateID", toTemplate.ID,
+				"error", consignmentReport.Error)
+		}
+		report.Consignments = append(report.Consignments, *consignmentReport)
+	}
+
+	return report, nil
+}
+
+// validateMigration checks that the request moves consignments to a later published version of the same family
+// and that the node template mapping refers to node templates of the two versions.
+func (s *WorkflowTemplateMigrationService) validateMigration(ctx context.Context, req *model.TemplateMigrationRequest) (*model.WorkflowTemplate, *model.WorkflowTemplate, error) {
+	fromTemplate, err := s.getWorkflowTemplate(ctx, req.FromTemplateID)
+	if err != nil {
+		return nil, nil, err
+	}
+	toTemplate, err := s.getWorkflowTemplate(ctx, req.ToTemplateID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fromTemplate.FamilyID != toTemplate.FamilyID {
+		return nil, nil, fmt.Errorf("%w: workflow templates %s and %s belong to different families", ErrInvalidTemplateMigration, fromTemplate.ID, toTemplate.ID)
+	}
+	if fromTemplate.Status != model.WorkflowTemplateStatusPublished || toTemplate.Status != model.WorkflowTemplateStatusPublished {
+		return nil, nil, fmt.Errorf("%w: both workflow templates must be published", ErrInvalidTemplateMigration)
+	}
+	if toTemplate.VersionNumber <= fromTemplate.VersionNumber {
+		return nil, nil, fmt.Errorf("%w: version %d is not later than version %d", ErrInvalidTemplateMigration, toTemplate.VersionNumber, fromTemplate.VersionNumber)
+	}
+	for oldNodeTemplateID, newNodeTemplateID := range req.NodeTemplateMapping {
+		if !slices.Contains(fromTemplate.NodeTemplates, oldNodeTemplateID) {
+			return nil, nil, fmt.Errorf("%w: node template %s is not part of workflow template %s", ErrInvalidTemplateMigration, oldNodeTemplateID, fromTemplate.ID)
+		}
+		if !slices.Contains(toTemplate.NodeTemplates, newNodeTemplateID) {
+			return nil, nil, fmt.Errorf("%w: node template %s is not part of workflow template %s", ErrInvalidTemplateMigration, newNodeTemplateID, toTemplate.ID)
+		}
+	}
+
+	return fromTemplate, toTemplate, nil
+}
+
+// getWorkflowTemplate retrieves a workflow template, returning ErrWorkflowTemplateNotFound if it does not exist.
+func (s *WorkflowTemplateMigrationService) getWorkflowTemplate(ctx context.Context, id uuid.UUID) (*model.WorkflowTemplate, error) {
+	workflowTemplate, err := s.templateProvider.GetWorkflowTemplateByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowTemplateNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to retrieve workflow template %s: %w", id, err)
+	}
+	return workflowTemplate, nil
+}
+
+// migrateConsignment migrates a single consignment in its own transaction. workflowTemplates caches the
+// workflow templates loaded so far, since most consignments use the same ones.
+func (s *WorkflowTemplateMigrationService) migrateConsignment(
+	ctx context.Context,
+	consignmentID uuid.UUID,
+	fromTemplateID uuid.UUID,
+	toTemplateID uuid.UUID,
+	nodeTemplateMapping map[uuid.UUID]uuid.UUID,
+	workflowTemplates map[uuid.UUID]*model.WorkflowTemplate,
+	dryRun bool,
+) *model.ConsignmentMigrationReport {
+	report := &model.ConsignmentMigrationReport{ConsignmentID: consignmentID}
+	fail := func(err error) *model.ConsignmentMigrationReport {
+		report.Status = model.ConsignmentMigrationStatusFailed
+		report.Error = err.Error()
+		return report
+	}
+
+	tx := s.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the consignment so that node updates for it wait until the migration is committed
+	query := tx
+	if !dryRun {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var consignment model.Consignment
+	if err := query.First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		tx.Rollback()
+		return fail(fmt.Errorf("failed to retrieve consignment: %w", err))
+	}
+	// Re-check under the lock: the consignment may have finished or been migrated since it was selected
+	if consignment.State != model.ConsignmentStateInProgress || !slices.Contains(consignment.WorkflowTemplateIDs, fromTemplateID) {
+		tx.Rollback()
+		report.Status = model.ConsignmentMigrationStatusSkipped
+		report.Conflicts = []string{"consignment is no longer in progress on the source workflow template version"}
+		return report
+	}
+
+	// The consignment's other workflow templates (from items mapped to other families) stay as they are
+	targetTemplateIDs := make(model.UUIDArray, 0, len(consignment.WorkflowTemplateIDs))
+	for _, id := range consignment.WorkflowTemplateIDs {
+		if id == fromTemplateID {
+			id = toTemplateID
+		}
+		if !slices.Contains(targetTemplateIDs, id) {
+			targetTemplateIDs = append(targetTemplateIDs, id)
+		}
+	}
+	targetTemplates := make([]model.WorkflowTemplate, 0, len(targetTemplateIDs))
+	var nodeTemplateIDs []uuid.UUID
+	for _, id := range targetTemplateIDs {
+		workflowTemplate, ok := workflowTemplates[id]
+		if !ok {
+			var err error
+			if workflowTemplate, err = s.getWorkflowTemplate(ctx, id); err != nil {
+				tx.Rollback()
+				return fail(err)
+			}
+			workflowTemplates[id] = workflowTemplate
+		}
+		targetTemplates = append(targetTemplates, *workflowTemplate)
+		for _, nodeTemplateID := range workflowTemplate.NodeTemplates {
+			if !slices.Contains(nodeTemplateIDs, nodeTemplateID) {
+				nodeTemplateIDs = append(nodeTemplateIDs, nodeTemplateID)
+			}
+		}
+	}
+	nodeTemplates, err := s.templateProvider.GetWorkflowNodeTemplatesByIDs(ctx, nodeTemplateIDs)
+	if err != nil {
+		tx.Rollback()
+		return fail(fmt.Errorf("failed to retrieve workflow node templates: %w", err))
+	}
+
+	nodes, err := s.nodeRepo.GetWorkflowNodesByConsignmentIDInTx(ctx, tx, consignmentID)
+	if err != nil {
+		tx.Rollback()
+		return fail(err)
+	}
+
+	// Items on the source version move to the target version, so that their item-scoped nodes follow them
+	for i, item := range consignment.Items {
+		if item.WorkflowTemplateID != nil && *item.WorkflowTemplateID == fromTemplateID {
+			consignment.Items[i].WorkflowTemplateID = &toTemplateID
+		}
+	}
+
+	plan, err := s.planMigration(consignment, nodes, targetTemplates, nodeTemplates, nodeTemplateMapping, time.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+		return fail(err)
+	}
+	report.Added = plan.report.Added
+	report.Voided = plan.report.Voided
+	report.Remapped = plan.report.Remapped
+	report.Relocked = plan.report.Relocked
+	report.Unlocked = plan.report.Unlocked
+	report.Finished = plan.report.Finished
+	report.Conflicts = plan.report.Conflicts
+	if len(report.Conflicts) > 0 {
+		tx.Rollback()
+		report.Status = model.ConsignmentMigrationStatusSkipped
+		return report
+	}
+	if dryRun {
+		tx.Rollback()
+		report.Status = model.ConsignmentMigrationStatusPlanned
+		return report
+	}
+
+	if _, err := s.nodeRepo.CreateWorkflowNodesInTx(ctx, tx, plan.addedNodes); err != nil {
+		tx.Rollback()
+		return fail(err)
+	}
+	if err := s.nodeRepo.UpdateWorkflowNodesInTx(ctx, tx, plan.updatedNodes); err != nil {
+		tx.Rollback()
+		return fail(err)
+	}
+
+	consignment.WorkflowTemplateIDs = targetTemplateIDs
+	consignment.EndNodeID = plan.endNodeID
+	if plan.report.Finished {
+		consignment.State = model.ConsignmentStateFinished
+	}
+	if err := tx.Model(&consignment).Select("items", "workflow_template_ids", "end_node_id", "state").Updates(&consignment).Error; err != nil {
+		tx.Rollback()
+		return fail(fmt.Errorf("failed to update consignment workflow templates: %w", err))
+	}
+
+	if s.preCommitCallback != nil && (len(plan.relockedNodes) > 0 || len(plan.unlockedNodes) > 0) {
+		if err := s.preCommitCallback(ctx, plan.relockedNodes, plan.unlockedNodes, consignment.GlobalContext); err != nil {
+			tx.Rollback()
+			return fail(fmt.Errorf("failed to update tasks of migrated workflow nodes: %w", err))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return fail(fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	report.Status = model.ConsignmentMigrationStatusMigrated
+	return report
+}
+
+// templateMigrationPlan is the set of changes that moves the nodes of a consignment to a new set of workflow templates.
+type templateMigrationPlan struct {
+	addedNodes    []model.WorkflowNode // New nodes, with their IDs, dependencies and state already set
+	updatedNodes  []model.WorkflowNode // Existing nodes that are kept or voided
+	relockedNodes []model.WorkflowNode // Nodes moved from READY back to LOCKED, whose tasks must be discarded
+	unlockedNodes []model.WorkflowNode // Nodes moved to READY, which must be registered with the Task Manager
+	endNodeID     *uuid.UUID           // End node of the consignment after the migration
+	report        model.ConsignmentMigrationReport
+}
+
+// planMigration computes how the nodes of a consignment change when its workflow templates become
+// targetTemplates. Nodes are matched by node template, following nodeTemplateMapping for node templates that
+// were replaced. Reasons the consignment cannot be migrated safely are reported as conflicts in the plan.
+func (s *WorkflowTemplateMigrationService) planMigration(
+	consignment model.Consignment,
+	nodes []model.WorkflowNode,
+	targetTemplates []model.WorkflowTemplate,
+	nodeTemplates []model.WorkflowNodeTemplate,
+	nodeTemplateMapping map[uuid.UUID]uuid.UUID,
+	now time.Time,
+) (*templateMigrationPlan, error) {
+	plan := &templateMigrationPlan{}
+	endNodeTemplateID := uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+	var voided []model.WorkflowNode
+	void := func(node model.WorkflowNode) {
+		extendedState := model.WorkflowNodeExtendedStateMigrationVoided
+		node.State = model.WorkflowNodeStateLocked
+		node.ExtendedState = &extendedState
+		node.SLADeadline = nil
+		node.SLABreachedAt = nil
+		voided = append(voided, node)
+		plan.report.Voided = append(plan.report.Voided, nodeChange(node, nil))
+	}
+
+	nodeTemplateByID, endDependencyTemplateIDs, err := indexTargetNodeTemplates(targetTemplates, nodeTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Match existing nodes to the nodes the target version requires; nodes of item-scoped node templates
+	// are matched per item
+	requiredNodes := requiredNodeInstances(targetTemplates, nodeTemplateByID, consignment.Items)
+	var kept []model.WorkflowNode
+	var endNode *model.WorkflowNode
+	nodeByKey := make(map[nodeInstanceKey]uuid.UUID)
+	previousTemplateIDs := make(map[uuid.UUID]uuid.UUID)
+	for _, node := range nodes {
+		// Nodes of child workflows follow their SUB_WORKFLOW node, which is never voided once started
+		if node.InSubWorkflow() {
+			continue
+		}
+		// Voided nodes are no longer part of the workflow and are left as they are
+		if node.IsVoided() {
+			continue
+		}
+		if node.WorkflowNodeTemplateID == endNodeTemplateID {
+			endNode = &node
+			continue
+		}
+
+		previousTemplateID := node.WorkflowNodeTemplateID
+		if !slices.Contains(requiredNodes, instanceKey(node.WorkflowNodeTemplateID, node.ItemIndex)) {
+			mappedTemplateID, mapped := nodeTemplateMapping[node.WorkflowNodeTemplateID]
+			if !mapped || !slices.Contains(requiredNodes, instanceKey(mappedTemplateID, node.ItemIndex)) {
+				if node.State != model.WorkflowNodeStateLocked && node.State != model.WorkflowNodeStateSkipped {
+					plan.report.Conflicts = append(plan.report.Conflicts, fmt.Sprintf(
+						"node %s is %s but its node template %s is not part of the target version; map it to a node template of the target version",
+						node.ID, node.State, node.WorkflowNodeTemplateID))
+					continue
+				}
+				void(node)
+				continue
+			}
+			node.WorkflowNodeTemplateID = mappedTemplateID
+			previousTemplateIDs[node.ID] = previousTemplateID
+		}
+
+		key := instanceKey(node.WorkflowNodeTemplateID, node.ItemIndex)
+		if existingNodeID, duplicate := nodeByKey[key]; duplicate {
+			plan.report.Conflicts = append(plan.report.Conflicts, fmt.Sprintf(
+				"nodes %s and %s would both use node template %s", existingNodeID, node.ID, node.WorkflowNodeTemplateID))
+			continue
+		}
+		nodeByKey[key] = node.ID
+		kept = append(kept, node)
+	}
+
+	// The end node follows the end nodes of the target templates
+	if len(endDependencyTemplateIDs) == 0 && endNode != nil {
+		if endNode.State != model.WorkflowNodeStateLocked {
+			plan.report.Conflicts = append(plan.report.Conflicts, fmt.Sprintf(
+				"end node %s is %s but the target version has no end node", endNode.ID, endNode.State))
+		} else {
+			void(*endNode)
+		}
+		endNode = nil
+	}
+
+	replan, err := s.stateMachine.replanConsignmentNodes(&consignment, kept, endNode, requiredNodes, nodeByKey,
+		nodeTemplateByID, endDependencyTemplateIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	plan.addedNodes = replan.addedNodes
+	plan.updatedNodes = append(replan.keptNodes, voided...)
+	plan.relockedNodes = replan.relockedNodes
+	plan.unlockedNodes = replan.unlockedNodes
+	plan.endNodeID = replan.endNodeID
+	plan.report.Finished = replan.finished
+
+	for _, node := range replan.keptNodes {
+		if previousTemplateID, remapped := previousTemplateIDs[node.ID]; remapped {
+			plan.report.Remapped = append(plan.report.Remapped, nodeChange(node, &previousTemplateID))
+		}
+	}
+	for _, node := range replan.addedNodes {
+		plan.report.Added = append(plan.report.Added, nodeChange(node, nil))
+	}
+	for _, node := range replan.relockedNodes {
+		plan.report.Relocked = append(plan.report.Relocked, nodeChange(node, nil))
+	}
+	for _, node := range replan.unlockedNodes {
+		plan.report.Unlocked = append(plan.report.Unlocked, nodeChange(node, nil))
+	}
+
+	return plan, nil
+}
+
+// nodeChange describes the given node in a migration report.
+func nodeChange(node model.WorkflowNode, previousNodeTemplateID *uuid.UUID) model.NodeMigrationChange {
+	return model.NodeMigrationChange{
+		WorkflowNodeID:         node.ID,
+		NodeTemplateID:         node.WorkflowNodeTemplateID,
+		PreviousNodeTemplateID: previousNodeTemplateID,
+		State:                  node.State,
+	}
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestWorkflowTemplateMigrationService_PlanMigration(t *testing.T) {
+	s := NewWorkflowTemplateMigrationService(nil, nil, nil)
+	now := time.Now().UTC()
+	consignmentID := uuid.New()
+	consignment := model.Consignment{BaseModel: model.BaseModel{ID: consignmentID}}
+	endNodeTemplateID := uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID)
+
+	nodeTemplate := func(id uuid.UUID, dependsOn ...uuid.UUID) model.WorkflowNodeTemplate {
+		return model.WorkflowNodeTemplate{BaseModel: model.BaseModel{ID: id}, DependsOn: dependsOn}
+	}
+	node := func(templateID uuid.UUID, state model.WorkflowNodeState, dependsOn ...uuid.UUID) model.WorkflowNode {
+		return model.WorkflowNode{
+			BaseModel:              model.BaseModel{ID: uuid.New()},
+			ConsignmentID:          &consignmentID,
+			WorkflowNodeTemplateID: templateID,
+			State:                  state,
+			DependsOn:              dependsOn,
+		}
+	}
+	findNode := func(nodes []model.WorkflowNode, templateID uuid.UUID) *model.WorkflowNode {
+		for i := range nodes {
+			if nodes[i].WorkflowNodeTemplateID == templateID {
+				return &nodes[i]
+			}
+		}
+		return nil
+	}
+
+	templateA, templateB, templateB2, templateC := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("Adds Prerequisite And Relocks Remapped Node", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateCompleted)
+		nodeB := node(templateB, model.WorkflowNodeStateReady, nodeA.ID)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeB.ID)
+		target := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA, templateC, templateB2}, EndNodeTemplateID: &templateB2}
+
+		plan, err := s.planMigration(consignment, []model.WorkflowNode{nodeA, nodeB, endNode}, []model.WorkflowTemplate{target},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA), nodeTemplate(templateC, templateA), nodeTemplate(templateB2, templateC)},
+			map[uuid.UUID]uuid.UUID{templateB: templateB2}, now)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.report.Conflicts)
+		assert.Empty(t, plan.report.Voided)
+
+		// C is new and unlocked since A is completed
+		require.Len(t, plan.addedNodes, 1)
+		nodeC := plan.addedNodes[0]
+		assert.Equal(t, templateC, nodeC.WorkflowNodeTemplateID)
+		assert.Equal(t, model.WorkflowNodeStateReady, nodeC.State)
+		assert.Equal(t, model.UUIDArray{nodeA.ID}, nodeC.DependsOn)
+		assert.Equal(t, []model.WorkflowNode{nodeC}, plan.unlockedNodes)
+
+		// B keeps its ID but now uses B2 and waits for C
+		migratedB := findNode(plan.updatedNodes, templateB2)
+		require.NotNil(t, migratedB)
+		assert.Equal(t, nodeB.ID, migratedB.ID)
+		assert.Equal(t, model.WorkflowNodeStateLocked, migratedB.State)
+		assert.Equal(t, model.UUIDArray{nodeC.ID}, migratedB.DependsOn)
+		require.Len(t, plan.relockedNodes, 1)
+		assert.Equal(t, nodeB.ID, plan.relockedNodes[0].ID)
+		require.Len(t, plan.report.Remapped, 1)
+		assert.Equal(t, templateB, *plan.report.Remapped[0].PreviousNodeTemplateID)
+
+		// The end node still follows B
+		assert.Equal(t, endNode.ID, *plan.endNodeID)
+		assert.Equal(t, model.UUIDArray{nodeB.ID}, findNode(plan.updatedNodes, endNodeTemplateID).DependsOn)
+	})
+
+	t.Run("Voids Locked And Skipped Nodes", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateReady)
+		nodeB := node(templateB, model.WorkflowNodeStateLocked, nodeA.ID)
+		nodeC := node(templateC, model.WorkflowNodeStateSkipped, nodeA.ID)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeB.ID)
+		target := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA}, EndNodeTemplateID: &templateA}
+
+		plan, err := s.planMigration(consignment, []model.WorkflowNode{nodeA, nodeB, nodeC, endNode}, []model.WorkflowTemplate{target},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA)}, nil, now)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.report.Conflicts)
+		assert.Empty(t, plan.addedNodes)
+		assert.Empty(t, plan.relockedNodes)
+		assert.Empty(t, plan.unlockedNodes)
+		assert.False(t, plan.report.Finished)
+		assert.Equal(t, model.UUIDArray{nodeA.ID}, findNode(plan.updatedNodes, endNodeTemplateID).DependsOn)
+
+		// The nodes are kept, so that their events and context changes still point at them
+		require.Len(t, plan.report.Voided, 2)
+		for _, voided := range []*model.WorkflowNode{findNode(plan.updatedNodes, templateB), findNode(plan.updatedNodes, templateC)} {
+			require.NotNil(t, voided)
+			assert.Equal(t, model.WorkflowNodeStateLocked, voided.State)
+			assert.Equal(t, model.WorkflowNodeExtendedStateMigrationVoided, *voided.ExtendedState)
+			assert.True(t, voided.IsVoided())
+		}
+	})
+
+	t.Run("Completes End Node When Last Pending Node Is Dropped", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateCompleted)
+		nodeB := node(templateB, model.WorkflowNodeStateLocked, nodeA.ID)
+		endNode := node(endNodeTemplateID, model.WorkflowNodeStateLocked, nodeB.ID)
+		target := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA}, EndNodeTemplateID: &templateA}
+
+		plan, err := s.planMigration(consignment, []model.WorkflowNode{nodeA, nodeB, endNode}, []model.WorkflowTemplate{target},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA)}, nil, now)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.report.Conflicts)
+		assert.True(t, plan.report.Finished)
+		// The end node is completed rather than registered with the Task Manager
+		assert.Empty(t, plan.unlockedNodes)
+		assert.Empty(t, plan.report.Unlocked)
+		migratedEnd := findNode(plan.updatedNodes, endNodeTemplateID)
+		require.NotNil(t, migratedEnd)
+		assert.Equal(t, model.WorkflowNodeStateCompleted, migratedEnd.State)
+		assert.Equal(t, model.UUIDArray{nodeA.ID}, migratedEnd.DependsOn)
+		require.Len(t, plan.report.Voided, 1)
+		assert.Equal(t, nodeB.ID, plan.report.Voided[0].WorkflowNodeID)
+	})
+
+	t.Run("Leaves Voided Nodes Alone", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateReady)
+		voidedB := node(templateB, model.WorkflowNodeStateLocked, nodeA.ID)
+		voidedB.ExtendedState = strPtr(model.WorkflowNodeExtendedStateMigrationVoided)
+		target := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA, templateB}}
+
+		plan, err := s.planMigration(consignment, []model.WorkflowNode{nodeA, voidedB}, []model.WorkflowTemplate{target},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA), nodeTemplate(templateB, templateA)}, nil, now)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.report.Voided)
+		// A voided node is never reused; the node template gets a new node
+		require.Len(t, plan.addedNodes, 1)
+		assert.NotEqual(t, voidedB.ID, plan.addedNodes[0].ID)
+		assert.Nil(t, findNode(plan.updatedNodes, templateB))
+	})
+
+	t.Run("Conflict When Started Node Would Be Voided", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateCompleted)
+		nodeB := node(templateB, model.WorkflowNodeStateInProgress, nodeA.ID)
+		target := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA, templateC}}
+
+		plan, err := s.planMigration(consignment, []model.WorkflowNode{nodeA, nodeB}, []model.WorkflowTemplate{target},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA), nodeTemplate(templateC, templateA)}, nil, now)
+
+		require.NoError(t, err)
+		require.Len(t, plan.report.Conflicts, 1)
+		assert.Contains(t, plan.report.Conflicts[0], nodeB.ID.String())
+	})
+
+	t.Run("Resolves Unlock Configuration", func(t *testing.T) {
+		nodeA := node(templateA, model.WorkflowNodeStateCompleted)
+		nodeA.Outcome = strPtr("APPROVED")
+		escalation := nodeTemplate(templateC)
+		escalation.UnlockConfiguration = &model.UnlockConfig{AnyOf: []model.UnlockGroup{{AllOf: []model.UnlockCondition{
+			{NodeTemplateID: templateA, Outcome: strPtr("APPROVED")},
+		}}}}
+		target := model.WorkflowTemplate{NodeTemplates: model.UUIDArray{templateA, templateC}}
+
+		plan, err := s.planMigration(consignment, []model.WorkflowNode{nodeA}, []model.WorkflowTemplate{target},
+			[]model.WorkflowNodeTemplate{nodeTemplate(templateA), escalation}, nil, now)
+
+		require.NoError(t, err)
+		require.Len(t, plan.addedNodes, 1)
+		assert.Equal(t, model.WorkflowNodeStateReady, plan.addedNodes[0].State)
+		assert.Equal(t, nodeA.ID, *plan.addedNodes[0].UnlockConfiguration.AnyOf[0].AllOf[0].NodeID)
+		assert.Nil(t, plan.endNodeID)
+	})
+}
+
+func TestWorkflowTemplateMigrationService_MigrateConsignments(t *testing.T) {
+	ctx := context.Background()
+	familyID := uuid.New()
+	templateA, templateB := uuid.New(), uuid.New()
+	fromTemplate := &model.WorkflowTemplate{
+		BaseModel:     model.BaseModel{ID: uuid.New()},
+		FamilyID:      familyID,
+		VersionNumber: 1,
+		Status:        model.WorkflowTemplateStatusPublished,
+		NodeTemplates: model.UUIDArray{templateA},
+	}
+	toTemplate := &model.WorkflowTemplate{
+		BaseModel:     model.BaseModel{ID: uuid.New()},
+		FamilyID:      familyID,
+		VersionNumber: 2,
+		Status:        model.WorkflowTemplateStatusPublished,
+		NodeTemplates: model.UUIDArray{templateA, templateB},
+	}
+	nodeTemplates := []model.WorkflowNodeTemplate{
+		{BaseModel: model.BaseModel{ID: templateA}},
+		{BaseModel: model.BaseModel{ID: templateB}, DependsOn: model.UUIDArray{templateA}},
+	}
+
+	t.Run("Rejects Different Family", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		templates.On("GetWorkflowTemplateByID", ctx, fromTemplate.ID).Return(fromTemplate, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, toTemplate.ID).Return(toTemplate, nil)
+		otherFamily := *toTemplate
+		otherFamily.ID = uuid.New()
+		otherFamily.FamilyID = uuid.New()
+		templates.On("GetWorkflowTemplateByID", ctx, otherFamily.ID).Return(&otherFamily, nil)
+
+		_, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{FromTemplateID: fromTemplate.ID, ToTemplateID: otherFamily.ID})
+
+		assert.ErrorIs(t, err, ErrInvalidTemplateMigration)
+	})
+
+	t.Run("Rejects Older Version", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		templates.On("GetWorkflowTemplateByID", ctx, fromTemplate.ID).Return(fromTemplate, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, toTemplate.ID).Return(toTemplate, nil)
+
+		_, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{FromTemplateID: toTemplate.ID, ToTemplateID: fromTemplate.ID})
+
+		assert.ErrorIs(t, err, ErrInvalidTemplateMigration)
+	})
+
+	t.Run("Rejects Mapping Outside Versions", func(t *testing.T) {
+		db, _ := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		templates.On("GetWorkflowTemplateByID", ctx, fromTemplate.ID).Return(fromTemplate, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, toTemplate.ID).Return(toTemplate, nil)
+
+		_, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{
+			FromTemplateID:      fromTemplate.ID,
+			ToTemplateID:        toTemplate.ID,
+			NodeTemplateMapping: map[uuid.UUID]uuid.UUID{templateB: templateA},
+		})
+
+		assert.ErrorIs(t, err, ErrInvalidTemplateMigration)
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		templates.On("GetWorkflowTemplateByID", ctx, fromTemplate.ID).Return(fromTemplate, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, toTemplate.ID).Return(toTemplate, nil)
+		consignmentID := uuid.New()
+		nodeA := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateA, State: model.WorkflowNodeStateCompleted}
+
+		sqlMock.ExpectQuery(`SELECT "id" FROM "consignments" WHERE state = \$1 AND workflow_template_ids @> \$2::jsonb ORDER BY created_at ASC`).
+			WithArgs("IN_PROGRESS", `["`+fromTemplate.ID.String()+`"]`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(consignmentID))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 ORDER BY "consignments"."id" LIMIT \$2$`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "state", "workflow_template_ids", "global_context"}).
+				AddRow(consignmentID, "IN_PROGRESS", []byte(`["`+fromTemplate.ID.String()+`"]`), []byte(`{}`)))
+		templates.On("GetWorkflowNodeTemplatesByIDs", ctx, []uuid.UUID{templateA, templateB}).Return(nodeTemplates, nil).Once()
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{nodeA}, nil).Once()
+		sqlMock.ExpectRollback()
+
+		report, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{
+			FromTemplateID: fromTemplate.ID,
+			ToTemplateID:   toTemplate.ID,
+			DryRun:         true,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Planned)
+		require.Len(t, report.Consignments, 1)
+		assert.Equal(t, model.ConsignmentMigrationStatusPlanned, report.Consignments[0].Status)
+		require.Len(t, report.Consignments[0].Added, 1)
+		assert.Equal(t, templateB, report.Consignments[0].Added[0].NodeTemplateID)
+		assert.Equal(t, model.WorkflowNodeStateReady, report.Consignments[0].Added[0].State)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertNotCalled(t, "CreateWorkflowNodesInTx", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Migrates Consignment", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		templates.On("GetWorkflowTemplateByID", ctx, fromTemplate.ID).Return(fromTemplate, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, toTemplate.ID).Return(toTemplate, nil)
+		consignmentID := uuid.New()
+		nodeA := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateA, State: model.WorkflowNodeStateCompleted}
+
+		sqlMock.ExpectQuery(`SELECT "id" FROM "consignments" WHERE state = \$1 AND workflow_template_ids @> \$2::jsonb ORDER BY created_at ASC`).
+			WithArgs("IN_PROGRESS", `["`+fromTemplate.ID.String()+`"]`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(consignmentID))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 ORDER BY "consignments"."id" LIMIT \$2 FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "state", "workflow_template_ids", "global_context"}).
+				AddRow(consignmentID, "IN_PROGRESS", []byte(`["`+fromTemplate.ID.String()+`"]`), []byte(`{}`)))
+		templates.On("GetWorkflowNodeTemplatesByIDs", ctx, []uuid.UUID{templateA, templateB}).Return(nodeTemplates, nil).Once()
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{nodeA}, nil).Once()
+		nodeRepo.On("CreateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].WorkflowNodeTemplateID == templateB && nodes[0].State == model.WorkflowNodeStateReady
+		})).Return(nil, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 1 && nodes[0].ID == nodeA.ID
+		})).Return(nil).Once()
+		sqlMock.ExpectExec(`UPDATE "consignments" SET "updated_at"=\$1,"state"=\$2,"items"=\$3,"end_node_id"=\$4,"workflow_template_ids"=\$5 WHERE "id" = \$6`).
+			WithArgs(sqlmock.AnyArg(), "IN_PROGRESS", sqlmock.AnyArg(), nil, `["`+toTemplate.ID.String()+`"]`, consignmentID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		var registered []model.WorkflowNode
+		service.SetPreCommitCallback(func(_ context.Context, relocked, unlocked []model.WorkflowNode, _ map[string]any) error {
+			assert.Empty(t, relocked)
+			registered = unlocked
+			return nil
+		})
+
+		report, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{FromTemplateID: fromTemplate.ID, ToTemplateID: toTemplate.ID})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Migrated)
+		require.Len(t, registered, 1)
+		assert.Equal(t, templateB, registered[0].WorkflowNodeTemplateID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+
+	t.Run("Callback Failure Leaves Consignment Unchanged", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		templates.On("GetWorkflowTemplateByID", ctx, fromTemplate.ID).Return(fromTemplate, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, toTemplate.ID).Return(toTemplate, nil)
+		consignmentID := uuid.New()
+		nodeA := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateA, State: model.WorkflowNodeStateCompleted}
+
+		sqlMock.ExpectQuery(`SELECT "id" FROM "consignments" WHERE state = \$1 AND workflow_template_ids @> \$2::jsonb ORDER BY created_at ASC`).
+			WithArgs("IN_PROGRESS", `["`+fromTemplate.ID.String()+`"]`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(consignmentID))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 ORDER BY "consignments"."id" LIMIT \$2 FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "state", "workflow_template_ids", "global_context"}).
+				AddRow(consignmentID, "IN_PROGRESS", []byte(`["`+fromTemplate.ID.String()+`"]`), []byte(`{}`)))
+		templates.On("GetWorkflowNodeTemplatesByIDs", ctx, []uuid.UUID{templateA, templateB}).Return(nodeTemplates, nil).Once()
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{nodeA}, nil).Once()
+		nodeRepo.On("CreateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+		sqlMock.ExpectExec(`UPDATE "consignments"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectRollback()
+		service.SetPreCommitCallback(func(context.Context, []model.WorkflowNode, []model.WorkflowNode, map[string]any) error {
+			return assert.AnError
+		})
+
+		report, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{FromTemplateID: fromTemplate.ID, ToTemplateID: toTemplate.ID})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, model.ConsignmentMigrationStatusFailed, report.Consignments[0].Status)
+		assert.NotEmpty(t, report.Consignments[0].Error)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Finishes Consignment Without Pending Nodes", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		templates := new(MockTemplateProvider)
+		nodeRepo := new(MockWorkflowNodeRepository)
+		service := NewWorkflowTemplateMigrationService(db, templates, nodeRepo)
+		consignmentID := uuid.New()
+
+		// Version 2 drops B, the only node left to do
+		withB := &model.WorkflowTemplate{
+			BaseModel:         model.BaseModel{ID: uuid.New()},
+			FamilyID:          familyID,
+			VersionNumber:     1,
+			Status:            model.WorkflowTemplateStatusPublished,
+			NodeTemplates:     model.UUIDArray{templateA, templateB},
+			EndNodeTemplateID: &templateB,
+		}
+		withoutB := &model.WorkflowTemplate{
+			BaseModel:         model.BaseModel{ID: uuid.New()},
+			FamilyID:          familyID,
+			VersionNumber:     2,
+			Status:            model.WorkflowTemplateStatusPublished,
+			NodeTemplates:     model.UUIDArray{templateA},
+			EndNodeTemplateID: &templateA,
+		}
+		templates.On("GetWorkflowTemplateByID", ctx, withB.ID).Return(withB, nil)
+		templates.On("GetWorkflowTemplateByID", ctx, withoutB.ID).Return(withoutB, nil)
+		templates.On("GetWorkflowNodeTemplatesByIDs", ctx, []uuid.UUID{templateA}).Return(nodeTemplates[:1], nil).Once()
+
+		nodeA := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateA, State: model.WorkflowNodeStateCompleted}
+		nodeB := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: templateB, State: model.WorkflowNodeStateLocked, DependsOn: model.UUIDArray{nodeA.ID}}
+		endNode := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}, ConsignmentID: &consignmentID, WorkflowNodeTemplateID: uuid.MustParse(DEFAULT_END_NODE_TEMPLATE_ID), State: model.WorkflowNodeStateLocked, DependsOn: model.UUIDArray{nodeB.ID}}
+
+		sqlMock.ExpectQuery(`SELECT "id" FROM "consignments"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(consignmentID))
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(consignmentID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "state", "workflow_template_ids", "global_context"}).
+				AddRow(consignmentID, "IN_PROGRESS", []byte(`["`+withB.ID.String()+`"]`), []byte(`{}`)))
+		nodeRepo.On("GetWorkflowNodesByConsignmentIDInTx", ctx, mock.Anything, consignmentID).
+			Return([]model.WorkflowNode{nodeA, nodeB, endNode}, nil).Once()
+		nodeRepo.On("CreateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			return len(nodes) == 0
+		})).Return(nil, nil).Once()
+		nodeRepo.On("UpdateWorkflowNodesInTx", ctx, mock.Anything, mock.MatchedBy(func(nodes []model.WorkflowNode) bool {
+			states := make(map[uuid.UUID]model.WorkflowNodeState, len(nodes))
+			for _, node := range nodes {
+				states[node.ID] = node.State
+			}
+			return len(nodes) == 3 && states[endNode.ID] == model.WorkflowNodeStateCompleted && states[nodeB.ID] == model.WorkflowNodeStateLocked
+		})).Return(nil).Once()
+		sqlMock.ExpectExec(`UPDATE "consignments" SET "updated_at"=\$1,"state"=\$2,.* WHERE "id" = \$6`).
+			WithArgs(sqlmock.AnyArg(), "FINISHED", sqlmock.AnyArg(), endNode.ID, `["`+withoutB.ID.String()+`"]`, consignmentID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+		service.SetPreCommitCallback(func(context.Context, []model.WorkflowNode, []model.WorkflowNode, map[string]any) error {
+			t.Fatal("no task must be registered or discarded")
+			return nil
+		})
+
+		report, err := service.MigrateConsignments(ctx, &model.TemplateMigrationRequest{FromTemplateID: withB.ID, ToTemplateID: withoutB.ID})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Migrated)
+		assert.True(t, report.Consignments[0].Finished)
+		require.Len(t, report.Consignments[0].Voided, 1)
+		assert.Equal(t, nodeB.ID, report.Consignments[0].Voided[0].WorkflowNodeID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		nodeRepo.AssertExpectations(t)
+	})
+}
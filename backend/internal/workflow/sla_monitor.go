@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// slaBatchSize is the maximum number of expired SLAs processed per monitor cycle.
+const slaBatchSize = 100
+
+// expiredSLASource returns READY or IN_PROGRESS workflow nodes whose SLA expired at now.
+type expiredSLASource func(ctx context.Context, now time.Time, limit int) ([]model.WorkflowNode, error)
+
+// slaBreachApplier applies the SLA breach of a workflow node and propagates its effects.
+type slaBreachApplier func(ctx context.Context, nodeID uuid.UUID, now time.Time) error
+
+// SLAMonitor periodically applies the SLA breaches of workflow nodes whose SLA expired.
+// Deadlines are persisted on the workflow nodes, so SLAs that expired while the server was
+// down are applied on the first cycle after it restarts.
+type SLAMonitor struct {
+	source   expiredSLASource
+	apply    slaBreachApplier
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newSLAMonitor(source expiredSLASource, apply slaBreachApplier, interval time.Duration) *SLAMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SLAMonitor{
+		source:   source,
+		apply:    apply,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the monitor loop in a background goroutine.
+func (m *SLAMonitor) Start() {
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.applyExpired(m.ctx)
+			select {
+			case <-m.ctx.Done():
+				slog.Info("workflow node SLA monitor stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the monitor loop and waits for the in-flight cycle to finish.
+func (m *SLAMonitor) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// applyExpired applies one batch of expired SLAs.
+// Returns the number of breaches applied successfully.
+func (m *SLAMonitor) applyExpired(ctx context.Context) int {
+	now := time.Now().UTC()
+	nodes, err := m.source(ctx, now, slaBatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load workflow nodes with expired SLA", "error", err)
+		return 0
+	}
+
+	applied := 0
+	for _, node := range nodes {
+		if ctx.Err() != nil {
+			return applied
+		}
+
+		// A failed breach is left in place and retried in the next cycle.
+		if err := m.apply(ctx, node.ID, now); err != nil {
+			slog.WarnContext(ctx, "failed to apply workflow node SLA breach, will retry",
+				"workflowNodeID", node.ID,
+				"slaDeadline", node.SLADeadline,
+				"error", err)
+			continue
+		}
+		applied++
+	}
+
+	return applied
+}
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+func TestSLAMonitor_ApplyExpired(t *testing.T) {
+	t.Run("Applies Each Expired SLA", func(t *testing.T) {
+		nodes := []model.WorkflowNode{
+			{BaseModel: model.BaseModel{ID: uuid.New()}},
+			{BaseModel: model.BaseModel{ID: uuid.New()}},
+		}
+		source := func(_ context.Context, _ time.Time, limit int) ([]model.WorkflowNode, error) {
+			assert.Equal(t, slaBatchSize, limit)
+			return nodes, nil
+		}
+		var applied []uuid.UUID
+		apply := func(_ context.Context, nodeID uuid.UUID, _ time.Time) error {
+			applied = append(applied, nodeID)
+			return nil
+		}
+
+		m := newSLAMonitor(source, apply, time.Second)
+		count := m.applyExpired(context.Background())
+
+		assert.Equal(t, 2, count)
+		assert.Equal(t, []uuid.UUID{nodes[0].ID, nodes[1].ID}, applied)
+	})
+
+	t.Run("Failure Does Not Stop Batch", func(t *testing.T) {
+		failing := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}}
+		other := model.WorkflowNode{BaseModel: model.BaseModel{ID: uuid.New()}}
+		source := func(context.Context, time.Time, int) ([]model.WorkflowNode, error) {
+			return []model.WorkflowNode{failing, other}, nil
+		}
+		apply := func(_ context.Context, nodeID uuid.UUID, _ time.Time) error {
+			if nodeID == failing.ID {
+				return errors.New("db error")
+			}
+			return nil
+		}
+
+		m := newSLAMonitor(source, apply, time.Second)
+		count := m.applyExpired(context.Background())
+
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Load Error", func(t *testing.T) {
+		source := func(context.Context, time.Time, int) ([]model.WorkflowNode, error) {
+			return nil, errors.New("db error")
+		}
+		apply := func(context.Context, uuid.UUID, time.Time) error {
+			t.Fatal("apply must not be called")
+			return nil
+		}
+
+		m := newSLAMonitor(source, apply, time.Second)
+		assert.Equal(t, 0, m.applyExpired(context.Background()))
+	})
+}
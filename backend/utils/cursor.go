@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Cursor is the position of the last item of a page in keyset pagination: the value of the column the list is
+// ordered by, and the ID of the item, which breaks ties. Clients receive it as an opaque string and pass it back
+// to get the next page, which starts after the item regardless of the rows inserted in the meantime.
+type Cursor struct {
+	Key string    `json:"k"` // Sort key of the item; timestamps are formatted as RFC 3339 with nanoseconds
+	ID  uuid.UUID `json:"i"` // ID of the item
+}
+
+// NewTimeCursor returns the cursor of an item of a list ordered by a timestamp, such as created_at.
+func NewTimeCursor(t time.Time, id uuid.UUID) Cursor {
+	return Cursor{Key: t.UTC().Format(time.RFC3339Nano), ID: id}
+}
+
+// Time returns the sort key of a cursor created by NewTimeCursor.
+func (c Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: sort key is not a timestamp", ErrInvalidCursor)
+	}
+	return t, nil
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes a cursor returned by Encode.
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// ParseCursorParam extracts the cursor query parameter, which selects keyset pagination. A cursor cannot be
+// combined with an offset.
+func ParseCursorParam(r *http.Request) (*Cursor, error) {
+	encoded := r.URL.Query().Get("cursor")
+	if encoded == "" {
+		return nil, nil
+	}
+	if r.URL.Query().Get("offset") != "" {
+		return nil, fmt.Errorf("'cursor' and 'offset' query parameters cannot be combined")
+	}
+	cursor, err := DecodeCursor(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'cursor' query parameter")
+	}
+	return cursor, nil
+}
+
+// KeysetPage trims items, fetched with a limit of limit+1, to limit, and returns the encoded cursor of the last
+// item of the page, or nil if there are no more items after it.
+func KeysetPage[T any](items []T, limit int, cursorOf func(item *T) Cursor) ([]T, *string) {
+	if len(items) <= limit {
+		return items, nil
+	}
+	items = items[:limit]
+	next := cursorOf(&items[limit-1]).Encode()
+	return items, &next
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	createdAt := time.Date(2026, 10, 17, 9, 30, 0, 123456000, time.UTC)
+	cursor := NewTimeCursor(createdAt, uuid.New())
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+	decodedTime, err := decoded.Time()
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(decodedTime))
+
+	_, err = Cursor{Key: "0902.10", ID: uuid.New()}.Time()
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	for _, encoded := range []string{"not base64!", "bm90IGpzb24", Cursor{Key: "0902.10"}.Encode()} {
+		_, err := DecodeCursor(encoded)
+		assert.ErrorIs(t, err, ErrInvalidCursor, encoded)
+	}
+}
+
+func TestParseCursorParam(t *testing.T) {
+	encoded := Cursor{Key: "0902.10", ID: uuid.New()}.Encode()
+	parse := func(query string) (*Cursor, error) {
+		req, _ := http.NewRequest("GET", "/api/v1/hscodes?"+query, nil)
+		return ParseCursorParam(req)
+	}
+
+	cursor, err := parse("")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+
+	cursor, err = parse("limit=10&cursor=" + encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "0902.10", cursor.Key)
+
+	_, err = parse("offset=10&cursor=" + encoded)
+	assert.Error(t, err)
+	_, err = parse("cursor=invalid")
+	assert.Error(t, err)
+}
+
+func TestKeysetPage(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	cursorOf := func(id *uuid.UUID) Cursor { return Cursor{Key: "key", ID: *id} }
+
+	items, next := KeysetPage(ids, 3, cursorOf)
+	assert.Len(t, items, 3)
+	assert.Nil(t, next)
+
+	items, next = KeysetPage(ids, 2, cursorOf)
+	assert.Equal(t, ids[:2], items)
+	require.NotNil(t, next)
+	cursor, err := DecodeCursor(*next)
+	require.NoError(t, err)
+	assert.Equal(t, ids[1], cursor.ID)
+}
@@ -73,7 +73,8 @@ func (h *OGAHandler) HandleInjectData(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleGetApplications handles GET /api/oga/applications
-// Returns all applications, optionally filtered by status query parameter
+// Returns all applications, optionally filtered by status query parameter, newest first.
+// Pagination query params: page and pageSize, or cursor (nextCursor of the previous page) and pageSize
 func (h *OGAHandler) HandleGetApplications(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -81,20 +82,35 @@ func (h *OGAHandler) HandleGetApplications(w http.ResponseWriter, r *http.Reques
 	}
 
 	ctx := r.Context()
-	status := r.URL.Query().Get("status")
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-
-	if err != nil {
-		WriteJSONError(w, http.StatusBadRequest, "Invalid page number")
+	query := r.URL.Query()
+	status := query.Get("status")
+	cursor := query.Get("cursor")
+
+	var page, pageSize int
+	var err error
+	if pageStr := query.Get("page"); pageStr != "" {
+		if cursor != "" {
+			WriteJSONError(w, http.StatusBadRequest, "Page and cursor cannot be combined")
+			return
+		}
+		if page, err = strconv.Atoi(pageStr); err != nil {
+			WriteJSONError(w, http.StatusBadRequest, "Invalid page number")
+			return
+		}
 	}
-	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
-
-	if err != nil {
-		WriteJSONError(w, http.StatusBadRequest, "Invalid page size")
+	if pageSizeStr := query.Get("pageSize"); pageSizeStr != "" {
+		if pageSize, err = strconv.Atoi(pageSizeStr); err != nil {
+			WriteJSONError(w, http.StatusBadRequest, "Invalid page size")
+			return
+		}
 	}
 
-	result, err := h.service.GetApplications(ctx, status, page, pageSize)
+	result, err := h.service.GetApplications(ctx, status, page, pageSize, cursor)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
 		slog.ErrorContext(ctx, "failed to get applications", "error", err)
 		WriteJSONError(w, http.StatusInternalServerError, "Failed to get applications")
 		return
@@ -188,3 +204,41 @@ func (h *OGAHandler) HandleReviewApplication(w http.ResponseWriter, r *http.Requ
 		"message": "Application reviewed successfully",
 	})
 }
+
+// HandleCancelApplication handles POST /api/oga/cancel
+// Called by the originating service when the consignment behind an application is cancelled
+func (h *OGAHandler) HandleCancelApplication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	var req CancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.TaskID == uuid.Nil {
+		WriteJSONError(w, http.StatusBadRequest, "taskId is required")
+		return
+	}
+
+	if err := h.service.CancelApplication(ctx, req.TaskID, req.Reason); err != nil {
+		if errors.Is(err, ErrApplicationNotFound) {
+			WriteJSONError(w, http.StatusNotFound, "Application not found")
+		} else {
+			slog.ErrorContext(ctx, "failed to cancel application",
+				"taskID", req.TaskID,
+				"error", err)
+			WriteJSONError(w, http.StatusInternalServerError, "Failed to cancel application: "+err.Error())
+		}
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Application cancelled successfully",
+	})
+}
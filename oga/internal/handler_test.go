@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// getApplications calls HandleGetApplications with the given query and returns the status code and the body.
+func getApplications(t *testing.T, handler *OGAHandler, query url.Values) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/oga/applications?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	handler.HandleGetApplications(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+func TestOGAHandler_HandleGetApplications_Cursor(t *testing.T) {
+	store := newTestStore(t)
+	handler := NewOGAHandler(NewOGAService(store, nil))
+	createdAt := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	created := make(map[uuid.UUID]bool)
+	for range 3 {
+		created[createTestApplication(t, store, createdAt)] = true
+	}
+
+	t.Run("Pages Through Applications", func(t *testing.T) {
+		listed := make(map[uuid.UUID]bool)
+		query := url.Values{"pageSize": {"2"}}
+		for pages := 1; ; pages++ {
+			code, body := getApplications(t, handler, query)
+			if code != http.StatusOK {
+				t.Fatalf("page %d: status %d, body %s", pages, code, body)
+			}
+			var resp PagedResponse[Application]
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatalf("page %d: failed to decode response: %v", pages, err)
+			}
+			switch {
+			case pages == 1 && (resp.Total == nil || *resp.Total != 3):
+				t.Errorf("total %v, want 3", resp.Total)
+			case pages > 1 && resp.Total != nil:
+				t.Errorf("page %d: total %d, want none on a cursor page", pages, *resp.Total)
+			}
+			for _, app := range resp.Items {
+				if listed[app.TaskID] {
+					t.Errorf("page %d: application %s listed twice", pages, app.TaskID)
+				}
+				listed[app.TaskID] = true
+			}
+			if resp.NextCursor == nil {
+				if pages != 2 {
+					t.Errorf("listed %d pages, want 2", pages)
+				}
+				break
+			}
+			// A new application does not shift the next page
+			createTestApplication(t, store, createdAt.Add(time.Hour))
+			query.Set("cursor", *resp.NextCursor)
+		}
+		if len(listed) != len(created) {
+			t.Errorf("listed %d applications, want %d", len(listed), len(created))
+		}
+		for id := range created {
+			if !listed[id] {
+				t.Errorf("application %s was not listed", id)
+			}
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		cursor := applicationCursor{CreatedAt: createdAt, TaskID: uuid.New()}.encode()
+		tests := []struct {
+			name  string
+			query url.Values
+		}{
+			{"Invalid Cursor", url.Values{"cursor": {"not-a-cursor"}}},
+			{"Page And Cursor", url.Values{"page": {"2"}, "cursor": {cursor}}},
+			{"Invalid Page", url.Values{"page": {"two"}}},
+			{"Invalid Page Size", url.Values{"pageSize": {"ten"}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				code, body := getApplications(t, handler, tt.query)
+				if code != http.StatusBadRequest {
+					t.Errorf("status %d, want %d, body %s", code, http.StatusBadRequest, body)
+				}
+			})
+		}
+	})
+}
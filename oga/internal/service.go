@@ -3,6 +3,7 @@ package internal
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,13 +18,17 @@ import (
 // ErrApplicationNotFound is returned when an application is not found
 var ErrApplicationNotFound = errors.New("application not found")
 
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // OGAService handles OGA portal operations
 type OGAService interface {
 	// CreateApplication creates a new application from injected data
 	CreateApplication(ctx context.Context, req *InjectRequest) error
 
-	// GetApplications returns a paginated list of applications (optionally filtered by status)
-	GetApplications(ctx context.Context, status string, page, pageSize int) (*PagedResponse[Application], error)
+	// GetApplications returns a paginated list of applications (optionally filtered by status), newest first.
+	// If cursor is set, the page starts after it instead of at page.
+	GetApplications(ctx context.Context, status string, page, pageSize int, cursor string) (*PagedResponse[Application], error)
 
 	// GetApplication returns a specific application by task ID
 	GetApplication(ctx context.Context, taskID uuid.UUID) (*Application, error)
@@ -31,6 +36,9 @@ type OGAService interface {
 	// ReviewApplication approves or rejects an application and sends response back to service
 	ReviewApplication(ctx context.Context, taskID uuid.UUID, reviewerData map[string]any) error
 
+	// CancelApplication closes an application whose consignment was cancelled by the trader
+	CancelApplication(ctx context.Context, taskID uuid.UUID, reason string) error
+
 	// Close closes the service and releases resources
 	Close() error
 }
@@ -40,6 +48,13 @@ type Meta struct {
 	VerificationId   string `json:"verificationId"`
 }
 
+// CancelRequest represents a cancellation notice from the originating service
+type CancelRequest struct {
+	TaskID     uuid.UUID `json:"taskId"`
+	WorkflowID uuid.UUID `json:"workflowId"`
+	Reason     string    `json:"reason"`
+}
+
 // InjectRequest represents the incoming data from services
 type InjectRequest struct {
 	TaskID     uuid.UUID      `json:"taskId"`
@@ -65,10 +80,37 @@ type Application struct {
 
 // PagedResponse is a generic paginated response wrapper.
 type PagedResponse[T any] struct {
-	Items    []T   `json:"items"`
-	Total    int64 `json:"total"`
-	Page     int   `json:"page"`
-	PageSize int   `json:"pageSize"`
+	Items      []T     `json:"items"`
+	Total      *int64  `json:"total,omitempty"` // Number of matching items; absent on pages selected by a cursor
+	Page       int     `json:"page"`
+	PageSize   int     `json:"pageSize"`
+	NextCursor *string `json:"nextCursor,omitempty"` // Cursor of the next page; absent on the last page
+}
+
+// applicationCursor is the position of the last application of a page in keyset pagination. Clients receive it
+// as an opaque string, and the next page starts after it regardless of the applications created in the meantime.
+type applicationCursor struct {
+	CreatedAt time.Time `json:"c"`
+	TaskID    uuid.UUID `json:"i"`
+}
+
+// encode returns the opaque string form of the cursor.
+func (c applicationCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeApplicationCursor decodes a cursor returned by encode.
+func decodeApplicationCursor(encoded string) (*applicationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor applicationCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.TaskID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
 }
 
 // TaskResponse represents the response sent back to the service
@@ -166,7 +208,7 @@ func (s *ogaService) CreateApplication(ctx context.Context, req *InjectRequest)
 }
 
 // GetApplications returns a paginated list of applications (optionally filtered by status)
-func (s *ogaService) GetApplications(ctx context.Context, status string, page, pageSize int) (*PagedResponse[Application], error) {
+func (s *ogaService) GetApplications(ctx context.Context, status string, page, pageSize int, cursor string) (*PagedResponse[Application], error) {
 	if page < 1 {
 		page = 1
 	}
@@ -174,11 +216,28 @@ func (s *ogaService) GetApplications(ctx context.Context, status string, page, p
 		pageSize = 20
 	}
 
+	var after *applicationCursor
 	offset := (page - 1) * pageSize
-	records, total, err := s.store.List(ctx, status, offset, pageSize)
+	if cursor != "" {
+		var err error
+		if after, err = decodeApplicationCursor(cursor); err != nil {
+			return nil, err
+		}
+		page, offset = 1, 0
+	}
+
+	// One application more than the page size is fetched to tell whether there is a next page
+	records, total, err := s.store.List(ctx, status, after, offset, pageSize+1)
 	if err != nil {
 		return nil, err
 	}
+	var nextCursor *string
+	if len(records) > pageSize {
+		records = records[:pageSize]
+		last := records[pageSize-1]
+		next := applicationCursor{CreatedAt: last.CreatedAt, TaskID: last.TaskID}.encode()
+		nextCursor = &next
+	}
 
 	applications := make([]Application, len(records))
 	for i, record := range records {
@@ -197,10 +256,11 @@ func (s *ogaService) GetApplications(ctx context.Context, status string, page, p
 	}
 
 	return &PagedResponse[Application]{
-		Items:    applications,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
+		Items:      applications,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		NextCursor: nextCursor,
 	}, nil
 }
 
@@ -291,6 +351,25 @@ func (s *ogaService) ReviewApplication(ctx context.Context, taskID uuid.UUID, re
 	return nil
 }
 
+// CancelApplication marks an application as cancelled so officers no longer act on it.
+// No response is sent back to the service since it initiated the cancellation.
+func (s *ogaService) CancelApplication(ctx context.Context, taskID uuid.UUID, reason string) error {
+	if _, err := s.store.GetByTaskID(taskID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrApplicationNotFound
+		}
+		return fmt.Errorf("failed to get application: %w", err)
+	}
+
+	if err := s.store.UpdateStatus(taskID, "CANCELLED", map[string]any{"cancellationReason": reason}); err != nil {
+		return fmt.Errorf("failed to update application status: %w", err)
+	}
+
+	slog.InfoContext(ctx, "application cancelled", "taskID", taskID, "reason", reason)
+
+	return nil
+}
+
 // sendToService sends the task response to the originating service
 func (s *ogaService) sendToService(ctx context.Context, serviceURL string, response TaskResponse) error {
 	jsonData, err := json.Marshal(response)
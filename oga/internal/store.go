@@ -51,7 +51,7 @@ type ApplicationRecord struct {
 	Data             JSONB      `gorm:"type:text"`                                   // Injected data from service
 	Meta             JSONB      `gorm:"type:text"`                                   // Meta Information on Rendering the form
 	ReviewerResponse JSONB      `gorm:"type:text"`                                   // Response from reviewer
-	Status           string     `gorm:"type:varchar(50);not null;default:'PENDING'"` // PENDING, APPROVED, REJECTED
+	Status           string     `gorm:"type:varchar(50);not null;default:'PENDING'"` // PENDING, APPROVED, REJECTED, CANCELLED
 	ReviewedAt       *time.Time `gorm:"type:datetime"`                               // When it was reviewed
 	CreatedAt        time.Time  `gorm:"autoCreateTime"`
 	UpdatedAt        time.Time  `gorm:"autoUpdateTime"`
@@ -100,22 +100,34 @@ func (s *ApplicationStore) GetByTaskID(taskID uuid.UUID) (*ApplicationRecord, er
 	return &app, nil
 }
 
-// List retrieves applications with optional status filter and pagination.
-func (s *ApplicationStore) List(ctx context.Context, status string, offset, limit int) ([]ApplicationRecord, int64, error) {
+// createdAtSortKey is the creation time of an application as a UTC timestamp with millisecond precision. SQLite
+// stores times as text in the time zone they were written in, with a variable number of fractional digits, so
+// the stored text does not sort chronologically; lists order and page by this normalized key instead.
+const createdAtSortKey = "strftime('%Y-%m-%d %H:%M:%f', created_at)"
+
+// List retrieves applications with optional status filter and pagination, newest first, and the number of
+// applications with the status. If after is set, only the applications after it are listed and they are not
+// counted, since counting would read all of them for every page.
+func (s *ApplicationStore) List(ctx context.Context, status string, after *applicationCursor, offset, limit int) ([]ApplicationRecord, *int64, error) {
 	var apps []ApplicationRecord
-	var total int64
+	var total *int64
 
 	query := s.db.WithContext(ctx).Model(&ApplicationRecord{})
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if after != nil {
+		query = query.Where("("+createdAtSortKey+", task_id) < (strftime('%Y-%m-%d %H:%M:%f', ?), ?)", after.CreatedAt, after.TaskID)
+	} else {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, nil, err
+		}
+		total = &count
 	}
-
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&apps).Error; err != nil {
-		return nil, 0, err
+	if err := query.Order(createdAtSortKey + " DESC, task_id DESC").Offset(offset).Limit(limit).Find(&apps).Error; err != nil {
+		return nil, nil, err
 	}
 
 	return apps, total, nil
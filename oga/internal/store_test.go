@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestStore(t *testing.T) *ApplicationStore {
+	t.Helper()
+	store, err := NewApplicationStore(filepath.Join(t.TempDir(), "oga_applications.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// createTestApplication inserts a pending application created at createdAt and returns its task ID.
+func createTestApplication(t *testing.T, store *ApplicationStore, createdAt time.Time) uuid.UUID {
+	t.Helper()
+	app := &ApplicationRecord{
+		TaskID:     uuid.New(),
+		WorkflowID: uuid.New(),
+		ServiceURL: "http://localhost/api/v1/tasks",
+		Status:     "PENDING",
+		CreatedAt:  createdAt,
+	}
+	if err := store.db.Create(app).Error; err != nil {
+		t.Fatalf("failed to create application: %v", err)
+	}
+	return app.TaskID
+}
+
+// listAllByCursor pages through the applications with the given status, pageSize at a time, and returns the
+// task IDs in the order they were listed. Before fetching each page after the first, beforePage is called with
+// the page number.
+func listAllByCursor(t *testing.T, store *ApplicationStore, status string, pageSize int, beforePage func(page int)) []uuid.UUID {
+	t.Helper()
+	var listed []uuid.UUID
+	var after *applicationCursor
+	for page := 1; ; page++ {
+		if page > 1 && beforePage != nil {
+			beforePage(page)
+		}
+		apps, _, err := store.List(context.Background(), status, after, 0, pageSize)
+		if err != nil {
+			t.Fatalf("failed to list page %d: %v", page, err)
+		}
+		for _, app := range apps {
+			listed = append(listed, app.TaskID)
+		}
+		if len(apps) < pageSize {
+			return listed
+		}
+		last := apps[len(apps)-1]
+		after = &applicationCursor{CreatedAt: last.CreatedAt, TaskID: last.TaskID}
+	}
+}
+
+func TestApplicationStore_List_Cursor(t *testing.T) {
+	t.Run("Equal Creation Times", func(t *testing.T) {
+		store := newTestStore(t)
+		createdAt := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+		var ids []uuid.UUID
+		for range 5 {
+			ids = append(ids, createTestApplication(t, store, createdAt))
+		}
+
+		listed := listAllByCursor(t, store, "", 2, nil)
+
+		// Applications created at the same time are ordered by task ID, and none is skipped or repeated
+		slices.SortFunc(ids, func(a, b uuid.UUID) int { return -slices.Compare(a[:], b[:]) })
+		if !slices.Equal(listed, ids) {
+			t.Errorf("listed %v, want %v", listed, ids)
+		}
+	})
+
+	t.Run("Applications Created Between Pages", func(t *testing.T) {
+		store := newTestStore(t)
+		base := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+		var ids []uuid.UUID
+		for i := range 4 {
+			ids = append(ids, createTestApplication(t, store, base.Add(time.Duration(i)*time.Minute)))
+		}
+
+		var inserted []uuid.UUID
+		listed := listAllByCursor(t, store, "", 2, func(int) {
+			inserted = append(inserted, createTestApplication(t, store, base.Add(time.Hour)))
+		})
+
+		// The pages continue after the last application listed; newer applications belong to a fresh listing
+		want := []uuid.UUID{ids[3], ids[2], ids[1], ids[0]}
+		if !slices.Equal(listed, want) {
+			t.Errorf("listed %v, want %v", listed, want)
+		}
+		if len(inserted) == 0 {
+			t.Fatal("no application was created between pages")
+		}
+	})
+
+	t.Run("Mixed Time Zones", func(t *testing.T) {
+		store := newTestStore(t)
+		base := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+		behindUTC := time.FixedZone("-0500", -5*60*60)
+		// Compared as stored text, the latest application, written in a zone behind UTC, would sort first
+		oldest := createTestApplication(t, store, base)
+		middle := createTestApplication(t, store, base.Add(500*time.Millisecond))
+		newest := createTestApplication(t, store, base.Add(time.Second).In(behindUTC))
+
+		listed := listAllByCursor(t, store, "", 1, nil)
+
+		want := []uuid.UUID{newest, middle, oldest}
+		if !slices.Equal(listed, want) {
+			t.Errorf("listed %v, want %v", listed, want)
+		}
+	})
+
+	t.Run("Status Filter", func(t *testing.T) {
+		store := newTestStore(t)
+		base := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+		pending := createTestApplication(t, store, base)
+		approved := createTestApplication(t, store, base.Add(time.Minute))
+		if err := store.UpdateStatus(approved, "APPROVED", map[string]any{"decision": "APPROVED"}); err != nil {
+			t.Fatalf("failed to update status: %v", err)
+		}
+
+		apps, total, err := store.List(context.Background(), "PENDING", nil, 0, 10)
+		if err != nil {
+			t.Fatalf("failed to list: %v", err)
+		}
+		if total == nil || *total != 1 || len(apps) != 1 || apps[0].TaskID != pending {
+			t.Errorf("listed %d of %v applications, want only %s", len(apps), total, pending)
+		}
+	})
+}